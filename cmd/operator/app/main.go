@@ -76,7 +76,7 @@ func Run() {
 	keeperErr := initKeeper(ctx)
 
 	var wg sync.WaitGroup
-	wg.Add(3)
+	wg.Add(4)
 
 	go func() {
 		defer wg.Done()
@@ -86,6 +86,10 @@ func Run() {
 		defer wg.Done()
 		runClickHouseReconcilerMetricsExporter(ctx)
 	}()
+	go func() {
+		defer wg.Done()
+		runClickHouseAPI(ctx)
+	}()
 	go func() {
 		defer wg.Done()
 		if keeperErr == nil {
@@ -0,0 +1,50 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	ctrlRuntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	controller "github.com/altinity/clickhouse-operator/pkg/controller/chb"
+)
+
+// initBackup registers ClickHouseBackup controller within the manager shared with keeper
+func initBackup() error {
+	if err := api.AddToScheme(scheme); err != nil {
+		logger.Error(err, "init backup - unable to api.AddToScheme")
+		return err
+	}
+
+	err := ctrlRuntime.
+		NewControllerManagedBy(manager).
+		For(&api.ClickHouseBackup{}).
+		// Progress is polled, status updates made by the controller itself should not trigger reconcile
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(
+			&controller.ChbReconciler{
+				Client: manager.GetClient(),
+				Scheme: manager.GetScheme(),
+			},
+		)
+	if err != nil {
+		logger.Error(err, "init backup - unable to ctrlRuntime.NewControllerManagedBy")
+		return err
+	}
+
+	// Initialization successful
+	return nil
+}
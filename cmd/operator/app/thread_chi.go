@@ -18,12 +18,18 @@ import (
 	"context"
 	"time"
 
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/metadata/metadatainformer"
 
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	"github.com/altinity/clickhouse-operator/pkg/chop"
 	chopinformers "github.com/altinity/clickhouse-operator/pkg/client/informers/externalversions"
 	"github.com/altinity/clickhouse-operator/pkg/controller/chi"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
 )
 
 // Prometheus exporter defaults
@@ -34,8 +40,8 @@ const (
 
 // CLI parameter variables
 var (
-	// Setting to 0 disables resync
 	// Informer fires Update() func to periodically verify current state
+	// Overridden by .watch.informers.resync section of the operator's config
 	kubeInformerFactoryResyncPeriod = defaultInformerFactoryResyncPeriod
 	chopInformerFactoryResyncPeriod = defaultInformerFactoryResyncPeriod
 )
@@ -45,29 +51,54 @@ func init() {
 
 var chiController *chi.Controller
 
+// secretsFieldSelector excludes Secrets, which are never referenced by CHIs, but are numerous and large,
+// such as service account tokens and Helm releases, from the cache of Secrets informer
+var secretsFieldSelector = fields.AndSelectors(
+	fields.OneTermNotEqualSelector("type", string(core.SecretTypeServiceAccountToken)),
+	fields.OneTermNotEqualSelector("type", "helm.sh/release.v1"),
+).String()
+
 // initClickHouse is an entry point of the application
 func initClickHouse(ctx context.Context) {
 	log.S().P()
 	defer log.E().P()
 
-	if debugRequest {
-		kubeInformerFactoryResyncPeriod = defaultInformerFactoryResyncDebugPeriod
-		chopInformerFactoryResyncPeriod = defaultInformerFactoryResyncDebugPeriod
-	}
-
 	// Initialize k8s API clients
 	kubeClient, extClient, chopClient := chop.GetClientset(kubeConfigFile, masterURL)
+	dynamicClient := chop.GetDynamicClient(kubeConfigFile, masterURL)
 
 	// Create operator instance
 	chop.New(kubeClient, chopClient, chopConfigFile)
 	log.V(1).F().Info("Config parsed:")
 	log.Info("\n" + chop.Config().String(true))
 
+	kubeInformerFactoryResyncPeriod = chop.Config().Watch.Informers.GetKubeResyncPeriod()
+	chopInformerFactoryResyncPeriod = chop.Config().Watch.Informers.GetCHOpResyncPeriod()
+	if debugRequest {
+		kubeInformerFactoryResyncPeriod = defaultInformerFactoryResyncDebugPeriod
+		chopInformerFactoryResyncPeriod = defaultInformerFactoryResyncDebugPeriod
+	}
+
 	// Create Informers
+	kubeInformerFactoryOptions := []kubeinformers.SharedInformerOption{
+		kubeinformers.WithNamespace(chop.Config().GetInformerNamespace()),
+	}
+	if chop.Config().Watch.Informers.FilterManagedObjects.Value() {
+		// Cache objects managed by the operator only, unrelated pods, StatefulSets and Services are not kept in memory
+		log.V(1).F().Info("Informers watch objects labeled %s=%s only", model.LabelAppName, model.LabelAppValue)
+		kubeInformerFactoryOptions = append(
+			kubeInformerFactoryOptions,
+			kubeinformers.WithTweakListOptions(func(options *meta.ListOptions) {
+				options.LabelSelector = labels.SelectorFromSet(map[string]string{
+					model.LabelAppName: model.LabelAppValue,
+				}).String()
+			}),
+		)
+	}
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(
 		kubeClient,
 		kubeInformerFactoryResyncPeriod,
-		kubeinformers.WithNamespace(chop.Config().GetInformerNamespace()),
+		kubeInformerFactoryOptions...,
 	)
 	chopInformerFactory := chopinformers.NewSharedInformerFactoryWithOptions(
 		chopClient,
@@ -80,6 +111,7 @@ func initClickHouse(ctx context.Context) {
 		chopClient,
 		extClient,
 		kubeClient,
+		dynamicClient,
 		chopInformerFactory,
 		kubeInformerFactory,
 	)
@@ -87,6 +119,58 @@ func initClickHouse(ctx context.Context) {
 	// Start Informers
 	kubeInformerFactory.Start(ctx.Done())
 	chopInformerFactory.Start(ctx.Done())
+
+	// Nodes are cluster-scoped and unlabeled, thus are watched by informer of their own
+	if chiController.ShouldWatchNodes(ctx) {
+		nodeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, kubeInformerFactoryResyncPeriod)
+		chiController.AddNodeInformer(nodeInformerFactory)
+		nodeInformerFactory.Start(ctx.Done())
+	}
+
+	// Namespaces are cluster-scoped, thus are watched by informer of their own, filtered by namespace selector
+	watchNamespaces, err := chiController.ShouldWatchNamespaces(ctx)
+	if err != nil {
+		log.F().Fatal("Unable to select watched namespaces: %v", err)
+	}
+	if watchNamespaces {
+		namespaceInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(
+			kubeClient,
+			kubeInformerFactoryResyncPeriod,
+			kubeinformers.WithTweakListOptions(func(options *meta.ListOptions) {
+				options.LabelSelector = chop.Config().Watch.NamespaceSelector
+			}),
+		)
+		chiController.AddEventHandlersNamespaces(namespaceInformerFactory)
+		namespaceInformerFactory.Start(ctx.Done())
+	}
+
+	// Secrets referenced by CHIs are not labeled, thus are watched by informers of their own, not filtered by labels.
+	// Informers are limited to watched namespaces, Secrets of types never referenced by CHIs are not cached,
+	// and metadata only of Secrets is cached, so data of all Secrets in watched namespaces is not kept in memory
+	metadataClient := chop.GetMetadataClient(kubeConfigFile, masterURL)
+	for _, namespace := range chop.Config().GetInformerNamespaces() {
+		secretInformerFactory := metadatainformer.NewFilteredSharedInformerFactory(
+			metadataClient,
+			kubeInformerFactoryResyncPeriod,
+			namespace,
+			func(options *meta.ListOptions) {
+				options.FieldSelector = secretsFieldSelector
+			},
+		)
+		chiController.AddEventHandlersSecrets(secretInformerFactory)
+		secretInformerFactory.Start(ctx.Done())
+	}
+
+	// Shared templates catalog namespace may be not covered by informers of watched namespaces
+	if chiController.ShouldWatchTemplatesCatalog(ctx) {
+		catalogInformerFactory := chopinformers.NewSharedInformerFactoryWithOptions(
+			chopClient,
+			chopInformerFactoryResyncPeriod,
+			chopinformers.WithNamespace(chop.Config().Template.CHI.Catalog.Namespace),
+		)
+		chiController.AddEventHandlersTemplatesCatalog(catalogInformerFactory)
+		catalogInformerFactory.Start(ctx.Done())
+	}
 }
 
 // runClickHouse is an entry point of the application
@@ -0,0 +1,58 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"flag"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+)
+
+// HTTP API defaults
+const (
+	defaultAPIEndpoint = ":9443"
+)
+
+// CLI parameter variables
+var (
+	// apiEP defines HTTP API end-point IP address
+	apiEP string
+	// apiTLSCertFile and apiTLSKeyFile define TLS certificate and key HTTP API is served with
+	apiTLSCertFile string
+	apiTLSKeyFile  string
+)
+
+func init() {
+	flag.StringVar(&apiEP, "api-endpoint", defaultAPIEndpoint, "The HTTP API endpoint.")
+	flag.StringVar(&apiTLSCertFile, "api-tls-cert-file", "", "Path to TLS certificate HTTP API is served with. HTTP API is disabled if not specified.")
+	flag.StringVar(&apiTLSKeyFile, "api-tls-key-file", "", "Path to TLS key HTTP API is served with. HTTP API is disabled if not specified.")
+}
+
+// runClickHouseAPI is an entry point of the application
+func runClickHouseAPI(ctx context.Context) {
+	log.S().P()
+	defer log.E().P()
+
+	if (apiTLSCertFile == "") || (apiTLSKeyFile == "") {
+		log.V(1).F().Info("HTTP API is disabled, no TLS certificate specified")
+		return
+	}
+
+	log.V(1).F().Info("Starting operator HTTP API")
+	if err := chiController.ServeAPI(ctx, apiEP, apiTLSCertFile, apiTLSKeyFile); err != nil {
+		log.V(1).F().Error("HTTP API FAILED err: %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlRuntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	//	ctrl "sigs.k8s.io/controller-runtime/pkg/controller"
@@ -54,21 +55,34 @@ func initKeeper(ctx context.Context) error {
 		return err
 	}
 
+	reconciler := &controller.ChkReconciler{
+		Client: manager.GetClient(),
+		Scheme: manager.GetScheme(),
+	}
+	// CHKs are requeued on edits of templates they use, as template edits do not bump generation of CHKs
 	err = ctrlRuntime.
 		NewControllerManagedBy(manager).
 		For(&api.ClickHouseKeeperInstallation{}).
 		Owns(&apps.StatefulSet{}).
-		Complete(
-			&controller.ChkReconciler{
-				Client: manager.GetClient(),
-				Scheme: manager.GetScheme(),
-			},
-		)
+		Watches(&api.ClickHouseKeeperInstallationTemplate{}, handler.EnqueueRequestsFromMapFunc(reconciler.MapTemplateToCHKs)).
+		Complete(reconciler)
 	if err != nil {
 		logger.Error(err, "init keeper - unable to ctrlRuntime.NewControllerManagedBy")
 		return err
 	}
 
+	if err = initBackup(); err != nil {
+		return err
+	}
+
+	if err = initUser(); err != nil {
+		return err
+	}
+
+	if err = initQuery(); err != nil {
+		return err
+	}
+
 	// Initialization successful
 	return nil
 }
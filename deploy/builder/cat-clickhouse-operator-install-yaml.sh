@@ -135,6 +135,46 @@ if [[ "${MANIFEST_PRINT_CRD}" == "yes" ]]; then
     SECTION_FILE_NAME="clickhouse-operator-install-yaml-template-01-section-crd-03-chk.yaml"
     ensure_file "${TEMPLATES_DIR}" "${SECTION_FILE_NAME}" "${REPO_PATH_TEMPLATES_PATH}"
     render_separator
+    cat "${TEMPLATES_DIR}/${SECTION_FILE_NAME}" | \
+        KIND="ClickHouseKeeperInstallation"       \
+        SINGULAR="clickhousekeeperinstallation"   \
+        PLURAL="clickhousekeeperinstallations"    \
+        SHORT="chk"                               \
+        OPERATOR_VERSION="${OPERATOR_VERSION}"    \
+        envsubst
+
+    # Render CHKIT
+    SECTION_FILE_NAME="clickhouse-operator-install-yaml-template-01-section-crd-03-chk.yaml"
+    ensure_file "${TEMPLATES_DIR}" "${SECTION_FILE_NAME}" "${REPO_PATH_TEMPLATES_PATH}"
+    render_separator
+    cat "${TEMPLATES_DIR}/${SECTION_FILE_NAME}" | \
+        KIND="ClickHouseKeeperInstallationTemplate"     \
+        SINGULAR="clickhousekeeperinstallationtemplate" \
+        PLURAL="clickhousekeeperinstallationtemplates"  \
+        SHORT="chkit"                                   \
+        OPERATOR_VERSION="${OPERATOR_VERSION}"          \
+        envsubst
+
+    # Render CHB
+    SECTION_FILE_NAME="clickhouse-operator-install-yaml-template-01-section-crd-04-chb.yaml"
+    ensure_file "${TEMPLATES_DIR}" "${SECTION_FILE_NAME}" "${REPO_PATH_TEMPLATES_PATH}"
+    render_separator
+    cat "${TEMPLATES_DIR}/${SECTION_FILE_NAME}" | \
+        OPERATOR_VERSION="${OPERATOR_VERSION}"    \
+        envsubst
+
+    # Render CHU
+    SECTION_FILE_NAME="clickhouse-operator-install-yaml-template-01-section-crd-05-chu.yaml"
+    ensure_file "${TEMPLATES_DIR}" "${SECTION_FILE_NAME}" "${REPO_PATH_TEMPLATES_PATH}"
+    render_separator
+    cat "${TEMPLATES_DIR}/${SECTION_FILE_NAME}" | \
+        OPERATOR_VERSION="${OPERATOR_VERSION}"    \
+        envsubst
+
+    # Render CHQ
+    SECTION_FILE_NAME="clickhouse-operator-install-yaml-template-01-section-crd-06-chq.yaml"
+    ensure_file "${TEMPLATES_DIR}" "${SECTION_FILE_NAME}" "${REPO_PATH_TEMPLATES_PATH}"
+    render_separator
     cat "${TEMPLATES_DIR}/${SECTION_FILE_NAME}" | \
         OPERATOR_VERSION="${OPERATOR_VERSION}"    \
         envsubst
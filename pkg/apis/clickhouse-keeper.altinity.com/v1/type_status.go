@@ -33,9 +33,14 @@ type ChkStatus struct {
 	// ReadyReplicas is the number of number of ready replicas in the cluster
 	ReadyReplicas []apiChi.ChiZookeeperNode `json:"readyReplicas,omitempty"`
 
+	// Ready specifies ready replicas out of desired replicas, such as 2/3
+	Ready string `json:"ready,omitempty"                  yaml:"ready,omitempty"`
+
 	Pods                   []string                      `json:"pods,omitempty"                   yaml:"pods,omitempty"`
 	PodIPs                 []string                      `json:"pod-ips,omitempty"                yaml:"pod-ips,omitempty"`
 	FQDNs                  []string                      `json:"fqdns,omitempty"                  yaml:"fqdns,omitempty"`
+	UsedTemplates          []*apiChi.TemplateRef         `json:"usedTemplates,omitempty"          yaml:"usedTemplates,omitempty"`
+	TemplatesChecksum      string                        `json:"templatesChecksum,omitempty"      yaml:"templatesChecksum,omitempty"`
 	NormalizedCHK          *ClickHouseKeeperInstallation `json:"normalized,omitempty"             yaml:"normalized,omitempty"`
 	NormalizedCHKCompleted *ClickHouseKeeperInstallation `json:"normalizedCompleted,omitempty"    yaml:"normalizedCompleted,omitempty"`
 }
@@ -57,9 +62,12 @@ func (s *ChkStatus) CopyFrom(from *ChkStatus, opts apiChi.CopyCHIStatusOptions)
 		s.Status = from.Status
 		s.Replicas = from.Replicas
 		s.ReadyReplicas = from.ReadyReplicas
+		s.Ready = from.Ready
 		s.Pods = from.Pods
 		s.PodIPs = from.PodIPs
 		s.FQDNs = from.FQDNs
+		s.UsedTemplates = from.UsedTemplates
+		s.TemplatesChecksum = from.TemplatesChecksum
 		s.NormalizedCHK = from.NormalizedCHK
 	}
 
@@ -75,14 +83,41 @@ func (s *ChkStatus) CopyFrom(from *ChkStatus, opts apiChi.CopyCHIStatusOptions)
 		s.Status = from.Status
 		s.Replicas = from.Replicas
 		s.ReadyReplicas = from.ReadyReplicas
+		s.Ready = from.Ready
 		s.Pods = from.Pods
 		s.PodIPs = from.PodIPs
 		s.FQDNs = from.FQDNs
+		s.UsedTemplates = from.UsedTemplates
+		s.TemplatesChecksum = from.TemplatesChecksum
 		s.NormalizedCHK = from.NormalizedCHK
 		s.NormalizedCHKCompleted = from.NormalizedCHKCompleted
 	}
 }
 
+// PushUsedTemplate pushes used template to the list of used templates
+func (s *ChkStatus) PushUsedTemplate(templateRef *apiChi.TemplateRef) {
+	if s == nil {
+		return
+	}
+	s.UsedTemplates = append(s.UsedTemplates, templateRef)
+}
+
+// GetUsedTemplates gets list of used templates
+func (s *ChkStatus) GetUsedTemplates() []*apiChi.TemplateRef {
+	if s == nil {
+		return nil
+	}
+	return s.UsedTemplates
+}
+
+// GetTemplatesChecksum gets checksum of templates applied to the CHK
+func (s *ChkStatus) GetTemplatesChecksum() string {
+	if s == nil {
+		return ""
+	}
+	return s.TemplatesChecksum
+}
+
 // HasNormalizedCHKCompleted is a checker
 func (s *ChkStatus) HasNormalizedCHKCompleted() bool {
 	return s.GetNormalizedCHKCompleted() != nil
@@ -38,8 +38,15 @@ type ClickHouseKeeperInstallation struct {
 	Runtime ClickHouseKeeperInstallationRuntime `json:"-" yaml:"-"`
 }
 
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseKeeperInstallationTemplate defines ClickHouseKeeperInstallation template
+type ClickHouseKeeperInstallationTemplate ClickHouseKeeperInstallation
+
 type ClickHouseKeeperInstallationRuntime struct {
 	statusCreatorMutex sync.Mutex `json:"-" yaml:"-"`
+	// MemberZones specifies zones where keeper members are located, indexed by member (server) id
+	MemberZones map[int]string `json:"-" yaml:"-"`
 }
 
 // EnsureStatus ensures status
@@ -157,10 +164,31 @@ func (chk *ClickHouseKeeperInstallation) MergeFrom(from *ClickHouseKeeperInstall
 	})
 }
 
+// MatchFullName matches full name
+func (chk *ClickHouseKeeperInstallation) MatchFullName(namespace, name string) bool {
+	if chk == nil {
+		return false
+	}
+	return (chk.Namespace == namespace) && (chk.Name == name)
+}
+
+// IsAuto checks whether templating policy is auto
+func (chk *ClickHouseKeeperInstallation) IsAuto() bool {
+	if chk == nil {
+		return false
+	}
+	if (chk.Namespace == "") && (chk.Name == "") {
+		return false
+	}
+	return chk.Spec.Templating.GetPolicy() == apiChi.TemplatingPolicyAuto
+}
+
 // ChkSpec defines spec section of ClickHouseKeeper resource
 type ChkSpec struct {
-	Configuration *ChkConfiguration `json:"configuration,omitempty"          yaml:"configuration,omitempty"`
-	Templates     *apiChi.Templates `json:"templates,omitempty"              yaml:"templates,omitempty"`
+	Templating    *apiChi.ChiTemplating `json:"templating,omitempty"             yaml:"templating,omitempty"`
+	Configuration *ChkConfiguration     `json:"configuration,omitempty"          yaml:"configuration,omitempty"`
+	Templates     *apiChi.Templates     `json:"templates,omitempty"              yaml:"templates,omitempty"`
+	UseTemplates  []*apiChi.TemplateRef `json:"useTemplates,omitempty"           yaml:"useTemplates,omitempty"`
 }
 
 func (spec ChkSpec) GetConfiguration() *ChkConfiguration {
@@ -184,8 +212,11 @@ func (spec *ChkSpec) MergeFrom(from *ChkSpec, _type apiChi.MergeType) {
 		return
 	}
 
+	spec.Templating = spec.Templating.MergeFrom(from.Templating, _type)
 	spec.Configuration = spec.Configuration.MergeFrom(from.Configuration, _type)
 	spec.Templates = spec.Templates.MergeFrom(from.Templates, _type)
+	// TODO may be it would be wiser to make more intelligent merge
+	spec.UseTemplates = append(spec.UseTemplates, from.UseTemplates...)
 }
 
 // ChkConfiguration defines configuration section of .spec
@@ -239,16 +270,19 @@ func (configuration *ChkConfiguration) MergeFrom(from *ChkConfiguration, _type a
 	configuration.Settings = configuration.Settings.MergeFrom(from.Settings)
 
 	// TODO merge clusters
-	// Copy Clusters for now
-	configuration.Clusters = from.Clusters
+	// Copy Clusters for now, in case any specified, so clusters inherited from a template are not wiped out
+	if len(from.Clusters) > 0 {
+		configuration.Clusters = from.Clusters
+	}
 
 	return configuration
 }
 
 // ChkCluster defines item of a clusters section of .configuration
 type ChkCluster struct {
-	Name   string            `json:"name,omitempty"         yaml:"name,omitempty"`
-	Layout *ChkClusterLayout `json:"layout,omitempty"       yaml:"layout,omitempty"`
+	Name          string            `json:"name,omitempty"          yaml:"name,omitempty"`
+	Layout        *ChkClusterLayout `json:"layout,omitempty"        yaml:"layout,omitempty"`
+	ZoneAwareness *ChkZoneAwareness `json:"zoneAwareness,omitempty" yaml:"zoneAwareness,omitempty"`
 }
 
 func (c *ChkCluster) GetLayout() *ChkClusterLayout {
@@ -258,6 +292,71 @@ func (c *ChkCluster) GetLayout() *ChkClusterLayout {
 	return c.Layout
 }
 
+func (c *ChkCluster) GetZoneAwareness() *ChkZoneAwareness {
+	if c == nil {
+		return nil
+	}
+	return c.ZoneAwareness
+}
+
+// ChkZoneAwareness defines how keeper members are spread across zones
+// and which zones are preferred to host raft leader
+type ChkZoneAwareness struct {
+	// TopologyKey specifies node label which identifies zone of a node
+	TopologyKey string `json:"topologyKey,omitempty" yaml:"topologyKey,omitempty"`
+	// MaxSkew specifies max allowed difference in members number between zones
+	MaxSkew int32 `json:"maxSkew,omitempty"     yaml:"maxSkew,omitempty"`
+	// Zones specifies raft priorities of members located in the zones
+	Zones []ChkZone `json:"zones,omitempty"       yaml:"zones,omitempty"`
+}
+
+// ChkZone defines raft priority of members located in the zone
+type ChkZone struct {
+	Name string `json:"name,omitempty"     yaml:"name,omitempty"`
+	// Priority specifies raft priority of members located in the zone.
+	// Member with higher priority is preferred to become a leader, priority 0 means member never becomes a leader.
+	Priority int `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+// NewChkZoneAwareness creates new zone awareness
+func NewChkZoneAwareness() *ChkZoneAwareness {
+	return new(ChkZoneAwareness)
+}
+
+// IsEnabled checks whether zone awareness is enabled
+func (z *ChkZoneAwareness) IsEnabled() bool {
+	return z != nil
+}
+
+// GetTopologyKey gets topology key
+func (z *ChkZoneAwareness) GetTopologyKey() string {
+	if z == nil {
+		return ""
+	}
+	return z.TopologyKey
+}
+
+// GetMaxSkew gets max skew
+func (z *ChkZoneAwareness) GetMaxSkew() int32 {
+	if z == nil {
+		return 0
+	}
+	return z.MaxSkew
+}
+
+// GetZonePriority gets raft priority of members located in specified zone
+func (z *ChkZoneAwareness) GetZonePriority(zone string) (int, bool) {
+	if z == nil {
+		return 0, false
+	}
+	for _, _zone := range z.Zones {
+		if _zone.Name == zone {
+			return _zone.Priority, true
+		}
+	}
+	return 0, false
+}
+
 // ChkClusterLayout defines layout section of .spec.configuration.clusters
 type ChkClusterLayout struct {
 	// The valid range of size is from 1 to 7.
@@ -319,3 +418,12 @@ type ClickHouseKeeperInstallationList struct {
 	meta.ListMeta `json:"metadata" yaml:"metadata"`
 	Items         []ClickHouseKeeperInstallation `json:"items" yaml:"items"`
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseKeeperInstallationTemplateList defines a list of ClickHouseKeeperInstallationTemplate resources
+type ClickHouseKeeperInstallationTemplateList struct {
+	meta.TypeMeta `json:",inline"  yaml:",inline"`
+	meta.ListMeta `json:"metadata" yaml:"metadata"`
+	Items         []ClickHouseKeeperInstallationTemplate `json:"items" yaml:"items"`
+}
@@ -34,6 +34,11 @@ func (in *ChkCluster) DeepCopyInto(out *ChkCluster) {
 		*out = new(ChkClusterLayout)
 		**out = **in
 	}
+	if in.ZoneAwareness != nil {
+		in, out := &in.ZoneAwareness, &out.ZoneAwareness
+		*out = new(ChkZoneAwareness)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -98,6 +103,11 @@ func (in *ChkConfiguration) DeepCopy() *ChkConfiguration {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChkSpec) DeepCopyInto(out *ChkSpec) {
 	*out = *in
+	if in.Templating != nil {
+		in, out := &in.Templating, &out.Templating
+		*out = new(clickhousealtinitycomv1.ChiTemplating)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Configuration != nil {
 		in, out := &in.Configuration, &out.Configuration
 		*out = new(ChkConfiguration)
@@ -108,6 +118,17 @@ func (in *ChkSpec) DeepCopyInto(out *ChkSpec) {
 		*out = new(clickhousealtinitycomv1.Templates)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.UseTemplates != nil {
+		in, out := &in.UseTemplates, &out.UseTemplates
+		*out = make([]*clickhousealtinitycomv1.TemplateRef, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(clickhousealtinitycomv1.TemplateRef)
+				**out = **in
+			}
+		}
+	}
 	return
 }
 
@@ -146,6 +167,17 @@ func (in *ChkStatus) DeepCopyInto(out *ChkStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UsedTemplates != nil {
+		in, out := &in.UsedTemplates, &out.UsedTemplates
+		*out = make([]*clickhousealtinitycomv1.TemplateRef, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(clickhousealtinitycomv1.TemplateRef)
+				**out = **in
+			}
+		}
+	}
 	if in.NormalizedCHK != nil {
 		in, out := &in.NormalizedCHK, &out.NormalizedCHK
 		*out = new(ClickHouseKeeperInstallation)
@@ -169,6 +201,43 @@ func (in *ChkStatus) DeepCopy() *ChkStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChkZone) DeepCopyInto(out *ChkZone) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChkZone.
+func (in *ChkZone) DeepCopy() *ChkZone {
+	if in == nil {
+		return nil
+	}
+	out := new(ChkZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChkZoneAwareness) DeepCopyInto(out *ChkZoneAwareness) {
+	*out = *in
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]ChkZone, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChkZoneAwareness.
+func (in *ChkZoneAwareness) DeepCopy() *ChkZoneAwareness {
+	if in == nil {
+		return nil
+	}
+	out := new(ChkZoneAwareness)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClickHouseKeeperInstallation) DeepCopyInto(out *ClickHouseKeeperInstallation) {
 	*out = *in
@@ -180,7 +249,7 @@ func (in *ClickHouseKeeperInstallation) DeepCopyInto(out *ClickHouseKeeperInstal
 		*out = new(ChkStatus)
 		(*in).DeepCopyInto(*out)
 	}
-	out.Runtime = in.Runtime
+	in.Runtime.DeepCopyInto(&out.Runtime)
 	return
 }
 
@@ -239,6 +308,13 @@ func (in *ClickHouseKeeperInstallationList) DeepCopyObject() runtime.Object {
 func (in *ClickHouseKeeperInstallationRuntime) DeepCopyInto(out *ClickHouseKeeperInstallationRuntime) {
 	*out = *in
 	out.statusCreatorMutex = in.statusCreatorMutex
+	if in.MemberZones != nil {
+		in, out := &in.MemberZones, &out.MemberZones
+		*out = make(map[int]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -251,3 +327,69 @@ func (in *ClickHouseKeeperInstallationRuntime) DeepCopy() *ClickHouseKeeperInsta
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickHouseKeeperInstallationTemplate) DeepCopyInto(out *ClickHouseKeeperInstallationTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(ChkStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Runtime.DeepCopyInto(&out.Runtime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseKeeperInstallationTemplate.
+func (in *ClickHouseKeeperInstallationTemplate) DeepCopy() *ClickHouseKeeperInstallationTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickHouseKeeperInstallationTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseKeeperInstallationTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickHouseKeeperInstallationTemplateList) DeepCopyInto(out *ClickHouseKeeperInstallationTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClickHouseKeeperInstallationTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseKeeperInstallationTemplateList.
+func (in *ClickHouseKeeperInstallationTemplateList) DeepCopy() *ClickHouseKeeperInstallationTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickHouseKeeperInstallationTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseKeeperInstallationTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Operations available for ClickHouseBackup
+const (
+	BackupOperationBackup  = "backup"
+	BackupOperationRestore = "restore"
+)
+
+// Statuses of ClickHouseBackup
+const (
+	BackupStatusPending    = "Pending"
+	BackupStatusInProgress = "InProgress"
+	BackupStatusCompleted  = "Completed"
+	BackupStatusFailed     = "Failed"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseBackup defines backup or restore of a ClickHouseInstallation
+type ClickHouseBackup struct {
+	meta.TypeMeta   `json:",inline"            yaml:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	Spec   ChbSpec    `json:"spec"             yaml:"spec"`
+	Status *ChbStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseBackupList defines a list of ClickHouseBackup resources
+type ClickHouseBackupList struct {
+	meta.TypeMeta `json:",inline"  yaml:",inline"`
+	meta.ListMeta `json:"metadata" yaml:"metadata"`
+	Items         []ClickHouseBackup `json:"items" yaml:"items"`
+}
+
+// ChbSpec defines spec section of ClickHouseBackup resource
+type ChbSpec struct {
+	// CHI specifies name of the ClickHouseInstallation to be backed up or restored, located in the same namespace
+	CHI string `json:"chi"                   yaml:"chi"`
+	// Cluster specifies cluster of the CHI to be backed up or restored. All clusters are processed in case not specified
+	Cluster string `json:"cluster,omitempty"     yaml:"cluster,omitempty"`
+	// Operation specifies what to do, either backup or restore
+	Operation string `json:"operation,omitempty"   yaml:"operation,omitempty"`
+	// BackupName specifies name of the backup within destination. Defaults to the ClickHouseBackup name.
+	// Restore refers to the name of the backup made earlier
+	BackupName string `json:"backupName,omitempty"  yaml:"backupName,omitempty"`
+	// Databases specifies databases to be backed up or restored. All databases except system ones are processed in case not specified
+	Databases   []string        `json:"databases,omitempty"   yaml:"databases,omitempty"`
+	Destination *ChbDestination `json:"destination,omitempty" yaml:"destination,omitempty"`
+}
+
+// ChbDestination defines where backups are stored
+type ChbDestination struct {
+	S3  *ChbObjectStorage `json:"s3,omitempty"  yaml:"s3,omitempty"`
+	GCS *ChbObjectStorage `json:"gcs,omitempty" yaml:"gcs,omitempty"`
+}
+
+// ChbObjectStorage defines S3-compatible object storage
+type ChbObjectStorage struct {
+	// Endpoint specifies URL of the bucket and path inside it, such as https://bucket.s3.amazonaws.com/backups
+	Endpoint string `json:"endpoint"                  yaml:"endpoint"`
+	// AccessKeyID and SecretAccessKey refer to a Secret with credentials.
+	// ClickHouse server credentials, such as IAM role, are used in case not specified
+	AccessKeyID     *SettingSource `json:"accessKeyId,omitempty"     yaml:"accessKeyId,omitempty"`
+	SecretAccessKey *SettingSource `json:"secretAccessKey,omitempty" yaml:"secretAccessKey,omitempty"`
+}
+
+// ChbStatus defines status section of ClickHouseBackup resource
+type ChbStatus struct {
+	Status    string `json:"status,omitempty"    yaml:"status,omitempty"`
+	Error     string `json:"error,omitempty"     yaml:"error,omitempty"`
+	StartTime string `json:"startTime,omitempty" yaml:"startTime,omitempty"`
+	EndTime   string `json:"endTime,omitempty"   yaml:"endTime,omitempty"`
+	// Hosts lists operations started, one per each cluster
+	Hosts []ChbHostStatus `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+}
+
+// ChbHostStatus defines status of the operation run by the host
+type ChbHostStatus struct {
+	Cluster string `json:"cluster"         yaml:"cluster"`
+	Host    string `json:"host"            yaml:"host"`
+	// ID specifies id of the operation in system.backups table of the host
+	ID          string `json:"id"              yaml:"id"`
+	Destination string `json:"destination"     yaml:"destination"`
+	// Status specifies status of the operation as reported by ClickHouse, such as BACKUP_CREATED
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	Error  string `json:"error,omitempty"  yaml:"error,omitempty"`
+}
+
+// GetOperation gets operation, backup by default
+func (spec *ChbSpec) GetOperation() string {
+	if spec == nil {
+		return BackupOperationBackup
+	}
+	switch spec.Operation {
+	case BackupOperationRestore:
+		return BackupOperationRestore
+	}
+	return BackupOperationBackup
+}
+
+// GetObjectStorage gets object storage backups are stored in
+func (d *ChbDestination) GetObjectStorage() *ChbObjectStorage {
+	if d == nil {
+		return nil
+	}
+	if d.S3 != nil {
+		return d.S3
+	}
+	return d.GCS
+}
+
+// EnsureStatus ensures status
+func (chb *ClickHouseBackup) EnsureStatus() *ChbStatus {
+	if chb == nil {
+		return nil
+	}
+	if chb.Status == nil {
+		chb.Status = &ChbStatus{}
+	}
+	return chb.Status
+}
+
+// GetStatus gets status
+func (chb *ClickHouseBackup) GetStatus() *ChbStatus {
+	if chb == nil {
+		return nil
+	}
+	return chb.Status
+}
+
+// GetBackupName gets name of the backup within destination
+func (chb *ClickHouseBackup) GetBackupName() string {
+	if chb == nil {
+		return ""
+	}
+	if chb.Spec.BackupName != "" {
+		return chb.Spec.BackupName
+	}
+	return chb.Name
+}
+
+// IsDone checks whether the operation is either completed or failed, so nothing is left to do
+func (s *ChbStatus) IsDone() bool {
+	if s == nil {
+		return false
+	}
+	switch s.Status {
+	case BackupStatusCompleted, BackupStatusFailed:
+		return true
+	}
+	return false
+}
@@ -424,6 +424,11 @@ func (spec *ChiSpec) MergeFrom(from *ChiSpec, _type MergeType) {
 	spec.Defaults = spec.Defaults.MergeFrom(from.Defaults, _type)
 	spec.Configuration = spec.Configuration.MergeFrom(from.Configuration, _type)
 	spec.Templates = spec.Templates.MergeFrom(from.Templates, _type)
+	spec.Gateway = spec.Gateway.MergeFrom(from.Gateway, _type)
+	spec.DetachedParts = spec.DetachedParts.MergeFrom(from.DetachedParts, _type)
+	spec.Upgrade = spec.Upgrade.MergeFrom(from.Upgrade, _type)
+	spec.Monitoring = spec.Monitoring.MergeFrom(from.Monitoring, _type)
+	spec.DDLQueue = spec.DDLQueue.MergeFrom(from.DDLQueue, _type)
 	// TODO may be it would be wiser to make more intelligent merge
 	spec.UseTemplates = append(spec.UseTemplates, from.UseTemplates...)
 }
@@ -556,6 +561,14 @@ func (chi *ClickHouseInstallation) GetCHIServiceTemplate() (*ServiceTemplate, bo
 	return chi.GetServiceTemplate(name)
 }
 
+// GetPodDisruptionBudgetTemplate gets PodDisruptionBudgetTemplate by name
+func (chi *ClickHouseInstallation) GetPodDisruptionBudgetTemplate(name string) (*PodDisruptionBudgetTemplate, bool) {
+	if !chi.Spec.Templates.GetPodDisruptionBudgetTemplatesIndex().Has(name) {
+		return nil, false
+	}
+	return chi.Spec.Templates.GetPodDisruptionBudgetTemplatesIndex().Get(name), true
+}
+
 // MatchNamespace matches namespace
 func (chi *ClickHouseInstallation) MatchNamespace(namespace string) bool {
 	if chi == nil {
@@ -612,6 +625,18 @@ func (chi *ClickHouseInstallation) IsStopped() bool {
 	return chi.Spec.Stop.Value()
 }
 
+// IsUsersStoredInSecret checks whether users config files are delivered in Secret instead of ConfigMap
+func (chi *ClickHouseInstallation) IsUsersStoredInSecret() bool {
+	if chi == nil {
+		return false
+	}
+	if chi.Spec.Defaults.IsConfigStoredInSecret() {
+		// All config files are delivered in Secrets
+		return true
+	}
+	return chi.EnsureRuntime().GetAttributes().UsersStoredInSecret
+}
+
 // Restart constants present available values for .spec.restart
 // Controlling the operator's Clickhouse instances restart policy
 const (
@@ -644,6 +669,30 @@ func (chi *ClickHouseInstallation) GetReconciling() *ChiReconciling {
 	return chi.Spec.Reconciling
 }
 
+// GetDetachedParts gets detached parts spec
+func (chi *ClickHouseInstallation) GetDetachedParts() *ChiDetachedParts {
+	if chi == nil {
+		return nil
+	}
+	return chi.Spec.DetachedParts
+}
+
+// GetDDLQueue gets distributed DDL queue spec
+func (chi *ClickHouseInstallation) GetDDLQueue() *ChiDDLQueue {
+	if chi == nil {
+		return nil
+	}
+	return chi.Spec.DDLQueue
+}
+
+// GetUpgrade gets upgrade spec
+func (chi *ClickHouseInstallation) GetUpgrade() *ChiUpgrade {
+	if chi == nil {
+		return nil
+	}
+	return chi.Spec.Upgrade
+}
+
 // CopyCHIOptions specifies options for CHI copier
 type CopyCHIOptions struct {
 	// SkipStatus specifies whether to copy status
@@ -14,18 +14,41 @@
 
 package v1
 
+import (
+	"strconv"
+	"strings"
+)
+
+// ClusterSecureAuto specifies value of cluster-level secure, which, in addition to bool values,
+// makes hosts communicate securely with TLS certificates generated by the operator
+const ClusterSecureAuto = "auto"
+
 // Cluster defines item of a clusters section of .configuration
 type Cluster struct {
 	Name         string              `json:"name,omitempty"         yaml:"name,omitempty"`
 	Zookeeper    *ChiZookeeperConfig `json:"zookeeper,omitempty"    yaml:"zookeeper,omitempty"`
 	Settings     *Settings           `json:"settings,omitempty"     yaml:"settings,omitempty"`
 	Files        *Settings           `json:"files,omitempty"        yaml:"files,omitempty"`
+	Streaming    *ChiStreaming       `json:"streaming,omitempty"    yaml:"streaming,omitempty"`
 	Templates    *ChiTemplateNames   `json:"templates,omitempty"    yaml:"templates,omitempty"`
 	SchemaPolicy *SchemaPolicy       `json:"schemaPolicy,omitempty" yaml:"schemaPolicy,omitempty"`
 	Insecure     *StringBool         `json:"insecure,omitempty"     yaml:"insecure,omitempty"`
 	Secure       *StringBool         `json:"secure,omitempty"       yaml:"secure,omitempty"`
 	Secret       *ClusterSecret      `json:"secret,omitempty"       yaml:"secret,omitempty"`
 	Layout       *ChiClusterLayout   `json:"layout,omitempty"       yaml:"layout,omitempty"`
+	// ServiceType specifies type of cluster-level Service created w/o ServiceTemplate.
+	// No cluster-level Service is created in case neither ServiceType nor ServiceTemplate is specified
+	ServiceType string `json:"serviceType,omitempty" yaml:"serviceType,omitempty"`
+	// ClickHouseVersion specifies ClickHouse version hosts of the cluster run, such as 24.3.
+	// Version is resolved into image of ClickHouse container according to operator config and overrides podTemplate image
+	ClickHouseVersion string `json:"clickhouseVersion,omitempty" yaml:"clickhouseVersion,omitempty"`
+	// Stop specifies whether hosts of the cluster are scaled down, while the rest of the CHI keeps running
+	Stop *StringBool `json:"stop,omitempty"   yaml:"stop,omitempty"`
+	// Cordon specifies whether the cluster is frozen - hosts of the cluster are taken out of Services
+	// and autogenerated clusters and are not reconciled, while the rest of the CHI keeps being reconciled
+	Cordon *StringBool `json:"cordon,omitempty" yaml:"cordon,omitempty"`
+	// DNS specifies custom DNS names hosts of the cluster are addressed by in remote_servers and host aliases
+	DNS *ChiClusterDNS `json:"dns,omitempty" yaml:"dns,omitempty"`
 
 	Runtime ClusterRuntime `json:"-" yaml:"-"`
 }
@@ -33,12 +56,22 @@ type Cluster struct {
 type ClusterRuntime struct {
 	Address ChiClusterAddress       `json:"-" yaml:"-"`
 	CHI     *ClickHouseInstallation `json:"-" yaml:"-" testdiff:"ignore"`
+	// SecretVersion specifies version of the cluster secret slots values, which are passed to pods via ENV vars
+	SecretVersion string `json:"-" yaml:"-"`
+	// SecretSlot specifies slot of the cluster secret used by hosts
+	SecretSlot string `json:"-" yaml:"-"`
+	// SecretSlotNext specifies slot carrying the new cluster secret, which hosts are to be switched to
+	SecretSlotNext string `json:"-" yaml:"-"`
+	// TLSCAChecksum specifies checksum of the bundle of CA certificates trusted by hosts of the 'secure: auto' cluster
+	TLSCAChecksum string `json:"-" yaml:"-"`
 }
 
 // SchemaPolicy defines schema management policy - replica or shard-based
 type SchemaPolicy struct {
 	Replica string `json:"replica" yaml:"replica"`
 	Shard   string `json:"shard"   yaml:"shard"`
+	// DryRun specifies to plan schema propagation to new hosts w/o executing it. Plan is reported in CHI status
+	DryRun *StringBool `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
 }
 
 // ChiClusterAddress defines address of a cluster within ClickHouseInstallation
@@ -49,6 +82,9 @@ type ChiClusterAddress struct {
 	ClusterIndex int    `json:"clusterIndex,omitempty" yaml:"clusterIndex,omitempty"`
 }
 
+// defaultInstancePortStep specifies default offset of ports of each next ClickHouse instance in the pod
+const defaultInstancePortStep = 10
+
 // ChiClusterLayout defines layout section of .spec.configuration.clusters
 type ChiClusterLayout struct {
 	// DEPRECATED - to be removed soon
@@ -58,6 +94,11 @@ type ChiClusterLayout struct {
 	// TODO refactor into map[string]ChiShard
 	Shards   []ChiShard   `json:"shards,omitempty"   yaml:"shards,omitempty"`
 	Replicas []ChiReplica `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// InstancesPerPod specifies number of ClickHouse instances to run in each pod of the cluster.
+	// Each instance serves a shard of its own, thus the cluster has ShardsCount * InstancesPerPod shards
+	InstancesPerPod int `json:"instancesPerPod,omitempty"  yaml:"instancesPerPod,omitempty"`
+	// InstancePortStep specifies how ports of each next instance in the pod are offset
+	InstancePortStep int `json:"instancePortStep,omitempty" yaml:"instancePortStep,omitempty"`
 
 	// Internal data
 	// Whether shards or replicas are explicitly specified as Shards []ChiShard or Replicas []ChiReplica
@@ -71,11 +112,46 @@ func NewClusterSchemaPolicy() *SchemaPolicy {
 	return new(SchemaPolicy)
 }
 
+// IsDryRun checks whether schema propagation is to be planned only
+func (p *SchemaPolicy) IsDryRun() bool {
+	if p == nil {
+		return false
+	}
+	return p.DryRun.Value()
+}
+
 // NewChiClusterLayout creates new cluster layout
 func NewChiClusterLayout() *ChiClusterLayout {
 	return new(ChiClusterLayout)
 }
 
+// GetInstancesPerPod gets number of ClickHouse instances to run in each pod
+func (l *ChiClusterLayout) GetInstancesPerPod() int {
+	if l == nil {
+		return 1
+	}
+	if l.InstancesPerPod < 1 {
+		return 1
+	}
+	return l.InstancesPerPod
+}
+
+// HasMultipleInstancesPerPod checks whether more than one ClickHouse instance runs in each pod
+func (l *ChiClusterLayout) HasMultipleInstancesPerPod() bool {
+	return l.GetInstancesPerPod() > 1
+}
+
+// GetInstancePortStep gets how ports of each next instance in the pod are offset
+func (l *ChiClusterLayout) GetInstancePortStep() int32 {
+	if l == nil {
+		return defaultInstancePortStep
+	}
+	if l.InstancePortStep < 1 {
+		return defaultInstancePortStep
+	}
+	return int32(l.InstancePortStep)
+}
+
 // FillShardReplicaSpecified fills whether shard or replicas are explicitly specified
 func (cluster *Cluster) FillShardReplicaSpecified() {
 	if len(cluster.Layout.Shards) > 0 {
@@ -163,11 +239,81 @@ func (cluster *Cluster) GetServiceTemplate() (*ServiceTemplate, bool) {
 	return cluster.Runtime.CHI.GetServiceTemplate(name)
 }
 
+// GetExtraServiceTemplates returns templates of extra Services of the cluster. Unknown templates are skipped
+func (cluster *Cluster) GetExtraServiceTemplates() []*ServiceTemplate {
+	var templates []*ServiceTemplate
+	for _, name := range cluster.Templates.GetClusterServiceTemplates() {
+		if template, ok := cluster.Runtime.CHI.GetServiceTemplate(name); ok {
+			templates = append(templates, template)
+		}
+	}
+	return templates
+}
+
+// GetPodDisruptionBudgetTemplate returns PodDisruptionBudget template, if exists
+func (cluster *Cluster) GetPodDisruptionBudgetTemplate() (*PodDisruptionBudgetTemplate, bool) {
+	if !cluster.Templates.HasPodDisruptionBudgetTemplate() {
+		return nil, false
+	}
+	name := cluster.Templates.GetPodDisruptionBudgetTemplate()
+	return cluster.Runtime.CHI.GetPodDisruptionBudgetTemplate(name)
+}
+
 // GetCHI gets parent CHI
 func (cluster *Cluster) GetCHI() *ClickHouseInstallation {
 	return cluster.Runtime.CHI
 }
 
+// IsStopped checks whether hosts of the cluster are stopped, either along with the whole CHI or on their own
+func (cluster *Cluster) IsStopped() bool {
+	if cluster == nil {
+		return false
+	}
+	return cluster.Stop.IsTrue() || cluster.GetCHI().IsStopped()
+}
+
+// IsCordoned checks whether the cluster is cordoned
+func (cluster *Cluster) IsCordoned() bool {
+	if cluster == nil {
+		return false
+	}
+	return cluster.Cordon.IsTrue()
+}
+
+// GetDNS gets custom DNS names specification of the cluster
+func (cluster *Cluster) GetDNS() *ChiClusterDNS {
+	if cluster == nil {
+		return nil
+	}
+	return cluster.DNS
+}
+
+// GetClickHouseVersion gets ClickHouse version the cluster is pinned to
+func (cluster *Cluster) GetClickHouseVersion() string {
+	if cluster == nil {
+		return ""
+	}
+	return cluster.ClickHouseVersion
+}
+
+// GetClickHouseMajorVersion gets major part of ClickHouse version the cluster is pinned to, such as 24 of 24.3
+func (cluster *Cluster) GetClickHouseMajorVersion() (int, bool) {
+	major, _, _ := strings.Cut(cluster.GetClickHouseVersion(), ".")
+	version, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// GetLayout gets layout of the cluster
+func (cluster *Cluster) GetLayout() *ChiClusterLayout {
+	if cluster == nil {
+		return nil
+	}
+	return cluster.Layout
+}
+
 // GetShard gets shard with specified index
 func (cluster *Cluster) GetShard(shard int) *ChiShard {
 	return &cluster.Layout.Shards[shard]
@@ -323,3 +469,8 @@ func (cluster *Cluster) GetSecure() *StringBool {
 	}
 	return cluster.Secure
 }
+
+// IsSecureAuto checks whether secure communication of the cluster relies on TLS certificates generated by the operator
+func (cluster *Cluster) IsSecureAuto() bool {
+	return strings.EqualFold(cluster.GetSecure().String(), ClusterSecureAuto)
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import "strings"
+
+// ChiClusterDNS defines custom DNS names hosts of the cluster are addressed by instead of in-cluster Service names,
+// such as names in a corporate DNS zone hosts are exposed via
+type ChiClusterDNS struct {
+	// Zone specifies DNS zone (or subdomain) host names are created in, such as replicas.example.com.
+	// Host is addressed as <host service name>.<zone> in remote_servers and host aliases
+	Zone string `json:"zone,omitempty"        yaml:"zone,omitempty"`
+	// ExternalDNS specifies whether host Services are annotated for ExternalDNS to publish host names in the zone
+	ExternalDNS *StringBool `json:"externalDNS,omitempty" yaml:"externalDNS,omitempty"`
+}
+
+// NewChiClusterDNS creates new ChiClusterDNS object
+func NewChiClusterDNS() *ChiClusterDNS {
+	return new(ChiClusterDNS)
+}
+
+// GetZone gets DNS zone w/o leading and trailing dots
+func (d *ChiClusterDNS) GetZone() string {
+	if d == nil {
+		return ""
+	}
+	return strings.Trim(d.Zone, ".")
+}
+
+// HasZone checks whether DNS zone is specified
+func (d *ChiClusterDNS) HasZone() bool {
+	return d.GetZone() != ""
+}
+
+// IsExternalDNS checks whether host Services are to be annotated for ExternalDNS
+func (d *ChiClusterDNS) IsExternalDNS() bool {
+	if !d.HasZone() {
+		return false
+	}
+	return d.ExternalDNS.IsTrue()
+}
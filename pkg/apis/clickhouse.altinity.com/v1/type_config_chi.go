@@ -14,6 +14,10 @@
 
 package v1
 
+import (
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
 const (
 	// CommonConfigDir specifies folder's name, where generated common XML files for ClickHouse would be placed
 	CommonConfigDir = "config.d"
@@ -36,6 +40,18 @@ type Configuration struct {
 	Quotas    *Settings           `json:"quotas,omitempty"    yaml:"quotas,omitempty"`
 	Settings  *Settings           `json:"settings,omitempty"  yaml:"settings,omitempty"`
 	Files     *Settings           `json:"files,omitempty"     yaml:"files,omitempty"`
+	// Fragments specifies named config fragments, defined once and referenced from settings of multiple sections.
+	// Fragments are rendered into include_from file and are referenced by ClickHouse XML includes
+	Fragments *Settings     `json:"fragments,omitempty" yaml:"fragments,omitempty"`
+	Security  *ChiSecurity  `json:"security,omitempty"  yaml:"security,omitempty"`
+	Functions *ChiFunctions `json:"functions,omitempty" yaml:"functions,omitempty"`
+	Logger    *ChiLogger    `json:"logger,omitempty"    yaml:"logger,omitempty"`
+	Streaming *ChiStreaming `json:"streaming,omitempty" yaml:"streaming,omitempty"`
+	// LogTables specifies destinations of system log tables, such as query_log, by table name
+	LogTables ChiLogTables `json:"logTables,omitempty" yaml:"logTables,omitempty"`
+	// ExcludedPaths specifies config paths, such as 'settings/logger/*' or 'users/default/networks',
+	// which operator must not render, because they are managed by the user out-of-band
+	ExcludedPaths []string `json:"excludedPaths,omitempty" yaml:"excludedPaths,omitempty"`
 	// TODO refactor into map[string]ChiCluster
 	Clusters []*Cluster `json:"clusters,omitempty"  yaml:"clusters,omitempty"`
 }
@@ -61,6 +77,13 @@ func (configuration *Configuration) MergeFrom(from *Configuration, _type MergeTy
 	configuration.Quotas = configuration.Quotas.MergeFrom(from.Quotas)
 	configuration.Settings = configuration.Settings.MergeFrom(from.Settings)
 	configuration.Files = configuration.Files.MergeFrom(from.Files)
+	configuration.Fragments = configuration.Fragments.MergeFrom(from.Fragments)
+	configuration.Security = configuration.Security.MergeFrom(from.Security, _type)
+	configuration.Functions = configuration.Functions.MergeFrom(from.Functions, _type)
+	configuration.Logger = configuration.Logger.MergeFrom(from.Logger, _type)
+	configuration.Streaming = configuration.Streaming.MergeFrom(from.Streaming, _type)
+	configuration.LogTables = configuration.LogTables.MergeFrom(from.LogTables, _type)
+	configuration.ExcludedPaths = util.MergeStringArrays(configuration.ExcludedPaths, from.ExcludedPaths)
 
 	// TODO merge clusters
 	// Copy Clusters for now
@@ -68,3 +91,33 @@ func (configuration *Configuration) MergeFrom(from *Configuration, _type MergeTy
 
 	return configuration
 }
+
+// GetFragments gets named config fragments
+func (configuration *Configuration) GetFragments() *Settings {
+	if configuration == nil {
+		return nil
+	}
+	return configuration.Fragments
+}
+
+// HasFragment checks whether named config fragment is defined
+func (configuration *Configuration) HasFragment(name string) bool {
+	fragments := configuration.GetFragments()
+	if fragments.Has(name) {
+		return true
+	}
+	for _, group := range fragments.Groups() {
+		if group == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetExcludedPaths gets config paths operator must not render
+func (configuration *Configuration) GetExcludedPaths() []string {
+	if configuration == nil {
+		return nil
+	}
+	return configuration.ExcludedPaths
+}
@@ -28,7 +28,9 @@ import (
 	log "github.com/golang/glog"
 	"github.com/imdario/mergo"
 	"gopkg.in/yaml.v3"
+	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/altinity/clickhouse-operator/pkg/apis/deployment"
 	"github.com/altinity/clickhouse-operator/pkg/util"
@@ -39,6 +41,9 @@ const (
 	defaultStatefulSetUpdateTimeout      = 300
 	defaultStatefulSetUpdatePollInterval = 15
 
+	// Default value for informers resync period in seconds
+	defaultInformerResyncPeriod = 60
+
 	// Default values for ClickHouse user configuration
 	// 1. user/profile
 	// 2. user/quota
@@ -58,6 +63,29 @@ const (
 	// ChSchemeAuto specifies that operator has to decide itself should https or http be used
 	ChSchemeAuto = "auto"
 
+	// Possible values for ClickHouse driver
+
+	// ChDriverHTTP specifies operator to connect to ClickHouse instances via HTTP(S) interface
+	ChDriverHTTP = "http"
+	// ChDriverNative specifies operator to connect to ClickHouse instances via native TCP protocol
+	ChDriverNative = "native"
+
+	// Possible values for ClickHouse native protocol compression
+
+	// ChCompressionNone specifies no compression
+	ChCompressionNone = "none"
+	// ChCompressionLZ4 specifies LZ4 compression
+	ChCompressionLZ4 = "lz4"
+	// ChCompressionZSTD specifies ZSTD compression
+	ChCompressionZSTD = "zstd"
+
+	// ChEndpointService specifies operator to connect to ClickHouse host via host's Service
+	ChEndpointService = "service"
+	// ChEndpointPodIP specifies operator to connect to ClickHouse host via IP address of host's Pod
+	ChEndpointPodIP = "podIP"
+	// ChEndpointPodFQDN specifies operator to connect to ClickHouse host via FQDN of host's Pod
+	ChEndpointPodFQDN = "podFQDN"
+
 	// Username and Password to be used by operator to connect to ClickHouse instances for
 	// 1. Metrics requests
 	// 2. Schema maintenance
@@ -68,6 +96,17 @@ const (
 	defaultChPort     = 8123
 	defaultChRootCA   = ""
 
+	// defaultChImageRepository specifies default repository ClickHouse versions are resolved into images of
+	defaultChImageRepository = "clickhouse/clickhouse-server"
+
+	// defaultChDriver specifies default driver to connect to ClickHouse instances with
+	defaultChDriver = ChDriverHTTP
+	// defaultChCompression specifies default compression of native protocol
+	defaultChCompression = ChCompressionLZ4
+	// defaultChNativePort and defaultChNativeTLSPort specify default ports of native protocol, plain and secure
+	defaultChNativePort    = 9000
+	defaultChNativeTLSPort = 9440
+
 	// Timeouts used to limit connection and queries from the operator to ClickHouse instances. In seconds
 	// defaultTimeoutConnect specifies default timeout to connect to the ClickHouse instance. In seconds
 	defaultTimeoutConnect = 2
@@ -75,6 +114,10 @@ const (
 	defaultTimeoutQuery = 5
 	// defaultTimeoutCollect specifies default timeout to collect metrics from the ClickHouse instance. In seconds
 	defaultTimeoutCollect = 8
+	// defaultSlowQueryThreshold specifies default duration SQL statement issued by the operator is reported as slow after. In seconds
+	defaultSlowQueryThreshold = 1
+	// defaultSlowQueriesTop specifies default number of the slowest statements of each CHI exposed via metrics
+	defaultSlowQueriesTop = 10
 
 	// defaultReconcileCHIsThreadsNumber specifies default number of controller threads running concurrently.
 	// Used in case no other specified in config
@@ -92,6 +135,14 @@ const (
 	// of shards in the cluster.
 	defaultReconcileShardsMaxConcurrencyPercent = 50
 
+	// defaultReconcileShutdownGracePeriod specifies default number of seconds the operator waits
+	// for in-flight host reconciles to complete on shutdown
+	defaultReconcileShutdownGracePeriod = 300
+
+	// defaultReconcileConcurrencyPolicy specifies default policy applied to the in-flight reconcile of the CHI
+	// in case the CHI is edited meanwhile
+	defaultReconcileConcurrencyPolicy = ReconcilingConcurrencyPolicyCancel
+
 	// DefaultReconcileThreadsWarmup specifies default reconcile threads warmup time
 	DefaultReconcileThreadsWarmup = 10 * time.Second
 
@@ -134,6 +185,16 @@ const (
 	OnStatefulSetUpdateFailureActionIgnore = "ignore"
 )
 
+const (
+	// What to do with existing StatefulSets on the first reconcile by a new operator version, in case CHI has no changes
+	// since the last completed reconcile - verify StatefulSets only, do not update them
+	OnOperatorRestartStatefulSetActionVerify = "verify"
+
+	// What to do with existing StatefulSets on the first reconcile by a new operator version, in case CHI has no changes
+	// since the last completed reconcile - update StatefulSets as usual
+	OnOperatorRestartStatefulSetActionUpdate = "update"
+)
+
 // OperatorConfig specifies operator configuration
 // !!! IMPORTANT !!!
 // !!! IMPORTANT !!!
@@ -163,6 +224,44 @@ type OperatorConfigRuntime struct {
 type OperatorConfigWatch struct {
 	// Namespaces where operator watches for events
 	Namespaces []string `json:"namespaces" yaml:"namespaces"`
+	// NamespaceSelector specifies label selector of namespaces where operator watches for events, such as "team=analytics".
+	// Namespaces matching the selector are watched along with namespaces listed explicitly.
+	// Namespaces created or labeled later are picked up at runtime
+	NamespaceSelector string `json:"namespaceSelector" yaml:"namespaceSelector"`
+	// Informers specifies tuning of informers caching watched objects
+	Informers OperatorConfigWatchInformers `json:"informers" yaml:"informers"`
+}
+
+// selectedNamespaces is a set of namespaces currently matching namespace selector.
+// The set is a runtime state, so it is kept out of OperatorConfig, which is copied and merged as a plain value
+var selectedNamespaces = struct {
+	sync.RWMutex
+	namespaces map[string]bool
+}{
+	namespaces: make(map[string]bool),
+}
+
+// OperatorConfigWatchInformers specifies informers section
+type OperatorConfigWatchInformers struct {
+	// Resync specifies periods of informers resync. In seconds
+	Resync struct {
+		// Kube specifies resync period of informers of k8s objects, such as pods, StatefulSets and Services
+		Kube int `json:"kube" yaml:"kube"`
+		// CHOp specifies resync period of informers of the operator's custom resources
+		CHOp int `json:"chop" yaml:"chop"`
+	} `json:"resync" yaml:"resync"`
+	// FilterManagedObjects specifies whether informers of k8s objects watch objects labeled as managed by the operator only
+	FilterManagedObjects *StringBool `json:"filterManagedObjects,omitempty" yaml:"filterManagedObjects,omitempty"`
+}
+
+// GetKubeResyncPeriod gets resync period of informers of k8s objects
+func (i OperatorConfigWatchInformers) GetKubeResyncPeriod() time.Duration {
+	return time.Duration(i.Resync.Kube) * time.Second
+}
+
+// GetCHOpResyncPeriod gets resync period of informers of the operator's custom resources
+func (i OperatorConfigWatchInformers) GetCHOpResyncPeriod() time.Duration {
+	return time.Duration(i.Resync.CHOp) * time.Second
 }
 
 // OperatorConfigConfig specifies Config section
@@ -174,6 +273,10 @@ type OperatorConfigConfig struct {
 	Network struct {
 		HostRegexpTemplate string `json:"hostRegexpTemplate" yaml:"hostRegexpTemplate"`
 	} `json:"network" yaml:"network"`
+
+	// ExcludedPaths specifies config paths operator must not render in any CHI,
+	// because they are managed by the user out-of-band. Extended by CHI-level '.spec.configuration.excludedPaths'
+	ExcludedPaths []string `json:"excludedPaths,omitempty" yaml:"excludedPaths,omitempty"`
 }
 
 // OperatorConfigRestartPolicyRuleSet specifies set of rules
@@ -213,6 +316,9 @@ type OperatorConfigFileRuntime struct {
 // OperatorConfigUser specifies User section
 type OperatorConfigUser struct {
 	Default OperatorConfigDefault `json:"default" yaml:"default"`
+	// StoreInSecret specifies whether users config files, which contain password hashes,
+	// are to be delivered in Secret instead of ConfigMap for all CHIs
+	StoreInSecret *StringBool `json:"storeInSecret,omitempty" yaml:"storeInSecret,omitempty"`
 }
 
 // OperatorConfigDefault specifies user-default section
@@ -228,6 +334,63 @@ type OperatorConfigDefault struct {
 	Password   string   `json:"password"   yaml:"password"`
 }
 
+// OperatorConfigClickHouseAccess specifies how the operator accesses ClickHouse instances
+type OperatorConfigClickHouseAccess struct {
+	// Username and Password to be used by operator to connect to ClickHouse instances
+	// for
+	// 1. Metrics requests
+	// 2. Schema maintenance
+	// User credentials can be specified in additional ClickHouse config files located in `chUsersConfigsPath` folder
+	Scheme   string `json:"scheme,omitempty"   yaml:"scheme,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	RootCA   string `json:"rootCA,omitempty"   yaml:"rootCA,omitempty"`
+
+	// Location of k8s Secret with username and password to be used by the operator to connect to ClickHouse instances
+	// Can be used instead of explicitly specified (above) username and password
+	Secret struct {
+		Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+		Name      string `json:"name,omitempty"      yaml:"name,omitempty"`
+
+		Runtime struct {
+			// Username and Password to be used by operator to connect to ClickHouse instances
+			// extracted from k8s secret specified above.
+			Username string
+			Password string
+			Fetched  bool
+			Error    string
+		}
+	} `json:"secret" yaml:"secret"`
+
+	// Port where to connect to ClickHouse instances to
+	Port int `json:"port" yaml:"port"`
+
+	// Driver specifies protocol to connect to ClickHouse instances via, either 'http' or 'native'.
+	// Native protocol is secured with TLS in case scheme is 'https'
+	Driver string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	// Compression specifies compression of native protocol, either 'none', 'lz4' or 'zstd'
+	Compression string `json:"compression,omitempty" yaml:"compression,omitempty"`
+
+	// Endpoints specifies how to address ClickHouse instances, in order of preference.
+	// Next endpoint is used in case previous one is not reachable.
+	Endpoints []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+
+	// Timeouts used to limit connection and queries from the operator to ClickHouse instances
+	Timeouts struct {
+		Connect time.Duration `json:"connect" yaml:"connect"`
+		Query   time.Duration `json:"query"   yaml:"query"`
+	} `json:"timeouts" yaml:"timeouts"`
+
+	// SlowQueries specifies how SQL statements issued by the operator to ClickHouse instances are reported as slow
+	SlowQueries struct {
+		// Threshold specifies how long statement runs for before being reported as slow.
+		// Zero disables slow statements reporting, default one is used in case not specified
+		Threshold *time.Duration `json:"threshold" yaml:"threshold"`
+		// Top specifies how many of the slowest statements of each CHI are exposed via metrics
+		Top int `json:"top" yaml:"top"`
+	} `json:"slowQueries" yaml:"slowQueries"`
+}
+
 // type RestartPolicy map[Matchable]StringBool
 
 // OperatorConfigClickHouse specifies ClickHouse section
@@ -235,42 +398,10 @@ type OperatorConfigClickHouse struct {
 	Config              OperatorConfigConfig        `json:"configuration" yaml:"configuration"`
 	ConfigRestartPolicy OperatorConfigRestartPolicy `json:"configurationRestartPolicy" yaml:"configurationRestartPolicy"`
 
-	Access struct {
-		// Username and Password to be used by operator to connect to ClickHouse instances
-		// for
-		// 1. Metrics requests
-		// 2. Schema maintenance
-		// User credentials can be specified in additional ClickHouse config files located in `chUsersConfigsPath` folder
-		Scheme   string `json:"scheme,omitempty"   yaml:"scheme,omitempty"`
-		Username string `json:"username,omitempty" yaml:"username,omitempty"`
-		Password string `json:"password,omitempty" yaml:"password,omitempty"`
-		RootCA   string `json:"rootCA,omitempty"   yaml:"rootCA,omitempty"`
-
-		// Location of k8s Secret with username and password to be used by the operator to connect to ClickHouse instances
-		// Can be used instead of explicitly specified (above) username and password
-		Secret struct {
-			Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
-			Name      string `json:"name,omitempty"      yaml:"name,omitempty"`
-
-			Runtime struct {
-				// Username and Password to be used by operator to connect to ClickHouse instances
-				// extracted from k8s secret specified above.
-				Username string
-				Password string
-				Fetched  bool
-				Error    string
-			}
-		} `json:"secret" yaml:"secret"`
-
-		// Port where to connect to ClickHouse instances to
-		Port int `json:"port" yaml:"port"`
+	Access OperatorConfigClickHouseAccess `json:"access" yaml:"access"`
 
-		// Timeouts used to limit connection and queries from the operator to ClickHouse instances
-		Timeouts struct {
-			Connect time.Duration `json:"connect" yaml:"connect"`
-			Query   time.Duration `json:"query"   yaml:"query"`
-		} `json:"timeouts" yaml:"timeouts"`
-	} `json:"access" yaml:"access"`
+	// Image specifies how ClickHouse versions clusters are pinned to are resolved into images
+	Image OperatorConfigClickHouseImage `json:"image" yaml:"image"`
 
 	// Metrics used to specify how the operator fetches metrics from ClickHouse instances
 	Metrics struct {
@@ -280,9 +411,47 @@ type OperatorConfigClickHouse struct {
 	} `json:"metrics" yaml:"metrics"`
 }
 
+// OperatorConfigClickHouseImage specifies how ClickHouse versions are resolved into images of ClickHouse container
+type OperatorConfigClickHouseImage struct {
+	// Repository specifies image repository, version is used as a tag in, such as clickhouse/clickhouse-server:24.3
+	Repository string `json:"repository,omitempty" yaml:"repository,omitempty"`
+	// Versions maps versions onto full images, such as 24.3: altinity/clickhouse-server:24.3.12.76.altinitystable
+	// Takes precedence over Repository
+	Versions map[string]string `json:"versions,omitempty" yaml:"versions,omitempty"`
+	// Index specifies URL of registry index newer versions are discovered in by CHIs having upgrade channel specified.
+	// Index is a JSON object of release channel onto list of versions, such as {"lts": ["24.3.12.75"], "stable": ["24.8.4.13"]}
+	Index string `json:"index,omitempty" yaml:"index,omitempty"`
+}
+
 // OperatorConfigTemplate specifies template section
 type OperatorConfigTemplate struct {
-	CHI OperatorConfigCHI `json:"chi" yaml:"chi"`
+	CHI     OperatorConfigCHI             `json:"chi"     yaml:"chi"`
+	Service OperatorConfigTemplateService `json:"service" yaml:"service"`
+	// Sidecars specifies containers to be injected into pods of all CHIs, unless CHI opts-out
+	Sidecars []core.Container `json:"sidecars,omitempty" yaml:"sidecars,omitempty"`
+	// LogContainer specifies log container added to pods of hosts having log volume
+	LogContainer OperatorConfigTemplateLogContainer `json:"logContainer" yaml:"logContainer"`
+}
+
+// OperatorConfigTemplateLogContainer specifies template log container section
+type OperatorConfigTemplateLogContainer struct {
+	// TailLogs specifies whether log container tails ClickHouse logs to stdout instead of just idling.
+	// Off by default, since it changes pod template of existing CHIs, thus restarts all hosts
+	TailLogs *StringBool `json:"tailLogs,omitempty" yaml:"tailLogs,omitempty"`
+}
+
+// OperatorConfigTemplateService specifies template service section
+type OperatorConfigTemplateService struct {
+	// Type specifies type of CHI-level Service created w/o ServiceTemplate, in case CHI does not specify it
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// InternalLoadBalancer specifies how InternalLoadBalancer service type is implemented
+	InternalLoadBalancer OperatorConfigTemplateServiceInternalLoadBalancer `json:"internalLoadBalancer,omitempty" yaml:"internalLoadBalancer,omitempty"`
+}
+
+// OperatorConfigTemplateServiceInternalLoadBalancer specifies template service internal load balancer section
+type OperatorConfigTemplateServiceInternalLoadBalancer struct {
+	// Annotations specifies annotations, which make cloud provider create LoadBalancer available within VPC only
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 }
 
 // OperatorConfigCHIPolicy specifies string value of .template.chi.policy
@@ -316,10 +485,19 @@ type OperatorConfigCHI struct {
 	Policy OperatorConfigCHIPolicy `json:"policy" yaml:"policy"`
 	// Path where to look for ClickHouseInstallation templates .yaml files
 	Path string `json:"path" yaml:"path"`
+	// Catalog specifies shared catalog of ClickHouseInstallation templates
+	Catalog OperatorConfigCHICatalog `json:"catalog" yaml:"catalog"`
 
 	Runtime OperatorConfigCHIRuntime `json:"runtime,omitempty" yaml:"runtime,omitempty"`
 }
 
+// OperatorConfigCHICatalog specifies shared catalog of ClickHouseInstallation templates
+type OperatorConfigCHICatalog struct {
+	// Namespace where shared ClickHouseInstallation templates are published.
+	// Templates referenced w/o namespace and not found in the namespace of the CHI are looked up in this namespace
+	Namespace string `json:"namespace" yaml:"namespace"`
+}
+
 // OperatorConfigCHIRuntime specifies chi runtime section
 type OperatorConfigCHIRuntime struct {
 	// CHI template files fetched from the path specified above. Maps "file name->file content"
@@ -338,6 +516,17 @@ type OperatorConfigReconcile struct {
 		ReconcileCHIsThreadsNumber           int `json:"reconcileCHIsThreadsNumber"           yaml:"reconcileCHIsThreadsNumber"`
 		ReconcileShardsThreadsNumber         int `json:"reconcileShardsThreadsNumber"         yaml:"reconcileShardsThreadsNumber"`
 		ReconcileShardsMaxConcurrencyPercent int `json:"reconcileShardsMaxConcurrencyPercent" yaml:"reconcileShardsMaxConcurrencyPercent"`
+		// MaxConcurrentHosts specifies default max number of hosts of different shards reconciled concurrently within one CHI.
+		// Overrides reconcileShardsThreadsNumber in case specified. May be overridden by CHI
+		MaxConcurrentHosts int `json:"maxConcurrentHosts" yaml:"maxConcurrentHosts"`
+		// MaxConcurrentRestarts specifies max number of hosts restarted concurrently across all CHIs managed by the operator.
+		// 0 means unlimited
+		MaxConcurrentRestarts int `json:"maxConcurrentRestarts" yaml:"maxConcurrentRestarts"`
+		// ShutdownGracePeriod specifies how many seconds the operator waits for in-flight reconciles on shutdown
+		ShutdownGracePeriod int `json:"shutdownGracePeriod" yaml:"shutdownGracePeriod"`
+		// ConcurrencyPolicy specifies default policy applied to the in-flight reconcile of the CHI edited meanwhile,
+		// either 'queue', 'cancel' or 'coalesce'. May be overridden by CHI
+		ConcurrencyPolicy string `json:"concurrencyPolicy" yaml:"concurrencyPolicy"`
 
 		// DEPRECATED, is replaced with reconcileCHIsThreadsNumber
 		ThreadsNumber int `json:"threadsNumber" yaml:"threadsNumber"`
@@ -353,6 +542,9 @@ type OperatorConfigReconcile struct {
 			PollInterval uint64 `json:"pollInterval" yaml:"pollInterval"`
 			OnFailure    string `json:"onFailure" yaml:"onFailure"`
 		} `json:"update" yaml:"update"`
+
+		// OnOperatorRestart specifies what to do with existing StatefulSets after operator upgrade
+		OnOperatorRestart string `json:"onOperatorRestart" yaml:"onOperatorRestart"`
 	} `json:"statefulSet" yaml:"statefulSet"`
 
 	Host OperatorConfigReconcileHost `json:"host" yaml:"host"`
@@ -360,7 +552,64 @@ type OperatorConfigReconcile struct {
 
 // OperatorConfigReconcileHost defines reconcile host config
 type OperatorConfigReconcileHost struct {
-	Wait OperatorConfigReconcileHostWait `json:"wait" yaml:"wait"`
+	Wait        OperatorConfigReconcileHostWait        `json:"wait"        yaml:"wait"`
+	PDB         OperatorConfigReconcileHostPDB         `json:"pdb"         yaml:"pdb"`
+	Probe       OperatorConfigReconcileHostProbe       `json:"probe"       yaml:"probe"`
+	Maintenance OperatorConfigReconcileHostMaintenance `json:"maintenance" yaml:"maintenance"`
+	Capacity    OperatorConfigReconcileHostCapacity    `json:"capacity"    yaml:"capacity"`
+}
+
+// Capacity checks of hosts
+const (
+	// HostCapacityCheckWarn specifies hosts no node can fit are reported via events, reconcile proceeds
+	HostCapacityCheckWarn = "warn"
+	// HostCapacityCheckFail specifies CHI having hosts no node can fit is refused to be reconciled
+	HostCapacityCheckFail = "fail"
+	// HostCapacityCheckNo specifies hosts are not checked
+	HostCapacityCheckNo = "no"
+)
+
+// OperatorConfigReconcileHostCapacity defines reconcile host capacity config
+type OperatorConfigReconcileHostCapacity struct {
+	// Check specifies how hosts, whose pods request more resources than allocatable on any node, are handled
+	Check string `json:"check,omitempty" yaml:"check,omitempty"`
+}
+
+// GetCheck gets capacity check mode, warn by default
+func (c *OperatorConfigReconcileHostCapacity) GetCheck() string {
+	if c == nil {
+		return HostCapacityCheckWarn
+	}
+	switch strings.ToLower(c.Check) {
+	case HostCapacityCheckFail:
+		return HostCapacityCheckFail
+	case HostCapacityCheckNo:
+		return HostCapacityCheckNo
+	}
+	return HostCapacityCheckWarn
+}
+
+// OperatorConfigReconcileHostProbe defines reconcile host probe config
+type OperatorConfigReconcileHostProbe struct {
+	// Ports specifies whether ClickHouse ports of the host are dialed by the operator after the host is started,
+	// so ports unreachable from the operator, due to NetworkPolicy or listen_host misconfiguration, are reported
+	Ports *StringBool `json:"ports,omitempty"   yaml:"ports,omitempty"`
+	// Timeout specifies how long, in seconds, each port is probed for before being reported as unreachable
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// OperatorConfigReconcileHostMaintenance defines reconcile host maintenance config
+type OperatorConfigReconcileHostMaintenance struct {
+	// DrainTimeout specifies how long, in seconds, running queries of the host under maintenance are waited for
+	// to complete before the host's StatefulSet is updated anyway
+	DrainTimeout int `json:"drainTimeout,omitempty" yaml:"drainTimeout,omitempty"`
+}
+
+// OperatorConfigReconcileHostPDB defines reconcile host PodDisruptionBudget config
+type OperatorConfigReconcileHostPDB struct {
+	// Suspend specifies whether PodDisruptionBudget budget is extended for the host being disrupted by the operator,
+	// so the host restarted by the operator does not consume budget available for external voluntary disruptions
+	Suspend *StringBool `json:"suspend,omitempty" yaml:"suspend,omitempty"`
 }
 
 // OperatorConfigReconcileHostWait defines reconcile host wait config
@@ -422,6 +671,8 @@ type OperatorConfig struct {
 		VModule         string `json:"vmodule"          yaml:"vmodule"`
 		LogBacktraceAt  string `json:"log_backtrace_at" yaml:"log_backtrace_at"`
 	} `json:"logger" yaml:"logger"`
+	// FeatureGates specifies features explicitly enabled or disabled
+	FeatureGates OperatorConfigFeatureGates `json:"featureGates" yaml:"featureGates"`
 
 	//
 	// The end of OperatorConfig
@@ -624,6 +875,19 @@ func (c *OperatorConfig) FindTemplate(templateRef *TemplateRef, fallbackNamespac
 		}
 	}
 
+	// Look for templates with specified name in shared templates catalog
+
+	if !c.HasTemplatesCatalog() {
+		return nil
+	}
+
+	for _, template := range c.Template.CHI.Runtime.Templates {
+		if template.MatchFullName(c.Template.CHI.Catalog.Namespace, templateRef.Name) {
+			// Found template with searched name in templates catalog
+			return template
+		}
+	}
+
 	return nil
 }
 
@@ -725,6 +989,23 @@ func (c *OperatorConfig) normalizeSectionTemplate() {
 	util.PreparePath(&c.Template.CHI.Path, c.Runtime.ConfigFolderPath, TemplatesDir)
 }
 
+func (c *OperatorConfig) normalizeSectionWatch() {
+	if c.Watch.Informers.Resync.Kube <= 0 {
+		c.Watch.Informers.Resync.Kube = defaultInformerResyncPeriod
+	}
+	if c.Watch.Informers.Resync.CHOp <= 0 {
+		c.Watch.Informers.Resync.CHOp = defaultInformerResyncPeriod
+	}
+	c.Watch.Informers.FilterManagedObjects = c.Watch.Informers.FilterManagedObjects.Normalize(false)
+	c.Watch.NamespaceSelector = strings.TrimSpace(c.Watch.NamespaceSelector)
+	if c.Watch.NamespaceSelector != "" {
+		if _, err := labels.Parse(c.Watch.NamespaceSelector); err != nil {
+			log.Warningf("Unable to parse namespace selector: %s err: %v. Skip it", c.Watch.NamespaceSelector, err)
+			c.Watch.NamespaceSelector = ""
+		}
+	}
+}
+
 func (c *OperatorConfig) normalizeSectionReconcileStatefulSet() {
 	// Process Create/Update section
 
@@ -750,6 +1031,13 @@ func (c *OperatorConfig) normalizeSectionReconcileStatefulSet() {
 	if c.Reconcile.StatefulSet.Update.OnFailure == "" {
 		c.Reconcile.StatefulSet.Update.OnFailure = OnStatefulSetUpdateFailureActionRollback
 	}
+
+	// Default operator restart action - verify only, thus operator upgrade does not restart pods
+	switch c.Reconcile.StatefulSet.OnOperatorRestart {
+	case OnOperatorRestartStatefulSetActionVerify, OnOperatorRestartStatefulSetActionUpdate:
+	default:
+		c.Reconcile.StatefulSet.OnOperatorRestart = OnOperatorRestartStatefulSetActionVerify
+	}
 }
 
 func (c *OperatorConfig) normalizeSectionClickHouseConfigurationUserDefault() {
@@ -774,6 +1062,18 @@ func (c *OperatorConfig) normalizeSectionClickHouseConfigurationUserDefault() {
 	// chConfigNetworksHostRegexpTemplate
 }
 
+// NormalizeChEndpoints normalizes list of endpoints, unknown and duplicate endpoints are skipped
+func NormalizeChEndpoints(endpoints []string) (normalized []string) {
+	for _, endpoint := range endpoints {
+		for _, known := range []string{ChEndpointService, ChEndpointPodIP, ChEndpointPodFQDN} {
+			if strings.EqualFold(endpoint, known) && !util.InArray(known, normalized) {
+				normalized = append(normalized, known)
+			}
+		}
+	}
+	return normalized
+}
+
 func (c *OperatorConfig) normalizeSectionClickHouseAccess() {
 	// Username and Password to be used by operator to connect to ClickHouse instances for
 	// 1. Metrics requests
@@ -807,8 +1107,39 @@ func (c *OperatorConfig) normalizeSectionClickHouseAccess() {
 		c.ClickHouse.Access.Password = c.ClickHouse.Access.Secret.Runtime.Password
 	}
 
+	switch strings.ToLower(c.ClickHouse.Access.Driver) {
+	case ChDriverHTTP:
+		c.ClickHouse.Access.Driver = ChDriverHTTP
+	case ChDriverNative:
+		c.ClickHouse.Access.Driver = ChDriverNative
+	default:
+		c.ClickHouse.Access.Driver = defaultChDriver
+	}
+	switch strings.ToLower(c.ClickHouse.Access.Compression) {
+	case ChCompressionNone:
+		c.ClickHouse.Access.Compression = ChCompressionNone
+	case ChCompressionLZ4:
+		c.ClickHouse.Access.Compression = ChCompressionLZ4
+	case ChCompressionZSTD:
+		c.ClickHouse.Access.Compression = ChCompressionZSTD
+	default:
+		c.ClickHouse.Access.Compression = defaultChCompression
+	}
+
 	if c.ClickHouse.Access.Port == 0 {
-		c.ClickHouse.Access.Port = defaultChPort
+		switch {
+		case (c.ClickHouse.Access.Driver == ChDriverNative) && (c.ClickHouse.Access.Scheme == ChSchemeHTTPS):
+			c.ClickHouse.Access.Port = defaultChNativeTLSPort
+		case c.ClickHouse.Access.Driver == ChDriverNative:
+			c.ClickHouse.Access.Port = defaultChNativePort
+		default:
+			c.ClickHouse.Access.Port = defaultChPort
+		}
+	}
+
+	c.ClickHouse.Access.Endpoints = NormalizeChEndpoints(c.ClickHouse.Access.Endpoints)
+	if len(c.ClickHouse.Access.Endpoints) == 0 {
+		c.ClickHouse.Access.Endpoints = []string{ChEndpointService}
 	}
 
 	// Timeouts
@@ -825,6 +1156,25 @@ func (c *OperatorConfig) normalizeSectionClickHouseAccess() {
 	// Adjust seconds to time.Duration
 	c.ClickHouse.Access.Timeouts.Query = c.ClickHouse.Access.Timeouts.Query * time.Second
 
+	// Slow queries
+
+	threshold := time.Duration(defaultSlowQueryThreshold)
+	if c.ClickHouse.Access.SlowQueries.Threshold != nil {
+		threshold = *c.ClickHouse.Access.SlowQueries.Threshold
+	}
+	// Adjust seconds to time.Duration
+	threshold = threshold * time.Second
+	c.ClickHouse.Access.SlowQueries.Threshold = &threshold
+
+	if c.ClickHouse.Access.SlowQueries.Top == 0 {
+		c.ClickHouse.Access.SlowQueries.Top = defaultSlowQueriesTop
+	}
+}
+
+func (c *OperatorConfig) normalizeSectionClickHouseImage() {
+	if c.ClickHouse.Image.Repository == "" {
+		c.ClickHouse.Image.Repository = defaultChImageRepository
+	}
 }
 
 func (c *OperatorConfig) normalizeSectionClickHouseMetrics() {
@@ -844,6 +1194,20 @@ func (c *OperatorConfig) normalizeSectionLogger() {
 	// Log_backtrace_at string `json:"log_backtrace_at" yaml:"log_backtrace_at"`
 }
 
+func (c *OperatorConfig) normalizeSectionFeatureGates() {
+	for feature, value := range c.FeatureGates {
+		if !IsKnownFeature(feature) {
+			log.Warningf("Unknown feature gate: %s. Skip it", feature)
+			delete(c.FeatureGates, feature)
+			continue
+		}
+		if !value.IsValid() {
+			log.Warningf("Unable to parse value of feature gate: %s. Use default", feature)
+			delete(c.FeatureGates, feature)
+		}
+	}
+}
+
 func (c *OperatorConfig) normalizeSectionReconcileRuntime() {
 	if c.Reconcile.Runtime.ThreadsNumber == 0 {
 		c.Reconcile.Runtime.ThreadsNumber = defaultReconcileCHIsThreadsNumber
@@ -857,6 +1221,16 @@ func (c *OperatorConfig) normalizeSectionReconcileRuntime() {
 	if c.Reconcile.Runtime.ReconcileShardsMaxConcurrencyPercent == 0 {
 		c.Reconcile.Runtime.ReconcileShardsMaxConcurrencyPercent = defaultReconcileShardsMaxConcurrencyPercent
 	}
+	if c.Reconcile.Runtime.MaxConcurrentRestarts < 0 {
+		c.Reconcile.Runtime.MaxConcurrentRestarts = 0
+	}
+	if c.Reconcile.Runtime.ShutdownGracePeriod == 0 {
+		c.Reconcile.Runtime.ShutdownGracePeriod = defaultReconcileShutdownGracePeriod
+	}
+	if !IsReconcilingConcurrencyPolicyValid(c.Reconcile.Runtime.ConcurrencyPolicy) {
+		c.Reconcile.Runtime.ConcurrencyPolicy = defaultReconcileConcurrencyPolicy
+	}
+	c.Reconcile.Runtime.ConcurrencyPolicy = strings.ToLower(c.Reconcile.Runtime.ConcurrencyPolicy)
 
 	//reconcileWaitExclude: true
 	//reconcileWaitInclude: false
@@ -888,9 +1262,11 @@ func (c *OperatorConfig) normalize() {
 	c.move()
 	c.Runtime.Namespace = os.Getenv(deployment.OPERATOR_POD_NAMESPACE)
 
+	c.normalizeSectionWatch()
 	c.normalizeSectionClickHouseConfigurationFile()
 	c.normalizeSectionClickHouseConfigurationUserDefault()
 	c.normalizeSectionClickHouseAccess()
+	c.normalizeSectionClickHouseImage()
 	c.normalizeSectionClickHouseMetrics()
 	c.normalizeSectionTemplate()
 	c.normalizeSectionReconcileStatefulSet()
@@ -899,6 +1275,7 @@ func (c *OperatorConfig) normalize() {
 	c.normalizeSectionLabel()
 	c.normalizeSectionStatefulSet()
 	c.normalizeSectionPod()
+	c.normalizeSectionFeatureGates()
 }
 
 // applyEnvVarParams applies ENV VARS over config
@@ -936,6 +1313,11 @@ func (c *OperatorConfig) applyDefaultWatchNamespace() {
 		return
 	}
 
+	if c.HasNamespaceSelector() {
+		// Namespaces are selected by labels
+		return
+	}
+
 	// No namespaces specified
 
 	if c.Runtime.Namespace == "kube-system" {
@@ -1009,11 +1391,59 @@ func (c *OperatorConfig) String(hideCredentials bool) string {
 // TODO unify with GetInformerNamespace
 func (c *OperatorConfig) IsWatchedNamespace(namespace string) bool {
 	// In case no namespaces specified - watch all namespaces
-	if len(c.Watch.Namespaces) == 0 {
+	if (len(c.Watch.Namespaces) == 0) && !c.HasNamespaceSelector() {
+		return true
+	}
+
+	if util.InArrayWithRegexp(namespace, c.Watch.Namespaces) {
 		return true
 	}
 
-	return util.InArrayWithRegexp(namespace, c.Watch.Namespaces)
+	return c.isSelectedNamespace(namespace)
+}
+
+// HasNamespaceSelector checks whether watched namespaces are selected by labels
+func (c *OperatorConfig) HasNamespaceSelector() bool {
+	return c.Watch.NamespaceSelector != ""
+}
+
+// isSelectedNamespace checks whether specified namespace currently matches namespace selector
+func (c *OperatorConfig) isSelectedNamespace(namespace string) bool {
+	if !c.HasNamespaceSelector() {
+		return false
+	}
+
+	selectedNamespaces.RLock()
+	defer selectedNamespaces.RUnlock()
+
+	return selectedNamespaces.namespaces[namespace]
+}
+
+// SetSelectedNamespace marks specified namespace as either matching namespace selector or not.
+// Returns whether namespace has changed its state
+func (c *OperatorConfig) SetSelectedNamespace(namespace string, selected bool) bool {
+	selectedNamespaces.Lock()
+	defer selectedNamespaces.Unlock()
+
+	if selectedNamespaces.namespaces[namespace] == selected {
+		return false
+	}
+	if selected {
+		selectedNamespaces.namespaces[namespace] = true
+	} else {
+		delete(selectedNamespaces.namespaces, namespace)
+	}
+	return true
+}
+
+// HasTemplatesCatalog checks whether shared catalog of ClickHouseInstallation templates is specified
+func (c *OperatorConfig) HasTemplatesCatalog() bool {
+	return c.Template.CHI.Catalog.Namespace != ""
+}
+
+// IsTemplatesCatalogNamespace checks whether specified namespace is the shared catalog of ClickHouseInstallation templates
+func (c *OperatorConfig) IsTemplatesCatalogNamespace(namespace string) bool {
+	return c.HasTemplatesCatalog() && (c.Template.CHI.Catalog.Namespace == namespace)
 }
 
 // GetInformerNamespace is a TODO stub
@@ -1027,7 +1457,7 @@ func (c *OperatorConfig) IsWatchedNamespace(namespace string) bool {
 func (c *OperatorConfig) GetInformerNamespace() string {
 	// Namespace where informers would watch notifications from
 	namespace := metav1.NamespaceAll
-	if len(c.Watch.Namespaces) == 1 {
+	if (len(c.Watch.Namespaces) == 1) && !c.HasNamespaceSelector() {
 		// We have exactly one watch namespace specified
 		// This scenario is implemented in go-client
 		// In any other case, just keep metav1.NamespaceAll
@@ -1043,6 +1473,22 @@ func (c *OperatorConfig) GetInformerNamespace() string {
 	return namespace
 }
 
+// GetInformerNamespaces gets namespaces where informers of their own for each namespace would watch notifications from.
+// In case watched namespaces are listed explicitly, each of them is returned, otherwise all namespaces are watched
+func (c *OperatorConfig) GetInformerNamespaces() []string {
+	if (len(c.Watch.Namespaces) == 0) || c.HasNamespaceSelector() {
+		return []string{metav1.NamespaceAll}
+	}
+	var labelRegexp = regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$")
+	for _, namespace := range c.Watch.Namespaces {
+		if !labelRegexp.MatchString(namespace) {
+			// Namespaces are specified by regexp
+			return []string{metav1.NamespaceAll}
+		}
+	}
+	return util.Unique(c.Watch.Namespaces)
+}
+
 // GetLogLevel gets logger level
 func (c *OperatorConfig) GetLogLevel() (log.Level, error) {
 	if i, err := strconv.Atoi(c.Logger.V); err == nil {
@@ -1058,6 +1504,15 @@ func (c *OperatorConfig) GetTerminationGracePeriod() *int64 {
 	return &terminationGracePeriod
 }
 
+// GetClickHouseImage resolves ClickHouse version into image of ClickHouse container.
+// Explicitly mapped versions take precedence, the rest of versions are used as tags within the repository
+func (c *OperatorConfig) GetClickHouseImage(version string) string {
+	if image, ok := c.ClickHouse.Image.Versions[version]; ok && (image != "") {
+		return image
+	}
+	return c.ClickHouse.Image.Repository + ":" + version
+}
+
 // GetRevisionHistoryLimit gets pointer to revisionHistoryLimit, as expected by
 // statefulSet.Spec.Template.Spec.RevisionHistoryLimit
 func (c *OperatorConfig) GetRevisionHistoryLimit() *int32 {
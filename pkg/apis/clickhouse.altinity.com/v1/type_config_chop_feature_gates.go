@@ -0,0 +1,116 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"sort"
+)
+
+// FeatureGate specifies name of a feature which can be enabled or disabled in operator's config
+type FeatureGate string
+
+// FeatureStage specifies maturity stage of a feature
+type FeatureStage string
+
+// Possible values of feature stage
+const (
+	// FeatureStageAlpha - feature is disabled by default and may be changed or removed
+	FeatureStageAlpha FeatureStage = "Alpha"
+	// FeatureStageBeta - feature is well tested and is enabled by default
+	FeatureStageBeta FeatureStage = "Beta"
+	// FeatureStageGA - feature is always enabled
+	FeatureStageGA FeatureStage = "GA"
+)
+
+// Known feature gates
+const (
+	// FeatureGatePerShardPDB - create PodDisruptionBudget per shard instead of per cluster
+	FeatureGatePerShardPDB FeatureGate = "PerShardPDB"
+	// FeatureGateReadWriteServices - create CHI-level read-write and read-only Services
+	FeatureGateReadWriteServices FeatureGate = "ReadWriteServices"
+	// FeatureGateClickHouseQuery - run DDL specified by ClickHouseQuery resources on behalf of the operator user
+	FeatureGateClickHouseQuery FeatureGate = "ClickHouseQuery"
+)
+
+// FeatureSpec describes a feature
+type FeatureSpec struct {
+	// Default specifies whether feature is enabled by default
+	Default bool `json:"default" yaml:"default"`
+	// Stage specifies maturity stage of the feature
+	Stage FeatureStage `json:"stage" yaml:"stage"`
+}
+
+// knownFeatures lists all features known to the operator
+var knownFeatures = map[FeatureGate]FeatureSpec{
+	FeatureGatePerShardPDB:       {Default: false, Stage: FeatureStageAlpha},
+	FeatureGateReadWriteServices: {Default: false, Stage: FeatureStageAlpha},
+	FeatureGateClickHouseQuery:   {Default: false, Stage: FeatureStageAlpha},
+}
+
+// FeatureGateStatus describes effective state of a feature
+type FeatureGateStatus struct {
+	Name    FeatureGate  `json:"name"    yaml:"name"`
+	Stage   FeatureStage `json:"stage"   yaml:"stage"`
+	Enabled bool         `json:"enabled" yaml:"enabled"`
+}
+
+// OperatorConfigFeatureGates specifies features explicitly enabled or disabled in the config
+type OperatorConfigFeatureGates map[FeatureGate]*StringBool
+
+// IsKnownFeature checks whether feature is known to the operator
+func IsKnownFeature(feature FeatureGate) bool {
+	_, ok := knownFeatures[feature]
+	return ok
+}
+
+// IsFeatureEnabled checks whether feature is enabled
+func (c *OperatorConfig) IsFeatureEnabled(feature FeatureGate) bool {
+	if c == nil {
+		return false
+	}
+
+	spec, ok := knownFeatures[feature]
+	if !ok {
+		// Unknown feature is never enabled
+		return false
+	}
+
+	if spec.Stage == FeatureStageGA {
+		// GA feature can not be disabled
+		return true
+	}
+
+	if value, ok := c.FeatureGates[feature]; ok && value.HasValue() {
+		// Explicitly specified in config
+		return value.Value()
+	}
+
+	return spec.Default
+}
+
+// GetFeatureGates gets effective state of all known features, sorted by name
+func (c *OperatorConfig) GetFeatureGates() (res []FeatureGateStatus) {
+	for feature, spec := range knownFeatures {
+		res = append(res, FeatureGateStatus{
+			Name:    feature,
+			Stage:   spec.Stage,
+			Enabled: c.IsFeatureEnabled(feature),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
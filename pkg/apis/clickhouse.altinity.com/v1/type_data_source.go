@@ -21,5 +21,7 @@ import (
 // DataSource is a set of possible data sources
 type DataSource struct {
 	// SecretKeyRef points to a secret and mirrors k8s SecretSource type
-	SecretKeyRef *core.SecretKeySelector `json:"secretKeyRef,omitempty" yaml:"secretKeyRef,omitempty"`
+	SecretKeyRef *core.SecretKeySelector `json:"secretKeyRef,omitempty"    yaml:"secretKeyRef,omitempty"`
+	// ConfigMapKeyRef points to a config map and mirrors k8s ConfigMapKeySelector type
+	ConfigMapKeyRef *core.ConfigMapKeySelector `json:"configMapKeyRef,omitempty" yaml:"configMapKeyRef,omitempty"`
 }
@@ -0,0 +1,104 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import "time"
+
+const (
+	// defaultDDLQueueInterval specifies default interval of polling distributed DDL queue
+	defaultDDLQueueInterval = 1 * time.Minute
+	// defaultDDLQueueStuckTimeout specifies default age of distributed DDL entry not completed by all hosts,
+	// exceeding which the entry is reported as stuck
+	defaultDDLQueueStuckTimeout = 5 * time.Minute
+)
+
+// ChiDDLQueue defines ddlQueue section of .spec
+// Provides periodic check of system.distributed_ddl_queue of clusters of the CHI.
+// Entries not completed by all hosts in time are reported via events and status.
+type ChiDDLQueue struct {
+	// Interval specifies interval of polling distributed DDL queue, in seconds
+	Interval int `json:"interval,omitempty"     yaml:"interval,omitempty"`
+	// StuckTimeout specifies age of entry not completed by all hosts, exceeding which the entry is reported as stuck, in seconds
+	StuckTimeout int `json:"stuckTimeout,omitempty" yaml:"stuckTimeout,omitempty"`
+	// Cleanup specifies whether entries completed by all hosts are deleted from keeper
+	Cleanup *StringBool `json:"cleanup,omitempty"      yaml:"cleanup,omitempty"`
+}
+
+// NewChiDDLQueue creates new ChiDDLQueue object
+func NewChiDDLQueue() *ChiDDLQueue {
+	return new(ChiDDLQueue)
+}
+
+// IsEnabled checks whether distributed DDL queue is to be polled
+func (q *ChiDDLQueue) IsEnabled() bool {
+	return q != nil
+}
+
+// GetInterval gets interval of polling distributed DDL queue
+func (q *ChiDDLQueue) GetInterval() time.Duration {
+	if (q == nil) || (q.Interval <= 0) {
+		return defaultDDLQueueInterval
+	}
+	return time.Duration(q.Interval) * time.Second
+}
+
+// GetStuckTimeout gets age of entry not completed by all hosts, exceeding which the entry is reported as stuck
+func (q *ChiDDLQueue) GetStuckTimeout() time.Duration {
+	if (q == nil) || (q.StuckTimeout <= 0) {
+		return defaultDDLQueueStuckTimeout
+	}
+	return time.Duration(q.StuckTimeout) * time.Second
+}
+
+// IsCleanupEnabled checks whether entries completed by all hosts are to be deleted from keeper
+func (q *ChiDDLQueue) IsCleanupEnabled() bool {
+	if q == nil {
+		return false
+	}
+	return q.Cleanup.IsTrue()
+}
+
+// MergeFrom merges from specified source
+func (q *ChiDDLQueue) MergeFrom(from *ChiDDLQueue, _type MergeType) *ChiDDLQueue {
+	if from == nil {
+		return q
+	}
+
+	if q == nil {
+		q = NewChiDDLQueue()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if q.Interval == 0 {
+			q.Interval = from.Interval
+		}
+		if q.StuckTimeout == 0 {
+			q.StuckTimeout = from.StuckTimeout
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Interval != 0 {
+			// Override by non-empty values only
+			q.Interval = from.Interval
+		}
+		if from.StuckTimeout != 0 {
+			// Override by non-empty values only
+			q.StuckTimeout = from.StuckTimeout
+		}
+	}
+	q.Cleanup = q.Cleanup.MergeFrom(from.Cleanup)
+
+	return q
+}
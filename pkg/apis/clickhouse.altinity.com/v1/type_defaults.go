@@ -14,12 +14,67 @@
 
 package v1
 
+// Settings presets maintained by the operator
+const (
+	// ProfilePresetSmall specifies settings for hosts with a few CPUs and a few GiB of RAM
+	ProfilePresetSmall = "small"
+	// ProfilePresetMedium specifies settings for hosts with about 8 CPUs and 32 GiB of RAM
+	ProfilePresetMedium = "medium"
+	// ProfilePresetLarge specifies settings for hosts with 16+ CPUs and 64+ GiB of RAM
+	ProfilePresetLarge = "large"
+)
+
+// Service types available for Services created w/o ServiceTemplate
+const (
+	// ServiceTypeHeadless specifies ClusterIP service w/o cluster IP assigned
+	ServiceTypeHeadless = "Headless"
+	// ServiceTypeClusterIP specifies ClusterIP service with cluster IP assigned
+	ServiceTypeClusterIP = "ClusterIP"
+	// ServiceTypeNodePort specifies NodePort service
+	ServiceTypeNodePort = "NodePort"
+	// ServiceTypeLoadBalancer specifies LoadBalancer service
+	ServiceTypeLoadBalancer = "LoadBalancer"
+	// ServiceTypeInternalLoadBalancer specifies LoadBalancer service annotated to be available within VPC only
+	ServiceTypeInternalLoadBalancer = "InternalLoadBalancer"
+)
+
+// Kinds of k8s objects generated ClickHouse config files are delivered in
+const (
+	// ConfigStorageConfigMap specifies config files to be delivered in ConfigMaps
+	ConfigStorageConfigMap = "ConfigMap"
+	// ConfigStorageSecret specifies config files to be delivered in Secrets
+	ConfigStorageSecret = "Secret"
+)
+
+// Distribution presets of hosts across k8s nodes and zones
+const (
+	// DistributionOnePerNode specifies no two hosts of the CHI to be scheduled onto the same node
+	DistributionOnePerNode = "OnePerNode"
+	// DistributionOnePerZone specifies hosts of the CHI to be spread evenly across zones, one host per zone
+	// as long as there are enough zones
+	DistributionOnePerZone = "OnePerZone"
+	// DistributionOnePerShardPerNode specifies no two hosts of the same shard to be scheduled onto the same node
+	DistributionOnePerShardPerNode = "OnePerShardPerNode"
+)
+
 // ChiDefaults defines defaults section of .spec
 type ChiDefaults struct {
 	ReplicasUseFQDN   *StringBool        `json:"replicasUseFQDN,omitempty"    yaml:"replicasUseFQDN,omitempty"`
 	DistributedDDL    *ChiDistributedDDL `json:"distributedDDL,omitempty"     yaml:"distributedDDL,omitempty"`
 	StorageManagement *StorageManagement `json:"storageManagement,omitempty"  yaml:"storageManagement,omitempty"`
 	Templates         *ChiTemplateNames  `json:"templates,omitempty"          yaml:"templates,omitempty"`
+	// ProfilePreset specifies operator-maintained settings preset to be applied to the CHI
+	ProfilePreset string `json:"profilePreset,omitempty"      yaml:"profilePreset,omitempty"`
+	// ServiceType specifies type of CHI-level Service created w/o ServiceTemplate
+	ServiceType string `json:"serviceType,omitempty"        yaml:"serviceType,omitempty"`
+	// ConfigStorage specifies kind of k8s objects generated ClickHouse config files are delivered in
+	ConfigStorage string `json:"configStorage,omitempty"      yaml:"configStorage,omitempty"`
+	// InjectSidecars specifies whether sidecars specified in operator config are injected into pods of the CHI
+	InjectSidecars *StringBool `json:"injectSidecars,omitempty"     yaml:"injectSidecars,omitempty"`
+	// Distribution specifies preset of hosts distribution across k8s nodes and zones
+	Distribution string `json:"distribution,omitempty"       yaml:"distribution,omitempty"`
+	// Probes specifies probes of ClickHouse container generated by the operator
+	Probes *ChiProbes `json:"probes,omitempty"             yaml:"probes,omitempty"`
 }
 
 // NewChiDefaults creates new ChiDefaults object
@@ -42,16 +97,110 @@ func (defaults *ChiDefaults) MergeFrom(from *ChiDefaults, _type MergeType) *ChiD
 		if !from.ReplicasUseFQDN.HasValue() {
 			defaults.ReplicasUseFQDN = defaults.ReplicasUseFQDN.MergeFrom(from.ReplicasUseFQDN)
 		}
+		if defaults.ProfilePreset == "" {
+			defaults.ProfilePreset = from.ProfilePreset
+		}
+		if defaults.ServiceType == "" {
+			defaults.ServiceType = from.ServiceType
+		}
+		if defaults.ConfigStorage == "" {
+			defaults.ConfigStorage = from.ConfigStorage
+		}
+		if !defaults.InjectSidecars.HasValue() {
+			defaults.InjectSidecars = defaults.InjectSidecars.MergeFrom(from.InjectSidecars)
+		}
+		if defaults.Distribution == "" {
+			defaults.Distribution = from.Distribution
+		}
 	case MergeTypeOverrideByNonEmptyValues:
 		if from.ReplicasUseFQDN.HasValue() {
 			// Override by non-empty values only
 			defaults.ReplicasUseFQDN = defaults.ReplicasUseFQDN.MergeFrom(from.ReplicasUseFQDN)
 		}
+		if from.ProfilePreset != "" {
+			// Override by non-empty values only
+			defaults.ProfilePreset = from.ProfilePreset
+		}
+		if from.ServiceType != "" {
+			// Override by non-empty values only
+			defaults.ServiceType = from.ServiceType
+		}
+		if from.ConfigStorage != "" {
+			// Override by non-empty values only
+			defaults.ConfigStorage = from.ConfigStorage
+		}
+		if from.InjectSidecars.HasValue() {
+			// Override by non-empty values only
+			defaults.InjectSidecars = defaults.InjectSidecars.MergeFrom(from.InjectSidecars)
+		}
+		if from.Distribution != "" {
+			// Override by non-empty values only
+			defaults.Distribution = from.Distribution
+		}
 	}
 
 	defaults.DistributedDDL = defaults.DistributedDDL.MergeFrom(from.DistributedDDL, _type)
 	defaults.StorageManagement = defaults.StorageManagement.MergeFrom(from.StorageManagement, _type)
 	defaults.Templates = defaults.Templates.MergeFrom(from.Templates, _type)
+	defaults.Probes = defaults.Probes.MergeFrom(from.Probes, _type)
 
 	return defaults
 }
+
+// GetProfilePreset gets name of the settings preset
+func (defaults *ChiDefaults) GetProfilePreset() string {
+	if defaults == nil {
+		return ""
+	}
+	return defaults.ProfilePreset
+}
+
+// GetServiceType gets type of CHI-level Service created w/o ServiceTemplate
+func (defaults *ChiDefaults) GetServiceType() string {
+	if defaults == nil {
+		return ""
+	}
+	return defaults.ServiceType
+}
+
+// GetConfigStorage gets kind of k8s objects generated ClickHouse config files are delivered in, ConfigMap by default
+func (defaults *ChiDefaults) GetConfigStorage() string {
+	if defaults == nil {
+		return ConfigStorageConfigMap
+	}
+	switch defaults.ConfigStorage {
+	case ConfigStorageSecret:
+		return ConfigStorageSecret
+	}
+	return ConfigStorageConfigMap
+}
+
+// IsConfigStoredInSecret checks whether generated ClickHouse config files are delivered in Secrets
+func (defaults *ChiDefaults) IsConfigStoredInSecret() bool {
+	return defaults.GetConfigStorage() == ConfigStorageSecret
+}
+
+// ShouldInjectSidecars checks whether sidecars specified in operator config are to be injected into pods of the CHI.
+// Sidecars are injected unless explicitly opted-out
+func (defaults *ChiDefaults) ShouldInjectSidecars() bool {
+	if defaults == nil {
+		return true
+	}
+	return !defaults.InjectSidecars.IsFalse()
+}
+
+// GetDistribution gets preset of hosts distribution across k8s nodes and zones
+func (defaults *ChiDefaults) GetDistribution() string {
+	if defaults == nil {
+		return ""
+	}
+	return defaults.Distribution
+}
+
+// GetProbes gets probes of ClickHouse container generated by the operator
+func (defaults *ChiDefaults) GetProbes() *ChiProbes {
+	if defaults == nil {
+		return nil
+	}
+	return defaults.Probes
+}
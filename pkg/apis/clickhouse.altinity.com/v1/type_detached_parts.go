@@ -0,0 +1,167 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import "time"
+
+// Reasons of detached parts, which are known to be safe to be dropped
+const (
+	// DetachedPartReasonIgnored specifies part, which is covered by another part and is ignored on start
+	DetachedPartReasonIgnored = "ignored"
+	// DetachedPartReasonBrokenOnStart specifies part, which was found broken on server start
+	DetachedPartReasonBrokenOnStart = "broken-on-start"
+)
+
+const (
+	// defaultDetachedPartsThreshold specifies number of detached parts on a host, exceeding which is reported
+	defaultDetachedPartsThreshold = 100
+	// defaultDetachedPartsInterval specifies default interval of polling hosts for detached parts
+	defaultDetachedPartsInterval = 5 * time.Minute
+	// defaultDetachedPartsMinAgeDays specifies default age of detached parts to be cleaned up
+	defaultDetachedPartsMinAgeDays = 7
+)
+
+// ChiDetachedParts defines detachedParts section of .spec
+// Provides periodic check of system.detached_parts on all hosts of the CHI.
+// Hosts having detached parts over the threshold are reported via events and status.
+type ChiDetachedParts struct {
+	// Threshold specifies number of detached parts on a host, exceeding which is reported
+	Threshold int `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// Interval specifies interval of polling hosts, in seconds
+	Interval int `json:"interval,omitempty"  yaml:"interval,omitempty"`
+	// Cleanup specifies automatic cleanup of known-safe detached parts
+	Cleanup *ChiDetachedPartsCleanup `json:"cleanup,omitempty"   yaml:"cleanup,omitempty"`
+}
+
+// ChiDetachedPartsCleanup defines automatic cleanup of detached parts
+type ChiDetachedPartsCleanup struct {
+	Enabled *StringBool `json:"enabled,omitempty"    yaml:"enabled,omitempty"`
+	// Reasons specifies reasons of detached parts to be dropped.
+	// Only known-safe reasons are accepted, the rest are ignored
+	Reasons []string `json:"reasons,omitempty"    yaml:"reasons,omitempty"`
+	// MinAgeDays specifies how many days detached part should stay detached before being dropped
+	MinAgeDays int `json:"minAgeDays,omitempty" yaml:"minAgeDays,omitempty"`
+}
+
+// NewChiDetachedParts creates new ChiDetachedParts object
+func NewChiDetachedParts() *ChiDetachedParts {
+	return new(ChiDetachedParts)
+}
+
+// IsEnabled checks whether hosts are to be polled for detached parts
+func (d *ChiDetachedParts) IsEnabled() bool {
+	return d != nil
+}
+
+// GetThreshold gets number of detached parts on a host, exceeding which is reported
+func (d *ChiDetachedParts) GetThreshold() int {
+	if (d == nil) || (d.Threshold <= 0) {
+		return defaultDetachedPartsThreshold
+	}
+	return d.Threshold
+}
+
+// GetInterval gets interval of polling hosts
+func (d *ChiDetachedParts) GetInterval() time.Duration {
+	if (d == nil) || (d.Interval <= 0) {
+		return defaultDetachedPartsInterval
+	}
+	return time.Duration(d.Interval) * time.Second
+}
+
+// GetCleanup gets cleanup
+func (d *ChiDetachedParts) GetCleanup() *ChiDetachedPartsCleanup {
+	if d == nil {
+		return nil
+	}
+	return d.Cleanup
+}
+
+// IsEnabled checks whether cleanup is enabled
+func (c *ChiDetachedPartsCleanup) IsEnabled() bool {
+	if c == nil {
+		return false
+	}
+	return c.Enabled.IsTrue()
+}
+
+// GetReasons gets known-safe reasons of detached parts to be dropped.
+// Only broken-on-start parts are dropped by default
+func (c *ChiDetachedPartsCleanup) GetReasons() (reasons []string) {
+	if (c == nil) || (len(c.Reasons) == 0) {
+		return []string{
+			DetachedPartReasonBrokenOnStart,
+		}
+	}
+	safe := []string{
+		DetachedPartReasonIgnored,
+		DetachedPartReasonBrokenOnStart,
+	}
+	for _, reason := range c.Reasons {
+		for _, s := range safe {
+			if reason == s {
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	return reasons
+}
+
+// GetMinAgeDays gets how many days detached part should stay detached before being dropped
+func (c *ChiDetachedPartsCleanup) GetMinAgeDays() int {
+	if (c == nil) || (c.MinAgeDays <= 0) {
+		return defaultDetachedPartsMinAgeDays
+	}
+	return c.MinAgeDays
+}
+
+// MergeFrom merges from specified source
+func (d *ChiDetachedParts) MergeFrom(from *ChiDetachedParts, _type MergeType) *ChiDetachedParts {
+	if from == nil {
+		return d
+	}
+
+	if d == nil {
+		d = NewChiDetachedParts()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if d.Threshold == 0 {
+			d.Threshold = from.Threshold
+		}
+		if d.Interval == 0 {
+			d.Interval = from.Interval
+		}
+		if d.Cleanup == nil {
+			d.Cleanup = from.Cleanup.DeepCopy()
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Threshold != 0 {
+			// Override by non-empty values only
+			d.Threshold = from.Threshold
+		}
+		if from.Interval != 0 {
+			// Override by non-empty values only
+			d.Interval = from.Interval
+		}
+		if from.Cleanup != nil {
+			// Override by non-empty values only
+			d.Cleanup = from.Cleanup.DeepCopy()
+		}
+	}
+
+	return d
+}
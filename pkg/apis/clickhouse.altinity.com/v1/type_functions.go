@@ -0,0 +1,134 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+const (
+	// FunctionTypeExecutable specifies function which runs a script once per data block
+	FunctionTypeExecutable = "executable"
+	// FunctionTypeExecutablePool specifies function which keeps a pool of running scripts
+	FunctionTypeExecutablePool = "executable_pool"
+	// FunctionNameReservedPrefix specifies prefix of names of functions maintained by the operator.
+	// User-defined functions with names starting with the prefix are skipped
+	FunctionNameReservedPrefix = "__chop_"
+)
+
+// ChiFunctions defines functions section of .spec.configuration
+// Provides declarative deployment of user-defined executable functions.
+// Refers to
+// https://clickhouse.com/docs/en/sql-reference/functions/udf
+type ChiFunctions struct {
+	// ScriptsConfigMap specifies name of the ConfigMap with scripts to be mounted as user_scripts_path
+	ScriptsConfigMap string `json:"scriptsConfigMap,omitempty" yaml:"scriptsConfigMap,omitempty"`
+	// Functions specifies list of user-defined executable functions
+	Functions []*ChiFunction `json:"functions,omitempty"        yaml:"functions,omitempty"`
+}
+
+// ChiFunction defines one user-defined executable function
+type ChiFunction struct {
+	Name       string                 `json:"name,omitempty"       yaml:"name,omitempty"`
+	Type       string                 `json:"type,omitempty"       yaml:"type,omitempty"`
+	Command    string                 `json:"command,omitempty"    yaml:"command,omitempty"`
+	Format     string                 `json:"format,omitempty"     yaml:"format,omitempty"`
+	ReturnType string                 `json:"returnType,omitempty" yaml:"returnType,omitempty"`
+	Arguments  []*ChiFunctionArgument `json:"arguments,omitempty"  yaml:"arguments,omitempty"`
+	// Settings specifies additional function settings, such as max_command_execution_time or pool_size
+	Settings map[string]string `json:"settings,omitempty"   yaml:"settings,omitempty"`
+}
+
+// ChiFunctionArgument defines argument of a user-defined executable function
+type ChiFunctionArgument struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// NewChiFunctions creates new ChiFunctions object
+func NewChiFunctions() *ChiFunctions {
+	return new(ChiFunctions)
+}
+
+// HasScriptsConfigMap checks whether scripts ConfigMap is specified
+func (f *ChiFunctions) HasScriptsConfigMap() bool {
+	return f.GetScriptsConfigMap() != ""
+}
+
+// GetScriptsConfigMap gets name of the scripts ConfigMap
+func (f *ChiFunctions) GetScriptsConfigMap() string {
+	if f == nil {
+		return ""
+	}
+	return f.ScriptsConfigMap
+}
+
+// HasFunctions checks whether any functions are specified
+func (f *ChiFunctions) HasFunctions() bool {
+	return len(f.GetFunctions()) > 0
+}
+
+// GetFunctions gets list of functions
+func (f *ChiFunctions) GetFunctions() []*ChiFunction {
+	if f == nil {
+		return nil
+	}
+	return f.Functions
+}
+
+// GetFunction gets function by name
+func (f *ChiFunctions) GetFunction(name string) *ChiFunction {
+	for _, function := range f.GetFunctions() {
+		if function.Name == name {
+			return function
+		}
+	}
+	return nil
+}
+
+// MergeFrom merges from specified source
+func (f *ChiFunctions) MergeFrom(from *ChiFunctions, _type MergeType) *ChiFunctions {
+	if from == nil {
+		return f
+	}
+
+	if f == nil {
+		f = NewChiFunctions()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if f.ScriptsConfigMap == "" {
+			f.ScriptsConfigMap = from.ScriptsConfigMap
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.ScriptsConfigMap != "" {
+			// Override by non-empty values only
+			f.ScriptsConfigMap = from.ScriptsConfigMap
+		}
+	}
+
+	// Functions are merged by name
+	for _, function := range from.Functions {
+		if function == nil {
+			continue
+		}
+		if existing := f.GetFunction(function.Name); existing != nil {
+			if _type == MergeTypeOverrideByNonEmptyValues {
+				*existing = *function.DeepCopy()
+			}
+			continue
+		}
+		f.Functions = append(f.Functions, function.DeepCopy())
+	}
+
+	return f
+}
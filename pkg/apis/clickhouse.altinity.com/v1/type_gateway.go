@@ -0,0 +1,112 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+// ChiGateway defines gateway section of .spec
+// Provides exposure of ClickHouse native protocol via Gateway API routes bound to the referenced Gateway.
+// Refers to
+// https://gateway-api.sigs.k8s.io/guides/tcp/
+type ChiGateway struct {
+	// GatewayRef specifies Gateway the routes are attached to
+	GatewayRef *ChiGatewayRef `json:"gatewayRef,omitempty" yaml:"gatewayRef,omitempty"`
+	// TCP specifies TCPRoute for the native protocol port
+	TCP *ChiGatewayRoute `json:"tcp,omitempty"        yaml:"tcp,omitempty"`
+	// TLS specifies TLSRoute for the secure native protocol port
+	TLS *ChiGatewayRoute `json:"tls,omitempty"        yaml:"tls,omitempty"`
+}
+
+// ChiGatewayRef defines reference to a Gateway
+type ChiGatewayRef struct {
+	Name      string `json:"name,omitempty"      yaml:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
+// ChiGatewayRoute defines one route to be generated
+type ChiGatewayRoute struct {
+	// SectionName specifies name of the Gateway's listener the route is attached to
+	SectionName string `json:"sectionName,omitempty" yaml:"sectionName,omitempty"`
+	// Hostnames specifies SNI hostnames, applicable to TLSRoute only
+	Hostnames []string `json:"hostnames,omitempty"   yaml:"hostnames,omitempty"`
+}
+
+// NewChiGateway creates new ChiGateway object
+func NewChiGateway() *ChiGateway {
+	return new(ChiGateway)
+}
+
+// GetGatewayRef gets reference to the Gateway
+func (g *ChiGateway) GetGatewayRef() *ChiGatewayRef {
+	if g == nil {
+		return nil
+	}
+	return g.GatewayRef
+}
+
+// HasTCP checks whether TCPRoute is requested
+func (g *ChiGateway) HasTCP() bool {
+	return g.IsEnabled() && (g.TCP != nil)
+}
+
+// HasTLS checks whether TLSRoute is requested
+func (g *ChiGateway) HasTLS() bool {
+	return g.IsEnabled() && (g.TLS != nil)
+}
+
+// IsEnabled checks whether any routes are to be generated
+func (g *ChiGateway) IsEnabled() bool {
+	if g == nil {
+		return false
+	}
+	return (g.GatewayRef != nil) && (g.GatewayRef.Name != "") && ((g.TCP != nil) || (g.TLS != nil))
+}
+
+// MergeFrom merges from specified source
+func (g *ChiGateway) MergeFrom(from *ChiGateway, _type MergeType) *ChiGateway {
+	if from == nil {
+		return g
+	}
+
+	if g == nil {
+		g = NewChiGateway()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if g.GatewayRef == nil {
+			g.GatewayRef = from.GatewayRef.DeepCopy()
+		}
+		if g.TCP == nil {
+			g.TCP = from.TCP.DeepCopy()
+		}
+		if g.TLS == nil {
+			g.TLS = from.TLS.DeepCopy()
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.GatewayRef != nil {
+			// Override by non-empty values only
+			g.GatewayRef = from.GatewayRef.DeepCopy()
+		}
+		if from.TCP != nil {
+			// Override by non-empty values only
+			g.TCP = from.TCP.DeepCopy()
+		}
+		if from.TLS != nil {
+			// Override by non-empty values only
+			g.TLS = from.TLS.DeepCopy()
+		}
+	}
+
+	return g
+}
@@ -21,6 +21,16 @@ import (
 	"github.com/altinity/clickhouse-operator/pkg/apis/swversion"
 )
 
+// Values of interserverHTTPHost, which are not custom host names or IP addresses
+const (
+	// InterserverHTTPHostHostname specifies host to be addressed by other replicas with the same hostname as in remote_servers
+	InterserverHTTPHostHostname = "Hostname"
+	// InterserverHTTPHostFQDN specifies host to be addressed by other replicas with pod's FQDN
+	InterserverHTTPHostFQDN = "FQDN"
+	// InterserverHTTPHostPodIP specifies host to be addressed by other replicas with pod's IP address
+	InterserverHTTPHostPodIP = "PodIP"
+)
+
 // ChiHost defines host (a data replica within a shard) of .spec.configuration.clusters[n].shards[m]
 type ChiHost struct {
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
@@ -37,6 +47,20 @@ type ChiHost struct {
 	Settings            *Settings         `json:"settings,omitempty"            yaml:"settings,omitempty"`
 	Files               *Settings         `json:"files,omitempty"               yaml:"files,omitempty"`
 	Templates           *ChiTemplateNames `json:"templates,omitempty"           yaml:"templates,omitempty"`
+	// ServiceType specifies type of host-level Service created w/o ServiceTemplate, Headless by default
+	ServiceType string `json:"serviceType,omitempty" yaml:"serviceType,omitempty"`
+	// Maintenance specifies whether host is under maintenance. Host under maintenance is excluded
+	// from cluster Services and remote_servers and has running queries drained before its StatefulSet is updated
+	Maintenance *StringBool `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+	// StartupPriority specifies priority of the host to be started within its shard.
+	// Hosts having higher priority are (re)started first, hosts having the same priority are started in order
+	StartupPriority int `json:"startupPriority,omitempty" yaml:"startupPriority,omitempty"`
+	// InterserverHTTPHost specifies how the host is addressed by other replicas fetching parts from it.
+	// Either one of Hostname, FQDN, PodIP or custom host name or IP address. Hostname by default
+	InterserverHTTPHost string `json:"interserverHTTPHost,omitempty" yaml:"interserverHTTPHost,omitempty"`
+	// InterserverCredentials specifies credentials replicas authenticate with fetching parts from each other.
+	// Allows to migrate hosts to new credentials one by one
+	InterserverCredentials *ChiInterserverCredentials `json:"interserverCredentials,omitempty" yaml:"interserverCredentials,omitempty"`
 
 	Runtime ChiHostRuntime `json:"-" yaml:"-"`
 }
@@ -52,6 +76,10 @@ type ChiHostRuntime struct {
 	// DesiredStatefulSet is a desired stateful set - reconcile target
 	DesiredStatefulSet *apps.StatefulSet       `json:"-" yaml:"-" testdiff:"ignore"`
 	CHI                *ClickHouseInstallation `json:"-" yaml:"-" testdiff:"ignore"`
+	// SecretsChecksum specifies checksum of referenced Secrets, which restart the host on change
+	SecretsChecksum string `json:"-" yaml:"-"`
+	// SecretsReloadChecksum specifies checksum of referenced Secrets, which reload config of the host on change
+	SecretsReloadChecksum string `json:"-" yaml:"-"`
 }
 
 // GetReconcileAttributes is an ensurer getter
@@ -104,6 +132,38 @@ func (host *ChiHost) InheritTemplatesFrom(shard *ChiShard, replica *ChiReplica,
 	host.Templates.HandleDeprecatedFields()
 }
 
+// InheritPortsFrom inherits ports from specified replica, ports specified by the host explicitly take precedence
+func (host *ChiHost) InheritPortsFrom(replica *ChiReplica) {
+	if replica == nil {
+		return
+	}
+
+	for _, p := range []struct {
+		host    *int32
+		replica int32
+	}{
+		{&host.TCPPort, replica.TCPPort},
+		{&host.TLSPort, replica.TLSPort},
+		{&host.HTTPPort, replica.HTTPPort},
+		{&host.HTTPSPort, replica.HTTPSPort},
+		{&host.InterserverHTTPPort, replica.InterserverHTTPPort},
+	} {
+		if IsPortUnassigned(*p.host) && !IsPortInvalid(p.replica) {
+			*p.host = p.replica
+		}
+	}
+}
+
+// InheritStartupPriorityFrom inherits startup priority from the replica, unless specified by the host explicitly
+func (host *ChiHost) InheritStartupPriorityFrom(replica *ChiReplica) {
+	if replica == nil {
+		return
+	}
+	if host.StartupPriority == 0 {
+		host.StartupPriority = replica.StartupPriority
+	}
+}
+
 func isUnassigned(port int32) bool {
 	return port == PortMayBeAssignedLaterOrLeftUnused
 }
@@ -131,6 +191,19 @@ func (host *ChiHost) MergeFrom(from *ChiHost) {
 	if isUnassigned(host.InterserverHTTPPort) {
 		host.InterserverHTTPPort = from.InterserverHTTPPort
 	}
+	if host.ServiceType == "" {
+		host.ServiceType = from.ServiceType
+	}
+	host.Maintenance = host.Maintenance.MergeFrom(from.Maintenance)
+	if host.StartupPriority == 0 {
+		host.StartupPriority = from.StartupPriority
+	}
+	if host.InterserverHTTPHost == "" {
+		host.InterserverHTTPHost = from.InterserverHTTPHost
+	}
+	if host.InterserverCredentials == nil {
+		host.InterserverCredentials = from.InterserverCredentials.DeepCopy()
+	}
 	host.Templates = host.Templates.MergeFrom(from.Templates, MergeTypeFillEmptyValues)
 	host.Templates.HandleDeprecatedFields()
 }
@@ -162,6 +235,17 @@ func (host *ChiHost) GetServiceTemplate() (*ServiceTemplate, bool) {
 	return host.Runtime.CHI.GetServiceTemplate(name)
 }
 
+// GetExtraServiceTemplates gets templates of extra Services of the host. Unknown templates are skipped
+func (host *ChiHost) GetExtraServiceTemplates() []*ServiceTemplate {
+	var templates []*ServiceTemplate
+	for _, name := range host.Templates.GetReplicaServiceTemplates() {
+		if template, ok := host.Runtime.CHI.GetServiceTemplate(name); ok {
+			templates = append(templates, template)
+		}
+	}
+	return templates
+}
+
 // GetStatefulSetReplicasNum gets stateful set replica num
 func (host *ChiHost) GetStatefulSetReplicasNum(shutdown bool) *int32 {
 	var num int32 = 0
@@ -249,9 +333,51 @@ func (host *ChiHost) WalkVolumeClaimTemplates(f func(template *VolumeClaimTempla
 	host.GetCHI().WalkVolumeClaimTemplates(f)
 }
 
-// IsStopped checks whether host is stopped
+// IsStopped checks whether host is stopped, either along with the whole CHI or along with its cluster
 func (host *ChiHost) IsStopped() bool {
-	return host.GetCHI().IsStopped()
+	return host.GetCHI().IsStopped() || host.GetCluster().IsStopped()
+}
+
+// IsCordoned checks whether host belongs to cordoned cluster
+func (host *ChiHost) IsCordoned() bool {
+	return host.GetCluster().IsCordoned()
+}
+
+// GetStartupPriority gets priority of the host to be started within its shard
+func (host *ChiHost) GetStartupPriority() int {
+	if host == nil {
+		return 0
+	}
+	return host.StartupPriority
+}
+
+// IsUnderMaintenance checks whether host is under maintenance
+func (host *ChiHost) IsUnderMaintenance() bool {
+	if host == nil {
+		return false
+	}
+	return host.Maintenance.IsTrue()
+}
+
+// GetInterserverHTTPHost gets how the host is addressed by other replicas fetching parts from it
+func (host *ChiHost) GetInterserverHTTPHost() string {
+	if (host == nil) || (host.InterserverHTTPHost == "") {
+		return InterserverHTTPHostHostname
+	}
+	return host.InterserverHTTPHost
+}
+
+// GetInterserverCredentials gets interserver credentials of the host
+func (host *ChiHost) GetInterserverCredentials() *ChiInterserverCredentials {
+	if host == nil {
+		return nil
+	}
+	return host.InterserverCredentials
+}
+
+// GetInstancesPerPod gets number of ClickHouse instances run in the pod of the host
+func (host *ChiHost) GetInstancesPerPod() int {
+	return host.GetCluster().GetLayout().GetInstancesPerPod()
 }
 
 // IsNewOne checks whether host is a new one
@@ -336,6 +462,9 @@ func (host *ChiHost) IsSecure() bool {
 	}
 
 	// No personal value - fallback to cluster value
+	if host.GetCluster().IsSecureAuto() {
+		return true
+	}
 	if host.GetCluster().GetSecure().HasValue() {
 		return host.GetCluster().GetSecure().Value()
 	}
@@ -0,0 +1,59 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	core "k8s.io/api/core/v1"
+)
+
+// ChiInterserverCredentials defines interserverCredentials section of hosts
+// Refers to
+// https://clickhouse.com/docs/en/operations/server-configuration-parameters/settings#interserver_http_credentials
+type ChiInterserverCredentials struct {
+	// User specifies <interserver_http_credentials><user>
+	User string `json:"user,omitempty"       yaml:"user,omitempty"`
+	// Password specifies ref to k8s Secret the password is read from
+	Password *SettingSource `json:"password,omitempty"   yaml:"password,omitempty"`
+	// AllowEmpty specifies <interserver_http_credentials><allow_empty>, which lets replicas without credentials
+	// to fetch parts from the host, while other hosts are being migrated to credentials
+	AllowEmpty *StringBool `json:"allowEmpty,omitempty" yaml:"allowEmpty,omitempty"`
+}
+
+// AsSettings returns specified fields as a map of ClickHouse setting path to value
+func (c *ChiInterserverCredentials) AsSettings() map[string]string {
+	m := make(map[string]string)
+	if c == nil {
+		return m
+	}
+	if c.User != "" {
+		m["interserver_http_credentials/user"] = c.User
+	}
+	if c.AllowEmpty.HasValue() {
+		m["interserver_http_credentials/allow_empty"] = c.AllowEmpty.CastToStringTrueFalse(false)
+	}
+	return m
+}
+
+// AsSecretSettings returns specified secret fields as a map of ClickHouse setting path to secret ref
+func (c *ChiInterserverCredentials) AsSecretSettings() map[string]*core.SecretKeySelector {
+	m := make(map[string]*core.SecretKeySelector)
+	if c == nil {
+		return m
+	}
+	if c.Password.HasSecretKeyRef() {
+		m["interserver_http_credentials/password"] = c.Password.GetSecretKeyRef()
+	}
+	return m
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"sort"
+	"strconv"
+)
+
+// logTables lists system log tables of ClickHouse, which can be configured via ChiLogTables
+// Refers to
+// https://clickhouse.com/docs/en/operations/system-tables
+var logTables = []string{
+	"asynchronous_insert_log",
+	"asynchronous_metric_log",
+	"backup_log",
+	"blob_storage_log",
+	"crash_log",
+	"error_log",
+	"filesystem_cache_log",
+	"metric_log",
+	"opentelemetry_span_log",
+	"part_log",
+	"processors_profile_log",
+	"query_log",
+	"query_thread_log",
+	"query_views_log",
+	"s3queue_log",
+	"session_log",
+	"text_log",
+	"trace_log",
+	"zookeeper_log",
+}
+
+// IsValidLogTable checks whether system log table is known
+func IsValidLogTable(name string) bool {
+	for _, table := range logTables {
+		if table == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ChiLogTables defines logTables section of .spec.configuration, specifies system log tables by name, such as query_log.
+// Provides typed access to server sections of system log tables, which otherwise would have to be specified as raw settings or files.
+// Refers to
+// https://clickhouse.com/docs/en/operations/server-configuration-parameters/settings#query-log
+type ChiLogTables map[string]*ChiLogTable
+
+// ChiLogTable defines destination of a system log table
+type ChiLogTable struct {
+	// Enabled specifies whether the log table is written to. Server section of the table is removed in case disabled
+	Enabled *StringBool `json:"enabled,omitempty"                   yaml:"enabled,omitempty"`
+	// Database specifies <database> the log table is created in
+	Database string `json:"database,omitempty"                  yaml:"database,omitempty"`
+	// Table specifies <table> name of the log table
+	Table string `json:"table,omitempty"                     yaml:"table,omitempty"`
+	// Engine specifies <engine> - full engine definition of the log table, such as "ENGINE = MergeTree ORDER BY event_time".
+	// Can not be used along with PartitionBy, OrderBy and TTL
+	Engine string `json:"engine,omitempty"                    yaml:"engine,omitempty"`
+	// PartitionBy specifies <partition_by> - partitioning key of the log table
+	PartitionBy string `json:"partitionBy,omitempty"               yaml:"partitionBy,omitempty"`
+	// OrderBy specifies <order_by> - sorting key of the log table
+	OrderBy string `json:"orderBy,omitempty"                   yaml:"orderBy,omitempty"`
+	// TTL specifies <ttl> - TTL expression of the log table, such as "event_date + INTERVAL 30 DAY DELETE"
+	TTL string `json:"ttl,omitempty"                       yaml:"ttl,omitempty"`
+	// StoragePolicy specifies <storage_policy> of the log table
+	StoragePolicy string `json:"storagePolicy,omitempty"             yaml:"storagePolicy,omitempty"`
+	// FlushIntervalMilliseconds specifies <flush_interval_milliseconds> - interval of flushing data from memory to the table
+	FlushIntervalMilliseconds int `json:"flushIntervalMilliseconds,omitempty" yaml:"flushIntervalMilliseconds,omitempty"`
+}
+
+// NewChiLogTable creates new ChiLogTable object
+func NewChiLogTable() *ChiLogTable {
+	return new(ChiLogTable)
+}
+
+// IsDisabled checks whether the log table is explicitly disabled
+func (t *ChiLogTable) IsDisabled() bool {
+	if t == nil {
+		return false
+	}
+	return t.Enabled.IsFalse()
+}
+
+// HasEngineParts checks whether any of engine parts - partitioning key, sorting key or TTL - is specified
+func (t *ChiLogTable) HasEngineParts() bool {
+	if t == nil {
+		return false
+	}
+	return (t.PartitionBy != "") || (t.OrderBy != "") || (t.TTL != "")
+}
+
+// Names gets sorted names of the log tables
+func (tables ChiLogTables) Names() []string {
+	var names []string
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AsSettings returns specified fields as a map of ClickHouse setting path to value
+func (tables ChiLogTables) AsSettings() map[string]*Setting {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	m := make(map[string]*Setting)
+	for name, table := range tables {
+		if table == nil {
+			continue
+		}
+		if table.IsDisabled() {
+			// Removed section disables the log table
+			m[name] = NewSettingScalar("").SetAttribute("remove", "1")
+			continue
+		}
+		for tag, value := range map[string]string{
+			"database":       table.Database,
+			"table":          table.Table,
+			"engine":         table.Engine,
+			"partition_by":   table.PartitionBy,
+			"order_by":       table.OrderBy,
+			"ttl":            table.TTL,
+			"storage_policy": table.StoragePolicy,
+		} {
+			if value != "" {
+				m[name+"/"+tag] = NewSettingScalar(value)
+			}
+		}
+		if table.FlushIntervalMilliseconds > 0 {
+			m[name+"/flush_interval_milliseconds"] = NewSettingScalar(strconv.Itoa(table.FlushIntervalMilliseconds))
+		}
+	}
+	return m
+}
+
+// MergeFrom merges from specified source
+func (tables ChiLogTables) MergeFrom(from ChiLogTables, _type MergeType) ChiLogTables {
+	if len(from) == 0 {
+		return tables
+	}
+
+	if tables == nil {
+		tables = make(ChiLogTables)
+	}
+
+	for name, table := range from {
+		tables[name] = tables[name].MergeFrom(table, _type)
+	}
+
+	return tables
+}
+
+// MergeFrom merges from specified source
+func (t *ChiLogTable) MergeFrom(from *ChiLogTable, _type MergeType) *ChiLogTable {
+	if from == nil {
+		return t
+	}
+
+	if t == nil {
+		t = NewChiLogTable()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if t.Database == "" {
+			t.Database = from.Database
+		}
+		if t.Table == "" {
+			t.Table = from.Table
+		}
+		if t.Engine == "" {
+			t.Engine = from.Engine
+		}
+		if t.PartitionBy == "" {
+			t.PartitionBy = from.PartitionBy
+		}
+		if t.OrderBy == "" {
+			t.OrderBy = from.OrderBy
+		}
+		if t.TTL == "" {
+			t.TTL = from.TTL
+		}
+		if t.StoragePolicy == "" {
+			t.StoragePolicy = from.StoragePolicy
+		}
+		if t.FlushIntervalMilliseconds == 0 {
+			t.FlushIntervalMilliseconds = from.FlushIntervalMilliseconds
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Database != "" {
+			// Override by non-empty values only
+			t.Database = from.Database
+		}
+		if from.Table != "" {
+			// Override by non-empty values only
+			t.Table = from.Table
+		}
+		if from.Engine != "" {
+			// Override by non-empty values only
+			t.Engine = from.Engine
+		}
+		if from.PartitionBy != "" {
+			// Override by non-empty values only
+			t.PartitionBy = from.PartitionBy
+		}
+		if from.OrderBy != "" {
+			// Override by non-empty values only
+			t.OrderBy = from.OrderBy
+		}
+		if from.TTL != "" {
+			// Override by non-empty values only
+			t.TTL = from.TTL
+		}
+		if from.StoragePolicy != "" {
+			// Override by non-empty values only
+			t.StoragePolicy = from.StoragePolicy
+		}
+		if from.FlushIntervalMilliseconds != 0 {
+			// Override by non-empty values only
+			t.FlushIntervalMilliseconds = from.FlushIntervalMilliseconds
+		}
+	}
+	t.Enabled = t.Enabled.MergeFrom(from.Enabled)
+
+	return t
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Log formats available for ChiLogger
+const (
+	LoggerFormatText = "text"
+	LoggerFormatJSON = "json"
+)
+
+// loggerLevels lists log levels supported by ClickHouse
+// Refers to
+// https://github.com/pocoproject/poco/blob/devel/Foundation/include/Poco/Logger.h
+var loggerLevels = []string{
+	"none",
+	"fatal",
+	"critical",
+	"error",
+	"warning",
+	"notice",
+	"information",
+	"debug",
+	"trace",
+	"test",
+}
+
+// ChiLogger defines logger section of .spec.configuration
+// Provides typed access to <logger> server section, which otherwise would have to be specified as raw settings.
+// Refers to
+// https://clickhouse.com/docs/en/operations/server-configuration-parameters/settings#logger
+type ChiLogger struct {
+	// Level specifies <logger><level>
+	Level string `json:"level,omitempty"   yaml:"level,omitempty"`
+	// Size specifies <logger><size> - size of the log file to be rotated at, such as "1000M"
+	Size string `json:"size,omitempty"    yaml:"size,omitempty"`
+	// Count specifies <logger><count> - number of rotated log files to keep
+	Count int `json:"count,omitempty"   yaml:"count,omitempty"`
+	// Console specifies <logger><console> - whether to log to console
+	Console *StringBool `json:"console,omitempty" yaml:"console,omitempty"`
+	// Format specifies log format, either text or json
+	Format string `json:"format,omitempty"  yaml:"format,omitempty"`
+}
+
+// NewChiLogger creates new ChiLogger object
+func NewChiLogger() *ChiLogger {
+	return new(ChiLogger)
+}
+
+// IsValidLoggerLevel checks whether log level is supported
+func IsValidLoggerLevel(level string) bool {
+	for _, l := range loggerLevels {
+		if strings.EqualFold(l, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidLoggerFormat checks whether log format is supported
+func IsValidLoggerFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case LoggerFormatText, LoggerFormatJSON:
+		return true
+	}
+	return false
+}
+
+// IsEmpty checks whether nothing is specified
+func (l *ChiLogger) IsEmpty() bool {
+	return len(l.AsSettings()) == 0
+}
+
+// AsSettings returns specified fields as a map of ClickHouse setting path to value
+func (l *ChiLogger) AsSettings() map[string]string {
+	if l == nil {
+		return nil
+	}
+
+	m := make(map[string]string)
+	if l.Level != "" {
+		m["logger/level"] = strings.ToLower(l.Level)
+	}
+	if l.Size != "" {
+		m["logger/size"] = l.Size
+	}
+	if l.Count > 0 {
+		m["logger/count"] = strconv.Itoa(l.Count)
+	}
+	if l.Console.HasValue() {
+		m["logger/console"] = l.Console.CastTo01(false)
+	}
+	if strings.EqualFold(l.Format, LoggerFormatJSON) {
+		m["logger/formatting/type"] = LoggerFormatJSON
+	}
+	return m
+}
+
+// MergeFrom merges from specified source
+func (l *ChiLogger) MergeFrom(from *ChiLogger, _type MergeType) *ChiLogger {
+	if from == nil {
+		return l
+	}
+
+	if l == nil {
+		l = NewChiLogger()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if l.Level == "" {
+			l.Level = from.Level
+		}
+		if l.Size == "" {
+			l.Size = from.Size
+		}
+		if l.Count == 0 {
+			l.Count = from.Count
+		}
+		if l.Format == "" {
+			l.Format = from.Format
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Level != "" {
+			// Override by non-empty values only
+			l.Level = from.Level
+		}
+		if from.Size != "" {
+			// Override by non-empty values only
+			l.Size = from.Size
+		}
+		if from.Count != 0 {
+			// Override by non-empty values only
+			l.Count = from.Count
+		}
+		if from.Format != "" {
+			// Override by non-empty values only
+			l.Format = from.Format
+		}
+	}
+	l.Console = l.Console.MergeFrom(from.Console)
+
+	return l
+}
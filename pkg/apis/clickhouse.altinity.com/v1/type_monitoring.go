@@ -0,0 +1,119 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+// ChiMonitoring defines monitoring section of .spec
+type ChiMonitoring struct {
+	ServiceMonitor *ChiServiceMonitor `json:"serviceMonitor,omitempty" yaml:"serviceMonitor,omitempty"`
+}
+
+// ChiServiceMonitor defines Prometheus Operator ServiceMonitor of the operator's metrics exporter, shared by all CHIs.
+// ServiceMonitor scrapes metrics of all CHIs published by the exporter, settings of all CHIs having it enabled are combined.
+type ChiServiceMonitor struct {
+	// Enabled specifies whether ServiceMonitor is generated
+	Enabled *StringBool `json:"enabled,omitempty"       yaml:"enabled,omitempty"`
+	// Interval specifies scrape interval, such as 30s. Prometheus default is used in case not specified
+	Interval string `json:"interval,omitempty"      yaml:"interval,omitempty"`
+	// ScrapeTimeout specifies scrape timeout, such as 10s. Prometheus default is used in case not specified
+	ScrapeTimeout string `json:"scrapeTimeout,omitempty" yaml:"scrapeTimeout,omitempty"`
+	// Labels specifies labels of ServiceMonitor, which are to match serviceMonitorSelector of Prometheus
+	Labels map[string]string `json:"labels,omitempty"        yaml:"labels,omitempty"`
+}
+
+// NewChiMonitoring creates new ChiMonitoring object
+func NewChiMonitoring() *ChiMonitoring {
+	return new(ChiMonitoring)
+}
+
+// NewChiServiceMonitor creates new ChiServiceMonitor object
+func NewChiServiceMonitor() *ChiServiceMonitor {
+	return new(ChiServiceMonitor)
+}
+
+// GetServiceMonitor gets ServiceMonitor
+func (m *ChiMonitoring) GetServiceMonitor() *ChiServiceMonitor {
+	if m == nil {
+		return nil
+	}
+	return m.ServiceMonitor
+}
+
+// IsServiceMonitorEnabled checks whether ServiceMonitor is to be generated
+func (m *ChiMonitoring) IsServiceMonitorEnabled() bool {
+	return m.GetServiceMonitor().IsEnabled()
+}
+
+// IsEnabled checks whether ServiceMonitor is to be generated
+func (s *ChiServiceMonitor) IsEnabled() bool {
+	if s == nil {
+		return false
+	}
+	return s.Enabled.IsTrue()
+}
+
+// MergeFrom merges from specified source
+func (m *ChiMonitoring) MergeFrom(from *ChiMonitoring, _type MergeType) *ChiMonitoring {
+	if from == nil {
+		return m
+	}
+
+	if m == nil {
+		m = NewChiMonitoring()
+	}
+
+	m.ServiceMonitor = m.ServiceMonitor.MergeFrom(from.ServiceMonitor, _type)
+
+	return m
+}
+
+// MergeFrom merges from specified source
+func (s *ChiServiceMonitor) MergeFrom(from *ChiServiceMonitor, _type MergeType) *ChiServiceMonitor {
+	if from == nil {
+		return s
+	}
+
+	if s == nil {
+		s = NewChiServiceMonitor()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if s.Interval == "" {
+			s.Interval = from.Interval
+		}
+		if s.ScrapeTimeout == "" {
+			s.ScrapeTimeout = from.ScrapeTimeout
+		}
+		if len(s.Labels) == 0 {
+			s.Labels = from.DeepCopy().Labels
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Interval != "" {
+			// Override by non-empty values only
+			s.Interval = from.Interval
+		}
+		if from.ScrapeTimeout != "" {
+			// Override by non-empty values only
+			s.ScrapeTimeout = from.ScrapeTimeout
+		}
+		if len(from.Labels) > 0 {
+			// Override by non-empty values only
+			s.Labels = from.DeepCopy().Labels
+		}
+	}
+	s.Enabled = s.Enabled.MergeFrom(from.Enabled)
+
+	return s
+}
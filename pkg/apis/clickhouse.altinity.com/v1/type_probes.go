@@ -0,0 +1,167 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import "strings"
+
+// Checks performed by probes generated by the operator
+const (
+	// ProbeCheckPing specifies probe to request /ping HTTP endpoint of ClickHouse
+	ProbeCheckPing = "Ping"
+	// ProbeCheckSQL specifies probe to run check script shipped by the operator, which queries ClickHouse via clickhouse-client
+	ProbeCheckSQL = "SQL"
+)
+
+// ChiProbes defines probes of ClickHouse container generated by the operator
+type ChiProbes struct {
+	Readiness *ChiProbe `json:"readiness,omitempty" yaml:"readiness,omitempty"`
+	Liveness  *ChiProbe `json:"liveness,omitempty"  yaml:"liveness,omitempty"`
+	// Startup specifies startup probe, which is not generated unless specified
+	Startup *ChiProbe `json:"startup,omitempty"   yaml:"startup,omitempty"`
+}
+
+// ChiProbe defines probe generated by the operator. Non-zero values override values of the generated probe
+type ChiProbe struct {
+	// Check specifies check performed by the probe - Ping or SQL
+	Check               string `json:"check,omitempty"               yaml:"check,omitempty"`
+	InitialDelaySeconds int    `json:"initialDelaySeconds,omitempty" yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int    `json:"periodSeconds,omitempty"       yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds      int    `json:"timeoutSeconds,omitempty"      yaml:"timeoutSeconds,omitempty"`
+	SuccessThreshold    int    `json:"successThreshold,omitempty"    yaml:"successThreshold,omitempty"`
+	FailureThreshold    int    `json:"failureThreshold,omitempty"    yaml:"failureThreshold,omitempty"`
+}
+
+// NewChiProbes creates new ChiProbes object
+func NewChiProbes() *ChiProbes {
+	return new(ChiProbes)
+}
+
+// NewChiProbe creates new ChiProbe object
+func NewChiProbe() *ChiProbe {
+	return new(ChiProbe)
+}
+
+// GetReadiness gets readiness probe
+func (p *ChiProbes) GetReadiness() *ChiProbe {
+	if p == nil {
+		return nil
+	}
+	return p.Readiness
+}
+
+// GetLiveness gets liveness probe
+func (p *ChiProbes) GetLiveness() *ChiProbe {
+	if p == nil {
+		return nil
+	}
+	return p.Liveness
+}
+
+// GetStartup gets startup probe
+func (p *ChiProbes) GetStartup() *ChiProbe {
+	if p == nil {
+		return nil
+	}
+	return p.Startup
+}
+
+// GetCheck gets check performed by the probe, Ping by default
+func (p *ChiProbe) GetCheck() string {
+	if p == nil {
+		return ProbeCheckPing
+	}
+	switch strings.ToLower(p.Check) {
+	case strings.ToLower(ProbeCheckSQL):
+		return ProbeCheckSQL
+	}
+	return ProbeCheckPing
+}
+
+// MergeFrom merges from specified source
+func (p *ChiProbes) MergeFrom(from *ChiProbes, _type MergeType) *ChiProbes {
+	if from == nil {
+		return p
+	}
+
+	if p == nil {
+		p = NewChiProbes()
+	}
+
+	p.Readiness = p.Readiness.MergeFrom(from.Readiness, _type)
+	p.Liveness = p.Liveness.MergeFrom(from.Liveness, _type)
+	p.Startup = p.Startup.MergeFrom(from.Startup, _type)
+
+	return p
+}
+
+// MergeFrom merges from specified source
+func (p *ChiProbe) MergeFrom(from *ChiProbe, _type MergeType) *ChiProbe {
+	if from == nil {
+		return p
+	}
+
+	if p == nil {
+		p = NewChiProbe()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if p.Check == "" {
+			p.Check = from.Check
+		}
+		if p.InitialDelaySeconds == 0 {
+			p.InitialDelaySeconds = from.InitialDelaySeconds
+		}
+		if p.PeriodSeconds == 0 {
+			p.PeriodSeconds = from.PeriodSeconds
+		}
+		if p.TimeoutSeconds == 0 {
+			p.TimeoutSeconds = from.TimeoutSeconds
+		}
+		if p.SuccessThreshold == 0 {
+			p.SuccessThreshold = from.SuccessThreshold
+		}
+		if p.FailureThreshold == 0 {
+			p.FailureThreshold = from.FailureThreshold
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Check != "" {
+			// Override by non-empty values only
+			p.Check = from.Check
+		}
+		if from.InitialDelaySeconds != 0 {
+			// Override by non-empty values only
+			p.InitialDelaySeconds = from.InitialDelaySeconds
+		}
+		if from.PeriodSeconds != 0 {
+			// Override by non-empty values only
+			p.PeriodSeconds = from.PeriodSeconds
+		}
+		if from.TimeoutSeconds != 0 {
+			// Override by non-empty values only
+			p.TimeoutSeconds = from.TimeoutSeconds
+		}
+		if from.SuccessThreshold != 0 {
+			// Override by non-empty values only
+			p.SuccessThreshold = from.SuccessThreshold
+		}
+		if from.FailureThreshold != 0 {
+			// Override by non-empty values only
+			p.FailureThreshold = from.FailureThreshold
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Statuses of ClickHouseQuery
+const (
+	QueryStatusPending   = "Pending"
+	QueryStatusRunning   = "Running"
+	QueryStatusCompleted = "Completed"
+	QueryStatusFailed    = "Failed"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseQuery defines DDL query run with 'ON CLUSTER' clause over a cluster of a ClickHouseInstallation
+type ClickHouseQuery struct {
+	meta.TypeMeta   `json:",inline"            yaml:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	Spec   ChqSpec    `json:"spec"             yaml:"spec"`
+	Status *ChqStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseQueryList defines a list of ClickHouseQuery resources
+type ClickHouseQueryList struct {
+	meta.TypeMeta `json:",inline"  yaml:",inline"`
+	meta.ListMeta `json:"metadata" yaml:"metadata"`
+	Items         []ClickHouseQuery `json:"items" yaml:"items"`
+}
+
+// ChqSpec defines spec section of ClickHouseQuery resource
+type ChqSpec struct {
+	// CHI specifies name of the ClickHouseInstallation the query is run on, located in the same namespace
+	CHI string `json:"chi"               yaml:"chi"`
+	// Cluster specifies name of the cluster the query is run over. Defaults to the first cluster of the CHI
+	Cluster string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	// Query specifies DDL query. 'ON CLUSTER' clause is added in case the query has none
+	Query string `json:"query"             yaml:"query"`
+}
+
+// ChqHostError defines exception the query is completed with on a host
+type ChqHostError struct {
+	Host  string `json:"host"  yaml:"host"`
+	Error string `json:"error" yaml:"error"`
+}
+
+// ChqStatus defines status section of ClickHouseQuery resource
+type ChqStatus struct {
+	Status             string `json:"status,omitempty"             yaml:"status,omitempty"`
+	Error              string `json:"error,omitempty"              yaml:"error,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty" yaml:"observedGeneration,omitempty"`
+	// Cluster specifies cluster the query is run over
+	Cluster string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	// Entry specifies entry of the distributed DDL queue, such as query-0000000042
+	Entry string `json:"entry,omitempty" yaml:"entry,omitempty"`
+	// Finished lists hosts which completed the query successfully
+	Finished []string `json:"finished,omitempty" yaml:"finished,omitempty"`
+	// Failed lists hosts which completed the query with an exception
+	Failed []ChqHostError `json:"failed,omitempty" yaml:"failed,omitempty"`
+	// Stragglers lists hosts which did not complete the query in time
+	Stragglers []string `json:"stragglers,omitempty" yaml:"stragglers,omitempty"`
+}
+
+// EnsureStatus ensures status
+func (chq *ClickHouseQuery) EnsureStatus() *ChqStatus {
+	if chq == nil {
+		return nil
+	}
+	if chq.Status == nil {
+		chq.Status = &ChqStatus{}
+	}
+	return chq.Status
+}
+
+// GetStatus gets status
+func (chq *ClickHouseQuery) GetStatus() *ChqStatus {
+	if chq == nil {
+		return nil
+	}
+	return chq.Status
+}
+
+// IsDone checks whether the current spec of the query is run already, successfully or not.
+// Query is not idempotent, so it is run once per generation
+func (chq *ClickHouseQuery) IsDone() bool {
+	status := chq.GetStatus()
+	if (status == nil) || (status.ObservedGeneration != chq.Generation) {
+		return false
+	}
+	return (status.Status == QueryStatusCompleted) || (status.Status == QueryStatusFailed)
+}
+
+// IsRunning checks whether the current spec of the query is being run or was being run when interrupted.
+// Query, which was being run, may have been completed by the cluster already, thus it is not re-run
+func (chq *ClickHouseQuery) IsRunning() bool {
+	status := chq.GetStatus()
+	if (status == nil) || (status.ObservedGeneration != chq.Generation) {
+		return false
+	}
+	return status.Status == QueryStatusRunning
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+// ChiSecurity defines security section of .spec.configuration
+// Provides typed access to commonly used security-related server settings and profile limits,
+// which otherwise would have to be specified as raw settings.
+type ChiSecurity struct {
+	// RemoteURLAllowHosts specifies <remote_url_allow_hosts> server section
+	RemoteURLAllowHosts *RemoteURLAllowHosts `json:"remoteURLAllowHosts,omitempty" yaml:"remoteURLAllowHosts,omitempty"`
+	// QueryComplexity specifies query complexity limits applied to the default profile
+	QueryComplexity *QueryComplexity `json:"queryComplexity,omitempty"     yaml:"queryComplexity,omitempty"`
+	// ListenBacklog specifies <listen_backlog> server setting
+	ListenBacklog int `json:"listenBacklog,omitempty"       yaml:"listenBacklog,omitempty"`
+}
+
+// RemoteURLAllowHosts defines hosts allowed to be used in URL-related storage engines and table functions
+// Refers to
+// https://clickhouse.com/docs/en/operations/server-configuration-parameters/settings#remote_url_allow_hosts
+type RemoteURLAllowHosts struct {
+	Hosts       []string `json:"hosts,omitempty"       yaml:"hosts,omitempty"`
+	HostRegexps []string `json:"hostRegexps,omitempty" yaml:"hostRegexps,omitempty"`
+}
+
+// QueryComplexity defines query complexity restrictions
+// Refers to
+// https://clickhouse.com/docs/en/operations/settings/query-complexity
+type QueryComplexity struct {
+	MaxQuerySize           int64 `json:"maxQuerySize,omitempty"           yaml:"maxQuerySize,omitempty"`
+	MaxASTDepth            int64 `json:"maxASTDepth,omitempty"            yaml:"maxASTDepth,omitempty"`
+	MaxASTElements         int64 `json:"maxASTElements,omitempty"         yaml:"maxASTElements,omitempty"`
+	MaxExpandedASTElements int64 `json:"maxExpandedASTElements,omitempty" yaml:"maxExpandedASTElements,omitempty"`
+	MaxExecutionTime       int64 `json:"maxExecutionTime,omitempty"       yaml:"maxExecutionTime,omitempty"`
+	MaxMemoryUsage         int64 `json:"maxMemoryUsage,omitempty"         yaml:"maxMemoryUsage,omitempty"`
+	MaxResultRows          int64 `json:"maxResultRows,omitempty"          yaml:"maxResultRows,omitempty"`
+	MaxRowsToRead          int64 `json:"maxRowsToRead,omitempty"          yaml:"maxRowsToRead,omitempty"`
+}
+
+// NewChiSecurity creates new ChiSecurity object
+func NewChiSecurity() *ChiSecurity {
+	return new(ChiSecurity)
+}
+
+// NewRemoteURLAllowHosts creates new RemoteURLAllowHosts object
+func NewRemoteURLAllowHosts() *RemoteURLAllowHosts {
+	return new(RemoteURLAllowHosts)
+}
+
+// NewQueryComplexity creates new QueryComplexity object
+func NewQueryComplexity() *QueryComplexity {
+	return new(QueryComplexity)
+}
+
+// GetRemoteURLAllowHosts gets remote URL allow hosts
+func (s *ChiSecurity) GetRemoteURLAllowHosts() *RemoteURLAllowHosts {
+	if s == nil {
+		return nil
+	}
+	return s.RemoteURLAllowHosts
+}
+
+// GetQueryComplexity gets query complexity
+func (s *ChiSecurity) GetQueryComplexity() *QueryComplexity {
+	if s == nil {
+		return nil
+	}
+	return s.QueryComplexity
+}
+
+// HasListenBacklog checks whether listen backlog is specified
+func (s *ChiSecurity) HasListenBacklog() bool {
+	if s == nil {
+		return false
+	}
+	return s.ListenBacklog > 0
+}
+
+// GetListenBacklog gets listen backlog
+func (s *ChiSecurity) GetListenBacklog() int {
+	if s == nil {
+		return 0
+	}
+	return s.ListenBacklog
+}
+
+// HasServerSettings checks whether any server-level (config.d) settings are specified
+func (s *ChiSecurity) HasServerSettings() bool {
+	return !s.GetRemoteURLAllowHosts().IsEmpty() || s.HasListenBacklog()
+}
+
+// HasProfileSettings checks whether any profile-level (users.d) settings are specified
+func (s *ChiSecurity) HasProfileSettings() bool {
+	return !s.GetQueryComplexity().IsEmpty()
+}
+
+// MergeFrom merges from specified source
+func (s *ChiSecurity) MergeFrom(from *ChiSecurity, _type MergeType) *ChiSecurity {
+	if from == nil {
+		return s
+	}
+
+	if s == nil {
+		s = NewChiSecurity()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if s.ListenBacklog == 0 {
+			s.ListenBacklog = from.ListenBacklog
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.ListenBacklog != 0 {
+			// Override by non-empty values only
+			s.ListenBacklog = from.ListenBacklog
+		}
+	}
+
+	s.RemoteURLAllowHosts = s.RemoteURLAllowHosts.MergeFrom(from.RemoteURLAllowHosts, _type)
+	s.QueryComplexity = s.QueryComplexity.MergeFrom(from.QueryComplexity, _type)
+
+	return s
+}
+
+// IsEmpty checks whether no hosts are specified
+func (h *RemoteURLAllowHosts) IsEmpty() bool {
+	if h == nil {
+		return true
+	}
+	return (len(h.Hosts) == 0) && (len(h.HostRegexps) == 0)
+}
+
+// MergeFrom merges from specified source
+func (h *RemoteURLAllowHosts) MergeFrom(from *RemoteURLAllowHosts, _type MergeType) *RemoteURLAllowHosts {
+	if from == nil {
+		return h
+	}
+
+	if h == nil {
+		h = NewRemoteURLAllowHosts()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if len(h.Hosts) == 0 {
+			h.Hosts = append([]string{}, from.Hosts...)
+		}
+		if len(h.HostRegexps) == 0 {
+			h.HostRegexps = append([]string{}, from.HostRegexps...)
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if len(from.Hosts) > 0 {
+			// Override by non-empty values only
+			h.Hosts = append([]string{}, from.Hosts...)
+		}
+		if len(from.HostRegexps) > 0 {
+			// Override by non-empty values only
+			h.HostRegexps = append([]string{}, from.HostRegexps...)
+		}
+	}
+
+	return h
+}
+
+// IsEmpty checks whether no limits are specified
+func (q *QueryComplexity) IsEmpty() bool {
+	return len(q.AsMap()) == 0
+}
+
+// AsMap returns specified limits as a map of ClickHouse setting name to value
+func (q *QueryComplexity) AsMap() map[string]int64 {
+	if q == nil {
+		return nil
+	}
+
+	m := make(map[string]int64)
+	for name, value := range map[string]int64{
+		"max_query_size":            q.MaxQuerySize,
+		"max_ast_depth":             q.MaxASTDepth,
+		"max_ast_elements":          q.MaxASTElements,
+		"max_expanded_ast_elements": q.MaxExpandedASTElements,
+		"max_execution_time":        q.MaxExecutionTime,
+		"max_memory_usage":          q.MaxMemoryUsage,
+		"max_result_rows":           q.MaxResultRows,
+		"max_rows_to_read":          q.MaxRowsToRead,
+	} {
+		if value > 0 {
+			m[name] = value
+		}
+	}
+	return m
+}
+
+// MergeFrom merges from specified source
+func (q *QueryComplexity) MergeFrom(from *QueryComplexity, _type MergeType) *QueryComplexity {
+	if from == nil {
+		return q
+	}
+
+	if q == nil {
+		q = NewQueryComplexity()
+	}
+
+	merge := func(dst *int64, src int64) {
+		switch _type {
+		case MergeTypeFillEmptyValues:
+			if *dst == 0 {
+				*dst = src
+			}
+		case MergeTypeOverrideByNonEmptyValues:
+			if src != 0 {
+				// Override by non-empty values only
+				*dst = src
+			}
+		}
+	}
+
+	merge(&q.MaxQuerySize, from.MaxQuerySize)
+	merge(&q.MaxASTDepth, from.MaxASTDepth)
+	merge(&q.MaxASTElements, from.MaxASTElements)
+	merge(&q.MaxExpandedASTElements, from.MaxExpandedASTElements)
+	merge(&q.MaxExecutionTime, from.MaxExecutionTime)
+	merge(&q.MaxMemoryUsage, from.MaxMemoryUsage)
+	merge(&q.MaxResultRows, from.MaxResultRows)
+	merge(&q.MaxRowsToRead, from.MaxRowsToRead)
+
+	return q
+}
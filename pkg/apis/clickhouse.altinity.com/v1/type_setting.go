@@ -240,6 +240,10 @@ func (s *Setting) FetchDataSourceAddress(defaultNamespace string, parseScalarStr
 			return s.parseDataSourceAddress(s.String(), defaultNamespace)
 		}
 	case SettingTypeSource:
+		if !s.HasSecretKeyRef() {
+			// ConfigMap refs are not addressed as secrets
+			return ObjectAddress{}, fmt.Errorf("%w - no secret ref", ErrDataSourceAddressHasIncorrectFormat)
+		}
 		// Fetch k8s address of the field from the source ref
 		name, key := s.GetNameKey()
 		return ObjectAddress{
@@ -49,6 +49,22 @@ func (s *SettingSource) HasSecretKeyRef() bool {
 	return s.GetSecretKeyRef() != nil
 }
 
+// GetConfigMapKeyRef gets ConfigMapKeySelector (typically named as ConfigMapKeyRef) or nil
+func (s *SettingSource) GetConfigMapKeyRef() *core.ConfigMapKeySelector {
+	if s == nil {
+		return nil
+	}
+	if s.ValueFrom == nil {
+		return nil
+	}
+	return s.ValueFrom.ConfigMapKeyRef
+}
+
+// HasConfigMapKeyRef checks whether ConfigMapKeySelector (typically named as ConfigMapKeyRef) is available
+func (s *SettingSource) HasConfigMapKeyRef() bool {
+	return s.GetConfigMapKeyRef() != nil
+}
+
 // HasValue checks whether SettingSource has no value
 func (s *SettingSource) HasValue() bool {
 	if s == nil {
@@ -57,7 +73,7 @@ func (s *SettingSource) HasValue() bool {
 	if s.ValueFrom == nil {
 		return false
 	}
-	return s.HasSecretKeyRef()
+	return s.HasSecretKeyRef() || s.HasConfigMapKeyRef()
 }
 
 // NewSettingSource makes new source Setting
@@ -137,3 +153,20 @@ func (s *Setting) HasSecretKeyRef() bool {
 
 	return s.GetSecretKeyRef() != nil
 }
+
+// GetConfigMapKeyRef gets ConfigMapKeySelector (typically named as ConfigMapKeyRef) or nil
+func (s *Setting) GetConfigMapKeyRef() *core.ConfigMapKeySelector {
+	if s == nil {
+		return nil
+	}
+	if !s.IsSource() {
+		return nil
+	}
+
+	return s.src.GetConfigMapKeyRef()
+}
+
+// HasConfigMapKeyRef checks whether ConfigMapKeySelector (typically named as ConfigMapKeyRef) is available
+func (s *Setting) HasConfigMapKeyRef() bool {
+	return s.GetConfigMapKeyRef() != nil
+}
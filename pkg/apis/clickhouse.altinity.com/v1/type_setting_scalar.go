@@ -19,6 +19,7 @@ import (
 	"math"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 // NewSettingScalar makes new scalar Setting
@@ -107,6 +108,18 @@ func (s *Setting) IsScalar() bool {
 	return s.Type() == SettingTypeScalar
 }
 
+// SettingFragmentRefPrefix specifies prefix of scalar setting value, which references named config fragment,
+// such as '@fragment:kafka_common'
+const SettingFragmentRefPrefix = "@fragment:"
+
+// FragmentRef gets name of config fragment the setting references, if any
+func (s *Setting) FragmentRef() (string, bool) {
+	if !s.IsScalar() || !strings.HasPrefix(s.ScalarString(), SettingFragmentRefPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(s.ScalarString(), SettingFragmentRefPrefix)), true
+}
+
 // ScalarString gets string scalar value of a setting
 func (s *Setting) ScalarString() string {
 	if s == nil {
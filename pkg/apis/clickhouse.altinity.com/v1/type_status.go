@@ -15,9 +15,14 @@
 package v1
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 	"sync"
 
+	apiMeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"github.com/altinity/clickhouse-operator/pkg/util"
 	"github.com/altinity/clickhouse-operator/pkg/version"
 )
@@ -26,6 +31,8 @@ const (
 	maxActions = 10
 	maxErrors  = 10
 	maxTaskIDs = 10
+	// maxSchemaPlans specifies how many hosts schema plans are kept in status for
+	maxSchemaPlans = 10
 )
 
 // Possible CHI statuses
@@ -36,6 +43,45 @@ const (
 	StatusTerminating = "Terminating"
 )
 
+// States of keeper migration
+const (
+	KeeperMigrationStateInProgress = "InProgress"
+	KeeperMigrationStateCompleted  = "Completed"
+	KeeperMigrationStateFailed     = "Failed"
+)
+
+// Phases of host reconcile
+const (
+	HostPhasePending     = "Pending"
+	HostPhaseReconciling = "Reconciling"
+	HostPhaseReady       = "Ready"
+	HostPhaseFailed      = "Failed"
+	HostPhaseCordoned    = "Cordoned"
+)
+
+// Types of CHI status conditions
+const (
+	// ConditionTypeKeeperHealthy reports health of ClickHouseKeeperInstallation ensembles the CHI is wired to
+	ConditionTypeKeeperHealthy = "KeeperHealthy"
+	// ConditionTypeDetachedPartsBelowThreshold reports whether all hosts have detached parts below the threshold
+	ConditionTypeDetachedPartsBelowThreshold = "DetachedPartsBelowThreshold"
+	// ConditionTypeDegraded reports reconcile of the CHI halted due to hosts failed health check in a row
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypeUpgradeAvailable reports whether newer patch versions of ClickHouse versions clusters are pinned to are available
+	ConditionTypeUpgradeAvailable = "UpgradeAvailable"
+	// ConditionTypeDDLQueueHealthy reports whether distributed DDL queue has no entries stuck on hosts
+	ConditionTypeDDLQueueHealthy = "DDLQueueHealthy"
+)
+
+// Types of host status conditions
+const (
+	// HostConditionTypeVolumesResized reports whether PVCs of the host are expanded to the storage requested by VolumeClaimTemplates
+	HostConditionTypeVolumesResized = "VolumesResized"
+	// HostConditionTypeRollingRestarted reports the host is restarted by the rolling restart in progress,
+	// so the rolling restart resumed after interruption does not restart the host again
+	HostConditionTypeRollingRestarted = "RollingRestarted"
+)
+
 // ChiStatus defines status section of ClickHouseInstallation resource.
 //
 // Note: application level reads and writes to ChiStatus fields should be done through synchronized getter/setter functions.
@@ -66,18 +112,147 @@ type ChiStatus struct {
 	HostsCompletedCount    int                     `json:"hostsCompleted,omitempty"         yaml:"hostsCompleted,omitempty"`
 	HostsDeletedCount      int                     `json:"hostsDeleted,omitempty"           yaml:"hostsDeleted,omitempty"`
 	HostsDeleteCount       int                     `json:"hostsDelete,omitempty"            yaml:"hostsDelete,omitempty"`
+	HostsReadyCount        int                     `json:"hostsReady,omitempty"             yaml:"hostsReady,omitempty"`
+	Ready                  string                  `json:"ready,omitempty"                  yaml:"ready,omitempty"`
+	ClickHouseVersion      string                  `json:"clickhouseVersion,omitempty"      yaml:"clickhouseVersion,omitempty"`
 	Pods                   []string                `json:"pods,omitempty"                   yaml:"pods,omitempty"`
 	PodIPs                 []string                `json:"pod-ips,omitempty"                yaml:"pod-ips,omitempty"`
+	Nodes                  map[string][]string     `json:"nodes,omitempty"                  yaml:"nodes,omitempty"`
 	FQDNs                  []string                `json:"fqdns,omitempty"                  yaml:"fqdns,omitempty"`
 	Endpoint               string                  `json:"endpoint,omitempty"               yaml:"endpoint,omitempty"`
 	NormalizedCHI          *ClickHouseInstallation `json:"normalized,omitempty"             yaml:"normalized,omitempty"`
 	NormalizedCHICompleted *ClickHouseInstallation `json:"normalizedCompleted,omitempty"    yaml:"normalizedCompleted,omitempty"`
 	HostsWithTablesCreated []string                `json:"hostsWithTablesCreated,omitempty" yaml:"hostsWithTablesCreated,omitempty"`
 	UsedTemplates          []*TemplateRef          `json:"usedTemplates,omitempty"          yaml:"usedTemplates,omitempty"`
+	BlockingTables         []string                `json:"blockingTables,omitempty"         yaml:"blockingTables,omitempty"`
+	Conditions             []meta.Condition        `json:"conditions,omitempty"             yaml:"conditions,omitempty"`
+	SchemaPlans            []ChiSchemaPlan         `json:"schemaPlans,omitempty"            yaml:"schemaPlans,omitempty"`
+	KeeperMigrations       []ChiKeeperMigration    `json:"keeperMigrations,omitempty"       yaml:"keeperMigrations,omitempty"`
+	HostStatuses           []ChiHostStatus         `json:"hostStatuses,omitempty"           yaml:"hostStatuses,omitempty"`
+	DetachedParts          []ChiHostDetachedParts  `json:"detachedParts,omitempty"          yaml:"detachedParts,omitempty"`
+	Footprint              *ChiFootprint           `json:"footprint,omitempty"              yaml:"footprint,omitempty"`
 
 	mu sync.RWMutex `json:"-" yaml:"-"`
 }
 
+// ChiSchemaPlan describes schema objects propagated to a new host, or planned to be propagated in dry-run mode
+type ChiSchemaPlan struct {
+	Host   string `json:"host"             yaml:"host"`
+	DryRun bool   `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+	Time   string `json:"time,omitempty"   yaml:"time,omitempty"`
+	// Create lists names of objects missing on the host, in the order they are created.
+	// Statements are not listed in order to keep status size within limits, they are logged by the operator
+	Create []string `json:"create,omitempty"   yaml:"create,omitempty"`
+	// Existing lists objects, which already exist on the host and are not created
+	Existing []string `json:"existing,omitempty" yaml:"existing,omitempty"`
+	// Differs lists objects, which exist on the host with definition different from the one on other hosts.
+	// Such objects are not touched
+	Differs []string `json:"differs,omitempty"  yaml:"differs,omitempty"`
+}
+
+// ChiKeeperMigration describes migration of replicated tables metadata of the cluster
+// to the ClickHouseKeeperInstallation the cluster is switched to
+type ChiKeeperMigration struct {
+	Cluster string `json:"cluster"             yaml:"cluster"`
+	// Keeper specifies namespace/name of the ClickHouseKeeperInstallation metadata is migrated to
+	Keeper    string `json:"keeper"              yaml:"keeper"`
+	State     string `json:"state"               yaml:"state"`
+	StartTime string `json:"startTime,omitempty" yaml:"startTime,omitempty"`
+	EndTime   string `json:"endTime,omitempty"   yaml:"endTime,omitempty"`
+	// Hosts lists hosts having metadata of all replicated tables restored in the keeper
+	Hosts []string `json:"hosts,omitempty"     yaml:"hosts,omitempty"`
+	// Tables specifies number of replicated tables restored on all hosts
+	Tables int    `json:"tables,omitempty"    yaml:"tables,omitempty"`
+	Error  string `json:"error,omitempty"     yaml:"error,omitempty"`
+}
+
+// HasHost checks whether the host is migrated already
+func (m *ChiKeeperMigration) HasHost(host string) bool {
+	if m == nil {
+		return false
+	}
+	for _, h := range m.Hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInProgress checks whether migration is in progress
+func (m *ChiKeeperMigration) IsInProgress() bool {
+	if m == nil {
+		return false
+	}
+	return m.State == KeeperMigrationStateInProgress
+}
+
+// ChiHostStatus describes reconcile progress of a host
+type ChiHostStatus struct {
+	// Cluster specifies cluster of the host, since host names are unique within a cluster only
+	Cluster string `json:"cluster,omitempty"  yaml:"cluster,omitempty"`
+	Host    string `json:"host"               yaml:"host"`
+	Phase   string `json:"phase"              yaml:"phase"`
+	// Version specifies version of ClickHouse the host is running, as reported by the last successful reconcile
+	Version string `json:"version,omitempty"  yaml:"version,omitempty"`
+	// Revision specifies revision of the host's StatefulSet applied by the last successful reconcile
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+	Time     string `json:"time,omitempty"     yaml:"time,omitempty"`
+	// Error specifies the last error host reconcile failed with
+	Error string `json:"error,omitempty"    yaml:"error,omitempty"`
+	// Conditions specifies conditions of the host, such as progress of volumes resize
+	Conditions []meta.Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+// NewChiHostStatus creates new status of the host
+func NewChiHostStatus(host *ChiHost) ChiHostStatus {
+	return ChiHostStatus{
+		Cluster: host.Runtime.Address.ClusterName,
+		Host:    host.GetName(),
+	}
+}
+
+// IsOf checks whether the status is the status of specified host
+func (s *ChiHostStatus) IsOf(cluster, host string) bool {
+	return (s.Cluster == cluster) && (s.Host == host)
+}
+
+// ChiHostDetachedParts describes detached parts of a host
+type ChiHostDetachedParts struct {
+	// Cluster specifies cluster of the host, since host names are unique within a cluster only
+	Cluster string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Host    string `json:"host"              yaml:"host"`
+	Count   int    `json:"count"             yaml:"count"`
+	// Reasons specifies number of detached parts per reason
+	Reasons map[string]int `json:"reasons,omitempty" yaml:"reasons,omitempty"`
+	// Dropped specifies number of detached parts dropped by the last cleanup
+	Dropped int    `json:"dropped,omitempty" yaml:"dropped,omitempty"`
+	Time    string `json:"time,omitempty"    yaml:"time,omitempty"`
+	Error   string `json:"error,omitempty"   yaml:"error,omitempty"`
+}
+
+// ChiFootprint describes resources requested by pods and volumes of the CHI, so the CHI can be charged back
+type ChiFootprint struct {
+	// Summary specifies requested resources in a short human-readable form, such as "cpu=4 memory=16Gi storage=300Gi"
+	Summary string `json:"summary,omitempty"  yaml:"summary,omitempty"`
+	// CPU specifies CPU requested by pods of running hosts
+	CPU string `json:"cpu,omitempty"      yaml:"cpu,omitempty"`
+	// Memory specifies memory requested by pods of running hosts
+	Memory string `json:"memory,omitempty"   yaml:"memory,omitempty"`
+	// Storage specifies storage requested by volume claim templates of all hosts, stopped ones included
+	Storage string `json:"storage,omitempty"  yaml:"storage,omitempty"`
+	// Clusters specifies requested resources per cluster
+	Clusters []ChiClusterFootprint `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+}
+
+// ChiClusterFootprint describes resources requested by pods and volumes of a cluster
+type ChiClusterFootprint struct {
+	Name    string `json:"name"              yaml:"name"`
+	CPU     string `json:"cpu,omitempty"     yaml:"cpu,omitempty"`
+	Memory  string `json:"memory,omitempty"  yaml:"memory,omitempty"`
+	Storage string `json:"storage,omitempty" yaml:"storage,omitempty"`
+}
+
 // CopyCHIStatusOptions specifies what to copy in CHI status options
 type CopyCHIStatusOptions struct {
 	Actions           bool
@@ -86,6 +261,14 @@ type CopyCHIStatusOptions struct {
 	MainFields        bool
 	WholeStatus       bool
 	InheritableFields bool
+	// DetachedParts specifies to copy detached parts report only
+	DetachedParts bool
+	// Upgrade specifies to copy UpgradeAvailable condition only
+	Upgrade bool
+	// DDLQueue specifies to copy DDLQueueHealthy condition only
+	DDLQueue bool
+	// Keeper specifies to copy KeeperHealthy condition only
+	Keeper bool
 }
 
 // FillStatusParams is a struct used to fill status params
@@ -230,6 +413,168 @@ func (s *ChiStatus) SetPodIPs(podIPs []string) {
 	})
 }
 
+// SetHostsReady sets number of ready hosts out of total number of hosts
+func (s *ChiStatus) SetHostsReady(ready, total int) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		s.HostsReadyCount = ready
+		s.Ready = fmt.Sprintf("%d/%d", ready, total)
+	})
+}
+
+// SetNodes sets map of k8s nodes to pods running on them
+func (s *ChiStatus) SetNodes(nodes map[string][]string) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		s.Nodes = nodes
+	})
+}
+
+// SetBlockingTables sets list of tables holding data, which block removal of shards
+func (s *ChiStatus) SetBlockingTables(tables []string) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		s.BlockingTables = tables
+	})
+}
+
+// SetCondition sets condition of specified type, transition time is changed only in case condition status changes
+func (s *ChiStatus) SetCondition(condition meta.Condition) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		apiMeta.SetStatusCondition(&s.Conditions, condition)
+	})
+}
+
+// RemoveCondition removes condition of specified type
+func (s *ChiStatus) RemoveCondition(conditionType string) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		apiMeta.RemoveStatusCondition(&s.Conditions, conditionType)
+	})
+}
+
+// SetHostCondition sets condition of specified type of the host, transition time is changed only in case condition status changes
+func (s *ChiStatus) SetHostCondition(host *ChiHost, condition meta.Condition) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		for i := range s.HostStatuses {
+			if s.HostStatuses[i].IsOf(host.Runtime.Address.ClusterName, host.GetName()) {
+				apiMeta.SetStatusCondition(&s.HostStatuses[i].Conditions, condition)
+				return
+			}
+		}
+		status := NewChiHostStatus(host)
+		apiMeta.SetStatusCondition(&status.Conditions, condition)
+		s.HostStatuses = append(s.HostStatuses, status)
+	})
+}
+
+// RemoveHostsCondition removes condition of specified type of all hosts
+func (s *ChiStatus) RemoveHostsCondition(conditionType string) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		for i := range s.HostStatuses {
+			apiMeta.RemoveStatusCondition(&s.HostStatuses[i].Conditions, conditionType)
+		}
+	})
+}
+
+// PushSchemaPlan pushes schema plan of the host, replacing earlier plan of the same host
+func (s *ChiStatus) PushSchemaPlan(plan ChiSchemaPlan) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		plans := []ChiSchemaPlan{plan}
+		for _, p := range s.SchemaPlans {
+			if p.Host != plan.Host {
+				plans = append(plans, p)
+			}
+		}
+		if len(plans) > maxSchemaPlans {
+			plans = plans[:maxSchemaPlans]
+		}
+		s.SchemaPlans = plans
+	})
+}
+
+// SetKeeperMigration sets keeper migration of the cluster, replacing earlier migration of the same cluster
+func (s *ChiStatus) SetKeeperMigration(migration ChiKeeperMigration) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		migrations := []ChiKeeperMigration{migration}
+		for _, m := range s.KeeperMigrations {
+			if m.Cluster != migration.Cluster {
+				migrations = append(migrations, m)
+			}
+		}
+		s.KeeperMigrations = migrations
+	})
+}
+
+// ResetHostStatuses makes specified hosts pending reconcile, statuses of hosts not specified are dropped.
+// Revision and error of the previous reconcile are kept.
+func (s *ChiStatus) ResetHostStatuses(hosts []*ChiHost, time string) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		var statuses []ChiHostStatus
+		for _, host := range hosts {
+			status := NewChiHostStatus(host)
+			for _, hs := range s.HostStatuses {
+				if hs.IsOf(status.Cluster, status.Host) {
+					status = hs
+				}
+			}
+			status.Phase = HostPhasePending
+			status.Time = time
+			statuses = append(statuses, status)
+		}
+		s.HostStatuses = statuses
+		s.ClickHouseVersion = clickHouseVersionNoSync(s)
+	})
+}
+
+// SetHostStatus sets status of the host, replacing earlier status of the same host
+func (s *ChiStatus) SetHostStatus(status ChiHostStatus) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		defer func() {
+			s.ClickHouseVersion = clickHouseVersionNoSync(s)
+		}()
+		for i := range s.HostStatuses {
+			if s.HostStatuses[i].IsOf(status.Cluster, status.Host) {
+				s.HostStatuses[i] = status
+				return
+			}
+		}
+		s.HostStatuses = append(s.HostStatuses, status)
+	})
+}
+
+// clickHouseVersionNoSync builds sorted comma-separated list of distinct ClickHouse versions reported by hosts
+func clickHouseVersionNoSync(s *ChiStatus) string {
+	var versions []string
+	for _, status := range s.HostStatuses {
+		if status.Version != "" {
+			versions = append(versions, status.Version)
+		}
+	}
+	versions = util.Unique(versions)
+	sort.Strings(versions)
+	return strings.Join(versions, ",")
+}
+
+// SetFootprint sets resources requested by the CHI
+func (s *ChiStatus) SetFootprint(footprint *ChiFootprint) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		s.Footprint = footprint
+	})
+}
+
+// GetFootprint gets resources requested by the CHI
+func (s *ChiStatus) GetFootprint() *ChiFootprint {
+	var footprint *ChiFootprint
+	doWithReadLock(s, func(s *ChiStatus) {
+		footprint = s.Footprint
+	})
+	return footprint
+}
+
+// SetDetachedParts sets detached parts report of all hosts
+func (s *ChiStatus) SetDetachedParts(parts []ChiHostDetachedParts) {
+	doWithWriteLock(s, func(s *ChiStatus) {
+		s.DetachedParts = parts
+	})
+}
+
 // HostDeleted increments deleted hosts counter
 func (s *ChiStatus) HostDeleted() {
 	doWithWriteLock(s, func(s *ChiStatus) {
@@ -344,6 +689,12 @@ func (s *ChiStatus) CopyFrom(f *ChiStatus, opts CopyCHIStatusOptions) {
 				s.Actions = from.Actions
 				s.Errors = from.Errors
 				s.HostsWithTablesCreated = from.HostsWithTablesCreated
+				s.Conditions = from.Conditions
+				s.SchemaPlans = from.SchemaPlans
+				s.KeeperMigrations = from.KeeperMigrations
+				s.HostStatuses = from.HostStatuses
+				s.ClickHouseVersion = from.ClickHouseVersion
+				s.DetachedParts = from.DetachedParts
 			}
 
 			if opts.Actions {
@@ -388,8 +739,19 @@ func (s *ChiStatus) CopyFrom(f *ChiStatus, opts CopyCHIStatusOptions) {
 				s.HostsCompletedCount = from.HostsCompletedCount
 				s.HostsDeletedCount = from.HostsDeletedCount
 				s.HostsDeleteCount = from.HostsDeleteCount
+				s.HostsReadyCount = from.HostsReadyCount
+				s.Ready = from.Ready
 				s.Pods = from.Pods
 				s.PodIPs = from.PodIPs
+				s.Nodes = from.Nodes
+				s.BlockingTables = from.BlockingTables
+				s.Conditions = from.Conditions
+				s.SchemaPlans = from.SchemaPlans
+				s.KeeperMigrations = from.KeeperMigrations
+				s.HostStatuses = from.HostStatuses
+				s.ClickHouseVersion = from.ClickHouseVersion
+				s.DetachedParts = from.DetachedParts
+				s.Footprint = from.Footprint
 				s.FQDNs = from.FQDNs
 				s.Endpoint = from.Endpoint
 				s.NormalizedCHI = from.NormalizedCHI
@@ -399,6 +761,39 @@ func (s *ChiStatus) CopyFrom(f *ChiStatus, opts CopyCHIStatusOptions) {
 				s.NormalizedCHI = from.NormalizedCHI
 			}
 
+			if opts.DetachedParts {
+				s.DetachedParts = from.DetachedParts
+				if condition := apiMeta.FindStatusCondition(from.Conditions, ConditionTypeDetachedPartsBelowThreshold); condition != nil {
+					apiMeta.SetStatusCondition(&s.Conditions, *condition)
+				} else {
+					apiMeta.RemoveStatusCondition(&s.Conditions, ConditionTypeDetachedPartsBelowThreshold)
+				}
+			}
+
+			if opts.Upgrade {
+				if condition := apiMeta.FindStatusCondition(from.Conditions, ConditionTypeUpgradeAvailable); condition != nil {
+					apiMeta.SetStatusCondition(&s.Conditions, *condition)
+				} else {
+					apiMeta.RemoveStatusCondition(&s.Conditions, ConditionTypeUpgradeAvailable)
+				}
+			}
+
+			if opts.DDLQueue {
+				if condition := apiMeta.FindStatusCondition(from.Conditions, ConditionTypeDDLQueueHealthy); condition != nil {
+					apiMeta.SetStatusCondition(&s.Conditions, *condition)
+				} else {
+					apiMeta.RemoveStatusCondition(&s.Conditions, ConditionTypeDDLQueueHealthy)
+				}
+			}
+
+			if opts.Keeper {
+				if condition := apiMeta.FindStatusCondition(from.Conditions, ConditionTypeKeeperHealthy); condition != nil {
+					apiMeta.SetStatusCondition(&s.Conditions, *condition)
+				} else {
+					apiMeta.RemoveStatusCondition(&s.Conditions, ConditionTypeKeeperHealthy)
+				}
+			}
+
 			if opts.WholeStatus {
 				s.CHOpVersion = from.CHOpVersion
 				s.CHOpCommit = from.CHOpCommit
@@ -422,8 +817,19 @@ func (s *ChiStatus) CopyFrom(f *ChiStatus, opts CopyCHIStatusOptions) {
 				s.HostsCompletedCount = from.HostsCompletedCount
 				s.HostsDeletedCount = from.HostsDeletedCount
 				s.HostsDeleteCount = from.HostsDeleteCount
+				s.HostsReadyCount = from.HostsReadyCount
+				s.Ready = from.Ready
 				s.Pods = from.Pods
 				s.PodIPs = from.PodIPs
+				s.Nodes = from.Nodes
+				s.BlockingTables = from.BlockingTables
+				s.Conditions = from.Conditions
+				s.SchemaPlans = from.SchemaPlans
+				s.KeeperMigrations = from.KeeperMigrations
+				s.HostStatuses = from.HostStatuses
+				s.ClickHouseVersion = from.ClickHouseVersion
+				s.DetachedParts = from.DetachedParts
+				s.Footprint = from.Footprint
 				s.FQDNs = from.FQDNs
 				s.Endpoint = from.Endpoint
 				s.NormalizedCHI = from.NormalizedCHI
@@ -608,6 +1014,20 @@ func (s *ChiStatus) GetHostsDeleteCount() int {
 	})
 }
 
+// GetHostsReadyCount gets number of ready hosts
+func (s *ChiStatus) GetHostsReadyCount() int {
+	return getIntWithReadLock(s, func(s *ChiStatus) int {
+		return s.HostsReadyCount
+	})
+}
+
+// GetClickHouseVersion gets versions of ClickHouse running on hosts
+func (s *ChiStatus) GetClickHouseVersion() string {
+	return getStringWithReadLock(s, func(s *ChiStatus) string {
+		return s.ClickHouseVersion
+	})
+}
+
 // GetPods gets list of pods
 func (s *ChiStatus) GetPods() []string {
 	return getStringArrWithReadLock(s, func(s *ChiStatus) []string {
@@ -622,6 +1042,77 @@ func (s *ChiStatus) GetPodIPs() []string {
 	})
 }
 
+// GetNodes gets map of k8s nodes to pods running on them
+func (s *ChiStatus) GetNodes() (nodes map[string][]string) {
+	doWithReadLock(s, func(s *ChiStatus) {
+		nodes = s.Nodes
+	})
+	return nodes
+}
+
+// GetBlockingTables gets list of tables holding data, which block removal of shards
+func (s *ChiStatus) GetBlockingTables() []string {
+	return getStringArrWithReadLock(s, func(s *ChiStatus) []string {
+		return s.BlockingTables
+	})
+}
+
+// GetConditions gets conditions
+func (s *ChiStatus) GetConditions() (conditions []meta.Condition) {
+	doWithReadLock(s, func(s *ChiStatus) {
+		conditions = s.Conditions
+	})
+	return conditions
+}
+
+// GetSchemaPlans gets schema plans of hosts
+func (s *ChiStatus) GetSchemaPlans() (plans []ChiSchemaPlan) {
+	doWithReadLock(s, func(s *ChiStatus) {
+		plans = s.SchemaPlans
+	})
+	return plans
+}
+
+// GetKeeperMigration gets copy of keeper migration of the cluster, nil in case there is no migration
+func (s *ChiStatus) GetKeeperMigration(cluster string) (migration *ChiKeeperMigration) {
+	doWithReadLock(s, func(s *ChiStatus) {
+		for i := range s.KeeperMigrations {
+			if s.KeeperMigrations[i].Cluster == cluster {
+				migration = s.KeeperMigrations[i].DeepCopy()
+			}
+		}
+	})
+	return migration
+}
+
+// GetHostStatus gets status of the host, nil in case host has no status
+func (s *ChiStatus) GetHostStatus(host *ChiHost) (status *ChiHostStatus) {
+	doWithReadLock(s, func(s *ChiStatus) {
+		for i := range s.HostStatuses {
+			if s.HostStatuses[i].IsOf(host.Runtime.Address.ClusterName, host.GetName()) {
+				status = s.HostStatuses[i].DeepCopy()
+			}
+		}
+	})
+	return status
+}
+
+// GetHostStatuses gets statuses of hosts
+func (s *ChiStatus) GetHostStatuses() (statuses []ChiHostStatus) {
+	doWithReadLock(s, func(s *ChiStatus) {
+		statuses = s.HostStatuses
+	})
+	return statuses
+}
+
+// GetDetachedParts gets detached parts report of all hosts
+func (s *ChiStatus) GetDetachedParts() (parts []ChiHostDetachedParts) {
+	doWithReadLock(s, func(s *ChiStatus) {
+		parts = s.DetachedParts
+	})
+	return parts
+}
+
 // GetFQDNs gets list of all FQDNs of hosts
 func (s *ChiStatus) GetFQDNs() []string {
 	return getStringArrWithReadLock(s, func(s *ChiStatus) []string {
@@ -69,6 +69,9 @@ var copyTestStatusFrom = &ChiStatus{
 	HostsDeleteCount:       11,
 	Pods:                   []string{"pod-a-1", "pod-a-2"},
 	PodIPs:                 []string{"podIP-a-1", "podIP-a-2"},
+	Nodes:                  map[string][]string{"node-a-1": {"pod-a-1", "pod-a-2"}},
+	BlockingTables:         []string{"db-a.table-a-1", "db-a.table-a-2"},
+	HostStatuses:           []ChiHostStatus{{Cluster: "cluster-a", Host: "host-a-1", Phase: HostPhaseReady, Revision: "revision-a-1"}},
 	FQDNs:                  []string{"fqdns-a-1", "fqdns-a-2"},
 	Endpoint:               "endpt-a",
 	NormalizedCHI:          normalizedChiA,
@@ -207,6 +210,9 @@ func Test_ChiStatus_BasicOperations_SingleStatus_ConcurrencyTest(t *testing.T) {
 				require.Equal(tt, copyTestStatusFrom.GetNormalizedCHI(), s.GetNormalizedCHI())
 				require.Equal(tt, copyTestStatusFrom.GetNormalizedCHICompleted(), s.GetNormalizedCHICompleted())
 				require.Equal(tt, copyTestStatusFrom.GetPodIPs(), s.GetPodIPs())
+				require.Equal(tt, copyTestStatusFrom.GetNodes(), s.GetNodes())
+				require.Equal(tt, copyTestStatusFrom.GetBlockingTables(), s.GetBlockingTables())
+				require.Equal(tt, copyTestStatusFrom.GetHostStatuses(), s.GetHostStatuses())
 				require.Equal(tt, copyTestStatusFrom.GetPods(), s.GetPods())
 				require.Equal(tt, copyTestStatusFrom.GetReplicasCount(), s.GetReplicasCount())
 				require.Equal(tt, copyTestStatusFrom.GetShardsCount(), s.GetShardsCount())
@@ -246,3 +252,19 @@ func Test_ChiStatus_BasicOperations_SingleStatus_ConcurrencyTest(t *testing.T) {
 		})
 	}
 }
+
+// Hosts of different clusters may have the same name, their statuses must not be mixed up
+func Test_ChiStatus_HostStatusesOfClusters(t *testing.T) {
+	hostA := &ChiHost{Name: "0-0", Runtime: ChiHostRuntime{Address: ChiHostAddress{ClusterName: "a"}}}
+	hostB := &ChiHost{Name: "0-0", Runtime: ChiHostRuntime{Address: ChiHostAddress{ClusterName: "b"}}}
+
+	status := &ChiStatus{}
+	status.ResetHostStatuses([]*ChiHost{hostA, hostB}, "time")
+	require.Len(t, status.GetHostStatuses(), 2)
+
+	statusA := NewChiHostStatus(hostA)
+	statusA.Phase = HostPhaseReady
+	status.SetHostStatus(statusA)
+	require.Equal(t, HostPhaseReady, status.GetHostStatus(hostA).Phase)
+	require.Equal(t, HostPhasePending, status.GetHostStatus(hostB).Phase)
+}
@@ -0,0 +1,328 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"strings"
+
+	core "k8s.io/api/core/v1"
+)
+
+// Names of named collections streaming brokers are rendered into.
+// Tables refer to them as ENGINE = Kafka(kafka, ...) and ENGINE = RabbitMQ(rabbitmq, ...)
+const (
+	StreamingKafkaNamedCollection    = "kafka"
+	StreamingRabbitMQNamedCollection = "rabbitmq"
+)
+
+// kafkaSecurityProtocols lists security protocols supported by librdkafka
+var kafkaSecurityProtocols = []string{
+	"PLAINTEXT",
+	"SSL",
+	"SASL_PLAINTEXT",
+	"SASL_SSL",
+}
+
+// kafkaSASLMechanisms lists SASL mechanisms supported by librdkafka
+var kafkaSASLMechanisms = []string{
+	"PLAIN",
+	"SCRAM-SHA-256",
+	"SCRAM-SHA-512",
+	"GSSAPI",
+	"OAUTHBEARER",
+}
+
+// ChiStreaming defines streaming section of .spec.configuration and .spec.configuration.clusters[n]
+// Provides typed access to global Kafka and RabbitMQ engines config, which otherwise would have to be specified as raw settings.
+// Cluster-level section overrides CHI-level one for hosts of the cluster.
+type ChiStreaming struct {
+	Kafka    *ChiKafka    `json:"kafka,omitempty"    yaml:"kafka,omitempty"`
+	RabbitMQ *ChiRabbitMQ `json:"rabbitmq,omitempty" yaml:"rabbitmq,omitempty"`
+}
+
+// ChiKafka defines global Kafka engine config
+// Refers to
+// https://clickhouse.com/docs/en/engines/table-engines/integrations/kafka#configuration
+type ChiKafka struct {
+	// Brokers specifies brokers rendered into 'kafka' named collection
+	Brokers []string `json:"brokers,omitempty"          yaml:"brokers,omitempty"`
+	// SecurityProtocol specifies <kafka><security_protocol>, such as SASL_SSL
+	SecurityProtocol string            `json:"securityProtocol,omitempty" yaml:"securityProtocol,omitempty"`
+	SASL             *ChiStreamingSASL `json:"sasl,omitempty"             yaml:"sasl,omitempty"`
+	TLS              *ChiStreamingTLS  `json:"tls,omitempty"              yaml:"tls,omitempty"`
+}
+
+// ChiStreamingSASL defines SASL authentication of Kafka engine
+type ChiStreamingSASL struct {
+	// Mechanism specifies <kafka><sasl_mechanism>, such as SCRAM-SHA-512
+	Mechanism string `json:"mechanism,omitempty" yaml:"mechanism,omitempty"`
+	Username  string `json:"username,omitempty"  yaml:"username,omitempty"`
+	// Password specifies ref to k8s Secret the password is read from
+	Password *SettingSource `json:"password,omitempty"  yaml:"password,omitempty"`
+}
+
+// ChiStreamingTLS defines TLS of Kafka engine
+// Files are expected to be mounted into the pod, via .spec.configuration.files secret refs for example
+type ChiStreamingTLS struct {
+	// CALocation specifies <kafka><ssl_ca_location>
+	CALocation string `json:"caLocation,omitempty"          yaml:"caLocation,omitempty"`
+	// CertificateLocation specifies <kafka><ssl_certificate_location>
+	CertificateLocation string `json:"certificateLocation,omitempty" yaml:"certificateLocation,omitempty"`
+	// KeyLocation specifies <kafka><ssl_key_location>
+	KeyLocation string `json:"keyLocation,omitempty"         yaml:"keyLocation,omitempty"`
+	// KeyPassword specifies ref to k8s Secret the key password is read from
+	KeyPassword *SettingSource `json:"keyPassword,omitempty"         yaml:"keyPassword,omitempty"`
+}
+
+// ChiRabbitMQ defines global RabbitMQ engine config
+// Refers to
+// https://clickhouse.com/docs/en/engines/table-engines/integrations/rabbitmq#configuration
+type ChiRabbitMQ struct {
+	// HostPort specifies host:port rendered into 'rabbitmq' named collection
+	HostPort string `json:"hostPort,omitempty" yaml:"hostPort,omitempty"`
+	// Secure specifies whether to connect via TLS, rendered into 'rabbitmq' named collection
+	Secure *StringBool `json:"secure,omitempty"   yaml:"secure,omitempty"`
+	// VHost specifies <rabbitmq><vhost>
+	VHost    string `json:"vhost,omitempty"    yaml:"vhost,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	// Password specifies ref to k8s Secret the password is read from
+	Password *SettingSource `json:"password,omitempty" yaml:"password,omitempty"`
+}
+
+// NewChiStreaming creates new ChiStreaming object
+func NewChiStreaming() *ChiStreaming {
+	return new(ChiStreaming)
+}
+
+// NewChiKafka creates new ChiKafka object
+func NewChiKafka() *ChiKafka {
+	return new(ChiKafka)
+}
+
+// NewChiRabbitMQ creates new ChiRabbitMQ object
+func NewChiRabbitMQ() *ChiRabbitMQ {
+	return new(ChiRabbitMQ)
+}
+
+// IsValidKafkaSecurityProtocol checks whether Kafka security protocol is supported
+func IsValidKafkaSecurityProtocol(protocol string) bool {
+	for _, p := range kafkaSecurityProtocols {
+		if strings.EqualFold(p, protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidKafkaSASLMechanism checks whether Kafka SASL mechanism is supported
+func IsValidKafkaSASLMechanism(mechanism string) bool {
+	for _, m := range kafkaSASLMechanisms {
+		if strings.EqualFold(m, mechanism) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetKafka gets Kafka
+func (s *ChiStreaming) GetKafka() *ChiKafka {
+	if s == nil {
+		return nil
+	}
+	return s.Kafka
+}
+
+// GetRabbitMQ gets RabbitMQ
+func (s *ChiStreaming) GetRabbitMQ() *ChiRabbitMQ {
+	if s == nil {
+		return nil
+	}
+	return s.RabbitMQ
+}
+
+// AsSettings returns specified fields as a map of ClickHouse setting path to value
+func (s *ChiStreaming) AsSettings() map[string]string {
+	m := make(map[string]string)
+	if kafka := s.GetKafka(); kafka != nil {
+		if len(kafka.Brokers) > 0 {
+			m["named_collections/"+StreamingKafkaNamedCollection+"/kafka_broker_list"] = strings.Join(kafka.Brokers, ",")
+		}
+		if kafka.SecurityProtocol != "" {
+			m["kafka/security_protocol"] = strings.ToUpper(kafka.SecurityProtocol)
+		}
+		if sasl := kafka.SASL; sasl != nil {
+			if sasl.Mechanism != "" {
+				m["kafka/sasl_mechanism"] = strings.ToUpper(sasl.Mechanism)
+			}
+			if sasl.Username != "" {
+				m["kafka/sasl_username"] = sasl.Username
+			}
+		}
+		if tls := kafka.TLS; tls != nil {
+			if tls.CALocation != "" {
+				m["kafka/ssl_ca_location"] = tls.CALocation
+			}
+			if tls.CertificateLocation != "" {
+				m["kafka/ssl_certificate_location"] = tls.CertificateLocation
+			}
+			if tls.KeyLocation != "" {
+				m["kafka/ssl_key_location"] = tls.KeyLocation
+			}
+		}
+	}
+	if rabbitmq := s.GetRabbitMQ(); rabbitmq != nil {
+		if rabbitmq.HostPort != "" {
+			m["named_collections/"+StreamingRabbitMQNamedCollection+"/rabbitmq_host_port"] = rabbitmq.HostPort
+		}
+		if rabbitmq.Secure.HasValue() {
+			m["named_collections/"+StreamingRabbitMQNamedCollection+"/rabbitmq_secure"] = rabbitmq.Secure.CastTo01(false)
+		}
+		if rabbitmq.VHost != "" {
+			m["rabbitmq/vhost"] = rabbitmq.VHost
+		}
+		if rabbitmq.Username != "" {
+			m["rabbitmq/username"] = rabbitmq.Username
+		}
+	}
+	return m
+}
+
+// AsSecretSettings returns specified secret fields as a map of ClickHouse setting path to secret ref
+func (s *ChiStreaming) AsSecretSettings() map[string]*core.SecretKeySelector {
+	m := make(map[string]*core.SecretKeySelector)
+	if kafka := s.GetKafka(); kafka != nil {
+		if kafka.SASL != nil && kafka.SASL.Password.HasSecretKeyRef() {
+			m["kafka/sasl_password"] = kafka.SASL.Password.GetSecretKeyRef()
+		}
+		if kafka.TLS != nil && kafka.TLS.KeyPassword.HasSecretKeyRef() {
+			m["kafka/ssl_key_password"] = kafka.TLS.KeyPassword.GetSecretKeyRef()
+		}
+	}
+	if rabbitmq := s.GetRabbitMQ(); rabbitmq != nil {
+		if rabbitmq.Password.HasSecretKeyRef() {
+			m["rabbitmq/password"] = rabbitmq.Password.GetSecretKeyRef()
+		}
+	}
+	return m
+}
+
+// MergeFrom merges from specified source
+func (s *ChiStreaming) MergeFrom(from *ChiStreaming, _type MergeType) *ChiStreaming {
+	if from == nil {
+		return s
+	}
+
+	if s == nil {
+		s = NewChiStreaming()
+	}
+
+	s.Kafka = s.Kafka.MergeFrom(from.Kafka, _type)
+	s.RabbitMQ = s.RabbitMQ.MergeFrom(from.RabbitMQ, _type)
+
+	return s
+}
+
+// MergeFrom merges from specified source
+func (k *ChiKafka) MergeFrom(from *ChiKafka, _type MergeType) *ChiKafka {
+	if from == nil {
+		return k
+	}
+
+	if k == nil {
+		k = NewChiKafka()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if len(k.Brokers) == 0 {
+			k.Brokers = append([]string{}, from.Brokers...)
+		}
+		if k.SecurityProtocol == "" {
+			k.SecurityProtocol = from.SecurityProtocol
+		}
+		if k.SASL == nil {
+			k.SASL = from.SASL.DeepCopy()
+		}
+		if k.TLS == nil {
+			k.TLS = from.TLS.DeepCopy()
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if len(from.Brokers) > 0 {
+			// Override by non-empty values only
+			k.Brokers = append([]string{}, from.Brokers...)
+		}
+		if from.SecurityProtocol != "" {
+			// Override by non-empty values only
+			k.SecurityProtocol = from.SecurityProtocol
+		}
+		if from.SASL != nil {
+			// Override by non-empty values only
+			k.SASL = from.SASL.DeepCopy()
+		}
+		if from.TLS != nil {
+			// Override by non-empty values only
+			k.TLS = from.TLS.DeepCopy()
+		}
+	}
+
+	return k
+}
+
+// MergeFrom merges from specified source
+func (r *ChiRabbitMQ) MergeFrom(from *ChiRabbitMQ, _type MergeType) *ChiRabbitMQ {
+	if from == nil {
+		return r
+	}
+
+	if r == nil {
+		r = NewChiRabbitMQ()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if r.HostPort == "" {
+			r.HostPort = from.HostPort
+		}
+		if r.VHost == "" {
+			r.VHost = from.VHost
+		}
+		if r.Username == "" {
+			r.Username = from.Username
+		}
+		if r.Password == nil {
+			r.Password = from.Password.DeepCopy()
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.HostPort != "" {
+			// Override by non-empty values only
+			r.HostPort = from.HostPort
+		}
+		if from.VHost != "" {
+			// Override by non-empty values only
+			r.VHost = from.VHost
+		}
+		if from.Username != "" {
+			// Override by non-empty values only
+			r.Username = from.Username
+		}
+		if from.Password != nil {
+			// Override by non-empty values only
+			r.Password = from.Password.DeepCopy()
+		}
+	}
+	r.Secure = r.Secure.MergeFrom(from.Secure)
+
+	return r
+}
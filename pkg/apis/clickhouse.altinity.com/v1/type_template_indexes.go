@@ -229,3 +229,57 @@ func (i *ServiceTemplatesIndex) Walk(f func(template *ServiceTemplate)) {
 		f(entry)
 	}
 }
+
+// PodDisruptionBudgetTemplatesIndex describes index of PodDisruptionBudget templates
+type PodDisruptionBudgetTemplatesIndex struct {
+	// templates maps 'name of the template' -> 'template itself'
+	templates map[string]*PodDisruptionBudgetTemplate `json:",omitempty" yaml:",omitempty" testdiff:"ignore"`
+}
+
+// NewPodDisruptionBudgetTemplatesIndex creates new PodDisruptionBudgetTemplatesIndex object
+func NewPodDisruptionBudgetTemplatesIndex() *PodDisruptionBudgetTemplatesIndex {
+	return &PodDisruptionBudgetTemplatesIndex{
+		templates: make(map[string]*PodDisruptionBudgetTemplate),
+	}
+}
+
+// Has checks whether index has entity `name`
+func (i *PodDisruptionBudgetTemplatesIndex) Has(name string) bool {
+	if i == nil {
+		return false
+	}
+	if i.templates == nil {
+		return false
+	}
+	_, ok := i.templates[name]
+	return ok
+}
+
+// Get returns entity `name` from the index
+func (i *PodDisruptionBudgetTemplatesIndex) Get(name string) *PodDisruptionBudgetTemplate {
+	if !i.Has(name) {
+		return nil
+	}
+	return i.templates[name]
+}
+
+// Set sets named template into index
+func (i *PodDisruptionBudgetTemplatesIndex) Set(name string, entry *PodDisruptionBudgetTemplate) {
+	if i == nil {
+		return
+	}
+	if i.templates == nil {
+		return
+	}
+	i.templates[name] = entry
+}
+
+// Walk calls specified function over each entry in the index
+func (i *PodDisruptionBudgetTemplatesIndex) Walk(f func(template *PodDisruptionBudgetTemplate)) {
+	if i == nil {
+		return
+	}
+	for _, entry := range i.templates {
+		f(entry)
+	}
+}
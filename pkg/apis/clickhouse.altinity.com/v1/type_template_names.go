@@ -147,6 +147,38 @@ func (templateNames *ChiTemplateNames) GetReplicaServiceTemplate() string {
 	return templateNames.ReplicaServiceTemplate
 }
 
+// GetClusterServiceTemplates gets templates of extra Services of the cluster
+func (templateNames *ChiTemplateNames) GetClusterServiceTemplates() []string {
+	if templateNames == nil {
+		return nil
+	}
+	return templateNames.ClusterServiceTemplates
+}
+
+// GetReplicaServiceTemplates gets templates of extra Services of the host
+func (templateNames *ChiTemplateNames) GetReplicaServiceTemplates() []string {
+	if templateNames == nil {
+		return nil
+	}
+	return templateNames.ReplicaServiceTemplates
+}
+
+// HasPodDisruptionBudgetTemplate checks whether PodDisruptionBudget template is specified
+func (templateNames *ChiTemplateNames) HasPodDisruptionBudgetTemplate() bool {
+	if templateNames == nil {
+		return false
+	}
+	return len(templateNames.PodDisruptionBudgetTemplate) > 0
+}
+
+// GetPodDisruptionBudgetTemplate gets PodDisruptionBudget template
+func (templateNames *ChiTemplateNames) GetPodDisruptionBudgetTemplate() string {
+	if templateNames == nil {
+		return ""
+	}
+	return templateNames.PodDisruptionBudgetTemplate
+}
+
 // HandleDeprecatedFields helps to deal with deprecated fields
 func (templateNames *ChiTemplateNames) HandleDeprecatedFields() {
 	if templateNames == nil {
@@ -206,6 +238,15 @@ func (templateNames *ChiTemplateNames) mergeFromFillEmptyValues(from *ChiTemplat
 	if templateNames.ReplicaServiceTemplate == "" {
 		templateNames.ReplicaServiceTemplate = from.ReplicaServiceTemplate
 	}
+	if len(templateNames.ClusterServiceTemplates) == 0 {
+		templateNames.ClusterServiceTemplates = from.ClusterServiceTemplates
+	}
+	if len(templateNames.ReplicaServiceTemplates) == 0 {
+		templateNames.ReplicaServiceTemplates = from.ReplicaServiceTemplates
+	}
+	if templateNames.PodDisruptionBudgetTemplate == "" {
+		templateNames.PodDisruptionBudgetTemplate = from.PodDisruptionBudgetTemplate
+	}
 	return templateNames
 }
 
@@ -238,5 +279,14 @@ func (templateNames *ChiTemplateNames) mergeFromOverwriteByNonEmptyValues(from *
 	if from.ReplicaServiceTemplate != "" {
 		templateNames.ReplicaServiceTemplate = from.ReplicaServiceTemplate
 	}
+	if len(from.ClusterServiceTemplates) > 0 {
+		templateNames.ClusterServiceTemplates = from.ClusterServiceTemplates
+	}
+	if len(from.ReplicaServiceTemplates) > 0 {
+		templateNames.ReplicaServiceTemplates = from.ReplicaServiceTemplates
+	}
+	if from.PodDisruptionBudgetTemplate != "" {
+		templateNames.PodDisruptionBudgetTemplate = from.PodDisruptionBudgetTemplate
+	}
 	return templateNames
 }
@@ -51,6 +51,13 @@ func (templates *Templates) GetServiceTemplates() []ServiceTemplate {
 	return templates.ServiceTemplates
 }
 
+func (templates *Templates) GetPodDisruptionBudgetTemplates() []PodDisruptionBudgetTemplate {
+	if templates == nil {
+		return nil
+	}
+	return templates.PodDisruptionBudgetTemplates
+}
+
 // Len returns accumulated len of all templates
 func (templates *Templates) Len() int {
 	if templates == nil {
@@ -61,7 +68,8 @@ func (templates *Templates) Len() int {
 		len(templates.HostTemplates) +
 		len(templates.PodTemplates) +
 		len(templates.VolumeClaimTemplates) +
-		len(templates.ServiceTemplates)
+		len(templates.ServiceTemplates) +
+		len(templates.PodDisruptionBudgetTemplates)
 }
 
 // MergeFrom merges from specified object
@@ -93,6 +101,7 @@ func (templates *Templates) MergeFrom(_from any, _type MergeType) *Templates {
 	templates.mergePodTemplates(from)
 	templates.mergeVolumeClaimTemplates(from)
 	templates.mergeServiceTemplates(from)
+	templates.mergePodDisruptionBudgetTemplates(from)
 
 	return templates
 }
@@ -234,6 +243,39 @@ func (templates *Templates) mergeServiceTemplates(from *Templates) {
 	}
 }
 
+// mergePodDisruptionBudgetTemplates merges PodDisruptionBudget templates section
+func (templates *Templates) mergePodDisruptionBudgetTemplates(from *Templates) {
+	if len(from.PodDisruptionBudgetTemplates) == 0 {
+		return
+	}
+
+	// We have templates to merge from
+	// Loop over all 'from' templates and either copy it in case no such template in receiver or merge it
+	for fromIndex := range from.PodDisruptionBudgetTemplates {
+		fromTemplate := &from.PodDisruptionBudgetTemplates[fromIndex]
+
+		// Try to find entry with the same name among local templates in receiver
+		sameNameFound := false
+		for toIndex := range templates.PodDisruptionBudgetTemplates {
+			toTemplate := &templates.PodDisruptionBudgetTemplates[toIndex]
+			if toTemplate.Name == fromTemplate.Name {
+				// Receiver already have such a template
+				sameNameFound = true
+				// Merge `to` template with `from` template
+				_ = mergo.Merge(toTemplate, *fromTemplate, mergo.WithSliceDeepCopy)
+				// Receiver `to` template is processed
+				break
+			}
+		}
+
+		if !sameNameFound {
+			// Receiver does not have template with such a name
+			// Append template from `from`
+			templates.PodDisruptionBudgetTemplates = append(templates.PodDisruptionBudgetTemplates, *fromTemplate.DeepCopy())
+		}
+	}
+}
+
 // GetHostTemplatesIndex returns index of host templates
 func (templates *Templates) GetHostTemplatesIndex() *HostTemplatesIndex {
 	if templates == nil {
@@ -313,3 +355,23 @@ func (templates *Templates) EnsureServiceTemplatesIndex() *ServiceTemplatesIndex
 	templates.ServiceTemplatesIndex = NewServiceTemplatesIndex()
 	return templates.ServiceTemplatesIndex
 }
+
+// GetPodDisruptionBudgetTemplatesIndex returns index of PodDisruptionBudget templates
+func (templates *Templates) GetPodDisruptionBudgetTemplatesIndex() *PodDisruptionBudgetTemplatesIndex {
+	if templates == nil {
+		return nil
+	}
+	return templates.PodDisruptionBudgetTemplatesIndex
+}
+
+// EnsurePodDisruptionBudgetTemplatesIndex ensures index exists
+func (templates *Templates) EnsurePodDisruptionBudgetTemplatesIndex() *PodDisruptionBudgetTemplatesIndex {
+	if templates == nil {
+		return nil
+	}
+	if templates.PodDisruptionBudgetTemplatesIndex != nil {
+		return templates.PodDisruptionBudgetTemplatesIndex
+	}
+	templates.PodDisruptionBudgetTemplatesIndex = NewPodDisruptionBudgetTemplatesIndex()
+	return templates.PodDisruptionBudgetTemplatesIndex
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"strings"
+	"time"
+)
+
+// Release channels ClickHouse versions are discovered in
+const (
+	// UpgradeChannelLTS specifies long-term support releases
+	UpgradeChannelLTS = "lts"
+	// UpgradeChannelStable specifies stable releases
+	UpgradeChannelStable = "stable"
+)
+
+// defaultUpgradeInterval specifies default interval of checking registry index for newer versions
+const defaultUpgradeInterval = 1 * time.Hour
+
+// ChiUpgrade defines upgrade section of .spec
+// Provides periodic discovery of newer patch versions of ClickHouse versions clusters are pinned to.
+// Available upgrades are reported via UpgradeAvailable condition and applied either automatically or upon approval.
+type ChiUpgrade struct {
+	// Channel specifies release channel newer versions are discovered in
+	Channel string `json:"channel,omitempty"   yaml:"channel,omitempty"`
+	// AutoApply specifies whether to apply discovered upgrades w/o approval
+	AutoApply *StringBool `json:"autoApply,omitempty" yaml:"autoApply,omitempty"`
+	// Interval specifies interval of checking for newer versions, in seconds
+	Interval int `json:"interval,omitempty"  yaml:"interval,omitempty"`
+}
+
+// NewChiUpgrade creates new ChiUpgrade object
+func NewChiUpgrade() *ChiUpgrade {
+	return new(ChiUpgrade)
+}
+
+// IsEnabled checks whether newer versions are to be discovered
+func (u *ChiUpgrade) IsEnabled() bool {
+	return u.GetChannel() != ""
+}
+
+// GetChannel gets release channel, empty in case channel is unknown
+func (u *ChiUpgrade) GetChannel() string {
+	if u == nil {
+		return ""
+	}
+	switch strings.ToLower(u.Channel) {
+	case UpgradeChannelLTS:
+		return UpgradeChannelLTS
+	case UpgradeChannelStable:
+		return UpgradeChannelStable
+	}
+	return ""
+}
+
+// IsAutoApply checks whether discovered upgrades are to be applied w/o approval
+func (u *ChiUpgrade) IsAutoApply() bool {
+	if u == nil {
+		return false
+	}
+	return u.AutoApply.IsTrue()
+}
+
+// GetInterval gets interval of checking for newer versions
+func (u *ChiUpgrade) GetInterval() time.Duration {
+	if (u == nil) || (u.Interval <= 0) {
+		return defaultUpgradeInterval
+	}
+	return time.Duration(u.Interval) * time.Second
+}
+
+// MergeFrom merges from specified source
+func (u *ChiUpgrade) MergeFrom(from *ChiUpgrade, _type MergeType) *ChiUpgrade {
+	if from == nil {
+		return u
+	}
+
+	if u == nil {
+		u = NewChiUpgrade()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if u.Channel == "" {
+			u.Channel = from.Channel
+		}
+		if !u.AutoApply.HasValue() {
+			u.AutoApply = u.AutoApply.MergeFrom(from.AutoApply)
+		}
+		if u.Interval == 0 {
+			u.Interval = from.Interval
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Channel != "" {
+			// Override by non-empty values only
+			u.Channel = from.Channel
+		}
+		if from.AutoApply.HasValue() {
+			// Override by non-empty values only
+			u.AutoApply = from.AutoApply
+		}
+		if from.Interval != 0 {
+			// Override by non-empty values only
+			u.Interval = from.Interval
+		}
+	}
+
+	return u
+}
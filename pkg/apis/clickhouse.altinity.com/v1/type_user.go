@@ -0,0 +1,130 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Statuses of ClickHouseUser
+const (
+	UserStatusPending   = "Pending"
+	UserStatusCompleted = "Completed"
+	UserStatusFailed    = "Failed"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseUser defines ClickHouse user managed with SQL statements on all hosts of a ClickHouseInstallation
+type ClickHouseUser struct {
+	meta.TypeMeta   `json:",inline"            yaml:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	Spec   ChuSpec    `json:"spec"             yaml:"spec"`
+	Status *ChuStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClickHouseUserList defines a list of ClickHouseUser resources
+type ClickHouseUserList struct {
+	meta.TypeMeta `json:",inline"  yaml:",inline"`
+	meta.ListMeta `json:"metadata" yaml:"metadata"`
+	Items         []ClickHouseUser `json:"items" yaml:"items"`
+}
+
+// ChuSpec defines spec section of ClickHouseUser resource
+type ChuSpec struct {
+	// CHI specifies name of the ClickHouseInstallation the user is created in, located in the same namespace
+	CHI string `json:"chi"                 yaml:"chi"`
+	// UserName specifies name of the user in ClickHouse. Defaults to the ClickHouseUser name
+	UserName string `json:"userName,omitempty" yaml:"userName,omitempty"`
+	// Password refers to a Secret with the password of the user. Password is stored in ClickHouse as SHA256 hash
+	Password *SettingSource `json:"password"            yaml:"password"`
+	// Networks specifies IP addresses or subnets the user is allowed to connect from. Any host is allowed in case not specified
+	Networks []string `json:"networks,omitempty" yaml:"networks,omitempty"`
+	// Profile specifies settings profile of the user
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	// Roles specifies roles granted to the user. All granted roles are default ones
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Grants specifies privileges granted to the user
+	Grants []ChuGrant `json:"grants,omitempty" yaml:"grants,omitempty"`
+}
+
+// ChuGrant defines privileges granted on a database or table
+type ChuGrant struct {
+	// Privileges specifies privileges to be granted, such as SELECT, INSERT or SELECT(column)
+	Privileges []string `json:"privileges"                yaml:"privileges"`
+	// On specifies database and table privileges are granted on, such as 'db.*' or '*.*'
+	On string `json:"on"                        yaml:"on"`
+	// WithGrantOption allows the user to grant the privileges to other users
+	WithGrantOption bool `json:"withGrantOption,omitempty" yaml:"withGrantOption,omitempty"`
+}
+
+// ChuStatus defines status section of ClickHouseUser resource
+type ChuStatus struct {
+	Status             string `json:"status,omitempty"             yaml:"status,omitempty"`
+	Error              string `json:"error,omitempty"              yaml:"error,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty" yaml:"observedGeneration,omitempty"`
+	// Hosts lists hosts the user is applied to
+	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// UserName, Roles and Grants specify what is applied to the hosts, so the ones removed from spec are dropped or revoked
+	UserName string     `json:"userName,omitempty" yaml:"userName,omitempty"`
+	Roles    []string   `json:"roles,omitempty"    yaml:"roles,omitempty"`
+	Grants   []ChuGrant `json:"grants,omitempty"   yaml:"grants,omitempty"`
+}
+
+// EnsureStatus ensures status
+func (chu *ClickHouseUser) EnsureStatus() *ChuStatus {
+	if chu == nil {
+		return nil
+	}
+	if chu.Status == nil {
+		chu.Status = &ChuStatus{}
+	}
+	return chu.Status
+}
+
+// GetStatus gets status
+func (chu *ClickHouseUser) GetStatus() *ChuStatus {
+	if chu == nil {
+		return nil
+	}
+	return chu.Status
+}
+
+// GetUserName gets name of the user in ClickHouse
+func (chu *ClickHouseUser) GetUserName() string {
+	if chu == nil {
+		return ""
+	}
+	if chu.Spec.UserName != "" {
+		return chu.Spec.UserName
+	}
+	return chu.Name
+}
+
+// Equals checks whether grants are the same
+func (g ChuGrant) Equals(to ChuGrant) bool {
+	if (g.On != to.On) || (g.WithGrantOption != to.WithGrantOption) || (len(g.Privileges) != len(to.Privileges)) {
+		return false
+	}
+	for i := range g.Privileges {
+		if g.Privileges[i] != to.Privileges[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -15,6 +15,8 @@
 package v1
 
 import (
+	"strings"
+
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -23,10 +25,57 @@ import (
 type VolumeClaimTemplate struct {
 	Name string `json:"name"                    yaml:"name"`
 	StorageManagement
+	Migration  PVCMigration                   `json:"migration,omitempty"     yaml:"migration,omitempty"`
 	ObjectMeta meta.ObjectMeta                `json:"metadata,omitempty"      yaml:"metadata,omitempty"`
 	Spec       core.PersistentVolumeClaimSpec `json:"spec,omitempty"          yaml:"spec,omitempty"`
 }
 
+// PVCMigration defines how data is migrated in case PVC can not be updated in-place, such as storage class change
+type PVCMigration string
+
+// Possible values of PVC migration
+const (
+	PVCMigrationUnspecified PVCMigration = ""
+	PVCMigrationNone        PVCMigration = "None"
+	PVCMigrationCopy        PVCMigration = "Copy"
+)
+
+// NewPVCMigrationFromString creates new PVCMigration from string
+func NewPVCMigrationFromString(s string) PVCMigration {
+	for _, migration := range []PVCMigration{
+		PVCMigrationNone,
+		PVCMigrationCopy,
+	} {
+		if strings.EqualFold(s, migration.String()) {
+			// Known value, ensure case-ness
+			return migration
+		}
+	}
+	return PVCMigration(s)
+}
+
+// IsValid checks whether PVCMigration is valid
+func (v PVCMigration) IsValid() bool {
+	switch v {
+	case
+		PVCMigrationUnspecified,
+		PVCMigrationNone,
+		PVCMigrationCopy:
+		return true
+	}
+	return false
+}
+
+// IsCopy checks whether data should be copied to the new PVC
+func (v PVCMigration) IsCopy() bool {
+	return v == PVCMigrationCopy
+}
+
+// String returns string value for PVCMigration
+func (v PVCMigration) String() string {
+	return string(v)
+}
+
 // PVCProvisioner defines PVC provisioner
 type PVCProvisioner string
 
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -51,6 +52,8 @@ type ClickHouseInstallation struct {
 type ClickHouseInstallationRuntime struct {
 	attributes        *ComparableAttributes `json:"-" yaml:"-"`
 	commonConfigMutex sync.Mutex            `json:"-" yaml:"-"`
+	// referencedSecrets lists Secrets, as namespace/name, the CHI has been normalized with
+	referencedSecrets []string `json:"-" yaml:"-"`
 }
 
 func newClickHouseInstallationRuntime() *ClickHouseInstallationRuntime {
@@ -63,6 +66,25 @@ func (runtime *ClickHouseInstallationRuntime) GetAttributes() *ComparableAttribu
 	return runtime.attributes
 }
 
+// AddReferencedSecret registers Secret the CHI has been normalized with
+func (runtime *ClickHouseInstallationRuntime) AddReferencedSecret(namespace, name string) {
+	key := namespace + "/" + name
+	for _, secret := range runtime.referencedSecrets {
+		if secret == key {
+			return
+		}
+	}
+	runtime.referencedSecrets = append(runtime.referencedSecrets, key)
+}
+
+// GetReferencedSecrets gets Secrets, as namespace/name, the CHI has been normalized with
+func (runtime *ClickHouseInstallationRuntime) GetReferencedSecrets() []string {
+	if runtime == nil {
+		return nil
+	}
+	return runtime.referencedSecrets
+}
+
 func (runtime *ClickHouseInstallationRuntime) LockCommonConfig() {
 	runtime.commonConfigMutex.Lock()
 }
@@ -77,6 +99,8 @@ type ComparableAttributes struct {
 	AdditionalVolumes      []core.Volume      `json:"-" yaml:"-"`
 	AdditionalVolumeMounts []core.VolumeMount `json:"-" yaml:"-"`
 	SkipOwnerRef           bool               `json:"-" yaml:"-"`
+	// UsersStoredInSecret specifies users config files to be delivered in Secret instead of ConfigMap
+	UsersStoredInSecret bool `json:"-" yaml:"-"`
 }
 
 // +genclient
@@ -100,17 +124,22 @@ type ClickHouseOperatorConfiguration struct {
 
 // ChiSpec defines spec section of ClickHouseInstallation resource
 type ChiSpec struct {
-	TaskID                 *string         `json:"taskID,omitempty"                 yaml:"taskID,omitempty"`
-	Stop                   *StringBool     `json:"stop,omitempty"                   yaml:"stop,omitempty"`
-	Restart                string          `json:"restart,omitempty"                yaml:"restart,omitempty"`
-	Troubleshoot           *StringBool     `json:"troubleshoot,omitempty"           yaml:"troubleshoot,omitempty"`
-	NamespaceDomainPattern string          `json:"namespaceDomainPattern,omitempty" yaml:"namespaceDomainPattern,omitempty"`
-	Templating             *ChiTemplating  `json:"templating,omitempty"             yaml:"templating,omitempty"`
-	Reconciling            *ChiReconciling `json:"reconciling,omitempty"            yaml:"reconciling,omitempty"`
-	Defaults               *ChiDefaults    `json:"defaults,omitempty"               yaml:"defaults,omitempty"`
-	Configuration          *Configuration  `json:"configuration,omitempty"          yaml:"configuration,omitempty"`
-	Templates              *Templates      `json:"templates,omitempty"              yaml:"templates,omitempty"`
-	UseTemplates           []*TemplateRef  `json:"useTemplates,omitempty"           yaml:"useTemplates,omitempty"`
+	TaskID                 *string           `json:"taskID,omitempty"                 yaml:"taskID,omitempty"`
+	Stop                   *StringBool       `json:"stop,omitempty"                   yaml:"stop,omitempty"`
+	Restart                string            `json:"restart,omitempty"                yaml:"restart,omitempty"`
+	Troubleshoot           *StringBool       `json:"troubleshoot,omitempty"           yaml:"troubleshoot,omitempty"`
+	NamespaceDomainPattern string            `json:"namespaceDomainPattern,omitempty" yaml:"namespaceDomainPattern,omitempty"`
+	Templating             *ChiTemplating    `json:"templating,omitempty"             yaml:"templating,omitempty"`
+	Reconciling            *ChiReconciling   `json:"reconciling,omitempty"            yaml:"reconciling,omitempty"`
+	Defaults               *ChiDefaults      `json:"defaults,omitempty"               yaml:"defaults,omitempty"`
+	Configuration          *Configuration    `json:"configuration,omitempty"          yaml:"configuration,omitempty"`
+	Templates              *Templates        `json:"templates,omitempty"              yaml:"templates,omitempty"`
+	UseTemplates           []*TemplateRef    `json:"useTemplates,omitempty"           yaml:"useTemplates,omitempty"`
+	Gateway                *ChiGateway       `json:"gateway,omitempty"                yaml:"gateway,omitempty"`
+	DetachedParts          *ChiDetachedParts `json:"detachedParts,omitempty"          yaml:"detachedParts,omitempty"`
+	Upgrade                *ChiUpgrade       `json:"upgrade,omitempty"                yaml:"upgrade,omitempty"`
+	Monitoring             *ChiMonitoring    `json:"monitoring,omitempty"             yaml:"monitoring,omitempty"`
+	DDLQueue               *ChiDDLQueue      `json:"ddlQueue,omitempty"               yaml:"ddlQueue,omitempty"`
 }
 
 // TemplateRef defines UseTemplate section of ClickHouseInstallation resource
@@ -463,8 +492,35 @@ type ChiReconciling struct {
 	Policy string `json:"policy,omitempty" yaml:"policy,omitempty"`
 	// ConfigMapPropagationTimeout specifies timeout for ConfigMap to propagate
 	ConfigMapPropagationTimeout int `json:"configMapPropagationTimeout,omitempty" yaml:"configMapPropagationTimeout,omitempty"`
+	// MaxReplicationDelay specifies max replication delay in seconds a host excluded from the cluster should catch up to
+	// before being included back into the cluster. Replication delay is not checked in case not specified
+	MaxReplicationDelay int `json:"maxReplicationDelay,omitempty" yaml:"maxReplicationDelay,omitempty"`
+	// MaxConcurrentHosts specifies max number of hosts of different shards reconciled concurrently.
+	// Replicas of the same shard are always reconciled one by one. Operator's default is used in case not specified
+	MaxConcurrentHosts int `json:"maxConcurrentHosts,omitempty" yaml:"maxConcurrentHosts,omitempty"`
+	// ConcurrencyPolicy specifies what to do with the in-flight reconcile in case the CHI is edited meanwhile,
+	// either 'queue', 'cancel' or 'coalesce'. Operator's default is used in case not specified
+	ConcurrencyPolicy string `json:"concurrencyPolicy,omitempty" yaml:"concurrencyPolicy,omitempty"`
+	// StatefulSetRecreatePolicy specifies how StatefulSet is recreated in case it can not be updated in-place,
+	// either 'default' or 'graceful'
+	StatefulSetRecreatePolicy string `json:"statefulSetRecreatePolicy,omitempty" yaml:"statefulSetRecreatePolicy,omitempty"`
+	// FailureThreshold specifies number of hosts in a row failing reconcile or health check after being reconciled,
+	// which halts reconcile of the whole CHI. Reconcile is not halted in case not specified
+	FailureThreshold int `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
 	// Cleanup specifies cleanup behavior
 	Cleanup *ChiCleanup `json:"cleanup,omitempty" yaml:"cleanup,omitempty"`
+	// Service specifies reconcile behavior for Services
+	Service *ChiServiceReconciling `json:"service,omitempty" yaml:"service,omitempty"`
+	// Access specifies how the operator accesses ClickHouse hosts
+	Access *ChiAccessReconciling `json:"access,omitempty" yaml:"access,omitempty"`
+	// Timeouts specifies wait timeouts of the CHI, overriding operator-global ones
+	Timeouts *ChiReconcilingTimeouts `json:"timeouts,omitempty" yaml:"timeouts,omitempty"`
+	// PartsBacklog specifies how parts backlog of a host is handled before the host is restarted
+	PartsBacklog *ChiReconcilingPartsBacklog `json:"partsBacklog,omitempty" yaml:"partsBacklog,omitempty"`
+	// PreRestartActions specifies actions performed on a host right before the host is restarted
+	PreRestartActions *ChiReconcilingPreRestartActions `json:"preRestartActions,omitempty" yaml:"preRestartActions,omitempty"`
+	// Secrets specifies how hosts pick up rotated Secrets referenced by pod templates
+	Secrets *ChiReconcilingSecrets `json:"secrets,omitempty" yaml:"secrets,omitempty"`
 }
 
 // NewChiReconciling creates new reconciling
@@ -490,6 +546,21 @@ func (t *ChiReconciling) MergeFrom(from *ChiReconciling, _type MergeType) *ChiRe
 		if t.ConfigMapPropagationTimeout == 0 {
 			t.ConfigMapPropagationTimeout = from.ConfigMapPropagationTimeout
 		}
+		if t.MaxReplicationDelay == 0 {
+			t.MaxReplicationDelay = from.MaxReplicationDelay
+		}
+		if t.MaxConcurrentHosts == 0 {
+			t.MaxConcurrentHosts = from.MaxConcurrentHosts
+		}
+		if t.ConcurrencyPolicy == "" {
+			t.ConcurrencyPolicy = from.ConcurrencyPolicy
+		}
+		if t.StatefulSetRecreatePolicy == "" {
+			t.StatefulSetRecreatePolicy = from.StatefulSetRecreatePolicy
+		}
+		if t.FailureThreshold == 0 {
+			t.FailureThreshold = from.FailureThreshold
+		}
 	case MergeTypeOverrideByNonEmptyValues:
 		if from.Policy != "" {
 			// Override by non-empty values only
@@ -499,9 +570,35 @@ func (t *ChiReconciling) MergeFrom(from *ChiReconciling, _type MergeType) *ChiRe
 			// Override by non-empty values only
 			t.ConfigMapPropagationTimeout = from.ConfigMapPropagationTimeout
 		}
+		if from.MaxReplicationDelay != 0 {
+			// Override by non-empty values only
+			t.MaxReplicationDelay = from.MaxReplicationDelay
+		}
+		if from.MaxConcurrentHosts != 0 {
+			// Override by non-empty values only
+			t.MaxConcurrentHosts = from.MaxConcurrentHosts
+		}
+		if from.ConcurrencyPolicy != "" {
+			// Override by non-empty values only
+			t.ConcurrencyPolicy = from.ConcurrencyPolicy
+		}
+		if from.StatefulSetRecreatePolicy != "" {
+			// Override by non-empty values only
+			t.StatefulSetRecreatePolicy = from.StatefulSetRecreatePolicy
+		}
+		if from.FailureThreshold != 0 {
+			// Override by non-empty values only
+			t.FailureThreshold = from.FailureThreshold
+		}
 	}
 
 	t.Cleanup = t.Cleanup.MergeFrom(from.Cleanup, _type)
+	t.Service = t.Service.MergeFrom(from.Service, _type)
+	t.Access = t.Access.MergeFrom(from.Access, _type)
+	t.Timeouts = t.Timeouts.MergeFrom(from.Timeouts, _type)
+	t.PartsBacklog = t.PartsBacklog.MergeFrom(from.PartsBacklog, _type)
+	t.PreRestartActions = t.PreRestartActions.MergeFrom(from.PreRestartActions, _type)
+	t.Secrets = t.Secrets.MergeFrom(from.Secrets, _type)
 
 	return t
 }
@@ -514,6 +611,7 @@ func (t *ChiReconciling) SetDefaults() *ChiReconciling {
 	t.Policy = ReconcilingPolicyUnspecified
 	t.ConfigMapPropagationTimeout = 10
 	t.Cleanup = NewChiCleanup().SetDefaults()
+	t.Service = NewChiServiceReconciling().SetDefaults()
 	return t
 }
 
@@ -549,14 +647,98 @@ func (t *ChiReconciling) SetConfigMapPropagationTimeout(timeout int) {
 	t.ConfigMapPropagationTimeout = timeout
 }
 
-// GetConfigMapPropagationTimeoutDuration gets config map propagation timeout duration
+// GetConfigMapPropagationTimeoutDuration gets config map propagation timeout duration.
+// Config reload timeout takes precedence over config map propagation timeout
 func (t *ChiReconciling) GetConfigMapPropagationTimeoutDuration() time.Duration {
 	if t == nil {
 		return 0
 	}
+	if timeout := t.GetTimeouts().GetConfigReload(); timeout > 0 {
+		return timeout
+	}
 	return time.Duration(t.GetConfigMapPropagationTimeout()) * time.Second
 }
 
+// GetMaxReplicationDelay gets max replication delay in seconds a host should catch up to before being included into the cluster
+func (t *ChiReconciling) GetMaxReplicationDelay() int {
+	if t == nil {
+		return 0
+	}
+	return t.MaxReplicationDelay
+}
+
+// HasMaxReplicationDelay checks whether replication delay should be checked before including host into the cluster
+func (t *ChiReconciling) HasMaxReplicationDelay() bool {
+	return t.GetMaxReplicationDelay() > 0
+}
+
+// GetMaxConcurrentHosts gets max number of hosts of different shards reconciled concurrently
+func (t *ChiReconciling) GetMaxConcurrentHosts() int {
+	if t == nil {
+		return 0
+	}
+	return t.MaxConcurrentHosts
+}
+
+// GetFailureThreshold gets number of hosts in a row failing reconcile or health check, which halts reconcile of the CHI
+func (t *ChiReconciling) GetFailureThreshold() int {
+	if t == nil {
+		return 0
+	}
+	return t.FailureThreshold
+}
+
+// Possible reconcile concurrency policy values
+const (
+	// ReconcilingConcurrencyPolicyQueue specifies in-flight reconcile is completed and each edit made meanwhile
+	// is reconciled afterwards in order
+	ReconcilingConcurrencyPolicyQueue = "queue"
+	// ReconcilingConcurrencyPolicyCancel specifies in-flight reconcile is cancelled after host(s) in progress
+	// are completed and the latest spec is reconciled afterwards
+	ReconcilingConcurrencyPolicyCancel = "cancel"
+	// ReconcilingConcurrencyPolicyCoalesce specifies in-flight reconcile is completed and all edits made meanwhile
+	// are reconciled afterwards at once, as the latest spec
+	ReconcilingConcurrencyPolicyCoalesce = "coalesce"
+)
+
+// IsReconcilingConcurrencyPolicyValid checks whether reconcile concurrency policy is known
+func IsReconcilingConcurrencyPolicyValid(policy string) bool {
+	switch strings.ToLower(policy) {
+	case
+		ReconcilingConcurrencyPolicyQueue,
+		ReconcilingConcurrencyPolicyCancel,
+		ReconcilingConcurrencyPolicyCoalesce:
+		return true
+	}
+	return false
+}
+
+// GetConcurrencyPolicy gets reconcile concurrency policy
+func (t *ChiReconciling) GetConcurrencyPolicy() string {
+	if t == nil {
+		return ""
+	}
+	return strings.ToLower(t.ConcurrencyPolicy)
+}
+
+// Possible StatefulSet recreate policy values
+const (
+	// ReconcilingStatefulSetRecreatePolicyDefault specifies StatefulSet is deleted and created anew right away
+	ReconcilingStatefulSetRecreatePolicyDefault = "default"
+	// ReconcilingStatefulSetRecreatePolicyGraceful specifies StatefulSet is recreated step by step:
+	// PVC retention is verified, StatefulSet is scaled down to zero and pod termination is confirmed,
+	// StatefulSet is recreated and tables are validated to be in place before the host is considered updated
+	ReconcilingStatefulSetRecreatePolicyGraceful = "graceful"
+)
+
+// IsStatefulSetRecreatePolicyGraceful checks whether StatefulSet should be recreated gracefully
+func (t *ChiReconciling) IsStatefulSetRecreatePolicyGraceful() bool {
+	if t == nil {
+		return false
+	}
+	return strings.ToLower(t.StatefulSetRecreatePolicy) == ReconcilingStatefulSetRecreatePolicyGraceful
+}
+
 // Possible reconcile policy values
 const (
 	ReconcilingPolicyUnspecified = "unspecified"
@@ -582,6 +764,536 @@ func (t *ChiReconciling) GetCleanup() *ChiCleanup {
 	return t.Cleanup
 }
 
+// GetService gets service reconciling
+func (t *ChiReconciling) GetService() *ChiServiceReconciling {
+	if t == nil {
+		return nil
+	}
+	return t.Service
+}
+
+// GetAccess gets access reconciling
+func (t *ChiReconciling) GetAccess() *ChiAccessReconciling {
+	if t == nil {
+		return nil
+	}
+	return t.Access
+}
+
+// GetTimeouts gets timeouts
+func (t *ChiReconciling) GetTimeouts() *ChiReconcilingTimeouts {
+	if t == nil {
+		return nil
+	}
+	return t.Timeouts
+}
+
+// GetPartsBacklog gets parts backlog reconciling
+func (t *ChiReconciling) GetPartsBacklog() *ChiReconcilingPartsBacklog {
+	if t == nil {
+		return nil
+	}
+	return t.PartsBacklog
+}
+
+// GetPreRestartActions gets pre-restart actions
+func (t *ChiReconciling) GetPreRestartActions() *ChiReconcilingPreRestartActions {
+	if t == nil {
+		return nil
+	}
+	return t.PreRestartActions
+}
+
+// GetSecrets gets secrets reconciling
+func (t *ChiReconciling) GetSecrets() *ChiReconcilingSecrets {
+	if t == nil {
+		return nil
+	}
+	return t.Secrets
+}
+
+// Possible service fields policy values
+const (
+	// ServiceFieldsPolicyPreserve keeps cloud/cluster-assigned fields of existing Service,
+	// such as externalIPs, loadBalancerIP, allocated nodePorts and foreign labels and annotations,
+	// unless they are explicitly specified in the service template
+	ServiceFieldsPolicyPreserve = "Preserve"
+	// ServiceFieldsPolicyReplace makes Service to be exactly as specified in the service template.
+	// Only immutable fields are migrated from existing Service
+	ServiceFieldsPolicyReplace = "Replace"
+)
+
+// ChiServiceReconciling defines reconcile behavior for Services
+type ChiServiceReconciling struct {
+	// FieldsPolicy specifies how fields of existing Service not specified in the template are treated
+	FieldsPolicy string `json:"fieldsPolicy,omitempty" yaml:"fieldsPolicy,omitempty"`
+}
+
+// NewChiServiceReconciling creates new service reconciling
+func NewChiServiceReconciling() *ChiServiceReconciling {
+	return new(ChiServiceReconciling)
+}
+
+// MergeFrom merges from specified service reconciling
+func (t *ChiServiceReconciling) MergeFrom(from *ChiServiceReconciling, _type MergeType) *ChiServiceReconciling {
+	if from == nil {
+		return t
+	}
+
+	if t == nil {
+		t = NewChiServiceReconciling()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if t.FieldsPolicy == "" {
+			t.FieldsPolicy = from.FieldsPolicy
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.FieldsPolicy != "" {
+			// Override by non-empty values only
+			t.FieldsPolicy = from.FieldsPolicy
+		}
+	}
+
+	return t
+}
+
+// SetDefaults set default values for service reconciling
+func (t *ChiServiceReconciling) SetDefaults() *ChiServiceReconciling {
+	if t == nil {
+		return nil
+	}
+	t.FieldsPolicy = ServiceFieldsPolicyPreserve
+	return t
+}
+
+// GetFieldsPolicy gets fields policy
+func (t *ChiServiceReconciling) GetFieldsPolicy() string {
+	if t == nil {
+		return ""
+	}
+	return t.FieldsPolicy
+}
+
+// IsFieldsPolicyReplace checks whether fields policy is "replace"
+func (t *ChiServiceReconciling) IsFieldsPolicyReplace() bool {
+	return strings.EqualFold(t.GetFieldsPolicy(), ServiceFieldsPolicyReplace)
+}
+
+// ChiAccessReconciling defines how the operator accesses ClickHouse hosts
+type ChiAccessReconciling struct {
+	// Endpoints specifies how to address ClickHouse hosts, in order of preference.
+	// Overrides operator's 'clickhouse.access.endpoints'
+	Endpoints []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+}
+
+// NewChiAccessReconciling creates new access reconciling
+func NewChiAccessReconciling() *ChiAccessReconciling {
+	return new(ChiAccessReconciling)
+}
+
+// MergeFrom merges from specified access reconciling
+func (t *ChiAccessReconciling) MergeFrom(from *ChiAccessReconciling, _type MergeType) *ChiAccessReconciling {
+	if from == nil {
+		return t
+	}
+
+	if t == nil {
+		t = NewChiAccessReconciling()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if len(t.Endpoints) == 0 {
+			t.Endpoints = append([]string{}, from.Endpoints...)
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if len(from.Endpoints) > 0 {
+			// Override by non-empty values only
+			t.Endpoints = append([]string{}, from.Endpoints...)
+		}
+	}
+
+	return t
+}
+
+// GetEndpoints gets endpoints
+func (t *ChiAccessReconciling) GetEndpoints() []string {
+	if t == nil {
+		return nil
+	}
+	return t.Endpoints
+}
+
+// ChiReconcilingTimeouts defines wait timeouts of the CHI, in seconds.
+// Operator's defaults are used for timeouts not specified
+type ChiReconcilingTimeouts struct {
+	// PodStart specifies how long host's pod is waited for to start and ClickHouse to become reachable
+	PodStart int `json:"podStart,omitempty"          yaml:"podStart,omitempty"`
+	// StatefulSetUpdate specifies how long host's StatefulSet is waited for to be updated and become ready.
+	// Overrides operator's 'reconcile.statefulSet.update.timeout'
+	StatefulSetUpdate int `json:"statefulSetUpdate,omitempty" yaml:"statefulSetUpdate,omitempty"`
+	// ConfigReload specifies how long config files are waited for to propagate into pods and be reloaded.
+	// Overrides '.spec.reconciling.configMapPropagationTimeout'
+	ConfigReload int `json:"configReload,omitempty"      yaml:"configReload,omitempty"`
+	// DDL specifies timeout of schema DDL queries run by the operator on hosts
+	DDL int `json:"ddl,omitempty"               yaml:"ddl,omitempty"`
+}
+
+// NewChiReconcilingTimeouts creates new reconciling timeouts
+func NewChiReconcilingTimeouts() *ChiReconcilingTimeouts {
+	return new(ChiReconcilingTimeouts)
+}
+
+// MergeFrom merges from specified reconciling timeouts
+func (t *ChiReconcilingTimeouts) MergeFrom(from *ChiReconcilingTimeouts, _type MergeType) *ChiReconcilingTimeouts {
+	if from == nil {
+		return t
+	}
+
+	if t == nil {
+		t = NewChiReconcilingTimeouts()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if t.PodStart == 0 {
+			t.PodStart = from.PodStart
+		}
+		if t.StatefulSetUpdate == 0 {
+			t.StatefulSetUpdate = from.StatefulSetUpdate
+		}
+		if t.ConfigReload == 0 {
+			t.ConfigReload = from.ConfigReload
+		}
+		if t.DDL == 0 {
+			t.DDL = from.DDL
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.PodStart != 0 {
+			// Override by non-empty values only
+			t.PodStart = from.PodStart
+		}
+		if from.StatefulSetUpdate != 0 {
+			// Override by non-empty values only
+			t.StatefulSetUpdate = from.StatefulSetUpdate
+		}
+		if from.ConfigReload != 0 {
+			// Override by non-empty values only
+			t.ConfigReload = from.ConfigReload
+		}
+		if from.DDL != 0 {
+			// Override by non-empty values only
+			t.DDL = from.DDL
+		}
+	}
+
+	return t
+}
+
+// toDuration casts timeout in seconds to duration, not specified timeout is zero
+func (t *ChiReconcilingTimeouts) toDuration(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetPodStart gets pod start timeout, zero in case not specified
+func (t *ChiReconcilingTimeouts) GetPodStart() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.toDuration(t.PodStart)
+}
+
+// GetStatefulSetUpdate gets StatefulSet update timeout, zero in case not specified
+func (t *ChiReconcilingTimeouts) GetStatefulSetUpdate() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.toDuration(t.StatefulSetUpdate)
+}
+
+// GetConfigReload gets config reload timeout, zero in case not specified
+func (t *ChiReconcilingTimeouts) GetConfigReload() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.toDuration(t.ConfigReload)
+}
+
+// GetDDL gets DDL queries timeout, zero in case not specified
+func (t *ChiReconcilingTimeouts) GetDDL() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.toDuration(t.DDL)
+}
+
+// defaultPartsBacklogTimeout specifies default timeout of waiting for parts backlog of a host to be merged
+const defaultPartsBacklogTimeout = 10 * time.Minute
+
+// ChiReconcilingPartsBacklog defines how parts backlog of a host is handled before the host is restarted.
+// Restarting replicas with huge number of parts multiplies startup time and replication load,
+// so restart is postponed until merges get number of parts under the threshold
+type ChiReconcilingPartsBacklog struct {
+	// Threshold specifies max number of active parts in a single partition a host may have to be restarted.
+	// Parts backlog is not checked in case not specified
+	Threshold int `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// Timeout specifies how long to wait for merges, in seconds. Host is restarted anyway after timeout
+	Timeout int `json:"timeout,omitempty"   yaml:"timeout,omitempty"`
+	// Optimize specifies whether to run OPTIMIZE ... FINAL on partitions over the threshold
+	Optimize *StringBool `json:"optimize,omitempty"  yaml:"optimize,omitempty"`
+}
+
+// NewChiReconcilingPartsBacklog creates new parts backlog reconciling
+func NewChiReconcilingPartsBacklog() *ChiReconcilingPartsBacklog {
+	return new(ChiReconcilingPartsBacklog)
+}
+
+// MergeFrom merges from specified parts backlog reconciling
+func (t *ChiReconcilingPartsBacklog) MergeFrom(from *ChiReconcilingPartsBacklog, _type MergeType) *ChiReconcilingPartsBacklog {
+	if from == nil {
+		return t
+	}
+
+	if t == nil {
+		t = NewChiReconcilingPartsBacklog()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if t.Threshold == 0 {
+			t.Threshold = from.Threshold
+		}
+		if t.Timeout == 0 {
+			t.Timeout = from.Timeout
+		}
+		if !t.Optimize.HasValue() {
+			t.Optimize = t.Optimize.MergeFrom(from.Optimize)
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.Threshold != 0 {
+			// Override by non-empty values only
+			t.Threshold = from.Threshold
+		}
+		if from.Timeout != 0 {
+			// Override by non-empty values only
+			t.Timeout = from.Timeout
+		}
+		if from.Optimize.HasValue() {
+			// Override by non-empty values only
+			t.Optimize = from.Optimize
+		}
+	}
+
+	return t
+}
+
+// IsEnabled checks whether parts backlog is to be checked before host restart
+func (t *ChiReconcilingPartsBacklog) IsEnabled() bool {
+	return t.GetThreshold() > 0
+}
+
+// GetThreshold gets max number of active parts in a single partition a host may have to be restarted
+func (t *ChiReconcilingPartsBacklog) GetThreshold() int {
+	if t == nil {
+		return 0
+	}
+	return t.Threshold
+}
+
+// GetTimeout gets timeout of waiting for merges
+func (t *ChiReconcilingPartsBacklog) GetTimeout() time.Duration {
+	if (t == nil) || (t.Timeout <= 0) {
+		return defaultPartsBacklogTimeout
+	}
+	return time.Duration(t.Timeout) * time.Second
+}
+
+// IsOptimize checks whether partitions over the threshold are to be optimized
+func (t *ChiReconcilingPartsBacklog) IsOptimize() bool {
+	if t == nil {
+		return false
+	}
+	return t.Optimize.IsTrue()
+}
+
+// ChiReconcilingPreRestartActions defines actions performed on a host right before the host is restarted.
+// Background merges and fetches in progress delay shutdown of a replica, so they can be stopped in advance.
+// Merges and fetches are started back after the host is reconciled, in case the host has not been restarted
+type ChiReconcilingPreRestartActions struct {
+	// StopMerges specifies whether to run SYSTEM STOP MERGES on the host before restart
+	StopMerges *StringBool `json:"stopMerges,omitempty"  yaml:"stopMerges,omitempty"`
+	// StopFetches specifies whether to run SYSTEM STOP FETCHES on the host before restart
+	StopFetches *StringBool `json:"stopFetches,omitempty" yaml:"stopFetches,omitempty"`
+}
+
+// NewChiReconcilingPreRestartActions creates new pre-restart actions
+func NewChiReconcilingPreRestartActions() *ChiReconcilingPreRestartActions {
+	return new(ChiReconcilingPreRestartActions)
+}
+
+// MergeFrom merges from specified pre-restart actions
+func (t *ChiReconcilingPreRestartActions) MergeFrom(from *ChiReconcilingPreRestartActions, _type MergeType) *ChiReconcilingPreRestartActions {
+	if from == nil {
+		return t
+	}
+
+	if t == nil {
+		t = NewChiReconcilingPreRestartActions()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if !t.StopMerges.HasValue() {
+			t.StopMerges = t.StopMerges.MergeFrom(from.StopMerges)
+		}
+		if !t.StopFetches.HasValue() {
+			t.StopFetches = t.StopFetches.MergeFrom(from.StopFetches)
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.StopMerges.HasValue() {
+			// Override by non-empty values only
+			t.StopMerges = from.StopMerges
+		}
+		if from.StopFetches.HasValue() {
+			// Override by non-empty values only
+			t.StopFetches = from.StopFetches
+		}
+	}
+
+	return t
+}
+
+// IsStopMerges checks whether merges are to be stopped before host restart
+func (t *ChiReconcilingPreRestartActions) IsStopMerges() bool {
+	if t == nil {
+		return false
+	}
+	return t.StopMerges.IsTrue()
+}
+
+// IsStopFetches checks whether fetches are to be stopped before host restart
+func (t *ChiReconcilingPreRestartActions) IsStopFetches() bool {
+	if t == nil {
+		return false
+	}
+	return t.StopFetches.IsTrue()
+}
+
+// IsEnabled checks whether any of pre-restart actions is to be performed
+func (t *ChiReconcilingPreRestartActions) IsEnabled() bool {
+	return t.IsStopMerges() || t.IsStopFetches()
+}
+
+// Possible actions on change of a Secret referenced by pod templates
+const (
+	// SecretOnChangeRestart restarts hosts one by one, the same way as any other change of the pod template does
+	SecretOnChangeRestart = "Restart"
+	// SecretOnChangeReload runs SYSTEM RELOAD CONFIG on hosts w/o restart.
+	// Applicable to Secrets mounted as files, such as certificates, which are updated in-place by kubelet
+	SecretOnChangeReload = "Reload"
+	// SecretOnChangeNone ignores changes of a Secret
+	SecretOnChangeNone = "None"
+)
+
+// ChiReconcilingSecrets defines how hosts pick up rotated Secrets, which are referenced by pod templates,
+// such as certificates and credentials provided by the user.
+// Secrets managed by the operator and the cluster secret are not affected
+type ChiReconcilingSecrets struct {
+	// OnChange specifies action on change of any referenced Secret, either 'Restart', 'Reload' or 'None'.
+	// 'Restart' in case not specified
+	OnChange string `json:"onChange,omitempty" yaml:"onChange,omitempty"`
+	// Items specifies action on change per Secret, overriding OnChange
+	Items []ChiReconcilingSecret `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// ChiReconcilingSecret defines action on change of a Secret
+type ChiReconcilingSecret struct {
+	// Name specifies name of the Secret
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// OnChange specifies action on change of the Secret, either 'Restart', 'Reload' or 'None'
+	OnChange string `json:"onChange,omitempty" yaml:"onChange,omitempty"`
+}
+
+// NewChiReconcilingSecrets creates new secrets reconciling
+func NewChiReconcilingSecrets() *ChiReconcilingSecrets {
+	return new(ChiReconcilingSecrets)
+}
+
+// MergeFrom merges from specified secrets reconciling. Items are merged by name
+func (t *ChiReconcilingSecrets) MergeFrom(from *ChiReconcilingSecrets, _type MergeType) *ChiReconcilingSecrets {
+	if from == nil {
+		return t
+	}
+
+	if t == nil {
+		t = NewChiReconcilingSecrets()
+	}
+
+	switch _type {
+	case MergeTypeFillEmptyValues:
+		if t.OnChange == "" {
+			t.OnChange = from.OnChange
+		}
+	case MergeTypeOverrideByNonEmptyValues:
+		if from.OnChange != "" {
+			// Override by non-empty values only
+			t.OnChange = from.OnChange
+		}
+	}
+
+	for _, item := range from.Items {
+		i := t.indexOf(item.Name)
+		switch {
+		case i < 0:
+			t.Items = append(t.Items, item)
+		case _type == MergeTypeFillEmptyValues:
+			if t.Items[i].OnChange == "" {
+				t.Items[i].OnChange = item.OnChange
+			}
+		case _type == MergeTypeOverrideByNonEmptyValues:
+			if item.OnChange != "" {
+				// Override by non-empty values only
+				t.Items[i].OnChange = item.OnChange
+			}
+		}
+	}
+
+	return t
+}
+
+// indexOf gets index of the item with specified name, -1 in case not found
+func (t *ChiReconcilingSecrets) indexOf(name string) int {
+	if t == nil {
+		return -1
+	}
+	for i := range t.Items {
+		if t.Items[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetOnChange gets action on change of the Secret with specified name
+func (t *ChiReconcilingSecrets) GetOnChange(name string) string {
+	if t == nil {
+		return SecretOnChangeNone
+	}
+	if i := t.indexOf(name); (i >= 0) && (t.Items[i].OnChange != "") {
+		return t.Items[i].OnChange
+	}
+	if t.OnChange != "" {
+		return t.OnChange
+	}
+	// Changes are ignored unless explicitly requested, so pods are not restarted on operator upgrade
+	return SecretOnChangeNone
+}
+
 // ChiTemplateNames defines references to .spec.templates to be used on current level of cluster
 type ChiTemplateNames struct {
 	HostTemplate            string `json:"hostTemplate,omitempty"            yaml:"hostTemplate,omitempty"`
@@ -592,6 +1304,13 @@ type ChiTemplateNames struct {
 	ClusterServiceTemplate  string `json:"clusterServiceTemplate,omitempty"  yaml:"clusterServiceTemplate,omitempty"`
 	ShardServiceTemplate    string `json:"shardServiceTemplate,omitempty"    yaml:"shardServiceTemplate,omitempty"`
 	ReplicaServiceTemplate  string `json:"replicaServiceTemplate,omitempty"  yaml:"replicaServiceTemplate,omitempty"`
+	// ClusterServiceTemplates specifies templates of extra Services of the cluster, one Service per template
+	ClusterServiceTemplates []string `json:"clusterServiceTemplates,omitempty" yaml:"clusterServiceTemplates,omitempty"`
+	// ReplicaServiceTemplates specifies templates of extra Services of each host, one Service per template,
+	// so, for example, HTTP port can be exposed via internal load balancer apart from the rest of ports
+	ReplicaServiceTemplates []string `json:"replicaServiceTemplates,omitempty" yaml:"replicaServiceTemplates,omitempty"`
+	// PodDisruptionBudgetTemplate specifies template of PodDisruptionBudgets of the cluster, either cluster- or shard-scoped
+	PodDisruptionBudgetTemplate string `json:"podDisruptionBudgetTemplate,omitempty" yaml:"podDisruptionBudgetTemplate,omitempty"`
 
 	// VolumeClaimTemplate is deprecated in favor of DataVolumeClaimTemplate and LogVolumeClaimTemplate
 	// !!! DEPRECATED !!!
@@ -608,6 +1327,9 @@ type ChiShard struct {
 	Files               *Settings         `json:"files,omitempty"               yaml:"files,omitempty"`
 	Templates           *ChiTemplateNames `json:"templates,omitempty"           yaml:"templates,omitempty"`
 	ReplicasCount       int               `json:"replicasCount,omitempty"       yaml:"replicasCount,omitempty"`
+	// ServiceType specifies type of shard-level Service created w/o ServiceTemplate.
+	// No shard-level Service is created in case neither ServiceType nor ServiceTemplate is specified
+	ServiceType string `json:"serviceType,omitempty" yaml:"serviceType,omitempty"`
 	// TODO refactor into map[string]ChiHost
 	Hosts []*ChiHost `json:"replicas,omitempty" yaml:"replicas,omitempty"`
 
@@ -630,6 +1352,15 @@ type ChiReplica struct {
 	Files       *Settings         `json:"files,omitempty"       yaml:"files,omitempty"`
 	Templates   *ChiTemplateNames `json:"templates,omitempty"   yaml:"templates,omitempty"`
 	ShardsCount int               `json:"shardsCount,omitempty" yaml:"shardsCount,omitempty"`
+	// StartupPriority specifies priority of hosts of the replica to be started within a shard, unless specified by the host explicitly.
+	// Hosts having higher priority are (re)started first, so they are up and merging parts before the rest of replicas
+	StartupPriority int `json:"startupPriority,omitempty" yaml:"startupPriority,omitempty"`
+	// Ports specify ports of all hosts of the replica, unless specified by the host explicitly
+	TCPPort             int32 `json:"tcpPort,omitempty"             yaml:"tcpPort,omitempty"`
+	TLSPort             int32 `json:"tlsPort,omitempty"             yaml:"tlsPort,omitempty"`
+	HTTPPort            int32 `json:"httpPort,omitempty"            yaml:"httpPort,omitempty"`
+	HTTPSPort           int32 `json:"httpsPort,omitempty"           yaml:"httpsPort,omitempty"`
+	InterserverHTTPPort int32 `json:"interserverHTTPPort,omitempty" yaml:"interserverHTTPPort,omitempty"`
 	// TODO refactor into map[string]ChiHost
 	Hosts []*ChiHost `json:"shards,omitempty" yaml:"shards,omitempty"`
 
@@ -688,11 +1419,15 @@ type Templates struct {
 	VolumeClaimTemplates []VolumeClaimTemplate `json:"volumeClaimTemplates,omitempty" yaml:"volumeClaimTemplates,omitempty"`
 	ServiceTemplates     []ServiceTemplate     `json:"serviceTemplates,omitempty"     yaml:"serviceTemplates,omitempty"`
 
+	PodDisruptionBudgetTemplates []PodDisruptionBudgetTemplate `json:"podDisruptionBudgetTemplates,omitempty" yaml:"podDisruptionBudgetTemplates,omitempty"`
+
 	// Index maps template name to template itself
 	HostTemplatesIndex        *HostTemplatesIndex        `json:",omitempty" yaml:",omitempty" testdiff:"ignore"`
 	PodTemplatesIndex         *PodTemplatesIndex         `json:",omitempty" yaml:",omitempty" testdiff:"ignore"`
 	VolumeClaimTemplatesIndex *VolumeClaimTemplatesIndex `json:",omitempty" yaml:",omitempty" testdiff:"ignore"`
 	ServiceTemplatesIndex     *ServiceTemplatesIndex     `json:",omitempty" yaml:",omitempty" testdiff:"ignore"`
+
+	PodDisruptionBudgetTemplatesIndex *PodDisruptionBudgetTemplatesIndex `json:",omitempty" yaml:",omitempty" testdiff:"ignore"`
 }
 
 // PodTemplate defines full Pod Template, directly used by StatefulSet
@@ -727,6 +1462,14 @@ type ServiceTemplate struct {
 	Spec         core.ServiceSpec `json:"spec,omitempty"         yaml:"spec,omitempty"`
 }
 
+// PodDisruptionBudgetTemplate defines CHI PodDisruptionBudget template.
+// Selector of the PodDisruptionBudget is always managed by the operator
+type PodDisruptionBudgetTemplate struct {
+	Name       string                         `json:"name"               yaml:"name"`
+	ObjectMeta meta.ObjectMeta                `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Spec       policy.PodDisruptionBudgetSpec `json:"spec,omitempty"     yaml:"spec,omitempty"`
+}
+
 // ChiDistributedDDL defines distributedDDL section of .spec.defaults
 type ChiDistributedDDL struct {
 	Profile string `json:"profile,omitempty" yaml:"profile"`
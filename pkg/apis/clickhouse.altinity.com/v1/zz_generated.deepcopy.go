@@ -25,7 +25,9 @@ import (
 	swversion "github.com/altinity/clickhouse-operator/pkg/apis/swversion"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	time "time"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -50,6 +52,142 @@ func (in CHISelector) DeepCopy() CHISelector {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChbDestination) DeepCopyInto(out *ChbDestination) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(ChbObjectStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCS != nil {
+		in, out := &in.GCS, &out.GCS
+		*out = new(ChbObjectStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChbDestination.
+func (in *ChbDestination) DeepCopy() *ChbDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(ChbDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChbHostStatus) DeepCopyInto(out *ChbHostStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChbHostStatus.
+func (in *ChbHostStatus) DeepCopy() *ChbHostStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ChbHostStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChbObjectStorage) DeepCopyInto(out *ChbObjectStorage) {
+	*out = *in
+	if in.AccessKeyID != nil {
+		in, out := &in.AccessKeyID, &out.AccessKeyID
+		*out = new(SettingSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretAccessKey != nil {
+		in, out := &in.SecretAccessKey, &out.SecretAccessKey
+		*out = new(SettingSource)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChbObjectStorage.
+func (in *ChbObjectStorage) DeepCopy() *ChbObjectStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(ChbObjectStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChbSpec) DeepCopyInto(out *ChbSpec) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Destination != nil {
+		in, out := &in.Destination, &out.Destination
+		*out = new(ChbDestination)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChbSpec.
+func (in *ChbSpec) DeepCopy() *ChbSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChbSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChbStatus) DeepCopyInto(out *ChbStatus) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]ChbHostStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChbStatus.
+func (in *ChbStatus) DeepCopy() *ChbStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ChbStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiAccessReconciling) DeepCopyInto(out *ChiAccessReconciling) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiAccessReconciling.
+func (in *ChiAccessReconciling) DeepCopy() *ChiAccessReconciling {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiAccessReconciling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChiCleanup) DeepCopyInto(out *ChiCleanup) {
 	*out = *in
@@ -92,6 +230,43 @@ func (in *ChiClusterAddress) DeepCopy() *ChiClusterAddress {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiClusterDNS) DeepCopyInto(out *ChiClusterDNS) {
+	*out = *in
+	if in.ExternalDNS != nil {
+		in, out := &in.ExternalDNS, &out.ExternalDNS
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiClusterDNS.
+func (in *ChiClusterDNS) DeepCopy() *ChiClusterDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiClusterDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiClusterFootprint) DeepCopyInto(out *ChiClusterFootprint) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiClusterFootprint.
+func (in *ChiClusterFootprint) DeepCopy() *ChiClusterFootprint {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiClusterFootprint)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChiClusterLayout) DeepCopyInto(out *ChiClusterLayout) {
 	*out = *in
@@ -127,6 +302,27 @@ func (in *ChiClusterLayout) DeepCopy() *ChiClusterLayout {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiDDLQueue) DeepCopyInto(out *ChiDDLQueue) {
+	*out = *in
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiDDLQueue.
+func (in *ChiDDLQueue) DeepCopy() *ChiDDLQueue {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiDDLQueue)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChiDefaults) DeepCopyInto(out *ChiDefaults) {
 	*out = *in
@@ -148,8 +344,18 @@ func (in *ChiDefaults) DeepCopyInto(out *ChiDefaults) {
 	if in.Templates != nil {
 		in, out := &in.Templates, &out.Templates
 		*out = new(ChiTemplateNames)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InjectSidecars != nil {
+		in, out := &in.InjectSidecars, &out.InjectSidecars
+		*out = new(StringBool)
 		**out = **in
 	}
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
+		*out = new(ChiProbes)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -164,101 +370,106 @@ func (in *ChiDefaults) DeepCopy() *ChiDefaults {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiDistributedDDL) DeepCopyInto(out *ChiDistributedDDL) {
+func (in *ChiDetachedParts) DeepCopyInto(out *ChiDetachedParts) {
 	*out = *in
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = new(ChiDetachedPartsCleanup)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiDistributedDDL.
-func (in *ChiDistributedDDL) DeepCopy() *ChiDistributedDDL {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiDetachedParts.
+func (in *ChiDetachedParts) DeepCopy() *ChiDetachedParts {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiDistributedDDL)
+	out := new(ChiDetachedParts)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiHost) DeepCopyInto(out *ChiHost) {
+func (in *ChiDetachedPartsCleanup) DeepCopyInto(out *ChiDetachedPartsCleanup) {
 	*out = *in
-	if in.Insecure != nil {
-		in, out := &in.Insecure, &out.Insecure
-		*out = new(StringBool)
-		**out = **in
-	}
-	if in.Secure != nil {
-		in, out := &in.Secure, &out.Secure
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
 		*out = new(StringBool)
 		**out = **in
 	}
-	if in.Settings != nil {
-		in, out := &in.Settings, &out.Settings
-		*out = new(Settings)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Files != nil {
-		in, out := &in.Files, &out.Files
-		*out = new(Settings)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Templates != nil {
-		in, out := &in.Templates, &out.Templates
-		*out = new(ChiTemplateNames)
-		**out = **in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	in.Runtime.DeepCopyInto(&out.Runtime)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHost.
-func (in *ChiHost) DeepCopy() *ChiHost {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiDetachedPartsCleanup.
+func (in *ChiDetachedPartsCleanup) DeepCopy() *ChiDetachedPartsCleanup {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiHost)
+	out := new(ChiDetachedPartsCleanup)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiHostAddress) DeepCopyInto(out *ChiHostAddress) {
+func (in *ChiDistributedDDL) DeepCopyInto(out *ChiDistributedDDL) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostAddress.
-func (in *ChiHostAddress) DeepCopy() *ChiHostAddress {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiDistributedDDL.
+func (in *ChiDistributedDDL) DeepCopy() *ChiDistributedDDL {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiHostAddress)
+	out := new(ChiDistributedDDL)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiHostConfig) DeepCopyInto(out *ChiHostConfig) {
+func (in *ChiFootprint) DeepCopyInto(out *ChiFootprint) {
 	*out = *in
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]ChiClusterFootprint, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostConfig.
-func (in *ChiHostConfig) DeepCopy() *ChiHostConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiFootprint.
+func (in *ChiFootprint) DeepCopy() *ChiFootprint {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiHostConfig)
+	out := new(ChiFootprint)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiHostReconcileAttributesCounters) DeepCopyInto(out *ChiHostReconcileAttributesCounters) {
+func (in *ChiFunction) DeepCopyInto(out *ChiFunction) {
 	*out = *in
-	if in.status != nil {
-		in, out := &in.status, &out.status
-		*out = make(map[ObjectStatus]int, len(*in))
+	if in.Arguments != nil {
+		in, out := &in.Arguments, &out.Arguments
+		*out = make([]*ChiFunctionArgument, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(ChiFunctionArgument)
+				**out = **in
+			}
+		}
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
@@ -266,99 +477,140 @@ func (in *ChiHostReconcileAttributesCounters) DeepCopyInto(out *ChiHostReconcile
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostReconcileAttributesCounters.
-func (in *ChiHostReconcileAttributesCounters) DeepCopy() *ChiHostReconcileAttributesCounters {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiFunction.
+func (in *ChiFunction) DeepCopy() *ChiFunction {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiHostReconcileAttributesCounters)
+	out := new(ChiFunction)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiHostRuntime) DeepCopyInto(out *ChiHostRuntime) {
+func (in *ChiFunctionArgument) DeepCopyInto(out *ChiFunctionArgument) {
 	*out = *in
-	out.Address = in.Address
-	out.Config = in.Config
-	if in.Version != nil {
-		in, out := &in.Version, &out.Version
-		*out = new(swversion.SoftWareVersion)
-		**out = **in
-	}
-	if in.reconcileAttributes != nil {
-		in, out := &in.reconcileAttributes, &out.reconcileAttributes
-		*out = new(HostReconcileAttributes)
-		**out = **in
-	}
-	if in.CurStatefulSet != nil {
-		in, out := &in.CurStatefulSet, &out.CurStatefulSet
-		*out = new(appsv1.StatefulSet)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.DesiredStatefulSet != nil {
-		in, out := &in.DesiredStatefulSet, &out.DesiredStatefulSet
-		*out = new(appsv1.StatefulSet)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.CHI != nil {
-		in, out := &in.CHI, &out.CHI
-		*out = new(ClickHouseInstallation)
-		(*in).DeepCopyInto(*out)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostRuntime.
-func (in *ChiHostRuntime) DeepCopy() *ChiHostRuntime {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiFunctionArgument.
+func (in *ChiFunctionArgument) DeepCopy() *ChiFunctionArgument {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiHostRuntime)
+	out := new(ChiFunctionArgument)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiObjectsCleanup) DeepCopyInto(out *ChiObjectsCleanup) {
+func (in *ChiFunctions) DeepCopyInto(out *ChiFunctions) {
 	*out = *in
+	if in.Functions != nil {
+		in, out := &in.Functions, &out.Functions
+		*out = make([]*ChiFunction, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(ChiFunction)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiObjectsCleanup.
-func (in *ChiObjectsCleanup) DeepCopy() *ChiObjectsCleanup {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiFunctions.
+func (in *ChiFunctions) DeepCopy() *ChiFunctions {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiObjectsCleanup)
+	out := new(ChiFunctions)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiReconciling) DeepCopyInto(out *ChiReconciling) {
+func (in *ChiGateway) DeepCopyInto(out *ChiGateway) {
 	*out = *in
-	if in.Cleanup != nil {
-		in, out := &in.Cleanup, &out.Cleanup
-		*out = new(ChiCleanup)
+	if in.GatewayRef != nil {
+		in, out := &in.GatewayRef, &out.GatewayRef
+		*out = new(ChiGatewayRef)
+		**out = **in
+	}
+	if in.TCP != nil {
+		in, out := &in.TCP, &out.TCP
+		*out = new(ChiGatewayRoute)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ChiGatewayRoute)
 		(*in).DeepCopyInto(*out)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReconciling.
-func (in *ChiReconciling) DeepCopy() *ChiReconciling {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiGateway.
+func (in *ChiGateway) DeepCopy() *ChiGateway {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiReconciling)
+	out := new(ChiGateway)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiReplica) DeepCopyInto(out *ChiReplica) {
+func (in *ChiGatewayRef) DeepCopyInto(out *ChiGatewayRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiGatewayRef.
+func (in *ChiGatewayRef) DeepCopy() *ChiGatewayRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiGatewayRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiGatewayRoute) DeepCopyInto(out *ChiGatewayRoute) {
+	*out = *in
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiGatewayRoute.
+func (in *ChiGatewayRoute) DeepCopy() *ChiGatewayRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiGatewayRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiHost) DeepCopyInto(out *ChiHost) {
 	*out = *in
+	if in.Insecure != nil {
+		in, out := &in.Insecure, &out.Insecure
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.Secure != nil {
+		in, out := &in.Secure, &out.Secure
+		*out = new(StringBool)
+		**out = **in
+	}
 	if in.Settings != nil {
 		in, out := &in.Settings, &out.Settings
 		*out = new(Settings)
@@ -372,352 +624,1467 @@ func (in *ChiReplica) DeepCopyInto(out *ChiReplica) {
 	if in.Templates != nil {
 		in, out := &in.Templates, &out.Templates
 		*out = new(ChiTemplateNames)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(StringBool)
 		**out = **in
 	}
-	if in.Hosts != nil {
-		in, out := &in.Hosts, &out.Hosts
-		*out = make([]*ChiHost, len(*in))
+	if in.InterserverCredentials != nil {
+		in, out := &in.InterserverCredentials, &out.InterserverCredentials
+		*out = new(ChiInterserverCredentials)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Runtime.DeepCopyInto(&out.Runtime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHost.
+func (in *ChiHost) DeepCopy() *ChiHost {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiHost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiHostAddress) DeepCopyInto(out *ChiHostAddress) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostAddress.
+func (in *ChiHostAddress) DeepCopy() *ChiHostAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiHostAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiHostConfig) DeepCopyInto(out *ChiHostConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostConfig.
+func (in *ChiHostConfig) DeepCopy() *ChiHostConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiHostConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiHostDetachedParts) DeepCopyInto(out *ChiHostDetachedParts) {
+	*out = *in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostDetachedParts.
+func (in *ChiHostDetachedParts) DeepCopy() *ChiHostDetachedParts {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiHostDetachedParts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiHostReconcileAttributesCounters) DeepCopyInto(out *ChiHostReconcileAttributesCounters) {
+	*out = *in
+	if in.status != nil {
+		in, out := &in.status, &out.status
+		*out = make(map[ObjectStatus]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostReconcileAttributesCounters.
+func (in *ChiHostReconcileAttributesCounters) DeepCopy() *ChiHostReconcileAttributesCounters {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiHostReconcileAttributesCounters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiHostRuntime) DeepCopyInto(out *ChiHostRuntime) {
+	*out = *in
+	out.Address = in.Address
+	out.Config = in.Config
+	if in.Version != nil {
+		in, out := &in.Version, &out.Version
+		*out = new(swversion.SoftWareVersion)
+		**out = **in
+	}
+	if in.reconcileAttributes != nil {
+		in, out := &in.reconcileAttributes, &out.reconcileAttributes
+		*out = new(HostReconcileAttributes)
+		**out = **in
+	}
+	if in.CurStatefulSet != nil {
+		in, out := &in.CurStatefulSet, &out.CurStatefulSet
+		*out = new(appsv1.StatefulSet)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DesiredStatefulSet != nil {
+		in, out := &in.DesiredStatefulSet, &out.DesiredStatefulSet
+		*out = new(appsv1.StatefulSet)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CHI != nil {
+		in, out := &in.CHI, &out.CHI
+		*out = new(ClickHouseInstallation)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostRuntime.
+func (in *ChiHostRuntime) DeepCopy() *ChiHostRuntime {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiHostRuntime)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiHostStatus) DeepCopyInto(out *ChiHostStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
-			if (*in)[i] != nil {
-				in, out := &(*in)[i], &(*out)[i]
-				*out = new(ChiHost)
-				(*in).DeepCopyInto(*out)
-			}
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	in.Runtime.DeepCopyInto(&out.Runtime)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReplica.
-func (in *ChiReplica) DeepCopy() *ChiReplica {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiHostStatus.
+func (in *ChiHostStatus) DeepCopy() *ChiHostStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiHostStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiInterserverCredentials) DeepCopyInto(out *ChiInterserverCredentials) {
+	*out = *in
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(SettingSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowEmpty != nil {
+		in, out := &in.AllowEmpty, &out.AllowEmpty
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiInterserverCredentials.
+func (in *ChiInterserverCredentials) DeepCopy() *ChiInterserverCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiInterserverCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiKeeperMigration) DeepCopyInto(out *ChiKeeperMigration) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiKeeperMigration.
+func (in *ChiKeeperMigration) DeepCopy() *ChiKeeperMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiKeeperMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiKafka) DeepCopyInto(out *ChiKafka) {
+	*out = *in
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SASL != nil {
+		in, out := &in.SASL, &out.SASL
+		*out = new(ChiStreamingSASL)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ChiStreamingTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiKafka.
+func (in *ChiKafka) DeepCopy() *ChiKafka {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiKafka)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiLogTable) DeepCopyInto(out *ChiLogTable) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiLogTable.
+func (in *ChiLogTable) DeepCopy() *ChiLogTable {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiLogTable)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in ChiLogTables) DeepCopyInto(out *ChiLogTables) {
+	{
+		in := &in
+		*out = make(ChiLogTables, len(*in))
+		for key, val := range *in {
+			var outVal *ChiLogTable
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(ChiLogTable)
+				(*in).DeepCopyInto(*out)
+			}
+			(*out)[key] = outVal
+		}
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiLogTables.
+func (in ChiLogTables) DeepCopy() ChiLogTables {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiLogTables)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiLogger) DeepCopyInto(out *ChiLogger) {
+	*out = *in
+	if in.Console != nil {
+		in, out := &in.Console, &out.Console
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiLogger.
+func (in *ChiLogger) DeepCopy() *ChiLogger {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiLogger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiMonitoring) DeepCopyInto(out *ChiMonitoring) {
+	*out = *in
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ChiServiceMonitor)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiMonitoring.
+func (in *ChiMonitoring) DeepCopy() *ChiMonitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiMonitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiObjectsCleanup) DeepCopyInto(out *ChiObjectsCleanup) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiObjectsCleanup.
+func (in *ChiObjectsCleanup) DeepCopy() *ChiObjectsCleanup {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiObjectsCleanup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiProbe) DeepCopyInto(out *ChiProbe) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiProbe.
+func (in *ChiProbe) DeepCopy() *ChiProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiProbes) DeepCopyInto(out *ChiProbes) {
+	*out = *in
+	if in.Readiness != nil {
+		in, out := &in.Readiness, &out.Readiness
+		*out = new(ChiProbe)
+		**out = **in
+	}
+	if in.Liveness != nil {
+		in, out := &in.Liveness, &out.Liveness
+		*out = new(ChiProbe)
+		**out = **in
+	}
+	if in.Startup != nil {
+		in, out := &in.Startup, &out.Startup
+		*out = new(ChiProbe)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiProbes.
+func (in *ChiProbes) DeepCopy() *ChiProbes {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiProbes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiRabbitMQ) DeepCopyInto(out *ChiRabbitMQ) {
+	*out = *in
+	if in.Secure != nil {
+		in, out := &in.Secure, &out.Secure
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(SettingSource)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiRabbitMQ.
+func (in *ChiRabbitMQ) DeepCopy() *ChiRabbitMQ {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiRabbitMQ)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReconciling) DeepCopyInto(out *ChiReconciling) {
+	*out = *in
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = new(ChiCleanup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ChiServiceReconciling)
+		**out = **in
+	}
+	if in.Access != nil {
+		in, out := &in.Access, &out.Access
+		*out = new(ChiAccessReconciling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeouts != nil {
+		in, out := &in.Timeouts, &out.Timeouts
+		*out = new(ChiReconcilingTimeouts)
+		**out = **in
+	}
+	if in.PartsBacklog != nil {
+		in, out := &in.PartsBacklog, &out.PartsBacklog
+		*out = new(ChiReconcilingPartsBacklog)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreRestartActions != nil {
+		in, out := &in.PreRestartActions, &out.PreRestartActions
+		*out = new(ChiReconcilingPreRestartActions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = new(ChiReconcilingSecrets)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReconciling.
+func (in *ChiReconciling) DeepCopy() *ChiReconciling {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReconciling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReconcilingPartsBacklog) DeepCopyInto(out *ChiReconcilingPartsBacklog) {
+	*out = *in
+	if in.Optimize != nil {
+		in, out := &in.Optimize, &out.Optimize
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReconcilingPartsBacklog.
+func (in *ChiReconcilingPartsBacklog) DeepCopy() *ChiReconcilingPartsBacklog {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReconcilingPartsBacklog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReconcilingPreRestartActions) DeepCopyInto(out *ChiReconcilingPreRestartActions) {
+	*out = *in
+	if in.StopMerges != nil {
+		in, out := &in.StopMerges, &out.StopMerges
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.StopFetches != nil {
+		in, out := &in.StopFetches, &out.StopFetches
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReconcilingPreRestartActions.
+func (in *ChiReconcilingPreRestartActions) DeepCopy() *ChiReconcilingPreRestartActions {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReconcilingPreRestartActions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReconcilingSecret) DeepCopyInto(out *ChiReconcilingSecret) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReconcilingSecret.
+func (in *ChiReconcilingSecret) DeepCopy() *ChiReconcilingSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReconcilingSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReconcilingSecrets) DeepCopyInto(out *ChiReconcilingSecrets) {
+	*out = *in
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ChiReconcilingSecret, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReconcilingSecrets.
+func (in *ChiReconcilingSecrets) DeepCopy() *ChiReconcilingSecrets {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReconcilingSecrets)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReconcilingTimeouts) DeepCopyInto(out *ChiReconcilingTimeouts) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReconcilingTimeouts.
+func (in *ChiReconcilingTimeouts) DeepCopy() *ChiReconcilingTimeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReconcilingTimeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReplica) DeepCopyInto(out *ChiReplica) {
+	*out = *in
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = new(Settings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = new(Settings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = new(ChiTemplateNames)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]*ChiHost, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(ChiHost)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	in.Runtime.DeepCopyInto(&out.Runtime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReplica.
+func (in *ChiReplica) DeepCopy() *ChiReplica {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReplica)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReplicaAddress) DeepCopyInto(out *ChiReplicaAddress) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReplicaAddress.
+func (in *ChiReplicaAddress) DeepCopy() *ChiReplicaAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReplicaAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiReplicaRuntime) DeepCopyInto(out *ChiReplicaRuntime) {
+	*out = *in
+	out.Address = in.Address
+	if in.CHI != nil {
+		in, out := &in.CHI, &out.CHI
+		*out = new(ClickHouseInstallation)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReplicaRuntime.
+func (in *ChiReplicaRuntime) DeepCopy() *ChiReplicaRuntime {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiReplicaRuntime)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiSchemaPlan) DeepCopyInto(out *ChiSchemaPlan) {
+	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Existing != nil {
+		in, out := &in.Existing, &out.Existing
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Differs != nil {
+		in, out := &in.Differs, &out.Differs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiSchemaPlan.
+func (in *ChiSchemaPlan) DeepCopy() *ChiSchemaPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiSchemaPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiSecurity) DeepCopyInto(out *ChiSecurity) {
+	*out = *in
+	if in.RemoteURLAllowHosts != nil {
+		in, out := &in.RemoteURLAllowHosts, &out.RemoteURLAllowHosts
+		*out = new(RemoteURLAllowHosts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.QueryComplexity != nil {
+		in, out := &in.QueryComplexity, &out.QueryComplexity
+		*out = new(QueryComplexity)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiSecurity.
+func (in *ChiSecurity) DeepCopy() *ChiSecurity {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiSecurity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiServiceMonitor) DeepCopyInto(out *ChiServiceMonitor) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiServiceMonitor.
+func (in *ChiServiceMonitor) DeepCopy() *ChiServiceMonitor {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiServiceMonitor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiServiceReconciling) DeepCopyInto(out *ChiServiceReconciling) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiServiceReconciling.
+func (in *ChiServiceReconciling) DeepCopy() *ChiServiceReconciling {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiServiceReconciling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiShard) DeepCopyInto(out *ChiShard) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int)
+		**out = **in
+	}
+	if in.InternalReplication != nil {
+		in, out := &in.InternalReplication, &out.InternalReplication
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = new(Settings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = new(Settings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = new(ChiTemplateNames)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]*ChiHost, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(ChiHost)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	in.Runtime.DeepCopyInto(&out.Runtime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiShard.
+func (in *ChiShard) DeepCopy() *ChiShard {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiShard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiShardAddress) DeepCopyInto(out *ChiShardAddress) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiShardAddress.
+func (in *ChiShardAddress) DeepCopy() *ChiShardAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiShardAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiShardRuntime) DeepCopyInto(out *ChiShardRuntime) {
+	*out = *in
+	out.Address = in.Address
+	if in.CHI != nil {
+		in, out := &in.CHI, &out.CHI
+		*out = new(ClickHouseInstallation)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiShardRuntime.
+func (in *ChiShardRuntime) DeepCopy() *ChiShardRuntime {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiShardRuntime)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiSpec) DeepCopyInto(out *ChiSpec) {
+	*out = *in
+	if in.TaskID != nil {
+		in, out := &in.TaskID, &out.TaskID
+		*out = new(string)
+		**out = **in
+	}
+	if in.Stop != nil {
+		in, out := &in.Stop, &out.Stop
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.Troubleshoot != nil {
+		in, out := &in.Troubleshoot, &out.Troubleshoot
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.Templating != nil {
+		in, out := &in.Templating, &out.Templating
+		*out = new(ChiTemplating)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reconciling != nil {
+		in, out := &in.Reconciling, &out.Reconciling
+		*out = new(ChiReconciling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Defaults != nil {
+		in, out := &in.Defaults, &out.Defaults
+		*out = new(ChiDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Configuration != nil {
+		in, out := &in.Configuration, &out.Configuration
+		*out = new(Configuration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = new(Templates)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UseTemplates != nil {
+		in, out := &in.UseTemplates, &out.UseTemplates
+		*out = make([]*TemplateRef, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(TemplateRef)
+				**out = **in
+			}
+		}
+	}
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(ChiGateway)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DetachedParts != nil {
+		in, out := &in.DetachedParts, &out.DetachedParts
+		*out = new(ChiDetachedParts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(ChiUpgrade)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(ChiMonitoring)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DDLQueue != nil {
+		in, out := &in.DDLQueue, &out.DDLQueue
+		*out = new(ChiDDLQueue)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiSpec.
+func (in *ChiSpec) DeepCopy() *ChiSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiStatus) DeepCopyInto(out *ChiStatus) {
+	*out = *in
+	if in.TaskIDsStarted != nil {
+		in, out := &in.TaskIDsStarted, &out.TaskIDsStarted
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TaskIDsCompleted != nil {
+		in, out := &in.TaskIDsCompleted, &out.TaskIDsCompleted
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodIPs != nil {
+		in, out := &in.PodIPs, &out.PodIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.FQDNs != nil {
+		in, out := &in.FQDNs, &out.FQDNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NormalizedCHI != nil {
+		in, out := &in.NormalizedCHI, &out.NormalizedCHI
+		*out = new(ClickHouseInstallation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NormalizedCHICompleted != nil {
+		in, out := &in.NormalizedCHICompleted, &out.NormalizedCHICompleted
+		*out = new(ClickHouseInstallation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostsWithTablesCreated != nil {
+		in, out := &in.HostsWithTablesCreated, &out.HostsWithTablesCreated
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UsedTemplates != nil {
+		in, out := &in.UsedTemplates, &out.UsedTemplates
+		*out = make([]*TemplateRef, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(TemplateRef)
+				**out = **in
+			}
+		}
+	}
+	if in.BlockingTables != nil {
+		in, out := &in.BlockingTables, &out.BlockingTables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SchemaPlans != nil {
+		in, out := &in.SchemaPlans, &out.SchemaPlans
+		*out = make([]ChiSchemaPlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.KeeperMigrations != nil {
+		in, out := &in.KeeperMigrations, &out.KeeperMigrations
+		*out = make([]ChiKeeperMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HostStatuses != nil {
+		in, out := &in.HostStatuses, &out.HostStatuses
+		*out = make([]ChiHostStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DetachedParts != nil {
+		in, out := &in.DetachedParts, &out.DetachedParts
+		*out = make([]ChiHostDetachedParts, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Footprint != nil {
+		in, out := &in.Footprint, &out.Footprint
+		*out = new(ChiFootprint)
+		(*in).DeepCopyInto(*out)
+	}
+	out.mu = in.mu
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiStatus.
+func (in *ChiStatus) DeepCopy() *ChiStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiStreaming) DeepCopyInto(out *ChiStreaming) {
+	*out = *in
+	if in.Kafka != nil {
+		in, out := &in.Kafka, &out.Kafka
+		*out = new(ChiKafka)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RabbitMQ != nil {
+		in, out := &in.RabbitMQ, &out.RabbitMQ
+		*out = new(ChiRabbitMQ)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiStreaming.
+func (in *ChiStreaming) DeepCopy() *ChiStreaming {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiStreaming)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiStreamingSASL) DeepCopyInto(out *ChiStreamingSASL) {
+	*out = *in
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(SettingSource)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiStreamingSASL.
+func (in *ChiStreamingSASL) DeepCopy() *ChiStreamingSASL {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiStreamingSASL)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiStreamingTLS) DeepCopyInto(out *ChiStreamingTLS) {
+	*out = *in
+	if in.KeyPassword != nil {
+		in, out := &in.KeyPassword, &out.KeyPassword
+		*out = new(SettingSource)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiStreamingTLS.
+func (in *ChiStreamingTLS) DeepCopy() *ChiStreamingTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiStreamingTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiTemplateNames) DeepCopyInto(out *ChiTemplateNames) {
+	*out = *in
+	if in.ClusterServiceTemplates != nil {
+		in, out := &in.ClusterServiceTemplates, &out.ClusterServiceTemplates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReplicaServiceTemplates != nil {
+		in, out := &in.ReplicaServiceTemplates, &out.ReplicaServiceTemplates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiTemplateNames.
+func (in *ChiTemplateNames) DeepCopy() *ChiTemplateNames {
+	if in == nil {
+		return nil
+	}
+	out := new(ChiTemplateNames)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChiTemplating) DeepCopyInto(out *ChiTemplating) {
+	*out = *in
+	if in.CHISelector != nil {
+		in, out := &in.CHISelector, &out.CHISelector
+		*out = make(CHISelector, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiTemplating.
+func (in *ChiTemplating) DeepCopy() *ChiTemplating {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiReplica)
+	out := new(ChiTemplating)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiReplicaAddress) DeepCopyInto(out *ChiReplicaAddress) {
+func (in *ChiUpgrade) DeepCopyInto(out *ChiUpgrade) {
 	*out = *in
+	if in.AutoApply != nil {
+		in, out := &in.AutoApply, &out.AutoApply
+		*out = new(StringBool)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReplicaAddress.
-func (in *ChiReplicaAddress) DeepCopy() *ChiReplicaAddress {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiUpgrade.
+func (in *ChiUpgrade) DeepCopy() *ChiUpgrade {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiReplicaAddress)
+	out := new(ChiUpgrade)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiReplicaRuntime) DeepCopyInto(out *ChiReplicaRuntime) {
+func (in *ChiZookeeperConfig) DeepCopyInto(out *ChiZookeeperConfig) {
 	*out = *in
-	out.Address = in.Address
-	if in.CHI != nil {
-		in, out := &in.CHI, &out.CHI
-		*out = new(ClickHouseInstallation)
-		(*in).DeepCopyInto(*out)
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]ChiZookeeperNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiReplicaRuntime.
-func (in *ChiReplicaRuntime) DeepCopy() *ChiReplicaRuntime {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiZookeeperConfig.
+func (in *ChiZookeeperConfig) DeepCopy() *ChiZookeeperConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiReplicaRuntime)
+	out := new(ChiZookeeperConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiShard) DeepCopyInto(out *ChiShard) {
+func (in *ChiZookeeperNode) DeepCopyInto(out *ChiZookeeperNode) {
 	*out = *in
-	if in.Weight != nil {
-		in, out := &in.Weight, &out.Weight
-		*out = new(int)
-		**out = **in
-	}
-	if in.InternalReplication != nil {
-		in, out := &in.InternalReplication, &out.InternalReplication
+	if in.Secure != nil {
+		in, out := &in.Secure, &out.Secure
 		*out = new(StringBool)
 		**out = **in
 	}
-	if in.Settings != nil {
-		in, out := &in.Settings, &out.Settings
-		*out = new(Settings)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Files != nil {
-		in, out := &in.Files, &out.Files
-		*out = new(Settings)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Templates != nil {
-		in, out := &in.Templates, &out.Templates
-		*out = new(ChiTemplateNames)
-		**out = **in
-	}
-	if in.Hosts != nil {
-		in, out := &in.Hosts, &out.Hosts
-		*out = make([]*ChiHost, len(*in))
-		for i := range *in {
-			if (*in)[i] != nil {
-				in, out := &(*in)[i], &(*out)[i]
-				*out = new(ChiHost)
-				(*in).DeepCopyInto(*out)
-			}
-		}
-	}
-	in.Runtime.DeepCopyInto(&out.Runtime)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiShard.
-func (in *ChiShard) DeepCopy() *ChiShard {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiZookeeperNode.
+func (in *ChiZookeeperNode) DeepCopy() *ChiZookeeperNode {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiShard)
+	out := new(ChiZookeeperNode)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiShardAddress) DeepCopyInto(out *ChiShardAddress) {
+func (in *ChqHostError) DeepCopyInto(out *ChqHostError) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiShardAddress.
-func (in *ChiShardAddress) DeepCopy() *ChiShardAddress {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChqHostError.
+func (in *ChqHostError) DeepCopy() *ChqHostError {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiShardAddress)
+	out := new(ChqHostError)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiShardRuntime) DeepCopyInto(out *ChiShardRuntime) {
+func (in *ChqSpec) DeepCopyInto(out *ChqSpec) {
 	*out = *in
-	out.Address = in.Address
-	if in.CHI != nil {
-		in, out := &in.CHI, &out.CHI
-		*out = new(ClickHouseInstallation)
-		(*in).DeepCopyInto(*out)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiShardRuntime.
-func (in *ChiShardRuntime) DeepCopy() *ChiShardRuntime {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChqSpec.
+func (in *ChqSpec) DeepCopy() *ChqSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiShardRuntime)
+	out := new(ChqSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiSpec) DeepCopyInto(out *ChiSpec) {
+func (in *ChqStatus) DeepCopyInto(out *ChqStatus) {
 	*out = *in
-	if in.TaskID != nil {
-		in, out := &in.TaskID, &out.TaskID
-		*out = new(string)
-		**out = **in
-	}
-	if in.Stop != nil {
-		in, out := &in.Stop, &out.Stop
-		*out = new(StringBool)
-		**out = **in
-	}
-	if in.Troubleshoot != nil {
-		in, out := &in.Troubleshoot, &out.Troubleshoot
-		*out = new(StringBool)
-		**out = **in
-	}
-	if in.Templating != nil {
-		in, out := &in.Templating, &out.Templating
-		*out = new(ChiTemplating)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Reconciling != nil {
-		in, out := &in.Reconciling, &out.Reconciling
-		*out = new(ChiReconciling)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Defaults != nil {
-		in, out := &in.Defaults, &out.Defaults
-		*out = new(ChiDefaults)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Configuration != nil {
-		in, out := &in.Configuration, &out.Configuration
-		*out = new(Configuration)
-		(*in).DeepCopyInto(*out)
+	if in.Finished != nil {
+		in, out := &in.Finished, &out.Finished
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.Templates != nil {
-		in, out := &in.Templates, &out.Templates
-		*out = new(Templates)
-		(*in).DeepCopyInto(*out)
+	if in.Failed != nil {
+		in, out := &in.Failed, &out.Failed
+		*out = make([]ChqHostError, len(*in))
+		copy(*out, *in)
 	}
-	if in.UseTemplates != nil {
-		in, out := &in.UseTemplates, &out.UseTemplates
-		*out = make([]*TemplateRef, len(*in))
-		for i := range *in {
-			if (*in)[i] != nil {
-				in, out := &(*in)[i], &(*out)[i]
-				*out = new(TemplateRef)
-				**out = **in
-			}
-		}
+	if in.Stragglers != nil {
+		in, out := &in.Stragglers, &out.Stragglers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiSpec.
-func (in *ChiSpec) DeepCopy() *ChiSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChqStatus.
+func (in *ChqStatus) DeepCopy() *ChqStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiSpec)
+	out := new(ChqStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiStatus) DeepCopyInto(out *ChiStatus) {
+func (in *ChuGrant) DeepCopyInto(out *ChuGrant) {
 	*out = *in
-	if in.TaskIDsStarted != nil {
-		in, out := &in.TaskIDsStarted, &out.TaskIDsStarted
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.TaskIDsCompleted != nil {
-		in, out := &in.TaskIDsCompleted, &out.TaskIDsCompleted
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Actions != nil {
-		in, out := &in.Actions, &out.Actions
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Errors != nil {
-		in, out := &in.Errors, &out.Errors
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.Pods != nil {
-		in, out := &in.Pods, &out.Pods
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChuGrant.
+func (in *ChuGrant) DeepCopy() *ChuGrant {
+	if in == nil {
+		return nil
 	}
-	if in.PodIPs != nil {
-		in, out := &in.PodIPs, &out.PodIPs
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	out := new(ChuGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChuSpec) DeepCopyInto(out *ChuSpec) {
+	*out = *in
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(SettingSource)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.FQDNs != nil {
-		in, out := &in.FQDNs, &out.FQDNs
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.NormalizedCHI != nil {
-		in, out := &in.NormalizedCHI, &out.NormalizedCHI
-		*out = new(ClickHouseInstallation)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.NormalizedCHICompleted != nil {
-		in, out := &in.NormalizedCHICompleted, &out.NormalizedCHICompleted
-		*out = new(ClickHouseInstallation)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.HostsWithTablesCreated != nil {
-		in, out := &in.HostsWithTablesCreated, &out.HostsWithTablesCreated
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.UsedTemplates != nil {
-		in, out := &in.UsedTemplates, &out.UsedTemplates
-		*out = make([]*TemplateRef, len(*in))
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]ChuGrant, len(*in))
 		for i := range *in {
-			if (*in)[i] != nil {
-				in, out := &(*in)[i], &(*out)[i]
-				*out = new(TemplateRef)
-				**out = **in
-			}
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.mu = in.mu
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiStatus.
-func (in *ChiStatus) DeepCopy() *ChiStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChuSpec.
+func (in *ChuSpec) DeepCopy() *ChuSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiStatus)
+	out := new(ChuSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiTemplateNames) DeepCopyInto(out *ChiTemplateNames) {
+func (in *ChuStatus) DeepCopyInto(out *ChuStatus) {
 	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]ChuGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiTemplateNames.
-func (in *ChiTemplateNames) DeepCopy() *ChiTemplateNames {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChuStatus.
+func (in *ChuStatus) DeepCopy() *ChuStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiTemplateNames)
+	out := new(ChuStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiTemplating) DeepCopyInto(out *ChiTemplating) {
+func (in *ClickHouseBackup) DeepCopyInto(out *ClickHouseBackup) {
 	*out = *in
-	if in.CHISelector != nil {
-		in, out := &in.CHISelector, &out.CHISelector
-		*out = make(CHISelector, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(ChbStatus)
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiTemplating.
-func (in *ChiTemplating) DeepCopy() *ChiTemplating {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseBackup.
+func (in *ClickHouseBackup) DeepCopy() *ClickHouseBackup {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiTemplating)
+	out := new(ClickHouseBackup)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiZookeeperConfig) DeepCopyInto(out *ChiZookeeperConfig) {
+func (in *ClickHouseBackupList) DeepCopyInto(out *ClickHouseBackupList) {
 	*out = *in
-	if in.Nodes != nil {
-		in, out := &in.Nodes, &out.Nodes
-		*out = make([]ChiZookeeperNode, len(*in))
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClickHouseBackup, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -725,35 +2092,22 @@ func (in *ChiZookeeperConfig) DeepCopyInto(out *ChiZookeeperConfig) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiZookeeperConfig.
-func (in *ChiZookeeperConfig) DeepCopy() *ChiZookeeperConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseBackupList.
+func (in *ClickHouseBackupList) DeepCopy() *ClickHouseBackupList {
 	if in == nil {
 		return nil
 	}
-	out := new(ChiZookeeperConfig)
+	out := new(ClickHouseBackupList)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ChiZookeeperNode) DeepCopyInto(out *ChiZookeeperNode) {
-	*out = *in
-	if in.Secure != nil {
-		in, out := &in.Secure, &out.Secure
-		*out = new(StringBool)
-		**out = **in
-	}
-	return
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChiZookeeperNode.
-func (in *ChiZookeeperNode) DeepCopy() *ChiZookeeperNode {
-	if in == nil {
-		return nil
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	out := new(ChiZookeeperNode)
-	in.DeepCopyInto(out)
-	return out
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -837,6 +2191,11 @@ func (in *ClickHouseInstallationRuntime) DeepCopyInto(out *ClickHouseInstallatio
 		(*in).DeepCopyInto(*out)
 	}
 	out.commonConfigMutex = in.commonConfigMutex
+	if in.referencedSecrets != nil {
+		in, out := &in.referencedSecrets, &out.referencedSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -982,6 +2341,136 @@ func (in *ClickHouseOperatorConfigurationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickHouseQuery) DeepCopyInto(out *ClickHouseQuery) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(ChqStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseQuery.
+func (in *ClickHouseQuery) DeepCopy() *ClickHouseQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickHouseQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseQuery) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickHouseQueryList) DeepCopyInto(out *ClickHouseQueryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClickHouseQuery, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseQueryList.
+func (in *ClickHouseQueryList) DeepCopy() *ClickHouseQueryList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickHouseQueryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseQueryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickHouseUser) DeepCopyInto(out *ClickHouseUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(ChuStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseUser.
+func (in *ClickHouseUser) DeepCopy() *ClickHouseUser {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickHouseUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickHouseUserList) DeepCopyInto(out *ClickHouseUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClickHouseUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickHouseUserList.
+func (in *ClickHouseUserList) DeepCopy() *ClickHouseUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickHouseUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClickHouseUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
@@ -1000,15 +2489,20 @@ func (in *Cluster) DeepCopyInto(out *Cluster) {
 		*out = new(Settings)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Streaming != nil {
+		in, out := &in.Streaming, &out.Streaming
+		*out = new(ChiStreaming)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Templates != nil {
 		in, out := &in.Templates, &out.Templates
 		*out = new(ChiTemplateNames)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.SchemaPolicy != nil {
 		in, out := &in.SchemaPolicy, &out.SchemaPolicy
 		*out = new(SchemaPolicy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Insecure != nil {
 		in, out := &in.Insecure, &out.Insecure
@@ -1030,6 +2524,21 @@ func (in *Cluster) DeepCopyInto(out *Cluster) {
 		*out = new(ChiClusterLayout)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Stop != nil {
+		in, out := &in.Stop, &out.Stop
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.Cordon != nil {
+		in, out := &in.Cordon, &out.Cordon
+		*out = new(StringBool)
+		**out = **in
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(ChiClusterDNS)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Runtime.DeepCopyInto(&out.Runtime)
 	return
 }
@@ -1178,6 +2687,51 @@ func (in *Configuration) DeepCopyInto(out *Configuration) {
 		*out = new(Settings)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Fragments != nil {
+		in, out := &in.Fragments, &out.Fragments
+		*out = new(Settings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Security != nil {
+		in, out := &in.Security, &out.Security
+		*out = new(ChiSecurity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Functions != nil {
+		in, out := &in.Functions, &out.Functions
+		*out = new(ChiFunctions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Logger != nil {
+		in, out := &in.Logger, &out.Logger
+		*out = new(ChiLogger)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Streaming != nil {
+		in, out := &in.Streaming, &out.Streaming
+		*out = new(ChiStreaming)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogTables != nil {
+		in, out := &in.LogTables, &out.LogTables
+		*out = make(ChiLogTables, len(*in))
+		for key, val := range *in {
+			var outVal *ChiLogTable
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(ChiLogTable)
+				(*in).DeepCopyInto(*out)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.ExcludedPaths != nil {
+		in, out := &in.ExcludedPaths, &out.ExcludedPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Clusters != nil {
 		in, out := &in.Clusters, &out.Clusters
 		*out = make([]*Cluster, len(*in))
@@ -1274,6 +2828,11 @@ func (in *DataSource) DeepCopyInto(out *DataSource) {
 		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1287,6 +2846,38 @@ func (in *DataSource) DeepCopy() *DataSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureGateStatus) DeepCopyInto(out *FeatureGateStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureGateStatus.
+func (in *FeatureGateStatus) DeepCopy() *FeatureGateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureGateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureSpec) DeepCopyInto(out *FeatureSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureSpec.
+func (in *FeatureSpec) DeepCopy() *FeatureSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FillStatusParams) DeepCopyInto(out *FillStatusParams) {
 	*out = *in
@@ -1475,6 +3066,21 @@ func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
 	out.StatefulSet = in.StatefulSet
 	out.Pod = in.Pod
 	out.Logger = in.Logger
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(OperatorConfigFeatureGates, len(*in))
+		for key, val := range *in {
+			var outVal *StringBool
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(StringBool)
+				**out = **in
+			}
+			(*out)[key] = outVal
+		}
+	}
 	if in.WatchNamespaces != nil {
 		in, out := &in.WatchNamespaces, &out.WatchNamespaces
 		*out = make([]string, len(*in))
@@ -1547,6 +3153,7 @@ func (in *OperatorConfigAnnotation) DeepCopy() *OperatorConfigAnnotation {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorConfigCHI) DeepCopyInto(out *OperatorConfigCHI) {
 	*out = *in
+	out.Catalog = in.Catalog
 	in.Runtime.DeepCopyInto(&out.Runtime)
 	return
 }
@@ -1561,6 +3168,22 @@ func (in *OperatorConfigCHI) DeepCopy() *OperatorConfigCHI {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigCHICatalog) DeepCopyInto(out *OperatorConfigCHICatalog) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigCHICatalog.
+func (in *OperatorConfigCHICatalog) DeepCopy() *OperatorConfigCHICatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigCHICatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorConfigCHIRuntime) DeepCopyInto(out *OperatorConfigCHIRuntime) {
 	*out = *in
@@ -1582,41 +3205,94 @@ func (in *OperatorConfigCHIRuntime) DeepCopyInto(out *OperatorConfigCHIRuntime)
 			}
 		}
 	}
-	out.mutex = in.mutex
-	if in.Template != nil {
-		in, out := &in.Template, &out.Template
-		*out = new(ClickHouseInstallation)
-		(*in).DeepCopyInto(*out)
+	out.mutex = in.mutex
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(ClickHouseInstallation)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigCHIRuntime.
+func (in *OperatorConfigCHIRuntime) DeepCopy() *OperatorConfigCHIRuntime {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigCHIRuntime)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigClickHouse) DeepCopyInto(out *OperatorConfigClickHouse) {
+	*out = *in
+	in.Config.DeepCopyInto(&out.Config)
+	in.ConfigRestartPolicy.DeepCopyInto(&out.ConfigRestartPolicy)
+	in.Access.DeepCopyInto(&out.Access)
+	in.Image.DeepCopyInto(&out.Image)
+	out.Metrics = in.Metrics
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigClickHouse.
+func (in *OperatorConfigClickHouse) DeepCopy() *OperatorConfigClickHouse {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigClickHouse)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigClickHouseImage) DeepCopyInto(out *OperatorConfigClickHouseImage) {
+	*out = *in
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigCHIRuntime.
-func (in *OperatorConfigCHIRuntime) DeepCopy() *OperatorConfigCHIRuntime {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigClickHouseImage.
+func (in *OperatorConfigClickHouseImage) DeepCopy() *OperatorConfigClickHouseImage {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorConfigCHIRuntime)
+	out := new(OperatorConfigClickHouseImage)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorConfigClickHouse) DeepCopyInto(out *OperatorConfigClickHouse) {
+func (in *OperatorConfigClickHouseAccess) DeepCopyInto(out *OperatorConfigClickHouseAccess) {
 	*out = *in
-	in.Config.DeepCopyInto(&out.Config)
-	in.ConfigRestartPolicy.DeepCopyInto(&out.ConfigRestartPolicy)
-	out.Access = in.Access
-	out.Metrics = in.Metrics
+	out.Secret = in.Secret
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Timeouts = in.Timeouts
+	out.SlowQueries = in.SlowQueries
+	if in.SlowQueries.Threshold != nil {
+		in, out := &in.SlowQueries.Threshold, &out.SlowQueries.Threshold
+		*out = new(time.Duration)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigClickHouse.
-func (in *OperatorConfigClickHouse) DeepCopy() *OperatorConfigClickHouse {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigClickHouseAccess.
+func (in *OperatorConfigClickHouseAccess) DeepCopy() *OperatorConfigClickHouseAccess {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorConfigClickHouse)
+	out := new(OperatorConfigClickHouseAccess)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1627,6 +3303,11 @@ func (in *OperatorConfigConfig) DeepCopyInto(out *OperatorConfigConfig) {
 	in.File.DeepCopyInto(&out.File)
 	in.User.DeepCopyInto(&out.User)
 	out.Network = in.Network
+	if in.ExcludedPaths != nil {
+		in, out := &in.ExcludedPaths, &out.ExcludedPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1661,6 +3342,36 @@ func (in *OperatorConfigDefault) DeepCopy() *OperatorConfigDefault {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in OperatorConfigFeatureGates) DeepCopyInto(out *OperatorConfigFeatureGates) {
+	{
+		in := &in
+		*out = make(OperatorConfigFeatureGates, len(*in))
+		for key, val := range *in {
+			var outVal *StringBool
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(StringBool)
+				**out = **in
+			}
+			(*out)[key] = outVal
+		}
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigFeatureGates.
+func (in OperatorConfigFeatureGates) DeepCopy() OperatorConfigFeatureGates {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigFeatureGates)
+	in.DeepCopyInto(out)
+	return *out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorConfigFile) DeepCopyInto(out *OperatorConfigFile) {
 	*out = *in
@@ -1766,6 +3477,10 @@ func (in *OperatorConfigReconcile) DeepCopy() *OperatorConfigReconcile {
 func (in *OperatorConfigReconcileHost) DeepCopyInto(out *OperatorConfigReconcileHost) {
 	*out = *in
 	in.Wait.DeepCopyInto(&out.Wait)
+	in.PDB.DeepCopyInto(&out.PDB)
+	in.Probe.DeepCopyInto(&out.Probe)
+	out.Maintenance = in.Maintenance
+	out.Capacity = in.Capacity
 	return
 }
 
@@ -1779,6 +3494,80 @@ func (in *OperatorConfigReconcileHost) DeepCopy() *OperatorConfigReconcileHost {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigReconcileHostCapacity) DeepCopyInto(out *OperatorConfigReconcileHostCapacity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigReconcileHostCapacity.
+func (in *OperatorConfigReconcileHostCapacity) DeepCopy() *OperatorConfigReconcileHostCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigReconcileHostCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigReconcileHostMaintenance) DeepCopyInto(out *OperatorConfigReconcileHostMaintenance) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigReconcileHostMaintenance.
+func (in *OperatorConfigReconcileHostMaintenance) DeepCopy() *OperatorConfigReconcileHostMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigReconcileHostMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigReconcileHostPDB) DeepCopyInto(out *OperatorConfigReconcileHostPDB) {
+	*out = *in
+	if in.Suspend != nil {
+		in, out := &in.Suspend, &out.Suspend
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigReconcileHostPDB.
+func (in *OperatorConfigReconcileHostPDB) DeepCopy() *OperatorConfigReconcileHostPDB {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigReconcileHostPDB)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigReconcileHostProbe) DeepCopyInto(out *OperatorConfigReconcileHostProbe) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigReconcileHostProbe.
+func (in *OperatorConfigReconcileHostProbe) DeepCopy() *OperatorConfigReconcileHostProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigReconcileHostProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorConfigReconcileHostWait) DeepCopyInto(out *OperatorConfigReconcileHostWait) {
 	*out = *in
@@ -1909,6 +3698,15 @@ func (in *OperatorConfigRuntime) DeepCopy() *OperatorConfigRuntime {
 func (in *OperatorConfigTemplate) DeepCopyInto(out *OperatorConfigTemplate) {
 	*out = *in
 	in.CHI.DeepCopyInto(&out.CHI)
+	in.Service.DeepCopyInto(&out.Service)
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.LogContainer.DeepCopyInto(&out.LogContainer)
 	return
 }
 
@@ -1922,10 +3720,76 @@ func (in *OperatorConfigTemplate) DeepCopy() *OperatorConfigTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigTemplateLogContainer) DeepCopyInto(out *OperatorConfigTemplateLogContainer) {
+	*out = *in
+	if in.TailLogs != nil {
+		in, out := &in.TailLogs, &out.TailLogs
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigTemplateLogContainer.
+func (in *OperatorConfigTemplateLogContainer) DeepCopy() *OperatorConfigTemplateLogContainer {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigTemplateLogContainer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigTemplateService) DeepCopyInto(out *OperatorConfigTemplateService) {
+	*out = *in
+	in.InternalLoadBalancer.DeepCopyInto(&out.InternalLoadBalancer)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigTemplateService.
+func (in *OperatorConfigTemplateService) DeepCopy() *OperatorConfigTemplateService {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigTemplateService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigTemplateServiceInternalLoadBalancer) DeepCopyInto(out *OperatorConfigTemplateServiceInternalLoadBalancer) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigTemplateServiceInternalLoadBalancer.
+func (in *OperatorConfigTemplateServiceInternalLoadBalancer) DeepCopy() *OperatorConfigTemplateServiceInternalLoadBalancer {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigTemplateServiceInternalLoadBalancer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorConfigUser) DeepCopyInto(out *OperatorConfigUser) {
 	*out = *in
 	in.Default.DeepCopyInto(&out.Default)
+	if in.StoreInSecret != nil {
+		in, out := &in.StoreInSecret, &out.StoreInSecret
+		*out = new(StringBool)
+		**out = **in
+	}
 	return
 }
 
@@ -1947,6 +3811,7 @@ func (in *OperatorConfigWatch) DeepCopyInto(out *OperatorConfigWatch) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	in.Informers.DeepCopyInto(&out.Informers)
 	return
 }
 
@@ -1960,6 +3825,77 @@ func (in *OperatorConfigWatch) DeepCopy() *OperatorConfigWatch {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigWatchInformers) DeepCopyInto(out *OperatorConfigWatchInformers) {
+	*out = *in
+	out.Resync = in.Resync
+	if in.FilterManagedObjects != nil {
+		in, out := &in.FilterManagedObjects, &out.FilterManagedObjects
+		*out = new(StringBool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigWatchInformers.
+func (in *OperatorConfigWatchInformers) DeepCopy() *OperatorConfigWatchInformers {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigWatchInformers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetTemplate) DeepCopyInto(out *PodDisruptionBudgetTemplate) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetTemplate.
+func (in *PodDisruptionBudgetTemplate) DeepCopy() *PodDisruptionBudgetTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetTemplatesIndex) DeepCopyInto(out *PodDisruptionBudgetTemplatesIndex) {
+	*out = *in
+	if in.templates != nil {
+		in, out := &in.templates, &out.templates
+		*out = make(map[string]*PodDisruptionBudgetTemplate, len(*in))
+		for key, val := range *in {
+			var outVal *PodDisruptionBudgetTemplate
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(PodDisruptionBudgetTemplate)
+				(*in).DeepCopyInto(*out)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetTemplatesIndex.
+func (in *PodDisruptionBudgetTemplatesIndex) DeepCopy() *PodDisruptionBudgetTemplatesIndex {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetTemplatesIndex)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodDistribution) DeepCopyInto(out *PodDistribution) {
 	*out = *in
@@ -2068,9 +4004,56 @@ func (in *PortDistribution) DeepCopy() *PortDistribution {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryComplexity) DeepCopyInto(out *QueryComplexity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryComplexity.
+func (in *QueryComplexity) DeepCopy() *QueryComplexity {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryComplexity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteURLAllowHosts) DeepCopyInto(out *RemoteURLAllowHosts) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostRegexps != nil {
+		in, out := &in.HostRegexps, &out.HostRegexps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteURLAllowHosts.
+func (in *RemoteURLAllowHosts) DeepCopy() *RemoteURLAllowHosts {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteURLAllowHosts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SchemaPolicy) DeepCopyInto(out *SchemaPolicy) {
 	*out = *in
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(StringBool)
+		**out = **in
+	}
 	return
 }
 
@@ -2359,6 +4342,13 @@ func (in *Templates) DeepCopyInto(out *Templates) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PodDisruptionBudgetTemplates != nil {
+		in, out := &in.PodDisruptionBudgetTemplates, &out.PodDisruptionBudgetTemplates
+		*out = make([]PodDisruptionBudgetTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.HostTemplatesIndex != nil {
 		in, out := &in.HostTemplatesIndex, &out.HostTemplatesIndex
 		*out = new(HostTemplatesIndex)
@@ -2379,6 +4369,11 @@ func (in *Templates) DeepCopyInto(out *Templates) {
 		*out = new(ServiceTemplatesIndex)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PodDisruptionBudgetTemplatesIndex != nil {
+		in, out := &in.PodDisruptionBudgetTemplatesIndex, &out.PodDisruptionBudgetTemplatesIndex
+		*out = new(PodDisruptionBudgetTemplatesIndex)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
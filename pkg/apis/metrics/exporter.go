@@ -28,7 +28,6 @@ import (
 	core "k8s.io/api/core/v1"
 	kube "k8s.io/client-go/kubernetes"
 
-	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
 	"github.com/altinity/clickhouse-operator/pkg/chop"
 	chopAPI "github.com/altinity/clickhouse-operator/pkg/client/clientset/versioned"
 	"github.com/altinity/clickhouse-operator/pkg/controller"
@@ -148,21 +147,7 @@ func (e *Exporter) newHostFetcher(host *WatchedHost) *ClickHouseMetricsFetcher {
 	// Make base cluster connection params
 	clusterConnectionParams := clickhouse.NewClusterConnectionParamsFromCHOpConfig(chop.Config())
 	// Adjust base cluster connection params with per-host props
-	switch clusterConnectionParams.Scheme {
-	case api.ChSchemeAuto:
-		switch {
-		case api.IsPortAssigned(host.HTTPPort):
-			clusterConnectionParams.Scheme = "http"
-			clusterConnectionParams.Port = int(host.HTTPPort)
-		case api.IsPortAssigned(host.HTTPSPort):
-			clusterConnectionParams.Scheme = "https"
-			clusterConnectionParams.Port = int(host.HTTPSPort)
-		}
-	case api.ChSchemeHTTP:
-		clusterConnectionParams.Port = int(host.HTTPPort)
-	case api.ChSchemeHTTPS:
-		clusterConnectionParams.Port = int(host.HTTPSPort)
-	}
+	clusterConnectionParams.SetHostPorts(host.TCPPort, host.TLSPort, host.HTTPPort, host.HTTPSPort)
 
 	return NewClickHouseFetcher(clusterConnectionParams.NewEndpointConnectionParams(host.Hostname))
 }
@@ -23,7 +23,9 @@ import (
 	"strconv"
 
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/dynamic"
 	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	kuberest "k8s.io/client-go/rest"
 	kubeclientcmd "k8s.io/client-go/tools/clientcmd"
 
@@ -65,12 +67,8 @@ func getKubeConfig(kubeConfigFile, masterURL string) (*kuberest.Config, error) {
 	return conf, nil
 }
 
-// GetClientset gets k8s API clients - both kube native client and our custom client
-func GetClientset(kubeConfigFile, masterURL string) (
-	*kube.Clientset,
-	*apiextensions.Clientset,
-	*chopclientset.Clientset,
-) {
+// buildKubeConfig creates kuberest.Config object with client rate limits applied
+func buildKubeConfig(kubeConfigFile, masterURL string) *kuberest.Config {
 	kubeConfig, err := getKubeConfig(kubeConfigFile, masterURL)
 	if err != nil {
 		log.F().Fatal("Unable to build kubeconf: %s", err.Error())
@@ -101,6 +99,17 @@ func GetClientset(kubeConfigFile, masterURL string) (
 		kubeConfig.Burst = int(parsedBurst)
 	}
 
+	return kubeConfig
+}
+
+// GetClientset gets k8s API clients - both kube native client and our custom client
+func GetClientset(kubeConfigFile, masterURL string) (
+	*kube.Clientset,
+	*apiextensions.Clientset,
+	*chopclientset.Clientset,
+) {
+	kubeConfig := buildKubeConfig(kubeConfigFile, masterURL)
+
 	kubeClientset, err := kube.NewForConfig(kubeConfig)
 	if err != nil {
 		log.F().Fatal("Unable to initialize kubernetes API clientset: %s", err.Error())
@@ -119,6 +128,24 @@ func GetClientset(kubeConfigFile, masterURL string) (
 	return kubeClientset, apiextensionsClientset, chopClientset
 }
 
+// GetDynamicClient gets k8s API dynamic client, used for resources without typed clients, such as Gateway API routes
+func GetDynamicClient(kubeConfigFile, masterURL string) dynamic.Interface {
+	dynamicClient, err := dynamic.NewForConfig(buildKubeConfig(kubeConfigFile, masterURL))
+	if err != nil {
+		log.F().Fatal("Unable to initialize kubernetes API dynamic client: %s", err.Error())
+	}
+	return dynamicClient
+}
+
+// GetMetadataClient gets k8s API metadata client, used to watch resources, whose metadata only is of interest, such as Secrets
+func GetMetadataClient(kubeConfigFile, masterURL string) metadata.Interface {
+	metadataClient, err := metadata.NewForConfig(buildKubeConfig(kubeConfigFile, masterURL))
+	if err != nil {
+		log.F().Fatal("Unable to initialize kubernetes API metadata client: %s", err.Error())
+	}
+	return metadataClient
+}
+
 var chop *CHOp
 
 // New creates chop instance
@@ -133,6 +160,24 @@ func New(kubeClient *kube.Clientset, chopClient *chopclientset.Clientset, initCH
 	chop.SetupLog()
 }
 
+// NewFromConfig creates global CHOp out of the provided config w/o reaching k8s,
+// so the operator's model is usable by tools running outside of the operator.
+// Model reads config of the global CHOp, thus it can be set up once per process only,
+// and config set up already, either by New or by NewFromConfig, is never replaced
+func NewFromConfig(config *v1.OperatorConfig) error {
+	if chop != nil {
+		return fmt.Errorf("operator config is set up already")
+	}
+	if config == nil {
+		config = &v1.OperatorConfig{}
+	}
+	instance := NewCHOp(version.Version, version.GitSHA, version.BuiltAt, nil, nil, "")
+	instance.ConfigManager.config = config
+	instance.ConfigManager.Postprocess()
+	chop = instance
+	return nil
+}
+
 // Get gets global CHOp
 func Get() *CHOp {
 	return chop
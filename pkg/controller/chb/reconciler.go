@@ -0,0 +1,351 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	apiMachinery "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chb"
+	chiModel "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// PollTime is the delay between polls of the operation progress
+const PollTime = 10 * time.Second
+
+// ChbReconciler reconciles a ClickHouseBackup object
+type ChbReconciler struct {
+	client.Client
+	Scheme *apiMachinery.Scheme
+}
+
+// Reconcile starts backup or restore operation and tracks its progress until it is over
+func (r *ChbReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return ctrl.Result{}, nil
+	}
+
+	chb := &api.ClickHouseBackup{}
+	if err := r.Get(ctx, req.NamespacedName, chb); err != nil {
+		if apiErrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return ctrl.Result{}, nil
+		}
+		// Return and requeue
+		return ctrl.Result{}, err
+	}
+
+	if chb.GetStatus().IsDone() {
+		// Operation is over, nothing to do
+		return ctrl.Result{}, nil
+	}
+
+	if len(chb.EnsureStatus().Hosts) == 0 {
+		r.start(ctx, chb)
+	} else {
+		r.poll(ctx, chb)
+	}
+
+	if err := r.Status().Update(ctx, chb); err != nil {
+		log.V(1).M(chb).F().Error("unable to update status of CHB %s/%s err: %v", chb.Namespace, chb.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	if chb.GetStatus().IsDone() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: PollTime}, nil
+}
+
+// start starts operation on each cluster of the CHI.
+// Operation which can not be started due to misconfiguration is failed, otherwise start is retried
+func (r *ChbReconciler) start(ctx context.Context, chb *api.ClickHouseBackup) {
+	status := chb.EnsureStatus()
+	if err := validate(chb); err != nil {
+		r.fail(chb, err)
+		return
+	}
+
+	status.Status = api.BackupStatusPending
+	chi, err := r.getCHI(ctx, chb)
+	if err != nil {
+		status.Error = err.Error()
+		log.V(1).M(chb).F().Warning("unable to get CHI %s/%s, postpone. err: %v", chb.Namespace, chb.Spec.CHI, err)
+		return
+	}
+
+	var clusters []*api.Cluster
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		if (chb.Spec.Cluster == "") || (chb.Spec.Cluster == cluster.Name) {
+			clusters = append(clusters, cluster)
+		}
+		return nil
+	})
+	if len(clusters) == 0 {
+		r.fail(chb, fmt.Errorf("no cluster %s found in CHI %s/%s", chb.Spec.Cluster, chb.Namespace, chb.Spec.CHI))
+		return
+	}
+
+	accessKeyID, secretAccessKey, err := r.getCredentials(ctx, chb)
+	if err != nil {
+		status.Error = err.Error()
+		log.V(1).M(chb).F().Warning("unable to get credentials, postpone. err: %v", err)
+		return
+	}
+
+	var hosts []api.ChbHostStatus
+	for _, cluster := range clusters {
+		host := cluster.FirstHost()
+		hostStatus := api.ChbHostStatus{
+			Cluster:     cluster.Name,
+			Host:        chiModel.CreateFQDN(host),
+			ID:          model.CreateOperationID(chb, cluster.Name),
+			Destination: model.CreateDestination(chb, cluster.Name),
+		}
+		sql := model.CreateOperationSQL(chb, cluster.Name, hostStatus.ID, hostStatus.Destination, accessKeyID, secretAccessKey)
+		if err := r.runOperation(ctx, host, hostStatus.ID, sql); err != nil {
+			// Failed SQL may be reported as a part of the error, credentials must not leak into status and log
+			err = hideCredentials(err, accessKeyID, secretAccessKey)
+			status.Error = err.Error()
+			log.V(1).M(chb).F().Warning("unable to start %s of cluster %s, postpone. err: %v", chb.Spec.GetOperation(), cluster.Name, err)
+			return
+		}
+		log.V(1).M(chb).F().Info("%s of cluster %s started on host %s. id: %s", chb.Spec.GetOperation(), cluster.Name, hostStatus.Host, hostStatus.ID)
+		hosts = append(hosts, hostStatus)
+	}
+
+	status.Status = api.BackupStatusInProgress
+	status.Error = ""
+	status.StartTime = time.Now().Format(time.RFC3339)
+	status.Hosts = hosts
+}
+
+// poll fetches progress of the operation from each host it was started on
+func (r *ChbReconciler) poll(ctx context.Context, chb *api.ClickHouseBackup) {
+	status := chb.EnsureStatus()
+	chi, err := r.getCHI(ctx, chb)
+	if err != nil {
+		log.V(1).M(chb).F().Warning("unable to get CHI %s/%s, postpone. err: %v", chb.Namespace, chb.Spec.CHI, err)
+		return
+	}
+
+	completed := 0
+	var failed []string
+	for i := range status.Hosts {
+		hostStatus := &status.Hosts[i]
+		if !model.IsOperationStatusFinal(hostStatus.Status) {
+			r.pollHost(ctx, chb, findHost(chi, hostStatus.Host), hostStatus)
+		}
+		switch {
+		case model.IsOperationStatusSucceeded(hostStatus.Status):
+			completed++
+		case model.IsOperationStatusFailed(hostStatus.Status):
+			failed = append(failed, hostStatus.Cluster)
+			completed++
+		}
+	}
+
+	if completed < len(status.Hosts) {
+		return
+	}
+
+	status.EndTime = time.Now().Format(time.RFC3339)
+	if len(failed) > 0 {
+		r.fail(chb, fmt.Errorf("%s failed for clusters: %v", chb.Spec.GetOperation(), failed))
+		return
+	}
+	status.Status = api.BackupStatusCompleted
+	log.V(1).M(chb).F().Info("%s completed", chb.Spec.GetOperation())
+}
+
+// pollHost fetches progress of the operation from the host
+func (r *ChbReconciler) pollHost(ctx context.Context, chb *api.ClickHouseBackup, host *api.ChiHost, hostStatus *api.ChbHostStatus) {
+	if host == nil {
+		hostStatus.Status = model.OperationStatusLost
+		hostStatus.Error = "host is not found in the CHI"
+		return
+	}
+
+	opStatus, opError, found, err := queryOperationStatus(ctx, newHostCluster(host), hostStatus.ID)
+	switch {
+	case err != nil:
+		// Host may be temporarily unavailable, try next time
+		log.V(1).M(chb).F().Warning("unable to poll operation %s on host %s err: %v", hostStatus.ID, hostStatus.Host, err)
+	case !found:
+		// system.backups is kept in memory, so operation is lost in case host was restarted
+		hostStatus.Status = model.OperationStatusLost
+		hostStatus.Error = "operation is not found on the host, host may have been restarted"
+	default:
+		hostStatus.Status = opStatus
+		hostStatus.Error = opError
+	}
+}
+
+// runOperation runs operation SQL on the host, unless operation with the same id is already started
+func (r *ChbReconciler) runOperation(ctx context.Context, host *api.ChiHost, id, sql string) error {
+	cluster := newHostCluster(host)
+	if _, _, found, err := queryOperationStatus(ctx, cluster, id); err != nil {
+		return err
+	} else if found {
+		// Operation was started already, status was not saved in time
+		return nil
+	}
+	// SQL has credentials inlined, so it is not logged
+	return cluster.SetLog(log.Silence()).ExecAll(ctx, []string{sql})
+}
+
+// hideCredentials replaces credentials mentioned in the error
+func hideCredentials(err error, credentials ...string) error {
+	msg := err.Error()
+	for _, credential := range credentials {
+		if credential != "" {
+			msg = strings.ReplaceAll(msg, credential, "***")
+		}
+	}
+	return errors.New(msg)
+}
+
+// fail marks operation as failed
+func (r *ChbReconciler) fail(chb *api.ClickHouseBackup, err error) {
+	log.V(1).M(chb).F().Error("%s of CHI %s/%s failed. err: %v", chb.Spec.GetOperation(), chb.Namespace, chb.Spec.CHI, err)
+	status := chb.EnsureStatus()
+	status.Status = api.BackupStatusFailed
+	status.Error = err.Error()
+	if status.EndTime == "" {
+		status.EndTime = time.Now().Format(time.RFC3339)
+	}
+}
+
+// getCHI gets normalized CHI the operation is run on
+func (r *ChbReconciler) getCHI(ctx context.Context, chb *api.ClickHouseBackup) (*api.ClickHouseInstallation, error) {
+	chi := &api.ClickHouseInstallation{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: chb.Namespace, Name: chb.Spec.CHI}, chi); err != nil {
+		return nil, err
+	}
+	return normalizer.NewNormalizer(func(namespace, name string) (*core.Secret, error) {
+		return r.getSecret(ctx, namespace, name)
+	}).CreateTemplatedCHI(chi, normalizer.NewOptions())
+}
+
+// getCredentials gets credentials of the object storage, if specified
+func (r *ChbReconciler) getCredentials(ctx context.Context, chb *api.ClickHouseBackup) (string, string, error) {
+	storage := chb.Spec.Destination.GetObjectStorage()
+	if !storage.AccessKeyID.HasValue() {
+		return "", "", nil
+	}
+	accessKeyID, err := r.getSecretValue(ctx, chb.Namespace, storage.AccessKeyID)
+	if err != nil {
+		return "", "", err
+	}
+	secretAccessKey, err := r.getSecretValue(ctx, chb.Namespace, storage.SecretAccessKey)
+	if err != nil {
+		return "", "", err
+	}
+	return accessKeyID, secretAccessKey, nil
+}
+
+// getSecretValue gets value the setting source refers to
+func (r *ChbReconciler) getSecretValue(ctx context.Context, namespace string, src *api.SettingSource) (string, error) {
+	name, key := src.GetNameKey()
+	secret, err := r.getSecret(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("no key %s found in secret %s/%s", key, namespace, name)
+	}
+	return string(value), nil
+}
+
+// getSecret gets secret
+func (r *ChbReconciler) getSecret(ctx context.Context, namespace, name string) (*core.Secret, error) {
+	secret := &core.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// validate checks whether operation is specified completely
+func validate(chb *api.ClickHouseBackup) error {
+	storage := chb.Spec.Destination.GetObjectStorage()
+	switch {
+	case chb.Spec.CHI == "":
+		return fmt.Errorf("no CHI specified")
+	case (storage == nil) || (storage.Endpoint == ""):
+		return fmt.Errorf("no destination specified")
+	case storage.AccessKeyID.HasValue() != storage.SecretAccessKey.HasValue():
+		return fmt.Errorf("both accessKeyId and secretAccessKey have to be specified")
+	}
+	return nil
+}
+
+// findHost finds host of the CHI by FQDN
+func findHost(chi *api.ClickHouseInstallation, fqdn string) (found *api.ChiHost) {
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		if chiModel.CreateFQDN(host) == fqdn {
+			found = host
+		}
+		return nil
+	})
+	return found
+}
+
+// newHostCluster creates connection to the host
+func newHostCluster(host *api.ChiHost) *clickhouse.Cluster {
+	params := clickhouse.NewClusterConnectionParamsFromCHOpConfig(chop.Config())
+	// Adjust connection params with per-host ports
+	params.SetHostPorts(host.TCPPort, host.TLSPort, host.HTTPPort, host.HTTPSPort)
+	cluster := clickhouse.NewCluster().SetHosts([]string{chiModel.CreateFQDN(host)})
+	cluster.ClusterConnectionParams = params
+	return cluster
+}
+
+// queryOperationStatus fetches status of the operation from system.backups of the host
+func queryOperationStatus(ctx context.Context, cluster *clickhouse.Cluster, id string) (status, opError string, found bool, err error) {
+	query, err := cluster.QueryAny(ctx, model.CreateOperationStatusSQL(id))
+	if err != nil {
+		return "", "", false, err
+	}
+	defer query.Close()
+
+	var statuses, errs []string
+	if err := query.UnzipColumnsAsStrings(&statuses, &errs); err != nil {
+		return "", "", false, err
+	}
+	if len(statuses) == 0 {
+		return "", "", false, nil
+	}
+	return statuses[0], errs[0], true, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+
+	authorization "k8s.io/api/authorization/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	chopInformers "github.com/altinity/clickhouse-operator/pkg/client/informers/externalversions"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+)
+
+// chitCatalogVerbs specifies verbs operator needs to be allowed to in templates catalog namespace
+var chitCatalogVerbs = []string{"get", "list", "watch"}
+
+// isCHITNamespace checks whether CHITs of the namespace are handled by the operator.
+// CHITs are handled in watched namespaces and in shared templates catalog namespace
+func isCHITNamespace(namespace string) bool {
+	return chop.Config().IsWatchedNamespace(namespace) || chop.Config().IsTemplatesCatalogNamespace(namespace)
+}
+
+// ShouldWatchTemplatesCatalog checks whether shared templates catalog namespace needs informer of its own.
+// Informers watch either all namespaces or exactly one namespace, thus catalog namespace is not covered in case
+// the operator watches one namespace, which is not the catalog.
+// Catalog is not watched in case the operator is not allowed to read CHITs in the catalog namespace.
+func (c *Controller) ShouldWatchTemplatesCatalog(ctx context.Context) bool {
+	if !chop.Config().HasTemplatesCatalog() {
+		return false
+	}
+	if !c.isTemplatesCatalogReadable(ctx) {
+		return false
+	}
+	informerNamespace := chop.Config().GetInformerNamespace()
+	return (informerNamespace != meta.NamespaceAll) && !chop.Config().IsTemplatesCatalogNamespace(informerNamespace)
+}
+
+// isTemplatesCatalogReadable checks whether RBAC allows the operator to read CHITs in shared templates catalog namespace
+func (c *Controller) isTemplatesCatalogReadable(ctx context.Context) bool {
+	namespace := chop.Config().Template.CHI.Catalog.Namespace
+	for _, verb := range chitCatalogVerbs {
+		review := &authorization.SelfSubjectAccessReview{
+			Spec: authorization.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorization.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      verb,
+					Group:     api.SchemeGroupVersion.Group,
+					Resource:  "clickhouseinstallationtemplates",
+				},
+			},
+		}
+		result, err := c.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, controller.NewCreateOptions())
+		if err != nil {
+			log.V(1).F().Warning("unable to check access to templates catalog namespace: %s err: %v", namespace, err)
+			return false
+		}
+		if !result.Status.Allowed {
+			log.V(1).F().Warning(
+				"templates catalog is not available. Operator is not allowed to %s CHITs in namespace: %s reason: %s",
+				verb, namespace, result.Status.Reason)
+			return false
+		}
+	}
+	log.V(1).F().Info("templates catalog is available in namespace: %s", namespace)
+	return true
+}
+
+// AddEventHandlersTemplatesCatalog adds event handlers of CHITs provided by informer factory of templates catalog namespace
+func (c *Controller) AddEventHandlersTemplatesCatalog(chopInformerFactory chopInformers.SharedInformerFactory) {
+	c.addEventHandlersCHIT(chopInformerFactory)
+}
@@ -27,9 +27,11 @@ import (
 	core "k8s.io/api/core/v1"
 	apiExtensions "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	utilRuntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	kubeInformers "k8s.io/client-go/informers"
 	kube "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -56,6 +58,7 @@ func NewController(
 	chopClient chopClientSet.Interface,
 	extClient apiExtensions.Interface,
 	kubeClient kube.Interface,
+	dynamicClient dynamic.Interface,
 	chopInformerFactory chopInformers.SharedInformerFactory,
 	kubeInformerFactory kubeInformers.SharedInformerFactory,
 ) *Controller {
@@ -81,6 +84,7 @@ func NewController(
 	// Create Controller instance
 	controller := &Controller{
 		kubeClient:              kubeClient,
+		dynamicClient:           dynamicClient,
 		extClient:               extClient,
 		chopClient:              chopClient,
 		chiLister:               chopInformerFactory.Clickhouse().V1().ClickHouseInstallations().Lister(),
@@ -97,9 +101,11 @@ func NewController(
 		statefulSetListerSynced: kubeInformerFactory.Apps().V1().StatefulSets().Informer().HasSynced,
 		podLister:               kubeInformerFactory.Core().V1().Pods().Lister(),
 		podListerSynced:         kubeInformerFactory.Core().V1().Pods().Informer().HasSynced,
+		secretsIndex:            newSecretsIndex(),
 		recorder:                recorder,
 	}
 	controller.initQueues()
+	controller.initRestarts()
 	controller.addEventHandlers(chopInformerFactory, kubeInformerFactory)
 
 	return controller
@@ -118,6 +124,7 @@ func (c *Controller) initQueues() {
 			//),
 		)
 	}
+	metricsQueues(c.queues)
 }
 
 func (c *Controller) addEventHandlersCHI(
@@ -158,7 +165,7 @@ func (c *Controller) addEventHandlersCHIT(
 	chopInformerFactory.Clickhouse().V1().ClickHouseInstallationTemplates().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			chit := obj.(*api.ClickHouseInstallationTemplate)
-			if !chop.Config().IsWatchedNamespace(chit.Namespace) {
+			if !isCHITNamespace(chit.Namespace) {
 				return
 			}
 			log.V(3).M(chit).Info("chitInformer.AddFunc")
@@ -167,7 +174,7 @@ func (c *Controller) addEventHandlersCHIT(
 		UpdateFunc: func(old, new interface{}) {
 			oldChit := old.(*api.ClickHouseInstallationTemplate)
 			newChit := new.(*api.ClickHouseInstallationTemplate)
-			if !chop.Config().IsWatchedNamespace(newChit.Namespace) {
+			if !isCHITNamespace(newChit.Namespace) {
 				return
 			}
 			log.V(3).M(newChit).Info("chitInformer.UpdateFunc")
@@ -175,7 +182,7 @@ func (c *Controller) addEventHandlersCHIT(
 		},
 		DeleteFunc: func(obj interface{}) {
 			chit := obj.(*api.ClickHouseInstallationTemplate)
-			if !chop.Config().IsWatchedNamespace(chit.Namespace) {
+			if !isCHITNamespace(chit.Namespace) {
 				return
 			}
 			log.V(3).M(chit).Info("chitInformer.DeleteFunc")
@@ -416,6 +423,7 @@ func (c *Controller) addEventHandlersPod(
 				return
 			}
 			log.V(3).M(pod).Info("podInformer.AddFunc")
+			c.rememberPodIP(pod)
 			c.enqueueObject(NewReconcilePod(reconcileAdd, nil, pod))
 		},
 		UpdateFunc: func(old, new interface{}) {
@@ -433,11 +441,48 @@ func (c *Controller) addEventHandlersPod(
 				return
 			}
 			log.V(3).M(pod).Info("podInformer.DeleteFunc")
+			c.forgetPodIP(pod)
 			c.enqueueObject(NewReconcilePod(reconcileDelete, pod, nil))
 		},
 	})
 }
 
+// rememberPodIP remembers IP address of the pod and returns IP address of the pod of the same name known before, if any.
+// Pod w/o IP address assigned yet does not override IP address known, so IP address of the recreated pod is compared
+// with IP address of the pod it replaces
+func (c *Controller) rememberPodIP(pod *core.Pod) (string, bool) {
+	key := pod.Namespace + "/" + pod.Name
+	if pod.Status.PodIP == "" {
+		prev, ok := c.podIPs.Load(key)
+		if !ok {
+			return "", false
+		}
+		return prev.(string), true
+	}
+	prev, ok := c.podIPs.Swap(key, pod.Status.PodIP)
+	if !ok {
+		return "", false
+	}
+	return prev.(string), true
+}
+
+// forgetPodIP forgets IP address of the deleted pod, unless the pod is to be recreated by its StatefulSet,
+// so IP address of the recreated pod is compared with IP address of the deleted one
+func (c *Controller) forgetPodIP(pod *core.Pod) {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind != "StatefulSet" {
+			continue
+		}
+		if statefulSet, err := c.statefulSetLister.StatefulSets(pod.Namespace).Get(owner.Name); err == nil {
+			if (statefulSet.DeletionTimestamp == nil) && (statefulSet.Spec.Replicas != nil) && (*statefulSet.Spec.Replicas > 0) {
+				// Pod is to be recreated
+				return
+			}
+		}
+	}
+	c.podIPs.Delete(pod.Namespace + "/" + pod.Name)
+}
+
 // addEventHandlers
 func (c *Controller) addEventHandlers(
 	chopInformerFactory chopInformers.SharedInformerFactory,
@@ -496,6 +541,11 @@ func (c *Controller) Run(ctx context.Context) {
 		}
 	}
 
+	// Rollout of hosts might be interrupted by operator restart
+	c.restoreSuspendedPDBs(ctx)
+	// Footprint gauge is otherwise populated on status update only, which may be long after operator restart
+	c.seedFootprintMetrics()
+
 	//
 	// Start threads
 	//
@@ -510,29 +560,135 @@ func (c *Controller) Run(ctx context.Context) {
 		worker := c.newWorker(c.queues[i], sys)
 		go wait.Until(worker.run, runWorkerPeriod, ctx.Done())
 	}
+	go c.runDetachedPartsPoller(ctx)
+	go c.runUpgradePoller(ctx)
+	go c.runConfigDriftPoller(ctx)
+	go c.runSecretsRotationPoller(ctx)
+	go c.runDDLQueuePoller(ctx)
+	go c.runStartupGatePoller(ctx)
 	defer log.V(1).F().Info("ClickHouseInstallation controller: shutting down workers")
 
 	log.V(1).F().Info("ClickHouseInstallation controller: workers started")
 	<-ctx.Done()
+
+	c.shutdownGracefully()
+}
+
+// shutdownGracefully stops workers from picking new items and waits for in-flight items
+// to be completed within shutdown grace period
+func (c *Controller) shutdownGracefully() {
+	c.shutdown.Lock()
+	c.shutdown.requested = true
+	c.shutdown.Unlock()
+
+	for i := range c.queues {
+		c.queues[i].Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.shutdown.inFlight.Wait()
+		close(done)
+	}()
+
+	gracePeriod := time.Duration(chop.Config().Reconcile.Runtime.ShutdownGracePeriod) * time.Second
+	log.V(1).F().Info("ClickHouseInstallation controller: waiting up to %s for in-flight items to complete", gracePeriod)
+	select {
+	case <-done:
+		log.V(1).F().Info("ClickHouseInstallation controller: all in-flight items completed")
+	case <-time.After(gracePeriod):
+		log.V(1).F().Warning("ClickHouseInstallation controller: shutdown grace period %s exceeded, exit with items in-flight", gracePeriod)
+		c.abortInFlight()
+	}
+}
+
+// startItem registers an item as being in-flight.
+// Returns false in case the controller is shutting down and no new items should be started
+func (c *Controller) startItem() bool {
+	c.shutdown.Lock()
+	defer c.shutdown.Unlock()
+
+	if c.shutdown.requested {
+		return false
+	}
+	c.shutdown.inFlight.Add(1)
+	return true
+}
+
+// seedFootprintMetrics populates footprint gauge with footprints of CHIs stored in their status
+func (c *Controller) seedFootprintMetrics() {
+	chis, err := c.chiLister.List(labels.Everything())
+	if err != nil {
+		log.V(1).F().Warning("unable to list CHIs err: %v", err)
+		return
+	}
+	for _, chi := range chis {
+		if !chop.Config().IsWatchedNamespace(chi.Namespace) {
+			continue
+		}
+		if footprint := chi.GetStatus().GetFootprint(); footprint != nil {
+			metricsCHIFootprint(chi, footprint)
+		}
+	}
+}
+
+// doneItem unregisters an in-flight item
+func (c *Controller) doneItem() {
+	c.shutdown.inFlight.Done()
+}
+
+// getAbortContext gets context, which is done as soon as in-flight items are aborted on shutdown
+func (c *Controller) getAbortContext() context.Context {
+	c.shutdown.Lock()
+	defer c.shutdown.Unlock()
+
+	if c.shutdown.abort == nil {
+		c.shutdown.abort, c.shutdown.abortCancel = context.WithCancel(context.Background())
+	}
+	return c.shutdown.abort
+}
+
+// abortInFlight aborts in-flight items, so they do not wait for anything anymore
+func (c *Controller) abortInFlight() {
+	c.getAbortContext()
+
+	c.shutdown.Lock()
+	defer c.shutdown.Unlock()
+
+	c.shutdown.abortCancel()
+}
+
+// isShuttingDown checks whether the controller is requested to shut down
+func (c *Controller) isShuttingDown() bool {
+	c.shutdown.Lock()
+	defer c.shutdown.Unlock()
+
+	return c.shutdown.requested
 }
 
 func prepareCHIAdd(command *ReconcileCHI) bool {
-	newjs, _ := json.Marshal(command.new)
-	newchi := api.ClickHouseInstallation{
+	command.new = normalizeCHIObject(command.new)
+	logCommand(command)
+	return true
+}
+
+// normalizeCHIObject makes a deep copy of the CHI object via JSON with type meta filled in,
+// the same way the enqueued CHI objects are prepared
+func normalizeCHIObject(chi *api.ClickHouseInstallation) *api.ClickHouseInstallation {
+	js, _ := json.Marshal(chi)
+	normalized := api.ClickHouseInstallation{
 		TypeMeta: meta.TypeMeta{
 			APIVersion: api.SchemeGroupVersion.String(),
 			Kind:       api.ClickHouseInstallationCRDResourceKind,
 		},
 	}
-	_ = json.Unmarshal(newjs, &newchi)
-	command.new = &newchi
-	logCommand(command)
-	return true
+	_ = json.Unmarshal(js, &normalized)
+	return &normalized
 }
 
 func prepareCHIUpdate(command *ReconcileCHI) bool {
 	actionPlan := model.NewActionPlan(command.old, command.new)
-	if !actionPlan.HasActionsToDo() {
+	if !actionPlan.HasActionsToDo() && !isRegenerateRequested(command) && !isCHIProvidedMetadataChanged(command.old, command.new) {
 		return false
 	}
 	oldjson, _ := json.MarshalIndent(command.old, "", "  ")
@@ -558,6 +714,16 @@ func prepareCHIUpdate(command *ReconcileCHI) bool {
 	return true
 }
 
+// isRegenerateRequested checks whether update command brings new request to regenerate objects.
+// Such a request is expressed by annotation only, so it is not seen by the action plan
+func isRegenerateRequested(command *ReconcileCHI) bool {
+	if (command.old == nil) || (command.new == nil) {
+		return false
+	}
+	return model.HasObjectsToRegenerate(command.new.ObjectMeta) &&
+		(command.old.Annotations[model.AnnotationRegenerate] != command.new.Annotations[model.AnnotationRegenerate])
+}
+
 func logCommand(command *ReconcileCHI) {
 	namespace := "uns"
 	name := "un"
@@ -586,13 +752,21 @@ func (c *Controller) enqueueObject(obj queue.PriorityQueueItem) {
 			enqueue = prepareCHIAdd(command)
 		case reconcileUpdate:
 			enqueue = prepareCHIUpdate(command)
+		case reconcileDelete:
+			// CHI is gone, nothing to reconcile anymore
+			c.backlogDrop(command)
+			c.forgetCoalesced(command)
+		}
+		if enqueue {
+			c.backlogPush(command)
 		}
 	case
 		*ReconcileCHIT,
 		*ReconcileChopConfig,
 		*ReconcileEndpoints,
 		*ReconcilePod,
-		*DropDns:
+		*DropDns,
+		*ReloadUsersConfig:
 		variants := api.DefaultReconcileSystemThreadsNumber
 		index = util.HashIntoIntTopped(handle, variants)
 		enqueue = true
@@ -720,6 +894,50 @@ func (c *Controller) patchCHIFinalizers(ctx context.Context, chi *api.ClickHouse
 	return nil
 }
 
+// deleteCHIAnnotation deletes annotation of ClickHouseInstallation
+func (c *Controller) deleteCHIAnnotation(ctx context.Context, chi *api.ClickHouseInstallation, annotation string) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation: nil,
+			},
+		},
+	})
+
+	_, err := c.chopClient.ClickhouseV1().ClickHouseInstallations(chi.Namespace).Patch(ctx, chi.Name, types.MergePatchType, payload, controller.NewPatchOptions())
+	if err != nil {
+		log.V(1).M(chi).F().Error("unable to delete annotation %s err: %v", annotation, err)
+	}
+	return err
+}
+
+// setCHIAnnotation sets annotation of the CHI to the specified value
+func (c *Controller) setCHIAnnotation(ctx context.Context, chi *api.ClickHouseInstallation, annotation, value string) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation: value,
+			},
+		},
+	})
+
+	_, err := c.chopClient.ClickhouseV1().ClickHouseInstallations(chi.Namespace).Patch(ctx, chi.Name, types.MergePatchType, payload, controller.NewPatchOptions())
+	if err != nil {
+		log.V(1).M(chi).F().Error("unable to set annotation %s err: %v", annotation, err)
+	}
+	return err
+}
+
 // UpdateCHIStatusOptions defines how to update CHI status
 type UpdateCHIStatusOptions struct {
 	api.CopyCHIStatusOptions
@@ -764,6 +982,12 @@ func (c *Controller) doUpdateCHIObjectStatus(ctx context.Context, chi *api.Click
 	log.V(3).M(chi).F().Info("Update CHI status")
 
 	podIPs := c.getPodsIPs(chi)
+	nodes := c.getPodsNodes(chi)
+	metricsCHINodes(chi, nodes)
+	if footprint := chi.GetStatus().GetFootprint(); footprint != nil {
+		metricsCHIFootprint(chi, footprint)
+	}
+	ready, total := c.getPodsReadiness(chi)
 
 	cur, err := c.chopClient.ClickhouseV1().ClickHouseInstallations(namespace).Get(ctx, name, controller.NewGetOptions())
 	if err != nil {
@@ -784,6 +1008,11 @@ func (c *Controller) doUpdateCHIObjectStatus(ctx context.Context, chi *api.Click
 	// Update status of a real object.
 	cur.EnsureStatus().CopyFrom(chi.Status, opts.CopyCHIStatusOptions)
 	cur.EnsureStatus().SetPodIPs(podIPs)
+	cur.EnsureStatus().SetNodes(nodes)
+	if total > 0 {
+		// Hosts are known for normalized CHI only
+		cur.EnsureStatus().SetHostsReady(ready, total)
+	}
 
 	_new, err := c.chopClient.ClickhouseV1().ClickHouseInstallations(chi.Namespace).UpdateStatus(ctx, cur, controller.NewUpdateOptions())
 	if err != nil {
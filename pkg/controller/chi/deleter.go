@@ -84,6 +84,15 @@ func (c *Controller) deleteConfigMapsCHI(ctx context.Context, chi *api.ClickHous
 		log.V(1).M(chi).F().Error("FAIL delete ConfigMap %s/%s err:%v", chi.Namespace, configMapCommonUsersName, err)
 	}
 
+	if chi.Spec.Defaults.IsConfigStoredInSecret() {
+		// Config files are delivered in Secrets having the same names
+		_ = c.deleteSecretIfExists(ctx, chi.Namespace, configMapCommon)
+	}
+	if chi.IsUsersStoredInSecret() {
+		// Users config files may be delivered in Secret on their own
+		_ = c.deleteSecretIfExists(ctx, chi.Namespace, configMapCommonUsersName)
+	}
+
 	return err
 }
 
@@ -240,6 +249,11 @@ func (c *Controller) deleteConfigMap(ctx context.Context, host *api.ChiHost) err
 		log.V(1).M(host).F().Error("FAIL delete ConfigMap %s/%s err:%v", namespace, name, err)
 	}
 
+	if host.GetCHI().Spec.Defaults.IsConfigStoredInSecret() {
+		// Config files are delivered in Secret having the same name
+		_ = c.deleteSecretIfExists(ctx, namespace, name)
+	}
+
 	//name = chopmodel.CreateConfigMapHostMigrationName(host)
 	//namespace = host.Address.Namespace
 	//log.V(1).M(host).F().Info("%s/%s", namespace, name)
@@ -265,6 +279,9 @@ func (c *Controller) deleteServiceHost(ctx context.Context, host *api.ChiHost) e
 	serviceName := model.CreateStatefulSetServiceName(host)
 	namespace := host.Runtime.Address.Namespace
 	log.V(1).M(host).F().Info("%s/%s", namespace, serviceName)
+	for _, template := range host.GetExtraServiceTemplates() {
+		_ = c.deleteServiceIfExists(ctx, namespace, model.CreateStatefulSetExtraServiceName(host, template))
+	}
 	return c.deleteServiceIfExists(ctx, namespace, serviceName)
 }
 
@@ -291,6 +308,9 @@ func (c *Controller) deleteServiceCluster(ctx context.Context, cluster *api.Clus
 	serviceName := model.CreateClusterServiceName(cluster)
 	namespace := cluster.Runtime.Address.Namespace
 	log.V(1).M(cluster).F().Info("%s/%s", namespace, serviceName)
+	for _, template := range cluster.GetExtraServiceTemplates() {
+		_ = c.deleteServiceIfExists(ctx, namespace, model.CreateClusterExtraServiceName(cluster, template))
+	}
 	return c.deleteServiceIfExists(ctx, namespace, serviceName)
 }
 
@@ -347,6 +367,32 @@ func (c *Controller) deleteSecretCluster(ctx context.Context, cluster *api.Clust
 	return c.deleteSecretIfExists(ctx, namespace, secretName)
 }
 
+// deleteSecretClusterSlots deletes Secret carrying slots of the cluster secret passed to pods
+func (c *Controller) deleteSecretClusterSlots(ctx context.Context, cluster *api.Cluster) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	secretName := model.CreateClusterSecretSlotsName(cluster)
+	namespace := cluster.Runtime.Address.Namespace
+	log.V(1).M(cluster).F().Info("%s/%s", namespace, secretName)
+	return c.deleteSecretIfExists(ctx, namespace, secretName)
+}
+
+// deleteSecretClusterTLS deletes Secret carrying auto-generated TLS certificates of the cluster
+func (c *Controller) deleteSecretClusterTLS(ctx context.Context, cluster *api.Cluster) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	secretName := model.CreateClusterTLSSecretName(cluster)
+	namespace := cluster.Runtime.Address.Namespace
+	log.V(1).M(cluster).F().Info("%s/%s", namespace, secretName)
+	return c.deleteSecretIfExists(ctx, namespace, secretName)
+}
+
 // deleteSecretIfExists deletes Secret in case it does not exist
 func (c *Controller) deleteSecretIfExists(ctx context.Context, namespace, name string) error {
 	if util.IsContextDone(ctx) {
@@ -28,6 +28,30 @@ var (
 	errCRUDUnexpectedFlow ErrorCRUD = errors.New("crud error - unexpected flow")
 )
 
+// errShutdown specifies reconcile interrupted due to the operator being shut down
+var errShutdown = errors.New("operator is shutting down")
+
+// errReconcileSuperseded specifies reconcile interrupted due to a newer spec of the CHI to be reconciled instead
+var errReconcileSuperseded = errors.New("reconcile is superseded by a newer spec")
+
+// errReconcileHalted specifies reconcile halted due to too many hosts in a row failed reconcile or health check
+var errReconcileHalted = errors.New("reconcile is halted")
+
+// errShardRemovalDataLoss specifies reconcile refused due to removed shards still holding data
+var errShardRemovalDataLoss = errors.New("shards to be removed hold data")
+
+// errHostReplacementDataLoss specifies host replacement refused due to the host holding the only copy of data
+var errHostReplacementDataLoss = errors.New("host to be replaced holds the only copy of data")
+
+// errClickHouseVersionDowngrade specifies reconcile refused due to ClickHouse version downgraded across major versions
+var errClickHouseVersionDowngrade = errors.New("clickhouse version is downgraded across major versions")
+
+// errHostUnschedulable specifies reconcile refused due to hosts requesting more resources than any node can fit
+var errHostUnschedulable = errors.New("hosts request more resources than any node can fit")
+
+// errImportMissingSecrets specifies import refused due to secrets referenced by the CHI being absent
+var errImportMissingSecrets = errors.New("secrets referenced by the imported CHI are missing")
+
 // ErrorDataPersistence specifies errors of the PVCs and PVs
 type ErrorDataPersistence error
 
@@ -39,27 +39,42 @@ const (
 	eventActionUpdate    = "Update"
 	eventActionDelete    = "Delete"
 	eventActionProgress  = "Progress"
+	eventActionCheck     = "Check"
 )
 
 const (
 	// Short, machine understandable string that gives the reason for the transition into the object's current status
-	eventReasonReconcileStarted       = "ReconcileStarted"
-	eventReasonReconcileInProgress    = "ReconcileInProgress"
-	eventReasonReconcileCompleted     = "ReconcileCompleted"
-	eventReasonReconcileFailed        = "ReconcileFailed"
-	eventReasonCreateStarted          = "CreateStarted"
-	eventReasonCreateInProgress       = "CreateInProgress"
-	eventReasonCreateCompleted        = "CreateCompleted"
-	eventReasonCreateFailed           = "CreateFailed"
-	eventReasonUpdateStarted          = "UpdateStarted"
-	eventReasonUpdateInProgress       = "UpdateInProgress"
-	eventReasonUpdateCompleted        = "UpdateCompleted"
-	eventReasonUpdateFailed           = "UpdateFailed"
-	eventReasonDeleteStarted          = "DeleteStarted"
-	eventReasonDeleteInProgress       = "DeleteInProgress"
-	eventReasonDeleteCompleted        = "DeleteCompleted"
-	eventReasonDeleteFailed           = "DeleteFailed"
-	eventReasonProgressHostsCompleted = "ProgressHostsCompleted"
+	eventReasonReconcileStarted           = "ReconcileStarted"
+	eventReasonReconcileInProgress        = "ReconcileInProgress"
+	eventReasonReconcileCompleted         = "ReconcileCompleted"
+	eventReasonReconcileFailed            = "ReconcileFailed"
+	eventReasonCreateStarted              = "CreateStarted"
+	eventReasonCreateInProgress           = "CreateInProgress"
+	eventReasonCreateCompleted            = "CreateCompleted"
+	eventReasonCreateFailed               = "CreateFailed"
+	eventReasonUpdateStarted              = "UpdateStarted"
+	eventReasonUpdateInProgress           = "UpdateInProgress"
+	eventReasonUpdateCompleted            = "UpdateCompleted"
+	eventReasonUpdateFailed               = "UpdateFailed"
+	eventReasonDeleteStarted              = "DeleteStarted"
+	eventReasonDeleteInProgress           = "DeleteInProgress"
+	eventReasonDeleteCompleted            = "DeleteCompleted"
+	eventReasonDeleteFailed               = "DeleteFailed"
+	eventReasonProgressHostsCompleted     = "ProgressHostsCompleted"
+	eventReasonDetachedPartsOverThreshold = "DetachedPartsOverThreshold"
+	eventReasonDetachedPartsDropped       = "DetachedPartsDropped"
+	eventReasonHostDrainTimeout           = "HostDrainTimeout"
+	eventReasonVolumeNodeAffinityConflict = "VolumeNodeAffinityConflict"
+	eventReasonPartsBacklogTimeout        = "PartsBacklogTimeout"
+	eventReasonUpgradeAvailable           = "UpgradeAvailable"
+	eventReasonUpgradeApplied             = "UpgradeApplied"
+	eventReasonConfigDrift                = "ConfigDrift"
+	eventReasonHostUnschedulable          = "HostUnschedulable"
+	eventReasonDDLQueueStuck              = "DDLQueueStuck"
+	eventReasonVolumeResizeNotAllowed     = "VolumeResizeNotAllowed"
+	eventReasonVolumeResizeFailed         = "VolumeResizeFailed"
+	eventReasonSecretsRotated             = "SecretsRotated"
+	eventReasonPortConflict               = "PortConflict"
 )
 
 // EventInfo emits event Info
@@ -0,0 +1,401 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	chiCreator "github.com/altinity/clickhouse-operator/pkg/model/chi/creator"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// HTTP paths where CHI export and import are served
+const (
+	exportPath = "/chi/export"
+	importPath = "/chi/import"
+)
+
+// CHIExport specifies everything needed to recreate the CHI in another k8s cluster
+type CHIExport struct {
+	ExportedAt string `json:"exportedAt,omitempty"`
+	// CHI is the CHI manifest as specified by the user, w/o status and cluster-specific metadata
+	CHI *api.ClickHouseInstallation `json:"chi,omitempty"`
+	// Secrets lists secrets the CHI relies on. Secrets values are not exported and have to be transferred separately
+	Secrets []CHIExportSecretRef `json:"secrets,omitempty"`
+	// Schema lists SQLs creating databases, tables and functions of the CHI
+	Schema      []string `json:"schema,omitempty"`
+	SchemaError string   `json:"schemaError,omitempty"`
+}
+
+// CHIExportSecretRef specifies secret referenced by the CHI
+type CHIExportSecretRef struct {
+	Name string   `json:"name"`
+	Keys []string `json:"keys,omitempty"`
+	// Generated specifies secret is generated by the operator and would be generated anew in the new k8s cluster
+	Generated bool `json:"generated,omitempty"`
+}
+
+// registerExportImport registers HTTP handlers for CHI export and import.
+// Callers are authorized with their own k8s RBAC: export requires 'get' and import requires 'create' of CHIs of the namespace
+func (c *Controller) registerExportImport(mux *http.ServeMux) {
+	mux.HandleFunc(exportPath, c.serveExport)
+	mux.HandleFunc(importPath, c.serveImport)
+}
+
+// serveExport publishes CHI export as JSON. CHI is specified by 'namespace' and 'name' query params
+func (c *Controller) serveExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	name := r.URL.Query().Get("name")
+	if (namespace == "") || (name == "") {
+		http.Error(w, "namespace and name are required", http.StatusBadRequest)
+		return
+	}
+	if !c.authorizeRequest(w, r, "get", namespace) {
+		return
+	}
+
+	export, err := c.newWorker(nil, true).exportCHI(r.Context(), namespace, name)
+	switch {
+	case apiErrors.IsNotFound(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveImport creates CHI out of the CHI export posted. Optional 'namespace' query param overrides CHI namespace
+func (c *Controller) serveImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limitRequestBody(w, r)
+	export := &CHIExport{}
+	if err := json.NewDecoder(r.Body).Decode(export); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if export.CHI == nil {
+		http.Error(w, "export has no CHI specified", http.StatusBadRequest)
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		namespace = export.CHI.Namespace
+	}
+	if namespace == "" {
+		http.Error(w, "namespace is required", http.StatusBadRequest)
+		return
+	}
+	if !c.authorizeRequest(w, r, "create", namespace) {
+		return
+	}
+
+	chi, err := c.newWorker(nil, true).importCHI(r.Context(), export, namespace)
+	switch {
+	case apiErrors.IsAlreadyExists(err):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(newExportedCHI(chi)); err != nil {
+		log.V(1).M(chi).F().Error("unable to write import response err: %v", err)
+	}
+}
+
+// exportCHI collects CHI export
+func (w *worker) exportCHI(ctx context.Context, namespace, name string) (*CHIExport, error) {
+	chi, err := w.c.chopClient.ClickhouseV1().ClickHouseInstallations(namespace).Get(ctx, name, controller.NewGetOptions())
+	if err != nil {
+		return nil, err
+	}
+	// Normalized CHI is used internally only and is not exported, since it has secrets resolved
+	normalized, err := w.normalizer.CreateTemplatedCHI(chi.DeepCopy(), normalizer.NewOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	export := &CHIExport{
+		ExportedAt: time.Now().Format(time.RFC3339),
+		CHI:        newExportedCHI(chi),
+		Secrets:    collectSecretRefs(normalized),
+	}
+	// Schema is collected on the best-effort basis, CHI may be stopped or unavailable
+	if export.Schema, err = w.exportSchema(ctx, normalized); err != nil {
+		w.a.V(1).M(chi).F().Warning("unable to export schema of CHI %s/%s err: %v", namespace, name, err)
+		export.SchemaError = err.Error()
+	}
+
+	w.a.V(1).M(chi).F().Info("CHI %s/%s exported. Secrets: %d SQLs: %d", namespace, name, len(export.Secrets), len(export.Schema))
+	return export, nil
+}
+
+// exportSchema collects SQLs creating schema of the CHI via the first alive host
+func (w *worker) exportSchema(ctx context.Context, chi *api.ClickHouseInstallation) ([]string, error) {
+	var alive *api.ChiHost
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		if alive != nil {
+			return nil
+		}
+		if _, err := w.getHostClickHouseVersion(ctx, host, versionOptions{}); err == nil {
+			alive = host
+		}
+		return nil
+	})
+	if alive == nil {
+		return nil, fmt.Errorf("no alive hosts")
+	}
+
+	_, sqls, err := w.ensureClusterSchemer(alive).CHISchemaSQLs(ctx, chi)
+	return sqls, err
+}
+
+// importCHI creates CHI out of the CHI export.
+// Schema, if any, is put into a ConfigMap and applied by the reconcile once hosts are up.
+func (w *worker) importCHI(ctx context.Context, export *CHIExport, namespace string) (*api.ClickHouseInstallation, error) {
+	if export.CHI == nil {
+		return nil, fmt.Errorf("export has no CHI specified")
+	}
+
+	chi := newExportedCHI(export.CHI)
+	if namespace != "" {
+		chi.Namespace = namespace
+	}
+
+	// Secrets are not exported, they have to be transferred before the import
+	var missing []string
+	for _, secret := range export.Secrets {
+		if secret.Generated {
+			continue
+		}
+		_, err := w.c.kubeClient.CoreV1().Secrets(chi.Namespace).Get(ctx, secret.Name, controller.NewGetOptions())
+		if apiErrors.IsNotFound(err) {
+			missing = append(missing, secret.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s/%v", errImportMissingSecrets, chi.Namespace, missing)
+	}
+
+	var schema []byte
+	if len(export.Schema) > 0 {
+		schema, _ = json.Marshal(export.Schema)
+		if chi.Annotations == nil {
+			chi.Annotations = make(map[string]string)
+		}
+		chi.Annotations[model.AnnotationImportSchema] = model.CreateConfigMapImportSchemaName(chi)
+	}
+
+	created, err := w.c.chopClient.ClickhouseV1().ClickHouseInstallations(chi.Namespace).Create(ctx, chi, controller.NewCreateOptions())
+	if err != nil {
+		return nil, err
+	}
+	w.a.V(1).M(created).F().Info("CHI %s/%s imported", created.Namespace, created.Name)
+
+	if schema != nil {
+		cm := chiCreator.NewCreator(created).CreateConfigMapCHIImportSchema(string(schema))
+		if _, err := w.c.kubeClient.CoreV1().ConfigMaps(cm.Namespace).Create(ctx, cm, controller.NewCreateOptions()); err != nil {
+			w.a.M(created).F().Error("unable to create import schema ConfigMap %s/%s err: %v", cm.Namespace, cm.Name, err)
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+// applyImportedSchema creates schema of the imported CHI on all hosts.
+// Annotation and ConfigMap are removed once schema is applied, otherwise it is retried by the next reconcile.
+func (w *worker) applyImportedSchema(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	name, ok := chi.Annotations[model.AnnotationImportSchema]
+	if !ok {
+		return
+	}
+
+	// Schema is accepted from the ConfigMap created by the import only, annotation can not point to an arbitrary ConfigMap
+	if name != model.CreateConfigMapImportSchemaName(chi) {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(chi).
+			M(chi).F().
+			Error("import schema ConfigMap %s/%s is not the one created by the import, ignore", chi.Namespace, name)
+		_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationImportSchema)
+		return
+	}
+
+	cm, err := w.c.kubeClient.CoreV1().ConfigMaps(chi.Namespace).Get(ctx, name, controller.NewGetOptions())
+	if err != nil {
+		w.a.V(1).M(chi).F().Warning("import schema ConfigMap %s/%s is not available, postpone. err: %v", chi.Namespace, name, err)
+		return
+	}
+	if !isOwnedByCHI(cm.OwnerReferences, chi) {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(chi).
+			M(chi).F().
+			Error("import schema ConfigMap %s/%s is not owned by the CHI, ignore", chi.Namespace, name)
+		_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationImportSchema)
+		return
+	}
+
+	var sqls []string
+	if err := json.Unmarshal([]byte(cm.Data[model.ImportSchemaConfigMapKey]), &sqls); err != nil {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(chi).
+			M(chi).F().
+			Error("unable to parse import schema ConfigMap %s/%s err: %v", chi.Namespace, name, err)
+		return
+	}
+
+	if err := w.ensureClusterSchemer(chi.FirstHost()).ExecCHI(ctx, chi, sqls); err != nil {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(chi).
+			M(chi).F().
+			Error("unable to apply imported schema, retry on the next reconcile. err: %v", err)
+		return
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileCompleted).
+		WithStatusAction(chi).
+		M(chi).F().
+		Info("imported schema applied. SQLs: %d", len(sqls))
+
+	_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationImportSchema)
+	err = w.c.kubeClient.CoreV1().ConfigMaps(chi.Namespace).Delete(ctx, name, controller.NewDeleteOptions())
+	if (err != nil) && !apiErrors.IsNotFound(err) {
+		w.a.V(1).M(chi).F().Warning("unable to delete import schema ConfigMap %s/%s err: %v", chi.Namespace, name, err)
+	}
+}
+
+// isOwnedByCHI checks whether owner references point to the CHI as a controller
+func isOwnedByCHI(refs []meta.OwnerReference, chi *api.ClickHouseInstallation) bool {
+	for _, ref := range refs {
+		if (ref.UID == chi.UID) && (ref.Kind == api.ClickHouseInstallationCRDResourceKind) && (ref.Controller != nil) && *ref.Controller {
+			return true
+		}
+	}
+	return false
+}
+
+// newExportedCHI makes a copy of the CHI w/o status and metadata bound to the k8s cluster the CHI lives in
+func newExportedCHI(chi *api.ClickHouseInstallation) *api.ClickHouseInstallation {
+	return &api.ClickHouseInstallation{
+		TypeMeta: meta.TypeMeta{
+			Kind:       api.ClickHouseInstallationCRDResourceKind,
+			APIVersion: api.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      chi.Name,
+			Namespace: chi.Namespace,
+			Labels:    util.CopyMap(chi.Labels),
+			Annotations: util.CopyMapExclude(
+				chi.Annotations,
+				core.LastAppliedConfigAnnotation,
+				model.AnnotationImportSchema,
+				model.AnnotationReplaceHost,
+				model.AnnotationRestart,
+			),
+		},
+		Spec: *chi.Spec.DeepCopy(),
+	}
+}
+
+// collectSecretRefs collects secrets the normalized CHI refers to via ENV vars and volumes
+func collectSecretRefs(chi *api.ClickHouseInstallation) []CHIExportSecretRef {
+	keys := make(map[string][]string)
+	add := func(name, key string) {
+		if !util.InArray(key, keys[name]) {
+			keys[name] = append(keys[name], key)
+		}
+	}
+
+	// Cluster secret is passed to pods out of slots kept by the operator, so the Secret it comes from is referred instead
+	var generated []string
+	slots := make(map[string]bool)
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		slots[model.CreateClusterSecretSlotsName(cluster)] = true
+		switch cluster.Secret.Source() {
+		case api.ClusterSecretSourceSecretRef:
+			ref := cluster.Secret.GetSecretKeyRef()
+			add(ref.Name, ref.Key)
+		case api.ClusterSecretSourceAuto:
+			ref := cluster.Secret.GetAutoSecretKeyRef(model.CreateClusterAutoSecretName(cluster))
+			add(ref.Name, ref.Key)
+			generated = append(generated, ref.Name)
+		}
+		return nil
+	})
+
+	attributes := chi.EnsureRuntime().GetAttributes()
+	for _, envVar := range attributes.AdditionalEnvVars {
+		if (envVar.ValueFrom != nil) && (envVar.ValueFrom.SecretKeyRef != nil) && !slots[envVar.ValueFrom.SecretKeyRef.Name] {
+			add(envVar.ValueFrom.SecretKeyRef.Name, envVar.ValueFrom.SecretKeyRef.Key)
+		}
+	}
+	for _, volume := range attributes.AdditionalVolumes {
+		if volume.Secret != nil {
+			for _, item := range volume.Secret.Items {
+				add(volume.Secret.SecretName, item.Key)
+			}
+		}
+	}
+
+	var refs []CHIExportSecretRef
+	for name := range keys {
+		refs = append(refs, CHIExportSecretRef{
+			Name:      name,
+			Keys:      keys[name],
+			Generated: util.InArray(name, generated),
+		})
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name < refs[j].Name
+	})
+	return refs
+}
@@ -16,6 +16,7 @@ package chi
 
 import (
 	"fmt"
+	"sort"
 
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
@@ -245,6 +246,31 @@ func (c *Controller) getPodsOfCHI(chi *api.ClickHouseInstallation) (pods []*core
 	return pods
 }
 
+// getScaleInProtectedPodNames gets names of all pods of the CHI annotated as protected from scale-in.
+// Pods are looked up by labels, so pods not present in the current layout of the CHI are found as well.
+func (c *Controller) getScaleInProtectedPodNames(chi *api.ClickHouseInstallation) (names []string) {
+	if (chi == nil) || (chi.Name == "") {
+		return nil
+	}
+
+	opts := controller.NewListOptions(model.NewLabeler(chi).GetSelectorCHIScope())
+	list, err := c.kubeClient.CoreV1().Pods(chi.Namespace).List(controller.NewContext(), opts)
+	if err != nil {
+		log.V(1).M(chi).F().Error("FAIL list Pod err: %v", err)
+		return nil
+	}
+	if list == nil {
+		return nil
+	}
+
+	for _, pod := range list.Items {
+		if model.IsScaleInProtected(pod.ObjectMeta) {
+			names = append(names, pod.Name)
+		}
+	}
+	return names
+}
+
 // getPodsIPs gets all pod IPs
 func (c *Controller) getPodsIPs(obj interface{}) (ips []string) {
 	log.V(3).M(obj).F().S().Info("looking for pods IPs")
@@ -261,6 +287,45 @@ func (c *Controller) getPodsIPs(obj interface{}) (ips []string) {
 	return ips
 }
 
+// getPodsNodes gets map of k8s nodes to names of pods running on them
+func (c *Controller) getPodsNodes(obj interface{}) map[string][]string {
+	nodes := make(map[string][]string)
+	for _, pod := range c.getPods(obj) {
+		if pod.Spec.NodeName == "" {
+			// Pod is not scheduled yet
+			continue
+		}
+		nodes[pod.Spec.NodeName] = append(nodes[pod.Spec.NodeName], pod.Name)
+	}
+	for node := range nodes {
+		sort.Strings(nodes[node])
+	}
+	return nodes
+}
+
+// getPodsReadiness gets number of ready pods and total number of hosts
+func (c *Controller) getPodsReadiness(chi *api.ClickHouseInstallation) (ready, total int) {
+	for _, pod := range c.getPods(chi) {
+		if isPodReady(pod) {
+			ready++
+		}
+	}
+	return ready, chi.HostsCount()
+}
+
+// isPodReady checks whether pod is ready. Pod is ready only in case all its containers are ready
+func isPodReady(pod *core.Pod) bool {
+	if (pod == nil) || (pod.DeletionTimestamp != nil) || (len(pod.Status.ContainerStatuses) == 0) {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if !status.Ready {
+			return false
+		}
+	}
+	return true
+}
+
 // GetCHIByObjectMeta gets CHI by namespaced name
 func (c *Controller) GetCHIByObjectMeta(objectMeta *meta.ObjectMeta, isCHI bool) (*api.ClickHouseInstallation, error) {
 	var chiName string
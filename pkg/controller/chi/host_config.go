@@ -0,0 +1,192 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// hostConfigPath specifies HTTP path where effective config files of hosts are served
+const hostConfigPath = "/chi/host/config"
+
+// HostConfig specifies config files a host is running with, as they are mounted into the host's pod
+type HostConfig struct {
+	Host string `json:"host"`
+	Pod  string `json:"pod"`
+	// Fingerprint of all config files of the host, equal fingerprints mean equal configs
+	Fingerprint string           `json:"fingerprint"`
+	Files       []HostConfigFile `json:"files,omitempty"`
+	// Errors lists ConfigMaps or Secrets, which are not available
+	Errors []string `json:"errors,omitempty"`
+}
+
+// HostConfigFile specifies one config file of a host
+type HostConfigFile struct {
+	// Path specifies full path of the file within the pod, such as /etc/clickhouse-server/conf.d/macros.xml
+	Path string `json:"path"`
+	// Source specifies ConfigMap or Secret the file comes from
+	Source      string `json:"source"`
+	Fingerprint string `json:"fingerprint"`
+	Content     string `json:"content,omitempty"`
+}
+
+// registerHostConfig registers HTTP handler for effective config files of hosts.
+// Callers are authorized with their own k8s RBAC and are required to be allowed to 'get' CHIs of the namespace
+func (c *Controller) registerHostConfig(mux *http.ServeMux) {
+	mux.HandleFunc(hostConfigPath, c.serveHostConfig)
+}
+
+// serveHostConfig publishes effective config files of hosts as JSON.
+// CHI is specified by 'namespace' and 'name' query params, optional 'host' query param selects one host
+// by either host name or pod name. Files content is omitted in case 'content' query param is 'no'.
+// Content of config files delivered in Secrets is never published, fingerprints only
+func (c *Controller) serveHostConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	name := r.URL.Query().Get("name")
+	if (namespace == "") || (name == "") {
+		http.Error(w, "namespace and name are required", http.StatusBadRequest)
+		return
+	}
+	if !c.authorizeRequest(w, r, "get", namespace) {
+		return
+	}
+	content := r.URL.Query().Get("content") != "no"
+
+	configs, err := c.newWorker(nil, true).getHostConfigs(r.Context(), namespace, name, r.URL.Query().Get("host"), content)
+	switch {
+	case apiErrors.IsNotFound(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(configs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// getHostConfigs collects config files of all hosts of the CHI, or of the specified host only
+func (w *worker) getHostConfigs(ctx context.Context, namespace, name, hostName string, content bool) ([]*HostConfig, error) {
+	chi, err := w.c.chopClient.ClickhouseV1().ClickHouseInstallations(namespace).Get(ctx, name, controller.NewGetOptions())
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := w.normalizer.CreateTemplatedCHI(chi.DeepCopy(), normalizer.NewOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*HostConfig
+	normalized.WalkHosts(func(host *api.ChiHost) error {
+		pod := model.CreatePodName(host)
+		if (hostName != "") && (hostName != host.GetName()) && (hostName != pod) {
+			return nil
+		}
+		configs = append(configs, w.getHostConfig(ctx, host, content))
+		return nil
+	})
+	if (hostName != "") && (len(configs) == 0) {
+		return nil, apiErrors.NewNotFound(api.SchemeGroupVersion.WithResource("hosts").GroupResource(), hostName)
+	}
+	return configs, nil
+}
+
+// getHostConfig collects config files of the host out of ConfigMaps or Secrets mounted into the host's pod
+func (w *worker) getHostConfig(ctx context.Context, host *api.ChiHost, content bool) *HostConfig {
+	config := &HostConfig{
+		Host: host.GetName(),
+		Pod:  model.CreatePodName(host),
+	}
+	chi := host.GetCHI()
+	secret := chi.Spec.Defaults.IsConfigStoredInSecret()
+	for _, source := range []struct {
+		name   string
+		dir    string
+		secret bool
+	}{
+		{model.CreateConfigMapCommonName(chi), model.DirPathCommonConfig, secret},
+		{model.CreateConfigMapCommonUsersName(chi), model.DirPathUsersConfig, chi.IsUsersStoredInSecret()},
+		{model.CreateConfigMapHostName(host), model.DirPathHostConfig, secret},
+	} {
+		kind, files, err := w.getConfigFiles(ctx, host.Runtime.Address.Namespace, source.name, source.secret)
+		if err != nil {
+			config.Errors = append(config.Errors, err.Error())
+			continue
+		}
+		for filename, data := range files {
+			file := HostConfigFile{
+				Path:        path.Join(source.dir, filename),
+				Source:      kind + "/" + source.name,
+				Fingerprint: util.HashIntoString([]byte(data)),
+			}
+			if content && !source.secret {
+				file.Content = data
+			}
+			config.Files = append(config.Files, file)
+		}
+	}
+
+	sort.Slice(config.Files, func(i, j int) bool {
+		return config.Files[i].Path < config.Files[j].Path
+	})
+	var fingerprints []string
+	for _, file := range config.Files {
+		fingerprints = append(fingerprints, file.Path+":"+file.Fingerprint)
+	}
+	config.Fingerprint = util.HashIntoString([]byte(strings.Join(fingerprints, "\n")))
+	return config
+}
+
+// getConfigFiles gets config files out of ConfigMap or Secret, depending on where config files are delivered in
+func (w *worker) getConfigFiles(ctx context.Context, namespace, name string, secret bool) (string, map[string]string, error) {
+	if secret {
+		s, err := w.c.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, controller.NewGetOptions())
+		if err != nil {
+			return "", nil, fmt.Errorf("secret %s/%s: %v", namespace, name, err)
+		}
+		files := make(map[string]string)
+		for filename, data := range s.Data {
+			files[filename] = string(data)
+		}
+		return "secret", files, nil
+	}
+
+	cm, err := w.c.kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, controller.NewGetOptions())
+	if err != nil {
+		return "", nil, fmt.Errorf("configmap %s/%s: %v", namespace, name, err)
+	}
+	return "configmap", cm.Data, nil
+}
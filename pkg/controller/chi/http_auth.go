@@ -0,0 +1,88 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"net/http"
+	"strings"
+
+	authentication "k8s.io/api/authentication/v1"
+	authorization "k8s.io/api/authorization/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+)
+
+// authorizedResource specifies resource HTTP API callers are authorized against
+const authorizedResource = "clickhouseinstallations"
+
+// authorizeRequest checks HTTP API caller is allowed to run the verb on CHIs of the namespace.
+// Caller authenticates with k8s bearer token, which is checked via TokenReview,
+// and is authorized via SubjectAccessReview, so API grants nothing beyond the caller's own RBAC.
+// Error response is written in case the caller is not allowed
+func (c *Controller) authorizeRequest(w http.ResponseWriter, r *http.Request, verb, namespace string) bool {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if (token == "") || (token == r.Header.Get("Authorization")) {
+		http.Error(w, "bearer token is required", http.StatusUnauthorized)
+		return false
+	}
+
+	review, err := c.kubeClient.AuthenticationV1().TokenReviews().Create(r.Context(), &authentication.TokenReview{
+		Spec: authentication.TokenReviewSpec{
+			Token: token,
+		},
+	}, controller.NewCreateOptions())
+	if err != nil {
+		log.V(1).F().Warning("unable to review token err: %v", err)
+		http.Error(w, "unable to authenticate", http.StatusInternalServerError)
+		return false
+	}
+	if !review.Status.Authenticated {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return false
+	}
+
+	user := review.Status.User
+	extra := make(map[string]authorization.ExtraValue)
+	for key, value := range user.Extra {
+		extra[key] = authorization.ExtraValue(value)
+	}
+	access, err := c.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(r.Context(), &authorization.SubjectAccessReview{
+		Spec: authorization.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorization.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     api.SchemeGroupVersion.Group,
+				Resource:  authorizedResource,
+			},
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+		},
+	}, controller.NewCreateOptions())
+	if err != nil {
+		log.V(1).F().Warning("unable to review access err: %v", err)
+		http.Error(w, "unable to authorize", http.StatusInternalServerError)
+		return false
+	}
+	if !access.Status.Allowed {
+		log.V(1).F().Info("user %s is not allowed to %s CHIs in namespace %s", user.Username, verb, namespace)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+)
+
+const (
+	// apiMaxBodyBytes limits size of request bodies accepted by HTTP API
+	apiMaxBodyBytes = 32 * 1024 * 1024
+	// apiReadHeaderTimeout limits time HTTP API callers may take to send request headers
+	apiReadHeaderTimeout = 10 * time.Second
+	// apiShutdownTimeout specifies how long HTTP API requests in-flight are waited for on shutdown
+	apiShutdownTimeout = 10 * time.Second
+)
+
+// ServeAPI serves HTTP API - CHI export and import, effective config of hosts and preview of rendered objects,
+// over TLS on the address of its own, till the context is done.
+// API is not served along with metrics, since callers send their k8s bearer tokens, which must not travel in cleartext
+func (c *Controller) ServeAPI(ctx context.Context, addr, certFile, keyFile string) error {
+	mux := http.NewServeMux()
+	c.registerExportImport(mux)
+	c.registerHostConfig(mux)
+	c.registerPreview(mux)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: apiReadHeaderTimeout,
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.V(1).F().Info("Serve HTTP API on %s", addr)
+	if err := server.ListenAndServeTLS(certFile, keyFile); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// limitRequestBody limits size of the request body, so large bodies are refused instead of being read into memory
+func limitRequestBody(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, apiMaxBodyBytes)
+}
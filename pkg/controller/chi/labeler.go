@@ -248,7 +248,7 @@ func (c *Controller) addLabels(labels map[string]string) map[string]string {
 	)
 }
 
-// appendLabelReadyOnPod appends Label "Ready" to the pod of the specified host
+// appendLabelReadyOnPod appends Label "Ready", along with replica role label, to the pod of the specified host
 func (c *Controller) appendLabelReadyOnPod(ctx context.Context, host *api.ChiHost) error {
 	if util.IsContextDone(ctx) {
 		log.V(2).Info("task is done")
@@ -261,7 +261,9 @@ func (c *Controller) appendLabelReadyOnPod(ctx context.Context, host *api.ChiHos
 		return err
 	}
 
-	if model.AppendLabelReady(&pod.ObjectMeta) {
+	ready := model.AppendLabelReady(&pod.ObjectMeta)
+	role := model.AppendLabelReplicaRole(&pod.ObjectMeta, host)
+	if ready || role {
 		// Modified, need to update
 		_, err = c.kubeClient.CoreV1().Pods(pod.Namespace).Update(ctx, pod, controller.NewUpdateOptions())
 		if err != nil {
@@ -16,12 +16,21 @@ package chi
 
 import (
 	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/altinity/queue"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
 	"github.com/altinity/clickhouse-operator/pkg/metrics"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
 // Metrics is a set of metrics that are tracked by the operator
@@ -49,13 +58,89 @@ type Metrics struct {
 	// HostReconcilesTimings is a histogram of durations of successfully completed host reconciles
 	HostReconcilesTimings metric.Float64Histogram
 
+	// HostRestartsInProgress is a number (up-down counter) of hosts being restarted across all CHIs
+	HostRestartsInProgress metric.Int64UpDownCounter
+	// HostRestartsWaiting is a number (up-down counter) of hosts waiting for the concurrent restarts limit
+	HostRestartsWaiting metric.Int64UpDownCounter
+
 	PodAddEvents    metric.Int64Counter
 	PodUpdateEvents metric.Int64Counter
 	PodDeleteEvents metric.Int64Counter
+
+	// CHINodeHosts is a number (gauge) of CHI hosts running on each k8s node
+	CHINodeHosts metric.Int64ObservableGauge
+	// CHIRequestedResources is an amount (gauge) of CPU cores, memory bytes and storage bytes requested by each cluster of CHI
+	CHIRequestedResources metric.Float64ObservableGauge
+
+	// HostSQLTimings is a histogram of durations of SQL calls made by the operator to hosts
+	HostSQLTimings metric.Float64Histogram
+	// HostSQLErrors is a number (counter) of failed SQL calls made by the operator to hosts
+	HostSQLErrors metric.Int64Counter
+	// HostSQLSlow is a number (counter) of SQL statements made by the operator to hosts running longer than threshold
+	HostSQLSlow metric.Int64Counter
+	// CHISlowSQL is a duration (gauge) of the slowest SQL statements made by the operator to hosts of each CHI
+	CHISlowSQL metric.Float64ObservableGauge
+
+	// ServiceUpdateErrors is a number (counter) of failed Service updates
+	ServiceUpdateErrors metric.Int64Counter
+
+	// QueueDepth is a number (gauge) of items waiting in each reconcile queue
+	QueueDepth metric.Int64ObservableGauge
 }
 
 var m *Metrics
 
+// chiNodes keeps latest known nodes usage of each CHI, to be reported by CHINodeHosts gauge
+var chiNodes = struct {
+	sync.Mutex
+	attributes map[string][]attribute.KeyValue
+	nodes      map[string]map[string][]string
+}{
+	attributes: make(map[string][]attribute.KeyValue),
+	nodes:      make(map[string]map[string][]string),
+}
+
+// requestedResources describes amount of a resource requested by a cluster
+type requestedResources struct {
+	cluster  string
+	resource string
+	value    float64
+}
+
+// chiFootprint keeps latest known requested resources of each CHI, to be reported by CHIRequestedResources gauge
+var chiFootprint = struct {
+	sync.Mutex
+	attributes map[string][]attribute.KeyValue
+	resources  map[string][]requestedResources
+}{
+	attributes: make(map[string][]attribute.KeyValue),
+	resources:  make(map[string][]requestedResources),
+}
+
+// slowSQL describes slow SQL statement made to the host
+type slowSQL struct {
+	cluster     string
+	host        string
+	fingerprint string
+	duration    float64
+}
+
+// chiSlowSQL keeps the slowest SQL statements of each CHI, to be reported by CHISlowSQL gauge
+var chiSlowSQL = struct {
+	sync.Mutex
+	attributes map[string][]attribute.KeyValue
+	top        map[string][]slowSQL
+}{
+	attributes: make(map[string][]attribute.KeyValue),
+	top:        make(map[string][]slowSQL),
+}
+
+// queues keeps reconcile queues of the controller, to be reported by QueueDepth gauge
+var queues = struct {
+	sync.Mutex
+	queues []queue.PriorityQueue
+}{}
+
 func createMetrics() *Metrics {
 	// The unit u should be defined using the appropriate [UCUM](https://ucum.org) case-sensitive code.
 	CHIReconcilesStarted, _ := metrics.Meter().Int64Counter(
@@ -105,6 +190,17 @@ func createMetrics() *Metrics {
 		metric.WithUnit("s"),
 	)
 
+	HostRestartsInProgress, _ := metrics.Meter().Int64UpDownCounter(
+		"clickhouse_operator_host_restarts_in_progress",
+		metric.WithDescription("number of hosts being restarted across all CHIs"),
+		metric.WithUnit("items"),
+	)
+	HostRestartsWaiting, _ := metrics.Meter().Int64UpDownCounter(
+		"clickhouse_operator_host_restarts_waiting",
+		metric.WithDescription("number of hosts waiting for the concurrent restarts limit"),
+		metric.WithUnit("items"),
+	)
+
 	PodAddEvents, _ := metrics.Meter().Int64Counter(
 		"clickhouse_operator_pod_add_events",
 		metric.WithDescription("number PodAdd events"),
@@ -121,6 +217,53 @@ func createMetrics() *Metrics {
 		metric.WithUnit("items"),
 	)
 
+	CHINodeHosts, _ := metrics.Meter().Int64ObservableGauge(
+		"clickhouse_operator_chi_node_hosts",
+		metric.WithDescription("number of CHI hosts running on k8s node"),
+		metric.WithUnit("items"),
+		metric.WithInt64Callback(observeCHINodeHosts),
+	)
+	CHIRequestedResources, _ := metrics.Meter().Float64ObservableGauge(
+		"clickhouse_operator_chi_requested_resources",
+		metric.WithDescription("amount of CPU cores, memory bytes and storage bytes requested by CHI cluster"),
+		metric.WithFloat64Callback(observeCHIRequestedResources),
+	)
+
+	HostSQLTimings, _ := metrics.Meter().Float64Histogram(
+		"clickhouse_operator_host_sql_timings",
+		metric.WithDescription("timings of SQL calls made to hosts"),
+		metric.WithUnit("s"),
+	)
+	HostSQLErrors, _ := metrics.Meter().Int64Counter(
+		"clickhouse_operator_host_sql_errors",
+		metric.WithDescription("number of failed SQL calls made to hosts"),
+		metric.WithUnit("items"),
+	)
+	HostSQLSlow, _ := metrics.Meter().Int64Counter(
+		"clickhouse_operator_host_sql_slow",
+		metric.WithDescription("number of SQL statements made to hosts running longer than slow query threshold"),
+		metric.WithUnit("items"),
+	)
+	CHISlowSQL, _ := metrics.Meter().Float64ObservableGauge(
+		"clickhouse_operator_chi_slow_sql",
+		metric.WithDescription("durations of the slowest SQL statements made to hosts of CHI"),
+		metric.WithUnit("s"),
+		metric.WithFloat64Callback(observeCHISlowSQL),
+	)
+
+	ServiceUpdateErrors, _ := metrics.Meter().Int64Counter(
+		"clickhouse_operator_service_update_errors",
+		metric.WithDescription("number of failed Service updates"),
+		metric.WithUnit("items"),
+	)
+
+	QueueDepth, _ := metrics.Meter().Int64ObservableGauge(
+		"clickhouse_operator_queue_depth",
+		metric.WithDescription("number of items waiting in reconcile queue"),
+		metric.WithUnit("items"),
+		metric.WithInt64Callback(observeQueueDepth),
+	)
+
 	return &Metrics{
 		CHIReconcilesStarted:   CHIReconcilesStarted,
 		CHIReconcilesCompleted: CHIReconcilesCompleted,
@@ -133,9 +276,24 @@ func createMetrics() *Metrics {
 		HostReconcilesErrors:    HostReconcilesErrors,
 		HostReconcilesTimings:   HostReconcilesTimings,
 
+		HostRestartsInProgress: HostRestartsInProgress,
+		HostRestartsWaiting:    HostRestartsWaiting,
+
 		PodAddEvents:    PodAddEvents,
 		PodUpdateEvents: PodUpdateEvents,
 		PodDeleteEvents: PodDeleteEvents,
+
+		CHINodeHosts:          CHINodeHosts,
+		CHIRequestedResources: CHIRequestedResources,
+
+		HostSQLTimings: HostSQLTimings,
+		HostSQLErrors:  HostSQLErrors,
+		HostSQLSlow:    HostSQLSlow,
+		CHISlowSQL:     CHISlowSQL,
+
+		ServiceUpdateErrors: ServiceUpdateErrors,
+
+		QueueDepth: QueueDepth,
 	}
 }
 
@@ -186,6 +344,13 @@ func metricsHostReconcilesTimings(ctx context.Context, chi *api.ClickHouseInstal
 	ensureMetrics().HostReconcilesTimings.Record(ctx, seconds, metric.WithAttributes(prepareLabels(chi)...))
 }
 
+func metricsHostRestartsInProgress(ctx context.Context, chi *api.ClickHouseInstallation, delta int64) {
+	ensureMetrics().HostRestartsInProgress.Add(ctx, delta, metric.WithAttributes(prepareLabels(chi)...))
+}
+func metricsHostRestartsWaiting(ctx context.Context, chi *api.ClickHouseInstallation, delta int64) {
+	ensureMetrics().HostRestartsWaiting.Add(ctx, delta, metric.WithAttributes(prepareLabels(chi)...))
+}
+
 func metricsPodAdd(ctx context.Context) {
 	ensureMetrics().PodAddEvents.Add(ctx, 1)
 }
@@ -195,3 +360,192 @@ func metricsPodUpdate(ctx context.Context) {
 func metricsPodDelete(ctx context.Context) {
 	ensureMetrics().PodDeleteEvents.Add(ctx, 1)
 }
+
+// metricsCHINodes remembers nodes usage of the CHI to be reported by the gauge
+func metricsCHINodes(chi *api.ClickHouseInstallation, nodes map[string][]string) {
+	ensureMetrics()
+	key := util.NamespaceNameString(chi.ObjectMeta)
+	chiNodes.Lock()
+	defer chiNodes.Unlock()
+	chiNodes.attributes[key] = prepareLabels(chi)
+	chiNodes.nodes[key] = nodes
+}
+
+// metricsCHINodesDelete forgets nodes usage of the deleted CHI
+func metricsCHINodesDelete(chi *api.ClickHouseInstallation) {
+	key := util.NamespaceNameString(chi.ObjectMeta)
+	chiNodes.Lock()
+	defer chiNodes.Unlock()
+	delete(chiNodes.attributes, key)
+	delete(chiNodes.nodes, key)
+}
+
+// metricsCHIFootprint remembers resources requested by clusters of the CHI to be reported by the gauge
+func metricsCHIFootprint(chi *api.ClickHouseInstallation, footprint *api.ChiFootprint) {
+	ensureMetrics()
+	var resources []requestedResources
+	for _, cluster := range footprint.Clusters {
+		for name, value := range map[string]string{
+			"cpu":     cluster.CPU,
+			"memory":  cluster.Memory,
+			"storage": cluster.Storage,
+		} {
+			quantity, err := resource.ParseQuantity(value)
+			if err != nil {
+				continue
+			}
+			resources = append(resources, requestedResources{
+				cluster:  cluster.Name,
+				resource: name,
+				value:    quantity.AsApproximateFloat64(),
+			})
+		}
+	}
+	key := util.NamespaceNameString(chi.ObjectMeta)
+	chiFootprint.Lock()
+	defer chiFootprint.Unlock()
+	chiFootprint.attributes[key] = prepareLabels(chi)
+	chiFootprint.resources[key] = resources
+}
+
+// metricsCHIFootprintDelete forgets resources requested by the deleted CHI
+func metricsCHIFootprintDelete(chi *api.ClickHouseInstallation) {
+	key := util.NamespaceNameString(chi.ObjectMeta)
+	chiFootprint.Lock()
+	defer chiFootprint.Unlock()
+	delete(chiFootprint.attributes, key)
+	delete(chiFootprint.resources, key)
+}
+
+func observeCHIRequestedResources(_ context.Context, observer metric.Float64Observer) error {
+	chiFootprint.Lock()
+	defer chiFootprint.Unlock()
+	for key, resources := range chiFootprint.resources {
+		for _, r := range resources {
+			attributes := append([]attribute.KeyValue{
+				attribute.String("cluster", r.cluster),
+				attribute.String("resource", r.resource),
+			}, chiFootprint.attributes[key]...)
+			observer.Observe(r.value, metric.WithAttributes(attributes...))
+		}
+	}
+	return nil
+}
+
+func observeCHINodeHosts(_ context.Context, observer metric.Int64Observer) error {
+	chiNodes.Lock()
+	defer chiNodes.Unlock()
+	for key, nodes := range chiNodes.nodes {
+		for node, pods := range nodes {
+			attributes := append([]attribute.KeyValue{attribute.String("node", node)}, chiNodes.attributes[key]...)
+			observer.Observe(int64(len(pods)), metric.WithAttributes(attributes...))
+		}
+	}
+	return nil
+}
+
+// prepareHostLabels prepares labels identifying the host within its CHI, host names are unique within a cluster only
+func prepareHostLabels(host *api.ChiHost) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("cluster", host.Runtime.Address.ClusterName),
+		attribute.String("host", host.GetName()),
+	}
+}
+
+// metricsHostSQL tracks SQL call made to the host. Used as schemer's query observer
+func metricsHostSQL(host *api.ChiHost, duration time.Duration, err error) {
+	attributes := metric.WithAttributes(append(prepareLabels(host.GetCHI()), prepareHostLabels(host)...)...)
+	ensureMetrics().HostSQLTimings.Record(context.Background(), duration.Seconds(), attributes)
+	if err != nil {
+		ensureMetrics().HostSQLErrors.Add(context.Background(), 1, attributes)
+	}
+}
+
+// metricsHostSlowSQL tracks slow SQL statement made to the host. Used as schemer's slow query observer.
+// The slowest statements of the CHI are kept to be reported by the gauge
+func metricsHostSlowSQL(host *api.ChiHost, sql string, duration time.Duration) {
+	chi := host.GetCHI()
+	attributes := prepareLabels(chi)
+	ensureMetrics().HostSQLSlow.Add(context.Background(), 1,
+		metric.WithAttributes(append(attributes, prepareHostLabels(host)...)...))
+
+	key := util.NamespaceNameString(chi.ObjectMeta)
+	chiSlowSQL.Lock()
+	defer chiSlowSQL.Unlock()
+	chiSlowSQL.attributes[key] = attributes
+	// Statement itself is logged along with its fingerprint, which is reported instead,
+	// so label values are of limited cardinality and do not carry data statements may contain
+	slow := slowSQL{
+		cluster:     host.Runtime.Address.ClusterName,
+		host:        host.GetName(),
+		fingerprint: clickhouse.SQLFingerprint(sql),
+		duration:    duration.Seconds(),
+	}
+	top := chiSlowSQL.top[key]
+	known := false
+	for i := range top {
+		// The same statement made to the same host is reported once, by the longest duration
+		if (top[i].cluster == slow.cluster) && (top[i].host == slow.host) && (top[i].fingerprint == slow.fingerprint) {
+			if slow.duration > top[i].duration {
+				top[i].duration = slow.duration
+			}
+			known = true
+		}
+	}
+	if !known {
+		top = append(top, slow)
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].duration > top[j].duration
+	})
+	if n := chop.Config().ClickHouse.Access.SlowQueries.Top; len(top) > n {
+		top = top[:n]
+	}
+	chiSlowSQL.top[key] = top
+}
+
+// metricsCHISlowSQLDelete forgets the slowest SQL statements of the deleted CHI
+func metricsCHISlowSQLDelete(chi *api.ClickHouseInstallation) {
+	key := util.NamespaceNameString(chi.ObjectMeta)
+	chiSlowSQL.Lock()
+	defer chiSlowSQL.Unlock()
+	delete(chiSlowSQL.attributes, key)
+	delete(chiSlowSQL.top, key)
+}
+
+func observeCHISlowSQL(_ context.Context, observer metric.Float64Observer) error {
+	chiSlowSQL.Lock()
+	defer chiSlowSQL.Unlock()
+	for key, top := range chiSlowSQL.top {
+		for i, slow := range top {
+			attributes := append([]attribute.KeyValue{
+				attribute.String("cluster", slow.cluster),
+				attribute.String("host", slow.host),
+				attribute.String("fingerprint", slow.fingerprint),
+				attribute.String("rank", strconv.Itoa(i+1)),
+			}, chiSlowSQL.attributes[key]...)
+			observer.Observe(slow.duration, metric.WithAttributes(attributes...))
+		}
+	}
+	return nil
+}
+
+func metricsServiceUpdateErrors(ctx context.Context, chi *api.ClickHouseInstallation) {
+	ensureMetrics().ServiceUpdateErrors.Add(ctx, 1, metric.WithAttributes(prepareLabels(chi)...))
+}
+
+// metricsQueues remembers reconcile queues to be reported by the gauge
+func metricsQueues(list []queue.PriorityQueue) {
+	queues.Lock()
+	defer queues.Unlock()
+	queues.queues = list
+}
+
+func observeQueueDepth(_ context.Context, observer metric.Int64Observer) error {
+	queues.Lock()
+	defer queues.Unlock()
+	for i, q := range queues.queues {
+		observer.Observe(int64(q.Len()), metric.WithAttributes(attribute.String("queue", strconv.Itoa(i))))
+	}
+	return nil
+}
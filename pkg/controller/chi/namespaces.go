@@ -0,0 +1,121 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+
+	authorization "k8s.io/api/authorization/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeInformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+)
+
+// ShouldWatchNamespaces checks whether namespaces are to be watched in order to select watched namespaces by labels.
+// Error is returned in case the operator is not allowed to read namespaces or the access can not be checked,
+// since no namespace would ever be selected and the operator would silently watch nothing
+func (c *Controller) ShouldWatchNamespaces(ctx context.Context) (bool, error) {
+	if !chop.Config().HasNamespaceSelector() {
+		return false, nil
+	}
+	for _, verb := range []string{"list", "watch"} {
+		review := &authorization.SelfSubjectAccessReview{
+			Spec: authorization.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorization.ResourceAttributes{
+					Verb:     verb,
+					Resource: "namespaces",
+				},
+			},
+		}
+		result, err := c.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, controller.NewCreateOptions())
+		if err != nil {
+			return false, fmt.Errorf("unable to check access to namespaces required by namespace selector %s err: %v",
+				chop.Config().Watch.NamespaceSelector, err)
+		}
+		if !result.Status.Allowed {
+			return false, fmt.Errorf("operator is not allowed to %s namespaces required by namespace selector %s",
+				verb, chop.Config().Watch.NamespaceSelector)
+		}
+	}
+	return true, nil
+}
+
+// AddEventHandlersNamespaces adds event handlers of namespaces provided by informer factory of namespaces.
+// Informer factory is expected to be filtered by namespace selector, thus namespace which stops matching the selector
+// is delivered as deleted one
+func (c *Controller) AddEventHandlersNamespaces(kubeInformerFactory kubeInformers.SharedInformerFactory) {
+	kubeInformerFactory.Core().V1().Namespaces().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			namespace := obj.(*core.Namespace)
+			log.V(3).M(namespace).Info("namespaceInformer.AddFunc")
+			c.selectNamespace(namespace.Name)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			namespace := new.(*core.Namespace)
+			log.V(3).M(namespace).Info("namespaceInformer.UpdateFunc")
+			c.selectNamespace(namespace.Name)
+		},
+		DeleteFunc: func(obj interface{}) {
+			namespace, ok := obj.(*core.Namespace)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				if namespace, ok = tombstone.Obj.(*core.Namespace); !ok {
+					return
+				}
+			}
+			log.V(3).M(namespace).Info("namespaceInformer.DeleteFunc")
+			c.unselectNamespace(namespace.Name)
+		},
+	})
+}
+
+// selectNamespace starts watching the namespace matching namespace selector.
+// Objects of the namespace delivered by informers earlier have been skipped, so they are enqueued now
+func (c *Controller) selectNamespace(namespace string) {
+	if !chop.Config().SetSelectedNamespace(namespace, true) {
+		return
+	}
+	log.V(1).Info("namespace matches namespace selector, start watching it: %s", namespace)
+
+	// Templates go first, so CHIs are reconciled with templates known
+	if chits, err := c.chitLister.ClickHouseInstallationTemplates(namespace).List(labels.Everything()); err == nil {
+		for _, chit := range chits {
+			c.enqueueObject(NewReconcileCHIT(reconcileAdd, nil, chit))
+		}
+	}
+	if chis, err := c.chiLister.ClickHouseInstallations(namespace).List(labels.Everything()); err == nil {
+		for _, chi := range chis {
+			c.enqueueObject(NewReconcileCHI(reconcileAdd, nil, chi))
+		}
+	}
+}
+
+// unselectNamespace stops watching the namespace not matching namespace selector anymore.
+// Objects of the namespace are left intact
+func (c *Controller) unselectNamespace(namespace string) {
+	if !chop.Config().SetSelectedNamespace(namespace, false) {
+		return
+	}
+	log.V(1).Info("namespace does not match namespace selector anymore, stop watching it: %s", namespace)
+}
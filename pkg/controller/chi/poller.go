@@ -111,7 +111,8 @@ func (c *Controller) waitHostDeleted(host *api.ChiHost) {
 	}
 }
 
-// pollHost polls host
+// pollHost polls host.
+// Options specified by the caller are used as they are, otherwise host is polled within the budget for host to start
 func (c *Controller) pollHost(
 	ctx context.Context,
 	host *api.ChiHost,
@@ -123,7 +124,14 @@ func (c *Controller) pollHost(
 		return nil
 	}
 
-	opts = opts.Ensure().FromConfig(chop.Config())
+	if opts == nil {
+		// Caller has no budget of its own, host is waited for to start
+		opts = controller.NewPollerOptions().FromConfig(chop.Config())
+		if timeout := host.GetCHI().GetReconciling().GetTimeouts().GetPodStart(); timeout > 0 {
+			// CHI may have its own budget for host to start
+			opts.Timeout = timeout
+		}
+	}
 	namespace := host.Runtime.Address.Namespace
 	name := host.Runtime.Address.HostName
 
@@ -156,6 +164,10 @@ func (c *Controller) pollHostStatefulSet(
 	if opts == nil {
 		opts = controller.NewPollerOptions().FromConfig(chop.Config())
 	}
+	if timeout := host.GetCHI().GetReconciling().GetTimeouts().GetStatefulSetUpdate(); timeout > 0 {
+		// CHI may have its own budget for StatefulSet to be updated
+		opts.Timeout = timeout
+	}
 
 	namespace := host.Runtime.Address.Namespace
 	name := host.Runtime.Address.StatefulSet
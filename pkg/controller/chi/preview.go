@@ -0,0 +1,95 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/render"
+)
+
+// previewPath specifies HTTP path where objects rendered out of CHI manifest are served
+const previewPath = "/chi/preview"
+
+// previewDefaultNamespace specifies namespace of CHI manifest w/o namespace specified
+const previewDefaultNamespace = "default"
+
+// CHIPreview specifies objects the operator would create for the CHI, w/o applying them.
+// Secrets data is never published, keys only
+type CHIPreview = render.Objects
+
+// registerPreview registers HTTP handler for preview of objects rendered out of CHI manifest.
+// Callers are authorized with their own k8s RBAC and are required to be allowed to 'create' CHIs of the namespace
+func (c *Controller) registerPreview(mux *http.ServeMux) {
+	mux.HandleFunc(previewPath, c.servePreview)
+}
+
+// servePreview renders objects out of CHI manifest posted, either in YAML or in JSON, and publishes them as JSON.
+// Nothing is applied to k8s. Optional 'namespace' query param overrides CHI namespace
+func (c *Controller) servePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limitRequestBody(w, r)
+	chi := &api.ClickHouseInstallation{}
+	if err := yaml.NewYAMLOrJSONDecoder(r.Body, 4096).Decode(chi); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (chi.Kind != "") && (chi.Kind != api.ClickHouseInstallationCRDResourceKind) {
+		http.Error(w, fmt.Sprintf("kind %s is not supported", chi.Kind), http.StatusBadRequest)
+		return
+	}
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		chi.Namespace = namespace
+	}
+	if chi.Namespace == "" {
+		chi.Namespace = previewDefaultNamespace
+	}
+	if !c.authorizeRequest(w, r, "create", chi.Namespace) {
+		return
+	}
+
+	preview, err := c.newWorker(nil, true).previewCHI(chi)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(preview); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// previewCHI renders objects of the CHI the same way reconcile does, w/o applying them.
+// Secrets referenced by the CHI are never resolved, so the preview does not reveal them
+func (w *worker) previewCHI(chi *api.ClickHouseInstallation) (*CHIPreview, error) {
+	preview, err := render.NewRenderer(render.NewOptions()).Render(chi)
+	if err != nil {
+		return nil, err
+	}
+
+	w.a.V(1).M(chi).F().Info("CHI %s/%s previewed. StatefulSets: %d Services: %d ConfigMaps: %d Secrets: %d",
+		chi.Namespace, chi.Name, len(preview.StatefulSets), len(preview.Services), len(preview.ConfigMaps), len(preview.Secrets))
+	return preview, nil
+}
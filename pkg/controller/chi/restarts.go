@@ -0,0 +1,87 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+)
+
+// initRestarts sets up operator-wide limit of concurrent host restarts
+func (c *Controller) initRestarts() {
+	if max := chop.Config().Reconcile.Runtime.MaxConcurrentRestarts; max > 0 {
+		c.restarts = make(chan struct{}, max)
+	}
+}
+
+// acquireRestart waits for a free slot of the concurrent host restarts limit.
+// Returns false in case the context is done before the slot is acquired
+func (c *Controller) acquireRestart(ctx context.Context) bool {
+	if c.restarts == nil {
+		return true
+	}
+	select {
+	case c.restarts <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// releaseRestart frees the slot of the concurrent host restarts limit
+func (c *Controller) releaseRestart() {
+	if c.restarts == nil {
+		return
+	}
+	<-c.restarts
+}
+
+// shouldLimitHostRestart checks whether the host is about to be restarted, so it falls under the concurrent restarts limit.
+// Forced restarts, such as requested by restart annotation, are limited along with restarts due to modifications
+func (w *worker) shouldLimitHostRestart(host *api.ChiHost) bool {
+	return w.isHostToBeRestarted(host)
+}
+
+// acquireHostRestart waits until the host is allowed to restart w/o exceeding the limit of hosts
+// restarted concurrently across all CHIs. Returned func releases the restart slot and has to be called
+// once the host is restarted
+func (w *worker) acquireHostRestart(ctx context.Context, host *api.ChiHost) (func(), error) {
+	if !w.shouldLimitHostRestart(host) {
+		return func() {}, nil
+	}
+
+	chi := host.GetCHI()
+	metricsHostRestartsWaiting(ctx, chi, 1)
+	startTime := time.Now()
+	acquired := w.c.acquireRestart(ctx)
+	metricsHostRestartsWaiting(ctx, chi, -1)
+	if !acquired {
+		return nil, ctx.Err()
+	}
+	if waited := time.Since(startTime); waited > time.Second {
+		w.a.V(1).M(host).F().Info(
+			"Host: %s waited %s for concurrent restarts limit of %d",
+			host.GetName(), waited.Round(time.Second), chop.Config().Reconcile.Runtime.MaxConcurrentRestarts)
+	}
+
+	metricsHostRestartsInProgress(ctx, chi, 1)
+	return func() {
+		metricsHostRestartsInProgress(ctx, chi, -1)
+		w.c.releaseRestart()
+	}, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"sync"
+
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+)
+
+// secretsIndex maps Secrets, as namespace/name, to CHIs, as namespace/name, referencing them
+type secretsIndex struct {
+	sync.RWMutex
+	// chis maps Secret to set of CHIs referencing it
+	chis map[string]map[string]bool
+	// secrets maps CHI to Secrets it references
+	secrets map[string][]string
+}
+
+// newSecretsIndex creates new secretsIndex
+func newSecretsIndex() *secretsIndex {
+	return &secretsIndex{
+		chis:    make(map[string]map[string]bool),
+		secrets: make(map[string][]string),
+	}
+}
+
+// set replaces Secrets referenced by the CHI
+func (i *secretsIndex) set(chi string, secrets []string) {
+	i.Lock()
+	defer i.Unlock()
+	i.unsetLocked(chi)
+	for _, secret := range secrets {
+		if i.chis[secret] == nil {
+			i.chis[secret] = make(map[string]bool)
+		}
+		i.chis[secret][chi] = true
+	}
+	if len(secrets) > 0 {
+		i.secrets[chi] = append([]string{}, secrets...)
+	}
+}
+
+// unset forgets Secrets referenced by the CHI
+func (i *secretsIndex) unset(chi string) {
+	i.Lock()
+	defer i.Unlock()
+	i.unsetLocked(chi)
+}
+
+func (i *secretsIndex) unsetLocked(chi string) {
+	for _, secret := range i.secrets[chi] {
+		delete(i.chis[secret], chi)
+		if len(i.chis[secret]) == 0 {
+			delete(i.chis, secret)
+		}
+	}
+	delete(i.secrets, chi)
+}
+
+// get gets CHIs referencing the Secret
+func (i *secretsIndex) get(secret string) (chis []string) {
+	i.RLock()
+	defer i.RUnlock()
+	for chi := range i.chis[secret] {
+		chis = append(chis, chi)
+	}
+	return chis
+}
+
+// indexCHISecrets registers Secrets the normalized CHI references, so the CHI is reconciled on change of any of them
+func (c *Controller) indexCHISecrets(chi *api.ClickHouseInstallation) {
+	if chi == nil {
+		return
+	}
+	c.secretsIndex.set(chi.Namespace+"/"+chi.Name, chi.EnsureRuntime().GetReferencedSecrets())
+}
+
+// unindexCHISecrets forgets Secrets the deleted CHI references
+func (c *Controller) unindexCHISecrets(chi *api.ClickHouseInstallation) {
+	c.secretsIndex.unset(chi.Namespace + "/" + chi.Name)
+}
+
+// AddEventHandlersSecrets adds event handlers of Secrets provided by metadata informer factory of Secrets.
+// Referenced Secrets are not labeled by the operator, thus informer factory is expected to be not filtered by labels.
+// Metadata only of Secrets is watched, so any change of a Secret, data or metadata, bumps its resourceVersion
+// and enqueues CHIs referencing it. Reconcile of a CHI, which Secrets data have not changed, has nothing to do
+func (c *Controller) AddEventHandlersSecrets(metadataInformerFactory metadatainformer.SharedInformerFactory) {
+	informer := metadataInformerFactory.ForResource(core.SchemeGroupVersion.WithResource("secrets")).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			secret := obj.(*meta.PartialObjectMetadata)
+			log.V(3).M(secret).Info("secretInformer.AddFunc")
+			c.enqueueSecretReferrers(&secret.ObjectMeta)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			oldSecret := old.(*meta.PartialObjectMetadata)
+			newSecret := new.(*meta.PartialObjectMetadata)
+			if oldSecret.ResourceVersion == newSecret.ResourceVersion {
+				// Resync, Secret is the same
+				return
+			}
+			log.V(3).M(newSecret).Info("secretInformer.UpdateFunc")
+			c.enqueueSecretReferrers(&newSecret.ObjectMeta)
+		},
+		DeleteFunc: func(obj interface{}) {
+			secret, ok := obj.(*meta.PartialObjectMetadata)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				if secret, ok = tombstone.Obj.(*meta.PartialObjectMetadata); !ok {
+					return
+				}
+			}
+			log.V(3).M(secret).Info("secretInformer.DeleteFunc")
+			c.enqueueSecretReferrers(&secret.ObjectMeta)
+		},
+	})
+}
+
+// enqueueSecretReferrers enqueues reconcile of CHIs referencing the Secret
+func (c *Controller) enqueueSecretReferrers(secret *meta.ObjectMeta) {
+	if !chop.Config().IsWatchedNamespace(secret.Namespace) || model.IsCHOPGeneratedObject(secret) {
+		// Secrets managed by the operator are versioned on their own
+		return
+	}
+	for _, key := range c.secretsIndex.get(secret.Namespace + "/" + secret.Name) {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			continue
+		}
+		chi, err := c.chiLister.ClickHouseInstallations(namespace).Get(name)
+		if err != nil {
+			// CHI is gone
+			c.secretsIndex.unset(key)
+			continue
+		}
+		if chi.IsStopped() {
+			continue
+		}
+		log.V(1).M(chi).F().Info("Secret %s/%s referenced by CHI is changed, reconcile CHI: %s/%s",
+			secret.Namespace, secret.Name, chi.Namespace, chi.Name)
+		// No old CHI, so reconcile does not exit on the same generation
+		c.enqueueObject(NewReconcileCHI(reconcileUpdate, nil, chi))
+	}
+}
@@ -45,6 +45,7 @@ const (
 	priorityReconcileChopConfig int = 3
 	priorityReconcileEndpoints  int = 15
 	priorityDropDNS             int = 7
+	priorityReloadUsersConfig   int = 15
 )
 
 // ReconcileCHI specifies reconcile request queue item
@@ -252,3 +253,31 @@ func NewReconcilePod(cmd string, old, new *core.Pod) *ReconcilePod {
 		new: new,
 	}
 }
+
+// ReloadUsersConfig specifies reload of users config of the CHI the pod, which IP address has changed, belongs to
+type ReloadUsersConfig struct {
+	PriorityQueueItem
+	initiator *meta.ObjectMeta
+	ip        string
+}
+
+var _ queue.PriorityQueueItem = &ReloadUsersConfig{}
+
+// Handle returns handle of the queue item
+func (r ReloadUsersConfig) Handle() queue.T {
+	if r.initiator != nil {
+		return "ReloadUsersConfig" + ":" + r.initiator.Namespace + "/" + r.initiator.Name
+	}
+	return ""
+}
+
+// NewReloadUsersConfig creates new reload users config queue item
+func NewReloadUsersConfig(initiator *meta.ObjectMeta, ip string) *ReloadUsersConfig {
+	return &ReloadUsersConfig{
+		PriorityQueueItem: PriorityQueueItem{
+			priority: priorityReloadUsersConfig,
+		},
+		initiator: initiator,
+		ip:        ip,
+	}
+}
@@ -15,8 +15,11 @@
 package chi
 
 import (
+	"context"
+	"sync"
 	"time"
 
+	"k8s.io/client-go/dynamic"
 	kube "k8s.io/client-go/kubernetes"
 	appsListers "k8s.io/client-go/listers/apps/v1"
 	coreListers "k8s.io/client-go/listers/core/v1"
@@ -36,6 +39,8 @@ type Controller struct {
 	// kubeClient used to Create() k8s resources as c.kubeClient.AppsV1().StatefulSets(namespace).Create(name)
 	kubeClient kube.Interface
 	extClient  apiExtensions.Interface
+	// dynamicClient used to manage k8s resources without typed clients, such as Gateway API routes
+	dynamicClient dynamic.Interface
 	// chopClient used to Update() CRD k8s resource as c.chopClient.ClickhouseV1().ClickHouseInstallations(chi.Namespace).Update(chiCopy)
 	chopClient chopClientSet.Interface
 
@@ -67,11 +72,45 @@ type Controller struct {
 	podLister coreListers.PodLister
 	// podListerSynced used in waitForCacheSync()
 	podListerSynced cache.InformerSynced
+	// nodeLister used as nodeLister.List(selector), nil in case nodes are not watched
+	nodeLister coreListers.NodeLister
+	// secretsIndex maps Secrets to CHIs referencing them
+	secretsIndex *secretsIndex
 
 	// queues used to organize events queue processed by operator
 	queues []queue.PriorityQueue
 	// not used explicitly
 	recorder record.EventRecorder
+
+	// shutdown protects shutdown state and in-flight items tracking
+	shutdown struct {
+		sync.Mutex
+		// requested specifies whether the controller is requested to stop picking new items
+		requested bool
+		// inFlight tracks items being processed by workers
+		inFlight sync.WaitGroup
+		// abort is done as soon as in-flight items are aborted, because shutdown grace period is exceeded
+		abort       context.Context
+		abortCancel context.CancelFunc
+	}
+
+	// backlog keeps reconcile commands of each CHI, which are not picked by workers yet, in order of arrival.
+	// Maps CHI queue item handle -> commands
+	backlog struct {
+		sync.Mutex
+		commands map[string][]*ReconcileCHI
+		// coalesced keeps resourceVersion of the CHI reconciled as the latest spec by coalesce policy.
+		// Maps CHI queue item handle -> resourceVersion
+		coalesced map[string]string
+	}
+
+	// restarts limits number of hosts restarted concurrently across all CHIs, each restart holds a slot.
+	// nil means unlimited
+	restarts chan struct{}
+
+	// podIPs keeps the last known IP address of each pod, so IP address change is told from the first IP address assigned.
+	// Maps namespace/name of the pod -> IP address
+	podIPs sync.Map
 }
 
 const (
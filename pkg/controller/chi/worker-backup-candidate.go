@@ -0,0 +1,128 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"sort"
+
+	core "k8s.io/api/core/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// isBackupCandidateEligible checks whether the pod is healthy enough to be a backup candidate of its shard:
+// pod is not being deleted, all its containers are ready and the operator has included it into the cluster
+func isBackupCandidateEligible(pod *core.Pod) bool {
+	if (pod == nil) || (pod.DeletionTimestamp != nil) {
+		return false
+	}
+	if !model.HasLabelReady(&pod.ObjectMeta) {
+		return false
+	}
+	if len(pod.Status.ContainerStatuses) == 0 {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if !status.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldMarkupBackupCandidate checks whether pod change may affect backup candidate of the shard
+func shouldMarkupBackupCandidate(old, new *core.Pod) bool {
+	if (old == nil) || (new == nil) {
+		return false
+	}
+	return (isBackupCandidateEligible(old) != isBackupCandidateEligible(new)) ||
+		(model.HasLabelBackupCandidate(&old.ObjectMeta) != model.HasLabelBackupCandidate(&new.ObjectMeta))
+}
+
+// markupShardBackupCandidate labels exactly one healthy replica of the shard the pod belongs to as a backup candidate,
+// so backup tooling is able to select one consistent target per shard by label.
+// Current candidate keeps the label as long as it is healthy, otherwise the first healthy replica is selected.
+// No replica is labelled in case the shard has no healthy replicas.
+func (w *worker) markupShardBackupCandidate(ctx context.Context, pod *core.Pod) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	selector := make(map[string]string)
+	for _, label := range []string{
+		model.LabelNamespace,
+		model.LabelAppName,
+		model.LabelCHIName,
+		model.LabelClusterName,
+		model.LabelShardName,
+	} {
+		value, ok := pod.Labels[label]
+		if !ok {
+			// Not a ClickHouse host pod
+			return
+		}
+		selector[label] = value
+	}
+
+	pods, err := w.c.podLister.Pods(pod.Namespace).List(k8sLabels.SelectorFromSet(selector))
+	if err != nil {
+		w.a.V(1).M(pod).F().Warning("unable to list pods of the shard of pod %s/%s err: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	var candidate *core.Pod
+	for _, p := range pods {
+		if model.HasLabelBackupCandidate(&p.ObjectMeta) && isBackupCandidateEligible(p) {
+			candidate = p
+			break
+		}
+	}
+	if candidate == nil {
+		for _, p := range pods {
+			if isBackupCandidateEligible(p) {
+				candidate = p
+				break
+			}
+		}
+	}
+
+	for _, p := range pods {
+		// Objects from the lister are shared, so they are not modified in place
+		p = p.DeepCopy()
+		var modified bool
+		if p.Name == candidate.GetName() {
+			modified = model.AppendLabelBackupCandidate(&p.ObjectMeta)
+		} else {
+			modified = model.DeleteLabelBackupCandidate(&p.ObjectMeta)
+		}
+		if !modified {
+			continue
+		}
+		if _, err := w.c.kubeClient.CoreV1().Pods(p.Namespace).Update(ctx, p, controller.NewUpdateOptions()); err != nil {
+			w.a.V(1).M(p).F().Warning("unable to update backup candidate label of pod %s/%s err: %v", p.Namespace, p.Name, err)
+			continue
+		}
+		w.a.V(2).M(p).F().Info("backup candidate label of pod %s/%s updated: %t", p.Namespace, p.Name, p.Name == candidate.GetName())
+	}
+}
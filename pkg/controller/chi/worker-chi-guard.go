@@ -0,0 +1,198 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// Reconciles of the same CHI never overlap, since all commands of the CHI share the same queue item handle
+// and queue does not hand out an item while the item with the same handle is in progress.
+// However, queue cancels context of the in-progress item as soon as a newer command of the same CHI is enqueued
+// and keeps the latest command only. Thus, what happens with the in-flight reconcile and with the edits made
+// meanwhile is driven by reconcile concurrency policy of the CHI instead:
+//  - queue - in-flight reconcile is completed, then each command from the backlog is reconciled in order
+//  - cancel - in-flight reconcile is interrupted between hosts, then the latest command is reconciled
+//  - coalesce - in-flight reconcile is completed, then the latest spec of the CHI is reconciled
+
+// ReconcileSupersededCtxKeyType specifies type for ReconcileSupersededCtxKey
+type ReconcileSupersededCtxKeyType string
+
+// ReconcileSupersededCtxKey specifies name of the key to be used for the context, which is done as soon as
+// the in-flight reconcile is superseded by a newer command of the same CHI
+const ReconcileSupersededCtxKey ReconcileSupersededCtxKeyType = "ReconcileSuperseded"
+
+// getReconcileConcurrencyPolicy gets reconcile concurrency policy of the CHI.
+// CHI value is used in case specified, operator's default otherwise
+func getReconcileConcurrencyPolicy(chi *api.ClickHouseInstallation) string {
+	if policy := chi.GetReconciling().GetConcurrencyPolicy(); api.IsReconcilingConcurrencyPolicyValid(policy) {
+		return policy
+	}
+	return chop.Config().Reconcile.Runtime.ConcurrencyPolicy
+}
+
+// isReconcileSuperseded checks whether the in-flight reconcile is superseded by a newer command of the same CHI
+// and has to be interrupted
+func isReconcileSuperseded(ctx context.Context) bool {
+	superseded, ok := ctx.Value(ReconcileSupersededCtxKey).(context.Context)
+	return ok && util.IsContextDone(superseded)
+}
+
+// processReconcileCHIGuarded reconciles add or update command of the CHI according to reconcile concurrency policy
+func (w *worker) processReconcileCHIGuarded(ctx context.Context, cmd *ReconcileCHI) error {
+	// Context of the queue item is cancelled by a newer command of the same CHI.
+	// Reconcile must not be interrupted at arbitrary point, so it runs in the context, which is not cancelled
+	// by a newer command, and is interrupted at safe points only, in case policy allows it.
+	// Still, the context is cancelled in case in-flight items are aborted on shutdown, so waits do not block forever
+	superseded := ctx
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(w.c.getAbortContext(), cancel)
+	defer stop()
+
+	policy := getReconcileConcurrencyPolicy(cmd.new)
+	w.a.V(2).M(cmd.new).F().Info("reconcile concurrency policy: %s", policy)
+
+	switch policy {
+	case api.ReconcilingConcurrencyPolicyQueue:
+		// Reconcile each command from the backlog in order, including the ones arrived meanwhile
+		var err error
+		for next := w.c.backlogPop(cmd); next != nil; next = w.c.backlogPop(cmd) {
+			if w.c.isShuttingDown() {
+				// Backlog is not persisted, the latest spec is reconciled by the next operator instance
+				return err
+			}
+			if e := w.updateCHI(ctx, next.old, next.new); e != nil {
+				err = e
+			}
+		}
+		return err
+
+	case api.ReconcilingConcurrencyPolicyCoalesce:
+		// All commands from the backlog are replaced with the latest spec
+		w.c.backlogDrop(cmd)
+		if w.c.isCoalesced(cmd) {
+			// Spec of the command has already been reconciled as the latest spec by the previous command
+			w.a.V(1).M(cmd.new).F().Info("skip superseded command of CHI %s/%s, resourceVersion %s is already reconciled",
+				cmd.new.Namespace, cmd.new.Name, cmd.new.ResourceVersion)
+			return nil
+		}
+		latest := w.getLatestCHI(cmd.new)
+		err := w.updateCHI(ctx, cmd.old, latest)
+		if err == nil {
+			w.c.rememberCoalesced(cmd, latest)
+		}
+		return err
+
+	default:
+		// Latest command replaces the ones in the backlog and interrupts in-flight reconcile
+		w.c.backlogDrop(cmd)
+		ctx = context.WithValue(ctx, ReconcileSupersededCtxKey, superseded)
+		return w.updateCHI(ctx, cmd.old, cmd.new)
+	}
+}
+
+// getLatestCHI gets the latest version of the CHI known to the operator, falls back to specified CHI
+func (w *worker) getLatestCHI(chi *api.ClickHouseInstallation) *api.ClickHouseInstallation {
+	latest, err := w.c.chiLister.ClickHouseInstallations(chi.Namespace).Get(chi.Name)
+	switch {
+	case apiErrors.IsNotFound(err):
+		// CHI is deleted, reconcile what is known
+		return chi
+	case err != nil:
+		log.V(1).M(chi).F().Warning("unable to get the latest CHI %s/%s err: %v", chi.Namespace, chi.Name, err)
+		return chi
+	case latest.ResourceVersion == chi.ResourceVersion:
+		return chi
+	}
+	w.a.V(1).M(chi).F().Info("coalesce edits of CHI %s/%s, resourceVersion %s is replaced with %s",
+		chi.Namespace, chi.Name, chi.ResourceVersion, latest.ResourceVersion)
+	// Lister object is shared with the informer, so it is normalized the same way as enqueued objects are
+	return normalizeCHIObject(latest)
+}
+
+// rememberCoalesced remembers resourceVersion of the CHI reconciled by the command as the latest spec,
+// so the commands superseded by this reconcile are skipped
+func (c *Controller) rememberCoalesced(cmd *ReconcileCHI, chi *api.ClickHouseInstallation) {
+	c.backlog.Lock()
+	defer c.backlog.Unlock()
+
+	if c.backlog.coalesced == nil {
+		c.backlog.coalesced = make(map[string]string)
+	}
+	c.backlog.coalesced[cmd.Handle().(string)] = chi.ResourceVersion
+}
+
+// isCoalesced checks whether spec of the command has already been reconciled as the latest spec by the previous command
+func (c *Controller) isCoalesced(cmd *ReconcileCHI) bool {
+	c.backlog.Lock()
+	defer c.backlog.Unlock()
+
+	resourceVersion, ok := c.backlog.coalesced[cmd.Handle().(string)]
+	return ok && (cmd.new != nil) && (resourceVersion == cmd.new.ResourceVersion)
+}
+
+// forgetCoalesced forgets resourceVersion of the CHI reconciled as the latest spec
+func (c *Controller) forgetCoalesced(cmd *ReconcileCHI) {
+	c.backlog.Lock()
+	defer c.backlog.Unlock()
+
+	delete(c.backlog.coalesced, cmd.Handle().(string))
+}
+
+// backlogPush appends command to the backlog of the CHI
+func (c *Controller) backlogPush(cmd *ReconcileCHI) {
+	c.backlog.Lock()
+	defer c.backlog.Unlock()
+
+	if c.backlog.commands == nil {
+		c.backlog.commands = make(map[string][]*ReconcileCHI)
+	}
+	handle := cmd.Handle().(string)
+	c.backlog.commands[handle] = append(c.backlog.commands[handle], cmd)
+}
+
+// backlogPop removes the earliest command from the backlog of the CHI. Returns nil in case backlog is empty
+func (c *Controller) backlogPop(cmd *ReconcileCHI) *ReconcileCHI {
+	c.backlog.Lock()
+	defer c.backlog.Unlock()
+
+	handle := cmd.Handle().(string)
+	commands := c.backlog.commands[handle]
+	if len(commands) == 0 {
+		return nil
+	}
+	if len(commands) == 1 {
+		delete(c.backlog.commands, handle)
+	} else {
+		c.backlog.commands[handle] = commands[1:]
+	}
+	return commands[0]
+}
+
+// backlogDrop removes all commands from the backlog of the CHI
+func (c *Controller) backlogDrop(cmd *ReconcileCHI) {
+	c.backlog.Lock()
+	defer c.backlog.Unlock()
+
+	delete(c.backlog.commands, cmd.Handle().(string))
+}
@@ -0,0 +1,110 @@
+package chi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	chopListers "github.com/altinity/clickhouse-operator/pkg/client/listers/clickhouse.altinity.com/v1"
+)
+
+func newGuardTestCHI(resourceVersion string) *api.ClickHouseInstallation {
+	return &api.ClickHouseInstallation{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       "ns",
+			Name:            "chi",
+			ResourceVersion: resourceVersion,
+		},
+	}
+}
+
+func newGuardTestWorker(objects ...*api.ClickHouseInstallation) *worker {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, obj := range objects {
+		_ = indexer.Add(obj)
+	}
+	c := &Controller{
+		chiLister: chopListers.NewClickHouseInstallationLister(indexer),
+	}
+	return &worker{
+		c: c,
+		a: NewAnnouncer().WithController(c),
+	}
+}
+
+func TestBacklog(t *testing.T) {
+	c := &Controller{}
+	cmd1 := NewReconcileCHI(reconcileUpdate, nil, newGuardTestCHI("1"))
+	cmd2 := NewReconcileCHI(reconcileUpdate, nil, newGuardTestCHI("2"))
+	cmd3 := NewReconcileCHI(reconcileUpdate, nil, newGuardTestCHI("3"))
+
+	c.backlogPush(cmd1)
+	c.backlogPush(cmd2)
+	require.Equal(t, cmd1, c.backlogPop(cmd3))
+	require.Equal(t, cmd2, c.backlogPop(cmd3))
+	require.Nil(t, c.backlogPop(cmd3))
+
+	c.backlogPush(cmd1)
+	c.backlogPush(cmd2)
+	c.backlogDrop(cmd3)
+	require.Nil(t, c.backlogPop(cmd3))
+}
+
+func TestCoalesced(t *testing.T) {
+	c := &Controller{}
+	cmd1 := NewReconcileCHI(reconcileUpdate, nil, newGuardTestCHI("1"))
+	cmd2 := NewReconcileCHI(reconcileUpdate, nil, newGuardTestCHI("2"))
+	require.False(t, c.isCoalesced(cmd1))
+
+	// The first command reconciled the latest spec, which is the spec of the second command
+	c.rememberCoalesced(cmd1, cmd2.new)
+	require.False(t, c.isCoalesced(cmd1))
+	require.True(t, c.isCoalesced(cmd2))
+
+	c.forgetCoalesced(cmd2)
+	require.False(t, c.isCoalesced(cmd2))
+}
+
+func TestGetLatestCHI(t *testing.T) {
+	chi := newGuardTestCHI("1")
+
+	// CHI is not known to the lister
+	w := newGuardTestWorker()
+	require.Same(t, chi, w.getLatestCHI(chi))
+
+	// CHI is up to date
+	w = newGuardTestWorker(newGuardTestCHI("1"))
+	require.Same(t, chi, w.getLatestCHI(chi))
+
+	// CHI has been edited meanwhile
+	edited := newGuardTestCHI("2")
+	w = newGuardTestWorker(edited)
+	latest := w.getLatestCHI(chi)
+	require.NotSame(t, edited, latest)
+	require.Equal(t, "2", latest.ResourceVersion)
+	require.Equal(t, api.SchemeGroupVersion.String(), latest.APIVersion)
+	require.Equal(t, api.ClickHouseInstallationCRDResourceKind, latest.Kind)
+}
+
+func TestIsReconcileSuperseded(t *testing.T) {
+	require.False(t, isReconcileSuperseded(context.Background()))
+
+	superseded, cancel := context.WithCancel(context.Background())
+	ctx := context.WithValue(context.Background(), ReconcileSupersededCtxKey, superseded)
+	require.False(t, isReconcileSuperseded(ctx))
+	cancel()
+	require.True(t, isReconcileSuperseded(ctx))
+}
+
+func TestAbortInFlight(t *testing.T) {
+	c := &Controller{}
+	ctx := c.getAbortContext()
+	require.NoError(t, ctx.Err())
+	c.abortInFlight()
+	require.Error(t, ctx.Err())
+	require.Error(t, c.getAbortContext().Err())
+}
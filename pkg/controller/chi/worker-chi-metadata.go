@@ -0,0 +1,346 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// chiProvidedMetadata is a set of labels and annotations of the CHI, which are propagated to child objects of the CHI
+type chiProvidedMetadata struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// newCHIProvidedMetadata gets labels and annotations propagated to child objects of the CHI
+func newCHIProvidedMetadata(chi *api.ClickHouseInstallation) *chiProvidedMetadata {
+	return &chiProvidedMetadata{
+		labels:      model.GetCHIProvidedLabels(chi),
+		annotations: model.GetCHIProvidedAnnotations(chi),
+	}
+}
+
+// equal checks whether metadata sets are equal
+func (m *chiProvidedMetadata) equal(other *chiProvidedMetadata) bool {
+	return reflect.DeepEqual(m.labels, other.labels) && reflect.DeepEqual(m.annotations, other.annotations)
+}
+
+// hasMacros checks whether any of values refers to macros, which are expanded differently for each child object
+func (m *chiProvidedMetadata) hasMacros() bool {
+	for _, values := range []map[string]string{m.labels, m.annotations} {
+		for _, value := range values {
+			if strings.Contains(value, "{") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isCHIProvidedMetadataChanged checks whether labels or annotations propagated to child objects of the CHI are changed
+func isCHIProvidedMetadataChanged(old, new *api.ClickHouseInstallation) bool {
+	if (old == nil) || (new == nil) {
+		return false
+	}
+	return !newCHIProvidedMetadata(old).equal(newCHIProvidedMetadata(new))
+}
+
+// isMetadataOnlyChange checks whether the CHI edit changes nothing but labels and annotations of child objects,
+// which can be applied directly to child objects w/o full reconcile of the hosts
+func (w *worker) isMetadataOnlyChange(old, new *api.ClickHouseInstallation) bool {
+	if !w.isGenerationTheSame(old, new) || !isCHIProvidedMetadataChanged(old, new) {
+		return false
+	}
+	// Macros have to be expanded in context of each child object, which is what full reconcile does
+	return !newCHIProvidedMetadata(new).hasMacros()
+}
+
+// reconcileCHIMetadata applies labels and annotations of the CHI to existing child objects of the CHI
+// w/o config regeneration and pod restarts. StatefulSets are left intact, since changing their pod templates
+// rolls pods, and StatefulSet's metadata is not updated apart from the pod template in order to keep them consistent.
+// Pods are updated directly instead. StatefulSets catch up with the next full reconcile
+func (w *worker) reconcileCHIMetadata(ctx context.Context, old, new *api.ClickHouseInstallation) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	prev := newCHIProvidedMetadata(old)
+	cur := newCHIProvidedMetadata(new)
+
+	w.a.V(1).
+		WithEvent(new, eventActionReconcile, eventReasonReconcileStarted).
+		WithStatusAction(new).
+		M(new).F().
+		Info("Update labels and annotations of child objects w/o reconcile of the hosts. CHI: %s/%s", new.Namespace, new.Name)
+
+	opts := controller.NewListOptions(model.NewLabeler(new).GetSelectorCHIScope())
+	updated := 0
+	updated += w.c.updatePodsMetadata(ctx, new, opts, prev, cur)
+	updated += w.c.updateConfigMapsMetadata(ctx, new, opts, prev, cur)
+	updated += w.c.updateServicesMetadata(ctx, new, opts, prev, cur)
+	updated += w.c.updatePVCsMetadata(ctx, new, opts, prev, cur)
+	updated += w.c.updatePDBsMetadata(ctx, new, opts, prev, cur)
+
+	w.a.V(1).
+		WithEvent(new, eventActionReconcile, eventReasonReconcileCompleted).
+		WithStatusAction(new).
+		M(new).F().
+		Info("Update labels and annotations of child objects completed. Objects updated: %d CHI: %s/%s", updated, new.Namespace, new.Name)
+	return nil
+}
+
+// applyCHIProvidedMetadata replaces previous CHI-provided labels and annotations of the object with current ones.
+// Returns whether the object is modified
+func applyCHIProvidedMetadata(objectMeta *meta.ObjectMeta, prev, cur *chiProvidedMetadata) bool {
+	labels := applyCHIProvidedMap(util.CopyMap(objectMeta.Labels), prev.labels, cur.labels)
+	annotations := applyCHIProvidedMap(util.CopyMap(objectMeta.Annotations), prev.annotations, cur.annotations)
+	if reflect.DeepEqual(labels, objectMeta.Labels) && reflect.DeepEqual(annotations, objectMeta.Annotations) {
+		return false
+	}
+	objectMeta.Labels = labels
+	objectMeta.Annotations = annotations
+	return true
+}
+
+// applyCHIProvidedMap deletes keys provided previously and not provided anymore and sets provided values
+func applyCHIProvidedMap(dst, prev, cur map[string]string) map[string]string {
+	for key := range prev {
+		if _, ok := cur[key]; !ok {
+			delete(dst, key)
+		}
+	}
+	if len(cur) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]string)
+	}
+	for key, value := range cur {
+		dst[key] = value
+	}
+	return dst
+}
+
+// updateObjectMetadata applies CHI-provided labels and annotations to the object fetched by get and stores the object by update.
+// Child objects are modified by other parties as well, thus object is re-fetched and update is retried on conflict.
+// Returns whether the object is updated
+func updateObjectMetadata(prev, cur *chiProvidedMetadata, get func() (*meta.ObjectMeta, error), update func() error) (bool, error) {
+	updated := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		updated = false
+		objectMeta, err := get()
+		if err != nil {
+			return err
+		}
+		if !applyCHIProvidedMetadata(objectMeta, prev, cur) {
+			return nil
+		}
+		if err := update(); err != nil {
+			return err
+		}
+		updated = true
+		return nil
+	})
+	return updated, err
+}
+
+// updatePodsMetadata updates labels and annotations of pods of the CHI
+func (c *Controller) updatePodsMetadata(ctx context.Context, chi *api.ClickHouseInstallation, opts meta.ListOptions, prev, cur *chiProvidedMetadata) int {
+	list, err := c.kubeClient.CoreV1().Pods(chi.Namespace).List(ctx, opts)
+	if err != nil {
+		log.M(chi).F().Error("FAIL list Pod err: %v", err)
+		return 0
+	}
+	updated := 0
+	for i := range list.Items {
+		namespace, name := list.Items[i].Namespace, list.Items[i].Name
+		var obj *core.Pod
+		ok, err := updateObjectMetadata(prev, cur,
+			func() (*meta.ObjectMeta, error) {
+				var err error
+				if obj, err = c.kubeClient.CoreV1().Pods(namespace).Get(ctx, name, controller.NewGetOptions()); err != nil {
+					return nil, err
+				}
+				return &obj.ObjectMeta, nil
+			},
+			func() error {
+				_, err := c.kubeClient.CoreV1().Pods(namespace).Update(ctx, obj, controller.NewUpdateOptions())
+				return err
+			},
+		)
+		if err != nil {
+			log.M(chi).F().Error("FAIL update metadata of Pod %s/%s err: %v", namespace, name, err)
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated
+}
+
+// updateConfigMapsMetadata updates labels and annotations of ConfigMaps of the CHI. Config files are not touched
+func (c *Controller) updateConfigMapsMetadata(ctx context.Context, chi *api.ClickHouseInstallation, opts meta.ListOptions, prev, cur *chiProvidedMetadata) int {
+	list, err := c.kubeClient.CoreV1().ConfigMaps(chi.Namespace).List(ctx, opts)
+	if err != nil {
+		log.M(chi).F().Error("FAIL list ConfigMap err: %v", err)
+		return 0
+	}
+	updated := 0
+	for i := range list.Items {
+		namespace, name := list.Items[i].Namespace, list.Items[i].Name
+		var obj *core.ConfigMap
+		ok, err := updateObjectMetadata(prev, cur,
+			func() (*meta.ObjectMeta, error) {
+				var err error
+				if obj, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, controller.NewGetOptions()); err != nil {
+					return nil, err
+				}
+				return &obj.ObjectMeta, nil
+			},
+			func() error {
+				_, err := c.kubeClient.CoreV1().ConfigMaps(namespace).Update(ctx, obj, controller.NewUpdateOptions())
+				return err
+			},
+		)
+		if err != nil {
+			log.M(chi).F().Error("FAIL update metadata of ConfigMap %s/%s err: %v", namespace, name, err)
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated
+}
+
+// updateServicesMetadata updates labels and annotations of Services of the CHI
+func (c *Controller) updateServicesMetadata(ctx context.Context, chi *api.ClickHouseInstallation, opts meta.ListOptions, prev, cur *chiProvidedMetadata) int {
+	list, err := c.kubeClient.CoreV1().Services(chi.Namespace).List(ctx, opts)
+	if err != nil {
+		log.M(chi).F().Error("FAIL list Service err: %v", err)
+		return 0
+	}
+	updated := 0
+	for i := range list.Items {
+		namespace, name := list.Items[i].Namespace, list.Items[i].Name
+		var obj *core.Service
+		ok, err := updateObjectMetadata(prev, cur,
+			func() (*meta.ObjectMeta, error) {
+				var err error
+				if obj, err = c.kubeClient.CoreV1().Services(namespace).Get(ctx, name, controller.NewGetOptions()); err != nil {
+					return nil, err
+				}
+				return &obj.ObjectMeta, nil
+			},
+			func() error {
+				_, err := c.kubeClient.CoreV1().Services(namespace).Update(ctx, obj, controller.NewUpdateOptions())
+				return err
+			},
+		)
+		if err != nil {
+			log.M(chi).F().Error("FAIL update metadata of Service %s/%s err: %v", namespace, name, err)
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated
+}
+
+// updatePVCsMetadata updates labels and annotations of PVCs of the CHI
+func (c *Controller) updatePVCsMetadata(ctx context.Context, chi *api.ClickHouseInstallation, opts meta.ListOptions, prev, cur *chiProvidedMetadata) int {
+	list, err := c.kubeClient.CoreV1().PersistentVolumeClaims(chi.Namespace).List(ctx, opts)
+	if err != nil {
+		log.M(chi).F().Error("FAIL list PVC err: %v", err)
+		return 0
+	}
+	updated := 0
+	for i := range list.Items {
+		namespace, name := list.Items[i].Namespace, list.Items[i].Name
+		var obj *core.PersistentVolumeClaim
+		ok, err := updateObjectMetadata(prev, cur,
+			func() (*meta.ObjectMeta, error) {
+				var err error
+				if obj, err = c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, controller.NewGetOptions()); err != nil {
+					return nil, err
+				}
+				return &obj.ObjectMeta, nil
+			},
+			func() error {
+				_, err := c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Update(ctx, obj, controller.NewUpdateOptions())
+				return err
+			},
+		)
+		if err != nil {
+			log.M(chi).F().Error("FAIL update metadata of PVC %s/%s err: %v", namespace, name, err)
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated
+}
+
+// updatePDBsMetadata updates labels and annotations of PDBs of the CHI
+func (c *Controller) updatePDBsMetadata(ctx context.Context, chi *api.ClickHouseInstallation, opts meta.ListOptions, prev, cur *chiProvidedMetadata) int {
+	list, err := c.kubeClient.PolicyV1().PodDisruptionBudgets(chi.Namespace).List(ctx, opts)
+	if err != nil {
+		log.M(chi).F().Error("FAIL list PDB err: %v", err)
+		return 0
+	}
+	updated := 0
+	for i := range list.Items {
+		namespace, name := list.Items[i].Namespace, list.Items[i].Name
+		var obj *policy.PodDisruptionBudget
+		ok, err := updateObjectMetadata(prev, cur,
+			func() (*meta.ObjectMeta, error) {
+				var err error
+				if obj, err = c.kubeClient.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, controller.NewGetOptions()); err != nil {
+					return nil, err
+				}
+				return &obj.ObjectMeta, nil
+			},
+			func() error {
+				_, err := c.kubeClient.PolicyV1().PodDisruptionBudgets(namespace).Update(ctx, obj, controller.NewUpdateOptions())
+				return err
+			},
+		)
+		if err != nil {
+			log.M(chi).F().Error("FAIL update metadata of PDB %s/%s err: %v", namespace, name, err)
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated
+}
@@ -19,6 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 	core "k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
@@ -36,6 +39,7 @@ import (
 	"github.com/altinity/clickhouse-operator/pkg/controller"
 	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
 	"github.com/altinity/clickhouse-operator/pkg/model/chi/creator"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
@@ -48,9 +52,23 @@ func (w *worker) reconcileCHI(ctx context.Context, old, new *api.ClickHouseInsta
 
 	w.logOldAndNew("non-normalized yet (native)", old, new)
 
+	if model.HasObjectsToRegenerate(new.ObjectMeta) && w.isGenerationTheSame(old, new) {
+		// Spec has no changes, only objects are requested to be re-rendered and re-applied
+		return w.regenerateCHI(ctx, new)
+	}
+
 	switch {
 	case w.isAfterFinalizerInstalled(old, new):
 		w.a.M(new).F().Info("isAfterFinalizerInstalled - continue reconcile-1")
+	case model.HasHostsToReplace(new.ObjectMeta):
+		w.a.M(new).F().Info("hasHostsToReplace - continue reconcile-1")
+	case model.IsRollingRestartRequested(new.ObjectMeta):
+		w.a.M(new).F().Info("isRollingRestartRequested - continue reconcile-1")
+	case w.isMetadataOnlyChange(old, new):
+		w.a.M(new).F().Info("isMetadataOnlyChange() - update metadata of child objects only, exit")
+		return w.reconcileCHIMetadata(ctx, old, new)
+	case w.isGenerationTheSame(old, new) && isCHIProvidedMetadataChanged(old, new):
+		w.a.M(new).F().Info("isCHIProvidedMetadataChanged() - metadata refers to macros - continue reconcile-1")
 	case w.isGenerationTheSame(old, new):
 		w.a.M(new).F().Info("isGenerationTheSame() - nothing to do here, exit")
 		return nil
@@ -73,10 +91,44 @@ func (w *worker) reconcileCHI(ctx context.Context, old, new *api.ClickHouseInsta
 	}
 
 	w.a.M(new).F().Info("Normalized OLD CHI: %s/%s", new.Namespace, new.Name)
-	old = w.normalize(old)
+	old, _ = w.normalize(old)
+
+	// Has to be checked before normalization, which fills status with the current operator version
+	verifyOnly := w.isVerifyOnlyReconcile(new)
 
 	w.a.M(new).F().Info("Normalized NEW CHI: %s/%s", new.Namespace, new.Name)
-	new = w.normalize(new)
+	new, err := w.normalize(new)
+	w.c.indexCHISecrets(new)
+	w.reconcileKeeperCondition(ctx, new)
+	switch {
+	case errors.Is(err, normalizer.ErrScaleInProtection):
+		// Layout deletes protected hosts, refuse to reconcile it
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+		return nil
+	case errors.Is(err, normalizer.ErrInvalidPodTemplate):
+		// Pods would fail, refuse to reconcile it
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+		return nil
+	case errors.Is(err, normalizer.ErrPortConflict):
+		// Pods would fail to listen on ports, refuse to reconcile it
+		w.a.WithEvent(new, eventActionReconcile, eventReasonPortConflict).
+			WithStatusAction(new).
+			WithStatusError(new).
+			M(new).F().
+			Error("Ports of hosts conflict, refuse to reconcile. CHI: %s/%s err: %v", new.Namespace, new.Name, err)
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+		return nil
+	}
+	if err := w.checkClickHouseVersionsDowngrade(new); err != nil {
+		// Older major version may be unable to read data, refuse to reconcile it
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+		return nil
+	}
+	if err := w.checkHostsCapacity(new); err != nil {
+		// StatefulSets would hang with pods pending forever, refuse to reconcile it
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+		return nil
+	}
 
 	new.SetAncestor(old)
 	w.logOldAndNew("normalized", old, new)
@@ -89,6 +141,12 @@ func (w *worker) reconcileCHI(ctx context.Context, old, new *api.ClickHouseInsta
 		w.a.M(new).F().Info("ActionPlan has actions - continue reconcile")
 	case w.isAfterFinalizerInstalled(old, new):
 		w.a.M(new).F().Info("isAfterFinalizerInstalled - continue reconcile-2")
+	case model.HasHostsToReplace(new.ObjectMeta):
+		w.a.M(new).F().Info("hasHostsToReplace - continue reconcile-2")
+	case model.IsRollingRestartRequested(new.ObjectMeta):
+		w.a.M(new).F().Info("isRollingRestartRequested - continue reconcile-2")
+	case w.isSecretsChanged(ctx, new):
+		w.a.M(new).F().Info("isSecretsChanged - continue reconcile-2")
 	default:
 		w.a.M(new).F().Info("ActionPlan has no actions and not finalizer - nothing to do")
 		return nil
@@ -100,11 +158,36 @@ func (w *worker) reconcileCHI(ctx context.Context, old, new *api.ClickHouseInsta
 	}
 
 	w.newTask(new)
+	if verifyOnly {
+		w.a.V(1).M(new).F().Info("CHI is reconciled by the new operator version and has no changes. Existing StatefulSets are verified only. CHI: %s/%s", new.Namespace, new.Name)
+		w.task.verifyOnly = true
+	}
+	if err := w.checkShardsRemoval(ctx, new, actionPlan); err != nil {
+		// Removed shards hold data, refuse to reconcile it
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+		return nil
+	}
+	w.acceptHostsReplacement(ctx, new)
 	w.markReconcileStart(ctx, new, actionPlan)
 	w.excludeStoppedCHIFromMonitoring(new)
 	w.walkHosts(ctx, new, actionPlan)
 
-	if err := w.reconcile(ctx, new); err != nil {
+	if err := w.reconcile(ctx, new); errors.Is(err, errShutdown) {
+		// Reconcile interrupted, persist progress. Reconcile is resumed by the next operator instance,
+		// since completed hosts are already up-to-date
+		w.a.WithEvent(new, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(new).
+			M(new).F().
+			Warning("reconcile interrupted by operator shutdown, to be continued after operator restart")
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+	} else if errors.Is(err, errReconcileSuperseded) {
+		// Reconcile interrupted in favour of a newer spec, which is reconciled next
+		w.a.WithEvent(new, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(new).
+			M(new).F().
+			Warning("reconcile interrupted by CHI edit, the latest spec is to be reconciled next")
+		w.markReconcileCompletedUnsuccessfully(ctx, new, err)
+	} else if err != nil {
 		// Something went wrong
 		w.a.WithEvent(new, eventActionReconcile, eventReasonReconcileFailed).
 			WithStatusError(new).
@@ -123,8 +206,11 @@ func (w *worker) reconcileCHI(ctx context.Context, old, new *api.ClickHouseInsta
 		}
 		w.clean(ctx, new)
 		w.dropReplicas(ctx, new, actionPlan)
+		w.applyImportedSchema(ctx, new)
 		w.addCHIToMonitoring(new)
 		w.waitForIPAddresses(ctx, new)
+		w.completeRollingRestart(ctx, new)
+		w.regenerateCHIObjects(ctx, new)
 		w.finalizeReconcileAndMarkCompleted(ctx, new)
 
 		metricsCHIReconcilesCompleted(ctx, new)
@@ -152,6 +238,8 @@ func (w *worker) reconcile(ctx context.Context, chi *api.ClickHouseInstallation)
 	w.a.V(2).M(chi).S().P()
 	defer w.a.V(2).M(chi).E().P()
 
+	w.startKeeperMigrations(ctx, chi)
+
 	counters := api.NewChiHostReconcileAttributesCounters()
 	chi.WalkHosts(func(host *api.ChiHost) error {
 		counters.Add(host.GetReconcileAttributes())
@@ -165,6 +253,23 @@ func (w *worker) reconcile(ctx context.Context, chi *api.ClickHouseInstallation)
 		ctx = context.WithValue(ctx, ReconcileShardsAndHostsOptionsCtxKey, &ReconcileShardsAndHostsOptions{
 			fullFanOut: true,
 		})
+	} else if w.isKeeperMigrationInProgress(chi) {
+		// Hosts switched to the new keeper have replicated tables read-only till metadata is restored,
+		// so hosts are migrated one by one
+		w.a.V(1).M(chi).Info(
+			"Keeper migration in progress. Enabling sequential mode. CHI: %s/%s",
+			chi.Namespace, chi.Name)
+		ctx = context.WithValue(ctx, ReconcileShardsAndHostsOptionsCtxKey, &ReconcileShardsAndHostsOptions{
+			sequential: true,
+		})
+	} else if model.IsRollingRestartRequested(chi.ObjectMeta) {
+		// Rolling restart has to keep all replicas but one available, so hosts are restarted one by one
+		w.a.V(1).M(chi).Info(
+			"Rolling restart requested. Enabling sequential mode. CHI: %s/%s",
+			chi.Namespace, chi.Name)
+		ctx = context.WithValue(ctx, ReconcileShardsAndHostsOptionsCtxKey, &ReconcileShardsAndHostsOptions{
+			sequential: true,
+		})
 	}
 
 	return chi.WalkTillError(
@@ -206,6 +311,10 @@ func (w *worker) reconcileCHIServicePreliminary(ctx context.Context, chi *api.Cl
 	if chi.IsStopped() {
 		// Stopped CHI must have no entry point
 		_ = w.c.deleteServiceCHI(ctx, chi)
+		for _, role := range []string{model.LabelReplicaRoleValueReadWrite, model.LabelReplicaRoleValueReadOnly} {
+			_ = w.c.deleteServiceIfExists(ctx, chi.Namespace, model.CreateCHIServiceReplicaRoleName(chi, role))
+		}
+		_ = w.c.deleteServiceIfExists(ctx, chi.Namespace, model.CreateCHIServiceGatewayName(chi))
 	}
 	return nil
 }
@@ -217,17 +326,31 @@ func (w *worker) reconcileCHIServiceFinal(ctx context.Context, chi *api.ClickHou
 		return nil
 	}
 
-	// Create entry point for the whole CHI
-	if service := w.task.creator.CreateServiceCHI(); service != nil {
-		if err := w.reconcileService(ctx, chi, service); err != nil {
+	// Create entry points for the whole CHI
+	var err error
+	for _, service := range w.task.creator.CreateServicesCHI() {
+		if e := w.reconcileService(ctx, chi, service); e != nil {
 			// Service not reconciled
 			w.task.registryFailed.RegisterService(service.ObjectMeta)
-			return err
+			if err == nil {
+				err = e
+			}
+			continue
 		}
 		w.task.registryReconciled.RegisterService(service.ObjectMeta)
 	}
+	if err != nil {
+		return err
+	}
 
-	return nil
+	// Prometheus Operator ServiceMonitor of the operator's metrics is shared by all CHIs
+	if ancestor := chi.GetAncestor(); chi.Spec.Monitoring.IsServiceMonitorEnabled() ||
+		((ancestor != nil) && ancestor.Spec.Monitoring.IsServiceMonitorEnabled()) {
+		w.reconcileOperatorServiceMonitor(ctx, chi, false)
+	}
+
+	// Create Gateway API routes for the whole CHI
+	return w.reconcileCHIGateway(ctx, chi)
 }
 
 // reconcileCHIAuxObjectsFinal reconciles CHI global objects
@@ -240,13 +363,79 @@ func (w *worker) reconcileCHIAuxObjectsFinal(ctx context.Context, chi *api.Click
 	w.a.V(2).M(chi).S().P()
 	defer w.a.V(2).M(chi).E().P()
 
+	// Hosts carrying new cluster secret are switched to it by remote_servers of the common ConfigMap
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		w.switchClusterSecretSlot(ctx, cluster)
+		return nil
+	})
+
 	// CHI ConfigMaps with update
 	chi.EnsureRuntime().LockCommonConfig()
 	err = w.reconcileCHIConfigMapCommon(ctx, chi, nil)
 	chi.EnsureRuntime().UnlockCommonConfig()
+
+	if (err == nil) && chi.Spec.Configuration.Functions.HasFunctions() {
+		// Make user-defined functions available without waiting for config reload
+		w.a.V(1).M(chi).F().Info("reload functions for CHI %s", chi.Name)
+		w.reloadFunctions(ctx, chi)
+	}
+
 	return err
 }
 
+// configMapPropagationTimeout specifies how long to wait for updated ConfigMap to reach pods.
+// kubelet propagates ConfigMap updates into mounted volumes with a delay of up to its sync period plus cache TTL.
+const configMapPropagationTimeout = 3 * time.Minute
+
+// reloadFunctions reloads user-defined functions on all hosts of the CHI.
+// Updated ConfigMap reaches pods with a delay, so reload is repeated till all functions are loaded by the host.
+// Marker function of the functions config is checked as well, so changed definitions of the same functions are waited for too
+func (w *worker) reloadFunctions(ctx context.Context, chi *api.ClickHouseInstallation) {
+	names := []string{model.CreateFunctionsMarkerName(chi.Spec.Configuration.Functions)}
+	for _, function := range chi.Spec.Configuration.Functions.GetFunctions() {
+		names = append(names, function.Name)
+	}
+
+	w.pollHostsTillConfigMapPropagated(ctx, chi, func(_ctx context.Context, host *api.ChiHost) bool {
+		loaded, err := w.ensureClusterSchemer(host).HostReloadFunctions(_ctx, host, names)
+		if err != nil {
+			w.a.V(1).M(host).F().Warning("unable to reload functions. Host: %s err: %v", host.GetName(), err)
+		}
+		return loaded
+	}, func(host *api.ChiHost, err error) {
+		w.a.V(1).M(host).F().Warning("functions are not loaded. Host: %s err: %v", host.GetName(), err)
+	})
+}
+
+// pollHostsTillConfigMapPropagated polls all hosts of the CHI concurrently till updated ConfigMap is picked up by each of them.
+// All hosts share the same deadline, since ConfigMap propagates into all pods at once,
+// so the CHI is not waited for longer than the propagation timeout regardless of the number of hosts
+func (w *worker) pollHostsTillConfigMapPropagated(
+	ctx context.Context,
+	chi *api.ClickHouseInstallation,
+	isDone func(ctx context.Context, host *api.ChiHost) bool,
+	failed func(host *api.ChiHost, err error),
+) {
+	ctx, cancel := context.WithTimeout(ctx, configMapPropagationTimeout)
+	defer cancel()
+
+	opts := controller.NewPollerOptions().FromConfig(chop.Config())
+	opts.Timeout = configMapPropagationTimeout
+
+	var wg sync.WaitGroup
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		wg.Add(1)
+		go func(host *api.ChiHost) {
+			defer wg.Done()
+			if err := w.c.pollHost(ctx, host, opts, isDone); err != nil {
+				failed(host, err)
+			}
+		}(host)
+		return nil
+	})
+	wg.Wait()
+}
+
 // reconcileCHIConfigMapCommon reconciles all CHI's common ConfigMap
 func (w *worker) reconcileCHIConfigMapCommon(
 	ctx context.Context,
@@ -262,13 +451,7 @@ func (w *worker) reconcileCHIConfigMapCommon(
 	// contains several sections, mapped as separated chopConfig files,
 	// such as remote servers, zookeeper setup, etc
 	configMapCommon := w.task.creator.CreateConfigMapCHICommon(options)
-	err := w.reconcileConfigMap(ctx, chi, configMapCommon)
-	if err == nil {
-		w.task.registryReconciled.RegisterConfigMap(configMapCommon.ObjectMeta)
-	} else {
-		w.task.registryFailed.RegisterConfigMap(configMapCommon.ObjectMeta)
-	}
-	return err
+	return w.reconcileConfigMap(ctx, chi, configMapCommon)
 }
 
 // reconcileCHIConfigMapUsers reconciles all CHI's users ConfigMap
@@ -281,13 +464,12 @@ func (w *worker) reconcileCHIConfigMapUsers(ctx context.Context, chi *api.ClickH
 
 	// ConfigMap common for all users resources in CHI
 	configMapUsers := w.task.creator.CreateConfigMapCHICommonUsers()
-	err := w.reconcileConfigMap(ctx, chi, configMapUsers)
-	if err == nil {
-		w.task.registryReconciled.RegisterConfigMap(configMapUsers.ObjectMeta)
-	} else {
-		w.task.registryFailed.RegisterConfigMap(configMapUsers.ObjectMeta)
+	if chi.IsUsersStoredInSecret() {
+		// Users config files contain password hashes and are delivered in Secret instead of ConfigMap.
+		// ConfigMap, which may be left from earlier reconciles, is not registered and thus is cleaned up
+		return w.reconcileConfigSecret(ctx, chi, w.task.creator.CreateConfigSecret(configMapUsers))
 	}
-	return err
+	return w.reconcileConfigMap(ctx, chi, configMapUsers)
 }
 
 // reconcileHostConfigMap reconciles host's personal ConfigMap
@@ -299,15 +481,7 @@ func (w *worker) reconcileHostConfigMap(ctx context.Context, host *api.ChiHost)
 
 	// ConfigMap for a host
 	configMap := w.task.creator.CreateConfigMapHost(host)
-	err := w.reconcileConfigMap(ctx, host.GetCHI(), configMap)
-	if err == nil {
-		w.task.registryReconciled.RegisterConfigMap(configMap.ObjectMeta)
-	} else {
-		w.task.registryFailed.RegisterConfigMap(configMap.ObjectMeta)
-		return err
-	}
-
-	return nil
+	return w.reconcileConfigMap(ctx, host.GetCHI(), configMap)
 }
 
 const unknownVersion = "failed to query"
@@ -410,6 +584,26 @@ func (w *worker) reconcileHostStatefulSet(ctx context.Context, host *api.ChiHost
 	host.Runtime.CurStatefulSet, _ = w.c.getStatefulSet(host, false)
 
 	w.a.V(1).M(host).F().Info("Reconcile host: %s. ClickHouse version: %s", host.GetName(), version)
+
+	if w.isVerifyOnlyHost(host) {
+		w.prepareHostStatefulSetWithStatus(ctx, host, false)
+		if host.GetReconcileAttributes().GetStatus() != api.ObjectStatusSame {
+			w.a.V(1).M(host).F().Warning(
+				"Reconcile host: %s. StatefulSet differs from the desired one, update skipped in verify-only pass after operator upgrade",
+				host.GetName())
+			w.dumpStatefulSetDiff(host, host.Runtime.CurStatefulSet, host.Runtime.DesiredStatefulSet)
+		}
+		// Existing StatefulSet is kept, so it should not be considered as an orphan
+		w.task.registryReconciled.RegisterStatefulSet(host.Runtime.DesiredStatefulSet.ObjectMeta)
+		host.GetCHI().EnsureStatus().HostUnchanged()
+		_ = w.c.updateCHIObjectStatus(ctx, host.GetCHI(), UpdateCHIStatusOptions{
+			CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+				MainFields: true,
+			},
+		})
+		return nil
+	}
+
 	// In case we have to force-restart host
 	// We'll do it via replicas: 0 in StatefulSet.
 	if w.shouldForceRestartHost(host) {
@@ -451,18 +645,20 @@ func (w *worker) reconcileHostService(ctx context.Context, host *api.ChiHost) er
 		log.V(2).Info("task is done")
 		return nil
 	}
-	service := w.task.creator.CreateServiceHost(host)
-	if service == nil {
-		// This is not a problem, service may be omitted
-		return nil
+	var err error
+	for _, service := range w.task.creator.CreateServicesHost(host) {
+		if e := w.reconcileService(ctx, host.GetCHI(), service); e != nil {
+			w.a.V(1).M(host).F().Warning("FAILED Reconcile service %s of the host: %s", service.Name, host.GetName())
+			w.task.registryFailed.RegisterService(service.ObjectMeta)
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		w.task.registryReconciled.RegisterService(service.ObjectMeta)
 	}
-	err := w.reconcileService(ctx, host.GetCHI(), service)
 	if err == nil {
-		w.a.V(1).M(host).F().Info("DONE Reconcile service of the host: %s", host.GetName())
-		w.task.registryReconciled.RegisterService(service.ObjectMeta)
-	} else {
-		w.a.V(1).M(host).F().Warning("FAILED Reconcile service of the host: %s", host.GetName())
-		w.task.registryFailed.RegisterService(service.ObjectMeta)
+		w.a.V(1).M(host).F().Info("DONE Reconcile services of the host: %s", host.GetName())
 	}
 	return err
 }
@@ -477,8 +673,8 @@ func (w *worker) reconcileCluster(ctx context.Context, cluster *api.Cluster) err
 	w.a.V(2).M(cluster).S().P()
 	defer w.a.V(2).M(cluster).E().P()
 
-	// Add ChkCluster's Service
-	if service := w.task.creator.CreateServiceCluster(cluster); service != nil {
+	// Add ChkCluster's Services
+	for _, service := range w.task.creator.CreateServicesCluster(cluster) {
 		if err := w.reconcileService(ctx, cluster.Runtime.CHI, service); err == nil {
 			w.task.registryReconciled.RegisterService(service.ObjectMeta)
 		} else {
@@ -497,23 +693,51 @@ func (w *worker) reconcileCluster(ctx context.Context, cluster *api.Cluster) err
 		}
 	}
 
-	pdb := w.task.creator.NewPodDisruptionBudget(cluster)
-	if err := w.reconcilePDB(ctx, cluster, pdb); err == nil {
-		w.task.registryReconciled.RegisterPDB(pdb.ObjectMeta)
-	} else {
-		w.task.registryFailed.RegisterPDB(pdb.ObjectMeta)
+	// Add ChkCluster's Secret carrying slots of the cluster secret passed to pods
+	switch cluster.Secret.Source() {
+	case api.ClusterSecretSourceSecretRef, api.ClusterSecretSourceAuto:
+		w.reconcileClusterSecretSlots(ctx, cluster)
+	}
+
+	// Add ChkCluster's TLS Secret
+	if cluster.IsSecureAuto() {
+		w.reconcileClusterTLSSecret(ctx, cluster)
 	}
 
+	w.reconcileClusterPDBs(ctx, cluster)
+
 	return nil
 }
 
-// getReconcileShardsWorkersNum calculates how many workers are allowed to be used for concurrent shard reconcile
+// reconcileClusterPDBs reconciles PodDisruptionBudget(s) of the cluster.
+// Depending on feature gate, either one cluster-wide PDB or one PDB per shard is reconciled.
+// PDBs of the other kind select the same pods, and eviction of a pod selected by more than one PDB is refused,
+// thus they are deleted before the desired ones are reconciled
+func (w *worker) reconcileClusterPDBs(ctx context.Context, cluster *api.Cluster) {
+	pdbs := w.task.creator.CreatePodDisruptionBudgets(cluster)
+	w.deleteClusterStalePDBs(ctx, cluster, pdbs)
+	for _, pdb := range pdbs {
+		if err := w.reconcilePDB(ctx, cluster, pdb); err == nil {
+			w.task.registryReconciled.RegisterPDB(pdb.ObjectMeta)
+		} else {
+			w.task.registryFailed.RegisterPDB(pdb.ObjectMeta)
+		}
+	}
+}
+
+// getReconcileShardsWorkersNum calculates how many workers are allowed to be used for concurrent shard reconcile.
+// Each worker reconciles hosts of one shard at a time, so this is the max number of hosts reconciled concurrently as well
 func (w *worker) getReconcileShardsWorkersNum(shards []*api.ChiShard, opts *ReconcileShardsAndHostsOptions) int {
-	availableWorkers := float64(chop.Config().Reconcile.Runtime.ReconcileShardsThreadsNumber)
+	availableWorkers := float64(getReconcileMaxConcurrentHosts(shards[0].Runtime.CHI))
 	maxConcurrencyPercent := float64(chop.Config().Reconcile.Runtime.ReconcileShardsMaxConcurrencyPercent)
 	_100Percent := float64(100)
 	shardsNum := float64(len(shards))
 
+	if opts.Sequential() {
+		// Shards are reconciled one by one
+		return 1
+	}
+
 	if opts.FullFanOut() {
 		// For full fan-out scenarios use all available workers.
 		// Always allow at least 1 worker.
@@ -526,9 +750,22 @@ func (w *worker) getReconcileShardsWorkersNum(shards []*api.ChiShard, opts *Reco
 	return int(math.Min(availableWorkers, maxAllowedWorkers))
 }
 
+// getReconcileMaxConcurrentHosts gets max number of hosts of different shards of the CHI reconciled concurrently.
+// CHI value takes priority over operator's value, number of shard threads is used in case neither is specified
+func getReconcileMaxConcurrentHosts(chi *api.ClickHouseInstallation) int {
+	if n := chi.GetReconciling().GetMaxConcurrentHosts(); n > 0 {
+		return n
+	}
+	if n := chop.Config().Reconcile.Runtime.MaxConcurrentHosts; n > 0 {
+		return n
+	}
+	return chop.Config().Reconcile.Runtime.ReconcileShardsThreadsNumber
+}
+
 // ReconcileShardsAndHostsOptions is and options for reconciler
 type ReconcileShardsAndHostsOptions struct {
 	fullFanOut bool
+	sequential bool
 }
 
 // FullFanOut gets value
@@ -539,6 +776,14 @@ func (o *ReconcileShardsAndHostsOptions) FullFanOut() bool {
 	return o.fullFanOut
 }
 
+// Sequential gets value
+func (o *ReconcileShardsAndHostsOptions) Sequential() bool {
+	if o == nil {
+		return false
+	}
+	return o.sequential
+}
+
 // reconcileShardsAndHosts reconciles shards and hosts of each shard
 func (w *worker) reconcileShardsAndHosts(ctx context.Context, shards []*api.ChiShard) error {
 	// Sanity check - CHI has to have shard(s)
@@ -576,40 +821,54 @@ func (w *worker) reconcileShardsAndHosts(ctx context.Context, shards []*api.ChiS
 	}
 
 	// Process shards using specified concurrency level while maintaining specified max concurrency percentage.
-	// Loop over shards.
+	// Each worker picks the next shard as soon as it is done with the previous one, so a slow shard does not hold the others.
+	// Hosts of each shard are reconciled one by one, so hosts of different shards are reconciled in parallel,
+	// while replicas of the same shard are serialized.
 	workersNum := w.getReconcileShardsWorkersNum(shards, opts)
 	w.a.V(1).Info("Starting rest of shards on workers: %d", workersNum)
-	for startShardIndex := startShard; startShardIndex < len(shards); startShardIndex += workersNum {
-		endShardIndex := startShardIndex + workersNum
-		if endShardIndex > len(shards) {
-			endShardIndex = len(shards)
-		}
-		concurrentlyProcessedShards := shards[startShardIndex:endShardIndex]
-
-		// Processing error protected with mutex
-		var err error
-		var errLock sync.Mutex
-
-		wg := sync.WaitGroup{}
-		wg.Add(len(concurrentlyProcessedShards))
-		// Launch shard concurrent processing
-		for j := range concurrentlyProcessedShards {
-			shard := concurrentlyProcessedShards[j]
-			go func() {
-				defer wg.Done()
+
+	queue := make(chan *api.ChiShard, len(shards)-startShard)
+	for _, shard := range shards[startShard:] {
+		queue <- shard
+	}
+	close(queue)
+
+	// Processing error protected with mutex
+	var err error
+	var errLock sync.Mutex
+	failed := func() bool {
+		errLock.Lock()
+		defer errLock.Unlock()
+		return err != nil
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(workersNum)
+	for i := 0; i < workersNum; i++ {
+		go func() {
+			defer wg.Done()
+			for shard := range queue {
+				if util.IsContextDone(ctx) {
+					log.V(2).Info("task is done")
+					return
+				}
+				if failed() {
+					// Shards in progress are completed, do not start the next ones
+					return
+				}
 				if e := w.reconcileShardWithHosts(ctx, shard); e != nil {
 					errLock.Lock()
 					err = e
 					errLock.Unlock()
 					return
 				}
-			}()
-		}
-		wg.Wait()
-		if err != nil {
-			w.a.V(1).Warning("Skipping rest of shards due to an error: %v", err)
-			return err
-		}
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		w.a.V(1).Warning("Skipping rest of shards due to an error: %v", err)
+		return err
 	}
 	return nil
 }
@@ -618,8 +877,29 @@ func (w *worker) reconcileShardWithHosts(ctx context.Context, shard *api.ChiShar
 	if err := w.reconcileShard(ctx, shard); err != nil {
 		return err
 	}
-	for replicaIndex := range shard.Hosts {
-		host := shard.Hosts[replicaIndex]
+
+	// Hosts having higher startup priority are reconciled first, so they are up and merging parts before the rest
+	groups := getShardHostsByStartupPriority(shard)
+
+	var hosts []*api.ChiHost
+	for _, group := range groups {
+		hosts = append(hosts, group...)
+	}
+	for _, host := range hosts {
+		if util.IsContextDone(ctx) {
+			log.V(2).Info("task is done")
+			return nil
+		}
+		if w.c.isShuttingDown() {
+			// Host(s) in progress are completed, do not start the next one
+			w.a.V(1).M(host).F().Warning("operator is shutting down, interrupt reconcile before host: %s", host.GetName())
+			return errShutdown
+		}
+		if isReconcileSuperseded(ctx) {
+			// Host(s) in progress are completed, do not start the next one, the latest spec is reconciled instead
+			w.a.V(1).M(host).F().Warning("CHI is edited, interrupt reconcile before host: %s", host.GetName())
+			return errReconcileSuperseded
+		}
 		if err := w.reconcileHost(ctx, host); err != nil {
 			return err
 		}
@@ -627,6 +907,27 @@ func (w *worker) reconcileShardWithHosts(ctx context.Context, shard *api.ChiShar
 	return nil
 }
 
+// getShardHostsByStartupPriority groups hosts of the shard by startup priority, higher priority goes first.
+// Hosts having the same priority keep their order within the shard
+func getShardHostsByStartupPriority(shard *api.ChiShard) [][]*api.ChiHost {
+	var priorities []int
+	groups := make(map[int][]*api.ChiHost)
+	for _, host := range shard.Hosts {
+		priority := host.GetStartupPriority()
+		if _, ok := groups[priority]; !ok {
+			priorities = append(priorities, priority)
+		}
+		groups[priority] = append(groups[priority], host)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
+	var result [][]*api.ChiHost
+	for _, priority := range priorities {
+		result = append(result, groups[priority])
+	}
+	return result
+}
+
 // reconcileShard reconciles specified shard, excluding nested replicas
 func (w *worker) reconcileShard(ctx context.Context, shard *api.ChiShard) error {
 	if util.IsContextDone(ctx) {
@@ -653,7 +954,7 @@ func (w *worker) reconcileShard(ctx context.Context, shard *api.ChiShard) error
 }
 
 // reconcileHost reconciles specified ClickHouse host
-func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
+func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) (err error) {
 	var (
 		reconcileHostStatefulSetOpts *reconcileHostStatefulSetOptions
 		migrateTableOpts             *migrateTableOptions
@@ -664,6 +965,20 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 		return nil
 	}
 
+	w.setHostStatus(ctx, host, api.HostPhaseReconciling, nil)
+	defer func() {
+		if err == nil {
+			return
+		}
+		if isHostReconcileFailure(err) {
+			// Host failed to be reconciled counts the same as the host failed health check
+			if tripped, failed := w.task.breaker.hostFailed(host.GetName()); tripped {
+				err = w.haltReconcile(ctx, host, failed)
+			}
+		}
+		w.setHostStatus(ctx, host, api.HostPhaseFailed, err)
+	}()
+
 	w.a.V(2).M(host).S().P()
 	defer w.a.V(2).M(host).E().P()
 
@@ -675,6 +990,10 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 		defer w.reconcileCHIServiceFinal(ctx, host.GetCHI())
 	}
 
+	if host.IsCordoned() {
+		return w.reconcileCordonedHost(ctx, host)
+	}
+
 	// Check whether ClickHouse is running and accessible and what version is available
 	if version, err := w.getHostClickHouseVersion(ctx, host, versionOptions{skipNew: true, skipStoppedAncestor: true}); err == nil {
 		w.a.V(1).
@@ -702,6 +1021,7 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 	}
 
 	_ = w.completeQueries(ctx, host)
+	w.waitHostPartsBacklog(ctx, host)
 
 	if err := w.reconcileHostConfigMap(ctx, host); err != nil {
 		metricsHostReconcilesErrors(ctx, host.GetCHI())
@@ -711,6 +1031,47 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 		return err
 	}
 
+	// Host restarts are limited across all CHIs, so many CHIs reconciled at once do not saturate shared storage or keeper.
+	// Slot is acquired before PVCs migration and host replacement, since both of them stop the host
+	releaseRestart, err := w.acquireHostRestart(ctx, host)
+	if err != nil {
+		w.a.V(1).
+			M(host).F().
+			Warning("Reconcile Host interrupted while waiting for concurrent restarts limit. Host: %s Err: %v", host.GetName(), err)
+		return err
+	}
+
+	replace := model.IsHostToReplace(host)
+	wiped := replace && model.IsHostReplaceWiped(host.GetCHI().ObjectMeta, host)
+	if replace && !wiped && (w.checkHostReplacement(ctx, host) != nil) {
+		// Host holds the only copy of data, it is reconciled as usual
+		replace = false
+	}
+	switch {
+	case wiped:
+		// Host is wiped out already by the interrupted replacement, tables are to be created once again
+		migrateTableOpts = &migrateTableOptions{
+			forceMigrate: true,
+		}
+	case replace:
+		// Host is recreated from scratch keeping its identity, data is re-fetched from other replicas:
+		// 1. recreate StatefulSet with new storage
+		// 2. run tables migration again
+		w.replaceHost(ctx, host)
+		reconcileHostStatefulSetOpts = &reconcileHostStatefulSetOptions{
+			forceRecreate: true,
+		}
+		migrateTableOpts = &migrateTableOptions{
+			forceMigrate: true,
+			dropReplica:  true,
+		}
+	default:
+		// Migrate data to the new storage in case PVCs can not be updated in-place
+		w.migrateHostPVCs(ctx, host)
+		// Expand PVCs in-place in case storage requested by VolumeClaimTemplates grows
+		w.resizeHostPVCs(ctx, host)
+	}
+
 	w.a.V(1).
 		M(host).F().
 		Info("Reconcile PVCs and check possible data loss for host: %s", host.GetName())
@@ -730,7 +1091,27 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 			Info("Data loss detected for host: %s. Will do force migrate", host.GetName())
 	}
 
-	if err := w.reconcileHostStatefulSet(ctx, host, reconcileHostStatefulSetOpts); err != nil {
+	// Host is about to be restarted by the operator, PDB should not count it against external disruptions
+	if w.shouldSuspendHostPDB(host) {
+		w.suspendHostPDB(ctx, host)
+		defer w.restoreHostPDB(host)
+	}
+
+	// Shorten shutdown of the host by stopping background merges and fetches in advance
+	preRestartActionsPerformed := w.runHostPreRestartActions(ctx, host)
+	revertPreRestartActions := func() {
+		if preRestartActionsPerformed {
+			preRestartActionsPerformed = false
+			w.revertHostPreRestartActions(host)
+		}
+	}
+	// Merges and fetches are started back even in case the reconcile is interrupted
+	defer revertPreRestartActions()
+
+	err = w.reconcileHostStatefulSet(ctx, host, reconcileHostStatefulSetOpts)
+	releaseRestart()
+	if err != nil {
+		revertPreRestartActions()
 		metricsHostReconcilesErrors(ctx, host.GetCHI())
 		w.a.V(1).
 			M(host).F().
@@ -756,7 +1137,30 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 			M(host).F().
 			Warning("Check host for ClickHouse availability before migrating tables. Host: %s Failed to get ClickHouse version: %s", host.GetName(), version)
 	}
+	revertPreRestartActions()
+	w.reloadHostSecrets(ctx, host)
+	if err := w.migrateHostToKeeper(ctx, host); err != nil {
+		metricsHostReconcilesErrors(ctx, host.GetCHI())
+		w.a.V(1).
+			M(host).F().
+			Warning("Reconcile Host interrupted with an error 6. Host: %s Err: %v", host.GetName(), err)
+		return err
+	}
 	_ = w.migrateTables(ctx, host, migrateTableOpts)
+	if replace {
+		w.syncReplacedHost(ctx, host)
+	}
+	if err := w.waitRollingRestartedHostCaughtUp(ctx, host); err != nil {
+		// The next host is not to be restarted while replicas lag behind
+		metricsHostReconcilesErrors(ctx, host.GetCHI())
+		w.a.V(1).
+			M(host).F().
+			Warning("Reconcile Host interrupted with an error 5. Host: %s Err: %v", host.GetName(), err)
+		return err
+	}
+
+	// Unreachable ports are reported in status, reconcile proceeds anyway
+	_ = w.probeHostPorts(ctx, host)
 
 	if err := w.includeHost(ctx, host); err != nil {
 		metricsHostReconcilesErrors(ctx, host.GetCHI())
@@ -774,18 +1178,35 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 			WithStatusAction(host.GetCHI()).
 			M(host).F().
 			Info("Reconcile Host completed. Host: %s ClickHouse version running: %s", host.GetName(), version)
+		w.task.breaker.hostSucceeded()
 	} else {
 		w.a.V(1).
 			WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileCompleted).
 			WithStatusAction(host.GetCHI()).
 			M(host).F().
 			Warning("Reconcile Host completed. Host: %s Failed to get ClickHouse version: %s", host.GetName(), version)
+		if tripped, failed := w.task.breaker.hostFailed(host.GetName()); tripped {
+			metricsHostReconcilesErrors(ctx, host.GetCHI())
+			return w.haltReconcile(ctx, host, failed)
+		}
 	}
 
 	now := time.Now()
 	hostsCompleted := 0
 	hostsCount := 0
 	host.GetCHI().EnsureStatus().HostCompleted()
+	hostStatus := api.NewChiHostStatus(host)
+	hostStatus.Phase = api.HostPhaseReady
+	hostStatus.Revision = w.getHostStatefulSetRevision(host)
+	hostStatus.Time = now.Format(time.RFC3339)
+	if !host.Runtime.Version.IsUnknown() {
+		hostStatus.Version = host.Runtime.Version.String()
+	}
+	if prev := host.GetCHI().EnsureStatus().GetHostStatus(host); prev != nil {
+		// Conditions of the host are maintained on their own
+		hostStatus.Conditions = prev.Conditions
+	}
+	host.GetCHI().EnsureStatus().SetHostStatus(hostStatus)
 	if host.GetCHI() != nil && host.GetCHI().Status != nil {
 		hostsCompleted = host.GetCHI().Status.GetHostsCompletedCount()
 		hostsCount = host.GetCHI().Status.GetHostsCount()
@@ -808,6 +1229,65 @@ func (w *worker) reconcileHost(ctx context.Context, host *api.ChiHost) error {
 	return nil
 }
 
+// reconcileCordonedHost freezes host of the cordoned cluster.
+// Host is excluded from CHI-level Service, while its k8s objects are kept as they are and are not touched by reconcile.
+func (w *worker) reconcileCordonedHost(ctx context.Context, host *api.ChiHost) error {
+	w.a.V(1).
+		M(host).F().
+		Info("Cluster %s is cordoned, host is excluded from service and is not reconciled. Host: %s", host.Runtime.Address.ClusterName, host.GetName())
+
+	_ = w.excludeHostFromService(ctx, host)
+
+	// Objects of the host are registered as reconciled, so they are not deleted as abandoned ones
+	namespace := host.Runtime.Address.Namespace
+	w.task.registryReconciled.RegisterStatefulSet(meta.ObjectMeta{Namespace: namespace, Name: model.CreateStatefulSetName(host)})
+	w.task.registryReconciled.RegisterService(meta.ObjectMeta{Namespace: namespace, Name: model.CreateStatefulSetServiceName(host)})
+	if host.GetCHI().Spec.Defaults.IsConfigStoredInSecret() {
+		w.task.registryReconciled.RegisterSecret(meta.ObjectMeta{Namespace: namespace, Name: model.CreateConfigMapHostName(host)})
+	} else {
+		w.task.registryReconciled.RegisterConfigMap(meta.ObjectMeta{Namespace: namespace, Name: model.CreateConfigMapHostName(host)})
+	}
+	w.c.walkDiscoveredPVCs(host, func(pvc *core.PersistentVolumeClaim) {
+		w.task.registryReconciled.RegisterPVC(pvc.ObjectMeta)
+	})
+
+	host.GetCHI().EnsureStatus().HostUnchanged()
+	w.setHostStatus(ctx, host, api.HostPhaseCordoned, nil)
+	return nil
+}
+
+// setHostStatus sets reconcile phase of the host in CHI status.
+// Revision applied by the previous reconcile of the host is kept.
+func (w *worker) setHostStatus(ctx context.Context, host *api.ChiHost, phase string, err error) {
+	status := api.NewChiHostStatus(host)
+	if prev := host.GetCHI().EnsureStatus().GetHostStatus(host); prev != nil {
+		status = *prev
+	}
+	status.Phase = phase
+	status.Time = time.Now().Format(time.RFC3339)
+	if err != nil {
+		status.Error = err.Error()
+	}
+	host.GetCHI().EnsureStatus().SetHostStatus(status)
+	_ = w.c.updateCHIObjectStatus(ctx, host.GetCHI(), UpdateCHIStatusOptions{
+		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+			MainFields: true,
+		},
+	})
+}
+
+// getHostStatefulSetRevision gets revision of the host's StatefulSet, empty in case StatefulSet is not available
+func (w *worker) getHostStatefulSetRevision(host *api.ChiHost) string {
+	statefulSet, err := w.c.getStatefulSetByHost(host)
+	if err != nil {
+		return ""
+	}
+	if statefulSet.Status.UpdateRevision != "" {
+		return statefulSet.Status.UpdateRevision
+	}
+	return statefulSet.Status.CurrentRevision
+}
+
 // reconcilePDB reconciles PodDisruptionBudget
 func (w *worker) reconcilePDB(ctx context.Context, cluster *api.Cluster, pdb *policy.PodDisruptionBudget) error {
 	cur, err := w.c.kubeClient.PolicyV1().PodDisruptionBudgets(pdb.Namespace).Get(ctx, pdb.Name, controller.NewGetOptions())
@@ -851,10 +1331,22 @@ func (w *worker) reconcileConfigMap(
 	w.a.V(2).M(chi).S().P()
 	defer w.a.V(2).M(chi).E().P()
 
+	if chi.Spec.Defaults.IsConfigStoredInSecret() {
+		// Config files are delivered in Secret instead of ConfigMap.
+		// ConfigMap, which may be left from earlier reconciles, is not registered and thus is cleaned up
+		return w.reconcileConfigSecret(ctx, chi, w.task.creator.CreateConfigSecret(configMap))
+	}
+
 	// Check whether this object already exists in k8s
 	curConfigMap, err := w.c.getConfigMap(&configMap.ObjectMeta, true)
 
 	if curConfigMap != nil {
+		if model.IsConfigDrifted(curConfigMap) {
+			w.a.V(1).
+				WithEvent(chi, eventActionReconcile, eventReasonConfigDrift).
+				M(chi).F().
+				Warning("ConfigMap %s/%s is edited out-of-band, restore generated content", configMap.Namespace, configMap.Name)
+		}
 		// We have ConfigMap - try to update it
 		err = w.updateConfigMap(ctx, chi, configMap)
 	}
@@ -870,6 +1362,47 @@ func (w *worker) reconcileConfigMap(
 			WithStatusError(chi).
 			M(chi).F().
 			Error("FAILED to reconcile ConfigMap: %s CHI: %s ", configMap.Name, chi.Name)
+		w.task.registryFailed.RegisterConfigMap(configMap.ObjectMeta)
+	} else {
+		w.task.registryReconciled.RegisterConfigMap(configMap.ObjectMeta)
+	}
+
+	return err
+}
+
+// reconcileConfigSecret reconciles core.Secret carrying config files
+func (w *worker) reconcileConfigSecret(
+	ctx context.Context,
+	chi *api.ClickHouseInstallation,
+	secret *core.Secret,
+) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	// Check whether this object already exists in k8s
+	curSecret, err := w.c.getSecret(secret)
+
+	if curSecret != nil {
+		// We have Secret - try to update it
+		err = w.updateSecret(ctx, chi, secret)
+	}
+
+	if apiErrors.IsNotFound(err) {
+		// Secret not found - even during Update process - try to create it
+		err = w.createSecret(ctx, chi, secret)
+	}
+
+	if err != nil {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusAction(chi).
+			WithStatusError(chi).
+			M(chi).F().
+			Error("FAILED to reconcile config Secret: %s CHI: %s ", secret.Name, chi.Name)
+		w.task.registryFailed.RegisterSecret(secret.ObjectMeta)
+	} else {
+		w.task.registryReconciled.RegisterSecret(secret.ObjectMeta)
 	}
 
 	return err
@@ -907,6 +1440,7 @@ func (w *worker) reconcileService(ctx context.Context, chi *api.ClickHouseInstal
 			w.a.V(1).M(chi).F().Info("Service: %s/%s not found. err: %v", service.Namespace, service.Name, err)
 		} else {
 			// The Service is either not found or not updated. Try to recreate it
+			metricsServiceUpdateErrors(ctx, chi)
 			w.a.WithEvent(chi, eventActionUpdate, eventReasonUpdateFailed).
 				WithStatusAction(chi).
 				WithStatusError(chi).
@@ -961,6 +1495,59 @@ func (w *worker) reconcileSecret(ctx context.Context, chi *api.ClickHouseInstall
 	return err
 }
 
+// reconcileClusterTLSSecret reconciles Secret carrying auto-generated TLS certificates of the cluster.
+// Certificates are re-issued only when needed, so the Secret is updated only in case hosts set has changed
+func (w *worker) reconcileClusterTLSSecret(ctx context.Context, cluster *api.Cluster) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	chi := cluster.Runtime.CHI
+	name := model.CreateClusterTLSSecretName(cluster)
+	cur, err := w.c.kubeClient.CoreV1().Secrets(chi.Namespace).Get(ctx, name, controller.NewGetOptions())
+	switch {
+	case apiErrors.IsNotFound(err):
+		// Secret is not created yet, certificates are to be generated from scratch
+		cur = nil
+	case err != nil:
+		// Secret may exist, so certificates can not be generated from scratch, since peers would not trust them
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusAction(chi).
+			WithStatusError(chi).
+			M(cluster).F().
+			Error("FAILED to get TLS Secret of the cluster: %s CHI: %s err: %v", cluster.Name, chi.Name, err)
+		w.task.registryFailed.RegisterSecret(meta.ObjectMeta{Namespace: chi.Namespace, Name: name})
+		return
+	}
+
+	secret, err := w.task.creator.CreateClusterTLSSecret(cluster, cur)
+	switch {
+	case err != nil:
+		// Nothing to reconcile
+	case cur == nil:
+		err = w.createSecret(ctx, chi, secret)
+	case !reflect.DeepEqual(cur.Data, secret.Data):
+		secret.ResourceVersion = cur.ResourceVersion
+		err = w.updateSecret(ctx, chi, secret)
+	}
+
+	if err != nil {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusAction(chi).
+			WithStatusError(chi).
+			M(cluster).F().
+			Error("FAILED to reconcile TLS Secret of the cluster: %s CHI: %s err: %v", cluster.Name, chi.Name, err)
+		if secret != nil {
+			w.task.registryFailed.RegisterSecret(secret.ObjectMeta)
+		}
+		return
+	}
+	w.task.registryReconciled.RegisterSecret(secret.ObjectMeta)
+	// Hosts are rolled in case trusted CAs have changed, since ClickHouse loads them on start only
+	cluster.Runtime.TLSCAChecksum = model.GetTLSCAChecksum(secret)
+}
+
 func (w *worker) dumpStatefulSetDiff(host *api.ChiHost, cur, new *apps.StatefulSet) {
 	if cur == nil {
 		w.a.V(1).M(host).Info("Cur StatefulSet is not available, nothing to compare to")
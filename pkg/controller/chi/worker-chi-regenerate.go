@@ -0,0 +1,139 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"strings"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// regenerateCHI re-renders and re-applies objects of the CHI requested by annotation w/o full reconcile of the hosts.
+// This is meant to repair objects damaged manually, such as a ConfigMap, while CHI spec has no changes
+func (w *worker) regenerateCHI(ctx context.Context, chi *api.ClickHouseInstallation) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	var ancestor *api.ClickHouseInstallation
+	if chi.HasAncestor() {
+		ancestor, _ = w.normalize(chi.GetAncestor())
+	}
+	chi, err := w.normalize(chi)
+	if err != nil {
+		// Objects can not be rendered out of invalid CHI
+		return nil
+	}
+	chi.SetAncestor(ancestor)
+
+	w.newTask(chi)
+	w.regenerateCHIObjects(ctx, chi)
+	return nil
+}
+
+// regenerateCHIObjects re-renders and re-applies classes of objects requested by annotation
+// and removes the annotation afterwards
+func (w *worker) regenerateCHIObjects(ctx context.Context, chi *api.ClickHouseInstallation) {
+	classes := model.GetObjectsToRegenerate(chi.ObjectMeta)
+	if len(classes) == 0 {
+		return
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileStarted).
+		WithStatusAction(chi).
+		M(chi).F().
+		Info("Regenerate objects: %s", strings.Join(classes, ","))
+
+	for _, class := range classes {
+		if util.IsContextDone(ctx) {
+			log.V(2).Info("task is done")
+			return
+		}
+		switch class {
+		case model.AnnotationRegenerateConfigMaps:
+			w.regenerateConfigMaps(ctx, chi)
+		case model.AnnotationRegenerateServices:
+			w.regenerateServices(ctx, chi)
+		case model.AnnotationRegenerateStatefulSets:
+			w.regenerateStatefulSets(ctx, chi)
+		}
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileCompleted).
+		WithStatusAction(chi).
+		M(chi).F().
+		Info("Regenerate objects completed: %s", strings.Join(classes, ","))
+	_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationRegenerate)
+}
+
+// regenerateConfigMaps re-applies common, users and hosts ConfigMaps of the CHI.
+// Hosts pick up updated config files on their own, w/o restart
+func (w *worker) regenerateConfigMaps(ctx context.Context, chi *api.ClickHouseInstallation) {
+	chi.EnsureRuntime().LockCommonConfig()
+	_ = w.reconcileCHIConfigMapCommon(ctx, chi, nil)
+	chi.EnsureRuntime().UnlockCommonConfig()
+	_ = w.reconcileCHIConfigMapUsers(ctx, chi)
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		_ = w.reconcileHostConfigMap(ctx, host)
+		return nil
+	})
+}
+
+// regenerateServices re-applies CHI, clusters, shards and hosts Services of the CHI
+func (w *worker) regenerateServices(ctx context.Context, chi *api.ClickHouseInstallation) {
+	_ = w.reconcileCHIServiceFinal(ctx, chi)
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		if service := w.task.creator.CreateServiceCluster(cluster); service != nil {
+			_ = w.reconcileService(ctx, chi, service)
+		}
+		for _, service := range w.task.creator.CreateServiceClusterExtra(cluster) {
+			_ = w.reconcileService(ctx, chi, service)
+		}
+		return nil
+	})
+	chi.WalkShards(func(shard *api.ChiShard) error {
+		_ = w.reconcileShard(ctx, shard)
+		return nil
+	})
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		_ = w.reconcileHostService(ctx, host)
+		return nil
+	})
+}
+
+// regenerateStatefulSets re-applies StatefulSets of the CHI host by host.
+// StatefulSets are re-applied even in case they are labeled as up-to-date, since they may be damaged manually
+func (w *worker) regenerateStatefulSets(ctx context.Context, chi *api.ClickHouseInstallation) {
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		if util.IsContextDone(ctx) {
+			return nil
+		}
+		w.prepareHostStatefulSetWithStatus(ctx, host, false)
+		if host.GetReconcileAttributes().GetStatus() == api.ObjectStatusSame {
+			host.GetReconcileAttributes().SetStatus(api.ObjectStatusModified)
+		}
+		if err := w.reconcileStatefulSet(ctx, host, false); err != nil {
+			w.a.V(1).M(host).F().Warning("FAILED to regenerate StatefulSet of the host: %s err: %v", host.GetName(), err)
+		}
+		return nil
+	})
+}
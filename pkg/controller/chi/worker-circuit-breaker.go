@@ -0,0 +1,113 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// Reasons of Degraded condition
+const (
+	degradedReasonHostsFailed = "HostsFailed"
+)
+
+// circuitBreaker tracks hosts failing to be reconciled or failing health check after being reconciled.
+// Breaker trips in case threshold number of hosts in a row fail, so reconcile of the CHI is halted
+// instead of proceeding to break the remaining replicas. Zero threshold means breaker never trips.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	// failed lists hosts failed in a row
+	failed []string
+}
+
+// newCircuitBreaker creates new circuit breaker
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+	}
+}
+
+// hostSucceeded resets hosts failed in a row
+func (b *circuitBreaker) hostSucceeded() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failed = nil
+}
+
+// hostFailed registers failed host and checks whether the breaker has tripped
+func (b *circuitBreaker) hostFailed(host string) (tripped bool, failed []string) {
+	if b == nil {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failed = append(b.failed, host)
+	if (b.threshold <= 0) || (len(b.failed) < b.threshold) {
+		return false, nil
+	}
+	return true, append([]string{}, b.failed...)
+}
+
+// isHostReconcileFailure checks whether error returned by host reconcile means the host has failed.
+// Reconcile interrupted by the operator itself, such as on shutdown or CHI edit, does not count
+func isHostReconcileFailure(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errShutdown),
+		errors.Is(err, errReconcileSuperseded),
+		errors.Is(err, errReconcileHalted),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
+
+// haltReconcile sets Degraded condition of the CHI as reconcile is halted due to hosts failed in a row
+func (w *worker) haltReconcile(ctx context.Context, host *api.ChiHost, failed []string) error {
+	chi := host.GetCHI()
+	err := fmt.Errorf("%w: %d hosts in a row failed reconcile or health check: %s", errReconcileHalted, len(failed), strings.Join(failed, ", "))
+	chi.EnsureStatus().SetCondition(meta.Condition{
+		Type:               api.ConditionTypeDegraded,
+		Status:             meta.ConditionTrue,
+		Reason:             degradedReasonHostsFailed,
+		Message:            err.Error(),
+		ObservedGeneration: chi.Generation,
+	})
+	_ = w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
+		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+			MainFields: true,
+		},
+	})
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+		WithStatusError(chi).
+		M(host).F().
+		Error("Reconcile halted. %v", err)
+	return err
+}
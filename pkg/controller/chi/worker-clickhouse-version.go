@@ -0,0 +1,79 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// checkClickHouseVersionsDowngrade ensures ClickHouse versions clusters are pinned to are not downgraded
+// across major versions, since data and metadata written by a newer major version may be unreadable by an older one.
+// Pinned version is compared to versions running hosts are reported with, so the check does not depend on
+// how the running version has been specified. Downgrades within the same major version are allowed
+func (w *worker) checkClickHouseVersionsDowngrade(chi *api.ClickHouseInstallation) error {
+	var downgrades []string
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		major, ok := cluster.GetClickHouseMajorVersion()
+		if !ok {
+			// Cluster is not pinned to any version
+			return nil
+		}
+		cluster.WalkHosts(func(host *api.ChiHost) error {
+			running := getHostRunningClickHouseVersion(host)
+			if runningMajor, ok := getClickHouseMajorVersion(running); ok && (major < runningMajor) {
+				downgrades = append(downgrades, fmt.Sprintf("%s/%s: %s -> %s",
+					cluster.Name, host.GetName(), running, cluster.GetClickHouseVersion()))
+			}
+			return nil
+		})
+		return nil
+	})
+
+	if len(downgrades) == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%w: %s", errClickHouseVersionDowngrade, strings.Join(downgrades, ", "))
+	w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+		WithStatusError(chi).
+		M(chi).F().
+		Error("refuse to downgrade ClickHouse: %v", err)
+	return err
+}
+
+// getHostRunningClickHouseVersion gets version of ClickHouse the host is running.
+// Version is fetched from the host during reconcile, otherwise version reported by the last reconcile is used
+func getHostRunningClickHouseVersion(host *api.ChiHost) string {
+	if !host.Runtime.Version.IsUnknown() {
+		return host.Runtime.Version.String()
+	}
+	if status := host.GetCHI().EnsureStatus().GetHostStatus(host); status != nil {
+		return status.Version
+	}
+	return ""
+}
+
+// getClickHouseMajorVersion gets major part of ClickHouse version, such as 23 of 23.8.8.21.altinitystable
+func getClickHouseMajorVersion(version string) (int, bool) {
+	major, _, _ := strings.Cut(version, ".")
+	if v, err := strconv.Atoi(major); err == nil {
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/k8s"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// getClusterSecretValue gets value of the cluster secret out of the Secret it is referenced by or auto-generated into
+func (w *worker) getClusterSecretValue(ctx context.Context, cluster *api.Cluster) ([]byte, error) {
+	var ref *core.SecretKeySelector
+	switch cluster.Secret.Source() {
+	case api.ClusterSecretSourceSecretRef:
+		ref = cluster.Secret.GetSecretKeyRef()
+	case api.ClusterSecretSourceAuto:
+		ref = cluster.Secret.GetAutoSecretKeyRef(model.CreateClusterAutoSecretName(cluster))
+	default:
+		return nil, fmt.Errorf("cluster secret is not delivered via Secret")
+	}
+
+	namespace := cluster.Runtime.CHI.Namespace
+	secret, err := w.c.kubeClient.CoreV1().Secrets(namespace).Get(ctx, ref.Name, controller.NewGetOptions())
+	if err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, fmt.Errorf("no key %s in Secret %s/%s", ref.Key, namespace, ref.Name)
+	}
+	return value, nil
+}
+
+// reconcileClusterSecretSlots reconciles Secret carrying slots of the cluster secret, which are passed to pods via ENV vars.
+// Changed cluster secret is written into the inactive slot, so hosts rolled by the reconcile carry both secrets,
+// while still using the active one. Hosts are switched to the other slot by switchClusterSecretSlot afterwards
+func (w *worker) reconcileClusterSecretSlots(ctx context.Context, cluster *api.Cluster) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	chi := cluster.Runtime.CHI
+	name := model.CreateClusterSecretSlotsName(cluster)
+	failed := func(err error) {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusAction(chi).
+			WithStatusError(chi).
+			M(cluster).F().
+			Error("FAILED to reconcile cluster secret slots of the cluster: %s CHI: %s err: %v", cluster.Name, chi.Name, err)
+		w.task.registryFailed.RegisterSecret(meta.ObjectMeta{Namespace: chi.Namespace, Name: name})
+	}
+
+	desired, err := w.getClusterSecretValue(ctx, cluster)
+	if err != nil {
+		failed(err)
+		return
+	}
+
+	secret, err := w.c.kubeClient.CoreV1().Secrets(chi.Namespace).Get(ctx, name, controller.NewGetOptions())
+	switch {
+	case apiErrors.IsNotFound(err):
+		// Both slots carry the same secret, nothing to switch
+		secret = w.task.creator.CreateClusterSecretSlots(cluster, desired)
+		if err = w.createSecret(ctx, chi, secret); err != nil {
+			failed(err)
+			return
+		}
+		if secret, err = w.c.getSecret(secret); err != nil {
+			failed(err)
+			return
+		}
+	case err != nil:
+		// Secret may exist, so slots can not be overwritten, since hosts may use either of them
+		failed(err)
+		return
+	}
+
+	active := model.GetClusterSecretActiveSlot(secret)
+	if next := model.GetClusterSecretInactiveSlot(active); !bytes.Equal(secret.Data[active], desired) {
+		if !bytes.Equal(secret.Data[next], desired) {
+			// Hosts are to carry the new secret along with the one they use
+			secret = secret.DeepCopy()
+			if secret.Data == nil {
+				secret.Data = make(map[string][]byte)
+			}
+			secret.Data[next] = desired
+			if err = w.updateSecret(ctx, chi, secret); err != nil {
+				failed(err)
+				return
+			}
+			if secret, err = w.c.getSecret(secret); err != nil {
+				failed(err)
+				return
+			}
+		}
+		cluster.Runtime.SecretSlotNext = next
+	}
+
+	w.task.registryReconciled.RegisterSecret(secret.ObjectMeta)
+	// Hosts are rolled in case any of the slots has changed, since ENV vars are read on start only
+	cluster.Runtime.SecretVersion = model.GetClusterSecretVersion(secret)
+	cluster.Runtime.SecretSlot = active
+}
+
+// switchClusterSecretSlot switches hosts of the cluster to the slot carrying the new cluster secret.
+// Switch happens once all hosts carry both secrets, which means they are able to talk to each other
+// having either secret in use. Hosts pick up the switch by reload of remote_servers config
+func (w *worker) switchClusterSecretSlot(ctx context.Context, cluster *api.Cluster) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	next := cluster.Runtime.SecretSlotNext
+	if next == "" {
+		// Nothing to switch
+		return
+	}
+
+	ready := true
+	cluster.WalkHosts(func(host *api.ChiHost) error {
+		if !ready {
+			return nil
+		}
+		statefulSet, err := w.c.getStatefulSet(host)
+		switch {
+		case err != nil:
+			ready = false
+		case statefulSet.Spec.Template.Annotations[model.AnnotationClusterSecretVersion] != cluster.Runtime.SecretVersion:
+			ready = false
+		case (statefulSet.Spec.Replicas != nil) && (*statefulSet.Spec.Replicas > 0) && !k8s.IsStatefulSetReady(statefulSet):
+			ready = false
+		}
+		if !ready {
+			w.a.V(1).M(host).F().Info("host does not carry new cluster secret yet: %s", host.GetName())
+		}
+		return nil
+	})
+	if !ready {
+		w.a.V(1).M(cluster).F().Info(
+			"Postpone switch of the cluster secret till all hosts carry it. Cluster: %s CHI: %s",
+			cluster.Name, cluster.Runtime.CHI.Name)
+		return
+	}
+
+	chi := cluster.Runtime.CHI
+	secret, err := w.c.kubeClient.CoreV1().Secrets(chi.Namespace).Get(ctx, model.CreateClusterSecretSlotsName(cluster), controller.NewGetOptions())
+	if err == nil {
+		// Active slot is kept in metadata, so switch does not change version of the slots and does not roll hosts
+		secret = secret.DeepCopy()
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[model.AnnotationClusterSecretActiveSlot] = next
+		err = w.updateSecret(ctx, chi, secret)
+	}
+	if err != nil {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusAction(chi).
+			WithStatusError(chi).
+			M(cluster).F().
+			Error("FAILED to switch cluster secret of the cluster: %s CHI: %s err: %v", cluster.Name, chi.Name, err)
+		return
+	}
+
+	cluster.Runtime.SecretSlot = next
+	cluster.Runtime.SecretSlotNext = ""
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonSecretsRotated).
+		WithStatusAction(chi).
+		M(cluster).F().
+		Info("Switch cluster secret of the cluster: %s CHI: %s", cluster.Name, chi.Name)
+}
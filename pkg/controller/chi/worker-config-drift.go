@@ -0,0 +1,124 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	chiCreator "github.com/altinity/clickhouse-operator/pkg/model/chi/creator"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// configDriftPollPeriod specifies how often config ConfigMaps of CHIs are checked for being edited out-of-band
+const configDriftPollPeriod = 5 * time.Minute
+
+// runConfigDriftPoller checks config ConfigMaps of CHIs for being edited out-of-band
+// and restores generated content, until ctx is done
+func (c *Controller) runConfigDriftPoller(ctx context.Context) {
+	w := c.newWorker(nil, true)
+	for {
+		chis, err := c.chiLister.List(labels.Everything())
+		if err != nil {
+			log.V(1).F().Warning("unable to list CHIs err: %v", err)
+		}
+		for _, chi := range chis {
+			switch {
+			case !chop.Config().IsWatchedNamespace(chi.Namespace):
+				continue
+			case chi.IsStopped() || chi.Spec.Defaults.IsConfigStoredInSecret():
+				continue
+			case chi.EnsureStatus().GetStatus() == api.StatusInProgress:
+				// ConfigMaps are restored by the reconcile in progress anyway
+				continue
+			}
+			w.reconcileConfigDrift(ctx, chi.DeepCopy())
+		}
+		if util.WaitContextDoneOrTimeout(ctx, configDriftPollPeriod) {
+			return
+		}
+	}
+}
+
+// reconcileConfigDrift restores generated content of config ConfigMaps of the CHI, which are edited out-of-band.
+// ConfigMap is restored only in case the CHI generates the same content the ConfigMap is annotated with,
+// otherwise the CHI is changed since the last reconcile and the ConfigMap is left to the pending reconcile
+func (w *worker) reconcileConfigDrift(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	normalized, err := w.normalizer.CreateTemplatedCHI(chi.DeepCopy(), normalizer.NewOptions())
+	if err != nil {
+		w.a.V(1).M(chi).F().Warning("unable to normalize CHI err: %v", err)
+		return
+	}
+
+	creator := chiCreator.NewCreator(normalized)
+	configMaps := []*core.ConfigMap{
+		creator.CreateConfigMapCHICommon(nil),
+	}
+	if !normalized.IsUsersStoredInSecret() {
+		configMaps = append(configMaps, creator.CreateConfigMapCHICommonUsers())
+	}
+	normalized.WalkHosts(func(host *api.ChiHost) error {
+		configMaps = append(configMaps, creator.CreateConfigMapHost(host))
+		return nil
+	})
+
+	for _, configMap := range configMaps {
+		w.restoreDriftedConfigMap(ctx, chi, configMap)
+	}
+}
+
+// restoreDriftedConfigMap restores generated content of the ConfigMap in case it is edited out-of-band
+func (w *worker) restoreDriftedConfigMap(ctx context.Context, chi *api.ClickHouseInstallation, configMap *core.ConfigMap) {
+	cur, err := w.c.kubeClient.CoreV1().ConfigMaps(configMap.Namespace).Get(ctx, configMap.Name, controller.NewGetOptions())
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			w.a.V(1).M(chi).F().Warning("unable to get ConfigMap %s/%s err: %v", configMap.Namespace, configMap.Name, err)
+		}
+		return
+	}
+	if !model.IsConfigDrifted(cur) {
+		return
+	}
+	if cur.Annotations[model.AnnotationConfigHash] != configMap.Annotations[model.AnnotationConfigHash] {
+		w.a.V(1).M(chi).F().Info("ConfigMap %s/%s is edited out-of-band, CHI is changed as well, leave it to reconcile",
+			configMap.Namespace, configMap.Name)
+		return
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonConfigDrift).
+		M(chi).F().
+		Warning("ConfigMap %s/%s is edited out-of-band, restore generated content", configMap.Namespace, configMap.Name)
+	if _, err := w.c.kubeClient.CoreV1().ConfigMaps(configMap.Namespace).Update(ctx, configMap, controller.NewUpdateOptions()); err != nil {
+		w.a.WithEvent(chi, eventActionUpdate, eventReasonUpdateFailed).
+			M(chi).F().
+			Error("Restore ConfigMap %s/%s failed with error %v", configMap.Namespace, configMap.Name, err)
+	}
+}
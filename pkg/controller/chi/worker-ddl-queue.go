@@ -0,0 +1,224 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apiMeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/schemer"
+	"github.com/altinity/clickhouse-operator/pkg/model/chk/keeper"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// ddlQueuePollPeriod specifies how often CHIs are checked for being due to poll their distributed DDL queue
+const ddlQueuePollPeriod = 30 * time.Second
+
+// Reasons of DDLQueueHealthy condition
+const (
+	ddlQueueReasonNoStuckEntries = "NoStuckEntries"
+	ddlQueueReasonEntriesStuck   = "EntriesStuck"
+	ddlQueueReasonPollFailed     = "PollFailed"
+)
+
+// runDDLQueuePoller polls distributed DDL queue of CHIs having ddlQueue specified,
+// each CHI according to its own interval, until ctx is done
+func (c *Controller) runDDLQueuePoller(ctx context.Context) {
+	w := c.newWorker(nil, true)
+	polled := make(map[string]time.Time)
+	for {
+		chis, err := c.chiLister.List(labels.Everything())
+		if err != nil {
+			log.V(1).F().Warning("unable to list CHIs err: %v", err)
+		}
+		due := make(map[string]bool)
+		for _, chi := range chis {
+			if !chop.Config().IsWatchedNamespace(chi.Namespace) || !chi.GetDDLQueue().IsEnabled() || chi.IsStopped() {
+				continue
+			}
+			key := chi.Namespace + "/" + chi.Name
+			due[key] = true
+			if time.Since(polled[key]) < chi.GetDDLQueue().GetInterval() {
+				continue
+			}
+			polled[key] = time.Now()
+			w.reconcileDDLQueue(ctx, chi.DeepCopy())
+		}
+		if err == nil {
+			// Forget CHIs, which are deleted or do not poll the queue anymore
+			for key := range polled {
+				if !due[key] {
+					delete(polled, key)
+				}
+			}
+		}
+		if util.WaitContextDoneOrTimeout(ctx, ddlQueuePollPeriod) {
+			return
+		}
+	}
+}
+
+// reconcileDDLQueue polls distributed DDL queue of the CHI on the first host of each cluster having keeper,
+// reports entries not completed by all hosts in time via events and DDLQueueHealthy condition
+// and deletes entries completed by all hosts from keeper in case cleanup is enabled
+func (w *worker) reconcileDDLQueue(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	wasHealthy := true
+	if condition := apiMeta.FindStatusCondition(chi.EnsureStatus().GetConditions(), api.ConditionTypeDDLQueueHealthy); condition != nil {
+		wasHealthy = condition.Status != meta.ConditionFalse
+	}
+
+	normalized, err := w.normalizer.CreateTemplatedCHI(chi.DeepCopy(), normalizer.NewOptions())
+	if err != nil {
+		w.a.V(1).M(chi).F().Warning("unable to normalize CHI err: %v", err)
+		return
+	}
+
+	spec := normalized.GetDDLQueue()
+	timeout := spec.GetStuckTimeout()
+	var stuck, failed []string
+	// Clusters sharing keeper share distributed DDL queue as well, so the queue is polled once per keeper
+	polled := make(map[string]bool)
+	normalized.WalkClusters(func(cluster *api.Cluster) error {
+		if cluster.Zookeeper.IsEmpty() || cluster.IsStopped() {
+			return nil
+		}
+		key := fmt.Sprintf("%v%s", cluster.Zookeeper.Nodes, cluster.Zookeeper.Root)
+		if polled[key] {
+			return nil
+		}
+		polled[key] = true
+
+		host := cluster.FirstHost()
+		if host == nil {
+			return nil
+		}
+		entries, err := w.ensureClusterSchemer(host).HostDDLQueue(ctx, host)
+		if err != nil {
+			w.a.V(1).M(host).F().Warning("unable to poll distributed DDL queue on host: %s err: %v", host.GetName(), err)
+			failed = append(failed, host.GetName())
+			return nil
+		}
+
+		var completed []string
+		for i := range entries {
+			entry := &entries[i]
+			switch {
+			case entry.IsCompleted():
+				completed = append(completed, entry.Entry)
+			case entry.Age > timeout:
+				stuck = append(stuck, describeStuckDDL(entry))
+			}
+		}
+		if spec.IsCleanupEnabled() && (len(completed) > 0) {
+			w.cleanupDDLQueue(ctx, normalized, cluster, completed)
+		}
+		return nil
+	})
+
+	condition := meta.Condition{
+		Type:               api.ConditionTypeDDLQueueHealthy,
+		Status:             meta.ConditionTrue,
+		Reason:             ddlQueueReasonNoStuckEntries,
+		Message:            fmt.Sprintf("no entries are pending for longer than %s", timeout),
+		ObservedGeneration: chi.Generation,
+	}
+	switch {
+	case len(stuck) > 0:
+		condition.Status = meta.ConditionFalse
+		condition.Reason = ddlQueueReasonEntriesStuck
+		condition.Message = fmt.Sprintf("entries are pending for longer than %s: %s", timeout, strings.Join(stuck, "; "))
+	case len(failed) > 0:
+		condition.Status = meta.ConditionUnknown
+		condition.Reason = ddlQueueReasonPollFailed
+		condition.Message = "unable to poll hosts: " + strings.Join(failed, ", ")
+	}
+
+	if (condition.Status == meta.ConditionFalse) && wasHealthy {
+		w.a.V(1).
+			WithEvent(chi, eventActionCheck, eventReasonDDLQueueStuck).
+			M(chi).F().
+			Warning("Distributed DDL is stuck. %s", condition.Message)
+	}
+
+	chi.EnsureStatus().SetCondition(condition)
+	_ = w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
+		TolerateAbsence: true,
+		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+			DDLQueue: true,
+		},
+	})
+}
+
+// describeStuckDDL describes stuck entry for status and events.
+// Age of the entry is not described, so status is not rewritten on each poll while the same entries are stuck
+func describeStuckDDL(entry *schemer.DDLQueueEntry) string {
+	description := fmt.Sprintf("%s on cluster %s pending on hosts: %s",
+		entry.Entry, entry.Cluster, strings.Join(entry.Pending, ", "))
+	if len(entry.Failed) > 0 {
+		description += " failed on hosts: " + strings.Join(entry.Failed, ", ")
+	}
+	return description
+}
+
+// cleanupDDLQueue deletes entries, completed by all hosts, from keeper of the cluster.
+// Keeper nodes are tried in order till the first one entries are deleted via.
+// Session is authenticated with the identity of the cluster, so entries protected by ACL are accessible the same way they are to ClickHouse
+func (w *worker) cleanupDDLQueue(ctx context.Context, chi *api.ClickHouseInstallation, cluster *api.Cluster, entries []string) {
+	path := strings.TrimSuffix(cluster.Zookeeper.Root, "/") + model.GetDistributedDDLPath(chi)
+	for i := range cluster.Zookeeper.Nodes {
+		node := &cluster.Zookeeper.Nodes[i]
+		if node.Secure.IsTrue() {
+			// Secure keeper connections are not supported by the client
+			continue
+		}
+		client := keeper.NewClient(node.Host, int(node.Port))
+		if cluster.Zookeeper.Identity != "" {
+			client.SetAuth(keeper.AuthSchemeDigest, cluster.Zookeeper.Identity)
+		}
+		deleted := 0
+		var err error
+		for _, entry := range entries {
+			if err = client.DeleteRecursive(ctx, path+"/"+entry); err != nil {
+				break
+			}
+			deleted++
+		}
+		if deleted > 0 {
+			w.a.V(1).M(chi).F().Info("Deleted %d completed distributed DDL entries of cluster %s from keeper %s",
+				deleted, cluster.Name, client)
+		}
+		if err == nil {
+			return
+		}
+		w.a.V(1).M(chi).F().Warning("unable to delete distributed DDL entries from keeper %s err: %v", client, err)
+		entries = entries[deleted:]
+	}
+}
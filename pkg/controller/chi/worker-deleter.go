@@ -16,6 +16,9 @@ package chi
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	core "k8s.io/api/core/v1"
@@ -27,6 +30,7 @@ import (
 	"github.com/altinity/clickhouse-operator/pkg/controller"
 	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
 	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
@@ -80,6 +84,75 @@ func (w *worker) dropReplicas(ctx context.Context, chi *api.ClickHouseInstallati
 	w.a.V(1).M(chi).F().E().Info("processed replicas: %d", cnt)
 }
 
+// checkShardsRemoval ensures shards removed by the action plan hold no data,
+// which is not replicated outside of the shard. Removal is refused unless CHI is annotated as allowed to lose data.
+func (w *worker) checkShardsRemoval(ctx context.Context, chi *api.ClickHouseInstallation, ap *model.ActionPlan) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	var hosts []*api.ChiHost
+	collect := func(host *api.ChiHost) error {
+		hosts = append(hosts, host)
+		return nil
+	}
+	ap.WalkRemoved(
+		func(cluster *api.Cluster) {
+			cluster.WalkHosts(collect)
+		},
+		func(shard *api.ChiShard) {
+			shard.WalkHosts(collect)
+		},
+		func(host *api.ChiHost) {
+		},
+	)
+
+	var blocking []string
+	var errs []string
+	for _, host := range hosts {
+		if _, err := w.c.getStatefulSet(host); err != nil {
+			// Host has no StatefulSet, nothing to check
+			continue
+		}
+		tables, err := w.ensureClusterSchemer(host).HostDataTables(ctx, host)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", host.GetName(), err))
+			continue
+		}
+		for _, table := range tables {
+			blocking = append(blocking, host.GetName()+":"+table)
+		}
+	}
+	blocking = util.Unique(blocking)
+
+	if (len(blocking) == 0) && (len(errs) == 0) {
+		chi.EnsureStatus().SetBlockingTables(nil)
+		return nil
+	}
+
+	if model.IsForceDataLoss(chi.ObjectMeta) {
+		w.a.V(1).M(chi).F().Warning(
+			"removed shards hold data, proceed due to %s annotation. Tables: %s",
+			model.AnnotationForceDataLoss, strings.Join(blocking, ", "))
+		chi.EnsureStatus().SetBlockingTables(nil)
+		return nil
+	}
+
+	chi.EnsureStatus().SetBlockingTables(blocking)
+	reasons := append([]string{}, blocking...)
+	if len(errs) > 0 {
+		reasons = append(reasons, "unable to verify "+strings.Join(errs, ", "))
+	}
+	err := fmt.Errorf("%w: %s. Annotate CHI with %s to force removal",
+		errShardRemovalDataLoss, strings.Join(reasons, ", "), model.AnnotationForceDataLoss)
+	w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+		WithStatusError(chi).
+		M(chi).F().
+		Error("refuse to remove shards: %v", err)
+	return err
+}
+
 func shouldPurgeStatefulSet(chi *api.ClickHouseInstallation, reconcileFailedObjs *model.Registry, m meta.ObjectMeta) bool {
 	if reconcileFailedObjs.HasStatefulSet(m) {
 		return chi.GetReconciling().GetCleanup().GetReconcileFailedObjects().GetStatefulSet() == api.ObjectsCleanupDelete
@@ -268,6 +341,10 @@ func (w *worker) deleteCHIProtocol(ctx context.Context, chi *api.ClickHouseInsta
 
 	var err error
 	chi, err = w.normalizer.CreateTemplatedCHI(chi, normalizer.NewOptions())
+	if errors.Is(err, normalizer.ErrInvalidPodTemplate) || errors.Is(err, normalizer.ErrPortConflict) {
+		// Invalid podTemplate or conflicting ports do not prevent CHI from being deleted
+		err = nil
+	}
 	if err != nil {
 		w.a.WithEvent(chi, eventActionDelete, eventReasonDeleteFailed).
 			WithStatusError(chi).
@@ -320,6 +397,16 @@ func (w *worker) deleteCHIProtocol(ctx context.Context, chi *api.ClickHouseInsta
 	// Delete ConfigMap(s)
 	_ = w.c.deleteConfigMapsCHI(ctx, chi)
 
+	// ServiceMonitor is shared by all CHIs, it is kept unless the CHI was the last one having it enabled
+	if chi.Spec.Monitoring.IsServiceMonitorEnabled() {
+		w.reconcileOperatorServiceMonitor(ctx, chi, true)
+	}
+
+	metricsCHINodesDelete(chi)
+	metricsCHIFootprintDelete(chi)
+	w.c.unindexCHISecrets(chi)
+	metricsCHISlowSQLDelete(chi)
+
 	w.a.V(1).
 		WithEvent(chi, eventActionDelete, eventReasonDeleteCompleted).
 		WithStatusAction(chi).
@@ -342,7 +429,7 @@ func (w *worker) canDropReplica(host *api.ChiHost, opts ...*dropReplicaOptions)
 		// Replica's state has to be kept in Zookeeper for retained volumes.
 		// ClickHouse expects to have state of the non-empty replica in-place when replica rejoins.
 		if model.GetReclaimPolicy(pvc.ObjectMeta) == api.PVCReclaimPolicyRetain {
-			w.a.V(1).F().Info("PVC: %s/%s blocks drop replica. Reclaim policy: %s", api.PVCReclaimPolicyRetain.String())
+			w.a.V(1).F().Info("PVC: %s/%s blocks drop replica. Reclaim policy: %s", pvc.Namespace, pvc.Name, api.PVCReclaimPolicyRetain.String())
 			can = false
 		}
 	})
@@ -486,7 +573,13 @@ func (w *worker) deleteHost(ctx context.Context, chi *api.ClickHouseInstallation
 	// Need to delete all these items
 
 	_ = w.deleteTables(ctx, host)
+	// Endpoints, such as pod IP, are not available once the host is deleted
+	endpoints := append(w.getHostEndpoints(host), model.CreateFQDN(host))
 	err = w.c.deleteHost(ctx, host)
+	// Pooled connections to the deleted host would never be reused
+	for _, endpoint := range endpoints {
+		clickhouse.DropHost(endpoint)
+	}
 
 	// When deleting the whole CHI (not particular host), CHI may already be unavailable, so update CHI tolerantly
 	chi.EnsureStatus().HostDeleted()
@@ -573,6 +666,14 @@ func (w *worker) deleteCluster(ctx context.Context, chi *api.ClickHouseInstallat
 		_ = w.c.deleteSecretCluster(ctx, cluster)
 	}
 
+	// Delete ChkCluster's Secret carrying slots of the cluster secret
+	_ = w.c.deleteSecretClusterSlots(ctx, cluster)
+
+	// Delete ChkCluster's TLS Secret
+	if cluster.IsSecureAuto() {
+		_ = w.c.deleteSecretClusterTLS(ctx, cluster)
+	}
+
 	// Delete all shards
 	cluster.WalkShards(func(index int, shard *api.ChiShard) error {
 		return w.deleteShard(ctx, chi, shard)
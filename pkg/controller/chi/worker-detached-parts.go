@@ -0,0 +1,215 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apiMeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/schemer"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// detachedPartsPollPeriod specifies how often CHIs are checked for being due to poll their hosts for detached parts
+const detachedPartsPollPeriod = 1 * time.Minute
+
+// Reasons of DetachedPartsBelowThreshold condition
+const (
+	detachedPartsReasonBelowThreshold = "BelowThreshold"
+	detachedPartsReasonOverThreshold  = "OverThreshold"
+	detachedPartsReasonPollFailed     = "PollFailed"
+)
+
+// runDetachedPartsPoller polls hosts of CHIs having detachedParts specified for detached parts,
+// each CHI according to its own interval, until ctx is done
+func (c *Controller) runDetachedPartsPoller(ctx context.Context) {
+	w := c.newWorker(nil, true)
+	polled := make(map[string]time.Time)
+	for {
+		chis, err := c.chiLister.List(labels.Everything())
+		if err != nil {
+			log.V(1).F().Warning("unable to list CHIs err: %v", err)
+		}
+		for _, chi := range chis {
+			if !chop.Config().IsWatchedNamespace(chi.Namespace) || !chi.GetDetachedParts().IsEnabled() || chi.IsStopped() {
+				continue
+			}
+			key := chi.Namespace + "/" + chi.Name
+			if time.Since(polled[key]) < chi.GetDetachedParts().GetInterval() {
+				continue
+			}
+			polled[key] = time.Now()
+			w.reconcileDetachedParts(ctx, chi.DeepCopy())
+		}
+		if util.WaitContextDoneOrTimeout(ctx, detachedPartsPollPeriod) {
+			return
+		}
+	}
+}
+
+// reconcileDetachedParts polls all hosts of the CHI for detached parts, drops known-safe ones in case cleanup is enabled
+// and reports hosts having detached parts over the threshold via events and DetachedPartsBelowThreshold condition
+func (w *worker) reconcileDetachedParts(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	wasBelowThreshold := true
+	if condition := apiMeta.FindStatusCondition(chi.EnsureStatus().GetConditions(), api.ConditionTypeDetachedPartsBelowThreshold); condition != nil {
+		wasBelowThreshold = condition.Status != meta.ConditionFalse
+	}
+
+	normalized, err := w.normalizer.CreateTemplatedCHI(chi.DeepCopy(), normalizer.NewOptions())
+	if err != nil {
+		w.a.V(1).M(chi).F().Warning("unable to normalize CHI err: %v", err)
+		return
+	}
+
+	spec := normalized.GetDetachedParts()
+	threshold := spec.GetThreshold()
+	now := time.Now().Format(time.RFC3339)
+	var reports []api.ChiHostDetachedParts
+	var over, failed []string
+	normalized.WalkHosts(func(host *api.ChiHost) error {
+		report := w.pollHostDetachedParts(ctx, host, spec.GetCleanup())
+		report.Time = now
+		switch {
+		case report.Error != "":
+			failed = append(failed, host.Runtime.Address.ClusterName+"/"+host.GetName())
+		case report.Count > threshold:
+			over = append(over, fmt.Sprintf("%s/%s: %d", host.Runtime.Address.ClusterName, host.GetName(), report.Count))
+		}
+		reports = append(reports, report)
+		return nil
+	})
+
+	condition := meta.Condition{
+		Type:               api.ConditionTypeDetachedPartsBelowThreshold,
+		Status:             meta.ConditionTrue,
+		Reason:             detachedPartsReasonBelowThreshold,
+		Message:            fmt.Sprintf("all hosts have %d detached parts or less", threshold),
+		ObservedGeneration: chi.Generation,
+	}
+	switch {
+	case len(over) > 0:
+		condition.Status = meta.ConditionFalse
+		condition.Reason = detachedPartsReasonOverThreshold
+		condition.Message = fmt.Sprintf("hosts have more than %d detached parts: %s", threshold, strings.Join(over, ", "))
+	case len(failed) > 0:
+		condition.Status = meta.ConditionUnknown
+		condition.Reason = detachedPartsReasonPollFailed
+		condition.Message = "unable to poll hosts: " + strings.Join(failed, ", ")
+	}
+
+	if (condition.Status == meta.ConditionFalse) && wasBelowThreshold {
+		w.a.V(1).
+			WithEvent(chi, eventActionCheck, eventReasonDetachedPartsOverThreshold).
+			M(chi).F().
+			Warning("Detached parts over threshold. %s", condition.Message)
+	}
+
+	chi.EnsureStatus().SetDetachedParts(reports)
+	chi.EnsureStatus().SetCondition(condition)
+	_ = w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
+		TolerateAbsence: true,
+		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+			DetachedParts: true,
+		},
+	})
+}
+
+// pollHostDetachedParts lists detached parts of the host and drops known-safe ones in case cleanup is enabled
+func (w *worker) pollHostDetachedParts(
+	ctx context.Context,
+	host *api.ChiHost,
+	cleanup *api.ChiDetachedPartsCleanup,
+) api.ChiHostDetachedParts {
+	report := api.ChiHostDetachedParts{
+		Cluster: host.Runtime.Address.ClusterName,
+		Host:    host.GetName(),
+	}
+	// Version is required in order to tell whether age of detached parts is available
+	if _, err := w.getHostClickHouseVersion(ctx, host, versionOptions{}); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	s := w.ensureClusterSchemer(host)
+	parts, err := s.HostDetachedParts(ctx, host)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	var keep, drop []schemer.DetachedPart
+	for _, part := range parts {
+		if cleanup.IsEnabled() && isDetachedPartToDrop(part, cleanup) {
+			drop = append(drop, part)
+		} else {
+			keep = append(keep, part)
+		}
+	}
+
+	if len(drop) > 0 {
+		report.Dropped, err = s.HostDropDetachedParts(ctx, host, drop)
+		if err != nil {
+			report.Error = err.Error()
+		}
+		// Parts failed to be dropped are still there
+		keep = append(keep, drop[report.Dropped:]...)
+		w.a.V(1).
+			WithEvent(host.GetCHI(), eventActionDelete, eventReasonDetachedPartsDropped).
+			M(host).F().
+			Info("Dropped %d of %d detached parts on host: %s", report.Dropped, len(drop), host.GetName())
+	}
+
+	for _, part := range keep {
+		if report.Reasons == nil {
+			report.Reasons = make(map[string]int)
+		}
+		report.Reasons[part.Reason]++
+		report.Count++
+	}
+
+	return report
+}
+
+// isDetachedPartToDrop checks whether detached part is of known-safe reason and old enough to be dropped.
+// Parts of unknown age are never dropped.
+// Broken-on-start parts are dropped for Replicated* tables only, since other tables may have no other copy of the data
+func isDetachedPartToDrop(part schemer.DetachedPart, cleanup *api.ChiDetachedPartsCleanup) bool {
+	if (part.AgeDays < 0) || (part.AgeDays < cleanup.GetMinAgeDays()) {
+		return false
+	}
+	if (part.Reason == api.DetachedPartReasonBrokenOnStart) && !part.Replicated {
+		return false
+	}
+	for _, reason := range cleanup.GetReasons() {
+		if part.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	discovery "k8s.io/api/discovery/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+const (
+	// endpointsPollInterval specifies how often EndpointSlices are polled while waiting for the host to leave them
+	endpointsPollInterval = 2 * time.Second
+	// endpointsExcludeTimeout specifies how long the host is waited to leave EndpointSlices, host is updated anyway after it
+	endpointsExcludeTimeout = 1 * time.Minute
+)
+
+// shouldExcludeHostFromServiceOnly determines whether the host, which is not to be excluded from ClickHouse cluster,
+// is still to be excluded from CHI-, cluster- and shard-level Services for the time it is restarted.
+// The only host of a shard stays in remote_servers, since the shard would be lost otherwise,
+// while clients are to reach the rest of the shards, not the restarting host.
+func (w *worker) shouldExcludeHostFromServiceOnly(host *api.ChiHost) bool {
+	return (host.GetShard().HostsCount() == 1) && w.isHostToBeRestarted(host)
+}
+
+// waitHostEndpointsExcluded waits for the pod of the host to leave EndpointSlices of CHI-, cluster- and shard-level
+// Services, so client connections are not reset by the restart. Host-level Service is not waited for,
+// since the operator itself reaches the host through it
+func (w *worker) waitHostEndpointsExcluded(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	services := getHostReadyServicesNames(host)
+	pod := model.CreatePodName(host)
+	deadline := time.Now().Add(endpointsExcludeTimeout)
+	for {
+		found, err := w.c.isPodInEndpointSlices(ctx, host.GetCHI(), services, pod)
+		if err != nil {
+			// EndpointSlices may be not available to the operator, it is not a reason to block the reconcile
+			w.a.V(1).M(host).F().Warning("Unable to check EndpointSlices, proceed. Host: %s err: %v", host.GetName(), err)
+			return
+		}
+		if !found {
+			w.a.V(1).M(host).F().Info("Host is excluded from EndpointSlices of Services. Host: %s", host.GetName())
+			return
+		}
+		if time.Now().After(deadline) {
+			w.a.V(1).M(host).F().Warning("Host is still in EndpointSlices of Services after %s, proceed. Host: %s",
+				endpointsExcludeTimeout, host.GetName())
+			return
+		}
+		if util.WaitContextDoneOrTimeout(ctx, endpointsPollInterval) {
+			log.V(2).Info("task is done")
+			return
+		}
+	}
+}
+
+// getHostReadyServicesNames gets names of Services selecting the host by "ready" label, which the host is excluded from
+func getHostReadyServicesNames(host *api.ChiHost) map[string]bool {
+	chi := host.GetCHI()
+	names := map[string]bool{
+		model.CreateCHIServiceName(chi):                   true,
+		model.CreateClusterServiceName(host.GetCluster()): true,
+		model.CreateShardServiceName(host.GetShard()):     true,
+	}
+	if chop.Config().IsFeatureEnabled(api.FeatureGateReadWriteServices) {
+		for _, role := range []string{model.LabelReplicaRoleValueReadWrite, model.LabelReplicaRoleValueReadOnly} {
+			names[model.CreateCHIServiceReplicaRoleName(chi, role)] = true
+		}
+	}
+	for _, template := range host.GetCluster().GetExtraServiceTemplates() {
+		names[model.CreateClusterExtraServiceName(host.GetCluster(), template)] = true
+	}
+	return names
+}
+
+// isPodInEndpointSlices checks whether the pod is an endpoint of any of specified Services of the CHI
+func (c *Controller) isPodInEndpointSlices(ctx context.Context, chi *api.ClickHouseInstallation, services map[string]bool, pod string) (bool, error) {
+	opts := controller.NewListOptions(model.NewLabeler(chi).GetSelectorCHIScope())
+	list, err := c.kubeClient.DiscoveryV1().EndpointSlices(chi.Namespace).List(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+	for i := range list.Items {
+		slice := &list.Items[i]
+		if !services[slice.Labels[discovery.LabelServiceName]] {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			if (endpoint.TargetRef != nil) && (endpoint.TargetRef.Kind == "Pod") && (endpoint.TargetRef.Name == pod) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/creator"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// reconcileCHIGateway reconciles Gateway API routes exposing native protocol of the CHI
+func (w *worker) reconcileCHIGateway(ctx context.Context, chi *api.ClickHouseInstallation) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	gateway := chi.Spec.Gateway
+	var ancestorGateway *api.ChiGateway
+	if ancestor := chi.GetAncestor(); ancestor != nil {
+		ancestorGateway = ancestor.Spec.Gateway
+	}
+
+	// Backend of the routes is reconciled along with the rest of CHI Services
+	for _, route := range w.task.creator.CreateGatewayRoutes() {
+		w.reconcileGatewayRoute(ctx, chi, route)
+	}
+
+	if !gateway.HasTCP() && ancestorGateway.HasTCP() {
+		w.deleteGatewayRoute(ctx, chi, creator.GatewayRouteKindTCP)
+	}
+	if !gateway.HasTLS() && ancestorGateway.HasTLS() {
+		w.deleteGatewayRoute(ctx, chi, creator.GatewayRouteKindTLS)
+	}
+
+	return nil
+}
+
+// reconcileGatewayRoute creates or updates Gateway API route
+func (w *worker) reconcileGatewayRoute(ctx context.Context, chi *api.ClickHouseInstallation, route *unstructured.Unstructured) {
+	client := w.c.dynamicClient.Resource(creator.GatewayRouteResource(route.GetKind())).Namespace(route.GetNamespace())
+
+	cur, err := client.Get(ctx, route.GetName(), controller.NewGetOptions())
+	switch {
+	case err == nil:
+		route.SetResourceVersion(cur.GetResourceVersion())
+		_, err = client.Update(ctx, route, controller.NewUpdateOptions())
+	case apiErrors.IsNotFound(err):
+		_, err = client.Create(ctx, route, controller.NewCreateOptions())
+	}
+
+	if err != nil {
+		// Gateway API CRDs may be not installed in the cluster
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusAction(chi).
+			M(chi).F().
+			Warning("unable to reconcile %s %s/%s err: %v", route.GetKind(), route.GetNamespace(), route.GetName(), err)
+		return
+	}
+
+	w.a.V(1).M(chi).F().Info("reconciled %s %s/%s", route.GetKind(), route.GetNamespace(), route.GetName())
+}
+
+// deleteGatewayRoute deletes Gateway API route of specified kind, which is not requested anymore
+func (w *worker) deleteGatewayRoute(ctx context.Context, chi *api.ClickHouseInstallation, kind string) {
+	name := model.CreateCHIGatewayRouteName(chi, kind)
+	err := w.c.dynamicClient.Resource(creator.GatewayRouteResource(kind)).Namespace(chi.Namespace).Delete(ctx, name, controller.NewDeleteOptions())
+	switch {
+	case err == nil:
+		w.a.V(1).M(chi).F().Info("deleted %s %s/%s", kind, chi.Namespace, name)
+	case apiErrors.IsNotFound(err):
+		// Nothing to delete
+	default:
+		w.a.V(1).M(chi).F().Warning("unable to delete %s %s/%s err: %v", kind, chi.Namespace, name, err)
+	}
+}
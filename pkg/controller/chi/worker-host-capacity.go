@@ -0,0 +1,170 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	authorization "k8s.io/api/authorization/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeInformers "k8s.io/client-go/informers"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+)
+
+// ShouldWatchNodes checks whether nodes are to be watched in order to check hosts capacity.
+// Nodes are not watched in case capacity check is disabled or the operator is not allowed to read nodes.
+func (c *Controller) ShouldWatchNodes(ctx context.Context) bool {
+	if chop.Config().Reconcile.Host.Capacity.GetCheck() == api.HostCapacityCheckNo {
+		return false
+	}
+	for _, verb := range []string{"list", "watch"} {
+		review := &authorization.SelfSubjectAccessReview{
+			Spec: authorization.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorization.ResourceAttributes{
+					Verb:     verb,
+					Resource: "nodes",
+				},
+			},
+		}
+		result, err := c.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, controller.NewCreateOptions())
+		if err != nil {
+			log.V(1).F().Warning("unable to check access to nodes err: %v", err)
+			return false
+		}
+		if !result.Status.Allowed {
+			log.V(1).F().Info("operator is not allowed to %s nodes, hosts capacity is not checked", verb)
+			return false
+		}
+	}
+	return true
+}
+
+// AddNodeInformer sets up nodes lister used to check hosts capacity.
+// Nodes are cluster-scoped, thus informer factory is expected to be neither namespaced nor filtered by labels
+func (c *Controller) AddNodeInformer(kubeInformerFactory kubeInformers.SharedInformerFactory) {
+	c.nodeLister = kubeInformerFactory.Core().V1().Nodes().Lister()
+}
+
+// checkHostsCapacity ensures pods of the hosts fit at least one node by resources requested,
+// so StatefulSets do not hang with pods pending forever without any CHI-level signal.
+// Hosts no node can fit are reported via event, and, in case operator config requires so, reconcile is refused.
+func (w *worker) checkHostsCapacity(chi *api.ClickHouseInstallation) error {
+	check := chop.Config().Reconcile.Host.Capacity.GetCheck()
+	if (check == api.HostCapacityCheckNo) || (w.c.nodeLister == nil) {
+		return nil
+	}
+	nodes, err := w.c.nodeLister.List(labels.Everything())
+	if err != nil || (len(nodes) == 0) {
+		// Nodes are not known (yet), nothing to check against
+		return nil
+	}
+
+	// Hosts sharing pod template request the same resources
+	unfit := make(map[string][]string)
+	fits := make(map[string]bool)
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		if host.IsStopped() {
+			return nil
+		}
+		template, ok := host.GetPodTemplate()
+		if !ok {
+			return nil
+		}
+		fit, checked := fits[template.Name]
+		if !checked {
+			fit = isPodFitAnyNode(&template.Spec, nodes)
+			fits[template.Name] = fit
+		}
+		if !fit {
+			unfit[template.Name] = append(unfit[template.Name], host.GetName())
+		}
+		return nil
+	})
+	if len(unfit) == 0 {
+		return nil
+	}
+
+	var descriptions []string
+	for name, hosts := range unfit {
+		template, _ := chi.GetPodTemplate(name)
+		descriptions = append(descriptions, fmt.Sprintf("podTemplate %s requests %s: %s",
+			name, describeResourceList(model.GetPodRequests(&template.Spec)), strings.Join(hosts, ", ")))
+	}
+	sort.Strings(descriptions)
+	err = fmt.Errorf("%w: %s", errHostUnschedulable, strings.Join(descriptions, "; "))
+
+	if check == api.HostCapacityCheckFail {
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonHostUnschedulable).
+			WithStatusError(chi).
+			M(chi).F().
+			Error("refuse to reconcile, no node can fit pods: %v", err)
+		return err
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonHostUnschedulable).
+		M(chi).F().
+		Warning("no node can fit pods, they would stay pending: %v", err)
+	return nil
+}
+
+// isPodFitAnyNode checks whether resources requested by the pod are allocatable on any schedulable node
+// matching node selector of the pod
+func isPodFitAnyNode(spec *core.PodSpec, nodes []*core.Node) bool {
+	requests := model.GetPodRequests(spec)
+	selector := labels.SelectorFromSet(spec.NodeSelector)
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		if isResourceListFit(requests, node.Status.Allocatable) {
+			return true
+		}
+	}
+	return false
+}
+
+// isResourceListFit checks whether all requested resources are available
+func isResourceListFit(requests, allocatable core.ResourceList) bool {
+	for name, quantity := range requests {
+		if quantity.IsZero() {
+			continue
+		}
+		available, ok := allocatable[name]
+		if !ok || (quantity.Cmp(available) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// describeResourceList describes resources for logs and events
+func describeResourceList(list core.ResourceList) string {
+	var descriptions []string
+	for name, quantity := range list {
+		descriptions = append(descriptions, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	sort.Strings(descriptions)
+	return strings.Join(descriptions, " ")
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+)
+
+// defaultHostDrainTimeout specifies how long running queries of the host under maintenance are waited for
+// in case it is not configured
+const defaultHostDrainTimeout = 10 * time.Minute
+
+// getHostDrainTimeout gets how long running queries of the host under maintenance are waited for
+func getHostDrainTimeout() time.Duration {
+	if timeout := chop.Config().Reconcile.Host.Maintenance.DrainTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return defaultHostDrainTimeout
+}
+
+// drainHostQueries waits for queries running on the host under maintenance to complete.
+// Host is expected to be excluded from cluster Services and remote_servers already, so no new queries arrive.
+// In case queries are still running after drain timeout, the host is handed over to StatefulSet update anyway
+func (w *worker) drainHostQueries(ctx context.Context, host *api.ChiHost) error {
+	log.V(1).M(host).F().S().Info("drain host queries start")
+	defer log.V(1).M(host).F().E().Info("drain host queries end")
+
+	timeout := getHostDrainTimeout()
+	opts := controller.NewPollerOptions().FromConfig(chop.Config())
+	opts.Timeout = timeout
+
+	err := controller.Poll(
+		ctx,
+		host.Runtime.Address.Namespace, host.Runtime.Address.HostName,
+		opts,
+		&controller.PollerFunctions{
+			IsDone: func(_ctx context.Context, _ any) bool {
+				n, err := w.ensureClusterSchemer(host).HostUserQueriesNum(_ctx, host)
+				if err != nil {
+					// Host is not accessible, there is nothing to drain
+					return true
+				}
+				w.a.V(2).M(host).F().Info("Host under maintenance has %d running queries. Host: %s", n, host.GetName())
+				return n == 0
+			},
+		},
+		nil,
+	)
+	if err != nil {
+		w.a.V(1).
+			WithEvent(host.GetCHI(), eventActionReconcile, eventReasonHostDrainTimeout).
+			WithStatusAction(host.GetCHI()).
+			M(host).F().
+			Warning("Host under maintenance still has running queries after %s, proceed anyway. Host: %s", timeout, host.GetName())
+		return err
+	}
+
+	w.a.V(1).
+		M(host).F().
+		Info("Host under maintenance has no running queries. Host: %s", host.GetName())
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+const (
+	// defaultProbeHostPortsTimeout specifies how long each port is probed for in case it is not configured
+	defaultProbeHostPortsTimeout = 30 * time.Second
+	// probeHostPortDialTimeout specifies timeout of each connection attempt
+	probeHostPortDialTimeout = 5 * time.Second
+	// probeHostPortPollInterval specifies how often connection attempts are made
+	probeHostPortPollInterval = 2 * time.Second
+)
+
+// hostPort specifies named port of the host
+type hostPort struct {
+	name string
+	port int32
+}
+
+// shouldProbeHostPorts determines whether ports of the host should be probed by the operator
+func (w *worker) shouldProbeHostPorts(host *api.ChiHost) bool {
+	if !chop.Config().Reconcile.Host.Probe.Ports.Value() {
+		return false
+	}
+	// Stopped host has no pod to be probed
+	return !host.IsStopped()
+}
+
+// getHostPortsToProbe gets ports of the host which are expected to be reachable
+func getHostPortsToProbe(host *api.ChiHost) (ports []hostPort) {
+	for _, p := range []hostPort{
+		{model.ChDefaultTCPPortName, host.TCPPort},
+		{model.ChDefaultTLSPortName, host.TLSPort},
+		{model.ChDefaultHTTPPortName, host.HTTPPort},
+		{model.ChDefaultHTTPSPortName, host.HTTPSPort},
+		{model.ChDefaultInterserverHTTPPortName, host.InterserverHTTPPort},
+	} {
+		if api.IsPortAssigned(p.port) {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// getProbeHostPortsTimeout gets how long each port is probed for
+func getProbeHostPortsTimeout() time.Duration {
+	if timeout := chop.Config().Reconcile.Host.Probe.Timeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return defaultProbeHostPortsTimeout
+}
+
+// probeHostPorts checks ClickHouse ports of the host are reachable from the operator.
+// Kubernetes readiness probe checks the host from within the pod only, thus ports blocked by NetworkPolicy
+// or not listened on due to listen_host misconfiguration are not caught by it.
+// Each unreachable port is reported in CHI status separately.
+func (w *worker) probeHostPorts(ctx context.Context, host *api.ChiHost) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	if !w.shouldProbeHostPorts(host) {
+		return nil
+	}
+
+	fqdn := model.CreateFQDN(host)
+	ports := getHostPortsToProbe(host)
+	timeout := getProbeHostPortsTimeout()
+	errs := make([]error, len(ports))
+
+	// Ports are probed concurrently, so the host is not probed longer than the timeout
+	var wg sync.WaitGroup
+	for i := range ports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = probeHostPort(ctx, net.JoinHostPort(fqdn, strconv.Itoa(int(ports[i].port))), timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	var unreachable []string
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		unreachable = append(unreachable, ports[i].name)
+		w.a.WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(host.GetCHI()).
+			M(host).F().
+			Error("Host %s port %s:%d is unreachable from the operator. err: %v", host.GetName(), ports[i].name, ports[i].port, err)
+	}
+
+	if len(unreachable) > 0 {
+		return fmt.Errorf("host %s ports are unreachable: %v", host.GetName(), unreachable)
+	}
+
+	w.a.V(1).M(host).F().Info("Host %s ports are reachable: %d", host.GetName(), len(ports))
+	return nil
+}
+
+// probeHostPort dials the address until connection is established or timeout is reached
+func probeHostPort(ctx context.Context, address string, timeout time.Duration) error {
+	dialer := &net.Dialer{Timeout: probeHostPortDialTimeout}
+	start := time.Now()
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		if time.Since(start) > timeout {
+			return err
+		}
+		if util.WaitContextDoneOrTimeout(ctx, probeHostPortPollInterval) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// shouldRequestHostReplacement checks whether pod has just been annotated to have its host replaced
+func shouldRequestHostReplacement(old, new *core.Pod) bool {
+	if (old == nil) || (new == nil) {
+		return false
+	}
+	return !model.IsPodToReplace(old.ObjectMeta) && model.IsPodToReplace(new.ObjectMeta)
+}
+
+// requestHostReplacement lists the pod in replace-host annotation of the CHI the pod belongs to,
+// so the host is replaced by the regular reconcile of the CHI.
+// Pod annotation is not removed explicitly, since the pod is deleted along with the StatefulSet of the replaced host
+func (w *worker) requestHostReplacement(ctx context.Context, pod *core.Pod) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	chi, err := w.c.GetCHIByObjectMeta(&pod.ObjectMeta, false)
+	if err != nil {
+		w.a.V(1).M(pod).F().Warning("Unable to find CHI of the pod %s/%s to be replaced err: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+
+	value := model.AppendHostToReplace(chi.ObjectMeta, pod.Name)
+	if value == chi.GetAnnotations()[model.AnnotationReplaceHost] {
+		return
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileStarted).
+		M(chi).F().
+		Info("Pod %s/%s is annotated to be replaced", pod.Namespace, pod.Name)
+	_ = w.c.setCHIAnnotation(ctx, chi, model.AnnotationReplaceHost, value)
+}
+
+// acceptHostsReplacement announces hosts listed in replace-host annotation of the CHI.
+// Annotation is kept till replaced hosts are up and synced, so interrupted replacement is resumed by the next reconcile.
+// Names of hosts absent in the CHI are removed from the annotation, since there is nothing to replace.
+func (w *worker) acceptHostsReplacement(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if !model.HasHostsToReplace(chi.ObjectMeta) {
+		return
+	}
+
+	var known, unknown []string
+	for _, name := range model.GetHostsToReplace(chi.ObjectMeta) {
+		found := false
+		chi.WalkHosts(func(host *api.ChiHost) error {
+			found = found || model.IsHostNamed(host, name)
+			return nil
+		})
+		if found {
+			known = append(known, name)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileInProgress).
+		WithStatusAction(chi).
+		M(chi).F().
+		Info("Hosts to be replaced: %v", known)
+
+	if len(unknown) > 0 {
+		w.a.V(1).M(chi).F().Warning("Hosts to be replaced are not found: %v", unknown)
+		if len(known) == 0 {
+			_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationReplaceHost)
+		} else {
+			_ = w.c.setCHIAnnotation(ctx, chi, model.AnnotationReplaceHost, strings.Join(known, ","))
+		}
+	}
+}
+
+// hostReplacementMutex serializes updates of host replacement annotations of the CHI made by hosts reconciled concurrently
+var hostReplacementMutex sync.Mutex
+
+// updateHostReplacementAnnotation updates host replacement annotation of the latest CHI with the value built by the function.
+// Annotation is deleted in case empty value is built.
+func (w *worker) updateHostReplacementAnnotation(
+	ctx context.Context,
+	host *api.ChiHost,
+	annotation string,
+	build func(objectMeta meta.ObjectMeta) string,
+) error {
+	hostReplacementMutex.Lock()
+	defer hostReplacementMutex.Unlock()
+
+	chi, err := w.c.chopClient.ClickhouseV1().ClickHouseInstallations(host.GetCHI().Namespace).Get(ctx, host.GetCHI().Name, controller.NewGetOptions())
+	if err != nil {
+		return err
+	}
+	value := build(chi.ObjectMeta)
+	switch {
+	case value == chi.GetAnnotations()[annotation]:
+		return nil
+	case value == "":
+		return w.c.deleteCHIAnnotation(ctx, chi, annotation)
+	default:
+		return w.c.setCHIAnnotation(ctx, chi, annotation, value)
+	}
+}
+
+// checkHostReplacement ensures host can be wiped out w/o data loss. Shard has to have another healthy replica
+// to fetch data from and the host has to hold no data, which is not replicated to other hosts.
+// Replacement is refused unless CHI is annotated as allowed to lose data.
+// Unreachable host, such as the one on a failed disk or node, can not be verified for data, which is not replicated,
+// and such data can not be recovered from the host anyway, so healthy sibling replica is relied on in this case.
+func (w *worker) checkHostReplacement(ctx context.Context, host *api.ChiHost) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	var reasons []string
+	healthySibling := w.hasHealthySiblingReplica(ctx, host)
+	if !healthySibling {
+		reasons = append(reasons, "shard has no other healthy replica")
+	}
+	if _, e := w.ensureClusterSchemer(host).HostClickHouseVersion(ctx, host); (e != nil) && healthySibling {
+		w.a.V(1).M(host).F().Warning(
+			"Replace host: %s - host is unreachable, rely on healthy replica of the shard. err: %v", host.GetName(), e)
+	} else {
+		tables, err := w.ensureClusterSchemer(host).HostUnreplicatedDataTables(ctx, host)
+		switch {
+		case err != nil:
+			reasons = append(reasons, fmt.Sprintf("unable to verify tables: %v", err))
+		case len(tables) > 0:
+			reasons = append(reasons, "tables are not replicated: "+strings.Join(tables, ", "))
+		}
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	chi := host.GetCHI()
+	if model.IsForceDataLoss(chi.ObjectMeta) {
+		w.a.V(1).M(host).F().Warning(
+			"Replace host: %s - %s, proceed due to %s annotation",
+			host.GetName(), strings.Join(reasons, ", "), model.AnnotationForceDataLoss)
+		return nil
+	}
+
+	err := fmt.Errorf("%w: %s. Annotate CHI with %s to force replacement",
+		errHostReplacementDataLoss, strings.Join(reasons, ", "), model.AnnotationForceDataLoss)
+	w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+		WithStatusError(chi).
+		M(host).F().
+		Error("Replace host: %s - refuse to replace: %v", host.GetName(), err)
+	return err
+}
+
+// hasHealthySiblingReplica checks whether shard of the host has another replica, which is ready and serves queries
+func (w *worker) hasHealthySiblingReplica(ctx context.Context, host *api.ChiHost) bool {
+	healthy := false
+	host.GetShard().WalkHosts(func(sibling *api.ChiHost) error {
+		if healthy || (sibling == host) || model.IsHostToReplace(sibling) {
+			return nil
+		}
+		if pod, err := w.c.getPod(sibling); (err != nil) || !isPodReady(pod) {
+			return nil
+		}
+		if _, err := w.ensureClusterSchemer(sibling).HostClickHouseVersion(ctx, sibling); err != nil {
+			return nil
+		}
+		healthy = true
+		return nil
+	})
+	return healthy
+}
+
+// replaceHost wipes out StatefulSet and PVCs of the host, so the host would be created from scratch with the same identity
+func (w *worker) replaceHost(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	w.a.V(1).
+		WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileInProgress).
+		WithStatusAction(host.GetCHI()).
+		M(host).F().
+		Info("Replace host: %s - delete StatefulSet and PVCs", host.GetName())
+
+	_ = w.c.deleteStatefulSet(ctx, host)
+
+	// PVCs are deleted regardless of reclaim policy, since storage of the host is to be replaced
+	namespace := host.Runtime.Address.Namespace
+	w.c.walkDiscoveredPVCs(host, func(pvc *core.PersistentVolumeClaim) {
+		err := w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvc.Name, controller.NewDeleteOptions())
+		if (err != nil) && !apiErrors.IsNotFound(err) {
+			w.a.M(host).F().Error("FAIL to delete PVC %s/%s err: %v", namespace, pvc.Name, err)
+		}
+	})
+
+	// PVC being terminated would be reused by the new pod, so wait for PVCs to be gone
+	start := time.Now()
+	timeout := time.Duration(chop.Config().Reconcile.StatefulSet.Update.Timeout) * time.Second
+	if t := host.GetCHI().GetReconciling().GetTimeouts().GetStatefulSetUpdate(); t > 0 {
+		timeout = t
+	}
+	for {
+		pvcs := 0
+		w.c.walkDiscoveredPVCs(host, func(pvc *core.PersistentVolumeClaim) {
+			pvcs++
+		})
+		if pvcs == 0 {
+			break
+		}
+		if time.Since(start) > timeout {
+			w.a.V(1).M(host).F().Warning("Replace host: %s - PVCs are not deleted in time, proceed", host.GetName())
+			break
+		}
+		if util.WaitContextDoneOrTimeout(ctx, 5*time.Second) {
+			log.V(2).Info("task is done")
+			return
+		}
+	}
+
+	w.a.V(1).M(host).F().Info("Replace host: %s - StatefulSet and PVCs deleted", host.GetName())
+
+	// Host is not to be wiped out once again in case replacement is interrupted
+	_ = w.updateHostReplacementAnnotation(ctx, host, model.AnnotationReplaceHostWiped, func(objectMeta meta.ObjectMeta) string {
+		return model.AppendHostReplaceWiped(objectMeta, host)
+	})
+}
+
+// syncReplacedHost waits for replicated tables of the replaced host to fetch data from other replicas.
+// Host is removed from host replacement annotations of the CHI as soon as it is synced.
+func (w *worker) syncReplacedHost(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	// Replicated tables of the wiped host are read-only in case their keeper metadata outlived the host,
+	// so metadata has to be restored before the tables are able to fetch data
+	restored, err := w.ensureClusterSchemer(host).HostRestoreReplicas(ctx, host)
+	if err != nil {
+		w.a.WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(host.GetCHI()).
+			M(host).F().
+			Error("Replace host: %s - unable to restore replicas, to be retried by the next reconcile err: %v", host.GetName(), err)
+		return
+	}
+	if len(restored) > 0 {
+		w.a.V(1).M(host).F().Info("Replace host: %s - replicas restored: %v", host.GetName(), restored)
+	}
+
+	w.a.V(1).M(host).F().Info("Replace host: %s - wait for replicas to sync", host.GetName())
+	if err := w.ensureClusterSchemer(host).HostSyncTables(ctx, host); err != nil {
+		w.a.WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(host.GetCHI()).
+			M(host).F().
+			Error("Replace host: %s - unable to sync replicas, to be retried by the next reconcile err: %v", host.GetName(), err)
+		return
+	}
+
+	for _, annotation := range []string{
+		model.AnnotationReplaceHost,
+		model.AnnotationReplaceHostWiped,
+	} {
+		_ = w.updateHostReplacementAnnotation(ctx, host, annotation, func(objectMeta meta.ObjectMeta) string {
+			return model.RemoveHostFromList(objectMeta, annotation, host)
+		})
+	}
+
+	w.a.V(1).
+		WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileCompleted).
+		WithStatusAction(host.GetCHI()).
+		M(host).F().
+		Info("Replace host: %s - completed", host.GetName())
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// isRollingRestartPending checks whether the host is to be restarted by the rolling restart in progress.
+// Hosts restarted before the rolling restart got interrupted are not restarted again
+func (w *worker) isRollingRestartPending(host *api.ChiHost) bool {
+	switch {
+	case !model.IsRollingRestartRequested(host.GetCHI().ObjectMeta):
+		return false
+	case host.GetReconcileAttributes().GetStatus() == api.ObjectStatusNew:
+		// New host is not restarted
+		return false
+	case w.hasHostCondition(host, api.HostConditionTypeRollingRestarted):
+		return false
+	}
+	return true
+}
+
+// rollingRestartReasonRestarted specifies reason of RollingRestarted host condition
+const rollingRestartReasonRestarted = "Restarted"
+
+// waitRollingRestartedHostCaughtUp waits for replicated tables of the host restarted by rolling restart
+// to catch up with other replicas, so the next host is not restarted while the shard is short of up-to-date replicas.
+// Replication delay has to get under 'reconciling.maxReplicationDelay' of the CHI in case it is specified,
+// otherwise replicated tables have to have no replication delay and empty replication queue.
+// Restarted host is marked in status, so the rolling restart is resumed from the next host after interruption
+func (w *worker) waitRollingRestartedHostCaughtUp(ctx context.Context, host *api.ChiHost) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	if !w.isRollingRestartPending(host) || host.IsStopped() {
+		return nil
+	}
+
+	reconciling := host.GetCHI().GetReconciling()
+	w.a.V(1).M(host).F().Info("Rolling restart: %s - wait for replication lag to clear", host.GetName())
+	err := w.c.pollHost(ctx, host, nil, func(ctx context.Context, host *api.ChiHost) bool {
+		if reconciling.HasMaxReplicationDelay() {
+			delay, err := w.ensureClusterSchemer(host).HostMaxReplicationDelay(ctx, host)
+			return (err == nil) && (delay <= reconciling.GetMaxReplicationDelay())
+		}
+		n, err := w.ensureClusterSchemer(host).HostLaggingReplicasNum(ctx, host)
+		return (err == nil) && (n == 0)
+	})
+	if err != nil {
+		w.a.WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(host.GetCHI()).
+			M(host).F().
+			Error("Rolling restart: %s - replication lag did not clear, restart is stopped err: %v", host.GetName(), err)
+		return err
+	}
+
+	host.GetCHI().EnsureStatus().SetHostCondition(host, meta.Condition{
+		Type:               api.HostConditionTypeRollingRestarted,
+		Status:             meta.ConditionTrue,
+		Reason:             rollingRestartReasonRestarted,
+		Message:            "host is restarted and replication lag is cleared",
+		LastTransitionTime: meta.Now(),
+	})
+	_ = w.c.updateCHIObjectStatus(ctx, host.GetCHI(), UpdateCHIStatusOptions{
+		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+			MainFields: true,
+		},
+	})
+
+	w.a.V(1).M(host).F().Info("Rolling restart: %s - replication lag cleared", host.GetName())
+	return nil
+}
+
+// completeRollingRestart removes rolling restart annotation of the CHI, which has all hosts restarted,
+// along with progress of the rolling restart. Progress of the rolling restart cancelled by removal of the annotation
+// is removed as well, so the next rolling restart restarts all hosts
+func (w *worker) completeRollingRestart(ctx context.Context, chi *api.ClickHouseInstallation) {
+	chi.EnsureStatus().RemoveHostsCondition(api.HostConditionTypeRollingRestarted)
+
+	if !model.IsRollingRestartRequested(chi.ObjectMeta) {
+		return
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileCompleted).
+		WithStatusAction(chi).
+		M(chi).F().
+		Info("Rolling restart completed")
+	_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationRestart)
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// Switching zookeeper config of a cluster to a ClickHouseKeeperInstallation, which has no metadata of the cluster yet,
+// turns replicated tables read-only as soon as hosts are restarted with the new config.
+// Keeper migration restores metadata of such tables in the new keeper out of the local data of each host.
+// Migration of the cluster proceeds host by host and is tracked in CHI status, so it is resumed in case
+// reconcile is interrupted.
+
+// getZookeeperCHK gets namespace/name of the ClickHouseKeeperInstallation the zookeeper config refers to,
+// empty string in case it does not refer to any CHK
+func (w *worker) getZookeeperCHK(ctx context.Context, zookeeper *api.ChiZookeeperConfig, namespace string) string {
+	if zookeeper == nil {
+		return ""
+	}
+	for i := range zookeeper.Nodes {
+		ns, name := parseKeeperNodeHost(zookeeper.Nodes[i].Host, namespace)
+		if name == "" {
+			continue
+		}
+		if chk := w.getCHK(ctx, ns, name); chk != nil {
+			return ns + "/" + name
+		}
+	}
+	return ""
+}
+
+// isZookeeperReferringCHK checks whether any of zookeeper nodes refers to the specified CHK
+func isZookeeperReferringCHK(zookeeper *api.ChiZookeeperConfig, namespace, chk string) bool {
+	if zookeeper == nil {
+		return false
+	}
+	for i := range zookeeper.Nodes {
+		ns, name := parseKeeperNodeHost(zookeeper.Nodes[i].Host, namespace)
+		if ns+"/"+name == chk {
+			return true
+		}
+	}
+	return false
+}
+
+// startKeeperMigrations starts migration of each cluster, which has zookeeper config switched to a CHK,
+// or resumes migration which is not completed yet
+func (w *worker) startKeeperMigrations(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		w.startKeeperMigration(ctx, cluster)
+		return nil
+	})
+}
+
+// startKeeperMigration starts or resumes migration of the cluster
+func (w *worker) startKeeperMigration(ctx context.Context, cluster *api.Cluster) {
+	chi := cluster.GetCHI()
+	keeper := w.getZookeeperCHK(ctx, cluster.Zookeeper, chi.Namespace)
+	if keeper == "" {
+		return
+	}
+
+	migration := chi.EnsureStatus().GetKeeperMigration(cluster.Name)
+	if (migration != nil) && (migration.Keeper == keeper) && (migration.State != api.KeeperMigrationStateCompleted) {
+		// Migration is interrupted or failed, migrated hosts are kept and the rest of hosts are migrated
+		migration.State = api.KeeperMigrationStateInProgress
+		migration.Error = ""
+		chi.EnsureStatus().SetKeeperMigration(*migration)
+		w.a.V(1).M(chi).F().Info("Keeper migration of cluster %s to %s resumed. Hosts migrated: %d/%d",
+			cluster.Name, keeper, len(migration.Hosts), cluster.HostsCount())
+		return
+	}
+
+	if !chi.HasAncestor() {
+		return
+	}
+	ancestor := chi.GetAncestor().FindCluster(cluster.Name)
+	if (ancestor == nil) || ancestor.Zookeeper.IsEmpty() || isZookeeperReferringCHK(ancestor.Zookeeper, chi.Namespace, keeper) {
+		// New cluster or the cluster has been using the CHK already, nothing to migrate
+		return
+	}
+
+	chi.EnsureStatus().SetKeeperMigration(api.ChiKeeperMigration{
+		Cluster:   cluster.Name,
+		Keeper:    keeper,
+		State:     api.KeeperMigrationStateInProgress,
+		StartTime: time.Now().Format(time.RFC3339),
+	})
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonReconcileStarted).
+		WithStatusAction(chi).
+		M(chi).F().
+		Info("Keeper migration of cluster %s to %s started. Hosts are migrated one by one", cluster.Name, keeper)
+}
+
+// isKeeperMigrationInProgress checks whether any cluster of the CHI is being migrated to keeper
+func (w *worker) isKeeperMigrationInProgress(chi *api.ClickHouseInstallation) bool {
+	inProgress := false
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		if chi.EnsureStatus().GetKeeperMigration(cluster.Name).IsInProgress() {
+			inProgress = true
+		}
+		return nil
+	})
+	return inProgress
+}
+
+// migrateHostToKeeper restores metadata of replicated tables of the host in the keeper the cluster is migrated to
+func (w *worker) migrateHostToKeeper(ctx context.Context, host *api.ChiHost) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	chi := host.GetCHI()
+	cluster := host.GetCluster()
+	migration := chi.EnsureStatus().GetKeeperMigration(cluster.Name)
+	fqdn := model.CreateFQDN(host)
+	if !migration.IsInProgress() || migration.HasHost(fqdn) || host.IsStopped() {
+		return nil
+	}
+
+	w.a.V(1).M(host).F().Info("Keeper migration: %s - wait for keeper session", host.GetName())
+	clusterSchemer := w.ensureClusterSchemer(host)
+	err := w.c.pollHost(ctx, host, nil, func(ctx context.Context, host *api.ChiHost) bool {
+		return clusterSchemer.HostKeeperAvailable(ctx, host)
+	})
+	var tables []string
+	if err == nil {
+		tables, err = clusterSchemer.HostRestoreReplicas(ctx, host)
+	}
+	migration.Tables += len(tables)
+
+	if err != nil {
+		migration.State = api.KeeperMigrationStateFailed
+		migration.Error = err.Error()
+		migration.EndTime = time.Now().Format(time.RFC3339)
+		chi.EnsureStatus().SetKeeperMigration(*migration)
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusError(chi).
+			M(host).F().
+			Error("Keeper migration: %s - failed, migration of cluster %s is stopped err: %v", host.GetName(), cluster.Name, err)
+		return err
+	}
+
+	migration.Hosts = append(migration.Hosts, fqdn)
+	w.a.V(1).M(host).F().Info("Keeper migration: %s - tables restored: %d", host.GetName(), len(tables))
+	if len(migration.Hosts) >= cluster.HostsCount() {
+		migration.State = api.KeeperMigrationStateCompleted
+		migration.EndTime = time.Now().Format(time.RFC3339)
+		w.a.V(1).
+			WithEvent(chi, eventActionReconcile, eventReasonReconcileCompleted).
+			WithStatusAction(chi).
+			M(chi).F().
+			Info("Keeper migration of cluster %s to %s completed. Tables restored: %d", cluster.Name, migration.Keeper, migration.Tables)
+	}
+	chi.EnsureStatus().SetKeeperMigration(*migration)
+	return nil
+}
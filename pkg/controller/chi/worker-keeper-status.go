@@ -0,0 +1,228 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	modelChk "github.com/altinity/clickhouse-operator/pkg/model/chk"
+	"github.com/altinity/clickhouse-operator/pkg/model/chk/keeper"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// keeperStatusTimeout specifies timeout of each request to keeper members
+const keeperStatusTimeout = 5 * time.Second
+
+// Reasons of KeeperHealthy condition
+const (
+	keeperReasonQuorumHealthy  = "QuorumHealthy"
+	keeperReasonQuorumDegraded = "QuorumDegraded"
+	keeperReasonQuorumLost     = "QuorumLost"
+	keeperReasonNoLeader       = "NoLeader"
+)
+
+// chkResource specifies resource of ClickHouseKeeperInstallation
+var chkResource = apiChk.SchemeGroupVersion.WithResource("clickhousekeeperinstallations")
+
+// keeperEnsembleStatus describes health of a keeper ensemble
+type keeperEnsembleStatus struct {
+	chk     *apiChk.ClickHouseKeeperInstallation
+	members int
+	quorum  int
+	leader  string
+	lag     int64
+	errors  []string
+}
+
+// isHealthy checks whether ensemble has leader and majority of members in quorum
+func (s *keeperEnsembleStatus) isHealthy() bool {
+	return (s.leader != "") && (s.quorum > s.members/2)
+}
+
+// reason gets reason of the condition describing the ensemble
+func (s *keeperEnsembleStatus) reason() string {
+	switch {
+	case s.leader == "":
+		return keeperReasonNoLeader
+	case s.quorum <= s.members/2:
+		return keeperReasonQuorumLost
+	case s.quorum < s.members:
+		return keeperReasonQuorumDegraded
+	}
+	return keeperReasonQuorumHealthy
+}
+
+// String describes the ensemble
+func (s *keeperEnsembleStatus) String() string {
+	leader := s.leader
+	if leader == "" {
+		leader = "none"
+	}
+	str := fmt.Sprintf("%s/%s: leader %s, quorum %d/%d members, max lag %d log entries",
+		s.chk.Namespace, s.chk.Name, leader, s.quorum, s.members, s.lag)
+	if len(s.errors) > 0 {
+		str += ", unavailable: " + strings.Join(s.errors, ", ")
+	}
+	return str
+}
+
+// reconcileKeeperCondition surfaces health of the ClickHouseKeeperInstallation ensembles the CHI is wired to
+// as KeeperHealthy condition of the CHI status, so keeper problems are visible without checking CHK.
+// CHI is considered wired to CHK in case any of its zookeeper nodes refers to the CHK services.
+// Condition is refreshed by every reconcile, including the ones having nothing to do or failing, and is persisted right away
+func (w *worker) reconcileKeeperCondition(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	defer func() {
+		_ = w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
+			TolerateAbsence: true,
+			CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+				Keeper: true,
+			},
+		})
+	}()
+
+	chks := w.getWiredCHKs(ctx, chi)
+	if len(chks) == 0 {
+		chi.EnsureStatus().RemoveCondition(api.ConditionTypeKeeperHealthy)
+		return
+	}
+
+	condition := meta.Condition{
+		Type:               api.ConditionTypeKeeperHealthy,
+		Status:             meta.ConditionTrue,
+		Reason:             keeperReasonQuorumHealthy,
+		ObservedGeneration: chi.Generation,
+	}
+	var messages []string
+	for _, chk := range chks {
+		status := getKeeperEnsembleStatus(ctx, chk)
+		messages = append(messages, status.String())
+		if status.reason() == keeperReasonQuorumHealthy {
+			continue
+		}
+		// The worst ensemble determines the condition
+		if !status.isHealthy() {
+			condition.Status = meta.ConditionFalse
+			condition.Reason = status.reason()
+		} else if condition.Status == meta.ConditionTrue {
+			condition.Reason = status.reason()
+		}
+	}
+	condition.Message = strings.Join(messages, "; ")
+
+	chi.EnsureStatus().SetCondition(condition)
+	if condition.Status == meta.ConditionTrue {
+		w.a.V(1).M(chi).F().Info("Keeper is healthy: %s", condition.Message)
+	} else {
+		w.a.V(1).M(chi).F().Warning("Keeper is unhealthy: %s", condition.Message)
+	}
+}
+
+// getWiredCHKs gets ClickHouseKeeperInstallations referred by zookeeper nodes of the CHI
+func (w *worker) getWiredCHKs(ctx context.Context, chi *api.ClickHouseInstallation) (chks []*apiChk.ClickHouseKeeperInstallation) {
+	seen := make(map[string]bool)
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		if cluster.Zookeeper == nil {
+			return nil
+		}
+		for i := range cluster.Zookeeper.Nodes {
+			namespace, name := parseKeeperNodeHost(cluster.Zookeeper.Nodes[i].Host, chi.Namespace)
+			key := namespace + "/" + name
+			if (name == "") || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if chk := w.getCHK(ctx, namespace, name); chk != nil {
+				chks = append(chks, chk)
+			}
+		}
+		return nil
+	})
+	return chks
+}
+
+// parseKeeperNodeHost gets namespace and name of the CHK, which may be referred by zookeeper node host.
+// Host may be either the CHK client service, such as 'chk', 'chk.ns' or 'chk.ns.svc.cluster.local',
+// or a member behind the CHK headless service, such as 'chk-0.chk-headless.ns.svc.cluster.local'
+func parseKeeperNodeHost(host, namespace string) (string, string) {
+	labels := strings.Split(host, ".")
+	name := labels[0]
+	next := 1
+	if (len(labels) > 1) && strings.HasSuffix(labels[1], "-headless") {
+		name = strings.TrimSuffix(labels[1], "-headless")
+		next = 2
+	}
+	if (len(labels) > next) && (labels[next] != "svc") {
+		namespace = labels[next]
+	}
+	return namespace, name
+}
+
+// getCHK gets ClickHouseKeeperInstallation, nil in case it is not found
+func (w *worker) getCHK(ctx context.Context, namespace, name string) *apiChk.ClickHouseKeeperInstallation {
+	obj, err := w.c.dynamicClient.Resource(chkResource).Namespace(namespace).Get(ctx, name, controller.NewGetOptions())
+	if err != nil {
+		// Not a CHK or CHK CRD is not installed
+		return nil
+	}
+	chk := &apiChk.ClickHouseKeeperInstallation{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), chk); err != nil {
+		log.V(1).Warning("unable to convert CHK %s/%s err: %v", namespace, name, err)
+		return nil
+	}
+	return chk
+}
+
+// getKeeperEnsembleStatus polls all members of the CHK for their raft state and log lag
+func getKeeperEnsembleStatus(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation) *keeperEnsembleStatus {
+	status := &keeperEnsembleStatus{
+		chk:     chk,
+		members: modelChk.GetReplicasCount(chk),
+	}
+	for id := 0; id < status.members; id++ {
+		client := keeper.NewClient(modelChk.GetMemberHostname(chk, id), chk.Spec.GetClientPort()).SetTimeout(keeperStatusTimeout)
+		state, err := client.ServerState(ctx)
+		if err != nil {
+			status.errors = append(status.errors, modelChk.GetMemberPodName(chk, id))
+			continue
+		}
+		switch state {
+		case keeper.ServerStateLeader, keeper.ServerStateStandalone:
+			status.leader = modelChk.GetMemberPodName(chk, id)
+			status.quorum++
+		case keeper.ServerStateFollower:
+			status.quorum++
+		}
+		// Lag is not available in old keeper versions, which do not support 'lgif' command
+		if lag, err := client.CommitLag(ctx); (err == nil) && (lag > status.lag) {
+			status.lag = lag
+		}
+	}
+	return status
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/schemer"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// partsBacklogPollInterval specifies how often parts backlog of a host is polled while waiting for merges
+const partsBacklogPollInterval = 10 * time.Second
+
+// shouldWaitPartsBacklog determines whether parts backlog of the host is to be checked before the host is restarted
+func (w *worker) shouldWaitPartsBacklog(host *api.ChiHost) bool {
+	if !host.GetCHI().GetReconciling().GetPartsBacklog().IsEnabled() {
+		return false
+	}
+	return w.isHostToBeRestarted(host)
+}
+
+// waitHostPartsBacklog waits for merges to get number of active parts of each partition of the host under the threshold
+// specified by CHI 'reconciling.partsBacklog' setting, optionally optimizing partitions over the threshold.
+// Host is restarted anyway after timeout, so reconcile is not blocked forever by a host unable to merge its parts
+func (w *worker) waitHostPartsBacklog(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	if !w.shouldWaitPartsBacklog(host) {
+		return
+	}
+
+	backlog := host.GetCHI().GetReconciling().GetPartsBacklog()
+	threshold := backlog.GetThreshold()
+	s := w.ensureClusterSchemer(host)
+	optimized := false
+	deadline := time.Now().Add(backlog.GetTimeout())
+
+	for {
+		partitions, err := s.HostPartsBacklog(ctx, host, threshold)
+		if err != nil {
+			// Host may be unreachable, crash-looping for example, restart is the way to fix it
+			w.a.V(1).M(host).F().Warning("Unable to check parts backlog, proceed. Host: %s err: %v", host.GetName(), err)
+			return
+		}
+		if len(partitions) == 0 {
+			w.a.V(1).M(host).F().Info("Parts backlog is under %d parts per partition. Host: %s", threshold, host.GetName())
+			return
+		}
+
+		w.a.V(1).
+			M(host).F().
+			Info("Parts backlog is over %d parts per partition, wait for merges. Host: %s partitions: %s",
+				threshold, host.GetName(), describePartsBacklog(partitions))
+
+		if backlog.IsOptimize() && !optimized {
+			// Partitions are optimized once, the rest of the time merges are waited for
+			optimized = true
+			if _, err := s.HostOptimizePartitions(ctx, host, partitions); err != nil {
+				w.a.V(1).M(host).F().Warning("Unable to optimize partitions. Host: %s err: %v", host.GetName(), err)
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			w.a.V(1).
+				WithEvent(host.GetCHI(), eventActionReconcile, eventReasonPartsBacklogTimeout).
+				M(host).F().
+				Warning("Parts backlog did not get under %d parts per partition in time, restart host anyway. Host: %s partitions: %s",
+					threshold, host.GetName(), describePartsBacklog(partitions))
+			return
+		}
+
+		if util.WaitContextDoneOrTimeout(ctx, partsBacklogPollInterval) {
+			log.V(2).Info("task is done")
+			return
+		}
+	}
+}
+
+// describePartsBacklog describes partitions over the threshold for logs and events
+func describePartsBacklog(partitions []schemer.PartsBacklogPartition) string {
+	var descriptions []string
+	for _, partition := range partitions {
+		descriptions = append(descriptions, fmt.Sprintf("%s.%s:%s parts: %d merges: %d",
+			partition.Database, partition.Table, partition.PartitionID, partition.Parts, partition.Merges))
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,244 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	policy "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/retry"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// deleteClusterStalePDBs deletes PodDisruptionBudgets of the cluster, which are not listed as desired,
+// such as PDBs of the other kind left after the PerShardPDB feature gate is switched, or PDBs of removed shards
+func (w *worker) deleteClusterStalePDBs(ctx context.Context, cluster *api.Cluster, desired []*policy.PodDisruptionBudget) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	namespace := cluster.Runtime.Address.Namespace
+	list, err := w.c.kubeClient.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, controller.NewListOptions(model.GetSelectorClusterScope(cluster)))
+	if err != nil {
+		w.a.V(1).M(cluster).F().Warning("unable to list PDBs of the cluster: %s err: %v", cluster.Name, err)
+		return
+	}
+
+	names := make(map[string]bool)
+	for _, pdb := range desired {
+		names[pdb.Name] = true
+	}
+	for i := range list.Items {
+		pdb := &list.Items[i]
+		if names[pdb.Name] || !model.IsCHOPGeneratedObject(&pdb.ObjectMeta) {
+			continue
+		}
+		if err := w.c.kubeClient.PolicyV1().PodDisruptionBudgets(namespace).Delete(ctx, pdb.Name, controller.NewDeleteOptions()); err != nil {
+			w.a.V(1).M(cluster).F().Warning("unable to delete stale PDB %s/%s err: %v", namespace, pdb.Name, err)
+			continue
+		}
+		w.a.V(1).M(cluster).F().Info("deleted stale PDB %s/%s", namespace, pdb.Name)
+	}
+}
+
+// shouldSuspendHostPDB determines whether PodDisruptionBudget should be extended for the host
+// The host is going to be restarted by the operator only in case it already exists and is modified
+func (w *worker) shouldSuspendHostPDB(host *api.ChiHost) bool {
+	if !chop.Config().Reconcile.Host.PDB.Suspend.Value() {
+		return false
+	}
+	return host.GetReconcileAttributes().GetStatus() == api.ObjectStatusModified
+}
+
+// getHostPDBName gets name of the PodDisruptionBudget the host is covered by
+func (w *worker) getHostPDBName(host *api.ChiHost) string {
+	if chop.Config().IsFeatureEnabled(api.FeatureGatePerShardPDB) {
+		return w.task.creator.NewPodDisruptionBudgetShard(host.GetShard()).Name
+	}
+	return w.task.creator.NewPodDisruptionBudget(host.GetCluster()).Name
+}
+
+// suspendHostPDB extends PodDisruptionBudget of the host by one more unavailable pod,
+// so the host restarted by the operator does not consume budget of external voluntary disruptions.
+// PDB is not extended in case external disruptions of the budget could take down the rest of the host's shard.
+func (w *worker) suspendHostPDB(ctx context.Context, host *api.ChiHost) {
+	w.a.V(1).M(host).F().Info("suspend PDB for host: %s", host.GetName())
+	w.updateHostPDB(ctx, host, func(pdb *policy.PodDisruptionBudget) bool {
+		if !isPDBAdjustable(pdb) {
+			w.a.V(1).M(host).F().Warning("PDB %s/%s has no integer maxUnavailable, skip it", pdb.Namespace, pdb.Name)
+			return false
+		}
+		if !isHostPDBSuspendSafe(pdb, host) {
+			w.a.V(1).M(host).F().Info("PDB %s/%s can not be extended without risk for siblings of the host: %s", pdb.Namespace, pdb.Name, host.GetName())
+			return false
+		}
+		return adjustPDB(pdb, func(hosts []string) []string {
+			return util.Unique(append(hosts, host.GetName()))
+		})
+	})
+}
+
+// restoreHostPDB reverts extension of PodDisruptionBudget made for the host.
+// PDB has to be restored even in case reconcile is cancelled, thus own context is used.
+func (w *worker) restoreHostPDB(host *api.ChiHost) {
+	w.a.V(1).M(host).F().Info("restore PDB for host: %s", host.GetName())
+	w.updateHostPDB(context.Background(), host, func(pdb *policy.PodDisruptionBudget) bool {
+		return adjustPDB(pdb, func(hosts []string) []string {
+			return util.RemoveFromArray(host.GetName(), hosts)
+		})
+	})
+}
+
+// updateHostPDB updates PodDisruptionBudget the host is covered by with the specified update function.
+// Update function returns false in case PDB should not be updated.
+// Hosts of different shards may be reconciled concurrently, thus update is retried on conflict.
+func (w *worker) updateHostPDB(ctx context.Context, host *api.ChiHost, update func(pdb *policy.PodDisruptionBudget) bool) {
+	if util.IsContextDone(ctx) {
+		return
+	}
+
+	namespace := host.GetCHI().Namespace
+	name := w.getHostPDBName(host)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pdb, err := w.c.kubeClient.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, controller.NewGetOptions())
+		if err != nil {
+			return err
+		}
+		if !update(pdb) {
+			return nil
+		}
+		_, err = w.c.kubeClient.PolicyV1().PodDisruptionBudgets(namespace).Update(ctx, pdb, controller.NewUpdateOptions())
+		return err
+	})
+	if err != nil {
+		w.a.V(1).M(host).F().Warning("unable to update PDB %s/%s err: %v", namespace, name, err)
+	}
+}
+
+// restoreSuspendedPDBs restores PodDisruptionBudgets left extended by hosts' rollout interrupted by operator restart.
+// No host is being restarted by the operator on startup, so all extensions are stale.
+func (c *Controller) restoreSuspendedPDBs(ctx context.Context) {
+	chis, err := c.chiLister.List(labels.Everything())
+	if err != nil {
+		log.V(1).F().Warning("unable to list CHIs err: %v", err)
+		return
+	}
+	for _, chi := range chis {
+		if !chop.Config().IsWatchedNamespace(chi.Namespace) {
+			continue
+		}
+		opts := controller.NewListOptions(model.NewLabeler(chi).GetSelectorCHIScope())
+		list, err := c.kubeClient.PolicyV1().PodDisruptionBudgets(chi.Namespace).List(ctx, opts)
+		if err != nil {
+			log.V(1).M(chi).F().Warning("unable to list PDBs err: %v", err)
+			continue
+		}
+		for i := range list.Items {
+			pdb := &list.Items[i]
+			if _, ok := pdb.Annotations[model.AnnotationPDBSuspendedBy]; !ok {
+				continue
+			}
+			log.V(1).M(chi).F().Info("restore PDB %s/%s suspended by: %s", pdb.Namespace, pdb.Name, pdb.Annotations[model.AnnotationPDBSuspendedBy])
+			if !adjustPDB(pdb, func([]string) []string { return nil }) {
+				continue
+			}
+			if _, err := c.kubeClient.PolicyV1().PodDisruptionBudgets(pdb.Namespace).Update(ctx, pdb, controller.NewUpdateOptions()); err != nil {
+				log.V(1).M(chi).F().Warning("unable to restore PDB %s/%s err: %v", pdb.Namespace, pdb.Name, err)
+			}
+		}
+	}
+}
+
+// isPDBAdjustable checks whether PDB has integer maxUnavailable, which can be extended
+func isPDBAdjustable(pdb *policy.PodDisruptionBudget) bool {
+	return (pdb.Spec.MaxUnavailable != nil) && (pdb.Spec.MaxUnavailable.Type == intstr.Int)
+}
+
+// getPDBOriginalMaxUnavailable gets maxUnavailable of the PDB as it was before any extension
+func getPDBOriginalMaxUnavailable(pdb *policy.PodDisruptionBudget) int32 {
+	if original, err := strconv.Atoi(pdb.Annotations[model.AnnotationPDBMaxUnavailable]); err == nil {
+		return int32(original)
+	}
+	return pdb.Spec.MaxUnavailable.IntVal
+}
+
+// isHostPDBSuspendSafe checks whether PDB can be extended for the host.
+// Extended PDB still allows the original number of external disruptions while the host is restarted,
+// and all of them may hit the host's shard, so the shard has to have replicas to survive both.
+func isHostPDBSuspendSafe(pdb *policy.PodDisruptionBudget, host *api.ChiHost) bool {
+	if !isPDBAdjustable(pdb) {
+		return false
+	}
+
+	// Hosts of the shard restarted by the operator, including the host itself
+	restarted := map[string]bool{
+		host.GetName(): true,
+	}
+	if value := pdb.Annotations[model.AnnotationPDBSuspendedBy]; value != "" {
+		for _, name := range strings.Split(value, ",") {
+			for _, sibling := range host.GetShard().Hosts {
+				if sibling.GetName() == name {
+					restarted[name] = true
+				}
+			}
+		}
+	}
+
+	unavailable := int32(len(restarted)) + getPDBOriginalMaxUnavailable(pdb)
+	return unavailable < int32(len(host.GetShard().Hosts))
+}
+
+// adjustPDB applies update to the list of hosts the PDB is extended for.
+// Returns false in case PDB can not be adjusted.
+func adjustPDB(pdb *policy.PodDisruptionBudget, update func(hosts []string) []string) bool {
+	if !isPDBAdjustable(pdb) {
+		return false
+	}
+
+	var hosts []string
+	if value := pdb.Annotations[model.AnnotationPDBSuspendedBy]; value != "" {
+		hosts = strings.Split(value, ",")
+	}
+	maxUnavailable := getPDBOriginalMaxUnavailable(pdb)
+
+	hosts = update(hosts)
+
+	if len(hosts) == 0 {
+		// Nothing is suspended anymore, restore original PDB
+		delete(pdb.Annotations, model.AnnotationPDBSuspendedBy)
+		delete(pdb.Annotations, model.AnnotationPDBMaxUnavailable)
+		pdb.Spec.MaxUnavailable = &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable}
+		return true
+	}
+
+	if pdb.Annotations == nil {
+		pdb.Annotations = make(map[string]string)
+	}
+	pdb.Annotations[model.AnnotationPDBSuspendedBy] = strings.Join(hosts, ",")
+	pdb.Annotations[model.AnnotationPDBMaxUnavailable] = strconv.Itoa(int(maxUnavailable))
+	pdb.Spec.MaxUnavailable = &intstr.IntOrString{Type: intstr.Int, IntVal: maxUnavailable + int32(len(hosts))}
+	return true
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// isHostToBeRestarted determines whether running ClickHouse of the host is expected to be restarted by the reconcile
+func (w *worker) isHostToBeRestarted(host *api.ChiHost) bool {
+	switch {
+	case host.IsStopped():
+		// Host is stopped on purpose, nothing to protect its startup from
+		return false
+	case w.isVerifyOnlyHost(host):
+		// Host would not be updated
+		return false
+	case w.shouldForceRestartHost(host):
+		return true
+	case host.GetReconcileAttributes().GetStatus() == api.ObjectStatusNew:
+		// New host has no parts
+		return false
+	case host.GetReconcileAttributes().GetStatus() == api.ObjectStatusSame:
+		// The same host would not be restarted
+		return false
+	}
+	return true
+}
+
+// runHostPreRestartActions performs actions specified by CHI 'reconciling.preRestartActions' setting
+// on the host about to be restarted, so the host shuts down faster.
+// Returns whether any action has been performed and has to be reverted after reconcile
+func (w *worker) runHostPreRestartActions(ctx context.Context, host *api.ChiHost) bool {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return false
+	}
+
+	actions := host.GetCHI().GetReconciling().GetPreRestartActions()
+	if !actions.IsEnabled() || !w.isHostToBeRestarted(host) {
+		return false
+	}
+
+	s := w.ensureClusterSchemer(host)
+	performed := false
+	if actions.IsStopMerges() {
+		if err := s.HostStopMerges(ctx, host); err == nil {
+			performed = true
+		} else {
+			// Host may be unreachable, crash-looping for example, restart is the way to fix it
+			w.a.V(1).M(host).F().Warning("Unable to stop merges, proceed. Host: %s err: %v", host.GetName(), err)
+		}
+	}
+	if actions.IsStopFetches() {
+		if err := s.HostStopFetches(ctx, host); err == nil {
+			performed = true
+		} else {
+			w.a.V(1).M(host).F().Warning("Unable to stop fetches, proceed. Host: %s err: %v", host.GetName(), err)
+		}
+	}
+
+	if performed {
+		w.a.V(1).M(host).F().Info("Pre-restart actions performed. Host: %s", host.GetName())
+	}
+	return performed
+}
+
+// preRestartActionsRevertTimeout specifies how long reverting of pre-restart actions may take
+const preRestartActionsRevertTimeout = 1 * time.Minute
+
+// revertHostPreRestartActions starts back merges and fetches stopped by pre-restart actions.
+// Restarted ClickHouse starts them on its own, however the host may happen to be not restarted by the reconcile.
+// Actions are reverted with a context of their own, so merges and fetches are not left stopped
+// in case the reconcile is interrupted
+func (w *worker) revertHostPreRestartActions(host *api.ChiHost) {
+	ctx, cancel := context.WithTimeout(context.Background(), preRestartActionsRevertTimeout)
+	defer cancel()
+
+	actions := host.GetCHI().GetReconciling().GetPreRestartActions()
+	s := w.ensureClusterSchemer(host)
+	if actions.IsStopMerges() {
+		if err := s.HostStartMerges(ctx, host); err != nil {
+			w.a.V(1).M(host).F().Warning("Unable to start merges. Host: %s err: %v", host.GetName(), err)
+		}
+	}
+	if actions.IsStopFetches() {
+		if err := s.HostStartFetches(ctx, host); err != nil {
+			w.a.V(1).M(host).F().Warning("Unable to start fetches. Host: %s err: %v", host.GetName(), err)
+		}
+	}
+}
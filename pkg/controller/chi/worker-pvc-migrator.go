@@ -0,0 +1,474 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+const (
+	// pvcMigrationSuffix is appended to the name of the PVC to build names of migration PVC and Job
+	pvcMigrationSuffix = "-migration"
+	// pvcMigrationSyncSuffix is appended to the name of the migration Job to build name of the Job,
+	// which syncs data changed during the copy after the host is stopped
+	pvcMigrationSyncSuffix = "-sync"
+	// pvcMigrationTimeout specifies how long to wait for data copy to complete
+	pvcMigrationTimeout = 6 * time.Hour
+	// pvcMigrationBindTimeout specifies how long to wait for PVC to be bound
+	pvcMigrationBindTimeout = 5 * time.Minute
+	// pvcMigrationPollInterval specifies how often to check data copy progress
+	pvcMigrationPollInterval = 10 * time.Second
+
+	pvcMigrationSourcePath = "/mnt/source"
+	pvcMigrationTargetPath = "/mnt/target"
+)
+
+// migrateHostPVCs migrates data of all host's PVCs which require migration to the new storage.
+// Migration is required in case PVC can not be updated in-place, such as storage class change,
+// and is explicitly requested by VolumeClaimTemplate's `migration: Copy`.
+func (w *worker) migrateHostPVCs(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	host.WalkVolumeMounts(api.DesiredStatefulSet, func(volumeMount *core.VolumeMount) {
+		if util.IsContextDone(ctx) {
+			return
+		}
+
+		pvc, volumeClaimTemplate, isModelCreated, err := w.fetchPVC(ctx, host, volumeMount)
+		if (err != nil) || (pvc == nil) || isModelCreated {
+			// No existing PVC - nothing to migrate
+			return
+		}
+
+		if !volumeClaimTemplate.Migration.IsCopy() || !isPVCStorageClassChanged(pvc, volumeClaimTemplate) {
+			// No migration required
+			return
+		}
+
+		if err := w.migratePVC(ctx, host, pvc, volumeClaimTemplate); err != nil {
+			w.a.V(1).
+				WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileFailed).
+				WithStatusAction(host.GetCHI()).
+				M(host).F().
+				Error("FAILED to migrate PVC %s/%s. Host: %s err: %v", pvc.Namespace, pvc.Name, host.GetName(), err)
+		}
+	})
+}
+
+// isPVCStorageClassChanged checks whether storage class of existing PVC differs from the one requested by the template
+func isPVCStorageClassChanged(pvc *core.PersistentVolumeClaim, template *api.VolumeClaimTemplate) bool {
+	desired := template.Spec.StorageClassName
+	if desired == nil {
+		// No explicit storage class requested, cluster default is used
+		return false
+	}
+	current := pvc.Spec.StorageClassName
+	return (current == nil) || (*current != *desired)
+}
+
+// migratePVC copies data from the existing PVC into new PVC of the requested storage class and
+// replaces existing PVC with the new one, keeping PVC name intact.
+// The following steps are performed:
+//  1. migration PVC is created from the template. Existence of the migration PVC tells migration is in progress
+//  2. data is copied by a Job which mounts both PVCs while the host is running, migration PVC is marked as copied
+//  3. host's StatefulSet is deleted in order to release the PVC and data changed meanwhile is synced by another Job
+//  4. PVs of both the original and the migration PVCs are retained, so no data is lost while PVCs are switched
+//  5. PV with copied data is rebound to the new PVC with the original name, which is verified to be bound
+//  6. Original reclaim policies are restored, so the original PV is released along its reclaim policy
+//
+// Interrupted migration is resumed from the migration PVC, so data which is copied already is not copied once again.
+// In case of copy failure, the migration Job and PVC are deleted and the original PVC is kept intact.
+// Host is stopped only after the data is copied, so failed copy does not stop the host.
+// In case of failure while switching, the original PV is rebound to the PVC with the original name
+// and PV with copied data is discarded.
+func (w *worker) migratePVC(
+	ctx context.Context,
+	host *api.ChiHost,
+	pvc *core.PersistentVolumeClaim,
+	template *api.VolumeClaimTemplate,
+) error {
+	namespace := pvc.Namespace
+	migrationName := pvc.Name + pvcMigrationSuffix
+	syncName := migrationName + pvcMigrationSyncSuffix
+
+	w.a.V(1).
+		WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileInProgress).
+		WithStatusAction(host.GetCHI()).
+		M(host).F().
+		Info("Migrate PVC %s/%s to storage class %s. Host: %s", namespace, pvc.Name, *template.Spec.StorageClassName, host.GetName())
+
+	// 1. Create migration PVC of the requested storage class, unless migration is resumed
+	migrationPVC, err := w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, migrationName, controller.NewGetOptions())
+	switch {
+	case apiErrors.IsNotFound(err):
+		migrationPVC = w.task.creator.CreatePVC(migrationName, host, &template.Spec)
+		if migrationPVC, err = w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, migrationPVC, controller.NewCreateOptions()); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		w.a.V(1).M(host).F().Info("Resume migration of PVC %s/%s", namespace, pvc.Name)
+	}
+
+	// 2. Copy data while the host is running. Migration PVC is bound by the Job's pod
+	if migrationPVC.Annotations[model.AnnotationPVCMigrationCopied] != model.AnnotationPVCMigrationCopiedValue {
+		if err := w.runPVCMigrationJob(ctx, w.createPVCMigrationJob(host, migrationName, pvc.Name, migrationName, false)); err != nil {
+			w.abortPVCMigration(ctx, namespace, migrationName)
+			return err
+		}
+		if err := w.markPVCMigrationCopied(ctx, namespace, migrationName); err != nil {
+			return err
+		}
+	}
+
+	// 3. Stop the host and sync data changed during the copy
+	if err := w.c.deleteStatefulSet(ctx, host); err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	if err := w.runPVCMigrationJob(ctx, w.createPVCMigrationJob(host, syncName, pvc.Name, migrationName, true)); err != nil {
+		// Host is started with the original PVC by the reconcile
+		w.abortPVCMigration(ctx, namespace, migrationName)
+		return err
+	}
+
+	migrationPVC, err = w.waitPVCBound(ctx, namespace, migrationName)
+	if err != nil {
+		return err
+	}
+	pvName := migrationPVC.Spec.VolumeName
+	originalPVName := pvc.Spec.VolumeName
+	if originalPVName == "" {
+		return fmt.Errorf("PVC %s/%s is not bound", namespace, pvc.Name)
+	}
+
+	// 4. Both PVs have to survive PVCs deletion
+	reclaimPolicy, err := w.setPVReclaimPolicy(ctx, pvName, core.PersistentVolumeReclaimRetain)
+	if err != nil {
+		return err
+	}
+	originalReclaimPolicy, err := w.setPVReclaimPolicy(ctx, originalPVName, core.PersistentVolumeReclaimRetain)
+	if err != nil {
+		_, _ = w.setPVReclaimPolicy(ctx, pvName, reclaimPolicy)
+		return err
+	}
+
+	// 5. Switch PV with copied data to the PVC with the original name
+	w.deletePVC(ctx, migrationPVC)
+	w.deletePVC(ctx, pvc)
+	if err := w.bindPVToNewPVC(ctx, host, template, pvc.Name, pvName); err != nil {
+		w.a.V(1).M(host).F().Warning("Unable to bind PV %s to PVC %s/%s, rebind original PV %s err: %v",
+			pvName, namespace, pvc.Name, originalPVName, err)
+		// PVC may have been created already, but is not bound to the PV with copied data
+		if leftover, e := w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvc.Name, controller.NewGetOptions()); e == nil {
+			w.deletePVC(ctx, leftover)
+		}
+		if e := w.bindPVToNewPVC(ctx, host, template, pvc.Name, originalPVName); e != nil {
+			w.a.V(1).M(host).F().Error("Unable to rebind original PV %s to PVC %s/%s, PVs %s and %s are retained err: %v",
+				originalPVName, namespace, pvc.Name, originalPVName, pvName, e)
+			return err
+		}
+		if _, e := w.setPVReclaimPolicy(ctx, originalPVName, originalReclaimPolicy); e != nil {
+			w.a.V(1).M(host).F().Warning("Unable to restore reclaim policy %s of PV %s err: %v", originalReclaimPolicy, originalPVName, e)
+		}
+		w.discardPVCMigrationPV(ctx, pvName, reclaimPolicy)
+		return err
+	}
+
+	// 6. Restore original reclaim policies. Original PV is released and is handled along its reclaim policy
+	if _, err := w.setPVReclaimPolicy(ctx, pvName, reclaimPolicy); err != nil {
+		w.a.V(1).M(host).F().Warning("Unable to restore reclaim policy %s of PV %s err: %v", reclaimPolicy, pvName, err)
+	}
+	if _, err := w.setPVReclaimPolicy(ctx, originalPVName, originalReclaimPolicy); err != nil {
+		w.a.V(1).M(host).F().Warning("Unable to restore reclaim policy %s of PV %s err: %v", originalReclaimPolicy, originalPVName, err)
+	}
+
+	w.a.V(1).
+		WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileInProgress).
+		WithStatusAction(host.GetCHI()).
+		M(host).F().
+		Info("Migrate PVC %s/%s completed. Host: %s", namespace, pvc.Name, host.GetName())
+
+	return nil
+}
+
+// runPVCMigrationJob runs migration Job, waits for it to complete and deletes it.
+// Job which exists already is waited for, so Job of the interrupted migration is not started once again
+func (w *worker) runPVCMigrationJob(ctx context.Context, job *batch.Job) error {
+	if _, err := w.c.kubeClient.BatchV1().Jobs(job.Namespace).Create(ctx, job, controller.NewCreateOptions()); err != nil && !apiErrors.IsAlreadyExists(err) {
+		return err
+	}
+	if err := w.waitPVCMigrationJob(ctx, job.Namespace, job.Name); err != nil {
+		w.deletePVCMigrationJob(ctx, job.Namespace, job.Name)
+		return err
+	}
+	w.deletePVCMigrationJob(ctx, job.Namespace, job.Name)
+	return nil
+}
+
+// markPVCMigrationCopied marks migration PVC as the one data is copied into
+func (w *worker) markPVCMigrationCopied(ctx context.Context, namespace, name string) error {
+	pvc, err := w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, controller.NewGetOptions())
+	if err != nil {
+		return err
+	}
+	if pvc.Annotations == nil {
+		pvc.Annotations = make(map[string]string)
+	}
+	pvc.Annotations[model.AnnotationPVCMigrationCopied] = model.AnnotationPVCMigrationCopiedValue
+	_, err = w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Update(ctx, pvc, controller.NewUpdateOptions())
+	return err
+}
+
+// abortPVCMigration deletes migration PVC of the failed migration, so the next attempt starts from scratch
+func (w *worker) abortPVCMigration(ctx context.Context, namespace, name string) {
+	if err := w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, name, controller.NewDeleteOptions()); err != nil && !apiErrors.IsNotFound(err) {
+		w.a.V(1).F().Warning("Unable to delete migration PVC %s/%s err: %v", namespace, name, err)
+	}
+}
+
+// setPVReclaimPolicy sets reclaim policy of the PV and returns the previous one
+func (w *worker) setPVReclaimPolicy(ctx context.Context, name string, policy core.PersistentVolumeReclaimPolicy) (core.PersistentVolumeReclaimPolicy, error) {
+	pv, err := w.c.kubeClient.CoreV1().PersistentVolumes().Get(ctx, name, controller.NewGetOptions())
+	if err != nil {
+		return "", err
+	}
+	previous := pv.Spec.PersistentVolumeReclaimPolicy
+	if previous == policy {
+		return previous, nil
+	}
+	pv.Spec.PersistentVolumeReclaimPolicy = policy
+	_, err = w.c.kubeClient.CoreV1().PersistentVolumes().Update(ctx, pv, controller.NewUpdateOptions())
+	return previous, err
+}
+
+// discardPVCMigrationPV restores reclaim policy of the PV with copied data, which is not used after failed switch,
+// and deletes it, so it is neither left available for binding nor retained forever.
+// Storage of the PV is released along the restored reclaim policy
+func (w *worker) discardPVCMigrationPV(ctx context.Context, name string, policy core.PersistentVolumeReclaimPolicy) {
+	if _, err := w.setPVReclaimPolicy(ctx, name, policy); err != nil {
+		w.a.V(1).F().Warning("Unable to restore reclaim policy %s of PV %s, PV is retained err: %v", policy, name, err)
+		return
+	}
+	if err := w.c.kubeClient.CoreV1().PersistentVolumes().Delete(ctx, name, controller.NewDeleteOptions()); err != nil && !apiErrors.IsNotFound(err) {
+		w.a.V(1).F().Warning("Unable to delete PV %s err: %v", name, err)
+	}
+}
+
+// bindPVToNewPVC makes retained PV available for binding and creates the PVC bound to it,
+// which is verified to be bound
+func (w *worker) bindPVToNewPVC(ctx context.Context, host *api.ChiHost, template *api.VolumeClaimTemplate, pvcName, pvName string) error {
+	pv, err := w.c.kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvName, controller.NewGetOptions())
+	if err != nil {
+		return err
+	}
+	pv.Spec.ClaimRef = nil
+	if _, err = w.c.kubeClient.CoreV1().PersistentVolumes().Update(ctx, pv, controller.NewUpdateOptions()); err != nil {
+		return err
+	}
+
+	pvc := w.task.creator.CreatePVC(pvcName, host, &template.Spec)
+	pvc.Spec.VolumeName = pvName
+	pvc = w.task.creator.PreparePersistentVolumeClaim(pvc, host, template)
+	if _, err := w.c.kubeClient.CoreV1().PersistentVolumeClaims(pvc.Namespace).Create(ctx, pvc, controller.NewCreateOptions()); err != nil {
+		return err
+	}
+	_, err = w.waitPVCBound(ctx, pvc.Namespace, pvcName)
+	return err
+}
+
+// waitPVCBound waits for the PVC to be bound
+func (w *worker) waitPVCBound(ctx context.Context, namespace, name string) (*core.PersistentVolumeClaim, error) {
+	opts := controller.NewPollerOptions()
+	opts.Timeout = pvcMigrationBindTimeout
+	opts.MainInterval = pvcMigrationPollInterval
+
+	var pvc *core.PersistentVolumeClaim
+	err := controller.Poll(
+		ctx,
+		namespace, name,
+		opts,
+		&controller.PollerFunctions{
+			Get: func(_ctx context.Context) (any, error) {
+				return w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(_ctx, name, controller.NewGetOptions())
+			},
+			IsDone: func(_ctx context.Context, a any) bool {
+				pvc = a.(*core.PersistentVolumeClaim)
+				return (pvc.Status.Phase == core.ClaimBound) && (pvc.Spec.VolumeName != "")
+			},
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PVC %s/%s is not bound: %w", namespace, name, err)
+	}
+	return pvc, nil
+}
+
+// createPVCMigrationJob creates Job which copies data from source PVC into target PVC.
+// Copy Job runs along the host's pod, so it is scheduled onto the same node the source PVC is attached to.
+// Sync Job runs after the host is stopped and brings target in line with the source, copying changed files only
+func (w *worker) createPVCMigrationJob(host *api.ChiHost, name, source, target string, sync bool) *batch.Job {
+	var backoffLimit int32 = 3
+	command := fmt.Sprintf("cp -a %s/. %s/ && sync", pvcMigrationSourcePath, pvcMigrationTargetPath)
+	var affinity *core.Affinity
+	if sync {
+		command = fmt.Sprintf(
+			"cp -a -u %[1]s/. %[2]s/ && "+
+				"cd %[2]s && find . -mindepth 1 -depth | while read -r f; do [ -e \"%[1]s/$f\" ] || [ -L \"%[1]s/$f\" ] || rm -rf \"$f\"; done && "+
+				"sync",
+			pvcMigrationSourcePath, pvcMigrationTargetPath,
+		)
+	} else {
+		affinity = &core.Affinity{
+			PodAffinity: &core.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []core.PodAffinityTerm{
+					{
+						LabelSelector: &meta.LabelSelector{
+							MatchLabels: model.GetSelectorHostScope(host),
+						},
+						TopologyKey: core.LabelHostname,
+					},
+				},
+			},
+		}
+	}
+	return &batch.Job{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      name,
+			Namespace: host.Runtime.Address.Namespace,
+		},
+		Spec: batch.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: core.PodTemplateSpec{
+				Spec: core.PodSpec{
+					RestartPolicy: core.RestartPolicyOnFailure,
+					Affinity:      affinity,
+					Containers: []core.Container{
+						{
+							Name:  "migration",
+							Image: getPVCMigrationImage(host),
+							Command: []string{
+								"/bin/sh",
+								"-c",
+								command,
+							},
+							VolumeMounts: []core.VolumeMount{
+								{
+									Name:      "source",
+									MountPath: pvcMigrationSourcePath,
+								},
+								{
+									Name:      "target",
+									MountPath: pvcMigrationTargetPath,
+								},
+							},
+						},
+					},
+					Volumes: []core.Volume{
+						{
+							Name: "source",
+							VolumeSource: core.VolumeSource{
+								PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{
+									ClaimName: source,
+								},
+							},
+						},
+						{
+							Name: "target",
+							VolumeSource: core.VolumeSource{
+								PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{
+									ClaimName: target,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// getPVCMigrationImage gets image to be used by migration Job. ClickHouse image of the host is used.
+func getPVCMigrationImage(host *api.ChiHost) string {
+	if template, ok := host.GetPodTemplate(); ok {
+		for _, container := range template.Spec.Containers {
+			if (container.Name == model.ClickHouseContainerName) && (container.Image != "") {
+				return container.Image
+			}
+		}
+	}
+	return model.DefaultClickHouseDockerImage
+}
+
+// waitPVCMigrationJob waits for migration Job to complete
+func (w *worker) waitPVCMigrationJob(ctx context.Context, namespace, name string) error {
+	opts := controller.NewPollerOptions()
+	opts.Timeout = pvcMigrationTimeout
+	opts.MainInterval = pvcMigrationPollInterval
+
+	var failed bool
+	err := controller.Poll(
+		ctx,
+		namespace, name,
+		opts,
+		&controller.PollerFunctions{
+			Get: func(_ctx context.Context) (any, error) {
+				return w.c.kubeClient.BatchV1().Jobs(namespace).Get(_ctx, name, controller.NewGetOptions())
+			},
+			IsDone: func(_ctx context.Context, a any) bool {
+				job := a.(*batch.Job)
+				for _, condition := range job.Status.Conditions {
+					if (condition.Type == batch.JobFailed) && (condition.Status == core.ConditionTrue) {
+						failed = true
+						return true
+					}
+				}
+				return job.Status.Succeeded > 0
+			},
+		},
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	if failed {
+		return fmt.Errorf("migration job %s/%s failed", namespace, name)
+	}
+	return nil
+}
+
+// deletePVCMigrationJob deletes migration Job along with its pods
+func (w *worker) deletePVCMigrationJob(ctx context.Context, namespace, name string) {
+	if err := w.c.kubeClient.BatchV1().Jobs(namespace).Delete(ctx, name, controller.NewDeleteOptions()); err != nil && !apiErrors.IsNotFound(err) {
+		w.a.V(1).F().Warning("Unable to delete migration Job %s/%s err: %v", namespace, name, err)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+const (
+	// pvcResizeTimeout specifies how long to wait for PVC to be expanded before reconcile of the host proceeds
+	pvcResizeTimeout = 5 * time.Minute
+	// pvcResizePollInterval specifies how often to check PVC expansion progress
+	pvcResizePollInterval = 5 * time.Second
+)
+
+// Reasons of VolumesResized host condition
+const (
+	volumesResizedReasonResized                 = "Resized"
+	volumesResizedReasonResizing                = "Resizing"
+	volumesResizedReasonFileSystemResizePending = "FileSystemResizePending"
+	volumesResizedReasonExpansionNotAllowed     = "ExpansionNotAllowed"
+	volumesResizedReasonResizeFailed            = "ResizeFailed"
+)
+
+// volumesResizedReasonsOrder lists reasons of VolumesResized host condition from the most to the least important one,
+// so the host condition reports the worst outcome among PVCs of the host
+var volumesResizedReasonsOrder = []string{
+	volumesResizedReasonResizeFailed,
+	volumesResizedReasonExpansionNotAllowed,
+	volumesResizedReasonResizing,
+	volumesResizedReasonFileSystemResizePending,
+	volumesResizedReasonResized,
+}
+
+// resizeHostPVCs expands PVCs of the host in-place in case storage requested by VolumeClaimTemplates grows.
+// PVC is expanded only in case its storage class allows volume expansion, otherwise the change is reported and skipped.
+// Progress is reported via VolumesResized condition of the host
+func (w *worker) resizeHostPVCs(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	outcomes := make(map[string][]string)
+	host.WalkVolumeMounts(api.DesiredStatefulSet, func(volumeMount *core.VolumeMount) {
+		if util.IsContextDone(ctx) {
+			return
+		}
+
+		pvc, volumeClaimTemplate, isModelCreated, err := w.fetchPVC(ctx, host, volumeMount)
+		if (err != nil) || (pvc == nil) || isModelCreated {
+			// No existing PVC - nothing to resize
+			return
+		}
+		if !isPVCStorageIncreased(pvc, volumeClaimTemplate) {
+			// No resize required
+			return
+		}
+		if volumeClaimTemplate.Migration.IsCopy() && isPVCStorageClassChanged(pvc, volumeClaimTemplate) {
+			// PVC is replaced by the migration with the new one of the requested storage
+			return
+		}
+
+		reason, err := w.resizePVC(ctx, host, pvc, volumeClaimTemplate)
+		if err != nil {
+			outcomes[reason] = append(outcomes[reason], fmt.Sprintf("%s: %v", pvc.Name, err))
+		} else {
+			outcomes[reason] = append(outcomes[reason], pvc.Name)
+		}
+	})
+
+	if len(outcomes) == 0 {
+		if !w.hasHostCondition(host, api.HostConditionTypeVolumesResized) {
+			// Volumes were never resized, nothing to report
+			return
+		}
+		outcomes[volumesResizedReasonResized] = nil
+	}
+
+	condition := newVolumesResizedCondition(outcomes)
+	host.GetCHI().EnsureStatus().SetHostCondition(host, condition)
+	_ = w.c.updateCHIObjectStatus(ctx, host.GetCHI(), UpdateCHIStatusOptions{
+		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+			MainFields: true,
+		},
+	})
+}
+
+// newVolumesResizedCondition builds VolumesResized host condition out of outcomes of PVCs resize, grouped by reason
+func newVolumesResizedCondition(outcomes map[string][]string) meta.Condition {
+	for _, reason := range volumesResizedReasonsOrder {
+		pvcs, ok := outcomes[reason]
+		if !ok {
+			continue
+		}
+		condition := meta.Condition{
+			Type:   api.HostConditionTypeVolumesResized,
+			Status: meta.ConditionFalse,
+			Reason: reason,
+		}
+		switch reason {
+		case volumesResizedReasonResized:
+			condition.Status = meta.ConditionTrue
+			condition.Message = "PVCs are of the storage requested by VolumeClaimTemplates"
+		case volumesResizedReasonResizing:
+			condition.Message = "PVCs are being expanded: " + strings.Join(pvcs, ", ")
+		case volumesResizedReasonFileSystemResizePending:
+			condition.Message = "PVCs wait for file system resize on pod (re)start: " + strings.Join(pvcs, ", ")
+		case volumesResizedReasonExpansionNotAllowed:
+			condition.Message = "Storage class does not allow volume expansion: " + strings.Join(pvcs, "; ")
+		case volumesResizedReasonResizeFailed:
+			condition.Message = "PVCs failed to expand: " + strings.Join(pvcs, "; ")
+		}
+		return condition
+	}
+	return meta.Condition{}
+}
+
+// hasHostCondition checks whether host has condition of specified type reported
+func (w *worker) hasHostCondition(host *api.ChiHost, conditionType string) bool {
+	status := host.GetCHI().EnsureStatus().GetHostStatus(host)
+	if status == nil {
+		return false
+	}
+	for _, condition := range status.Conditions {
+		if condition.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// resizePVC expands PVC to the storage requested by the template and waits for the expansion to complete.
+// Reason of VolumesResized host condition the PVC resize ends up with is returned
+func (w *worker) resizePVC(
+	ctx context.Context,
+	host *api.ChiHost,
+	pvc *core.PersistentVolumeClaim,
+	template *api.VolumeClaimTemplate,
+) (string, error) {
+	desired := template.Spec.Resources.Requests[core.ResourceStorage]
+	current := pvc.Spec.Resources.Requests[core.ResourceStorage]
+
+	expandable, err := w.isPVCExpandable(ctx, pvc)
+	switch {
+	case err != nil:
+		w.a.V(1).
+			WithEvent(host.GetCHI(), eventActionReconcile, eventReasonVolumeResizeFailed).
+			M(host).F().
+			Warning("Unable to check whether PVC %s/%s can be expanded. Host: %s err: %v", pvc.Namespace, pvc.Name, host.GetName(), err)
+		return volumesResizedReasonResizeFailed, err
+	case !expandable:
+		w.a.V(1).
+			WithEvent(host.GetCHI(), eventActionReconcile, eventReasonVolumeResizeNotAllowed).
+			M(host).F().
+			Warning("Storage class of PVC %s/%s does not allow volume expansion, requested storage %s is not applied. Host: %s",
+				pvc.Namespace, pvc.Name, desired.String(), host.GetName())
+		return volumesResizedReasonExpansionNotAllowed, fmt.Errorf("requested %s", desired.String())
+	}
+
+	w.a.V(1).
+		WithEvent(host.GetCHI(), eventActionReconcile, eventReasonReconcileInProgress).
+		WithStatusAction(host.GetCHI()).
+		M(host).F().
+		Info("Expand PVC %s/%s from %s to %s. Host: %s", pvc.Namespace, pvc.Name, current.String(), desired.String(), host.GetName())
+
+	pvc.Spec.Resources.Requests = pvc.Spec.Resources.Requests.DeepCopy()
+	pvc.Spec.Resources.Requests[core.ResourceStorage] = desired
+	if _, err := w.c.kubeClient.CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(ctx, pvc, controller.NewUpdateOptions()); err != nil {
+		w.a.V(1).
+			WithEvent(host.GetCHI(), eventActionReconcile, eventReasonVolumeResizeFailed).
+			M(host).F().
+			Warning("Unable to expand PVC %s/%s. Host: %s err: %v", pvc.Namespace, pvc.Name, host.GetName(), err)
+		return volumesResizedReasonResizeFailed, err
+	}
+
+	return w.waitPVCResized(ctx, host, pvc.Namespace, pvc.Name, desired.String()), nil
+}
+
+// waitPVCResized waits for capacity of the PVC to reach the requested storage.
+// Reason of VolumesResized host condition the PVC resize ends up with is returned
+func (w *worker) waitPVCResized(ctx context.Context, host *api.ChiHost, namespace, name, storage string) string {
+	start := time.Now()
+	for {
+		pvc, err := w.c.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, controller.NewGetOptions())
+		if err == nil {
+			if isPVCCapacityReached(pvc) {
+				w.a.V(1).M(host).F().Info("PVC %s/%s is expanded to %s. Host: %s", namespace, name, storage, host.GetName())
+				return volumesResizedReasonResized
+			}
+			if hasPVCCondition(pvc, core.PersistentVolumeClaimFileSystemResizePending) {
+				// Volume is expanded, file system is resized by kubelet on its own
+				w.a.V(1).M(host).F().Info("PVC %s/%s waits for file system resize. Host: %s", namespace, name, host.GetName())
+				return volumesResizedReasonFileSystemResizePending
+			}
+		}
+		if time.Since(start) > pvcResizeTimeout {
+			w.a.V(1).M(host).F().Warning("PVC %s/%s is not expanded in time, proceed. Host: %s", namespace, name, host.GetName())
+			return volumesResizedReasonResizing
+		}
+		w.a.V(2).M(host).F().Info("Wait for PVC %s/%s to be expanded to %s", namespace, name, storage)
+		if util.WaitContextDoneOrTimeout(ctx, pvcResizePollInterval) {
+			log.V(2).Info("task is done")
+			return volumesResizedReasonResizing
+		}
+	}
+}
+
+// isPVCExpandable checks whether storage class of the PVC allows volume expansion
+func (w *worker) isPVCExpandable(ctx context.Context, pvc *core.PersistentVolumeClaim) (bool, error) {
+	if (pvc.Spec.StorageClassName == nil) || (*pvc.Spec.StorageClassName == "") {
+		// Statically provisioned volume, can not be expanded by the provisioner
+		return false, nil
+	}
+	storageClass, err := w.c.kubeClient.StorageV1().StorageClasses().Get(ctx, *pvc.Spec.StorageClassName, controller.NewGetOptions())
+	if err != nil {
+		return false, err
+	}
+	return (storageClass.AllowVolumeExpansion != nil) && *storageClass.AllowVolumeExpansion, nil
+}
+
+// isPVCStorageIncreased checks whether storage requested by the template is bigger than storage requested by existing PVC
+func isPVCStorageIncreased(pvc *core.PersistentVolumeClaim, template *api.VolumeClaimTemplate) bool {
+	desired, ok := template.Spec.Resources.Requests[core.ResourceStorage]
+	if !ok {
+		return false
+	}
+	current := pvc.Spec.Resources.Requests[core.ResourceStorage]
+	return desired.Cmp(current) > 0
+}
+
+// isPVCCapacityReached checks whether actual capacity of the PVC reached the requested storage
+func isPVCCapacityReached(pvc *core.PersistentVolumeClaim) bool {
+	requested, ok := pvc.Spec.Resources.Requests[core.ResourceStorage]
+	if !ok {
+		return true
+	}
+	capacity, ok := pvc.Status.Capacity[core.ResourceStorage]
+	return ok && (capacity.Cmp(requested) >= 0)
+}
+
+// hasPVCCondition checks whether PVC has condition of specified type set
+func hasPVCCondition(pvc *core.PersistentVolumeClaim, conditionType core.PersistentVolumeClaimConditionType) bool {
+	for _, condition := range pvc.Status.Conditions {
+		if (condition.Type == conditionType) && (condition.Status == core.ConditionTrue) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepStatefulSetVolumeClaimTemplatesStorage keeps storage requested by VolumeClaimTemplates of the current StatefulSet
+// in the new StatefulSet. VolumeClaimTemplates of StatefulSet are immutable, while storage of existing PVCs is expanded in-place,
+// so StatefulSet is not to be recreated just because of storage request change
+func keepStatefulSetVolumeClaimTemplatesStorage(cur, new *apps.StatefulSet) {
+	if (cur == nil) || (new == nil) {
+		return
+	}
+	for i := range new.Spec.VolumeClaimTemplates {
+		claim := &new.Spec.VolumeClaimTemplates[i]
+		for j := range cur.Spec.VolumeClaimTemplates {
+			if cur.Spec.VolumeClaimTemplates[j].Name != claim.Name {
+				continue
+			}
+			storage, ok := cur.Spec.VolumeClaimTemplates[j].Spec.Resources.Requests[core.ResourceStorage]
+			if !ok || (claim.Spec.Resources.Requests == nil) {
+				continue
+			}
+			// Requests may be shared with VolumeClaimTemplate of the CHI
+			claim.Spec.Resources.Requests = claim.Spec.Resources.Requests.DeepCopy()
+			claim.Spec.Resources.Requests[core.ResourceStorage] = storage
+		}
+	}
+}
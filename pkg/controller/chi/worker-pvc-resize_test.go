@@ -0,0 +1,112 @@
+package chi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+func newResizeTestRequests(storage string) core.ResourceList {
+	if storage == "" {
+		return nil
+	}
+	return core.ResourceList{
+		core.ResourceStorage: resource.MustParse(storage),
+	}
+}
+
+func newResizeTestClaim(name, storage string) core.PersistentVolumeClaim {
+	return core.PersistentVolumeClaim{
+		ObjectMeta: meta.ObjectMeta{
+			Name: name,
+		},
+		Spec: core.PersistentVolumeClaimSpec{
+			Resources: core.ResourceRequirements{
+				Requests: newResizeTestRequests(storage),
+			},
+		},
+	}
+}
+
+func newResizeTestStatefulSet(claims ...core.PersistentVolumeClaim) *apps.StatefulSet {
+	return &apps.StatefulSet{
+		Spec: apps.StatefulSetSpec{
+			VolumeClaimTemplates: claims,
+		},
+	}
+}
+
+func TestIsPVCStorageIncreased(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		desired  string
+		expected bool
+	}{
+		{name: "increased", current: "10Gi", desired: "20Gi", expected: true},
+		{name: "same", current: "10Gi", desired: "10Gi", expected: false},
+		{name: "same in other units", current: "1Gi", desired: "1024Mi", expected: false},
+		{name: "decreased", current: "20Gi", desired: "10Gi", expected: false},
+		{name: "not requested by template", current: "10Gi", desired: "", expected: false},
+		{name: "not requested by PVC", current: "", desired: "10Gi", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := newResizeTestClaim("data", tt.current)
+			template := &api.VolumeClaimTemplate{
+				Spec: newResizeTestClaim("data", tt.desired).Spec,
+			}
+			require.Equal(t, tt.expected, isPVCStorageIncreased(&pvc, template))
+		})
+	}
+}
+
+func TestKeepStatefulSetVolumeClaimTemplatesStorage(t *testing.T) {
+	t.Run("storage of the current StatefulSet is kept", func(t *testing.T) {
+		cur := newResizeTestStatefulSet(newResizeTestClaim("data", "10Gi"), newResizeTestClaim("logs", "1Gi"))
+		new := newResizeTestStatefulSet(newResizeTestClaim("data", "20Gi"), newResizeTestClaim("logs", "2Gi"))
+		keepStatefulSetVolumeClaimTemplatesStorage(cur, new)
+		require.True(t, resource.MustParse("10Gi").Equal(new.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[core.ResourceStorage]))
+		require.True(t, resource.MustParse("1Gi").Equal(new.Spec.VolumeClaimTemplates[1].Spec.Resources.Requests[core.ResourceStorage]))
+	})
+
+	t.Run("new claims are kept as is", func(t *testing.T) {
+		cur := newResizeTestStatefulSet(newResizeTestClaim("data", "10Gi"))
+		new := newResizeTestStatefulSet(newResizeTestClaim("data", "20Gi"), newResizeTestClaim("logs", "2Gi"))
+		keepStatefulSetVolumeClaimTemplatesStorage(cur, new)
+		require.True(t, resource.MustParse("10Gi").Equal(new.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[core.ResourceStorage]))
+		require.True(t, resource.MustParse("2Gi").Equal(new.Spec.VolumeClaimTemplates[1].Spec.Resources.Requests[core.ResourceStorage]))
+	})
+
+	t.Run("claims having no requests are kept as is", func(t *testing.T) {
+		cur := newResizeTestStatefulSet(newResizeTestClaim("data", ""), newResizeTestClaim("logs", "1Gi"))
+		new := newResizeTestStatefulSet(newResizeTestClaim("data", "20Gi"), newResizeTestClaim("logs", ""))
+		keepStatefulSetVolumeClaimTemplatesStorage(cur, new)
+		require.True(t, resource.MustParse("20Gi").Equal(new.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[core.ResourceStorage]))
+		require.Nil(t, new.Spec.VolumeClaimTemplates[1].Spec.Resources.Requests)
+	})
+
+	t.Run("shared requests are not modified", func(t *testing.T) {
+		shared := newResizeTestRequests("20Gi")
+		claim := newResizeTestClaim("data", "")
+		claim.Spec.Resources.Requests = shared
+		cur := newResizeTestStatefulSet(newResizeTestClaim("data", "10Gi"))
+		new := newResizeTestStatefulSet(claim)
+		keepStatefulSetVolumeClaimTemplatesStorage(cur, new)
+		require.True(t, resource.MustParse("10Gi").Equal(new.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[core.ResourceStorage]))
+		require.True(t, resource.MustParse("20Gi").Equal(shared[core.ResourceStorage]))
+	})
+
+	t.Run("missing StatefulSet", func(t *testing.T) {
+		new := newResizeTestStatefulSet(newResizeTestClaim("data", "20Gi"))
+		keepStatefulSetVolumeClaimTemplatesStorage(nil, new)
+		require.True(t, resource.MustParse("20Gi").Equal(new.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[core.ResourceStorage]))
+		keepStatefulSetVolumeClaimTemplatesStorage(new, nil)
+	})
+}
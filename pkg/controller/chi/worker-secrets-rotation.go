@@ -0,0 +1,164 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// secretsRotationPollPeriod specifies how often Secrets referenced by pods of CHIs are checked for being rotated.
+// Rotation is picked up by Secrets informer, so the poll is a resync fallback only and is done rarely
+const secretsRotationPollPeriod = 10 * time.Minute
+
+// runSecretsRotationPoller checks Secrets referenced by pods of CHIs for being rotated
+// and enqueues reconcile of CHIs having rotated Secrets, until ctx is done.
+// Poller is a fallback of Secrets informer - it catches rotations informer events were missed for,
+// such as rotations happened while the operator was down, and fills index of Secrets for CHIs not reconciled since start
+func (c *Controller) runSecretsRotationPoller(ctx context.Context) {
+	w := c.newWorker(nil, true)
+	for {
+		chis, err := c.chiLister.List(labels.Everything())
+		if err != nil {
+			log.V(1).F().Warning("unable to list CHIs err: %v", err)
+		}
+		for _, chi := range chis {
+			switch {
+			case !chop.Config().IsWatchedNamespace(chi.Namespace):
+				continue
+			case chi.IsStopped():
+				continue
+			case chi.EnsureStatus().GetStatus() == api.StatusInProgress:
+				// Secrets are picked up by the reconcile in progress anyway
+				continue
+			}
+			w.checkSecretsRotation(ctx, chi)
+		}
+		if util.WaitContextDoneOrTimeout(ctx, secretsRotationPollPeriod) {
+			return
+		}
+	}
+}
+
+// checkSecretsRotation enqueues reconcile of the CHI in case any of Secrets referenced by its pods is rotated
+func (w *worker) checkSecretsRotation(ctx context.Context, chi *api.ClickHouseInstallation) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	normalized, err := w.normalizer.CreateTemplatedCHI(chi.DeepCopy(), normalizer.NewOptions())
+	if err != nil {
+		w.a.V(1).M(chi).F().Warning("unable to normalize CHI err: %v", err)
+		return
+	}
+	// Index is kept fresh for CHIs not reconciled since the operator started
+	w.c.indexCHISecrets(normalized)
+	if !w.isSecretsChanged(ctx, normalized) {
+		return
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionReconcile, eventReasonSecretsRotated).
+		M(chi).F().
+		Info("Secrets referenced by pods are rotated, reconcile CHI: %s/%s", chi.Namespace, chi.Name)
+	// No old CHI, so reconcile does not exit on the same generation
+	w.c.enqueueObject(NewReconcileCHI(reconcileUpdate, nil, chi))
+}
+
+// isSecretsChanged checks whether Secrets referenced by pods have changed since pods were started or reloaded
+func (w *worker) isSecretsChanged(ctx context.Context, chi *api.ClickHouseInstallation) bool {
+	changed := false
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		if changed || util.IsContextDone(ctx) || host.IsStopped() {
+			return nil
+		}
+		if w.isHostSecretsChecksumChanged(host) || w.isHostSecretsReloadChecksumChanged(host, false) {
+			w.a.V(1).M(host).F().Info("referenced secrets changed for host: %s", host.GetName())
+			changed = true
+		}
+		return nil
+	})
+	return changed
+}
+
+// isHostSecretsChecksumChanged checks whether Secrets, which restart the host on change, have changed
+// since StatefulSet of the host was updated.
+// StatefulSet w/o checksum is not restarted by rotation, checksum is stamped by the reconcile once onChange is set to Restart
+func (w *worker) isHostSecretsChecksumChanged(host *api.ChiHost) bool {
+	statefulSet, err := w.c.getStatefulSet(host)
+	if err != nil {
+		// Nothing to restart
+		return false
+	}
+	cur, ok := statefulSet.Spec.Template.Annotations[model.AnnotationSecretsChecksum]
+	return ok && (cur != host.Runtime.SecretsChecksum)
+}
+
+// isHostSecretsReloadChecksumChanged checks whether Secrets, which reload config of the host on change, have changed
+// since config of the host was reloaded. Pod w/o checksum is treated as changed in case strict only
+func (w *worker) isHostSecretsReloadChecksumChanged(host *api.ChiHost, strict bool) bool {
+	if host.Runtime.SecretsReloadChecksum == "" {
+		return false
+	}
+	pod, err := w.c.getPod(host)
+	if err != nil {
+		// Nothing to reload
+		return false
+	}
+	cur, ok := pod.Annotations[model.AnnotationSecretsReloadChecksum]
+	return (ok || strict) && (cur != host.Runtime.SecretsReloadChecksum)
+}
+
+// reloadHostSecrets reloads config of the host in case Secrets, which reload config on change, have changed.
+// Kubelet updates files of mounted Secrets in-place, so the host picks them up w/o restart
+func (w *worker) reloadHostSecrets(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	if host.IsStopped() || !w.isHostSecretsReloadChecksumChanged(host, true) {
+		return
+	}
+
+	if err := w.ensureClusterSchemer(host).HostReloadConfig(ctx, host); err != nil {
+		w.a.V(1).M(host).F().Warning("unable to reload config of host: %s err: %v", host.GetName(), err)
+		return
+	}
+
+	pod, err := w.c.getPod(host)
+	if err != nil {
+		return
+	}
+	pod.Annotations = util.MergeStringMapsOverwrite(pod.Annotations, map[string]string{
+		model.AnnotationSecretsReloadChecksum: host.Runtime.SecretsReloadChecksum,
+	})
+	if _, err := w.c.kubeClient.CoreV1().Pods(pod.Namespace).Update(ctx, pod, controller.NewUpdateOptions()); err != nil {
+		w.a.V(1).M(host).F().Warning("unable to annotate pod of host: %s err: %v", host.GetName(), err)
+		return
+	}
+	w.a.V(1).M(host).F().Info("Config reloaded with rotated secrets. Host: %s", host.GetName())
+}
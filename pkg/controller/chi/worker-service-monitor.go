@@ -0,0 +1,132 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"sort"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/creator"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// reconcileOperatorServiceMonitor reconciles Prometheus Operator ServiceMonitor of the operator's metrics Service.
+// ServiceMonitor is shared by all CHIs, so it is kept as long as any CHI has it enabled and is deleted afterwards.
+// CHI being reconciled is taken as it is specified, since the one known to the informer may be outdated.
+// CHI being deleted is specified as deleted, so it does not keep ServiceMonitor
+func (w *worker) reconcileOperatorServiceMonitor(ctx context.Context, chi *api.ClickHouseInstallation, deleted bool) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	namespace := creator.GetOperatorServiceMonitorNamespace()
+	if namespace == "" {
+		w.a.V(1).M(chi).F().Warning("unable to reconcile ServiceMonitor, namespace of the operator is not known")
+		return
+	}
+
+	monitors, err := w.getServiceMonitors(chi, deleted)
+	if err != nil {
+		w.a.V(1).M(chi).F().Warning("unable to list CHIs to reconcile ServiceMonitor err: %v", err)
+		return
+	}
+
+	serviceMonitor := creator.CreateOperatorServiceMonitor(namespace, monitors)
+	client := w.c.dynamicClient.Resource(creator.ServiceMonitorResource).Namespace(namespace)
+	if serviceMonitor == nil {
+		// No CHI has ServiceMonitor enabled
+		err := client.Delete(ctx, model.OperatorServiceMonitorName, controller.NewDeleteOptions())
+		switch {
+		case err == nil:
+			w.a.V(1).M(chi).F().Info("deleted ServiceMonitor %s/%s", namespace, model.OperatorServiceMonitorName)
+		case apiErrors.IsNotFound(err):
+			// Nothing to delete
+		default:
+			w.a.V(1).M(chi).F().Warning("unable to delete ServiceMonitor %s/%s err: %v", namespace, model.OperatorServiceMonitorName, err)
+		}
+		return
+	}
+
+	cur, err := client.Get(ctx, serviceMonitor.GetName(), controller.NewGetOptions())
+	switch {
+	case err == nil:
+		serviceMonitor.SetResourceVersion(cur.GetResourceVersion())
+		_, err = client.Update(ctx, serviceMonitor, controller.NewUpdateOptions())
+	case apiErrors.IsNotFound(err):
+		_, err = client.Create(ctx, serviceMonitor, controller.NewCreateOptions())
+	}
+
+	if err != nil {
+		// Prometheus Operator CRDs may be not installed in the cluster
+		w.a.WithEvent(chi, eventActionReconcile, eventReasonReconcileFailed).
+			WithStatusAction(chi).
+			M(chi).F().
+			Warning("unable to reconcile ServiceMonitor %s/%s err: %v", serviceMonitor.GetNamespace(), serviceMonitor.GetName(), err)
+		return
+	}
+
+	w.a.V(1).M(chi).F().Info("reconciled ServiceMonitor %s/%s", serviceMonitor.GetNamespace(), serviceMonitor.GetName())
+}
+
+// getServiceMonitors gets ServiceMonitor settings of all CHIs having ServiceMonitor enabled, ordered by CHI namespace and name.
+// CHIs other than the specified one are taken as they were normalized by their latest completed reconcile
+func (w *worker) getServiceMonitors(chi *api.ClickHouseInstallation, deleted bool) ([]*api.ChiServiceMonitor, error) {
+	chis, err := w.c.chiLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	monitors := make(map[string]*api.ChiServiceMonitor)
+	add := func(chi *api.ClickHouseInstallation) {
+		if !chi.IsStopped() && chi.Spec.Monitoring.IsServiceMonitorEnabled() {
+			monitors[chi.Namespace+"/"+chi.Name] = chi.Spec.Monitoring.GetServiceMonitor()
+		}
+	}
+	for _, cur := range chis {
+		if !chop.Config().IsWatchedNamespace(cur.Namespace) || !cur.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if (cur.Namespace == chi.Namespace) && (cur.Name == chi.Name) {
+			continue
+		}
+		if normalized := cur.Status.GetNormalizedCHICompleted(); normalized != nil {
+			cur = normalized
+		}
+		add(cur)
+	}
+	if !deleted {
+		add(chi)
+	}
+
+	var keys []string
+	for key := range monitors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var result []*api.ChiServiceMonitor
+	for _, key := range keys {
+		result = append(result, monitors[key])
+	}
+	return result, nil
+}
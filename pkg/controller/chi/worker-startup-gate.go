@@ -0,0 +1,172 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+const (
+	// startupGatePollPeriod specifies how often shards are checked for gated hosts to be released,
+	// in case readiness events of higher priority replicas are missed or never come
+	startupGatePollPeriod = 1 * time.Minute
+	// startupGateWindow specifies how recently the host has to be started to be gated.
+	// Hosts running for long are not gated in case a replica of higher priority fails on its own
+	startupGateWindow = 10 * time.Minute
+	// startupGateTimeout specifies how long merges of the gated host may stay stopped,
+	// so replicas of higher priority, which do not come up, do not stop merges forever
+	startupGateTimeout = 30 * time.Minute
+)
+
+// runStartupGatePoller checks shards of CHIs for hosts to be gated or released, until ctx is done
+func (c *Controller) runStartupGatePoller(ctx context.Context) {
+	w := c.newWorker(nil, true)
+	for {
+		chis, err := c.chiLister.List(labels.Everything())
+		if err != nil {
+			log.V(1).F().Warning("unable to list CHIs err: %v", err)
+		}
+		for _, chi := range chis {
+			if !chop.Config().IsWatchedNamespace(chi.Namespace) || chi.IsStopped() {
+				continue
+			}
+			normalized, err := w.normalizer.CreateTemplatedCHI(chi.DeepCopy(), normalizer.NewOptions())
+			if err != nil {
+				continue
+			}
+			normalized.WalkShards(func(shard *api.ChiShard) error {
+				w.gateShardStartup(ctx, shard)
+				return nil
+			})
+		}
+		if util.WaitContextDoneOrTimeout(ctx, startupGatePollPeriod) {
+			return
+		}
+	}
+}
+
+// gatePodShardStartup gates startup of hosts of the shard the pod belongs to
+func (w *worker) gatePodShardStartup(ctx context.Context, pod *core.Pod) {
+	chi, err := w.createCHIFromObjectMeta(&pod.ObjectMeta, false, normalizer.NewOptions())
+	if err != nil {
+		return
+	}
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		if model.CreatePodName(host) == pod.Name {
+			w.gateShardStartup(ctx, host.GetShard())
+		}
+		return nil
+	})
+}
+
+// gateShardStartup stops merges of hosts, which are just started while replicas of higher startup priority are not ready yet,
+// such as on a full restart after an outage, when pods are restarted by kubelet, not by the operator.
+// Gated hosts fetch parts merged by replicas of higher priority instead of merging the same parts on their own.
+// Merges are started back once all replicas of higher priority are ready, or the gate times out
+func (w *worker) gateShardStartup(ctx context.Context, shard *api.ChiShard) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	groups := getShardHostsByStartupPriority(shard)
+	if len(groups) < 2 {
+		// Replicas have the same priority, nothing to order
+		return
+	}
+
+	higherReady := true
+	for _, group := range groups {
+		groupReady := true
+		for _, host := range group {
+			pod, err := w.c.podLister.Pods(host.Runtime.Address.Namespace).Get(model.CreatePodName(host))
+			if err != nil {
+				groupReady = false
+				continue
+			}
+			if !isPodReady(pod) {
+				groupReady = false
+				continue
+			}
+			w.gateHostStartup(ctx, host, pod, !higherReady)
+		}
+		higherReady = higherReady && groupReady
+	}
+}
+
+// gateHostStartup stops or starts back merges of the ready host, depending on readiness of replicas of higher priority
+func (w *worker) gateHostStartup(ctx context.Context, host *api.ChiHost, pod *core.Pod, gate bool) {
+	gatedAt, gated := pod.Annotations[model.AnnotationStartupGated]
+	switch {
+	case gate && !gated && isPodStartedRecently(pod, startupGateWindow):
+		if err := w.ensureClusterSchemer(host).HostStopMerges(ctx, host); err != nil {
+			w.a.V(1).M(host).F().Warning("Unable to stop merges of gated host: %s err: %v", host.GetName(), err)
+			return
+		}
+		w.annotateStartupGatedPod(ctx, host, pod, time.Now().UTC().Format(time.RFC3339))
+		w.a.V(1).M(host).F().Info("Replicas of higher startup priority are not ready, merges stopped. Host: %s", host.GetName())
+	case gated && (!gate || isStartupGateExpired(gatedAt)):
+		if err := w.ensureClusterSchemer(host).HostStartMerges(ctx, host); err != nil {
+			w.a.V(1).M(host).F().Warning("Unable to start merges of gated host: %s err: %v", host.GetName(), err)
+			return
+		}
+		w.annotateStartupGatedPod(ctx, host, pod, "")
+		w.a.V(1).M(host).F().Info("Startup gate released, merges started. Host: %s", host.GetName())
+	}
+}
+
+// annotateStartupGatedPod sets or removes, in case value is empty, startup gate annotation of the pod.
+// Annotation keeps the gate across operator restarts, restarted pod has no annotation and runs merges
+func (w *worker) annotateStartupGatedPod(ctx context.Context, host *api.ChiHost, pod *core.Pod, value string) {
+	pod = pod.DeepCopy()
+	if value == "" {
+		delete(pod.Annotations, model.AnnotationStartupGated)
+	} else {
+		pod.Annotations = util.MergeStringMapsOverwrite(pod.Annotations, map[string]string{
+			model.AnnotationStartupGated: value,
+		})
+	}
+	if _, err := w.c.kubeClient.CoreV1().Pods(pod.Namespace).Update(ctx, pod, controller.NewUpdateOptions()); err != nil {
+		w.a.V(1).M(host).F().Warning("unable to annotate pod of host: %s err: %v", host.GetName(), err)
+	}
+}
+
+// isPodStartedRecently checks whether all containers of the pod are running for less than the window
+func isPodStartedRecently(pod *core.Pod, window time.Duration) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if (status.State.Running == nil) || (time.Since(status.State.Running.StartedAt.Time) > window) {
+			return false
+		}
+	}
+	return len(pod.Status.ContainerStatuses) > 0
+}
+
+// isStartupGateExpired checks whether the gate set at the specified time is timed out
+func isStartupGateExpired(gatedAt string) bool {
+	at, err := time.Parse(time.RFC3339, gatedAt)
+	return (err != nil) || (time.Since(at) > startupGateTimeout)
+}
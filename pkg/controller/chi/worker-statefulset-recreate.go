@@ -0,0 +1,187 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// recreateStatefulSetGracefully recreates StatefulSet of the host step by step, so data of the host is preserved
+// and failure of any step stops recreation and is reported, instead of leaving the host half-configured:
+//  1. PVCs of the host are verified to be retained upon StatefulSet deletion
+//  2. StatefulSet is scaled down to zero and pod termination is confirmed
+//  3. StatefulSet is deleted and created anew, re-attaching retained PVCs
+//  4. Tables of the host are validated via SQL to be in place
+func (w *worker) recreateStatefulSetGracefully(ctx context.Context, host *api.ChiHost, register bool) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	namespace := host.Runtime.Address.Namespace
+	name := host.Runtime.Address.StatefulSet
+
+	w.a.V(1).
+		WithEvent(host.GetCHI(), eventActionUpdate, eventReasonUpdateInProgress).
+		WithStatusAction(host.GetCHI()).
+		M(host).F().
+		Info("Recreate StatefulSet(%s/%s) gracefully - started", namespace, name)
+
+	// Tables are counted before the host goes down, in order to validate them after recreation.
+	// Host may be unreachable, crash-looping for example, in this case there is nothing to validate against
+	tables, err := w.ensureClusterSchemer(host).HostTablesNum(ctx, host)
+	if err != nil {
+		w.a.V(1).M(host).F().Warning("Unable to count tables on host: %s, tables will not be validated. err: %v", host.GetName(), err)
+		tables = 0
+	}
+
+	if err := w.verifyPVCsRetained(host); err != nil {
+		return w.recreateStatefulSetFailed(host, "verify PVC retention", err)
+	}
+
+	if err := w.scaleDownStatefulSet(ctx, host); err != nil {
+		return w.recreateStatefulSetFailed(host, "scale down", err)
+	}
+
+	if err := w.c.deleteStatefulSet(ctx, host); err != nil && !apiErrors.IsNotFound(err) {
+		return w.recreateStatefulSetFailed(host, "delete", err)
+	}
+
+	_ = w.reconcilePVCs(ctx, host, api.DesiredStatefulSet)
+	w.ensureVolumesNodeAffinity(ctx, host)
+	if err := w.createStatefulSet(ctx, host, register); err != nil {
+		return w.recreateStatefulSetFailed(host, "create", err)
+	}
+
+	if tables > 0 {
+		if err := w.validateHostTables(ctx, host, tables); err != nil {
+			return w.recreateStatefulSetFailed(host, "validate tables", err)
+		}
+	}
+
+	w.a.V(1).
+		WithEvent(host.GetCHI(), eventActionUpdate, eventReasonUpdateCompleted).
+		WithStatusAction(host.GetCHI()).
+		M(host).F().
+		Info("Recreate StatefulSet(%s/%s) gracefully - completed. Tables validated: %d", namespace, name, tables)
+	return nil
+}
+
+// recreateStatefulSetFailed reports failed step of graceful StatefulSet recreation
+func (w *worker) recreateStatefulSetFailed(host *api.ChiHost, step string, err error) error {
+	err = fmt.Errorf("recreate StatefulSet %s/%s failed on step '%s': %v",
+		host.Runtime.Address.Namespace, host.Runtime.Address.StatefulSet, step, err)
+	w.a.WithEvent(host.GetCHI(), eventActionUpdate, eventReasonUpdateFailed).
+		WithStatusAction(host.GetCHI()).
+		WithStatusError(host.GetCHI()).
+		M(host).F().
+		Error("%v", err)
+	return err
+}
+
+// verifyPVCsRetained verifies PVCs of the host are not deleted along with StatefulSet.
+// Recreate scales StatefulSet down before deletion, so PVCs have to be retained on scale down as well
+func (w *worker) verifyPVCsRetained(host *api.ChiHost) (err error) {
+	statefulSet, e := w.c.getStatefulSet(host)
+	if e != nil {
+		if apiErrors.IsNotFound(e) {
+			// No StatefulSet - nothing to delete PVCs along with
+			return nil
+		}
+		return e
+	}
+
+	if policy := statefulSet.Spec.PersistentVolumeClaimRetentionPolicy; policy != nil {
+		if policy.WhenDeleted == apps.DeletePersistentVolumeClaimRetentionPolicyType {
+			return fmt.Errorf("StatefulSet %s/%s deletes its PVCs when deleted", statefulSet.Namespace, statefulSet.Name)
+		}
+		if policy.WhenScaled == apps.DeletePersistentVolumeClaimRetentionPolicyType {
+			return fmt.Errorf("StatefulSet %s/%s deletes its PVCs when scaled down", statefulSet.Namespace, statefulSet.Name)
+		}
+	}
+
+	w.c.walkDiscoveredPVCs(host, func(pvc *core.PersistentVolumeClaim) {
+		if err != nil {
+			return
+		}
+		if pvc.DeletionTimestamp != nil {
+			err = fmt.Errorf("PVC %s/%s is being deleted", pvc.Namespace, pvc.Name)
+			return
+		}
+		for _, owner := range pvc.OwnerReferences {
+			if (owner.Kind == "StatefulSet") && (owner.Name == statefulSet.Name) {
+				err = fmt.Errorf("PVC %s/%s is owned by StatefulSet and would be garbage collected", pvc.Namespace, pvc.Name)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// scaleDownStatefulSet scales StatefulSet of the host down to zero and waits for the pod of the host to terminate
+func (w *worker) scaleDownStatefulSet(ctx context.Context, host *api.ChiHost) error {
+	statefulSet, err := w.c.getStatefulSet(host)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	var zero int32 = 0
+	statefulSet.Spec.Replicas = &zero
+	if _, err := w.c.kubeClient.AppsV1().StatefulSets(statefulSet.Namespace).Update(ctx, statefulSet, controller.NewUpdateOptions()); err != nil {
+		return err
+	}
+
+	terminated := false
+	_ = w.c.pollHost(ctx, host, nil, func(_ context.Context, host *api.ChiHost) bool {
+		_, err := w.c.getPod(host)
+		terminated = apiErrors.IsNotFound(err)
+		return terminated
+	})
+	if !terminated {
+		return fmt.Errorf("pod %s/%s is not terminated", host.Runtime.Address.Namespace, model.CreatePodName(host))
+	}
+	return nil
+}
+
+// validateHostTables waits for the host to have at least specified number of tables
+func (w *worker) validateHostTables(ctx context.Context, host *api.ChiHost, expected int) error {
+	tables := 0
+	_ = w.c.pollHost(ctx, host, nil, func(_ctx context.Context, host *api.ChiHost) bool {
+		num, err := w.ensureClusterSchemer(host).HostTablesNum(_ctx, host)
+		if err != nil {
+			return false
+		}
+		tables = num
+		return tables >= expected
+	})
+	if tables < expected {
+		return fmt.Errorf("host %s has %d tables, expected %d", host.GetName(), tables, expected)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apiMeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// upgradePollPeriod specifies how often CHIs are checked for being due to discover newer versions
+const upgradePollPeriod = 1 * time.Minute
+
+// upgradeIndexTimeout specifies timeout of fetching registry index
+const upgradeIndexTimeout = 10 * time.Second
+
+// upgradeIndexClient fetches registry index. Registry may hang up, so requests are limited in time
+var upgradeIndexClient = &http.Client{
+	Timeout: upgradeIndexTimeout,
+}
+
+// Reasons of UpgradeAvailable condition
+const (
+	upgradeReasonAvailable        = "Available"
+	upgradeReasonUpToDate         = "UpToDate"
+	upgradeReasonApplied          = "Applied"
+	upgradeReasonIndexUnavailable = "IndexUnavailable"
+)
+
+// upgradeIndex specifies released versions by release channel
+type upgradeIndex map[string][]string
+
+// clusterUpgrade specifies newer version the cluster can be upgraded to
+type clusterUpgrade struct {
+	cluster string
+	from    string
+	to      string
+}
+
+// String returns string representation of the upgrade
+func (u clusterUpgrade) String() string {
+	return fmt.Sprintf("%s: %s -> %s", u.cluster, u.from, u.to)
+}
+
+// runUpgradePoller discovers newer patch versions for CHIs having upgrade channel specified,
+// each CHI according to its own interval, until ctx is done.
+// CHIs annotated with upgrade approval are handled on the next poll regardless of interval
+func (c *Controller) runUpgradePoller(ctx context.Context) {
+	w := c.newWorker(nil, true)
+	polled := make(map[string]time.Time)
+	for {
+		if url := chop.Config().ClickHouse.Image.Index; url != "" {
+			chis, err := c.chiLister.List(labels.Everything())
+			listed := err == nil
+			if !listed {
+				log.V(1).F().Warning("unable to list CHIs err: %v", err)
+			}
+			// Index is fetched once per poll and only in case there are CHIs due to be checked
+			var index upgradeIndex
+			fetched := false
+			due := make(map[string]bool)
+			for _, chi := range chis {
+				if !chop.Config().IsWatchedNamespace(chi.Namespace) || !chi.GetUpgrade().IsEnabled() || chi.IsStopped() {
+					continue
+				}
+				key := chi.Namespace + "/" + chi.Name
+				due[key] = true
+				if (time.Since(polled[key]) < chi.GetUpgrade().GetInterval()) && !model.HasUpgradeApproval(chi.ObjectMeta) {
+					continue
+				}
+				polled[key] = time.Now()
+				if !fetched {
+					index, err = fetchUpgradeIndex(ctx, url)
+					fetched = true
+				}
+				w.reconcileUpgrade(ctx, chi.DeepCopy(), index, err)
+			}
+			if listed {
+				// Forget CHIs, which are deleted or do not discover upgrades anymore
+				for key := range polled {
+					if !due[key] {
+						delete(polled, key)
+					}
+				}
+			}
+		}
+		if util.WaitContextDoneOrTimeout(ctx, upgradePollPeriod) {
+			return
+		}
+	}
+}
+
+// fetchUpgradeIndex fetches registry index of released versions
+func fetchUpgradeIndex(ctx context.Context, url string) (upgradeIndex, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := upgradeIndexClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status of %s: %s", url, response.Status)
+	}
+
+	index := make(upgradeIndex)
+	if err := json.NewDecoder(response.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", url, err)
+	}
+	return index, nil
+}
+
+// reconcileUpgrade reports newer versions available to clusters of the CHI via UpgradeAvailable condition
+// and applies them in case either upgrade is auto-applied or CHI is annotated with approval of the version.
+// Upgrade is applied by bumping clickhouseVersion of clusters, so hosts are upgraded by regular rolling reconcile
+func (w *worker) reconcileUpgrade(ctx context.Context, chi *api.ClickHouseInstallation, index upgradeIndex, indexErr error) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	wasAvailable := false
+	if condition := apiMeta.FindStatusCondition(chi.EnsureStatus().GetConditions(), api.ConditionTypeUpgradeAvailable); condition != nil {
+		wasAvailable = condition.Status == meta.ConditionTrue
+	}
+	approved := model.GetUpgradeApprovedVersion(chi.ObjectMeta)
+	channel := chi.GetUpgrade().GetChannel()
+
+	condition := meta.Condition{
+		Type:               api.ConditionTypeUpgradeAvailable,
+		ObservedGeneration: chi.Generation,
+	}
+	upgrades := findClusterUpgrades(chi, index[channel])
+	applicable := upgrades
+	if !chi.GetUpgrade().IsAutoApply() {
+		applicable = filterClusterUpgrades(upgrades, approved)
+	}
+	switch {
+	case indexErr != nil:
+		condition.Status = meta.ConditionUnknown
+		condition.Reason = upgradeReasonIndexUnavailable
+		condition.Message = fmt.Sprintf("unable to fetch registry index: %v", indexErr)
+	case len(upgrades) == 0:
+		condition.Status = meta.ConditionFalse
+		condition.Reason = upgradeReasonUpToDate
+		condition.Message = fmt.Sprintf("all pinned clusters run the latest %s patch versions", channel)
+		if approved != "" {
+			// Nothing to approve, approval is not kept for future upgrades
+			w.a.V(1).M(chi).F().Warning("no upgrade available, drop %s annotation", model.AnnotationUpgradeApproved)
+			_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationUpgradeApproved)
+		}
+	case len(applicable) > 0:
+		if err := w.applyClusterUpgrades(ctx, chi, applicable, approved); err != nil {
+			condition.Status = meta.ConditionTrue
+			condition.Reason = upgradeReasonAvailable
+			condition.Message = fmt.Sprintf("unable to apply upgrade %s: %v", describeClusterUpgrades(applicable), err)
+		} else {
+			condition.Status = meta.ConditionFalse
+			condition.Reason = upgradeReasonApplied
+			condition.Message = "upgrade applied: " + describeClusterUpgrades(applicable)
+		}
+	default:
+		condition.Status = meta.ConditionTrue
+		condition.Reason = upgradeReasonAvailable
+		condition.Message = fmt.Sprintf("clusters can be upgraded: %s. Annotate CHI with %s=<version> to apply",
+			describeClusterUpgrades(upgrades), model.AnnotationUpgradeApproved)
+		if approved != "" {
+			// Approved version is not the one available, approval of an outdated version is not kept
+			w.a.V(1).M(chi).F().Warning("approved version %s is not available, drop %s annotation", approved, model.AnnotationUpgradeApproved)
+			_ = w.c.deleteCHIAnnotation(ctx, chi, model.AnnotationUpgradeApproved)
+		}
+		if !wasAvailable {
+			w.a.V(1).
+				WithEvent(chi, eventActionCheck, eventReasonUpgradeAvailable).
+				M(chi).F().
+				Info("Upgrade available. %s", condition.Message)
+		}
+	}
+
+	chi.EnsureStatus().SetCondition(condition)
+	_ = w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
+		TolerateAbsence: true,
+		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
+			Upgrade: true,
+		},
+	})
+}
+
+// applyClusterUpgrades bumps clickhouseVersion of the upgraded clusters and drops upgrade approval annotation.
+// Unless upgrade is auto-applied, the approved version is checked against the current CHI, since it may be edited meanwhile
+func (w *worker) applyClusterUpgrades(ctx context.Context, chi *api.ClickHouseInstallation, upgrades []clusterUpgrade, approved string) error {
+	cur, err := w.c.chopClient.ClickhouseV1().ClickHouseInstallations(chi.Namespace).Get(ctx, chi.Name, controller.NewGetOptions())
+	if err != nil {
+		return err
+	}
+	if !cur.GetUpgrade().IsAutoApply() && (model.GetUpgradeApprovedVersion(cur.ObjectMeta) != approved) {
+		return fmt.Errorf("approval of version %s is changed meanwhile", approved)
+	}
+
+	for _, upgrade := range upgrades {
+		cluster := cur.FindCluster(upgrade.cluster)
+		if (cluster == nil) || (cluster.ClickHouseVersion != upgrade.from) {
+			return fmt.Errorf("cluster %s is edited meanwhile", upgrade.cluster)
+		}
+		cluster.ClickHouseVersion = upgrade.to
+	}
+	delete(cur.Annotations, model.AnnotationUpgradeApproved)
+
+	if _, err := w.c.chopClient.ClickhouseV1().ClickHouseInstallations(cur.Namespace).Update(ctx, cur, controller.NewUpdateOptions()); err != nil {
+		return err
+	}
+
+	w.a.V(1).
+		WithEvent(chi, eventActionUpdate, eventReasonUpgradeApplied).
+		M(chi).F().
+		Info("Upgrade applied: %s", describeClusterUpgrades(upgrades))
+	return nil
+}
+
+// filterClusterUpgrades selects upgrades to the approved version
+func filterClusterUpgrades(upgrades []clusterUpgrade, approved string) (filtered []clusterUpgrade) {
+	if approved == "" {
+		return nil
+	}
+	for _, upgrade := range upgrades {
+		if upgrade.to == approved {
+			filtered = append(filtered, upgrade)
+		}
+	}
+	return filtered
+}
+
+// findClusterUpgrades finds newer patch versions of the same release and flavor among versions of the channel
+// for clusters pinned to full versions, such as 24.3.5.47 or 23.8.8.21.altinitystable.
+// Clusters pinned to release only, such as 24.3, already follow the latest patch version of the release
+func findClusterUpgrades(chi *api.ClickHouseInstallation, versions []string) (upgrades []clusterUpgrade) {
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		current, flavor, ok := parseClickHouseVersion(cluster.GetClickHouseVersion())
+		if !ok || (len(current) < 3) {
+			return nil
+		}
+		latest, target := current, ""
+		for _, version := range versions {
+			candidate, candidateFlavor, ok := parseClickHouseVersion(version)
+			if !ok || (len(candidate) < 2) || (candidate[0] != current[0]) || (candidate[1] != current[1]) {
+				// Other release
+				continue
+			}
+			if candidateFlavor != flavor {
+				// Other builds, such as upstream ones for Altinity Stable builds
+				continue
+			}
+			if compareClickHouseVersions(candidate, latest) > 0 {
+				latest, target = candidate, version
+			}
+		}
+		if target != "" {
+			upgrades = append(upgrades, clusterUpgrade{
+				cluster: cluster.Name,
+				from:    cluster.GetClickHouseVersion(),
+				to:      target,
+			})
+		}
+		return nil
+	})
+	return upgrades
+}
+
+// describeClusterUpgrades describes upgrades for logs, events and condition
+func describeClusterUpgrades(upgrades []clusterUpgrade) string {
+	var descriptions []string
+	for _, upgrade := range upgrades {
+		descriptions = append(descriptions, upgrade.String())
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// parseClickHouseVersion parses dot-separated ClickHouse version into numeric components and flavor.
+// Flavor is the non-numeric suffix of builds other than upstream ones, such as `altinitystable` of 23.8.8.21.altinitystable
+func parseClickHouseVersion(version string) (components []int, flavor string, ok bool) {
+	if version == "" {
+		return nil, "", false
+	}
+	parts := strings.Split(version, ".")
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			if i == 0 {
+				return nil, "", false
+			}
+			// Numeric components are not expected past the flavor
+			flavor = strings.Join(parts[i:], ".")
+			for _, rest := range parts[i:] {
+				if _, err := strconv.Atoi(rest); (err == nil) || (rest == "") {
+					return nil, "", false
+				}
+			}
+			return components, flavor, true
+		}
+		components = append(components, n)
+	}
+	return components, "", true
+}
+
+// compareClickHouseVersions compares versions component by component, missing components are treated as zeros
+func compareClickHouseVersions(a, b []int) int {
+	for i := 0; (i < len(a)) || (i < len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
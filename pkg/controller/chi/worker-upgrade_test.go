@@ -0,0 +1,33 @@
+package chi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClickHouseVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		components []int
+		flavor     string
+		ok         bool
+	}{
+		{version: "24.3", components: []int{24, 3}, ok: true},
+		{version: "24.3.5.47", components: []int{24, 3, 5, 47}, ok: true},
+		{version: "23.8.8.21.altinitystable", components: []int{23, 8, 8, 21}, flavor: "altinitystable", ok: true},
+		{version: "22.8.15.25.altinityfips", components: []int{22, 8, 15, 25}, flavor: "altinityfips", ok: true},
+		{version: ""},
+		{version: "latest"},
+		{version: "23.8.altinitystable.21"},
+		{version: "23.8."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			components, flavor, ok := parseClickHouseVersion(tt.version)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.components, components)
+			require.Equal(t, tt.flavor, flavor)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"context"
+
+	core "k8s.io/api/core/v1"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/controller"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// ensureVolumesNodeAffinity makes desired StatefulSet of the host carry node affinity of PVs bound to PVCs of the host,
+// so the pod of recreated StatefulSet is not scheduled onto a node, where its local PVs can not be attached.
+// Node affinity of the PV, which conflicts with node affinity of the pod, is reported and is not applied
+func (w *worker) ensureVolumesNodeAffinity(ctx context.Context, host *api.ChiHost) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return
+	}
+
+	statefulSet := host.Runtime.DesiredStatefulSet
+	if statefulSet == nil {
+		return
+	}
+	podSpec := &statefulSet.Spec.Template.Spec
+
+	w.c.walkDiscoveredPVCs(host, func(pvc *core.PersistentVolumeClaim) {
+		terms := w.getVolumeNodeSelectorTerms(ctx, host, pvc)
+		if len(terms) == 0 {
+			return
+		}
+
+		affinity, ok := model.IntersectRequiredNodeAffinity(podSpec.Affinity, terms)
+		if !ok {
+			w.a.V(1).
+				WithEvent(host.GetCHI(), eventActionReconcile, eventReasonVolumeNodeAffinityConflict).
+				WithStatusError(host.GetCHI()).
+				M(host).F().
+				Error("Node affinity of PV %s bound to PVC %s/%s conflicts with node affinity of the pod of StatefulSet %s/%s. "+
+					"Pod may fail to be scheduled",
+					pvc.Spec.VolumeName, pvc.Namespace, pvc.Name, statefulSet.Namespace, statefulSet.Name)
+			return
+		}
+
+		podSpec.Affinity = affinity
+		w.a.V(1).
+			M(host).F().
+			Info("Node affinity of PV %s bound to PVC %s/%s is applied to the pod of StatefulSet %s/%s",
+				pvc.Spec.VolumeName, pvc.Namespace, pvc.Name, statefulSet.Namespace, statefulSet.Name)
+	})
+}
+
+// getVolumeNodeSelectorTerms gets required node selector terms of the PV bound to the PVC, if any
+func (w *worker) getVolumeNodeSelectorTerms(ctx context.Context, host *api.ChiHost, pvc *core.PersistentVolumeClaim) []core.NodeSelectorTerm {
+	if pvc.Spec.VolumeName == "" {
+		// PVC is not bound yet
+		return nil
+	}
+
+	pv, err := w.c.kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, controller.NewGetOptions())
+	if err != nil {
+		w.a.V(1).M(host).F().Warning("unable to get PV %s bound to PVC %s/%s err: %v", pvc.Spec.VolumeName, pvc.Namespace, pvc.Name, err)
+		return nil
+	}
+
+	if (pv.Spec.NodeAffinity == nil) || (pv.Spec.NodeAffinity.Required == nil) {
+		return nil
+	}
+	return pv.Spec.NodeAffinity.Required.NodeSelectorTerms
+}
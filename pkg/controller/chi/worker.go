@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/juliangruber/go-intersect"
@@ -41,6 +42,7 @@ import (
 	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
 	"github.com/altinity/clickhouse-operator/pkg/model/k8s"
 	"github.com/altinity/clickhouse-operator/pkg/util"
+	"github.com/altinity/clickhouse-operator/pkg/version"
 )
 
 // FinalizerName specifies name of the finalizer to be used with CHI
@@ -65,16 +67,21 @@ type task struct {
 	registryFailed     *model.Registry
 	cmUpdate           time.Time
 	start              time.Time
+	// verifyOnly specifies whether existing StatefulSets are verified only, without being updated
+	verifyOnly bool
+	// breaker halts reconcile in case too many hosts in a row fail reconcile or health check
+	breaker *circuitBreaker
 }
 
 // newTask creates new context
-func newTask(creator *chiCreator.Creator) task {
+func newTask(creator *chiCreator.Creator, failureThreshold int) task {
 	return task{
 		creator:            creator,
 		registryReconciled: model.NewRegistry(),
 		registryFailed:     model.NewRegistry(),
 		cmUpdate:           time.Time{},
 		start:              time.Now(),
+		breaker:            newCircuitBreaker(failureThreshold),
 	}
 }
 
@@ -99,17 +106,51 @@ func (c *Controller) newWorker(q queue.PriorityQueue, sys bool) *worker {
 
 // newContext creates new reconcile task
 func (w *worker) newTask(chi *api.ClickHouseInstallation) {
-	w.task = newTask(chiCreator.NewCreator(chi))
+	w.task = newTask(chiCreator.NewCreator(chi), chi.GetReconciling().GetFailureThreshold())
 }
 
 // timeToStart specifies time that operator does not accept changes
 const timeToStart = 1 * time.Minute
 
+// maxSchemaPlanNames specifies how many names of objects of a schema plan are listed in CHI status per list
+const maxSchemaPlanNames = 100
+
 // isJustStarted checks whether worked just started
 func (w *worker) isJustStarted() bool {
 	return time.Since(w.start) < timeToStart
 }
 
+// isVerifyOnlyReconcile checks whether reconcile is a verify-only pass.
+// It is the first reconcile by the new operator version of a CHI, which has no changes since the last completed reconcile.
+// Existing StatefulSets are not updated in this case, so operator upgrade does not restart pods cluster-wide.
+// CHI has to be checked as fetched from k8s, before normalization, because normalization fills status with the current operator version.
+func (w *worker) isVerifyOnlyReconcile(chi *api.ClickHouseInstallation) bool {
+	if chop.Config().Reconcile.StatefulSet.OnOperatorRestart != api.OnOperatorRestartStatefulSetActionVerify {
+		return false
+	}
+	if !chi.HasAncestor() {
+		return false
+	}
+	if (chi.EnsureStatus().GetCHOpVersion() == version.Version) && (chi.EnsureStatus().GetCHOpCommit() == version.GitSHA) {
+		// CHI has already been reconciled by the current operator version
+		return false
+	}
+	if model.HasHostsToReplace(chi.ObjectMeta) || model.IsRollingRestartRequested(chi.ObjectMeta) {
+		// Hosts replacement and restart are explicit requests to act
+		return false
+	}
+	return chi.Generation == chi.GetAncestor().Generation
+}
+
+// isVerifyOnlyHost checks whether host's StatefulSet is to be verified only, without being updated
+func (w *worker) isVerifyOnlyHost(host *api.ChiHost) bool {
+	if !w.task.verifyOnly {
+		return false
+	}
+	sts, err := w.c.getStatefulSet(host, false)
+	return (err == nil) && (sts != nil)
+}
+
 func (w *worker) isConfigurationChangeRequiresReboot(host *api.ChiHost) bool {
 	return model.IsConfigurationChangeRequiresReboot(host)
 }
@@ -123,6 +164,11 @@ func (w *worker) shouldForceRestartHost(host *api.ChiHost) bool {
 		return true
 	}
 
+	if w.isRollingRestartPending(host) {
+		w.a.V(1).M(host).F().Info("Rolling restart requested, force restart. Host: %s", host.GetName())
+		return true
+	}
+
 	if host.GetReconcileAttributes().GetStatus() == api.ObjectStatusNew {
 		w.a.V(1).M(host).F().Info("Host is new, no restart applicable. Host: %s", host.GetName())
 		return false
@@ -184,12 +230,21 @@ func (w *worker) run() {
 		//	return
 		//}
 
+		if !w.c.startItem() {
+			// Controller is shutting down, do not pick new items
+			w.a.Info("shutdown in progress, skip item")
+			w.queue.Done(item)
+			return
+		}
+
 		if err := w.processItem(ctx, item); err != nil {
 			// Item not processed
 			// this code cannot return an error and needs to indicate error has been ignored
 			utilRuntime.HandleError(err)
 		}
 
+		w.c.doneItem()
+
 		// Forget indicates that an item is finished being retried.  Doesn't matter whether its for perm failing
 		// or for success, we'll stop the rate limiter from tracking it.  This only clears the `rateLimiter`, you
 		// still have to call `Done` on the queue.
@@ -202,10 +257,8 @@ func (w *worker) run() {
 
 func (w *worker) processReconcileCHI(ctx context.Context, cmd *ReconcileCHI) error {
 	switch cmd.cmd {
-	case reconcileAdd:
-		return w.updateCHI(ctx, nil, cmd.new)
-	case reconcileUpdate:
-		return w.updateCHI(ctx, cmd.old, cmd.new)
+	case reconcileAdd, reconcileUpdate:
+		return w.processReconcileCHIGuarded(ctx, cmd)
 	case reconcileDelete:
 		return w.discoveryAndDeleteCHI(ctx, cmd.old)
 	}
@@ -267,13 +320,25 @@ func (w *worker) processReconcilePod(ctx context.Context, cmd *ReconcilePod) err
 		metricsPodAdd(ctx)
 		return nil
 	case reconcileUpdate:
-		//ignore
 		//w.a.V(1).M(cmd.new).F().Info("Update Pod. %s/%s", cmd.new.Namespace, cmd.new.Name)
 		//metricsPodUpdate(ctx)
-		return nil
+		if shouldMarkupBackupCandidate(cmd.old, cmd.new) {
+			w.markupShardBackupCandidate(ctx, cmd.new)
+		}
+		if isPodReady(cmd.old) != isPodReady(cmd.new) {
+			w.updateCHIReadiness(ctx, cmd.new)
+			w.gatePodShardStartup(ctx, cmd.new)
+		}
+		if shouldRequestHostReplacement(cmd.old, cmd.new) {
+			w.requestHostReplacement(ctx, cmd.new)
+		}
+		return w.updatePod(ctx, cmd.old, cmd.new)
 	case reconcileDelete:
 		w.a.V(1).M(cmd.old).F().Info("Delete Pod. %s/%s", cmd.old.Namespace, cmd.old.Name)
 		metricsPodDelete(ctx)
+		// Deleted pod may have been a backup candidate of the shard
+		w.markupShardBackupCandidate(ctx, cmd.old)
+		w.updateCHIReadiness(ctx, cmd.old)
 		return nil
 	}
 
@@ -283,6 +348,18 @@ func (w *worker) processReconcilePod(ctx context.Context, cmd *ReconcilePod) err
 	return nil
 }
 
+// updateCHIReadiness refreshes ready hosts of the CHI the pod belongs to in CHI status
+func (w *worker) updateCHIReadiness(ctx context.Context, pod *core.Pod) {
+	chi, err := w.createCHIFromObjectMeta(&pod.ObjectMeta, false, normalizer.NewOptions())
+	if err != nil {
+		w.a.V(2).M(pod).F().Info("unable to find CHI by %v err: %v", pod.Labels, err)
+		return
+	}
+	_ = w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
+		TolerateAbsence: true,
+	})
+}
+
 func (w *worker) processDropDns(ctx context.Context, cmd *DropDns) error {
 	if chi, err := w.createCHIFromObjectMeta(cmd.initiator, false, normalizer.NewOptions()); err == nil {
 		w.a.V(2).M(cmd.initiator).Info("flushing DNS for CHI %s", chi.Name)
@@ -316,6 +393,8 @@ func (w *worker) processItem(ctx context.Context, item interface{}) error {
 		return w.processReconcilePod(ctx, cmd)
 	case *DropDns:
 		return w.processDropDns(ctx, cmd)
+	case *ReloadUsersConfig:
+		return w.processReloadUsersConfig(ctx, cmd)
 	}
 
 	// Unknown item type, don't know what to do with it
@@ -325,7 +404,7 @@ func (w *worker) processItem(ctx context.Context, item interface{}) error {
 }
 
 // normalize
-func (w *worker) normalize(c *api.ClickHouseInstallation) *api.ClickHouseInstallation {
+func (w *worker) normalize(c *api.ClickHouseInstallation) (*api.ClickHouseInstallation, error) {
 
 	chi, err := w.normalizer.CreateTemplatedCHI(c, normalizer.NewOptions())
 	if err != nil {
@@ -339,6 +418,7 @@ func (w *worker) normalize(c *api.ClickHouseInstallation) *api.ClickHouseInstall
 	w.a.V(1).M(chi).Info("IPs of the CHI normalizer %s/%s: len: %d %v", chi.Namespace, chi.Name, len(ips), ips)
 	opts := normalizer.NewOptions()
 	opts.DefaultUserAdditionalIPs = ips
+	opts.ScaleInProtectedHosts = w.c.getScaleInProtectedPodNames(chi)
 
 	chi, err = w.normalizer.CreateTemplatedCHI(c, opts)
 	if err != nil {
@@ -348,7 +428,7 @@ func (w *worker) normalize(c *api.ClickHouseInstallation) *api.ClickHouseInstall
 			Error("FAILED to normalize CHI 2: %v", err)
 	}
 
-	return chi
+	return chi, err
 }
 
 // ensureFinalizer
@@ -382,14 +462,73 @@ func (w *worker) ensureFinalizer(ctx context.Context, chi *api.ClickHouseInstall
 
 // updateEndpoints updates endpoints
 func (w *worker) updateEndpoints(ctx context.Context, old, new *core.Endpoints) error {
+	w.updateUsersIPs(ctx, &new.ObjectMeta)
+	return nil
+}
+
+// updatePod reacts on pod changes.
+// Pod rescheduled to another node gets new IP address, which has to be reflected in users config.
+func (w *worker) updatePod(ctx context.Context, old, new *core.Pod) error {
+	if (old == nil) || (new == nil) {
+		return nil
+	}
+	prev, known := w.c.rememberPodIP(new)
+	if (new.Status.PodIP == "") || (old.Status.PodIP == new.Status.PodIP) {
+		// No IP address assigned yet or IP address is the same
+		return nil
+	}
+
+	w.a.V(1).M(new).F().Info("Pod IP assigned %s/%s: %s => %s", new.Namespace, new.Name, old.Status.PodIP, new.Status.PodIP)
+	chi := w.updateUsersIPs(ctx, &new.ObjectMeta)
+	if (chi == nil) || !known || (prev == new.Status.PodIP) {
+		// IP address is assigned to the pod for the first time or is the same the pod of the same name had before,
+		// no need to hurry ClickHouse to pick up users config
+		return nil
+	}
 
-	if chi, err := w.createCHIFromObjectMeta(&new.ObjectMeta, false, normalizer.NewOptions()); err == nil {
+	w.a.V(1).M(new).F().Info("Pod IP changed %s/%s: %s => %s", new.Namespace, new.Name, prev, new.Status.PodIP)
+	// ClickHouse picks up updated users config on its own as soon as the ConfigMap is propagated into pods,
+	// explicit reload is made in order not to wait for ClickHouse's own config check period.
+	// Reload waits for the ConfigMap to propagate, so it is queued in order not to hold pod updates processing
+	w.c.enqueueObject(NewReloadUsersConfig(&new.ObjectMeta, new.Status.PodIP))
+	return nil
+}
+
+// processReloadUsersConfig reloads users config of the CHI the pod, which IP address has changed, belongs to
+func (w *worker) processReloadUsersConfig(ctx context.Context, cmd *ReloadUsersConfig) error {
+	chi, err := w.createCHIFromObjectMeta(cmd.initiator, false, normalizer.NewOptions())
+	if err != nil {
+		w.a.V(1).M(cmd.initiator).F().Info("unable to find CHI by %v err: %v", cmd.initiator.Labels, err)
+		return nil
+	}
+	w.reloadUsersConfig(ctx, chi, cmd.ip)
+	return nil
+}
+
+// reloadUsersConfig reloads users config on all hosts of the CHI.
+// Updated ConfigMap reaches pods with a delay, so reload is repeated till the host allows default user to connect from the IP.
+func (w *worker) reloadUsersConfig(ctx context.Context, chi *api.ClickHouseInstallation, ip string) {
+	w.pollHostsTillConfigMapPropagated(ctx, chi, func(_ctx context.Context, host *api.ChiHost) bool {
+		reloaded, err := w.ensureClusterSchemer(host).HostReloadUsersConfig(_ctx, host, ip)
+		if err != nil {
+			w.a.V(1).M(host).F().Warning("unable to reload users config. Host: %s err: %v", host.GetName(), err)
+		}
+		return reloaded
+	}, func(host *api.ChiHost, err error) {
+		w.a.V(1).M(host).F().Warning("users config with IP %s is not loaded. Host: %s err: %v", ip, host.GetName(), err)
+	})
+}
+
+// updateUsersIPs re-renders users config of the CHI the object belongs to with actual pods' IP addresses.
+// Returns CHI with users config updated
+func (w *worker) updateUsersIPs(ctx context.Context, objectMeta *meta.ObjectMeta) *api.ClickHouseInstallation {
+	if chi, err := w.createCHIFromObjectMeta(objectMeta, false, normalizer.NewOptions()); err == nil {
 		w.a.V(1).M(chi).Info("updating endpoints for CHI-1 %s", chi.Name)
 		ips := w.c.getPodsIPs(chi)
 		w.a.V(1).M(chi).Info("IPs of the CHI-1 update endpoints %s/%s: len: %d %v", chi.Namespace, chi.Name, len(ips), ips)
 		opts := normalizer.NewOptions()
 		opts.DefaultUserAdditionalIPs = ips
-		if chi, err := w.createCHIFromObjectMeta(&new.ObjectMeta, false, opts); err == nil {
+		if chi, err := w.createCHIFromObjectMeta(objectMeta, false, opts); err == nil {
 			w.a.V(1).M(chi).Info("Update users IPS-1")
 
 			// TODO unify with finalize reconcile
@@ -401,11 +540,12 @@ func (w *worker) updateEndpoints(ctx context.Context, old, new *core.Endpoints)
 					Normalized: true,
 				},
 			})
+			return chi
 		} else {
-			w.a.M(&new.ObjectMeta).F().Error("internal unable to find CHI by %v err: %v", new.Labels, err)
+			w.a.M(objectMeta).F().Error("internal unable to find CHI by %v err: %v", objectMeta.Labels, err)
 		}
 	} else {
-		w.a.M(&new.ObjectMeta).F().Error("external unable to find CHI by %v err %v", new.Labels, err)
+		w.a.M(objectMeta).F().Error("external unable to find CHI by %v err %v", objectMeta.Labels, err)
 	}
 	return nil
 }
@@ -649,6 +789,12 @@ func (w *worker) markReconcileStart(ctx context.Context, chi *api.ClickHouseInst
 
 	// Write desired normalized CHI with initialized .Status, so it would be possible to monitor progress
 	chi.EnsureStatus().ReconcileStart(ap.GetRemovedHostsNum())
+	var hosts []*api.ChiHost
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		hosts = append(hosts, host)
+		return nil
+	})
+	chi.EnsureStatus().ResetHostStatuses(hosts, time.Now().Format(time.RFC3339))
 	_ = w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
 		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
 			MainFields: true,
@@ -684,6 +830,7 @@ func (w *worker) finalizeReconcileAndMarkCompleted(ctx context.Context, _chi *ap
 			chi.SetAncestor(chi.GetTarget())
 			chi.SetTarget(nil)
 			chi.EnsureStatus().ReconcileComplete()
+			chi.EnsureStatus().RemoveCondition(api.ConditionTypeDegraded)
 			// TODO unify with update endpoints
 			w.newTask(chi)
 			w.reconcileCHIConfigMapUsers(ctx, chi)
@@ -718,6 +865,12 @@ func (w *worker) markReconcileCompletedUnsuccessfully(ctx context.Context, chi *
 		chi.EnsureStatus().ReconcileComplete()
 	case errors.Is(err, errCRUDAbort):
 		chi.EnsureStatus().ReconcileAbort()
+	case errors.Is(err, errShutdown):
+		chi.EnsureStatus().ReconcileAbort()
+	case errors.Is(err, errReconcileSuperseded):
+		chi.EnsureStatus().ReconcileAbort()
+	case errors.Is(err, errReconcileHalted):
+		chi.EnsureStatus().ReconcileAbort()
 	}
 	w.c.updateCHIObjectStatus(ctx, chi, UpdateCHIStatusOptions{
 		CopyCHIStatusOptions: api.CopyCHIStatusOptions{
@@ -831,11 +984,12 @@ func (w *worker) getRemoteServersGeneratorOptions() *model.RemoteServersGenerato
 	// Base model.RemoteServersGeneratorOptions specifies to exclude:
 	// 1. all newly added hosts
 	// 2. all explicitly excluded hosts
+	// 3. all hosts under maintenance
 	return model.NewRemoteServersGeneratorOptions().ExcludeReconcileAttributes(
 		api.NewChiHostReconcileAttributes().
 			SetAdd().
 			SetExclude(),
-	)
+	).ExcludeHostsUnderMaintenance()
 }
 
 // options build ClickHouseConfigFilesGeneratorOptions
@@ -930,7 +1084,22 @@ func (w *worker) migrateTables(ctx context.Context, host *api.ChiHost, opts ...*
 			"Adding tables on shard/host:%d/%d cluster:%s",
 			host.Runtime.Address.ShardIndex, host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ClusterName)
 
-	err := w.ensureClusterSchemer(host).HostCreateTables(ctx, host)
+	clusterSchemer := w.ensureClusterSchemer(host)
+	plan := clusterSchemer.HostSchemaPlan(ctx, host)
+	w.reportSchemaPlan(host, plan)
+	if host.GetCluster().SchemaPolicy.IsDryRun() {
+		// Host is not marked as having tables created, so the plan is applied as soon as dry-run is turned off
+		w.a.V(1).
+			WithEvent(host.GetCHI(), eventActionCreate, eventReasonCreateCompleted).
+			WithStatusAction(host.GetCHI()).
+			M(host).F().
+			Info("Schema dry-run on shard/host:%d/%d cluster:%s. Objects to be created: %d existing: %d differ: %d. See status.schemaPlans",
+				host.Runtime.Address.ShardIndex, host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ClusterName,
+				len(plan.GetNames()), len(plan.Existing), len(plan.Differs))
+		return nil
+	}
+
+	err := clusterSchemer.HostApplySchemaPlan(ctx, host, plan)
 	if err == nil {
 		w.a.V(1).
 			WithEvent(host.GetCHI(), eventActionCreate, eventReasonCreateCompleted).
@@ -950,6 +1119,38 @@ func (w *worker) migrateTables(ctx context.Context, host *api.ChiHost, opts ...*
 	return err
 }
 
+// reportSchemaPlan reports schema plan of the host in CHI status.
+// Status lists names of objects only, in order to keep CHI within object size limits. Statements are logged
+func (w *worker) reportSchemaPlan(host *api.ChiHost, plan *schemer.SchemaPlan) {
+	if plan == nil {
+		return
+	}
+	host.GetCHI().EnsureStatus().PushSchemaPlan(api.ChiSchemaPlan{
+		Host:     model.CreateFQDN(host),
+		DryRun:   host.GetCluster().SchemaPolicy.IsDryRun(),
+		Time:     time.Now().Format(time.RFC3339),
+		Create:   truncateSchemaPlanNames(plan.GetNames()),
+		Existing: truncateSchemaPlanNames(plan.Existing),
+		Differs:  truncateSchemaPlanNames(plan.Differs),
+	})
+	if sqls := plan.GetSQLs(); len(sqls) > 0 {
+		w.a.V(1).M(host).F().Info("Schema plan of host %s:\n%s", host.GetName(), strings.Join(sqls, ";\n"))
+	}
+	if len(plan.Differs) > 0 {
+		w.a.V(1).M(host).F().Warning(
+			"Objects on host %s differ from other hosts and are not touched: %v", host.GetName(), plan.Differs)
+	}
+}
+
+// truncateSchemaPlanNames limits number of names listed, noting how many names are not listed
+func truncateSchemaPlanNames(names []string) []string {
+	if len(names) <= maxSchemaPlanNames {
+		return names
+	}
+	more := len(names) - maxSchemaPlanNames
+	return append(names[:maxSchemaPlanNames:maxSchemaPlanNames], fmt.Sprintf("... %d more objects are not listed", more))
+}
+
 // shouldMigrateTables
 func (w *worker) shouldMigrateTables(host *api.ChiHost, opts ...*migrateTableOptions) bool {
 	o := NewMigrateTableOptionsArr(opts...).First()
@@ -1002,6 +1203,16 @@ func (w *worker) excludeHost(ctx context.Context, host *api.ChiHost) error {
 	defer log.V(1).M(host).F().E().Info("exclude host end")
 
 	if !w.shouldExcludeHost(host) {
+		if w.shouldExcludeHostFromServiceOnly(host) {
+			w.a.V(1).
+				M(host).F().
+				Info("Exclude from services host %d shard %d cluster %s",
+					host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
+			_ = w.excludeHostFromService(ctx, host)
+			if w.shouldWaitExcludeHost(host) {
+				w.waitHostEndpointsExcluded(ctx, host)
+			}
+		}
 		return nil
 	}
 
@@ -1012,6 +1223,9 @@ func (w *worker) excludeHost(ctx context.Context, host *api.ChiHost) error {
 
 	_ = w.excludeHostFromService(ctx, host)
 	w.excludeHostFromClickHouseCluster(ctx, host)
+	if w.shouldWaitExcludeHost(host) {
+		w.waitHostEndpointsExcluded(ctx, host)
+	}
 	return nil
 }
 
@@ -1020,6 +1234,9 @@ func (w *worker) completeQueries(ctx context.Context, host *api.ChiHost) error {
 	log.V(1).M(host).F().S().Info("complete queries start")
 	defer log.V(1).M(host).F().E().Info("complete queries end")
 
+	if host.IsUnderMaintenance() {
+		return w.drainHostQueries(ctx, host)
+	}
 	if w.shouldWaitQueries(host) {
 		return w.waitHostNoActiveQueries(ctx, host)
 	}
@@ -1033,6 +1250,9 @@ func (w *worker) shouldIncludeHost(host *api.ChiHost) bool {
 	case host.IsStopped():
 		// No need to include stopped host
 		return false
+	case host.IsUnderMaintenance():
+		// Host under maintenance stays out of the cluster until maintenance is over
+		return false
 	}
 	return true
 }
@@ -1058,6 +1278,16 @@ func (w *worker) includeHost(ctx context.Context, host *api.ChiHost) error {
 			host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
 
 	w.includeHostIntoClickHouseCluster(ctx, host)
+
+	// Client traffic is let in via Services only once the host serves SQL.
+	// Host may take a while to start serving SQL after restart, so poll it instead of probing once,
+	// otherwise the host would be left out of services till the next reconcile, which may never happen.
+	if _, err := w.pollHostForClickHouseVersion(ctx, host); err != nil {
+		w.a.V(1).
+			M(host).F().
+			Warning("Host does not respond to SQL, keep it out of services. Host: %s err: %v", host.GetName(), err)
+		return nil
+	}
 	_ = w.includeHostIntoService(ctx, host)
 
 	return nil
@@ -1124,6 +1354,9 @@ func (w *worker) includeHostIntoClickHouseCluster(ctx context.Context, host *api
 		Info("going to include host %d shard %d cluster %s",
 			host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
 
+	// Lagging replica should not receive queries via remote_servers
+	w.waitHostReplicationDelay(ctx, host)
+
 	// Specify in options to add this host into ClickHouse config file
 	host.GetCHI().EnsureRuntime().LockCommonConfig()
 	host.GetReconcileAttributes().UnsetExclude()
@@ -1146,12 +1379,24 @@ func (w *worker) shouldExcludeHost(host *api.ChiHost) bool {
 			Info("Host is stopped, no need to exclude stopped host. Host/shard/cluster: %d/%d/%s",
 				host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
 		return false
+	case host.IsUnderMaintenance():
+		w.a.V(1).
+			M(host).F().
+			Info("Host is under maintenance, need to exclude. Host/shard/cluster: %d/%d/%s",
+				host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
+		return true
 	case host.GetShard().HostsCount() == 1:
 		w.a.V(1).
 			M(host).F().
 			Info("Host is the only host in the shard (means no replication), no need to exclude. Host/shard/cluster: %d/%d/%s",
 				host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
 		return false
+	case w.isVerifyOnlyHost(host):
+		w.a.V(1).
+			M(host).F().
+			Info("Host is verified only, would not be updated, no need to exclude. Host/shard/cluster: %d/%d/%s",
+				host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
+		return false
 	case w.shouldForceRestartHost(host):
 		w.a.V(1).
 			M(host).F().
@@ -1261,6 +1506,50 @@ func (w *worker) shouldWaitIncludeHost(host *api.ChiHost) bool {
 	return chop.Config().Reconcile.Host.Wait.Include.Value()
 }
 
+// shouldWaitReplicationDelay determines whether host's replication delay to be checked before including host into cluster
+func (w *worker) shouldWaitReplicationDelay(host *api.ChiHost) bool {
+	switch {
+	case !host.GetCHI().GetReconciling().HasMaxReplicationDelay():
+		return false
+	case !host.GetReconcileAttributes().IsExclude():
+		// Host was not excluded from the cluster, nothing to gate
+		return false
+	case host.GetShard().HostsCount() == 1:
+		// No replication in one-host-shard
+		return false
+	}
+	return true
+}
+
+// waitHostReplicationDelay waits for replication delay of the host to get under the threshold specified by
+// CHI 'reconciling.maxReplicationDelay' setting. Host is included into the cluster anyway after timeout,
+// so reconcile does not leave it out of the cluster forever
+func (w *worker) waitHostReplicationDelay(ctx context.Context, host *api.ChiHost) {
+	if !w.shouldWaitReplicationDelay(host) {
+		return
+	}
+
+	maxDelay := host.GetCHI().GetReconciling().GetMaxReplicationDelay()
+	w.a.V(1).
+		M(host).F().
+		Info("Wait for replication delay to get under %d seconds. Host/shard/cluster: %d/%d/%s", maxDelay,
+			host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
+	err := w.c.pollHost(ctx, host, nil, func(ctx context.Context, host *api.ChiHost) bool {
+		delay, err := w.ensureClusterSchemer(host).HostMaxReplicationDelay(ctx, host)
+		if err != nil {
+			return false
+		}
+		w.a.V(2).M(host).F().Info("Replication delay: %d seconds. Host: %s", delay, host.GetName())
+		return delay <= maxDelay
+	})
+	if err != nil {
+		w.a.V(1).
+			M(host).F().
+			Warning("Replication delay did not get under %d seconds in time, include host anyway. Host/shard/cluster: %d/%d/%s", maxDelay,
+				host.Runtime.Address.ReplicaIndex, host.Runtime.Address.ShardIndex, host.Runtime.Address.ClusterName)
+	}
+}
+
 // waitHostInCluster
 func (w *worker) waitHostInCluster(ctx context.Context, host *api.ChiHost) error {
 	return w.c.pollHost(ctx, host, nil, w.ensureClusterSchemer(host).IsHostInCluster)
@@ -1352,6 +1641,23 @@ func (w *worker) createConfigMap(ctx context.Context, chi *api.ClickHouseInstall
 	return err
 }
 
+// isServiceHeadless checks whether service has no cluster IP assigned
+func isServiceHeadless(service *core.Service) bool {
+	return service.Spec.ClusterIP == core.ClusterIPNone
+}
+
+// isServiceUsingNodePorts checks whether service has node ports allocated.
+// LoadBalancer service may opt out of node ports allocation
+func isServiceUsingNodePorts(service *core.Service) bool {
+	switch service.Spec.Type {
+	case core.ServiceTypeNodePort:
+		return true
+	case core.ServiceTypeLoadBalancer:
+		return (service.Spec.AllocateLoadBalancerNodePorts == nil) || *service.Spec.AllocateLoadBalancerNodePorts
+	}
+	return false
+}
+
 // updateService
 func (w *worker) updateService(
 	ctx context.Context,
@@ -1370,10 +1676,19 @@ func (w *worker) updateService(
 			curService.Spec.Type, targetService.Spec.Type)
 	}
 
+	if isServiceHeadless(curService) != isServiceHeadless(targetService) {
+		return fmt.Errorf(
+			"just recreate the service in case of headless service change '%t'=>'%t'",
+			isServiceHeadless(curService), isServiceHeadless(targetService))
+	}
+
 	// Updating a Service is a complicated business
 
 	newService := targetService.DeepCopy()
 
+	// Whether the new service should be exactly as specified, or should keep fields assigned to the current one
+	replace := chi.GetReconciling().GetService().IsFieldsPolicyReplace()
+
 	// spec.resourceVersion is required in order to update an object
 	newService.ResourceVersion = curService.ResourceVersion
 
@@ -1400,24 +1715,46 @@ func (w *worker) updateService(
 	// No changes in service type is allowed.
 	// Already exposed port details can not be changed.
 
-	serviceTypeIsNodePort := (curService.Spec.Type == core.ServiceTypeNodePort) && (newService.Spec.Type == core.ServiceTypeNodePort)
-	serviceTypeIsLoadBalancer := (curService.Spec.Type == core.ServiceTypeLoadBalancer) && (newService.Spec.Type == core.ServiceTypeLoadBalancer)
-	if serviceTypeIsNodePort || serviceTypeIsLoadBalancer {
+	// Node ports are migrated only in case both current and new services use them,
+	// since service not using node ports must not have them specified
+	if isServiceUsingNodePorts(curService) && isServiceUsingNodePorts(newService) {
 		for i := range newService.Spec.Ports {
 			newPort := &newService.Spec.Ports[i]
 			for j := range curService.Spec.Ports {
 				curPort := &curService.Spec.Ports[j]
 				if newPort.Port == curPort.Port {
-					// Already have this port specified - reuse all internals,
-					// due to limitations with auto-assigned values
-					*newPort = *curPort
-					w.a.M(chi).F().Info("reuse Port %d values", newPort.Port)
+					if replace {
+						// Reuse allocated node port only, in case it is not explicitly specified in the template
+						if newPort.NodePort == 0 {
+							newPort.NodePort = curPort.NodePort
+							w.a.M(chi).F().Info("reuse Port %d NodePort %d", newPort.Port, newPort.NodePort)
+						}
+					} else {
+						// Already have this port specified - reuse all internals,
+						// due to limitations with auto-assigned values
+						*newPort = *curPort
+						w.a.M(chi).F().Info("reuse Port %d values", newPort.Port)
+					}
 					break
 				}
 			}
 		}
 	}
 
+	//
+	// Migrate ExternalIPs and LoadBalancerIP to the new service
+	//
+	// These fields are often assigned by cloud integrations or by cluster administrators out-of-band.
+	// With 'Preserve' policy existing values are kept unless the template specifies them explicitly.
+	if !replace {
+		if len(newService.Spec.ExternalIPs) == 0 {
+			newService.Spec.ExternalIPs = curService.Spec.ExternalIPs
+		}
+		if newService.Spec.LoadBalancerIP == "" {
+			newService.Spec.LoadBalancerIP = curService.Spec.LoadBalancerIP
+		}
+	}
+
 	//
 	// Migrate HealthCheckNodePort to the new service
 	//
@@ -1442,8 +1779,12 @@ func (w *worker) updateService(
 	//
 	// Migrate labels, annotations and finalizers to the new service
 	//
-	newService.ObjectMeta.Labels = util.MergeStringMapsPreserve(newService.ObjectMeta.Labels, curService.ObjectMeta.Labels)
-	newService.ObjectMeta.Annotations = util.MergeStringMapsPreserve(newService.ObjectMeta.Annotations, curService.ObjectMeta.Annotations)
+	// Finalizers are always migrated, since they are typically set by cloud load balancer controllers
+	// and are required for proper cleanup of the cloud resources.
+	if !replace {
+		newService.ObjectMeta.Labels = util.MergeStringMapsPreserve(newService.ObjectMeta.Labels, curService.ObjectMeta.Labels)
+		newService.ObjectMeta.Annotations = util.MergeStringMapsPreserve(newService.ObjectMeta.Annotations, curService.ObjectMeta.Annotations)
+	}
 	newService.ObjectMeta.Finalizers = util.MergeStringArrays(newService.ObjectMeta.Finalizers, curService.ObjectMeta.Finalizers)
 
 	//
@@ -1458,7 +1799,7 @@ func (w *worker) updateService(
 			M(chi).F().
 			Info("Update Service success: %s/%s", newService.Namespace, newService.Name)
 	} else {
-		w.a.M(chi).F().Error("Update Service fail: %s/%s failed with error %v", newService.Namespace, newService.Name)
+		w.a.M(chi).F().Error("Update Service fail: %s/%s failed with error %v", newService.Namespace, newService.Name, err)
 	}
 
 	return err
@@ -1489,6 +1830,35 @@ func (w *worker) createService(ctx context.Context, chi *api.ClickHouseInstallat
 	return err
 }
 
+// updateSecret
+func (w *worker) updateSecret(ctx context.Context, chi *api.ClickHouseInstallation, secret *core.Secret) error {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return nil
+	}
+
+	updatedSecret, err := w.c.kubeClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, controller.NewUpdateOptions())
+	if err == nil {
+		w.a.V(1).
+			WithEvent(chi, eventActionUpdate, eventReasonUpdateCompleted).
+			WithStatusAction(chi).
+			M(chi).F().
+			Info("Update Secret %s/%s", secret.Namespace, secret.Name)
+		if updatedSecret.ResourceVersion != secret.ResourceVersion {
+			// Secret carries config files, which are propagated into pods the same way as ConfigMap's
+			w.task.cmUpdate = time.Now()
+		}
+	} else {
+		w.a.WithEvent(chi, eventActionUpdate, eventReasonUpdateFailed).
+			WithStatusAction(chi).
+			WithStatusError(chi).
+			M(chi).F().
+			Error("Update Secret %s/%s failed with error %v", secret.Namespace, secret.Name, err)
+	}
+
+	return err
+}
+
 // createSecret
 func (w *worker) createSecret(ctx context.Context, chi *api.ClickHouseInstallation, secret *core.Secret) error {
 	if util.IsContextDone(ctx) {
@@ -1708,6 +2078,9 @@ func (w *worker) updateStatefulSet(ctx context.Context, host *api.ChiHost, regis
 		return nil
 	}
 
+	// Storage of existing PVCs is expanded in-place, StatefulSet is not recreated because of it
+	keepStatefulSetVolumeClaimTemplatesStorage(curStatefulSet, newStatefulSet)
+
 	action := errCRUDRecreate
 	if k8s.IsStatefulSetReady(curStatefulSet) {
 		action = w.c.updateStatefulSet(ctx, curStatefulSet, newStatefulSet, host)
@@ -1741,6 +2114,9 @@ func (w *worker) updateStatefulSet(ctx context.Context, host *api.ChiHost, regis
 			M(host).F().
 			Info("Update StatefulSet(%s/%s) switch from Update to Recreate", namespace, name)
 		w.dumpStatefulSetDiff(host, curStatefulSet, newStatefulSet)
+		if host.GetCHI().GetReconciling().IsStatefulSetRecreatePolicyGraceful() {
+			return w.recreateStatefulSetGracefully(ctx, host, register)
+		}
 		return w.recreateStatefulSet(ctx, host, register)
 	case errCRUDUnexpectedFlow:
 		w.a.V(1).M(host).Warning("Got unexpected flow action. Ignore and continue for now")
@@ -1760,15 +2136,19 @@ func (w *worker) recreateStatefulSet(ctx context.Context, host *api.ChiHost, reg
 
 	_ = w.c.deleteStatefulSet(ctx, host)
 	_ = w.reconcilePVCs(ctx, host, api.DesiredStatefulSet)
+	w.ensureVolumesNodeAffinity(ctx, host)
 	return w.createStatefulSet(ctx, host, register)
 }
 
-// applyPVCResourcesRequests
+// applyPVCResourcesRequests applies resources requests of the template to the PVC.
+// Storage request is not applied, storage is expanded by PVCs resize, which checks storage class allows expansion
 func (w *worker) applyPVCResourcesRequests(
 	pvc *core.PersistentVolumeClaim,
 	template *api.VolumeClaimTemplate,
 ) bool {
-	return w.applyResourcesList(pvc.Spec.Resources.Requests, template.Spec.Resources.Requests)
+	requests := template.Spec.Resources.Requests.DeepCopy()
+	delete(requests, core.ResourceStorage)
+	return w.applyResourcesList(pvc.Spec.Resources.Requests, requests)
 }
 
 // applyResourcesList
@@ -1836,22 +2216,37 @@ func (w *worker) ensureClusterSchemer(host *api.ChiHost) *schemer.ClusterSchemer
 	// Make base cluster connection params
 	clusterConnectionParams := clickhouse.NewClusterConnectionParamsFromCHOpConfig(chop.Config())
 	// Adjust base cluster connection params with per-host props
-	switch clusterConnectionParams.Scheme {
-	case api.ChSchemeAuto:
-		switch {
-		case api.IsPortAssigned(host.HTTPPort):
-			clusterConnectionParams.Scheme = "http"
-			clusterConnectionParams.Port = int(host.HTTPPort)
-		case api.IsPortAssigned(host.HTTPSPort):
-			clusterConnectionParams.Scheme = "https"
-			clusterConnectionParams.Port = int(host.HTTPSPort)
-		}
-	case api.ChSchemeHTTP:
-		clusterConnectionParams.Port = int(host.HTTPPort)
-	case api.ChSchemeHTTPS:
-		clusterConnectionParams.Port = int(host.HTTPSPort)
-	}
+	clusterConnectionParams.SetHostPorts(host.TCPPort, host.TLSPort, host.HTTPPort, host.HTTPSPort)
 	w.schemer = schemer.NewClusterSchemer(clusterConnectionParams, host.Runtime.Version)
+	w.schemer.SetEndpointsGetter(w.getHostEndpoints)
+	w.schemer.SetQueryObserver(metricsHostSQL)
+	w.schemer.SetSlowQueryObserver(metricsHostSlowSQL)
+	w.schemer.SetDDLTimeout(host.GetCHI().GetReconciling().GetTimeouts().GetDDL())
 
 	return w.schemer
 }
+
+// getHostEndpoints gets endpoints the operator connects to the host via, in order of preference.
+// CHI-level 'reconciling.access.endpoints' takes precedence over operator's 'clickhouse.access.endpoints'
+func (w *worker) getHostEndpoints(host *api.ChiHost) (endpoints []string) {
+	kinds := host.GetCHI().GetReconciling().GetAccess().GetEndpoints()
+	if len(kinds) == 0 {
+		kinds = chop.Config().ClickHouse.Access.Endpoints
+	}
+
+	for _, kind := range kinds {
+		switch kind {
+		case api.ChEndpointService:
+			endpoints = append(endpoints, model.CreateFQDN(host))
+		case api.ChEndpointPodFQDN:
+			endpoints = append(endpoints, model.CreatePodOwnFQDN(host))
+		case api.ChEndpointPodIP:
+			if pod, err := w.c.getPod(host); err == nil && pod.Status.PodIP != "" {
+				endpoints = append(endpoints, pod.Status.PodIP)
+			} else {
+				w.a.V(2).M(host).F().Info("no pod IP available for host: %s", host.GetName())
+			}
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,265 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chk
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/metrics"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chk"
+	"github.com/altinity/clickhouse-operator/pkg/model/chk/keeper"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// keeperScrapeTimeout specifies timeout of 'mntr' request to each keeper member during metrics scrape
+const keeperScrapeTimeout = 3 * time.Second
+
+// keeperMonitorGauges maps 'mntr' stats of keeper member to gauges exported by the operator.
+// This is helpful for keeper images, which lack built-in prometheus endpoint
+var keeperMonitorGauges = []struct {
+	stat        string
+	name        string
+	description string
+	unit        string
+}{
+	{"zk_avg_latency", "clickhouse_operator_keeper_avg_latency", "average latency of requests served by keeper member", "ms"},
+	{"zk_max_latency", "clickhouse_operator_keeper_max_latency", "max latency of requests served by keeper member", "ms"},
+	{"zk_min_latency", "clickhouse_operator_keeper_min_latency", "min latency of requests served by keeper member", "ms"},
+	{"zk_outstanding_requests", "clickhouse_operator_keeper_outstanding_requests", "number of requests queued by keeper member", "items"},
+	{"zk_num_alive_connections", "clickhouse_operator_keeper_alive_connections", "number of client connections to keeper member", "items"},
+	{"zk_znode_count", "clickhouse_operator_keeper_znode_count", "number of znodes", "items"},
+	{"zk_watch_count", "clickhouse_operator_keeper_watch_count", "number of watches", "items"},
+	{"zk_ephemerals_count", "clickhouse_operator_keeper_ephemerals_count", "number of ephemeral znodes", "items"},
+	{"zk_approximate_data_size", "clickhouse_operator_keeper_approximate_data_size", "approximate size of data kept by keeper member", "By"},
+}
+
+// keeperMember specifies keeper member to be scraped
+type keeperMember struct {
+	pod        string
+	hostname   string
+	port       int
+	attributes []attribute.KeyValue
+}
+
+// Metrics is a set of keeper metrics tracked by the operator
+type Metrics struct {
+	// KeeperUp is a flag (gauge) whether keeper member replies to 'mntr'
+	KeeperUp metric.Int64ObservableGauge
+	// KeeperIsLeader is a flag (gauge) whether keeper member is the leader
+	KeeperIsLeader metric.Int64ObservableGauge
+	// KeeperMonitor are gauges of 'mntr' stats, in the order of keeperMonitorGauges
+	KeeperMonitor []metric.Int64ObservableGauge
+
+	// ReconcilesStarted is a number (counter) of started CHK reconciles
+	ReconcilesStarted metric.Int64Counter
+	// ReconcilesCompleted is a number (counter) of successfully completed CHK reconciles
+	ReconcilesCompleted metric.Int64Counter
+	// ReconcilesErrors is a number (counter) of failed CHK reconciles
+	ReconcilesErrors metric.Int64Counter
+	// ReconcilesTimings is a histogram of durations of successfully completed CHK reconciles
+	ReconcilesTimings metric.Float64Histogram
+}
+
+var m *Metrics
+
+// keepers keeps members of each known CHK, to be scraped on metrics collection
+var keepers = struct {
+	sync.Mutex
+	members map[string][]keeperMember
+}{
+	members: make(map[string][]keeperMember),
+}
+
+func createMetrics() *Metrics {
+	var instruments []metric.Observable
+
+	KeeperUp, _ := metrics.Meter().Int64ObservableGauge(
+		"clickhouse_operator_keeper_up",
+		metric.WithDescription("whether keeper member replies to 'mntr' command, 1 means up"),
+		metric.WithUnit("items"),
+	)
+	KeeperIsLeader, _ := metrics.Meter().Int64ObservableGauge(
+		"clickhouse_operator_keeper_is_leader",
+		metric.WithDescription("whether keeper member is the leader, 1 means leader"),
+		metric.WithUnit("items"),
+	)
+	instruments = append(instruments, KeeperUp, KeeperIsLeader)
+
+	var KeeperMonitor []metric.Int64ObservableGauge
+	for _, g := range keeperMonitorGauges {
+		gauge, _ := metrics.Meter().Int64ObservableGauge(
+			g.name,
+			metric.WithDescription(g.description),
+			metric.WithUnit(g.unit),
+		)
+		KeeperMonitor = append(KeeperMonitor, gauge)
+		instruments = append(instruments, gauge)
+	}
+
+	ReconcilesStarted, _ := metrics.Meter().Int64Counter(
+		"clickhouse_operator_chk_reconciles_started",
+		metric.WithDescription("number of CHK reconciles started"),
+		metric.WithUnit("items"),
+	)
+	ReconcilesCompleted, _ := metrics.Meter().Int64Counter(
+		"clickhouse_operator_chk_reconciles_completed",
+		metric.WithDescription("number of CHK reconciles completed successfully"),
+		metric.WithUnit("items"),
+	)
+	ReconcilesErrors, _ := metrics.Meter().Int64Counter(
+		"clickhouse_operator_chk_reconciles_errors",
+		metric.WithDescription("number of CHK reconciles errors"),
+		metric.WithUnit("items"),
+	)
+	ReconcilesTimings, _ := metrics.Meter().Float64Histogram(
+		"clickhouse_operator_chk_reconciles_timings",
+		metric.WithDescription("timings of CHK reconciles completed successfully"),
+		metric.WithUnit("s"),
+	)
+
+	res := &Metrics{
+		KeeperUp:       KeeperUp,
+		KeeperIsLeader: KeeperIsLeader,
+		KeeperMonitor:  KeeperMonitor,
+
+		ReconcilesStarted:   ReconcilesStarted,
+		ReconcilesCompleted: ReconcilesCompleted,
+		ReconcilesErrors:    ReconcilesErrors,
+		ReconcilesTimings:   ReconcilesTimings,
+	}
+
+	// All gauges are observed by one callback, so each member is scraped once per collection
+	if _, err := metrics.Meter().RegisterCallback(res.observeKeepers, instruments...); err != nil {
+		log.Warning("unable to register keeper metrics callback: %v", err)
+	}
+
+	return res
+}
+
+func ensureMetrics() *Metrics {
+	if (m == nil) && (metrics.Meter() != nil) {
+		m = createMetrics()
+	}
+	return m
+}
+
+// prepareLabels prepares attributes of the CHK
+func prepareLabels(chk *apiChk.ClickHouseKeeperInstallation) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("chk", chk.Name),
+		attribute.String("namespace", chk.Namespace),
+	}
+}
+
+func metricsReconcilesStarted(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation) {
+	if m := ensureMetrics(); m != nil {
+		m.ReconcilesStarted.Add(ctx, 1, metric.WithAttributes(prepareLabels(chk)...))
+	}
+}
+func metricsReconcilesCompleted(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation) {
+	if m := ensureMetrics(); m != nil {
+		m.ReconcilesCompleted.Add(ctx, 1, metric.WithAttributes(prepareLabels(chk)...))
+	}
+}
+func metricsReconcilesErrors(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation) {
+	if m := ensureMetrics(); m != nil {
+		m.ReconcilesErrors.Add(ctx, 1, metric.WithAttributes(prepareLabels(chk)...))
+	}
+}
+func metricsReconcilesTimings(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation, seconds float64) {
+	if m := ensureMetrics(); m != nil {
+		m.ReconcilesTimings.Record(ctx, seconds, metric.WithAttributes(prepareLabels(chk)...))
+	}
+}
+
+// metricsKeeperWatch remembers members of the CHK to be scraped
+func metricsKeeperWatch(chk *apiChk.ClickHouseKeeperInstallation) {
+	ensureMetrics()
+	var members []keeperMember
+	for id := 0; id < model.GetReplicasCount(chk); id++ {
+		pod := model.GetMemberPodName(chk, id)
+		members = append(members, keeperMember{
+			pod:        pod,
+			hostname:   model.GetMemberHostname(chk, id),
+			port:       chk.Spec.GetClientPort(),
+			attributes: append(prepareLabels(chk), attribute.String("pod", pod)),
+		})
+	}
+
+	keepers.Lock()
+	defer keepers.Unlock()
+	keepers.members[util.NamespaceNameString(chk.ObjectMeta)] = members
+}
+
+// metricsKeeperForget forgets members of the deleted CHK
+func metricsKeeperForget(key string) {
+	keepers.Lock()
+	defer keepers.Unlock()
+	delete(keepers.members, key)
+}
+
+// observeKeepers scrapes all known keeper members concurrently and observes their stats
+func (m *Metrics) observeKeepers(ctx context.Context, observer metric.Observer) error {
+	keepers.Lock()
+	var members []keeperMember
+	for _, list := range keepers.members {
+		members = append(members, list...)
+	}
+	keepers.Unlock()
+
+	stats := make([]map[string]string, len(members))
+	var wg sync.WaitGroup
+	for i := range members {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client := keeper.NewClient(members[i].hostname, members[i].port).SetTimeout(keeperScrapeTimeout)
+			stats[i], _ = client.Monitor(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, member := range members {
+		attributes := metric.WithAttributes(member.attributes...)
+		if stats[i] == nil {
+			observer.ObserveInt64(m.KeeperUp, 0, attributes)
+			continue
+		}
+		observer.ObserveInt64(m.KeeperUp, 1, attributes)
+
+		leader := int64(0)
+		switch stats[i]["zk_server_state"] {
+		case keeper.ServerStateLeader, keeper.ServerStateStandalone:
+			leader = 1
+		}
+		observer.ObserveInt64(m.KeeperIsLeader, leader, attributes)
+
+		for j, g := range keeperMonitorGauges {
+			// Stats may be reported as float, such as average latency
+			if value, err := strconv.ParseFloat(stats[i][g.stat], 64); err == nil {
+				observer.ObserveInt64(m.KeeperMonitor[j], int64(value), attributes)
+			}
+		}
+	}
+	return nil
+}
@@ -61,6 +61,8 @@ func (r *ChkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	new = &apiChk.ClickHouseKeeperInstallation{}
 	if err := r.Get(ctx, req.NamespacedName, new); err != nil {
 		if apiErrors.IsNotFound(err) {
+			// Deleted CHK is not scraped for metrics anymore
+			metricsKeeperForget(req.NamespacedName.String())
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected.
 			// For additional cleanup logic use finalizers.
@@ -71,6 +73,9 @@ func (r *ChkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return ctrl.Result{}, err
 	}
 
+	// Templates edits do not bump generation of the CHK, thus are told by checksum of templates applied
+	appliedTemplatesChecksum := new.GetStatus().GetTemplatesChecksum()
+
 	if new.HasAncestor() {
 		log.V(2).M(new).F().Info("has ancestor, use it as a base for reconcile. CHK: %s/%s", new.Namespace, new.Name)
 		old = new.GetAncestor()
@@ -85,25 +90,65 @@ func (r *ChkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	log.V(2).M(new).F().Info("Normalized NEW CHK %s/%s", new.Namespace, new.Name)
 	new = r.normalize(new)
 	new.SetAncestor(old)
+	metricsKeeperWatch(new)
+
+	startTime := time.Now()
+	metricsReconcilesStarted(ctx, new)
+	// Reconciles aborted by shutdown or deletion of the CHK are counted as neither completed nor failed
+	failed := func(err error) (ctrl.Result, error) {
+		metricsReconcilesErrors(ctx, new)
+		return ctrl.Result{}, err
+	}
 
 	if util.IsContextDone(ctx) {
 		log.V(2).Info("task is done")
 		return ctrl.Result{}, nil
 	}
 
-	if old.GetGeneration() != new.GetGeneration() {
+	zoneAware := model.GetZoneAwareness(new).IsEnabled()
+	if zoneAware {
+		// Raft priorities of the members depend on zones members are located in
+		if zones, err := r.getMemberZones(new); err == nil {
+			new.Runtime.MemberZones = zones
+		} else {
+			log.V(1).M(new).F().Warning("Unable to get zones of the members. err: %v", err)
+		}
+	}
+
+	if (old.GetGeneration() != new.GetGeneration()) || isTemplatesChanged(appliedTemplatesChecksum, new) {
+		// Members are restarted one by one, followers before the leader
+		reconcileStatefulSet := r.reconcileStatefulSetOrdered
+		if isMembershipChanged(new) {
+			// Members are added or removed by quorum reconfiguration, existing members are restarted only if required
+			reconcileStatefulSet = r.reconcileStatefulSetMembership
+		} else if isVolumeClaimTemplatesChanged(new) {
+			// Members are moved onto new volumes one by one, each re-syncing data from the quorum
+			reconcileStatefulSet = r.reconcileStatefulSetVolumes
+		}
 		for _, f := range []reconcileFunc{
 			r.reconcileConfigMap,
-			r.reconcileStatefulSet,
+			reconcileStatefulSet,
 			r.reconcileClientService,
 			r.reconcileHeadlessService,
 			r.reconcilePodDisruptionBudget,
 		} {
 			if err := f(new); err != nil {
 				log.V(1).Error("Error during reconcile. f: %s err: %s", getFunctionName(f), err)
-				return reconcile.Result{}, err
+				return failed(err)
 			}
 		}
+	} else if zoneAware {
+		// Members may have been (re)scheduled into another zones, keep raft priorities up-to-date
+		if err := r.reconcileConfigMap(new); err != nil {
+			log.V(1).Error("Error during reconcile. f: %s err: %s", getFunctionName(r.reconcileConfigMap), err)
+			return failed(err)
+		}
+	}
+	if zoneAware {
+		// Priorities of config files are ignored by the quorum with reconfiguration enabled
+		if err := r.reconcileQuorumPriorities(new); err != nil {
+			log.V(1).M(new).F().Warning("Unable to update raft priorities of the members. err: %v", err)
+		}
 	}
 
 	// Fetch the ClickHouseKeeper instance
@@ -117,14 +162,16 @@ func (r *ChkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 			return ctrl.Result{}, nil
 		}
 		// Return and requeue
-		return ctrl.Result{}, err
+		return failed(err)
 	}
 
 	if err := r.reconcileClusterStatus(new); err != nil {
 		log.V(1).Error("Error during reconcile status. f: %s err: %s", getFunctionName(r.reconcileClusterStatus), err)
-		return reconcile.Result{}, err
+		return failed(err)
 	}
 
+	metricsReconcilesCompleted(ctx, new)
+	metricsReconcilesTimings(ctx, new, time.Since(startTime).Seconds())
 	return ctrl.Result{}, nil
 }
 
@@ -282,6 +329,8 @@ func (r *ChkReconciler) reconcileClusterStatus(chk *apiChk.ClickHouseKeeperInsta
 				})
 		}
 
+		cur.Status.Ready = fmt.Sprintf("%d/%d", len(readyMembers), model.GetReplicasCount(chk))
+
 		log.V(2).Info("ReadyReplicas: " + fmt.Sprintf("%v", cur.Status.ReadyReplicas))
 
 		if len(readyMembers) == model.GetReplicasCount(chk) {
@@ -290,6 +339,9 @@ func (r *ChkReconciler) reconcileClusterStatus(chk *apiChk.ClickHouseKeeperInsta
 			cur.Status.Status = "In progress"
 		}
 
+		cur.Status.UsedTemplates = chk.GetStatus().GetUsedTemplates()
+		cur.Status.TemplatesChecksum = chk.GetStatus().GetTemplatesChecksum()
+
 		cur.Status.NormalizedCHK = nil
 		cur.Status.NormalizedCHKCompleted = chk.DeepCopy()
 		cur.Status.NormalizedCHKCompleted.ObjectMeta.ResourceVersion = ""
@@ -306,7 +358,7 @@ func (r *ChkReconciler) reconcileClusterStatus(chk *apiChk.ClickHouseKeeperInsta
 
 // normalize
 func (r *ChkReconciler) normalize(c *apiChk.ClickHouseKeeperInstallation) *apiChk.ClickHouseKeeperInstallation {
-	chk, err := model.NewNormalizer().CreateTemplatedCHK(c, normalizer.NewOptions())
+	chk, err := model.NewNormalizer(r.getTemplates).CreateTemplatedCHK(c, normalizer.NewOptions())
 	if err != nil {
 		log.V(1).
 			M(chk).F().
@@ -314,3 +366,60 @@ func (r *ChkReconciler) normalize(c *apiChk.ClickHouseKeeperInstallation) *apiCh
 	}
 	return chk
 }
+
+// getTemplates gets all CHK templates available
+func (r *ChkReconciler) getTemplates() (templates []*apiChk.ClickHouseKeeperInstallation) {
+	list := &apiChk.ClickHouseKeeperInstallationTemplateList{}
+	if err := r.List(context.TODO(), list); err != nil {
+		log.V(1).Warning("unable to list CHK templates. err: %v", err)
+		return nil
+	}
+	for i := range list.Items {
+		templates = append(templates, (*apiChk.ClickHouseKeeperInstallation)(&list.Items[i]))
+	}
+	return templates
+}
+
+// isTemplatesChanged checks whether templates applied to the CHK have changed since the previous reconcile.
+// CHK reconciled by the operator w/o templates checksum is not treated as changed, checksum is stored instead
+func isTemplatesChanged(applied string, chk *apiChk.ClickHouseKeeperInstallation) bool {
+	return (applied != "") && (applied != chk.GetStatus().GetTemplatesChecksum())
+}
+
+// MapTemplateToCHKs maps CHK template to reconcile requests of CHKs the template applies to,
+// so edits of the template reach CHKs using it
+func (r *ChkReconciler) MapTemplateToCHKs(ctx context.Context, obj client.Object) (requests []reconcile.Request) {
+	template, ok := obj.(*apiChk.ClickHouseKeeperInstallationTemplate)
+	if !ok {
+		return nil
+	}
+	list := &apiChk.ClickHouseKeeperInstallationList{}
+	if err := r.List(ctx, list); err != nil {
+		log.V(1).Warning("unable to list CHKs. err: %v", err)
+		return nil
+	}
+	auto := (*apiChk.ClickHouseKeeperInstallation)(template).IsAuto()
+	for i := range list.Items {
+		chk := &list.Items[i]
+		if auto || isTemplateUsed(chk, template) {
+			requests = append(requests, reconcile.Request{NamespacedName: getNamespacedName(chk)})
+		}
+	}
+	return requests
+}
+
+// isTemplateUsed checks whether the CHK uses or requests the template
+func isTemplateUsed(chk *apiChk.ClickHouseKeeperInstallation, template *apiChk.ClickHouseKeeperInstallationTemplate) bool {
+	refs := append([]*apiChi.TemplateRef{}, chk.Spec.UseTemplates...)
+	refs = append(refs, chk.GetStatus().GetUsedTemplates()...)
+	for _, ref := range refs {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = chk.Namespace
+		}
+		if (ref.Name == template.Name) && (namespace == template.Namespace) {
+			return true
+		}
+	}
+	return false
+}
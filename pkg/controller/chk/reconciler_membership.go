@@ -0,0 +1,236 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	apiEquality "k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chk"
+	"github.com/altinity/clickhouse-operator/pkg/model/chk/keeper"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+const (
+	// memberWaitTimeout specifies how long to wait for members to start or to join the quorum
+	memberWaitTimeout = 5 * time.Minute
+	// memberPollInterval specifies how often members are polled
+	memberPollInterval = 5 * time.Second
+)
+
+// isMembershipChanged checks whether the number of members of the CHK is changed
+func isMembershipChanged(chk *apiChk.ClickHouseKeeperInstallation) bool {
+	if !chk.HasAncestor() {
+		return false
+	}
+	oldCount := model.GetReplicasCount(chk.GetAncestor())
+	newCount := model.GetReplicasCount(chk)
+	return (oldCount != 0) && (newCount != 0) && (oldCount != newCount)
+}
+
+// isPodTemplateChanged checks whether pod template of the members is changed, so members have to be restarted
+func isPodTemplateChanged(chk *apiChk.ClickHouseKeeperInstallation) bool {
+	if !chk.HasAncestor() {
+		return true
+	}
+	return !apiEquality.Semantic.DeepEqual(
+		model.CreateStatefulSet(chk.GetAncestor()).Spec.Template,
+		model.CreateStatefulSet(chk).Spec.Template,
+	)
+}
+
+// reconcileStatefulSetMembership changes the number of members of the quorum w/o restarting existing members.
+// Keeper with reconfiguration enabled ignores raft configuration of config files, thus members are always
+// added or removed by quorum reconfiguration. Added members are started and then joined to the quorum with 'reconfig'
+// command, removed members are excluded from the quorum with 'reconfig' command first and then stopped.
+// Other changes of members are applied afterwards by ordered restart.
+// In case Keeper does not support dynamic reconfiguration, raft configuration of config files is applied by rolling restart.
+func (r *ChkReconciler) reconcileStatefulSetMembership(chk *apiChk.ClickHouseKeeperInstallation) error {
+	ctx := context.TODO()
+	oldCount := model.GetReplicasCount(chk.GetAncestor())
+	newCount := model.GetReplicasCount(chk)
+
+	var err error
+	if newCount > oldCount {
+		err = r.scaleOut(ctx, chk, oldCount, newCount)
+	} else {
+		err = r.scaleIn(ctx, chk, oldCount, newCount)
+	}
+	switch {
+	case errors.Is(err, keeper.ErrReconfigNotSupported):
+		log.V(1).M(chk).F().Warning("Quorum can not be reconfigured dynamically, fallback to rolling restart. err: %v", err)
+		return r.reconcileStatefulSet(chk)
+	case err != nil:
+		// Raft configuration of config files is ignored by members, so rolling restart would not change the quorum
+		return fmt.Errorf("unable to reconfigure quorum: %w", err)
+	}
+
+	if isPodTemplateChanged(chk) {
+		return r.reconcileStatefulSetOrdered(chk)
+	}
+	return nil
+}
+
+// scaleOut starts new members and joins them to the existing quorum
+func (r *ChkReconciler) scaleOut(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation, oldCount, newCount int) error {
+	log.V(1).M(chk).F().Info("Scale out keeper quorum from %d to %d members", oldCount, newCount)
+
+	if err := r.reconcileStatefulSetReplicas(chk); err != nil {
+		return err
+	}
+
+	var ids []int
+	var joining []string
+	for id := oldCount; id < newCount; id++ {
+		ids = append(ids, id)
+		joining = append(joining, model.CreateRaftMember(chk, id).String())
+	}
+
+	// New members have to be up and running before being joined
+	if err := waitMembers(ctx, chk, ids, "running", (*keeper.Client).IsOK); err != nil {
+		return err
+	}
+
+	if err := getQuorumClient(chk).Reconfig(ctx, joining, nil); err != nil {
+		return err
+	}
+	log.V(1).M(chk).F().Info("Members joined the quorum: %v", joining)
+
+	return waitMembers(ctx, chk, ids, "joined", (*keeper.Client).IsQuorumMember)
+}
+
+// scaleIn excludes members from the quorum and stops them
+func (r *ChkReconciler) scaleIn(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation, oldCount, newCount int) error {
+	log.V(1).M(chk).F().Info("Scale in keeper quorum from %d to %d members", oldCount, newCount)
+
+	var leaving []int
+	for id := newCount; id < oldCount; id++ {
+		leaving = append(leaving, id)
+	}
+
+	// Members have to leave the quorum before being stopped, otherwise the quorum may be lost
+	if err := getQuorumClient(chk).Reconfig(ctx, nil, leaving); err != nil {
+		return err
+	}
+	log.V(1).M(chk).F().Info("Members left the quorum: %v", leaving)
+
+	return r.reconcileStatefulSetReplicas(chk)
+}
+
+// reconcileQuorumPriorities updates raft priorities of the members of the quorum with 'reconfig' command,
+// since Keeper with reconfiguration enabled ignores priorities specified in config files.
+// Members are neither added nor removed here, membership is changed by scaling only
+func (r *ChkReconciler) reconcileQuorumPriorities(chk *apiChk.ClickHouseKeeperInstallation) error {
+	ctx := context.TODO()
+	client := getQuorumClient(chk)
+	actual, err := client.GetRaftConfig(ctx)
+	switch {
+	case errors.Is(err, keeper.ErrReconfigNotSupported):
+		// Priorities are applied from config files
+		return nil
+	case err != nil:
+		return err
+	}
+
+	desired := make(map[int]keeper.RaftMember)
+	for id, member := range model.CreateRaftConfig(chk) {
+		if _, ok := actual[id]; ok {
+			desired[id] = member
+		}
+	}
+	for id := range actual {
+		if _, ok := desired[id]; !ok {
+			// Member is not managed here, keep it as is
+			desired[id] = actual[id]
+		}
+	}
+
+	joining, _ := keeper.DiffRaftConfig(actual, desired)
+	if len(joining) == 0 {
+		return nil
+	}
+	if err := client.Reconfig(ctx, joining, nil); err != nil {
+		return err
+	}
+	log.V(1).M(chk).F().Info("Members of the quorum updated: %v", joining)
+	return nil
+}
+
+// reconcileStatefulSetReplicas updates number of replicas of the StatefulSet only, so existing pods are not restarted
+func (r *ChkReconciler) reconcileStatefulSetReplicas(chk *apiChk.ClickHouseKeeperInstallation) error {
+	return r.reconcile(
+		chk,
+		&apps.StatefulSet{},
+		model.CreateStatefulSet(chk),
+		"StatefulSet",
+		func(curObject, newObject client.Object) error {
+			cur, ok1 := curObject.(*apps.StatefulSet)
+			new, ok2 := newObject.(*apps.StatefulSet)
+			if !ok1 || !ok2 {
+				return fmt.Errorf("unable to cast")
+			}
+			cur.Spec.Replicas = new.Spec.Replicas
+			return nil
+		},
+	)
+}
+
+// getQuorumClient gets client to the member which is kept in the quorum regardless of the scaling direction
+func getQuorumClient(chk *apiChk.ClickHouseKeeperInstallation) *keeper.Client {
+	return getMemberClient(chk, 0)
+}
+
+// getMemberClient gets client to the specified member
+func getMemberClient(chk *apiChk.ClickHouseKeeperInstallation, id int) *keeper.Client {
+	return keeper.NewClient(model.GetMemberHostname(chk, id), chk.Spec.GetClientPort())
+}
+
+// waitMembers waits for all specified members to satisfy the condition
+func waitMembers(
+	ctx context.Context,
+	chk *apiChk.ClickHouseKeeperInstallation,
+	ids []int,
+	description string,
+	condition func(*keeper.Client, context.Context) bool,
+) error {
+	start := time.Now()
+	for {
+		var pending []int
+		for _, id := range ids {
+			if !condition(getMemberClient(chk, id), ctx) {
+				pending = append(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			log.V(1).M(chk).F().Info("Members are %s: %v", description, ids)
+			return nil
+		}
+		if time.Since(start) > memberWaitTimeout {
+			return fmt.Errorf("members are not %s in time: %v", description, pending)
+		}
+		log.V(2).M(chk).F().Info("Wait for members to be %s: %v", description, pending)
+		if util.WaitContextDoneOrTimeout(ctx, memberPollInterval) {
+			return fmt.Errorf("task is done")
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chk"
+	"github.com/altinity/clickhouse-operator/pkg/model/chk/keeper"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// reconcileStatefulSetOrdered updates StatefulSet of the CHK, restarting members in quorum-safe order.
+// Followers are restarted one by one before the leader, and after each restart the operator waits for the member
+// to re-join the quorum and for the quorum to have a leader elected, so no more than one member is out at a time.
+// StatefulSet is updated with OnDelete strategy, so pods are restarted by the operator and not by the StatefulSet controller.
+// In case members can not be polled, falls back to the StatefulSet rolling restart.
+func (r *ChkReconciler) reconcileStatefulSetOrdered(chk *apiChk.ClickHouseKeeperInstallation) error {
+	ctx := context.TODO()
+	count := model.GetReplicasCount(chk)
+	if !chk.HasAncestor() || (count < 2) {
+		// Nothing to order - either new CHK or single member
+		return r.reconcileStatefulSet(chk)
+	}
+
+	statefulSet := model.CreateStatefulSet(chk)
+	if err := r.Get(ctx, getNamespacedName(statefulSet), &apps.StatefulSet{}); err != nil {
+		if apiErrors.IsNotFound(err) {
+			return r.reconcileStatefulSet(chk)
+		}
+		return err
+	}
+
+	order, err := getRestartOrder(ctx, chk)
+	if err != nil {
+		log.V(1).M(chk).F().Warning("Unable to order members restart, fallback to rolling restart. err: %v", err)
+		return r.reconcileStatefulSet(chk)
+	}
+	log.V(1).M(chk).F().Info("Restart keeper members in order: %v", order)
+
+	if err := r.reconcileStatefulSetOnDelete(chk); err != nil {
+		return err
+	}
+	for _, id := range order {
+		if err := r.restartMember(ctx, chk, id); err != nil {
+			return err
+		}
+	}
+
+	// Pods are of the updated revision already, so restoring rolling update strategy does not restart them
+	return r.reconcileStatefulSetReplicasAndStrategy(chk)
+}
+
+// getRestartOrder gets ids of the members in the order they are to be restarted - followers first, leader last.
+// Members, which are out of the quorum already, are restarted before followers.
+func getRestartOrder(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation) ([]int, error) {
+	var unavailable, followers, leaders []int
+	for id := 0; id < model.GetReplicasCount(chk); id++ {
+		state, err := getMemberClient(chk, id).ServerState(ctx)
+		switch {
+		case err != nil:
+			unavailable = append(unavailable, id)
+		case state == keeper.ServerStateLeader:
+			leaders = append(leaders, id)
+		default:
+			followers = append(followers, id)
+		}
+	}
+	if len(unavailable) == model.GetReplicasCount(chk) {
+		return nil, fmt.Errorf("no members are available")
+	}
+	return append(append(unavailable, followers...), leaders...), nil
+}
+
+// reconcileStatefulSetOnDelete updates StatefulSet with OnDelete strategy, so pods are not restarted on update
+func (r *ChkReconciler) reconcileStatefulSetOnDelete(chk *apiChk.ClickHouseKeeperInstallation) error {
+	return r.reconcile(
+		chk,
+		&apps.StatefulSet{},
+		model.CreateStatefulSet(chk),
+		"StatefulSet",
+		func(curObject, newObject client.Object) error {
+			cur, ok1 := curObject.(*apps.StatefulSet)
+			new, ok2 := newObject.(*apps.StatefulSet)
+			if !ok1 || !ok2 {
+				return fmt.Errorf("unable to cast")
+			}
+			markPodRestartedNow(new)
+			cur.Spec.Replicas = new.Spec.Replicas
+			cur.Spec.Template = new.Spec.Template
+			cur.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
+				Type: apps.OnDeleteStatefulSetStrategyType,
+			}
+			return nil
+		},
+	)
+}
+
+// reconcileStatefulSetReplicasAndStrategy updates number of replicas and update strategy of the StatefulSet only
+func (r *ChkReconciler) reconcileStatefulSetReplicasAndStrategy(chk *apiChk.ClickHouseKeeperInstallation) error {
+	return r.reconcile(
+		chk,
+		&apps.StatefulSet{},
+		model.CreateStatefulSet(chk),
+		"StatefulSet",
+		func(curObject, newObject client.Object) error {
+			cur, ok1 := curObject.(*apps.StatefulSet)
+			new, ok2 := newObject.(*apps.StatefulSet)
+			if !ok1 || !ok2 {
+				return fmt.Errorf("unable to cast")
+			}
+			cur.Spec.Replicas = new.Spec.Replicas
+			cur.Spec.UpdateStrategy = new.Spec.UpdateStrategy
+			return nil
+		},
+	)
+}
+
+// restartMember deletes pod of the member and waits for the pod to be recreated, for the member to re-join the quorum
+// and for the quorum to have a leader
+func (r *ChkReconciler) restartMember(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation, id int) error {
+	name := types.NamespacedName{
+		Namespace: chk.Namespace,
+		Name:      model.GetMemberPodName(chk, id),
+	}
+	log.V(1).M(chk).F().Info("Restart keeper member: %d pod: %s", id, name)
+
+	pod := &core.Pod{}
+	if err := r.Get(ctx, name, pod); err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return err
+		}
+	} else {
+		uid := pod.UID
+		if err := r.Delete(ctx, pod); err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+		if err := r.waitPodRecreated(ctx, chk, name, uid); err != nil {
+			return err
+		}
+	}
+
+	if err := waitMembers(ctx, chk, []int{id}, "joined", (*keeper.Client).IsQuorumMember); err != nil {
+		return err
+	}
+	return waitLeader(ctx, chk)
+}
+
+// waitPodRecreated waits for the pod to be recreated, replacing the pod with the specified uid, and to get ready
+func (r *ChkReconciler) waitPodRecreated(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation, name types.NamespacedName, uid types.UID) error {
+	start := time.Now()
+	for {
+		pod := &core.Pod{}
+		if err := r.Get(ctx, name, pod); err == nil && (pod.UID != uid) && isPodReady(pod) {
+			return nil
+		}
+		if time.Since(start) > memberWaitTimeout {
+			return fmt.Errorf("pod %s is not recreated in time", name)
+		}
+		log.V(2).M(chk).F().Info("Wait for pod to be recreated: %s", name)
+		if util.WaitContextDoneOrTimeout(ctx, memberPollInterval) {
+			return fmt.Errorf("task is done")
+		}
+	}
+}
+
+// waitLeader waits for the quorum to have a leader elected
+func waitLeader(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation) error {
+	start := time.Now()
+	for {
+		for id := 0; id < model.GetReplicasCount(chk); id++ {
+			if state, err := getMemberClient(chk, id).ServerState(ctx); err == nil && (state == keeper.ServerStateLeader) {
+				log.V(1).M(chk).F().Info("Keeper leader is elected: %d", id)
+				return nil
+			}
+		}
+		if time.Since(start) > memberWaitTimeout {
+			return fmt.Errorf("no leader is elected in time")
+		}
+		log.V(2).M(chk).F().Info("Wait for keeper leader to be elected")
+		if util.WaitContextDoneOrTimeout(ctx, memberPollInterval) {
+			return fmt.Errorf("task is done")
+		}
+	}
+}
+
+// isPodReady checks whether all containers of the pod are ready
+func isPodReady(pod *core.Pod) bool {
+	if len(pod.Status.ContainerStatuses) == 0 {
+		return false
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if !containerStatus.Ready {
+			return false
+		}
+	}
+	return true
+}
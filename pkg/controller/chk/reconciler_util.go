@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	apps "k8s.io/api/apps/v1"
@@ -80,6 +82,43 @@ func (r *ChkReconciler) getReadyPods(chk *api.ClickHouseKeeperInstallation) ([]s
 	return readyPods, nil
 }
 
+// getMemberZones gets zones where keeper members are located, indexed by member id.
+// Zone of a member is taken from the label of the node the member's pod is scheduled on.
+func (r *ChkReconciler) getMemberZones(chk *api.ClickHouseKeeperInstallation) (map[int]string, error) {
+	labelSelector := labels.SelectorFromSet(model.GetPodLabels(chk))
+	listOps := &client.ListOptions{
+		Namespace:     chk.Namespace,
+		LabelSelector: labelSelector,
+	}
+	podList := &core.PodList{}
+	if err := r.List(context.TODO(), podList, listOps); err != nil {
+		return nil, err
+	}
+
+	topologyKey := model.GetZoneAwareness(chk).GetTopologyKey()
+	zones := make(map[int]string)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" {
+			// Pod is not scheduled yet
+			continue
+		}
+		// Member id is the ordinal of the StatefulSet's pod
+		id, err := strconv.Atoi(pod.Name[strings.LastIndex(pod.Name, "-")+1:])
+		if err != nil {
+			continue
+		}
+		node := &core.Node{}
+		if err := r.Get(context.TODO(), types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+			return nil, err
+		}
+		if zone, ok := node.Labels[topologyKey]; ok {
+			zones[id] = zone
+		}
+	}
+
+	return zones, nil
+}
+
 func markPodRestartedNow(sts *apps.StatefulSet) {
 	v, _ := time.Now().UTC().MarshalText()
 	sts.Spec.Template.Annotations = map[string]string{"kubectl.kubernetes.io/restartedAt": string(v)}
@@ -0,0 +1,248 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apiEquality "k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chk"
+	"github.com/altinity/clickhouse-operator/pkg/model/chk/keeper"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// volumeMigrationMinMembers specifies how many members the CHK should have for volumes to be migrated.
+// Member being migrated starts with empty volume, so the rest of members have to keep the quorum meanwhile
+const volumeMigrationMinMembers = 3
+
+// isVolumeClaimTemplatesChanged checks whether volume claim templates of the CHK are changed, such as storage class or size
+func isVolumeClaimTemplatesChanged(chk *apiChk.ClickHouseKeeperInstallation) bool {
+	if !chk.HasAncestor() {
+		return false
+	}
+	oldClaims := model.CreateStatefulSet(chk.GetAncestor()).Spec.VolumeClaimTemplates
+	newClaims := model.CreateStatefulSet(chk).Spec.VolumeClaimTemplates
+	if (len(oldClaims) == 0) || (len(newClaims) == 0) {
+		// No persistent data to migrate
+		return false
+	}
+	if len(oldClaims) != len(newClaims) {
+		return true
+	}
+	for i := range newClaims {
+		if !apiEquality.Semantic.DeepEqual(oldClaims[i].Spec, newClaims[i].Spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileStatefulSetVolumes migrates members onto PVCs made of the changed volume claim templates.
+// Volume claim templates of StatefulSet are immutable, so StatefulSet is re-created with its pods left running.
+// Then members are migrated one by one, followers first, the leader last: PVCs and pod of the member are deleted,
+// the member is started on new empty PVCs and re-syncs data from the quorum.
+// Next member is migrated only after the previous one is in sync with the leader, so the quorum is never lost.
+func (r *ChkReconciler) reconcileStatefulSetVolumes(chk *apiChk.ClickHouseKeeperInstallation) error {
+	ctx := context.TODO()
+	count := model.GetReplicasCount(chk)
+	switch {
+	case count != model.GetReplicasCount(chk.GetAncestor()):
+		log.V(1).M(chk).F().Warning("Volumes are not migrated along with members count change, apply volumes change separately")
+		return r.reconcileStatefulSetOrdered(chk)
+	case count < volumeMigrationMinMembers:
+		log.V(1).M(chk).F().Warning("Volumes are not migrated, at least %d members are required to keep the quorum", volumeMigrationMinMembers)
+		return r.reconcileStatefulSetOrdered(chk)
+	}
+
+	order, err := getRestartOrder(ctx, chk)
+	if err != nil {
+		log.V(1).M(chk).F().Warning("Unable to order members migration, volumes are not migrated. err: %v", err)
+		return r.reconcileStatefulSetOrdered(chk)
+	}
+	log.V(1).M(chk).F().Info("Migrate volumes of keeper members in order: %v", order)
+
+	if err := r.recreateStatefulSetOrphan(ctx, chk); err != nil {
+		return err
+	}
+	for _, id := range order {
+		if err := r.migrateMemberVolumes(ctx, chk, id); err != nil {
+			return err
+		}
+	}
+
+	// Pods are of the updated revision already, so restoring rolling update strategy does not restart them
+	return r.reconcileStatefulSetReplicasAndStrategy(chk)
+}
+
+// recreateStatefulSetOrphan deletes StatefulSet leaving its pods and PVCs intact and creates it again with OnDelete strategy,
+// so pods are adopted by the new StatefulSet and are not restarted by the StatefulSet controller
+func (r *ChkReconciler) recreateStatefulSetOrphan(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation) error {
+	statefulSet := model.CreateStatefulSet(chk)
+	name := getNamespacedName(statefulSet)
+
+	cur := &apps.StatefulSet{}
+	if err := r.Get(ctx, name, cur); err == nil {
+		log.V(1).M(chk).F().Info("Delete StatefulSet with pods orphaned: %s", name)
+		if err := r.Delete(ctx, cur, client.PropagationPolicy("Orphan")); err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+	} else if !apiErrors.IsNotFound(err) {
+		return err
+	}
+
+	start := time.Now()
+	for {
+		if err := r.Get(ctx, name, &apps.StatefulSet{}); apiErrors.IsNotFound(err) {
+			break
+		}
+		if time.Since(start) > memberWaitTimeout {
+			return fmt.Errorf("StatefulSet %s is not deleted in time", name)
+		}
+		if util.WaitContextDoneOrTimeout(ctx, memberPollInterval) {
+			return fmt.Errorf("task is done")
+		}
+	}
+
+	statefulSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
+		Type: apps.OnDeleteStatefulSetStrategyType,
+	}
+	return r.reconcile(chk, &apps.StatefulSet{}, statefulSet, "StatefulSet", nil)
+}
+
+// migrateMemberVolumes deletes PVCs and pod of the member and waits for the member to be started on new PVCs
+// and to re-sync data from the quorum. Member, which has PVCs made of the current volume claim templates already, is skipped
+func (r *ChkReconciler) migrateMemberVolumes(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation, id int) error {
+	claims := model.CreateStatefulSet(chk).Spec.VolumeClaimTemplates
+	var pvcs []*core.PersistentVolumeClaim
+	for i := range claims {
+		pvc := &core.PersistentVolumeClaim{}
+		name := types.NamespacedName{
+			Namespace: chk.Namespace,
+			Name:      model.GetMemberPVCName(chk, claims[i].Name, id),
+		}
+		if err := r.Get(ctx, name, pvc); err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !isPVCOfClaim(pvc, &claims[i]) {
+			pvcs = append(pvcs, pvc)
+		}
+	}
+	if len(pvcs) == 0 {
+		log.V(1).M(chk).F().Info("Keeper member %d has volumes migrated already", id)
+		return nil
+	}
+
+	podName := types.NamespacedName{
+		Namespace: chk.Namespace,
+		Name:      model.GetMemberPodName(chk, id),
+	}
+	log.V(1).M(chk).F().Info("Migrate volumes of keeper member: %d pod: %s", id, podName)
+
+	// PVCs are protected from being deleted while used by the pod, so they are gone only after the pod is deleted
+	for _, pvc := range pvcs {
+		if err := r.Delete(ctx, pvc); err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	uid, err := r.deletePod(ctx, podName)
+	if err != nil {
+		return err
+	}
+	if err := r.waitPVCsDeleted(ctx, chk, pvcs); err != nil {
+		return err
+	}
+
+	// Pod may have been re-created before old PVCs were gone, such pod stays pending and has to be re-created once more
+	pod := &core.Pod{}
+	if err := r.Get(ctx, podName, pod); err == nil && (pod.UID != uid) && (pod.Status.Phase == core.PodPending) {
+		if uid, err = r.deletePod(ctx, podName); err != nil {
+			return err
+		}
+	}
+	if err := r.waitPodRecreated(ctx, chk, podName, uid); err != nil {
+		return err
+	}
+
+	if err := waitMembers(ctx, chk, []int{id}, "in sync", (*keeper.Client).IsInSync); err != nil {
+		return err
+	}
+	return waitLeader(ctx, chk)
+}
+
+// deletePod deletes the pod and returns uid of the deleted pod
+func (r *ChkReconciler) deletePod(ctx context.Context, name types.NamespacedName) (types.UID, error) {
+	pod := &core.Pod{}
+	if err := r.Get(ctx, name, pod); err != nil {
+		if apiErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if err := r.Delete(ctx, pod); err != nil && !apiErrors.IsNotFound(err) {
+		return "", err
+	}
+	return pod.UID, nil
+}
+
+// waitPVCsDeleted waits for the PVCs to be deleted. PVC re-created with the same name meanwhile is not waited for
+func (r *ChkReconciler) waitPVCsDeleted(ctx context.Context, chk *apiChk.ClickHouseKeeperInstallation, pvcs []*core.PersistentVolumeClaim) error {
+	start := time.Now()
+	for {
+		var pending []string
+		for _, pvc := range pvcs {
+			cur := &core.PersistentVolumeClaim{}
+			if err := r.Get(ctx, getNamespacedName(pvc), cur); err == nil && (cur.UID == pvc.UID) {
+				pending = append(pending, pvc.Name)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Since(start) > memberWaitTimeout {
+			return fmt.Errorf("PVCs are not deleted in time: %v", pending)
+		}
+		log.V(2).M(chk).F().Info("Wait for PVCs to be deleted: %v", pending)
+		if util.WaitContextDoneOrTimeout(ctx, memberPollInterval) {
+			return fmt.Errorf("task is done")
+		}
+	}
+}
+
+// isPVCOfClaim checks whether PVC is made of the volume claim template, by storage class and requested size
+func isPVCOfClaim(pvc *core.PersistentVolumeClaim, claim *core.PersistentVolumeClaim) bool {
+	if (claim.Spec.StorageClassName != nil) &&
+		((pvc.Spec.StorageClassName == nil) || (*pvc.Spec.StorageClassName != *claim.Spec.StorageClassName)) {
+		return false
+	}
+	want, ok := claim.Spec.Resources.Requests[core.ResourceStorage]
+	if !ok {
+		return true
+	}
+	have := pvc.Spec.Resources.Requests[core.ResourceStorage]
+	return have.Cmp(want) == 0
+}
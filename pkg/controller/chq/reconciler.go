@@ -0,0 +1,214 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chq
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	apiMachinery "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/schemer"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// RetryTime is the delay between attempts to run the query in case CHI is not available yet
+const RetryTime = 30 * time.Second
+
+// ChqReconciler reconciles a ClickHouseQuery object
+type ChqReconciler struct {
+	client.Client
+	// APIReader reads CHIs and Secrets directly from the API server, so no cluster-wide informers are started for them
+	APIReader client.Reader
+	Scheme    *apiMachinery.Scheme
+}
+
+// Reconcile runs the query over the cluster once per generation of the ClickHouseQuery.
+// Query is marked as running in status before it is run, so the query, which is not idempotent,
+// is not re-run neither on status update conflict nor on operator restart
+func (r *ChqReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return ctrl.Result{}, nil
+	}
+
+	chq := &api.ClickHouseQuery{}
+	if err := r.Get(ctx, req.NamespacedName, chq); err != nil {
+		if apiErrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return ctrl.Result{}, nil
+		}
+		// Return and requeue
+		return ctrl.Result{}, err
+	}
+
+	if !chq.DeletionTimestamp.IsZero() || chq.IsDone() || chq.IsRunning() {
+		// Query is not idempotent, so it is not re-run unless spec is changed
+		return ctrl.Result{}, nil
+	}
+
+	cluster := r.prepare(ctx, chq)
+	if cluster == nil {
+		// Query is either pending or failed w/o being run
+		if err := r.Status().Update(ctx, chq); err != nil {
+			log.V(1).M(chq).F().Error("unable to update status of CHQ %s/%s err: %v", chq.Namespace, chq.Name, err)
+			return ctrl.Result{}, err
+		}
+		if chq.GetStatus().Status == api.QueryStatusPending {
+			return ctrl.Result{RequeueAfter: RetryTime}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Persist running state before the query is run. Conflict means CHQ is changed meanwhile and query is not run
+	chq.EnsureStatus().Status = api.QueryStatusRunning
+	if err := r.Status().Update(ctx, chq); err != nil {
+		log.V(1).M(chq).F().Error("unable to update status of CHQ %s/%s err: %v", chq.Namespace, chq.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	r.run(ctx, chq, cluster)
+
+	// Result is reported on the latest CHQ, query is not re-run in case of conflict
+	status := chq.GetStatus().DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(ctx, req.NamespacedName, chq); err != nil {
+			return err
+		}
+		chq.Status = status
+		return r.Status().Update(ctx, chq)
+	})
+	if err != nil {
+		log.V(1).M(chq).F().Error("unable to report result of CHQ %s/%s, query is not re-run. err: %v", chq.Namespace, chq.Name, err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// prepare validates the query and finds the cluster the query is run over.
+// Query is pending till the CHI is available, invalid query is failed
+func (r *ChqReconciler) prepare(ctx context.Context, chq *api.ClickHouseQuery) *api.Cluster {
+	status := chq.EnsureStatus()
+	*status = api.ChqStatus{
+		Status:             api.QueryStatusPending,
+		ObservedGeneration: chq.Generation,
+	}
+	if chq.Spec.CHI == "" {
+		r.fail(chq, fmt.Errorf("no CHI specified"))
+		return nil
+	}
+	if strings.TrimSpace(chq.Spec.Query) == "" {
+		r.fail(chq, fmt.Errorf("no query specified"))
+		return nil
+	}
+
+	chi, err := r.getCHI(ctx, chq)
+	if err != nil {
+		status.Error = err.Error()
+		log.V(1).M(chq).F().Warning("unable to get CHI %s/%s, postpone. err: %v", chq.Namespace, chq.Spec.CHI, err)
+		return nil
+	}
+
+	cluster := chi.FindCluster(chq.Spec.Cluster)
+	if chq.Spec.Cluster == "" {
+		cluster = chi.FindCluster(0)
+	}
+	if cluster == nil {
+		r.fail(chq, fmt.Errorf("no cluster %s found in CHI %s/%s", chq.Spec.Cluster, chq.Namespace, chq.Spec.CHI))
+		return nil
+	}
+	status.Cluster = cluster.Name
+	return cluster
+}
+
+// run runs the query over the cluster and reports per-host results in status.
+// Query is either completed or failed
+func (r *ChqReconciler) run(ctx context.Context, chq *api.ClickHouseQuery, cluster *api.Cluster) {
+	status := chq.EnsureStatus()
+	result, err := newClusterSchemer(cluster).ClusterExecOnCluster(ctx, cluster, chq.Spec.Query)
+	if result != nil {
+		status.Entry = result.Entry
+		status.Finished = result.Finished
+		status.Stragglers = result.Stragglers
+		for _, host := range util.MapGetSortedKeys(result.Failed) {
+			status.Failed = append(status.Failed, api.ChqHostError{
+				Host:  host,
+				Error: result.Failed[host],
+			})
+		}
+		sort.Strings(status.Finished)
+		sort.Strings(status.Stragglers)
+	}
+	switch {
+	case err != nil:
+		r.fail(chq, err)
+	case result == nil:
+		r.fail(chq, fmt.Errorf("query is interrupted"))
+	case !result.IsCompleted():
+		r.fail(chq, fmt.Errorf("query is not completed by all hosts: %s", result))
+	default:
+		status.Status = api.QueryStatusCompleted
+		status.Error = ""
+		log.V(1).M(chq).F().Info("query completed on cluster %s of CHI %s/%s: %s", cluster.Name, chq.Namespace, chq.Spec.CHI, result)
+	}
+}
+
+// fail marks query as failed
+func (r *ChqReconciler) fail(chq *api.ClickHouseQuery, err error) {
+	log.V(1).M(chq).F().Error("query of CHI %s/%s failed. err: %v", chq.Namespace, chq.Spec.CHI, err)
+	status := chq.EnsureStatus()
+	status.Status = api.QueryStatusFailed
+	status.Error = err.Error()
+}
+
+// getCHI gets normalized CHI the query is run on
+func (r *ChqReconciler) getCHI(ctx context.Context, chq *api.ClickHouseQuery) (*api.ClickHouseInstallation, error) {
+	chi := &api.ClickHouseInstallation{}
+	if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: chq.Namespace, Name: chq.Spec.CHI}, chi); err != nil {
+		return nil, err
+	}
+	return normalizer.NewNormalizer(func(namespace, name string) (*core.Secret, error) {
+		secret := &core.Secret{}
+		if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}).CreateTemplatedCHI(chi, normalizer.NewOptions())
+}
+
+// newClusterSchemer creates schemer connecting to the first host of the cluster, which initiates the query
+func newClusterSchemer(cluster *api.Cluster) *schemer.ClusterSchemer {
+	params := clickhouse.NewClusterConnectionParamsFromCHOpConfig(chop.Config())
+	if host := cluster.FirstHost(); host != nil {
+		// Adjust connection params with per-host ports
+		params.SetHostPorts(host.TCPPort, host.TLSPort, host.HTTPPort, host.HTTPSPort)
+	}
+	return schemer.NewClusterSchemer(params, nil).
+		SetDDLTimeout(cluster.GetCHI().GetReconciling().GetTimeouts().GetDDL())
+}
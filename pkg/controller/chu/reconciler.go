@@ -0,0 +1,249 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	apiMachinery "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	chiModel "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chu"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// FinalizerName specifies name of the finalizer, which drops the user from ClickHouse on ClickHouseUser deletion
+const FinalizerName = "finalizer.clickhouseuser.altinity.com"
+
+const (
+	// RetryTime is the delay between attempts to apply the user in case some hosts are not available
+	RetryTime = 30 * time.Second
+	// ResyncTime is the delay between re-applies of the user, which picks up password rotated in the Secret
+	// and hosts added to the CHI
+	ResyncTime = 5 * time.Minute
+)
+
+// ChuReconciler reconciles a ClickHouseUser object
+type ChuReconciler struct {
+	client.Client
+	// APIReader reads CHIs and Secrets directly from the API server, so no cluster-wide informers are started for them
+	APIReader client.Reader
+	Scheme    *apiMachinery.Scheme
+}
+
+// Reconcile applies the user to all hosts of the CHI and drops it on deletion
+func (r *ChuReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("task is done")
+		return ctrl.Result{}, nil
+	}
+
+	chu := &api.ClickHouseUser{}
+	if err := r.Get(ctx, req.NamespacedName, chu); err != nil {
+		if apiErrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return ctrl.Result{}, nil
+		}
+		// Return and requeue
+		return ctrl.Result{}, err
+	}
+
+	if !chu.DeletionTimestamp.IsZero() {
+		return r.delete(ctx, chu)
+	}
+
+	if !controllerutil.ContainsFinalizer(chu, FinalizerName) {
+		controllerutil.AddFinalizer(chu, FinalizerName)
+		if err := r.Update(ctx, chu); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	r.apply(ctx, chu)
+
+	if err := r.Status().Update(ctx, chu); err != nil {
+		log.V(1).M(chu).F().Error("unable to update status of CHU %s/%s err: %v", chu.Namespace, chu.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	switch chu.GetStatus().Status {
+	case api.UserStatusCompleted:
+		return ctrl.Result{RequeueAfter: ResyncTime}, nil
+	case api.UserStatusFailed:
+		// Misconfiguration, wait for the spec to be fixed
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: RetryTime}, nil
+}
+
+// apply creates or updates the user on all hosts of the CHI.
+// User which can not be applied due to misconfiguration is failed, otherwise apply is retried
+func (r *ChuReconciler) apply(ctx context.Context, chu *api.ClickHouseUser) {
+	status := chu.EnsureStatus()
+	status.ObservedGeneration = chu.Generation
+	if err := model.Validate(chu); err != nil {
+		r.fail(chu, err)
+		return
+	}
+
+	status.Status = api.UserStatusPending
+	chi, err := r.getCHI(ctx, chu)
+	if err != nil {
+		status.Error = err.Error()
+		log.V(1).M(chu).F().Warning("unable to get CHI %s/%s, postpone. err: %v", chu.Namespace, chu.Spec.CHI, err)
+		return
+	}
+
+	password, err := r.getSecretValue(ctx, chu.Namespace, chu.Spec.Password)
+	if err != nil {
+		status.Error = err.Error()
+		log.V(1).M(chu).F().Warning("unable to get password, postpone. err: %v", err)
+		return
+	}
+
+	sqls := model.CreateUserSQLs(chu, model.CreatePasswordHash(password))
+	var hosts, failed []string
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		fqdn := chiModel.CreateFQDN(host)
+		// SQLs have password hash inlined, so they are not logged
+		if err := newHostCluster(host).SetLog(log.Silence()).ExecAll(ctx, sqls); err != nil {
+			log.V(1).M(chu).F().Warning("unable to apply user %s on host %s err: %v", chu.GetUserName(), fqdn, err)
+			failed = append(failed, fqdn)
+			return nil
+		}
+		hosts = append(hosts, fqdn)
+		return nil
+	})
+
+	if len(failed) > 0 {
+		// Some hosts may be temporarily unavailable, all SQLs are re-applied next time
+		status.Error = fmt.Sprintf("unable to apply user on hosts: %s", strings.Join(failed, ", "))
+		status.Hosts = hosts
+		return
+	}
+
+	status.Status = api.UserStatusCompleted
+	status.Error = ""
+	status.Hosts = hosts
+	status.UserName = chu.GetUserName()
+	status.Roles = chu.Spec.Roles
+	status.Grants = chu.Spec.Grants
+	log.V(1).M(chu).F().Info("user %s applied on %d hosts of CHI %s/%s", chu.GetUserName(), len(hosts), chu.Namespace, chu.Spec.CHI)
+}
+
+// delete drops the user from all hosts of the CHI and releases ClickHouseUser
+func (r *ChuReconciler) delete(ctx context.Context, chu *api.ClickHouseUser) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(chu, FinalizerName) {
+		return ctrl.Result{}, nil
+	}
+
+	chi, err := r.getCHI(ctx, chu)
+	switch {
+	case apiErrors.IsNotFound(err):
+		// CHI is gone along with its users
+	case err != nil:
+		return ctrl.Result{}, err
+	case !chi.DeletionTimestamp.IsZero():
+		// CHI is being deleted along with its users
+	default:
+		userName := chu.GetStatus().UserName
+		if userName == "" {
+			userName = chu.GetUserName()
+		}
+		sqls := []string{model.CreateDropUserSQL(userName)}
+		var failed []string
+		chi.WalkHosts(func(host *api.ChiHost) error {
+			if err := newHostCluster(host).ExecAll(ctx, sqls); err != nil {
+				failed = append(failed, chiModel.CreateFQDN(host))
+			}
+			return nil
+		})
+		if len(failed) > 0 {
+			log.V(1).M(chu).F().Warning("unable to drop user %s on hosts: %s, retry", userName, strings.Join(failed, ", "))
+			return ctrl.Result{RequeueAfter: RetryTime}, nil
+		}
+		log.V(1).M(chu).F().Info("user %s dropped from CHI %s/%s", userName, chu.Namespace, chu.Spec.CHI)
+	}
+
+	controllerutil.RemoveFinalizer(chu, FinalizerName)
+	return ctrl.Result{}, r.Update(ctx, chu)
+}
+
+// fail marks user as failed
+func (r *ChuReconciler) fail(chu *api.ClickHouseUser, err error) {
+	log.V(1).M(chu).F().Error("user %s of CHI %s/%s failed. err: %v", chu.GetUserName(), chu.Namespace, chu.Spec.CHI, err)
+	status := chu.EnsureStatus()
+	status.Status = api.UserStatusFailed
+	status.Error = err.Error()
+}
+
+// getCHI gets normalized CHI the user is applied to
+func (r *ChuReconciler) getCHI(ctx context.Context, chu *api.ClickHouseUser) (*api.ClickHouseInstallation, error) {
+	chi := &api.ClickHouseInstallation{}
+	if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: chu.Namespace, Name: chu.Spec.CHI}, chi); err != nil {
+		return nil, err
+	}
+	return normalizer.NewNormalizer(func(namespace, name string) (*core.Secret, error) {
+		return r.getSecret(ctx, namespace, name)
+	}).CreateTemplatedCHI(chi, normalizer.NewOptions())
+}
+
+// getSecretValue gets value the setting source refers to
+func (r *ChuReconciler) getSecretValue(ctx context.Context, namespace string, src *api.SettingSource) (string, error) {
+	name, key := src.GetNameKey()
+	secret, err := r.getSecret(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("no key %s found in secret %s/%s", key, namespace, name)
+	}
+	return string(value), nil
+}
+
+// getSecret gets secret
+func (r *ChuReconciler) getSecret(ctx context.Context, namespace, name string) (*core.Secret, error) {
+	secret := &core.Secret{}
+	if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// newHostCluster creates connection to the host
+func newHostCluster(host *api.ChiHost) *clickhouse.Cluster {
+	params := clickhouse.NewClusterConnectionParamsFromCHOpConfig(chop.Config())
+	// Adjust connection params with per-host ports
+	params.SetHostPorts(host.TCPPort, host.TLSPort, host.HTTPPort, host.HTTPSPort)
+	cluster := clickhouse.NewCluster().SetHosts([]string{chiModel.CreateFQDN(host)})
+	cluster.ClusterConnectionParams = params
+	return cluster
+}
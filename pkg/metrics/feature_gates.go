@@ -0,0 +1,66 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelApi "go.opentelemetry.io/otel/metric"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+)
+
+// featureGatesPath specifies HTTP path where effective feature gates are published
+const featureGatesPath = "/features"
+
+// registerFeatureGatesMetric registers gauge which reports state of each feature gate known to the operator
+func registerFeatureGatesMetric() {
+	_, err := meter.Int64ObservableGauge(
+		"clickhouse_operator_feature_gates",
+		otelApi.WithDescription("state of operator feature gates, 1 means enabled"),
+		otelApi.WithUnit("items"),
+		otelApi.WithInt64Callback(func(_ context.Context, observer otelApi.Int64Observer) error {
+			for _, feature := range chop.Config().GetFeatureGates() {
+				value := int64(0)
+				if feature.Enabled {
+					value = 1
+				}
+				observer.Observe(
+					value,
+					otelApi.WithAttributes(
+						attribute.String("feature", string(feature.Name)),
+						attribute.String("stage", string(feature.Stage)),
+					),
+				)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Warning("unable to register feature gates metric: %v", err)
+	}
+}
+
+// serveFeatureGates publishes effective feature gates as JSON
+func serveFeatureGates(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(chop.Config().GetFeatureGates()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -85,6 +85,7 @@ func StartMetricsExporter(endpoint, path string) {
 	//meter := otel.Meter("chi_meter_2")
 
 	meter = meterProvider.Meter("clickhouse-operator-meter", otelApi.WithInstrumentationVersion(version.Version))
+	registerFeatureGatesMetric()
 
 	// Start the prometheus HTTP server and pass the exporter Collector to it
 	serveMetrics(endpoint, path)
@@ -99,6 +100,7 @@ func Meter() otelApi.Meter {
 func serveMetrics(addr, path string) {
 	fmt.Printf("start serving metrics at: %s%s\n", addr, path)
 	http.Handle(path, promhttp.Handler())
+	http.HandleFunc(featureGatesPath, serveFeatureGates)
 	err := http.ListenAndServe(addr, nil)
 	if err != nil {
 		fmt.Printf("error serving http: %v", err)
@@ -0,0 +1,129 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chb
+
+import (
+	"fmt"
+	"strings"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+)
+
+// Operation statuses reported by system.backups, which are final
+// Refers to
+// https://clickhouse.com/docs/en/operations/system-tables/backups
+const (
+	OperationStatusBackupCreated    = "BACKUP_CREATED"
+	OperationStatusBackupFailed     = "BACKUP_FAILED"
+	OperationStatusRestored         = "RESTORED"
+	OperationStatusRestoreFailed    = "RESTORE_FAILED"
+	OperationStatusBackupCancelled  = "BACKUP_CANCELLED"
+	OperationStatusRestoreCancelled = "RESTORE_CANCELLED"
+	// OperationStatusLost is not reported by ClickHouse, it specifies operation is not found on the host
+	OperationStatusLost = "LOST"
+)
+
+// systemDatabases lists databases which are neither backed up nor restored by default
+var systemDatabases = []string{
+	"system",
+	"information_schema",
+	"INFORMATION_SCHEMA",
+}
+
+// IsOperationStatusFinal checks whether operation with the status is over
+func IsOperationStatusFinal(status string) bool {
+	return IsOperationStatusSucceeded(status) || IsOperationStatusFailed(status)
+}
+
+// IsOperationStatusSucceeded checks whether operation with the status is completed successfully
+func IsOperationStatusSucceeded(status string) bool {
+	switch status {
+	case OperationStatusBackupCreated, OperationStatusRestored:
+		return true
+	}
+	return false
+}
+
+// IsOperationStatusFailed checks whether operation with the status is failed
+func IsOperationStatusFailed(status string) bool {
+	switch status {
+	case
+		OperationStatusBackupFailed,
+		OperationStatusRestoreFailed,
+		OperationStatusBackupCancelled,
+		OperationStatusRestoreCancelled,
+		OperationStatusLost:
+		return true
+	}
+	return false
+}
+
+// CreateOperationID creates id of the operation run on the cluster.
+// ID is unique per ClickHouseBackup object, so the operation is not started twice
+func CreateOperationID(chb *api.ClickHouseBackup, cluster string) string {
+	return fmt.Sprintf("%s-%s", chb.UID, cluster)
+}
+
+// CreateDestination creates URL the cluster is backed up to or restored from
+func CreateDestination(chb *api.ClickHouseBackup, cluster string) string {
+	endpoint := strings.TrimSuffix(chb.Spec.Destination.GetObjectStorage().Endpoint, "/")
+	return fmt.Sprintf("%s/%s/%s/", endpoint, chb.GetBackupName(), cluster)
+}
+
+// CreateOperationSQL creates SQL which runs backup or restore of the cluster asynchronously.
+// Operation runs ON CLUSTER, so ClickHouse coordinates shards and replicas itself:
+// each shard is backed up once and restored replicated tables are filled by replication.
+func CreateOperationSQL(chb *api.ClickHouseBackup, cluster, id, destination, accessKeyID, secretAccessKey string) string {
+	s3 := fmt.Sprintf("S3(%s)", clickhouse.Quote(destination))
+	if accessKeyID != "" {
+		s3 = fmt.Sprintf("S3(%s, %s, %s)", clickhouse.Quote(destination), clickhouse.Quote(accessKeyID), clickhouse.Quote(secretAccessKey))
+	}
+
+	switch chb.Spec.GetOperation() {
+	case api.BackupOperationRestore:
+		return fmt.Sprintf(
+			"RESTORE %s ON CLUSTER %s FROM %s SETTINGS id=%s ASYNC",
+			createElements(chb.Spec.Databases), clickhouse.Quote(cluster), s3, clickhouse.Quote(id),
+		)
+	default:
+		return fmt.Sprintf(
+			"BACKUP %s ON CLUSTER %s TO %s SETTINGS id=%s ASYNC",
+			createElements(chb.Spec.Databases), clickhouse.Quote(cluster), s3, clickhouse.Quote(id),
+		)
+	}
+}
+
+// CreateOperationStatusSQL creates SQL which fetches status and error of the operation
+func CreateOperationStatusSQL(id string) string {
+	return fmt.Sprintf("SELECT toString(status), error FROM system.backups WHERE id=%s", clickhouse.Quote(id))
+}
+
+// createElements creates list of databases to be processed
+func createElements(databases []string) string {
+	if len(databases) == 0 {
+		var quoted []string
+		for _, db := range systemDatabases {
+			quoted = append(quoted, clickhouse.QuoteIdentifier(db))
+		}
+		return "ALL EXCEPT DATABASES " + strings.Join(quoted, ", ")
+	}
+
+	var elements []string
+	for _, db := range databases {
+		elements = append(elements, "DATABASE "+clickhouse.QuoteIdentifier(db))
+	}
+	return strings.Join(elements, ", ")
+}
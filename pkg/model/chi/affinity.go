@@ -228,6 +228,122 @@ func mergeNodeAffinity(dst *core.NodeAffinity, src *core.NodeAffinity) *core.Nod
 	return dst
 }
 
+// IntersectRequiredNodeAffinity restricts required node affinity of the affinity by specified node selector terms,
+// so pod can be scheduled onto nodes matching both the affinity and the terms. Node selector terms are ORed,
+// requirements within a term are ANDed, thus resulting terms are cross-product of both lists of terms.
+// Returns false and keeps affinity unchanged in case no node can match both
+func IntersectRequiredNodeAffinity(affinity *core.Affinity, terms []core.NodeSelectorTerm) (*core.Affinity, bool) {
+	if len(terms) == 0 {
+		return affinity, true
+	}
+
+	cur := getNodeSelectorTerms(getNodeAffinity(affinity))
+	if len(cur) == 0 {
+		// Nothing to intersect with, terms are taken as is
+		cur = []core.NodeSelectorTerm{{}}
+	}
+
+	var res []core.NodeSelectorTerm
+	for i := range cur {
+		for j := range terms {
+			term := intersectNodeSelectorTerms(&cur[i], &terms[j])
+			if isNodeSelectorTermSatisfiable(term) {
+				res = append(res, *term)
+			}
+		}
+	}
+	if len(res) == 0 {
+		return affinity, false
+	}
+
+	if affinity == nil {
+		affinity = &core.Affinity{}
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &core.NodeAffinity{}
+	}
+	affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &core.NodeSelector{
+		NodeSelectorTerms: res,
+	}
+	return affinity, true
+}
+
+// getNodeAffinity
+func getNodeAffinity(affinity *core.Affinity) *core.NodeAffinity {
+	if affinity == nil {
+		return nil
+	}
+	return affinity.NodeAffinity
+}
+
+// intersectNodeSelectorTerms creates node selector term matching nodes matched by both terms
+func intersectNodeSelectorTerms(a, b *core.NodeSelectorTerm) *core.NodeSelectorTerm {
+	res := a.DeepCopy()
+	for _, requirement := range b.MatchExpressions {
+		res.MatchExpressions = appendNodeSelectorRequirement(res.MatchExpressions, requirement)
+	}
+	for _, requirement := range b.MatchFields {
+		res.MatchFields = appendNodeSelectorRequirement(res.MatchFields, requirement)
+	}
+	return res
+}
+
+// appendNodeSelectorRequirement appends requirement to the list, unless the same requirement is already listed
+func appendNodeSelectorRequirement(requirements []core.NodeSelectorRequirement, requirement core.NodeSelectorRequirement) []core.NodeSelectorRequirement {
+	for i := range requirements {
+		if _, equal := messagediff.DeepDiff(requirements[i], requirement); equal {
+			return requirements
+		}
+	}
+	return append(requirements, *requirement.DeepCopy())
+}
+
+// isNodeSelectorTermSatisfiable checks whether requirements of the term do not contradict each other.
+// Only In, NotIn, Exists and DoesNotExist operators are checked, the rest are considered to be satisfiable
+func isNodeSelectorTermSatisfiable(term *core.NodeSelectorTerm) bool {
+	return areNodeSelectorRequirementsSatisfiable(term.MatchExpressions) &&
+		areNodeSelectorRequirementsSatisfiable(term.MatchFields)
+}
+
+// areNodeSelectorRequirementsSatisfiable checks whether ANDed requirements do not contradict each other
+func areNodeSelectorRequirementsSatisfiable(requirements []core.NodeSelectorRequirement) bool {
+	// Values allowed for the key, nil means any value
+	allowed := make(map[string][]string)
+	denied := make(map[string][]string)
+	exists := make(map[string]bool)
+	absent := make(map[string]bool)
+	for _, requirement := range requirements {
+		key := requirement.Key
+		switch requirement.Operator {
+		case core.NodeSelectorOpIn:
+			exists[key] = true
+			if values, ok := allowed[key]; ok {
+				allowed[key] = util.IntersectStringArrays(values, requirement.Values)
+			} else {
+				allowed[key] = requirement.Values
+			}
+		case core.NodeSelectorOpNotIn:
+			denied[key] = append(denied[key], requirement.Values...)
+		case core.NodeSelectorOpExists:
+			exists[key] = true
+		case core.NodeSelectorOpDoesNotExist:
+			absent[key] = true
+		}
+	}
+
+	for key := range exists {
+		if absent[key] {
+			return false
+		}
+	}
+	for key, values := range allowed {
+		if len(util.SubtractStringArrays(values, denied[key])) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // newPodAffinity
 func newPodAffinity(template *api.PodTemplate) *core.PodAffinity {
 	// Return podAffinity only in case something was added into it
@@ -849,6 +965,58 @@ func PrepareAffinity(podTemplate *api.PodTemplate, host *api.ChiHost) {
 	}
 }
 
+// ApplyDistribution converts distribution preset specified by .spec.defaults.distribution
+// into podAntiAffinity or topologySpreadConstraints of the pod template.
+// Rules specified by the pod template explicitly are kept, preset ones are appended
+func ApplyDistribution(podTemplate *api.PodTemplate, host *api.ChiHost) {
+	if podTemplate == nil {
+		return
+	}
+
+	// Hosts of the CHI, regardless of cluster
+	chiLabels := map[string]string{
+		LabelNamespace: labelsNamer.getNamePartNamespace(host),
+		LabelAppName:   LabelAppValue,
+		LabelCHIName:   labelsNamer.getNamePartCHIName(host),
+	}
+
+	switch host.GetCHI().Spec.Defaults.GetDistribution() {
+	case api.DistributionOnePerNode:
+		appendRequiredPodAntiAffinity(podTemplate, chiLabels, core.LabelHostname)
+	case api.DistributionOnePerShardPerNode:
+		appendRequiredPodAntiAffinity(podTemplate, GetSelectorShardScope(host.GetShard()), core.LabelHostname)
+	case api.DistributionOnePerZone:
+		podTemplate.Spec.TopologySpreadConstraints = append(
+			podTemplate.Spec.TopologySpreadConstraints,
+			core.TopologySpreadConstraint{
+				MaxSkew:           1,
+				TopologyKey:       core.LabelTopologyZone,
+				WhenUnsatisfiable: core.DoNotSchedule,
+				LabelSelector: &meta.LabelSelector{
+					MatchLabels: chiLabels,
+				},
+			},
+		)
+	}
+}
+
+// appendRequiredPodAntiAffinity appends podAntiAffinity term, which prevents pods matching labels
+// from being scheduled into the same topology domain
+func appendRequiredPodAntiAffinity(podTemplate *api.PodTemplate, matchLabels map[string]string, topologyKey string) {
+	if podTemplate.Spec.Affinity == nil {
+		podTemplate.Spec.Affinity = &core.Affinity{}
+	}
+	podTemplate.Spec.Affinity.PodAntiAffinity = appendPodAntiAffinityTerm(
+		podTemplate.Spec.Affinity.PodAntiAffinity,
+		&core.PodAffinityTerm{
+			LabelSelector: &meta.LabelSelector{
+				MatchLabels: matchLabels,
+			},
+			TopologyKey: topologyKey,
+		},
+	)
+}
+
 // processNodeSelector
 func processNodeSelector(nodeSelector *core.NodeSelector, host *api.ChiHost) {
 	if nodeSelector == nil {
@@ -15,13 +15,309 @@
 package chi
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
 	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com"
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
 	"github.com/altinity/clickhouse-operator/pkg/chop"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
+// Set of kubernetes annotations used by the operator
+const (
+	// AnnotationScaleInProtection marks host's pod as protected from being deleted by scale-in
+	AnnotationScaleInProtection      = clickhouse_altinity_com.APIGroupName + "/" + "scale-in-protection"
+	AnnotationScaleInProtectionValue = "true"
+
+	// AnnotationForceDataLoss allows CHI to remove shards which still hold data
+	AnnotationForceDataLoss      = clickhouse_altinity_com.APIGroupName + "/" + "force-data-loss"
+	AnnotationForceDataLossValue = "true"
+
+	// AnnotationReplaceHost lists hosts to be recreated from scratch keeping their identity.
+	// Hosts are specified by host name, StatefulSet name or pod name, comma-separated.
+	// Pod of the host can be annotated with the same annotation set to 'true' as well
+	AnnotationReplaceHost      = clickhouse_altinity_com.APIGroupName + "/" + "replace-host"
+	AnnotationReplaceHostValue = "true"
+	// AnnotationReplaceHostWiped lists StatefulSets of the hosts being replaced, which storage is wiped out already.
+	// Interrupted replacement is resumed w/o wiping the host once again
+	AnnotationReplaceHostWiped = clickhouse_altinity_com.APIGroupName + "/" + "replace-host-wiped"
+
+	// AnnotationRestart requests one-time restart of all hosts of the CHI.
+	// RollingRestart restarts hosts one by one, waiting for replication lag to clear before proceeding to the next host.
+	// Annotation is removed after the restart is completed
+	AnnotationRestart               = clickhouse_altinity_com.APIGroupName + "/" + "restart"
+	AnnotationRestartRollingRestart = "RollingRestart"
+
+	// AnnotationRegenerate requests one-time re-render and re-apply of the specified classes of objects of the CHI
+	// w/o full reconcile of the hosts. Classes are specified comma-separated, such as 'configmaps,services'.
+	// Annotation is removed after the objects are re-applied
+	AnnotationRegenerate             = clickhouse_altinity_com.APIGroupName + "/" + "regenerate"
+	AnnotationRegenerateConfigMaps   = "configmaps"
+	AnnotationRegenerateServices     = "services"
+	AnnotationRegenerateStatefulSets = "sts"
+
+	// AnnotationImportSchema specifies ConfigMap with schema to be created on the imported CHI after the reconcile
+	AnnotationImportSchema = clickhouse_altinity_com.APIGroupName + "/" + "import-schema"
+	// ImportSchemaConfigMapKey specifies key of the import schema ConfigMap, which keeps JSON list of SQLs
+	ImportSchemaConfigMapKey = "schema.json"
+
+	// AnnotationClusterSecretVersion specifies version of the cluster secret the pod is started with
+	AnnotationClusterSecretVersion = clickhouse_altinity_com.APIGroupName + "/" + "cluster-secret-version"
+	// AnnotationClusterSecretActiveSlot specifies slot of the cluster secret Secret, which is used by hosts
+	AnnotationClusterSecretActiveSlot = clickhouse_altinity_com.APIGroupName + "/" + "cluster-secret-active-slot"
+
+	// AnnotationTLSCAChecksum specifies checksum of the bundle of cluster CA certificates the pod is started with
+	AnnotationTLSCAChecksum = clickhouse_altinity_com.APIGroupName + "/" + "tls-ca-checksum"
+
+	// AnnotationSecretsChecksum specifies checksum of referenced Secrets the pod is started with
+	AnnotationSecretsChecksum = clickhouse_altinity_com.APIGroupName + "/" + "secrets-checksum"
+	// AnnotationSecretsReloadChecksum specifies checksum of referenced Secrets the pod has reloaded config with
+	AnnotationSecretsReloadChecksum = clickhouse_altinity_com.APIGroupName + "/" + "secrets-reload-checksum"
+
+	// AnnotationStartupGated specifies time merges of the host are stopped at, till replicas of higher startup priority are ready
+	AnnotationStartupGated = clickhouse_altinity_com.APIGroupName + "/" + "startup-gated"
+
+	// AnnotationUpgradeApproved approves upgrade of clusters to the newer version reported by UpgradeAvailable condition.
+	// Value is the approved version. Annotation is removed after the upgrade is applied
+	AnnotationUpgradeApproved = clickhouse_altinity_com.APIGroupName + "/" + "upgrade-approved"
+
+	// AnnotationConfigHash specifies hash of data of the generated config ConfigMap,
+	// which tells ConfigMaps edited out-of-band from the generated ones
+	AnnotationConfigHash = clickhouse_altinity_com.APIGroupName + "/" + "config-hash"
+
+	// AnnotationPDBSuspendedBy lists hosts PodDisruptionBudget is extended for during the operator-driven rollout
+	AnnotationPDBSuspendedBy = clickhouse_altinity_com.APIGroupName + "/" + "pdb-suspended-by"
+	// AnnotationPDBMaxUnavailable specifies original maxUnavailable of the extended PodDisruptionBudget
+	AnnotationPDBMaxUnavailable = clickhouse_altinity_com.APIGroupName + "/" + "pdb-max-unavailable"
+
+	// AnnotationPVCMigrationCopied marks migration PVC data is copied into while the host is running,
+	// so interrupted migration is resumed w/o copying data once again
+	AnnotationPVCMigrationCopied      = clickhouse_altinity_com.APIGroupName + "/" + "pvc-migration-copied"
+	AnnotationPVCMigrationCopiedValue = "true"
+
+	// AnnotationExternalDNSHostname specifies DNS name ExternalDNS publishes for the Service
+	AnnotationExternalDNSHostname = "external-dns.alpha.kubernetes.io/hostname"
+)
+
+// annotationsControl lists annotations which control the operator's actions on the CHI.
+// These annotations are not propagated to child objects of the CHI, because adding or removing any of them
+// would change pod template and thus roll all hosts of the CHI
+var annotationsControl = []string{
+	AnnotationForceDataLoss,
+	AnnotationReplaceHost,
+	AnnotationReplaceHostWiped,
+	AnnotationRestart,
+	AnnotationRegenerate,
+	AnnotationImportSchema,
+	AnnotationUpgradeApproved,
+}
+
+// IsScaleInProtected checks whether object is annotated as protected from scale-in
+func IsScaleInProtected(objectMeta meta.ObjectMeta) bool {
+	value, ok := objectMeta.Annotations[AnnotationScaleInProtection]
+	return ok && strings.EqualFold(value, AnnotationScaleInProtectionValue)
+}
+
+// IsForceDataLoss checks whether object is annotated as allowed to lose data on shards removal
+func IsForceDataLoss(objectMeta meta.ObjectMeta) bool {
+	value, ok := objectMeta.Annotations[AnnotationForceDataLoss]
+	return ok && strings.EqualFold(value, AnnotationForceDataLossValue)
+}
+
+// HasUpgradeApproval checks whether object is annotated with approval of available upgrade
+func HasUpgradeApproval(objectMeta meta.ObjectMeta) bool {
+	return GetUpgradeApprovedVersion(objectMeta) != ""
+}
+
+// GetUpgradeApprovedVersion gets version the object is annotated as approved to be upgraded to
+func GetUpgradeApprovedVersion(objectMeta meta.ObjectMeta) string {
+	return strings.TrimSpace(objectMeta.Annotations[AnnotationUpgradeApproved])
+}
+
+// CreateConfigHash creates hash of config ConfigMap data, which does not depend on order of keys
+func CreateConfigHash(data map[string]string) string {
+	var keys []string
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	b := &bytes.Buffer{}
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteByte(0)
+		b.WriteString(data[key])
+		b.WriteByte(0)
+	}
+	return util.HashIntoString(b.Bytes())
+}
+
+// IsConfigDrifted checks whether data of the config ConfigMap differs from data the ConfigMap was generated with.
+// ConfigMaps generated w/o hash annotation are not considered to be drifted
+func IsConfigDrifted(configMap *core.ConfigMap) bool {
+	if configMap == nil {
+		return false
+	}
+	hash, ok := configMap.Annotations[AnnotationConfigHash]
+	return ok && (hash != CreateConfigHash(configMap.Data))
+}
+
+// getHostsList gets comma-separated list of hosts specified by the annotation
+func getHostsList(objectMeta meta.ObjectMeta, annotation string) (hosts []string) {
+	for _, host := range strings.Split(objectMeta.Annotations[annotation], ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// IsHostNamed checks whether host is specified by the name, which can be host name, StatefulSet name or pod name
+func IsHostNamed(host *api.ChiHost, name string) bool {
+	switch name {
+	case host.GetName(), CreateStatefulSetName(host), CreatePodName(host):
+		return true
+	}
+	return false
+}
+
+// GetHostsToReplace gets list of hosts requested to be replaced
+func GetHostsToReplace(objectMeta meta.ObjectMeta) (hosts []string) {
+	return getHostsList(objectMeta, AnnotationReplaceHost)
+}
+
+// HasHostsToReplace checks whether object is annotated with hosts to be replaced
+func HasHostsToReplace(objectMeta meta.ObjectMeta) bool {
+	return len(GetHostsToReplace(objectMeta)) > 0
+}
+
+// IsPodToReplace checks whether pod is annotated to have its host replaced
+func IsPodToReplace(objectMeta meta.ObjectMeta) bool {
+	value, ok := objectMeta.Annotations[AnnotationReplaceHost]
+	return ok && strings.EqualFold(value, AnnotationReplaceHostValue)
+}
+
+// AppendHostToReplace builds value of replace-host annotation with the host appended, unless it is listed already
+func AppendHostToReplace(objectMeta meta.ObjectMeta, host string) string {
+	hosts := GetHostsToReplace(objectMeta)
+	for _, name := range hosts {
+		if name == host {
+			return strings.Join(hosts, ",")
+		}
+	}
+	return strings.Join(append(hosts, host), ",")
+}
+
+// IsRollingRestartRequested checks whether object is annotated with rolling restart request
+func IsRollingRestartRequested(objectMeta meta.ObjectMeta) bool {
+	value, ok := objectMeta.Annotations[AnnotationRestart]
+	return ok && strings.EqualFold(value, AnnotationRestartRollingRestart)
+}
+
+// GetObjectsToRegenerate gets known classes of objects requested to be regenerated, in order of regeneration
+func GetObjectsToRegenerate(objectMeta meta.ObjectMeta) (classes []string) {
+	requested := make(map[string]bool)
+	for _, class := range strings.Split(objectMeta.Annotations[AnnotationRegenerate], ",") {
+		requested[strings.ToLower(strings.TrimSpace(class))] = true
+	}
+	for _, class := range []string{
+		AnnotationRegenerateConfigMaps,
+		AnnotationRegenerateServices,
+		AnnotationRegenerateStatefulSets,
+	} {
+		if requested[class] {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// HasObjectsToRegenerate checks whether object is annotated with objects to be regenerated
+func HasObjectsToRegenerate(objectMeta meta.ObjectMeta) bool {
+	return len(GetObjectsToRegenerate(objectMeta)) > 0
+}
+
+// IsHostToReplace checks whether host is requested to be replaced
+func IsHostToReplace(host *api.ChiHost) bool {
+	for _, name := range GetHostsToReplace(host.GetCHI().ObjectMeta) {
+		if IsHostNamed(host, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHostReplaceWiped checks whether storage of the host being replaced is wiped out already
+func IsHostReplaceWiped(objectMeta meta.ObjectMeta, host *api.ChiHost) bool {
+	for _, name := range getHostsList(objectMeta, AnnotationReplaceHostWiped) {
+		if IsHostNamed(host, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendHostReplaceWiped builds value of replace-host-wiped annotation with the host appended, unless it is listed already
+func AppendHostReplaceWiped(objectMeta meta.ObjectMeta, host *api.ChiHost) string {
+	hosts := getHostsList(objectMeta, AnnotationReplaceHostWiped)
+	if IsHostReplaceWiped(objectMeta, host) {
+		return strings.Join(hosts, ",")
+	}
+	return strings.Join(append(hosts, CreateStatefulSetName(host)), ",")
+}
+
+// RemoveHostFromList builds value of the hosts list annotation with all names of the host removed
+func RemoveHostFromList(objectMeta meta.ObjectMeta, annotation string, host *api.ChiHost) string {
+	var hosts []string
+	for _, name := range getHostsList(objectMeta, annotation) {
+		if !IsHostNamed(host, name) {
+			hosts = append(hosts, name)
+		}
+	}
+	return strings.Join(hosts, ",")
+}
+
+// hashClusterSecret builds HMAC-SHA256 of the cluster secret value keyed with Secret's UID
+func hashClusterSecret(secret *core.Secret, value []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret.UID))
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GetSecretChecksum gets checksum of data of the k8s Secret.
+// Checksum is built out of Secret's data only, so changes of Secret's metadata do not change the checksum.
+func GetSecretChecksum(secret *core.Secret) string {
+	if secret == nil {
+		return ""
+	}
+	var keys []string
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	b := &bytes.Buffer{}
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteString("=")
+		b.Write(secret.Data[key])
+		b.WriteString(";")
+	}
+	for _, key := range util.MapGetSortedKeys(secret.StringData) {
+		b.WriteString(key)
+		b.WriteString("=")
+		b.WriteString(secret.StringData[key])
+		b.WriteString(";")
+	}
+	return util.HashIntoString(b.Bytes())
+}
+
 // Annotator is an entity which can annotate CHI artifacts
 type Annotator struct {
 	chi *api.ClickHouseInstallation
@@ -77,7 +373,7 @@ func (a *Annotator) GetServiceCluster(cluster *api.Cluster) map[string]string {
 // GetServiceShard
 func (a *Annotator) GetServiceShard(shard *api.ChiShard) map[string]string {
 	return util.MergeStringMapsOverwrite(
-		a.getShardScope(shard),
+		a.GetShardScope(shard),
 		nil,
 	)
 }
@@ -86,10 +382,21 @@ func (a *Annotator) GetServiceShard(shard *api.ChiShard) map[string]string {
 func (a *Annotator) GetServiceHost(host *api.ChiHost) map[string]string {
 	return util.MergeStringMapsOverwrite(
 		a.GetHostScope(host),
-		nil,
+		a.getServiceHostExternalDNS(host),
 	)
 }
 
+// getServiceHostExternalDNS gets ExternalDNS annotations publishing name of the host in custom DNS zone of the cluster
+func (a *Annotator) getServiceHostExternalDNS(host *api.ChiHost) map[string]string {
+	if !host.GetCluster().GetDNS().IsExternalDNS() {
+		return nil
+	}
+	hostname, _ := CreateHostDNSName(host)
+	return map[string]string{
+		AnnotationExternalDNSHostname: hostname,
+	}
+}
+
 // getCHIScope gets annotations for CHI-scoped object
 func (a *Annotator) getCHIScope() map[string]string {
 	// Combine generated annotations and CHI-provided annotations
@@ -102,12 +409,36 @@ func (a *Annotator) GetClusterScope(cluster *api.Cluster) map[string]string {
 	return a.filterOutPredefined(a.appendCHIProvidedTo(nil))
 }
 
-// getShardScope gets annotations for Shard-scoped object
-func (a *Annotator) getShardScope(shard *api.ChiShard) map[string]string {
+// GetShardScope gets annotations for Shard-scoped object
+func (a *Annotator) GetShardScope(shard *api.ChiShard) map[string]string {
 	// Combine generated annotations and CHI-provided annotations
 	return a.filterOutPredefined(a.appendCHIProvidedTo(nil))
 }
 
+// GetPodTemplate gets annotations for pod template of the host
+func (a *Annotator) GetPodTemplate(host *api.ChiHost) map[string]string {
+	annotations := a.GetHostScope(host)
+	if version := host.GetCluster().Runtime.SecretVersion; version != "" {
+		// Cluster secret is passed via ENV var, so pods have to be restarted in order to pick up new secret
+		annotations = util.MergeStringMapsOverwrite(annotations, map[string]string{
+			AnnotationClusterSecretVersion: version,
+		})
+	}
+	if checksum := host.GetCluster().Runtime.TLSCAChecksum; checksum != "" {
+		// Trusted CAs are loaded by ClickHouse on start, so pods are rolled on CA rotation in order to trust the new CA
+		annotations = util.MergeStringMapsOverwrite(annotations, map[string]string{
+			AnnotationTLSCAChecksum: checksum,
+		})
+	}
+	if checksum := host.Runtime.SecretsChecksum; checksum != "" {
+		// Referenced Secrets are to be picked up by restart, so pods are rolled on change of any of them
+		annotations = util.MergeStringMapsOverwrite(annotations, map[string]string{
+			AnnotationSecretsChecksum: checksum,
+		})
+	}
+	return annotations
+}
+
 // GetHostScope gets annotations for Host-scoped object
 func (a *Annotator) GetHostScope(host *api.ChiHost) map[string]string {
 	return a.filterOutPredefined(a.appendCHIProvidedTo(nil))
@@ -120,8 +451,17 @@ func (a *Annotator) filterOutPredefined(m map[string]string) map[string]string {
 
 // appendCHIProvidedTo appends CHI-provided annotations to specified annotations
 func (a *Annotator) appendCHIProvidedTo(dst map[string]string) map[string]string {
-	source := util.CopyMapFilter(a.chi.Annotations, chop.Config().Annotation.Include, chop.Config().Annotation.Exclude)
-	return util.MergeStringMapsOverwrite(dst, source)
+	return util.MergeStringMapsOverwrite(dst, GetCHIProvidedAnnotations(a.chi))
+}
+
+// GetCHIProvidedAnnotations gets annotations of the CHI, which are propagated to child objects of the CHI
+func GetCHIProvidedAnnotations(chi *api.ClickHouseInstallation) map[string]string {
+	if chi == nil {
+		return nil
+	}
+	source := util.CopyMapFilter(chi.Annotations, chop.Config().Annotation.Include, chop.Config().Annotation.Exclude)
+	source = util.CopyMapExclude(source, annotationsControl...)
+	return util.CopyMapFilter(source, nil, util.AnnotationsTobeSkipped)
 }
 
 // GetPV
@@ -0,0 +1,69 @@
+package chi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+)
+
+func newAnnotatorTestHost(annotations map[string]string) *api.ChiHost {
+	chi := &api.ClickHouseInstallation{
+		ObjectMeta: meta.ObjectMeta{
+			Name:        "chi",
+			Namespace:   "ns",
+			Annotations: annotations,
+		},
+		Spec: api.ChiSpec{
+			Configuration: &api.Configuration{
+				Clusters: []*api.Cluster{
+					{
+						Name: "cluster",
+					},
+				},
+			},
+		},
+	}
+	host := &api.ChiHost{
+		Name: "0-0",
+	}
+	host.Runtime.CHI = chi
+	host.Runtime.Address.ClusterName = "cluster"
+	return host
+}
+
+func TestAnnotatorPodTemplateIgnoresControlAnnotations(t *testing.T) {
+	_ = chop.NewFromConfig(nil)
+
+	base := map[string]string{
+		"custom": "value",
+	}
+	expected := NewAnnotator(newAnnotatorTestHost(base).GetCHI()).GetPodTemplate(newAnnotatorTestHost(base))
+	require.Equal(t, map[string]string{"custom": "value"}, expected)
+
+	for _, annotation := range []struct {
+		name  string
+		value string
+	}{
+		{AnnotationForceDataLoss, AnnotationForceDataLossValue},
+		{AnnotationReplaceHost, "0-0"},
+		{AnnotationRestart, AnnotationRestartRollingRestart},
+		{AnnotationRegenerate, AnnotationRegenerateStatefulSets},
+		{AnnotationImportSchema, "schema"},
+		{AnnotationUpgradeApproved, "24.3.5.47"},
+	} {
+		t.Run(annotation.name, func(t *testing.T) {
+			annotations := map[string]string{
+				"custom":        "value",
+				annotation.name: annotation.value,
+			}
+			host := newAnnotatorTestHost(annotations)
+			actual := NewAnnotator(host.GetCHI()).GetPodTemplate(host)
+			require.Equal(t, expected, actual)
+			require.Equal(t, map[string]string{"custom": "value"}, GetCHIProvidedAnnotations(host.GetCHI()))
+		})
+	}
+}
@@ -21,11 +21,23 @@ const (
 )
 
 const (
+	// defaultProfileName specifies name of the ClickHouse settings profile applied by default
+	defaultProfileName = "default"
+)
+
+const (
+	configFunctions     = "functions"
+	configFunctionsPath = "functions-path"
+	configFragments     = "fragments"
+	configIncludeFrom   = "include-from"
 	configMacros        = "macros"
+	configOpenSSL       = "openssl"
 	configHostnamePorts = "hostname-ports"
 	configProfiles      = "profiles"
 	configQuotas        = "quotas"
 	configRemoteServers = "remote_servers"
+	configSecurity      = "security"
+	configSecurityUsers = "security-profiles"
 	configSettings      = "settings"
 	configUsers         = "users"
 	configZookeeper     = "zookeeper"
@@ -58,12 +70,26 @@ const (
 	// DirPathSecretFilesConfig specifies full path to folder, where secrets are mounted
 	DirPathSecretFilesConfig = "/etc/clickhouse-server/secrets.d/"
 
+	// DirPathConfigMapFilesConfig specifies full path to folder, where config maps referenced by files are mounted
+	DirPathConfigMapFilesConfig = "/etc/clickhouse-server/configmaps.d/"
+
+	// DirPathTLS specifies full path to folder, where auto-generated TLS certificates of the host are mounted
+	DirPathTLS = "/etc/clickhouse-server/tls/"
+
+	// DirPathUserScripts specifies full path to folder, where scripts of user-defined executable functions are mounted
+	DirPathUserScripts = "/etc/clickhouse-server/user_scripts/"
+
 	// DirPathClickHouseData specifies full path of data folder where ClickHouse would place its data storage
 	DirPathClickHouseData = "/var/lib/clickhouse"
 
 	// DirPathClickHouseLog  specifies full path of data folder where ClickHouse would place its log files
 	DirPathClickHouseLog = "/var/log/clickhouse-server"
 
+	// FileNameClickHouseServerLog specifies name of the ClickHouse server log file within log folder
+	FileNameClickHouseServerLog = "clickhouse-server.log"
+	// FileNameClickHouseServerErrLog specifies name of the ClickHouse error log file within log folder
+	FileNameClickHouseServerErrLog = "clickhouse-server.err.log"
+
 	// DirPathDockerEntrypointInit specified full path of docker-entrypoint-initdb.d
 	// For more details please check: https://github.com/ClickHouse/ClickHouse/issues/3319
 	DirPathDockerEntrypointInit = "/docker-entrypoint-initdb.d"
@@ -102,6 +128,11 @@ const (
 	ChDefaultInterserverHTTPPortNumber = int32(9009)
 )
 
+const (
+	// PodEnvPodIP specifies ENV var providing pod's IP address to ClickHouse, used as interserver host
+	PodEnvPodIP = "CLICKHOUSE_POD_IP"
+)
+
 const (
 	// ZkDefaultPort specifies Zookeeper default port
 	ZkDefaultPort = 2181
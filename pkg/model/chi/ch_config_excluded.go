@@ -0,0 +1,77 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"path"
+	"strings"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// Config paths are slash-separated, rooted at the config section, such as 'settings/logger/level' or
+// 'users/default/networks/ip', the same as in configuration restart policy rules.
+// Server config sections generated by the operator are rooted at 'settings' as well
+const (
+	excludedPathRemoteServers  = configSettings + "/remote_servers"
+	excludedPathZookeeper      = configSettings + "/zookeeper"
+	excludedPathDistributedDDL = configSettings + "/distributed_ddl"
+	excludedPathMacros         = configSettings + "/macros"
+	excludedPathOpenSSL        = configSettings + "/openSSL"
+)
+
+// configPathsExcluder decides whether config path is managed by the user out-of-band,
+// so the operator must neither render it nor override it
+type configPathsExcluder struct {
+	patterns []api.Matchable
+}
+
+// newConfigPathsExcluder creates new configPathsExcluder out of lists of path patterns.
+// Pattern may contain '*' wildcard, such as 'settings/logger/*'
+func newConfigPathsExcluder(lists ...[]string) *configPathsExcluder {
+	e := &configPathsExcluder{}
+	for _, list := range lists {
+		for _, pattern := range list {
+			pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+			if pattern != "" {
+				e.patterns = append(e.patterns, api.Matchable(pattern))
+			}
+		}
+	}
+	return e
+}
+
+// isEmpty checks whether nothing is excluded
+func (e *configPathsExcluder) isEmpty() bool {
+	if e == nil {
+		return true
+	}
+	return len(e.patterns) == 0
+}
+
+// isExcluded checks whether the path or any of its parents is excluded
+func (e *configPathsExcluder) isExcluded(_path string) bool {
+	if e.isEmpty() {
+		return false
+	}
+	for p := strings.Trim(_path, "/"); (p != "") && (p != "."); p = path.Dir(p) {
+		for i := range e.patterns {
+			if e.patterns[i].Match(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
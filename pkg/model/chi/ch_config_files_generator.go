@@ -80,9 +80,17 @@ func (c *ClickHouseConfigFilesGenerator) CreateConfigFilesGroupCommon(options *C
 	// commonConfigSections maps section name to section XML chopConfig of the following sections:
 	// 1. remote servers
 	// 2. common settings
-	// 3. common files
+	// 3. security settings
+	// 4. user-defined functions
+	// 5. config fragments
+	// 6. common files
 	util.IncludeNonEmpty(commonConfigSections, createConfigSectionFilename(configRemoteServers), c.chConfigGenerator.GetRemoteServers(options.GetRemoteServersGeneratorOptions()))
 	util.IncludeNonEmpty(commonConfigSections, createConfigSectionFilename(configSettings), c.chConfigGenerator.GetSettingsGlobal())
+	util.IncludeNonEmpty(commonConfigSections, createConfigSectionFilename(configSecurity), c.chConfigGenerator.GetSecurity())
+	util.IncludeNonEmpty(commonConfigSections, createConfigSectionFilename(configFunctions), c.chConfigGenerator.GetFunctions())
+	util.IncludeNonEmpty(commonConfigSections, createConfigSectionFilename(configFunctionsPath), c.chConfigGenerator.GetFunctionsPath())
+	util.IncludeNonEmpty(commonConfigSections, createConfigFragmentsFilename(), c.chConfigGenerator.GetFragments())
+	util.IncludeNonEmpty(commonConfigSections, createConfigSectionFilename(configIncludeFrom), c.chConfigGenerator.GetFragmentsIncludeFrom())
 	util.MergeStringMapsOverwrite(commonConfigSections, c.chConfigGenerator.GetSectionFromFiles(api.SectionCommon, true, nil))
 	// Extra user-specified config files
 	util.MergeStringMapsOverwrite(commonConfigSections, c.chopConfig.ClickHouse.Config.File.Runtime.CommonConfigFiles)
@@ -97,10 +105,12 @@ func (c *ClickHouseConfigFilesGenerator) CreateConfigFilesGroupUsers() map[strin
 	// 1. users
 	// 2. quotas
 	// 3. profiles
-	// 4. user files
+	// 4. security profile limits
+	// 5. user files
 	util.IncludeNonEmpty(commonUsersConfigSections, createConfigSectionFilename(configUsers), c.chConfigGenerator.GetUsers())
 	util.IncludeNonEmpty(commonUsersConfigSections, createConfigSectionFilename(configQuotas), c.chConfigGenerator.GetQuotas())
 	util.IncludeNonEmpty(commonUsersConfigSections, createConfigSectionFilename(configProfiles), c.chConfigGenerator.GetProfiles())
+	util.IncludeNonEmpty(commonUsersConfigSections, createConfigSectionFilename(configSecurityUsers), c.chConfigGenerator.GetSecurityProfiles())
 	util.MergeStringMapsOverwrite(commonUsersConfigSections, c.chConfigGenerator.GetSectionFromFiles(api.SectionUsers, false, nil))
 	// Extra user-specified config files
 	util.MergeStringMapsOverwrite(commonUsersConfigSections, c.chopConfig.ClickHouse.Config.File.Runtime.UsersConfigFiles)
@@ -115,6 +125,7 @@ func (c *ClickHouseConfigFilesGenerator) CreateConfigFilesGroupHost(host *api.Ch
 	util.IncludeNonEmpty(hostConfigSections, createConfigSectionFilename(configMacros), c.chConfigGenerator.GetHostMacros(host))
 	util.IncludeNonEmpty(hostConfigSections, createConfigSectionFilename(configHostnamePorts), c.chConfigGenerator.GetHostHostnameAndPorts(host))
 	util.IncludeNonEmpty(hostConfigSections, createConfigSectionFilename(configZookeeper), c.chConfigGenerator.GetHostZookeeper(host))
+	util.IncludeNonEmpty(hostConfigSections, createConfigSectionFilename(configOpenSSL), c.chConfigGenerator.GetHostOpenSSL(host))
 	util.IncludeNonEmpty(hostConfigSections, createConfigSectionFilename(configSettings), c.chConfigGenerator.GetSettings(host))
 	util.MergeStringMapsOverwrite(hostConfigSections, c.chConfigGenerator.GetSectionFromFiles(api.SectionHost, true, host))
 	// Extra user-specified config files
@@ -128,3 +139,10 @@ func (c *ClickHouseConfigFilesGenerator) CreateConfigFilesGroupHost(host *api.Ch
 func createConfigSectionFilename(section string) string {
 	return "chop-generated-" + section + ".xml"
 }
+
+// createConfigFragmentsFilename creates filename of config fragments file.
+// File is not named '.xml' in order not to be merged by ClickHouse into server config,
+// fragments are accessible via include_from only
+func createConfigFragmentsFilename() string {
+	return "chop-generated-" + configFragments + ".incl"
+}
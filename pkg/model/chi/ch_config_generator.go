@@ -16,10 +16,14 @@ package chi
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 	"github.com/altinity/clickhouse-operator/pkg/xml"
 )
@@ -40,40 +44,46 @@ const (
 // which produces XML which are parts of ClickHouse configuration and can/should be used as ClickHouse config files.
 type ClickHouseConfigGenerator struct {
 	chi *api.ClickHouseInstallation
+	// excluder specifies config paths managed by the user out-of-band, which are not rendered
+	excluder *configPathsExcluder
 }
 
 // NewClickHouseConfigGenerator returns new ClickHouseConfigGenerator struct
 func NewClickHouseConfigGenerator(chi *api.ClickHouseInstallation) *ClickHouseConfigGenerator {
 	return &ClickHouseConfigGenerator{
 		chi: chi,
+		excluder: newConfigPathsExcluder(
+			chop.Config().ClickHouse.Config.ExcludedPaths,
+			chi.Spec.Configuration.GetExcludedPaths(),
+		),
 	}
 }
 
 // GetUsers creates data for users section. Used as "users.xml"
 func (c *ClickHouseConfigGenerator) GetUsers() string {
-	return c.generateXMLConfig(c.chi.Spec.Configuration.Users, configUsers)
+	return c.generateXMLConfig(c.excludeSettings(c.chi.Spec.Configuration.Users, configUsers), configUsers)
 }
 
 // GetProfiles creates data for profiles section. Used as "profiles.xml"
 func (c *ClickHouseConfigGenerator) GetProfiles() string {
-	return c.generateXMLConfig(c.chi.Spec.Configuration.Profiles, configProfiles)
+	return c.generateXMLConfig(c.excludeSettings(c.chi.Spec.Configuration.Profiles, configProfiles), configProfiles)
 }
 
 // GetQuotas creates data for "quotas.xml"
 func (c *ClickHouseConfigGenerator) GetQuotas() string {
-	return c.generateXMLConfig(c.chi.Spec.Configuration.Quotas, configQuotas)
+	return c.generateXMLConfig(c.excludeSettings(c.chi.Spec.Configuration.Quotas, configQuotas), configQuotas)
 }
 
 // GetSettingsGlobal creates data for "settings.xml"
 func (c *ClickHouseConfigGenerator) GetSettingsGlobal() string {
 	// No host specified means request to generate common config
-	return c.generateXMLConfig(c.chi.Spec.Configuration.Settings, "")
+	return c.generateXMLConfig(c.excludeSettings(c.chi.Spec.Configuration.Settings, configSettings), "")
 }
 
 // GetSettings creates data for "settings.xml"
 func (c *ClickHouseConfigGenerator) GetSettings(host *api.ChiHost) string {
 	// Generate config for the specified host
-	return c.generateXMLConfig(host.Settings, "")
+	return c.generateXMLConfig(c.excludeSettings(host.Settings, configSettings), "")
 }
 
 // GetSectionFromFiles creates data for custom common config files
@@ -101,10 +111,30 @@ func (c *ClickHouseConfigGenerator) GetHostZookeeper(host *api.ChiHost) string {
 		return ""
 	}
 
+	zookeeper := c.isManaged(excludedPathZookeeper)
+	distributedDDL := c.isManaged(excludedPathDistributedDDL)
+	if !zookeeper && !distributedDDL {
+		return ""
+	}
+
 	b := &bytes.Buffer{}
 	// <yandex>
-	//		<zookeeper>
 	util.Iline(b, 0, "<"+xmlTagYandex+">")
+	if zookeeper {
+		c.getHostZookeeperNodes(zk, b)
+	}
+	if distributedDDL {
+		c.getDistributedDDL(b)
+	}
+	// </yandex>
+	util.Iline(b, 0, "</"+xmlTagYandex+">")
+
+	return b.String()
+}
+
+// getHostZookeeperNodes writes zookeeper section
+func (c *ClickHouseConfigGenerator) getHostZookeeperNodes(zk *api.ChiZookeeperConfig, b *bytes.Buffer) {
+	//		<zookeeper>
 	util.Iline(b, 4, "<zookeeper>")
 
 	// Append Zookeeper nodes
@@ -147,7 +177,10 @@ func (c *ClickHouseConfigGenerator) GetHostZookeeper(host *api.ChiHost) string {
 
 	// </zookeeper>
 	util.Iline(b, 4, "</zookeeper>")
+}
 
+// getDistributedDDL writes distributed_ddl section
+func (c *ClickHouseConfigGenerator) getDistributedDDL(b *bytes.Buffer) {
 	// <distributed_ddl>
 	//      <path>/x/y/chi.name/z</path>
 	//      <profile>X</profile>
@@ -157,8 +190,277 @@ func (c *ClickHouseConfigGenerator) GetHostZookeeper(host *api.ChiHost) string {
 		util.Iline(b, 4, "    <profile>%s</profile>", c.chi.Spec.Defaults.DistributedDDL.GetProfile())
 	}
 	//		</distributed_ddl>
-	// </yandex>
 	util.Iline(b, 4, "</distributed_ddl>")
+}
+
+// GetHostOpenSSL creates data for "openssl.xml".
+// Hosts of the cluster having 'secure: auto' serve secure ports with the certificate generated by the operator
+// and accept only certificates issued by the cluster CA when connecting to other hosts
+func (c *ClickHouseConfigGenerator) GetHostOpenSSL(host *api.ChiHost) string {
+	if !host.GetCluster().IsSecureAuto() || !c.isManaged(excludedPathOpenSSL) {
+		return ""
+	}
+
+	b := &bytes.Buffer{}
+	// <yandex>
+	util.Iline(b, 0, "<"+xmlTagYandex+">")
+	// <openSSL>
+	util.Iline(b, 4, "<openSSL>")
+
+	// <server>
+	//     <certificateFile>/etc/clickhouse-server/tls/tls.crt</certificateFile>
+	//     <privateKeyFile>/etc/clickhouse-server/tls/tls.key</privateKeyFile>
+	//     <caConfig>/etc/clickhouse-server/tls/ca.crt</caConfig>
+	//     ...
+	// </server>
+	util.Iline(b, 8, "<server>")
+	util.Iline(b, 8, "    <certificateFile>%s</certificateFile>", DirPathTLS+TLSFileCertificate)
+	util.Iline(b, 8, "    <privateKeyFile>%s</privateKeyFile>", DirPathTLS+TLSFilePrivateKey)
+	util.Iline(b, 8, "    <caConfig>%s</caConfig>", DirPathTLS+TLSFileCACertificate)
+	// Client certificate is verified in case provided, clients w/o certificate, such as the operator, are accepted
+	util.Iline(b, 8, "    <verificationMode>relaxed</verificationMode>")
+	util.Iline(b, 8, "    <loadDefaultCAFile>false</loadDefaultCAFile>")
+	util.Iline(b, 8, "    <cacheSessions>true</cacheSessions>")
+	util.Iline(b, 8, "    <disableProtocols>sslv2,sslv3,tlsv1,tlsv1_1</disableProtocols>")
+	util.Iline(b, 8, "    <preferServerCiphers>true</preferServerCiphers>")
+	util.Iline(b, 8, "</server>")
+
+	// <client>
+	//     <certificateFile>/etc/clickhouse-server/tls/tls.crt</certificateFile>
+	//     <privateKeyFile>/etc/clickhouse-server/tls/tls.key</privateKeyFile>
+	//     <caConfig>/etc/clickhouse-server/tls/ca.crt</caConfig>
+	//     ...
+	// </client>
+	util.Iline(b, 8, "<client>")
+	util.Iline(b, 8, "    <certificateFile>%s</certificateFile>", DirPathTLS+TLSFileCertificate)
+	util.Iline(b, 8, "    <privateKeyFile>%s</privateKeyFile>", DirPathTLS+TLSFilePrivateKey)
+	util.Iline(b, 8, "    <caConfig>%s</caConfig>", DirPathTLS+TLSFileCACertificate)
+	util.Iline(b, 8, "    <verificationMode>strict</verificationMode>")
+	util.Iline(b, 8, "    <loadDefaultCAFile>false</loadDefaultCAFile>")
+	util.Iline(b, 8, "    <cacheSessions>true</cacheSessions>")
+	util.Iline(b, 8, "    <disableProtocols>sslv2,sslv3,tlsv1,tlsv1_1</disableProtocols>")
+	util.Iline(b, 8, "    <invalidCertificateHandler>")
+	util.Iline(b, 8, "        <name>RejectCertificateHandler</name>")
+	util.Iline(b, 8, "    </invalidCertificateHandler>")
+	util.Iline(b, 8, "</client>")
+
+	// </openSSL>
+	util.Iline(b, 4, "</openSSL>")
+	// </yandex>
+	util.Iline(b, 0, "</"+xmlTagYandex+">")
+
+	return b.String()
+}
+
+// GetSecurity creates data for "security.xml"
+func (c *ClickHouseConfigGenerator) GetSecurity() string {
+	security := c.chi.Spec.Configuration.Security
+	if !security.HasServerSettings() {
+		return ""
+	}
+
+	hosts := security.GetRemoteURLAllowHosts()
+	withHosts := !hosts.IsEmpty() && c.isManaged(configSettings+"/remote_url_allow_hosts")
+	withBacklog := security.HasListenBacklog() && c.isManaged(configSettings+"/listen_backlog")
+	if !withHosts && !withBacklog {
+		// All settings are specified in settings section explicitly
+		return ""
+	}
+
+	b := &bytes.Buffer{}
+	// <yandex>
+	util.Iline(b, 0, "<"+xmlTagYandex+">")
+
+	// <remote_url_allow_hosts>
+	//     <host>HOST</host>
+	//     <host_regexp>REGEXP</host_regexp>
+	// </remote_url_allow_hosts>
+	if withHosts {
+		util.Iline(b, 4, "<remote_url_allow_hosts>")
+		for _, host := range hosts.Hosts {
+			util.Iline(b, 4, "    <host>%s</host>", xml.EscapeText(host))
+		}
+		for _, hostRegexp := range hosts.HostRegexps {
+			util.Iline(b, 4, "    <host_regexp>%s</host_regexp>", xml.EscapeText(hostRegexp))
+		}
+		util.Iline(b, 4, "</remote_url_allow_hosts>")
+	}
+
+	// <listen_backlog>X</listen_backlog>
+	if withBacklog {
+		util.Iline(b, 4, "<listen_backlog>%d</listen_backlog>", security.GetListenBacklog())
+	}
+
+	// </yandex>
+	util.Iline(b, 0, "</"+xmlTagYandex+">")
+
+	return b.String()
+}
+
+// GetSecurityProfiles creates data for "security-profiles.xml"
+// Limits are merged into 'default' profile under its own settings, so settings specified in profiles section are kept intact
+func (c *ClickHouseConfigGenerator) GetSecurityProfiles() string {
+	security := c.chi.Spec.Configuration.Security
+	if !security.HasProfileSettings() {
+		return ""
+	}
+
+	limits := security.GetQueryComplexity().AsMap()
+	var names []string
+	for name := range limits {
+		if c.chi.Spec.Configuration.Profiles.Has(defaultProfileName + "/" + name) {
+			// User's own value of the 'default' profile takes priority
+			continue
+		}
+		if c.isManaged(configProfiles + "/" + defaultProfileName + "/" + name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+
+	b := &bytes.Buffer{}
+	// <yandex>
+	//     <profiles>
+	//         <default>
+	util.Iline(b, 0, "<"+xmlTagYandex+">")
+	util.Iline(b, 4, "<profiles>")
+	util.Iline(b, 8, "<%s>", defaultProfileName)
+	for _, name := range names {
+		util.Iline(b, 12, "<%s>%d</%[1]s>", name, limits[name])
+	}
+	//         </default>
+	//     </profiles>
+	// </yandex>
+	util.Iline(b, 8, "</%s>", defaultProfileName)
+	util.Iline(b, 4, "</profiles>")
+	util.Iline(b, 0, "</"+xmlTagYandex+">")
+
+	return b.String()
+}
+
+// GetFunctions creates data for "functions.xml"
+// This file is referred by user_defined_executable_functions_config and describes user-defined executable functions
+func (c *ClickHouseConfigGenerator) GetFunctions() string {
+	functions := c.chi.Spec.Configuration.Functions
+	if !functions.HasFunctions() {
+		return ""
+	}
+
+	b := &bytes.Buffer{}
+	// <yandex>
+	util.Iline(b, 0, "<"+xmlTagYandex+">")
+	for _, function := range functions.GetFunctions() {
+		// <function>
+		//     <type>executable</type>
+		//     <name>NAME</name>
+		//     <return_type>TYPE</return_type>
+		//     <argument>
+		//         <type>TYPE</type>
+		//         <name>NAME</name>
+		//     </argument>
+		//     <format>FORMAT</format>
+		//     <command>COMMAND</command>
+		// </function>
+		util.Iline(b, 4, "<function>")
+		util.Iline(b, 8, "<type>%s</type>", xml.EscapeText(function.Type))
+		util.Iline(b, 8, "<name>%s</name>", xml.EscapeText(function.Name))
+		util.Iline(b, 8, "<return_type>%s</return_type>", xml.EscapeText(function.ReturnType))
+		for _, argument := range function.Arguments {
+			util.Iline(b, 8, "<argument>")
+			util.Iline(b, 12, "<type>%s</type>", xml.EscapeText(argument.Type))
+			if argument.Name != "" {
+				util.Iline(b, 12, "<name>%s</name>", xml.EscapeText(argument.Name))
+			}
+			util.Iline(b, 8, "</argument>")
+		}
+		util.Iline(b, 8, "<format>%s</format>", xml.EscapeText(function.Format))
+		util.Iline(b, 8, "<command>%s</command>", xml.EscapeText(function.Command))
+		// Additional function settings are sorted in order to have stable config
+		var names []string
+		for name := range function.Settings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			util.Iline(b, 8, "<%s>%s</%[1]s>", name, xml.EscapeText(function.Settings[name]))
+		}
+		util.Iline(b, 4, "</function>")
+	}
+	// Marker function tells version of the functions config loaded by the host.
+	// It is never run by ClickHouse, command is not searched in user scripts and fails in case it is called explicitly
+	util.Iline(b, 4, "<function>")
+	util.Iline(b, 8, "<type>%s</type>", api.FunctionTypeExecutable)
+	util.Iline(b, 8, "<name>%s</name>", CreateFunctionsMarkerName(functions))
+	util.Iline(b, 8, "<return_type>UInt8</return_type>")
+	util.Iline(b, 8, "<format>TabSeparated</format>")
+	util.Iline(b, 8, "<command>false</command>")
+	util.Iline(b, 8, "<execute_direct>0</execute_direct>")
+	util.Iline(b, 4, "</function>")
+	// </yandex>
+	util.Iline(b, 0, "</"+xmlTagYandex+">")
+
+	return b.String()
+}
+
+// functionsMarkerPrefix specifies prefix of the name of the marker function of the functions config.
+// Prefix is reserved, so the marker never clashes with user-defined functions
+const functionsMarkerPrefix = api.FunctionNameReservedPrefix + "functions_config_"
+
+// CreateFunctionsMarkerName creates name of the marker function of the functions config.
+// ClickHouse does not expose definitions of executable functions, so the marker function named after the hash
+// of all definitions is loaded along with them and tells whether the host has loaded the current definitions
+func CreateFunctionsMarkerName(functions *api.ChiFunctions) string {
+	js, _ := json.Marshal(functions.GetFunctions())
+	return functionsMarkerPrefix + util.HashIntoString(js)[:16]
+}
+
+// GetFragments creates data for "fragments.incl"
+// Named config fragments are referenced by settings via XML includes
+func (c *ClickHouseConfigGenerator) GetFragments() string {
+	return c.generateXMLConfig(c.chi.Spec.Configuration.GetFragments(), "")
+}
+
+// GetFragmentsIncludeFrom creates data for "include-from.xml"
+// Points ClickHouse to config fragments file
+func (c *ClickHouseConfigGenerator) GetFragmentsIncludeFrom() string {
+	if c.chi.Spec.Configuration.GetFragments().Len() == 0 {
+		return ""
+	}
+
+	b := &bytes.Buffer{}
+	// <yandex>
+	util.Iline(b, 0, "<"+xmlTagYandex+">")
+	// <include_from>PATH</include_from>
+	util.Iline(b, 4, "<include_from>%s</include_from>", DirPathCommonConfig+createConfigFragmentsFilename())
+	// </yandex>
+	util.Iline(b, 0, "</"+xmlTagYandex+">")
+
+	return b.String()
+}
+
+// GetFunctionsPath creates data for "functions-path.xml"
+// Points ClickHouse to functions config and mounted user scripts
+func (c *ClickHouseConfigGenerator) GetFunctionsPath() string {
+	functions := c.chi.Spec.Configuration.Functions
+	if !functions.HasFunctions() && !functions.HasScriptsConfigMap() {
+		return ""
+	}
+
+	b := &bytes.Buffer{}
+	// <yandex>
+	util.Iline(b, 0, "<"+xmlTagYandex+">")
+	// <user_defined_executable_functions_config>PATH</user_defined_executable_functions_config>
+	if functions.HasFunctions() {
+		util.Iline(b, 4, "<user_defined_executable_functions_config>%s</user_defined_executable_functions_config>", DirPathCommonConfig+createConfigSectionFilename(configFunctions))
+	}
+	// <user_scripts_path>PATH</user_scripts_path>
+	if functions.HasScriptsConfigMap() {
+		util.Iline(b, 4, "<user_scripts_path>%s</user_scripts_path>", DirPathUserScripts)
+	}
+	// </yandex>
 	util.Iline(b, 0, "</"+xmlTagYandex+">")
 
 	return b.String()
@@ -167,8 +469,9 @@ func (c *ClickHouseConfigGenerator) GetHostZookeeper(host *api.ChiHost) string {
 // RemoteServersGeneratorOptions specifies options for remote-servers generator
 type RemoteServersGeneratorOptions struct {
 	exclude struct {
-		attributes *api.HostReconcileAttributes
-		hosts      []*api.ChiHost
+		attributes  *api.HostReconcileAttributes
+		hosts       []*api.ChiHost
+		maintenance bool
 	}
 }
 
@@ -207,12 +510,27 @@ func (o *RemoteServersGeneratorOptions) ExcludeReconcileAttributes(attrs *api.Ho
 	return o
 }
 
+// ExcludeHostsUnderMaintenance specifies to exclude hosts under maintenance
+func (o *RemoteServersGeneratorOptions) ExcludeHostsUnderMaintenance() *RemoteServersGeneratorOptions {
+	if o == nil {
+		return o
+	}
+
+	o.exclude.maintenance = true
+	return o
+}
+
 // Exclude tells whether to exclude the host
 func (o *RemoteServersGeneratorOptions) Exclude(host *api.ChiHost) bool {
 	if o == nil {
 		return false
 	}
 
+	if o.exclude.maintenance && host.IsUnderMaintenance() {
+		// Host is under maintenance
+		return true
+	}
+
 	if o.exclude.attributes.Any(host.GetReconcileAttributes()) {
 		// Reconcile attributes specify to exclude this host
 		return true
@@ -234,6 +552,11 @@ func (o *RemoteServersGeneratorOptions) Include(host *api.ChiHost) bool {
 		return false
 	}
 
+	if o.exclude.maintenance && host.IsUnderMaintenance() {
+		// Host is under maintenance
+		return false
+	}
+
 	if o.exclude.attributes.Any(host.GetReconcileAttributes()) {
 		// Reconcile attributes specify to exclude this host
 		return false
@@ -259,12 +582,12 @@ func (o *RemoteServersGeneratorOptions) String() string {
 	for _, host := range o.exclude.hosts {
 		hostnames = append(hostnames, host.Name)
 	}
-	return fmt.Sprintf("exclude hosts: %s, attributes: %s", "["+strings.Join(hostnames, ",")+"]", o.exclude.attributes)
+	return fmt.Sprintf("exclude hosts: %s, attributes: %s, maintenance: %t", "["+strings.Join(hostnames, ",")+"]", o.exclude.attributes, o.exclude.maintenance)
 }
 
 // defaultRemoteServersGeneratorOptions
 func defaultRemoteServersGeneratorOptions() *RemoteServersGeneratorOptions {
-	return NewRemoteServersGeneratorOptions()
+	return NewRemoteServersGeneratorOptions().ExcludeHostsUnderMaintenance()
 }
 
 // CHIHostsNum count hosts according to the options
@@ -303,6 +626,11 @@ func (c *ClickHouseConfigGenerator) ShardHostsNum(shard *api.ChiShard, options *
 }
 
 func (c *ClickHouseConfigGenerator) getRemoteServersReplica(host *api.ChiHost, b *bytes.Buffer) {
+	c.getRemoteServersReplicaInstance(host, 0, b)
+}
+
+// getRemoteServersReplicaInstance writes replica entry of the specified ClickHouse instance of the host
+func (c *ClickHouseConfigGenerator) getRemoteServersReplicaInstance(host *api.ChiHost, instance int, b *bytes.Buffer) {
 	// <replica>
 	//		<host>XXX</host>
 	//		<port>XXX</port>
@@ -315,14 +643,18 @@ func (c *ClickHouseConfigGenerator) getRemoteServersReplica(host *api.ChiHost, b
 		port = host.TCPPort
 	}
 	util.Iline(b, 16, "<replica>")
-	util.Iline(b, 16, "    <host>%s</host>", c.getRemoteServersReplicaHostname(host))
-	util.Iline(b, 16, "    <port>%d</port>", port)
+	util.Iline(b, 16, "    <host>%s</host>", xml.EscapeText(c.getRemoteServersReplicaHostname(host)))
+	util.Iline(b, 16, "    <port>%d</port>", GetPodInstancePort(host, port, instance))
 	util.Iline(b, 16, "    <secure>%d</secure>", c.getSecure(host))
 	util.Iline(b, 16, "</replica>")
 }
 
 // GetRemoteServers creates "remote_servers.xml" content and calculates data generation parameters for other sections
 func (c *ClickHouseConfigGenerator) GetRemoteServers(options *RemoteServersGeneratorOptions) string {
+	if !c.isManaged(excludedPathRemoteServers) {
+		return ""
+	}
+
 	if options == nil {
 		options = defaultRemoteServersGeneratorOptions()
 	}
@@ -342,6 +674,10 @@ func (c *ClickHouseConfigGenerator) GetRemoteServers(options *RemoteServersGener
 			// Skip empty cluster
 			return nil
 		}
+		if cluster.Stop.IsTrue() {
+			// Skip cluster stopped on its own, it has no hosts to serve queries
+			return nil
+		}
 		// <my_cluster_name>
 		util.Iline(b, 8, "<%s>", cluster.Name)
 
@@ -351,8 +687,8 @@ func (c *ClickHouseConfigGenerator) GetRemoteServers(options *RemoteServersGener
 			// Secret value is explicitly specified
 			util.Iline(b, 12, "<secret>%s</secret>", cluster.Secret.Value)
 		case api.ClusterSecretSourceSecretRef, api.ClusterSecretSourceAuto:
-			// Use secret via ENV var from secret
-			util.Iline(b, 12, `<secret from_env="%s" />`, InternodeClusterSecretEnvName)
+			// Use secret via ENV var of the active slot
+			util.Iline(b, 12, `<secret from_env="%s" />`, CreateClusterSecretSlotEnvName(cluster.Runtime.SecretSlot))
 		}
 
 		// Build each shard XML
@@ -362,25 +698,28 @@ func (c *ClickHouseConfigGenerator) GetRemoteServers(options *RemoteServersGener
 				return nil
 			}
 
-			// <shard>
-			//		<internal_replication>VALUE(true/false)</internal_replication>
-			util.Iline(b, 12, "<shard>")
-			util.Iline(b, 16, "<internal_replication>%s</internal_replication>", shard.InternalReplication)
-
-			//		<weight>X</weight>
-			if shard.HasWeight() {
-				util.Iline(b, 16, "<weight>%d</weight>", shard.GetWeight())
-			}
+			// Each ClickHouse instance in the pod serves a shard of its own
+			for instance := 0; instance < cluster.GetLayout().GetInstancesPerPod(); instance++ {
+				// <shard>
+				//		<internal_replication>VALUE(true/false)</internal_replication>
+				util.Iline(b, 12, "<shard>")
+				util.Iline(b, 16, "<internal_replication>%s</internal_replication>", shard.InternalReplication)
 
-			shard.WalkHosts(func(host *api.ChiHost) error {
-				if options.Include(host) {
-					c.getRemoteServersReplica(host, b)
+				//		<weight>X</weight>
+				if shard.HasWeight() {
+					util.Iline(b, 16, "<weight>%d</weight>", shard.GetWeight())
 				}
-				return nil
-			})
 
-			// </shard>
-			util.Iline(b, 12, "</shard>")
+				shard.WalkHosts(func(host *api.ChiHost) error {
+					if options.Include(host) {
+						c.getRemoteServersReplicaInstance(host, instance, b)
+					}
+					return nil
+				})
+
+				// </shard>
+				util.Iline(b, 12, "</shard>")
+			}
 
 			return nil
 		})
@@ -406,8 +745,10 @@ func (c *ClickHouseConfigGenerator) GetRemoteServers(options *RemoteServersGener
 		util.Iline(b, 8, "    <shard>")
 		util.Iline(b, 8, "        <internal_replication>true</internal_replication>")
 		c.chi.WalkHosts(func(host *api.ChiHost) error {
-			if options.Include(host) {
-				c.getRemoteServersReplica(host, b)
+			if options.Include(host) && !isHostOfSuspendedCluster(host) {
+				HostWalkInstances(host, func(instance int) {
+					c.getRemoteServersReplicaInstance(host, instance, b)
+				})
 			}
 			return nil
 		})
@@ -423,16 +764,18 @@ func (c *ClickHouseConfigGenerator) GetRemoteServers(options *RemoteServersGener
 		clusterName = AllShardsOneReplicaClusterName
 		util.Iline(b, 8, "<%s>", clusterName)
 		c.chi.WalkHosts(func(host *api.ChiHost) error {
-			if options.Include(host) {
-				// <shard>
-				//     <internal_replication>
-				util.Iline(b, 12, "<shard>")
-				util.Iline(b, 12, "    <internal_replication>false</internal_replication>")
-
-				c.getRemoteServersReplica(host, b)
-
-				// </shard>
-				util.Iline(b, 12, "</shard>")
+			if options.Include(host) && !isHostOfSuspendedCluster(host) {
+				HostWalkInstances(host, func(instance int) {
+					// <shard>
+					//     <internal_replication>
+					util.Iline(b, 12, "<shard>")
+					util.Iline(b, 12, "    <internal_replication>false</internal_replication>")
+
+					c.getRemoteServersReplicaInstance(host, instance, b)
+
+					// </shard>
+					util.Iline(b, 12, "</shard>")
+				})
 			}
 			return nil
 		})
@@ -448,8 +791,18 @@ func (c *ClickHouseConfigGenerator) GetRemoteServers(options *RemoteServersGener
 	return b.String()
 }
 
+// isHostOfSuspendedCluster checks whether host belongs to cluster, which is either stopped or cordoned on its own.
+// Such hosts are not listed in autogenerated clusters referring to all hosts
+func isHostOfSuspendedCluster(host *api.ChiHost) bool {
+	return host.GetCluster().Stop.IsTrue() || host.IsCordoned()
+}
+
 // GetHostMacros creates "macros.xml" content
 func (c *ClickHouseConfigGenerator) GetHostMacros(host *api.ChiHost) string {
+	if !c.isManaged(excludedPathMacros) {
+		return ""
+	}
+
 	b := &bytes.Buffer{}
 
 	// <yandex>
@@ -467,13 +820,22 @@ func (c *ClickHouseConfigGenerator) GetHostMacros(host *api.ChiHost) string {
 
 	// All Shards One Replica ChkCluster
 	// <CLUSTER_NAME-shard>0-based shard index within all-shards-one-replica-cluster</CLUSTER_NAME-shard>
-	util.Iline(b, 8, "<%s-shard>%d</%[1]s-shard>", AllShardsOneReplicaClusterName, host.Runtime.Address.CHIScopeIndex)
+	// Shard served by each ClickHouse instance of the pod is provided via ENV var
+	if HostHasMultipleInstances(host) {
+		util.Iline(b, 8, `<%s-shard from_env="%s" />`, AllShardsOneReplicaClusterName, PodInstanceEnvAllShardsShard)
+	} else {
+		util.Iline(b, 8, "<%s-shard>%d</%[1]s-shard>", AllShardsOneReplicaClusterName, GetPodInstanceAllShardsShardIndex(host, 0))
+	}
 
 	// <cluster> and <shard> macros are applicable to main cluster only. All aux clusters do not have ambiguous macros
 	// <cluster></cluster> macro
 	util.Iline(b, 8, "<cluster>%s</cluster>", host.Runtime.Address.ClusterName)
 	// <shard></shard> macro
-	util.Iline(b, 8, "<shard>%s</shard>", host.Runtime.Address.ShardName)
+	if HostHasMultipleInstances(host) {
+		util.Iline(b, 8, `<shard from_env="%s" />`, PodInstanceEnvShard)
+	} else {
+		util.Iline(b, 8, "<shard>%s</shard>", host.Runtime.Address.ShardName)
+	}
 	// <replica>replica id = full deployment id</replica>
 	// full deployment id is unique to identify replica within the cluster
 	util.Iline(b, 8, "<replica>%s</replica>", CreatePodHostname(host))
@@ -494,22 +856,31 @@ func (c *ClickHouseConfigGenerator) GetHostHostnameAndPorts(host *api.ChiHost) s
 	// <yandex>
 	util.Iline(b, 0, "<"+xmlTagYandex+">")
 
-	if host.TCPPort != ChDefaultTCPPortNumber {
-		util.Iline(b, 4, "<tcp_port>%d</tcp_port>", host.TCPPort)
+	if HostHasMultipleInstances(host) {
+		c.getHostInstancePorts(host, b)
+		// </yandex>
+		util.Iline(b, 0, "</"+xmlTagYandex+">")
+		return b.String()
 	}
-	if host.TLSPort != ChDefaultTLSPortNumber {
-		util.Iline(b, 4, "<tcp_port_secure>%d</tcp_port_secure>", host.TLSPort)
-	}
-	if host.HTTPPort != ChDefaultHTTPPortNumber {
-		util.Iline(b, 4, "<http_port>%d</http_port>", host.HTTPPort)
-	}
-	if host.HTTPSPort != ChDefaultHTTPSPortNumber {
-		util.Iline(b, 4, "<https_port>%d</https_port>", host.HTTPSPort)
+
+	for _, p := range []struct {
+		tag      string
+		port     int32
+		_default int32
+	}{
+		{"tcp_port", host.TCPPort, ChDefaultTCPPortNumber},
+		{"tcp_port_secure", host.TLSPort, ChDefaultTLSPortNumber},
+		{"http_port", host.HTTPPort, ChDefaultHTTPPortNumber},
+		{"https_port", host.HTTPSPort, ChDefaultHTTPSPortNumber},
+	} {
+		if (p.port != p._default) && c.isManaged(configSettings+"/"+p.tag) {
+			util.Iline(b, 4, "<%s>%d</%[1]s>", p.tag, p.port)
+		}
 	}
 
 	// Interserver host and port
-	util.Iline(b, 4, "<interserver_http_host>%s</interserver_http_host>", c.getRemoteServersReplicaHostname(host))
-	if host.InterserverHTTPPort != ChDefaultInterserverHTTPPortNumber {
+	c.getInterserverHTTPHost(host, b)
+	if (host.InterserverHTTPPort != ChDefaultInterserverHTTPPortNumber) && c.isManaged(configSettings+"/interserver_http_port") {
 		util.Iline(b, 4, "<interserver_http_port>%d</interserver_http_port>", host.InterserverHTTPPort)
 	}
 
@@ -519,6 +890,76 @@ func (c *ClickHouseConfigGenerator) GetHostHostnameAndPorts(host *api.ChiHost) s
 	return b.String()
 }
 
+// getHostInstancePorts writes ports of the host which runs multiple ClickHouse instances in the pod.
+// Ports of each instance are provided via ENV vars, additional listeners are disabled, because instances would clash on them
+func (c *ClickHouseConfigGenerator) getHostInstancePorts(host *api.ChiHost, b *bytes.Buffer) {
+	for _, p := range []struct {
+		tag  string
+		port int32
+		env  string
+	}{
+		{"tcp_port", host.TCPPort, PodInstanceEnvTCPPort},
+		{"tcp_port_secure", host.TLSPort, PodInstanceEnvTLSPort},
+		{"http_port", host.HTTPPort, PodInstanceEnvHTTPPort},
+		{"https_port", host.HTTPSPort, PodInstanceEnvHTTPSPort},
+		{"interserver_http_port", host.InterserverHTTPPort, PodInstanceEnvInterserverHTTPPort},
+	} {
+		if api.IsPortAssigned(p.port) && c.isManaged(configSettings+"/"+p.tag) {
+			util.Iline(b, 4, `<%s from_env="%s" />`, p.tag, p.env)
+		}
+	}
+	for _, tag := range []string{"mysql_port", "postgresql_port"} {
+		if c.isManaged(configSettings + "/" + tag) {
+			util.Iline(b, 4, `<%s remove="1" />`, tag)
+		}
+	}
+
+	// Interserver host
+	c.getInterserverHTTPHost(host, b)
+}
+
+// getInterserverHTTPHost writes interserver host, the host is addressed by other replicas with
+func (c *ClickHouseConfigGenerator) getInterserverHTTPHost(host *api.ChiHost, b *bytes.Buffer) {
+	if !c.isManaged(configSettings + "/interserver_http_host") {
+		return
+	}
+	if host.GetInterserverHTTPHost() == api.InterserverHTTPHostPodIP {
+		// Pod IP is known within the pod only
+		util.Iline(b, 4, `<interserver_http_host from_env="%s" />`, PodEnvPodIP)
+		return
+	}
+	util.Iline(b, 4, "<interserver_http_host>%s</interserver_http_host>", xml.EscapeText(CreateInterserverHTTPHost(host)))
+}
+
+// isManaged checks whether config path is managed by the operator, i.e. is not excluded
+func (c *ClickHouseConfigGenerator) isManaged(path string) bool {
+	if c.excluder.isExcluded(path) {
+		log.V(2).M(c.chi).F().Info("Config path %s is excluded, not rendered", path)
+		return false
+	}
+	return true
+}
+
+// excludeSettings gets settings without excluded paths. Settings specified in CHI, but excluded,
+// conflict with out-of-band config management, so they are skipped with a warning
+func (c *ClickHouseConfigGenerator) excludeSettings(settings *api.Settings, section string) *api.Settings {
+	if settings.Len() == 0 || c.excluder.isEmpty() {
+		return settings
+	}
+
+	var res *api.Settings
+	settings.WalkKeys(func(key string, setting *api.Setting) {
+		path := section + "/" + settings.Key2Name(key)
+		if c.excluder.isExcluded(path) {
+			log.V(1).M(c.chi).F().Warning("Setting %s conflicts with excluded config paths, skipped", path)
+			return
+		}
+		res = res.Ensure().SetKey(key, setting)
+	})
+
+	return res
+}
+
 // generateXMLConfig creates XML using map[string]string definitions
 func (c *ClickHouseConfigGenerator) generateXMLConfig(settings *api.Settings, prefix string) string {
 	if settings.Len() == 0 {
@@ -542,7 +983,21 @@ func (c *ClickHouseConfigGenerator) generateXMLConfig(settings *api.Settings, pr
 
 // getDistributedDDLPath returns string path used in <distributed_ddl><path>XXX</path></distributed_ddl>
 func (c *ClickHouseConfigGenerator) getDistributedDDLPath() string {
-	return fmt.Sprintf(DistributedDDLPathPattern, c.chi.Name)
+	return CreateDistributedDDLPath(c.chi)
+}
+
+// CreateDistributedDDLPath returns keeper path of distributed DDL queue of the CHI
+func CreateDistributedDDLPath(chi *api.ClickHouseInstallation) string {
+	return fmt.Sprintf(DistributedDDLPathPattern, chi.Name)
+}
+
+// GetDistributedDDLPath returns keeper path of distributed DDL queue the CHI uses,
+// which is the one generated by the operator, unless it is overridden via settings
+func GetDistributedDDLPath(chi *api.ClickHouseInstallation) string {
+	if path := chi.Spec.Configuration.Settings.Get("distributed_ddl/path").String(); path != "" {
+		return path
+	}
+	return CreateDistributedDDLPath(chi)
 }
 
 // getRemoteServersReplicaHostname returns hostname (podhostname + service or FQDN) for "remote_servers.xml"
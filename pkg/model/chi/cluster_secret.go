@@ -0,0 +1,73 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	core "k8s.io/api/core/v1"
+)
+
+// Cluster secret delivered from a k8s Secret ('auto' or 'valueFrom') is passed to ClickHouse via ENV var.
+// ClickHouse accepts one cluster secret at a time, so in order to change the secret w/o a window, where hosts
+// refuse each other, the secret is kept in two slots of the Secret managed by the operator.
+// Each slot is passed to pods via ENV var of its own, while remote_servers refers to the active slot only.
+// New secret is written into the inactive slot and hosts are rolled to carry both secrets, still using the active one.
+// Once all hosts carry both secrets, remote_servers is switched to the other slot, which hosts pick up by config reload.
+const (
+	ClusterSecretSlotA = "a"
+	ClusterSecretSlotB = "b"
+)
+
+// CreateClusterSecretSlotEnvName creates name of the ENV var the slot of the cluster secret is passed to pods via.
+// Slot A keeps ENV var name used before slots were introduced, so remote_servers of running hosts stays valid
+func CreateClusterSecretSlotEnvName(slot string) string {
+	if slot == ClusterSecretSlotB {
+		return InternodeClusterSecretEnvName + "_B"
+	}
+	return InternodeClusterSecretEnvName
+}
+
+// GetClusterSecretActiveSlot gets slot of the cluster secret used by hosts
+func GetClusterSecretActiveSlot(secret *core.Secret) string {
+	if secret == nil {
+		return ClusterSecretSlotA
+	}
+	if secret.Annotations[AnnotationClusterSecretActiveSlot] == ClusterSecretSlotB {
+		return ClusterSecretSlotB
+	}
+	return ClusterSecretSlotA
+}
+
+// GetClusterSecretInactiveSlot gets slot of the cluster secret, which is not used by hosts
+func GetClusterSecretInactiveSlot(active string) string {
+	if active == ClusterSecretSlotB {
+		return ClusterSecretSlotA
+	}
+	return ClusterSecretSlotB
+}
+
+// GetClusterSecretVersion gets version of the cluster secret slots, which are passed to pods via ENV vars.
+// Version is an HMAC of both slots values salted with Secret's UID, so it does not reveal a plain hash of the secret,
+// and switch of the active slot, which is a change of Secret's metadata, does not change the version.
+func GetClusterSecretVersion(secret *core.Secret) string {
+	if secret == nil {
+		return ""
+	}
+	var value []byte
+	for _, slot := range []string{ClusterSecretSlotA, ClusterSecretSlotB} {
+		value = append(value, secret.Data[slot]...)
+		value = append(value, 0)
+	}
+	return hashClusterSecret(secret, value)
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newClusterSecretSlots(a, b, active string) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Name: "chi-cluster-secret-slots",
+			UID:  "uid",
+			Annotations: map[string]string{
+				AnnotationClusterSecretActiveSlot: active,
+			},
+		},
+		Data: map[string][]byte{
+			ClusterSecretSlotA: []byte(a),
+			ClusterSecretSlotB: []byte(b),
+		},
+	}
+}
+
+func TestClusterSecretSlots(t *testing.T) {
+	require.Equal(t, InternodeClusterSecretEnvName, CreateClusterSecretSlotEnvName(ClusterSecretSlotA))
+	require.Equal(t, InternodeClusterSecretEnvName, CreateClusterSecretSlotEnvName(""))
+	require.NotEqual(t, InternodeClusterSecretEnvName, CreateClusterSecretSlotEnvName(ClusterSecretSlotB))
+
+	require.Equal(t, ClusterSecretSlotA, GetClusterSecretActiveSlot(nil))
+	require.Equal(t, ClusterSecretSlotA, GetClusterSecretActiveSlot(newClusterSecretSlots("old", "new", "")))
+	require.Equal(t, ClusterSecretSlotB, GetClusterSecretActiveSlot(newClusterSecretSlots("old", "new", ClusterSecretSlotB)))
+	require.Equal(t, ClusterSecretSlotB, GetClusterSecretInactiveSlot(ClusterSecretSlotA))
+	require.Equal(t, ClusterSecretSlotA, GetClusterSecretInactiveSlot(ClusterSecretSlotB))
+}
+
+func TestClusterSecretVersion(t *testing.T) {
+	version := GetClusterSecretVersion(newClusterSecretSlots("old", "new", ClusterSecretSlotA))
+	require.NotEmpty(t, version)
+	require.NotContains(t, version, "old")
+
+	// Switch of the active slot does not roll hosts
+	require.Equal(t, version, GetClusterSecretVersion(newClusterSecretSlots("old", "new", ClusterSecretSlotB)))
+	// Change of either slot rolls hosts
+	require.NotEqual(t, version, GetClusterSecretVersion(newClusterSecretSlots("old", "newer", ClusterSecretSlotA)))
+	require.NotEqual(t, version, GetClusterSecretVersion(newClusterSecretSlots("older", "new", ClusterSecretSlotA)))
+	// Values are not mixed up across slots
+	require.NotEqual(t,
+		GetClusterSecretVersion(newClusterSecretSlots("ab", "c", ClusterSecretSlotA)),
+		GetClusterSecretVersion(newClusterSecretSlots("a", "bc", ClusterSecretSlotA)),
+	)
+	require.Empty(t, GetClusterSecretVersion(nil))
+}
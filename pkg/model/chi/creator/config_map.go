@@ -20,6 +20,7 @@ import (
 
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
 	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
 // CreateConfigMapCHICommon creates new core.ConfigMap
@@ -35,6 +36,7 @@ func (c *Creator) CreateConfigMapCHICommon(options *model.ClickHouseConfigFilesG
 		// Data contains several sections which are to be several xml chopConfig files
 		Data: c.chConfigFilesGenerator.CreateConfigFilesGroupCommon(options),
 	}
+	setupConfigHash(cm)
 	// And after the object is ready we can put version label
 	model.MakeObjectVersion(&cm.ObjectMeta, cm)
 	return cm
@@ -53,6 +55,7 @@ func (c *Creator) CreateConfigMapCHICommonUsers() *core.ConfigMap {
 		// Data contains several sections which are to be several xml chopConfig files
 		Data: c.chConfigFilesGenerator.CreateConfigFilesGroupUsers(),
 	}
+	setupConfigHash(cm)
 	// And after the object is ready we can put version label
 	model.MakeObjectVersion(&cm.ObjectMeta, cm)
 	return cm
@@ -71,7 +74,29 @@ func (c *Creator) CreateConfigMapHost(host *api.ChiHost) *core.ConfigMap {
 		// Data contains several sections which are to be several xml chopConfig files
 		Data: c.chConfigFilesGenerator.CreateConfigFilesGroupHost(host),
 	}
+	setupConfigHash(cm)
 	// And after the object is ready we can put version label
 	model.MakeObjectVersion(&cm.ObjectMeta, cm)
 	return cm
 }
+
+// CreateConfigMapCHIImportSchema creates new core.ConfigMap with schema SQLs to be applied on the imported CHI
+func (c *Creator) CreateConfigMapCHIImportSchema(schema string) *core.ConfigMap {
+	return &core.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{
+			Name:            model.CreateConfigMapImportSchemaName(c.chi),
+			Namespace:       c.chi.Namespace,
+			OwnerReferences: getOwnerReferences(c.chi),
+		},
+		Data: map[string]string{
+			model.ImportSchemaConfigMapKey: schema,
+		},
+	}
+}
+
+// setupConfigHash annotates config ConfigMap with hash of its data, so ConfigMap edited out-of-band can be told
+func setupConfigHash(cm *core.ConfigMap) {
+	cm.Annotations = util.MergeStringMapsOverwrite(cm.Annotations, map[string]string{
+		model.AnnotationConfigHash: model.CreateConfigHash(cm.Data),
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creator
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+)
+
+// Gateway API routes kinds
+const (
+	GatewayRouteKindTCP = "TCPRoute"
+	GatewayRouteKindTLS = "TLSRoute"
+)
+
+// GatewayAPIGroupVersion specifies Gateway API group and version routes are created with
+var GatewayAPIGroupVersion = schema.GroupVersion{Group: "gateway.networking.k8s.io", Version: "v1alpha2"}
+
+// GatewayRouteResource gets resource of Gateway API route of specified kind
+func GatewayRouteResource(kind string) schema.GroupVersionResource {
+	switch kind {
+	case GatewayRouteKindTLS:
+		return GatewayAPIGroupVersion.WithResource("tlsroutes")
+	default:
+		return GatewayAPIGroupVersion.WithResource("tcproutes")
+	}
+}
+
+// CreateGatewayRouteTCP creates new TCPRoute for native protocol port of specified CHI
+func (c *Creator) CreateGatewayRouteTCP() *unstructured.Unstructured {
+	return c.createGatewayRoute(GatewayRouteKindTCP, c.chi.Spec.Gateway.TCP, c.getGatewayTCPPort())
+}
+
+// CreateGatewayRouteTLS creates new TLSRoute for secure native protocol port of specified CHI
+func (c *Creator) CreateGatewayRouteTLS() *unstructured.Unstructured {
+	return c.createGatewayRoute(GatewayRouteKindTLS, c.chi.Spec.Gateway.TLS, c.getGatewayTLSPort())
+}
+
+// getGatewayTCPPort gets native protocol port of the gateway Service, which is the one hosts of the CHI listen on
+func (c *Creator) getGatewayTCPPort() int32 {
+	if host := c.chi.FirstHost(); (host != nil) && api.IsPortAssigned(host.TCPPort) {
+		return host.TCPPort
+	}
+	return model.ChDefaultTCPPortNumber
+}
+
+// getGatewayTLSPort gets secure native protocol port of the gateway Service, which is the one hosts of the CHI listen on
+func (c *Creator) getGatewayTLSPort() int32 {
+	if host := c.chi.FirstHost(); (host != nil) && api.IsPortAssigned(host.TLSPort) {
+		return host.TLSPort
+	}
+	return model.ChDefaultTLSPortNumber
+}
+
+// createGatewayRoute creates Gateway API route of specified kind, pointing to the gateway Service of the CHI
+func (c *Creator) createGatewayRoute(kind string, route *api.ChiGatewayRoute, port int32) *unstructured.Unstructured {
+	gatewayRef := c.chi.Spec.Gateway.GetGatewayRef()
+
+	parentRef := map[string]interface{}{
+		"name": gatewayRef.Name,
+	}
+	if gatewayRef.Namespace != "" {
+		parentRef["namespace"] = gatewayRef.Namespace
+	}
+	if route.SectionName != "" {
+		parentRef["sectionName"] = route.SectionName
+	}
+
+	spec := map[string]interface{}{
+		"parentRefs": []interface{}{
+			parentRef,
+		},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": model.CreateCHIServiceGatewayName(c.chi),
+						"port": int64(port),
+					},
+				},
+			},
+		},
+	}
+	if (kind == GatewayRouteKindTLS) && (len(route.Hostnames) > 0) {
+		var hostnames []interface{}
+		for _, hostname := range route.Hostnames {
+			hostnames = append(hostnames, hostname)
+		}
+		spec["hostnames"] = hostnames
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": spec,
+		},
+	}
+	obj.SetAPIVersion(GatewayAPIGroupVersion.String())
+	obj.SetKind(kind)
+	obj.SetName(model.CreateCHIGatewayRouteName(c.chi, kind))
+	obj.SetNamespace(c.chi.Namespace)
+	obj.SetLabels(model.Macro(c.chi).Map(c.labels.GetServiceCHI(c.chi)))
+	obj.SetAnnotations(model.Macro(c.chi).Map(c.annotations.GetServiceCHI(c.chi)))
+	obj.SetOwnerReferences(getOwnerReferences(c.chi))
+	return obj
+}
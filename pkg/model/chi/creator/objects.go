@@ -0,0 +1,92 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creator
+
+import (
+	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+)
+
+// Object lists below are shared by the reconcile and by the render package,
+// so rendered manifests never drift from objects the operator creates
+
+// CreateServicesCHI creates Services of the whole CHI - entry point, read-write and read-only entry points
+// and backend of Gateway API routes. Stopped CHI has no entry points
+func (c *Creator) CreateServicesCHI() (services []*core.Service) {
+	if c.chi.IsStopped() {
+		return nil
+	}
+	if service := c.CreateServiceCHI(); service != nil {
+		services = append(services, service)
+	}
+	if chop.Config().IsFeatureEnabled(api.FeatureGateReadWriteServices) {
+		for _, role := range []string{model.LabelReplicaRoleValueReadWrite, model.LabelReplicaRoleValueReadOnly} {
+			services = append(services, c.CreateServiceCHIReplicaRole(role))
+		}
+	}
+	if c.chi.Spec.Gateway.IsEnabled() {
+		services = append(services, c.CreateServiceCHIGateway())
+	}
+	return services
+}
+
+// CreateGatewayRoutes creates Gateway API routes requested by the CHI. Stopped CHI has no routes
+func (c *Creator) CreateGatewayRoutes() (routes []*unstructured.Unstructured) {
+	if c.chi.IsStopped() {
+		return nil
+	}
+	if c.chi.Spec.Gateway.HasTCP() {
+		routes = append(routes, c.CreateGatewayRouteTCP())
+	}
+	if c.chi.Spec.Gateway.HasTLS() {
+		routes = append(routes, c.CreateGatewayRouteTLS())
+	}
+	return routes
+}
+
+// CreateServicesCluster creates Services of the cluster, including extra Services
+func (c *Creator) CreateServicesCluster(cluster *api.Cluster) (services []*core.Service) {
+	if service := c.CreateServiceCluster(cluster); service != nil {
+		services = append(services, service)
+	}
+	return append(services, c.CreateServiceClusterExtra(cluster)...)
+}
+
+// CreateServicesHost creates Services of the host, extra Services go first
+func (c *Creator) CreateServicesHost(host *api.ChiHost) (services []*core.Service) {
+	services = append(services, c.CreateServiceHostExtra(host)...)
+	if service := c.CreateServiceHost(host); service != nil {
+		services = append(services, service)
+	}
+	return services
+}
+
+// CreatePodDisruptionBudgets creates PodDisruptionBudget(s) of the cluster.
+// Depending on feature gate, either one cluster-wide PDB or one PDB per shard is created
+func (c *Creator) CreatePodDisruptionBudgets(cluster *api.Cluster) (pdbs []*policy.PodDisruptionBudget) {
+	if chop.Config().IsFeatureEnabled(api.FeatureGatePerShardPDB) {
+		cluster.WalkShards(func(index int, shard *api.ChiShard) error {
+			pdbs = append(pdbs, c.NewPodDisruptionBudgetShard(shard))
+			return nil
+		})
+		return pdbs
+	}
+	return append(pdbs, c.NewPodDisruptionBudget(cluster))
+}
@@ -23,11 +23,12 @@ import (
 
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
 	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
 // NewPodDisruptionBudget creates new PodDisruptionBudget
 func (c *Creator) NewPodDisruptionBudget(cluster *api.Cluster) *policy.PodDisruptionBudget {
-	return &policy.PodDisruptionBudget{
+	pdb := &policy.PodDisruptionBudget{
 		ObjectMeta: meta.ObjectMeta{
 			Name:            fmt.Sprintf("%s-%s", cluster.Runtime.Address.CHIName, cluster.Runtime.Address.ClusterName),
 			Namespace:       c.chi.Namespace,
@@ -45,4 +46,54 @@ func (c *Creator) NewPodDisruptionBudget(cluster *api.Cluster) *policy.PodDisrup
 			},
 		},
 	}
+	if template, ok := cluster.GetPodDisruptionBudgetTemplate(); ok {
+		c.setupPodDisruptionBudgetFromTemplate(pdb, template)
+	}
+	return pdb
+}
+
+// NewPodDisruptionBudgetShard creates new shard-scoped PodDisruptionBudget
+func (c *Creator) NewPodDisruptionBudgetShard(shard *api.ChiShard) *policy.PodDisruptionBudget {
+	pdb := &policy.PodDisruptionBudget{
+		ObjectMeta: meta.ObjectMeta{
+			Name: fmt.Sprintf(
+				"%s-%s-%s",
+				shard.Runtime.Address.CHIName,
+				shard.Runtime.Address.ClusterName,
+				shard.Runtime.Address.ShardName,
+			),
+			Namespace:       c.chi.Namespace,
+			Labels:          model.Macro(c.chi).Map(c.labels.GetShardScope(shard)),
+			Annotations:     model.Macro(c.chi).Map(c.annotations.GetShardScope(shard)),
+			OwnerReferences: getOwnerReferences(c.chi),
+		},
+		Spec: policy.PodDisruptionBudgetSpec{
+			Selector: &meta.LabelSelector{
+				MatchLabels: model.GetSelectorShardScope(shard),
+			},
+			MaxUnavailable: &intstr.IntOrString{
+				Type:   intstr.Int,
+				IntVal: 1,
+			},
+		},
+	}
+	// Shard-scoped PodDisruptionBudgets follow template of the cluster
+	if template, ok := shard.GetCluster().GetPodDisruptionBudgetTemplate(); ok {
+		c.setupPodDisruptionBudgetFromTemplate(pdb, template)
+	}
+	return pdb
+}
+
+// setupPodDisruptionBudgetFromTemplate applies PodDisruptionBudgetTemplate to the PodDisruptionBudget.
+// Name, namespace, owner references and selector are managed by the operator and are kept as is,
+// labels and annotations of the template are combined with the ones provided by the operator.
+func (c *Creator) setupPodDisruptionBudgetFromTemplate(pdb *policy.PodDisruptionBudget, template *api.PodDisruptionBudgetTemplate) {
+	selector := pdb.Spec.Selector
+
+	pdb.Spec = *template.Spec.DeepCopy()
+	pdb.Spec.Selector = selector
+
+	// Combine labels and annotations
+	pdb.Labels = util.MergeStringMapsOverwrite(model.Macro(c.chi).Map(template.ObjectMeta.Labels), pdb.Labels)
+	pdb.Annotations = util.MergeStringMapsOverwrite(model.Macro(c.chi).Map(template.ObjectMeta.Annotations), pdb.Annotations)
 }
@@ -0,0 +1,136 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creator
+
+import (
+	"path"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/k8s"
+)
+
+// setupPodInstances adds a container per each additional ClickHouse instance to be run in the pod.
+// Additional containers are cloned from fully set up main ClickHouse container, so they have the same
+// image, resources and config volumes, but own ports, ENV vars and sub-folders in data and log volumes.
+func (c *Creator) setupPodInstances(statefulSet *apps.StatefulSet, host *api.ChiHost) {
+	if !model.HostHasMultipleInstances(host) {
+		return
+	}
+
+	container, ok := getMainContainer(statefulSet)
+	if !ok {
+		return
+	}
+	main := container.DeepCopy()
+
+	// Main container runs instance 0, which has all values of the host
+	container.Env = append(container.Env, model.CreatePodInstanceEnvVars(host, 0)...)
+
+	for instance := 1; instance < host.GetInstancesPerPod(); instance++ {
+		k8s.PodSpecAddContainer(
+			&statefulSet.Spec.Template.Spec,
+			newPodInstanceContainer(main, host, instance),
+		)
+	}
+}
+
+// newPodInstanceContainer creates container of the additional ClickHouse instance out of the main container
+func newPodInstanceContainer(main *core.Container, host *api.ChiHost, instance int) core.Container {
+	container := main.DeepCopy()
+	container.Name = model.CreatePodInstanceContainerName(instance)
+
+	// Instance listens on own ports only, user-specified ports of the main container would clash
+	container.Ports = nil
+	model.HostWalkInstanceAssignedPorts(
+		host,
+		instance,
+		func(name string, port int32, protocol core.Protocol) bool {
+			container.Ports = append(container.Ports,
+				core.ContainerPort{
+					Name:          name,
+					ContainerPort: port,
+					Protocol:      protocol,
+				},
+			)
+			// Do not abort, continue iterating
+			return false
+		},
+	)
+
+	container.Env = append(container.Env, model.CreatePodInstanceEnvVars(host, instance)...)
+
+	setupPodInstanceProbe(container.LivenessProbe, host, instance)
+	setupPodInstanceProbe(container.ReadinessProbe, host, instance)
+	setupPodInstanceProbe(container.StartupProbe, host, instance)
+
+	// Instance keeps data and logs in sub-folders of the volumes shared with other instances
+	for i := range container.VolumeMounts {
+		volumeMount := &container.VolumeMounts[i]
+		switch volumeMount.MountPath {
+		case model.DirPathClickHouseData, model.DirPathClickHouseLog:
+			volumeMount.SubPath = path.Join(volumeMount.SubPath, model.CreatePodInstanceDirName(instance))
+		}
+	}
+
+	return *container
+}
+
+// setupPodInstanceProbe points probe to ports of the specified ClickHouse instance
+func setupPodInstanceProbe(probe *core.Probe, host *api.ChiHost, instance int) {
+	if probe == nil {
+		return
+	}
+	if probe.HTTPGet != nil {
+		probe.HTTPGet.Port = getPodInstanceProbePort(probe.HTTPGet.Port, host, instance)
+	}
+	if probe.TCPSocket != nil {
+		probe.TCPSocket.Port = getPodInstanceProbePort(probe.TCPSocket.Port, host, instance)
+	}
+	if (probe.Exec != nil) && api.IsPortAssigned(host.TCPPort) {
+		// SQL probe addresses TCP port of the host
+		port := model.GetPodInstancePort(host, host.TCPPort, instance)
+		for i := range probe.Exec.Command {
+			probe.Exec.Command[i] = strings.ReplaceAll(probe.Exec.Command[i], sqlProbePortArg(host.TCPPort), sqlProbePortArg(port))
+		}
+	}
+}
+
+// getPodInstanceProbePort gets port of the specified ClickHouse instance, which corresponds to the host port,
+// referenced either by name or by number
+func getPodInstanceProbePort(port intstr.IntOrString, host *api.ChiHost, instance int) intstr.IntOrString {
+	result := port
+	model.HostWalkAssignedPorts(
+		host,
+		func(name string, _port *int32, protocol core.Protocol) bool {
+			switch {
+			case (port.Type == intstr.String) && (port.StrVal == name):
+				result = intstr.FromString(model.CreatePodInstancePortName(name, instance))
+				return true
+			case (port.Type == intstr.Int) && (port.IntVal == *_port):
+				result = intstr.FromInt(int(model.GetPodInstancePort(host, *_port, instance)))
+				return true
+			}
+			// Do not abort, continue iterating
+			return false
+		},
+	)
+	return result
+}
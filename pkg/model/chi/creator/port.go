@@ -0,0 +1,169 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/apis/deployment"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+)
+
+// ValidateHostPorts checks ports of the hosts for conflicts and returns conflicts found.
+// Ports listened by ClickHouse instances of a pod must not overlap each other.
+// Pods with hostNetwork listen on ports of the node, so hostNetwork pods, which may be scheduled
+// on the same node, must not share ports as well. Scheduling constraints, such as affinity, are not fully
+// accounted for, so hostNetwork pods have to be kept apart explicitly, either by podDistribution or by nodeSelector
+func ValidateHostPorts(chi *api.ClickHouseInstallation) (conflicts []string) {
+	var hostNetworkHosts []*api.ChiHost
+	chi.WalkHosts(func(host *api.ChiHost) error {
+		for port, names := range getHostPorts(host) {
+			if len(names) > 1 {
+				conflicts = append(conflicts, fmt.Sprintf("host %s: ports %s have the same number %d",
+					model.CreateStatefulSetName(host), strings.Join(names, ", "), port))
+			}
+		}
+		if podTemplate, ok := host.GetPodTemplate(); ok && podTemplate.Spec.HostNetwork {
+			hostNetworkHosts = append(hostNetworkHosts, host)
+		}
+		return nil
+	})
+
+	for i := range hostNetworkHosts {
+		for j := i + 1; j < len(hostNetworkHosts); j++ {
+			a, b := hostNetworkHosts[i], hostNetworkHosts[j]
+			if !hostsMayShareNode(a, b) {
+				continue
+			}
+			if ports := intersectHostPorts(a, b); len(ports) > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("hostNetwork hosts %s and %s may run on the same node and both listen on ports %v",
+					model.CreateStatefulSetName(a), model.CreateStatefulSetName(b), ports))
+			}
+		}
+	}
+
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// getHostPorts gets ports listened by all ClickHouse instances of the host pod, mapped to names of the ports
+func getHostPorts(host *api.ChiHost) map[int32][]string {
+	ports := make(map[int32][]string)
+	model.HostWalkInstances(host, func(instance int) {
+		model.HostWalkInstanceAssignedPorts(
+			host,
+			instance,
+			func(name string, port int32, protocol core.Protocol) bool {
+				ports[port] = append(ports[port], name)
+				// Do not abort, continue iterating
+				return false
+			},
+		)
+	})
+	return ports
+}
+
+// intersectHostPorts gets ports listened by both hosts, sorted
+func intersectHostPorts(a, b *api.ChiHost) (ports []int) {
+	bPorts := getHostPorts(b)
+	for port := range getHostPorts(a) {
+		if _, ok := bPorts[port]; ok {
+			ports = append(ports, int(port))
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// hostsMayShareNode checks whether pods of the hosts may be scheduled on the same node,
+// i.e. neither of the hosts keeps apart from the other one with node-level anti-affinity
+// and node selectors of the hosts do not exclude each other
+func hostsMayShareNode(a, b *api.ChiHost) bool {
+	return !hostKeepsApart(a, b) && !hostKeepsApart(b, a) && !hostsNodeSelectorsExclusive(a, b)
+}
+
+// hostsNodeSelectorsExclusive checks whether node selectors of the hosts require different values of the same label,
+// so no node matches both of them
+func hostsNodeSelectorsExclusive(a, b *api.ChiHost) bool {
+	aTemplate, ok := a.GetPodTemplate()
+	if !ok {
+		return false
+	}
+	bTemplate, ok := b.GetPodTemplate()
+	if !ok {
+		return false
+	}
+	for label, value := range aTemplate.Spec.NodeSelector {
+		if other, ok := bTemplate.Spec.NodeSelector[label]; ok && (other != value) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostKeepsApart checks whether pod distribution of the host does not allow other host to share node
+func hostKeepsApart(host, other *api.ChiHost) bool {
+	podTemplate, ok := host.GetPodTemplate()
+	if !ok {
+		return false
+	}
+
+	sameCluster := host.Runtime.Address.ClusterName == other.Runtime.Address.ClusterName
+	sameShard := sameCluster && (host.Runtime.Address.ShardIndex == other.Runtime.Address.ShardIndex)
+	sameReplica := sameCluster && (host.Runtime.Address.ReplicaIndex == other.Runtime.Address.ReplicaIndex)
+
+	for _, podDistribution := range podTemplate.PodDistribution {
+		if (podDistribution.TopologyKey != "") && (podDistribution.TopologyKey != core.LabelHostname) {
+			// Hosts are kept apart in other topology domains, such as zones, and still may share node
+			continue
+		}
+		inScope := true
+		switch podDistribution.Scope {
+		case deployment.PodDistributionScopeShard:
+			inScope = sameShard
+		case deployment.PodDistributionScopeReplica:
+			inScope = sameReplica
+		case deployment.PodDistributionScopeCluster:
+			inScope = sameCluster
+		}
+		switch podDistribution.Type {
+		case deployment.PodDistributionClickHouseAntiAffinity:
+			if inScope {
+				return true
+			}
+		case deployment.PodDistributionMaxNumberPerNode:
+			if inScope && (podDistribution.Number <= 1) {
+				return true
+			}
+		case deployment.PodDistributionShardAntiAffinity:
+			// Pods are matched by shard name
+			if inScope && (host.Runtime.Address.ShardName == other.Runtime.Address.ShardName) {
+				return true
+			}
+		case deployment.PodDistributionReplicaAntiAffinity:
+			// Pods are matched by replica name
+			if inScope && (host.Runtime.Address.ReplicaName == other.Runtime.Address.ReplicaName) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -15,6 +15,8 @@
 package creator
 
 import (
+	"fmt"
+
 	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -24,12 +26,23 @@ import (
 
 // newDefaultLivenessProbe is a unification wrapper
 func newDefaultLivenessProbe(host *api.ChiHost) *core.Probe {
-	return newDefaultClickHouseLivenessProbe(host)
+	spec := host.GetCHI().Spec.Defaults.GetProbes().GetLiveness()
+	return applyProbeSpec(newDefaultClickHouseLivenessProbe(host), spec, host, sqlProbeQueryAlive)
 }
 
 // newDefaultReadinessProbe is a unification wrapper
 func newDefaultReadinessProbe(host *api.ChiHost) *core.Probe {
-	return newDefaultClickHouseReadinessProbe(host)
+	spec := host.GetCHI().Spec.Defaults.GetProbes().GetReadiness()
+	return applyProbeSpec(newDefaultClickHouseReadinessProbe(host), spec, host, sqlProbeQueryReady)
+}
+
+// newDefaultStartupProbe is a unification wrapper. Startup probe is not generated unless specified in CHI defaults
+func newDefaultStartupProbe(host *api.ChiHost) *core.Probe {
+	spec := host.GetCHI().Spec.Defaults.GetProbes().GetStartup()
+	if spec == nil {
+		return nil
+	}
+	return applyProbeSpec(newDefaultClickHouseStartupProbe(host), spec, host, sqlProbeQueryAlive)
 }
 
 // newDefaultClickHouseLivenessProbe returns default ClickHouse liveness probe
@@ -103,3 +116,99 @@ func newDefaultClickHouseReadinessProbe(host *api.ChiHost) *core.Probe {
 	// Probe is not available
 	return nil
 }
+
+// newDefaultClickHouseStartupProbe returns default ClickHouse startup probe.
+// Startup probe tolerates long start of ClickHouse, such as loading of many tables, up to 5 minutes
+func newDefaultClickHouseStartupProbe(host *api.ChiHost) *core.Probe {
+	probe := newDefaultClickHouseLivenessProbe(host)
+	if probe == nil {
+		// Probe is not available
+		return nil
+	}
+	probe.InitialDelaySeconds = 0
+	probe.PeriodSeconds = 3
+	probe.FailureThreshold = 100
+	return probe
+}
+
+const (
+	// SQLProbeUserEnvVarName specifies ENV var of ClickHouse container carrying user the SQL probe connects as.
+	// `default` user is used in case ENV var is not set
+	SQLProbeUserEnvVarName = "CLICKHOUSE_PROBE_USER"
+	// SQLProbePasswordEnvVarName specifies ENV var of ClickHouse container carrying password of the SQL probe user
+	SQLProbePasswordEnvVarName = "CLICKHOUSE_PROBE_PASSWORD"
+	// sqlProbeScript specifies check script, which runs SQL query via clickhouse-client.
+	// Credentials are read from ENV vars of the container, so they do not show up in the pod spec.
+	// Probe fails in case ClickHouse does not accept queries or the query throws
+	sqlProbeScript = `clickhouse-client --host=127.0.0.1 %s ` +
+		`--user="${` + SQLProbeUserEnvVarName + `:-default}" --password="${` + SQLProbePasswordEnvVarName + `}" ` +
+		`--query="%s" > /dev/null`
+	// sqlProbeQueryAlive checks ClickHouse accepts queries
+	sqlProbeQueryAlive = "SELECT 1"
+	// sqlProbeQueryReady checks ClickHouse accepts queries and has no read-only replicas
+	sqlProbeQueryReady = "SELECT throwIf(count() > 0, 'replicas are read-only') FROM system.replicas WHERE is_readonly"
+	// sqlProbeTimeoutSeconds specifies default timeout of SQL probe, as query takes longer than /ping request
+	sqlProbeTimeoutSeconds = 5
+)
+
+// sqlProbePortArg creates clickhouse-client port argument
+func sqlProbePortArg(port int32) string {
+	return fmt.Sprintf("--port=%d", port)
+}
+
+// newClickHouseSQLProbeHandler returns handler of probe running SQL query via clickhouse-client
+func newClickHouseSQLProbeHandler(host *api.ChiHost, query string) (core.ProbeHandler, bool) {
+	if !api.IsPortAssigned(host.TCPPort) {
+		// SQL probe is not available
+		return core.ProbeHandler{}, false
+	}
+	return core.ProbeHandler{
+		Exec: &core.ExecAction{
+			Command: []string{
+				"sh",
+				"-c",
+				fmt.Sprintf(sqlProbeScript, sqlProbePortArg(host.TCPPort), query),
+			},
+		},
+	}, true
+}
+
+// applyProbeSpec applies probe specified in CHI defaults to the probe generated by the operator.
+// Probe runs SQL query in case SQL check is specified, non-zero values of the spec override values of the probe
+func applyProbeSpec(probe *core.Probe, spec *api.ChiProbe, host *api.ChiHost, query string) *core.Probe {
+	if spec == nil {
+		return probe
+	}
+
+	if spec.GetCheck() == api.ProbeCheckSQL {
+		if handler, ok := newClickHouseSQLProbeHandler(host, query); ok {
+			if probe == nil {
+				probe = &core.Probe{}
+			}
+			probe.ProbeHandler = handler
+			probe.TimeoutSeconds = sqlProbeTimeoutSeconds
+		}
+	}
+
+	if probe == nil {
+		// Probe is not available
+		return nil
+	}
+
+	if spec.InitialDelaySeconds > 0 {
+		probe.InitialDelaySeconds = int32(spec.InitialDelaySeconds)
+	}
+	if spec.PeriodSeconds > 0 {
+		probe.PeriodSeconds = int32(spec.PeriodSeconds)
+	}
+	if spec.TimeoutSeconds > 0 {
+		probe.TimeoutSeconds = int32(spec.TimeoutSeconds)
+	}
+	if spec.SuccessThreshold > 0 {
+		probe.SuccessThreshold = int32(spec.SuccessThreshold)
+	}
+	if spec.FailureThreshold > 0 {
+		probe.FailureThreshold = int32(spec.FailureThreshold)
+	}
+	return probe
+}
@@ -18,6 +18,8 @@ import (
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
@@ -34,3 +36,42 @@ func (c *Creator) CreateClusterSecret(name string) *core.Secret {
 		Type: core.SecretTypeOpaque,
 	}
 }
+
+// CreateClusterSecretSlots creates Secret carrying slots of the cluster secret, which are passed to pods via ENV vars.
+// Both slots carry the same secret and slot A is the active one
+func (c *Creator) CreateClusterSecretSlots(cluster *api.Cluster, value []byte) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: c.chi.Namespace,
+			Name:      model.CreateClusterSecretSlotsName(cluster),
+			Annotations: map[string]string{
+				model.AnnotationClusterSecretActiveSlot: model.ClusterSecretSlotA,
+			},
+		},
+		Data: map[string][]byte{
+			model.ClusterSecretSlotA: value,
+			model.ClusterSecretSlotB: value,
+		},
+		Type: core.SecretTypeOpaque,
+	}
+}
+
+// CreateConfigSecret creates Secret carrying the same config files as the specified ConfigMap.
+// It is used instead of the ConfigMap in case CHI requires config files to be delivered in Secrets
+func (c *Creator) CreateConfigSecret(configMap *core.ConfigMap) *core.Secret {
+	secret := &core.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Name:            configMap.Name,
+			Namespace:       configMap.Namespace,
+			Labels:          util.CopyMap(configMap.Labels),
+			Annotations:     util.CopyMap(configMap.Annotations),
+			OwnerReferences: configMap.OwnerReferences,
+		},
+		Data: make(map[string][]byte),
+		Type: core.SecretTypeOpaque,
+	}
+	for file, content := range configMap.Data {
+		secret.Data[file] = []byte(content)
+	}
+	return secret
+}
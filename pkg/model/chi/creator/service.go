@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
 	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
 	"github.com/altinity/clickhouse-operator/pkg/model/k8s"
 	"github.com/altinity/clickhouse-operator/pkg/util"
@@ -74,6 +75,84 @@ func (c *Creator) CreateServiceCHI() *core.Service {
 			// ExternalTrafficPolicy: core.ServiceExternalTrafficPolicyTypeLocal, // For core.ServiceTypeLoadBalancer only
 		},
 	}
+	setupServiceType(svc, c.getServiceTypeCHI())
+	model.MakeObjectVersion(&svc.ObjectMeta, svc)
+	return svc
+}
+
+// getServiceTypeCHI gets type of CHI-level Service created w/o ServiceTemplate.
+// CHI-specified type takes priority over operator-wide type
+func (c *Creator) getServiceTypeCHI() string {
+	if serviceType := c.chi.Spec.Defaults.GetServiceType(); serviceType != "" {
+		return serviceType
+	}
+	return chop.Config().Template.Service.Type
+}
+
+// CreateServiceCHIGateway creates new core.Service for specified CHI, which is used as a backend of Gateway API routes.
+// Such a Service is always of ClusterIP type and exposes native protocol ports only.
+func (c *Creator) CreateServiceCHIGateway() *core.Service {
+	svc := &core.Service{
+		ObjectMeta: meta.ObjectMeta{
+			Name:            model.CreateCHIServiceGatewayName(c.chi),
+			Namespace:       c.chi.Namespace,
+			Labels:          model.Macro(c.chi).Map(c.labels.GetServiceCHI(c.chi)),
+			Annotations:     model.Macro(c.chi).Map(c.annotations.GetServiceCHI(c.chi)),
+			OwnerReferences: getOwnerReferences(c.chi),
+		},
+		Spec: core.ServiceSpec{
+			Ports: []core.ServicePort{
+				{
+					Name:       model.ChDefaultTCPPortName,
+					Protocol:   core.ProtocolTCP,
+					Port:       c.getGatewayTCPPort(),
+					TargetPort: intstr.FromString(model.ChDefaultTCPPortName),
+				},
+				{
+					Name:       model.ChDefaultTLSPortName,
+					Protocol:   core.ProtocolTCP,
+					Port:       c.getGatewayTLSPort(),
+					TargetPort: intstr.FromString(model.ChDefaultTLSPortName),
+				},
+			},
+			Selector: c.labels.GetSelectorCHIScopeReady(),
+			Type:     core.ServiceTypeClusterIP,
+		},
+	}
+	model.MakeObjectVersion(&svc.ObjectMeta, svc)
+	return svc
+}
+
+// CreateServiceCHIReplicaRole creates new core.Service for specified CHI, which selects hosts having specified replica role.
+// Such a Service is always of ClusterIP type and is not affected by CHI-level ServiceTemplate.
+func (c *Creator) CreateServiceCHIReplicaRole(role string) *core.Service {
+	svc := &core.Service{
+		ObjectMeta: meta.ObjectMeta{
+			Name:            model.CreateCHIServiceReplicaRoleName(c.chi, role),
+			Namespace:       c.chi.Namespace,
+			Labels:          model.Macro(c.chi).Map(c.labels.GetServiceCHIReplicaRole(c.chi, role)),
+			Annotations:     model.Macro(c.chi).Map(c.annotations.GetServiceCHI(c.chi)),
+			OwnerReferences: getOwnerReferences(c.chi),
+		},
+		Spec: core.ServiceSpec{
+			Ports: []core.ServicePort{
+				{
+					Name:       model.ChDefaultHTTPPortName,
+					Protocol:   core.ProtocolTCP,
+					Port:       model.ChDefaultHTTPPortNumber,
+					TargetPort: intstr.FromString(model.ChDefaultHTTPPortName),
+				},
+				{
+					Name:       model.ChDefaultTCPPortName,
+					Protocol:   core.ProtocolTCP,
+					Port:       model.ChDefaultTCPPortNumber,
+					TargetPort: intstr.FromString(model.ChDefaultTCPPortName),
+				},
+			},
+			Selector: c.labels.GetSelectorCHIReplicaRoleReady(role),
+			Type:     core.ServiceTypeClusterIP,
+		},
+	}
 	model.MakeObjectVersion(&svc.ObjectMeta, svc)
 	return svc
 }
@@ -97,10 +176,50 @@ func (c *Creator) CreateServiceCluster(cluster *api.Cluster) *core.Service {
 			model.Macro(cluster),
 		)
 	}
-	// No template specified, no need to create service
+	if cluster.ServiceType != "" {
+		// Service type specified, create default service of this type
+		svc := &core.Service{
+			ObjectMeta: meta.ObjectMeta{
+				Name:            serviceName,
+				Namespace:       cluster.Runtime.Address.Namespace,
+				Labels:          model.Macro(cluster).Map(c.labels.GetServiceCluster(cluster)),
+				Annotations:     model.Macro(cluster).Map(c.annotations.GetServiceCluster(cluster)),
+				OwnerReferences: ownerReferences,
+			},
+			Spec: core.ServiceSpec{
+				Ports:    newDefaultServicePorts(),
+				Selector: model.GetSelectorClusterScopeReady(cluster),
+			},
+		}
+		setupServiceType(svc, cluster.ServiceType)
+		model.MakeObjectVersion(&svc.ObjectMeta, svc)
+		return svc
+	}
+	// Neither template nor service type specified, no need to create service
 	return nil
 }
 
+// CreateServiceClusterExtra creates extra core.Service(s) for specified Cluster, one per ServiceTemplate listed
+func (c *Creator) CreateServiceClusterExtra(cluster *api.Cluster) []*core.Service {
+	var services []*core.Service
+	for _, template := range cluster.GetExtraServiceTemplates() {
+		svc := c.createServiceFromTemplate(
+			template,
+			cluster.Runtime.Address.Namespace,
+			model.CreateClusterExtraServiceName(cluster, template),
+			c.labels.GetServiceCluster(cluster),
+			c.annotations.GetServiceCluster(cluster),
+			model.GetSelectorClusterScopeReady(cluster),
+			getOwnerReferences(c.chi),
+			model.Macro(cluster),
+		)
+		if svc != nil {
+			services = append(services, svc)
+		}
+	}
+	return services
+}
+
 // CreateServiceShard creates new core.Service for specified Shard
 func (c *Creator) CreateServiceShard(shard *api.ChiShard) *core.Service {
 	if template, ok := shard.GetServiceTemplate(); ok {
@@ -116,7 +235,26 @@ func (c *Creator) CreateServiceShard(shard *api.ChiShard) *core.Service {
 			model.Macro(shard),
 		)
 	}
-	// No template specified, no need to create service
+	if shard.ServiceType != "" {
+		// Service type specified, create default service of this type
+		svc := &core.Service{
+			ObjectMeta: meta.ObjectMeta{
+				Name:            model.CreateShardServiceName(shard),
+				Namespace:       shard.Runtime.Address.Namespace,
+				Labels:          model.Macro(shard).Map(c.labels.GetServiceShard(shard)),
+				Annotations:     model.Macro(shard).Map(c.annotations.GetServiceShard(shard)),
+				OwnerReferences: getOwnerReferences(c.chi),
+			},
+			Spec: core.ServiceSpec{
+				Ports:    newDefaultServicePorts(),
+				Selector: model.GetSelectorShardScopeReady(shard),
+			},
+		}
+		setupServiceType(svc, shard.ServiceType)
+		model.MakeObjectVersion(&svc.ObjectMeta, svc)
+		return svc
+	}
+	// Neither template nor service type specified, no need to create service
 	return nil
 }
 
@@ -124,7 +262,7 @@ func (c *Creator) CreateServiceShard(shard *api.ChiShard) *core.Service {
 func (c *Creator) CreateServiceHost(host *api.ChiHost) *core.Service {
 	if template, ok := host.GetServiceTemplate(); ok {
 		// .templates.ServiceTemplate specified
-		return c.createServiceFromTemplate(
+		svc := c.createServiceFromTemplate(
 			template,
 			host.Runtime.Address.Namespace,
 			model.CreateStatefulSetServiceName(host),
@@ -134,6 +272,11 @@ func (c *Creator) CreateServiceHost(host *api.ChiHost) *core.Service {
 			getOwnerReferences(c.chi),
 			model.Macro(host),
 		)
+		if svc != nil {
+			setupServiceHostTargetPorts(svc, host)
+			model.MakeObjectVersion(&svc.ObjectMeta, svc)
+		}
+		return svc
 	}
 
 	// Create default Service
@@ -154,28 +297,120 @@ func (c *Creator) CreateServiceHost(host *api.ChiHost) *core.Service {
 		},
 	}
 	appendServicePorts(svc, host)
+	setupServiceType(svc, host.ServiceType)
 	model.MakeObjectVersion(&svc.ObjectMeta, svc)
 	return svc
 }
 
+// CreateServiceHostExtra creates extra core.Service(s) for specified host, one per ServiceTemplate listed
+func (c *Creator) CreateServiceHostExtra(host *api.ChiHost) []*core.Service {
+	var services []*core.Service
+	for _, template := range host.GetExtraServiceTemplates() {
+		svc := c.createServiceFromTemplate(
+			template,
+			host.Runtime.Address.Namespace,
+			model.CreateStatefulSetExtraServiceName(host, template),
+			c.labels.GetServiceHost(host),
+			c.annotations.GetServiceHost(host),
+			model.GetSelectorHostScope(host),
+			getOwnerReferences(c.chi),
+			model.Macro(host),
+		)
+		if svc != nil {
+			setupServiceHostTargetPorts(svc, host)
+			model.MakeObjectVersion(&svc.ObjectMeta, svc)
+			services = append(services, svc)
+		}
+	}
+	return services
+}
+
 func appendServicePorts(service *core.Service, host *api.ChiHost) {
-	// Walk over all assigned ports of the host and append each port to the list of service's ports
-	model.HostWalkAssignedPorts(
-		host,
-		func(name string, port *int32, protocol core.Protocol) bool {
-			// Append assigned port to the list of service's ports
-			service.Spec.Ports = append(service.Spec.Ports,
-				core.ServicePort{
-					Name:       name,
-					Protocol:   protocol,
-					Port:       *port,
-					TargetPort: intstr.FromInt(int(*port)),
-				},
-			)
-			// Do not abort, continue iterating
-			return false
+	// Walk over all assigned ports of all ClickHouse instances of the host and append each port to the list of service's ports
+	model.HostWalkInstances(host, func(instance int) {
+		model.HostWalkInstanceAssignedPorts(
+			host,
+			instance,
+			func(name string, port int32, protocol core.Protocol) bool {
+				// Append assigned port to the list of service's ports
+				service.Spec.Ports = append(service.Spec.Ports,
+					core.ServicePort{
+						Name:       name,
+						Protocol:   protocol,
+						Port:       port,
+						TargetPort: intstr.FromInt(int(port)),
+					},
+				)
+				// Do not abort, continue iterating
+				return false
+			},
+		)
+	})
+}
+
+// setupServiceHostTargetPorts points ports of the host Service created from ServiceTemplate, which have no target port specified,
+// to the ports of the host having the same names. Otherwise target port would default to the port of the Service,
+// which does not follow ports overridden by the host
+func setupServiceHostTargetPorts(service *core.Service, host *api.ChiHost) {
+	for i := range service.Spec.Ports {
+		servicePort := &service.Spec.Ports[i]
+		if servicePort.TargetPort != (intstr.IntOrString{}) {
+			// Target port is specified explicitly
+			continue
+		}
+		model.HostWalkAssignedPorts(
+			host,
+			func(name string, port *int32, protocol core.Protocol) bool {
+				if name == servicePort.Name {
+					servicePort.TargetPort = intstr.FromInt(int(*port))
+					return true
+				}
+				// Do not abort, continue iterating
+				return false
+			},
+		)
+	}
+}
+
+// newDefaultServicePorts creates ports of the Service created w/o ServiceTemplate
+func newDefaultServicePorts() []core.ServicePort {
+	return []core.ServicePort{
+		{
+			Name:       model.ChDefaultHTTPPortName,
+			Protocol:   core.ProtocolTCP,
+			Port:       model.ChDefaultHTTPPortNumber,
+			TargetPort: intstr.FromString(model.ChDefaultHTTPPortName),
+		},
+		{
+			Name:       model.ChDefaultTCPPortName,
+			Protocol:   core.ProtocolTCP,
+			Port:       model.ChDefaultTCPPortNumber,
+			TargetPort: intstr.FromString(model.ChDefaultTCPPortName),
 		},
-	)
+	}
+}
+
+// setupServiceType sets up Service created w/o ServiceTemplate to be of the specified type.
+// Service is headless in case type is not specified
+func setupServiceType(svc *core.Service, serviceType string) {
+	switch serviceType {
+	case api.ServiceTypeClusterIP:
+		svc.Spec.Type = core.ServiceTypeClusterIP
+		svc.Spec.ClusterIP = ""
+	case api.ServiceTypeNodePort:
+		svc.Spec.Type = core.ServiceTypeNodePort
+		svc.Spec.ClusterIP = ""
+	case api.ServiceTypeLoadBalancer:
+		svc.Spec.Type = core.ServiceTypeLoadBalancer
+		svc.Spec.ClusterIP = ""
+	case api.ServiceTypeInternalLoadBalancer:
+		svc.Spec.Type = core.ServiceTypeLoadBalancer
+		svc.Spec.ClusterIP = ""
+		svc.Annotations = util.MergeStringMapsPreserve(svc.Annotations, chop.Config().Template.Service.InternalLoadBalancer.Annotations)
+	default:
+		svc.Spec.Type = core.ServiceTypeClusterIP
+		svc.Spec.ClusterIP = model.TemplateDefaultsServiceClusterIP
+	}
 }
 
 // createServiceFromTemplate create Service from ServiceTemplate and additional info
@@ -0,0 +1,134 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creator
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/apis/deployment"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// ServiceMonitorKind specifies kind of Prometheus Operator ServiceMonitor
+const ServiceMonitorKind = "ServiceMonitor"
+
+// ServiceMonitorResource specifies resource ServiceMonitors are created as
+var ServiceMonitorResource = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"}
+
+// Ports of the operator's metrics Service, as they are named in operator's deployment,
+// Service is selected by its 'app: clickhouse-operator' label
+const (
+	serviceMonitorPortClickHouseMetrics = "clickhouse-metrics"
+	serviceMonitorPortOperatorMetrics   = "operator-metrics"
+)
+
+// defaultServiceMonitorLabels specifies labels ServiceMonitor is selected by Prometheus with,
+// as Prometheus is set up in deploy/prometheus, in case no CHI specifies labels
+var defaultServiceMonitorLabels = map[string]string{
+	"app": "prometheus",
+}
+
+// GetOperatorServiceMonitorNamespace gets namespace of the operator's ServiceMonitor, which is the namespace of the operator
+func GetOperatorServiceMonitorNamespace() string {
+	namespace, _ := chop.Get().ConfigManager.GetRuntimeParam(deployment.OPERATOR_POD_NAMESPACE)
+	return namespace
+}
+
+// CreateOperatorServiceMonitor creates Prometheus Operator ServiceMonitor of the operator's metrics Service.
+// Metrics of all CHIs are published by the operator's metrics exporter, both ClickHouse metrics and operator metrics,
+// thus single ServiceMonitor scrapes metrics of all CHIs at once, regardless of the number of CHIs.
+// ServiceMonitor combines settings of all CHIs having it enabled: labels are merged, the shortest interval and timeout win.
+// Returns nil in case no CHI has ServiceMonitor enabled
+func CreateOperatorServiceMonitor(namespace string, monitors []*api.ChiServiceMonitor) *unstructured.Unstructured {
+	if len(monitors) == 0 {
+		return nil
+	}
+
+	labels := make(map[string]string)
+	var interval, scrapeTimeout string
+	for _, monitor := range monitors {
+		labels = util.MergeStringMapsPreserve(labels, monitor.Labels)
+		interval = shortestDuration(interval, monitor.Interval)
+		scrapeTimeout = shortestDuration(scrapeTimeout, monitor.ScrapeTimeout)
+	}
+	if len(labels) == 0 {
+		labels = defaultServiceMonitorLabels
+	}
+
+	var endpoints []interface{}
+	for _, port := range []string{serviceMonitorPortClickHouseMetrics, serviceMonitorPortOperatorMetrics} {
+		endpoint := map[string]interface{}{
+			"port": port,
+			// Series are labeled by the exporter, labels must not be overwritten by target labels
+			"honorLabels": true,
+		}
+		if interval != "" {
+			endpoint["interval"] = interval
+		}
+		if scrapeTimeout != "" {
+			endpoint["scrapeTimeout"] = scrapeTimeout
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"endpoints": endpoints,
+				"selector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						"app": "clickhouse-operator",
+					},
+				},
+				"namespaceSelector": map[string]interface{}{
+					"matchNames": []interface{}{namespace},
+				},
+			},
+		},
+	}
+	obj.SetAPIVersion(ServiceMonitorResource.GroupVersion().String())
+	obj.SetKind(ServiceMonitorKind)
+	obj.SetName(model.OperatorServiceMonitorName)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(labels)
+	return obj
+}
+
+// shortestDuration picks the shorter one of Prometheus durations, such as 30s.
+// Duration, which is empty or can not be parsed, is picked only in case neither of them can be parsed
+func shortestDuration(a, b string) string {
+	durationA, errA := time.ParseDuration(a)
+	durationB, errB := time.ParseDuration(b)
+	switch {
+	case (errA != nil) && (errB != nil):
+		if a != "" {
+			return a
+		}
+		return b
+	case errA != nil:
+		return b
+	case errB != nil:
+		return a
+	case durationB < durationA:
+		return b
+	}
+	return a
+}
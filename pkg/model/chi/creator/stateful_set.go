@@ -15,6 +15,10 @@
 package creator
 
 import (
+	"fmt"
+	"path"
+	"strings"
+
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -59,6 +63,7 @@ func (c *Creator) CreateStatefulSet(host *api.ChiHost, shutdown bool) *apps.Stat
 
 	c.setupStatefulSetPodTemplate(statefulSet, host)
 	c.setupStatefulSetVolumeClaimTemplates(statefulSet, host)
+	c.setupPodInstances(statefulSet, host)
 	model.MakeObjectVersion(&statefulSet.ObjectMeta, statefulSet)
 
 	return statefulSet
@@ -72,6 +77,8 @@ func (c *Creator) setupStatefulSetPodTemplate(statefulSet *apps.StatefulSet, hos
 
 	// Post-process StatefulSet
 	ensureStatefulSetTemplateIntegrity(statefulSet, host)
+	setupClickHouseImage(statefulSet, host)
+	injectSidecars(statefulSet, host)
 	setupEnvVars(statefulSet, host)
 	c.personalizeStatefulSetTemplate(statefulSet, host)
 }
@@ -83,6 +90,39 @@ func ensureStatefulSetTemplateIntegrity(statefulSet *apps.StatefulSet, host *api
 	ensureNamedPortsSpecified(statefulSet, host)
 }
 
+// setupClickHouseImage sets image of ClickHouse container according to ClickHouse version the cluster is pinned to.
+// Pinned version takes precedence over image specified by the pod template
+func setupClickHouseImage(statefulSet *apps.StatefulSet, host *api.ChiHost) {
+	version := host.GetCluster().GetClickHouseVersion()
+	if version == "" {
+		return
+	}
+	container, ok := getClickHouseContainer(statefulSet)
+	if !ok {
+		return
+	}
+	container.Image = chop.Config().GetClickHouseImage(version)
+}
+
+// injectSidecars injects sidecars specified in operator config into the StatefulSet, unless CHI opts-out.
+// Containers specified by the pod template explicitly take precedence over sidecars with the same name
+func injectSidecars(statefulSet *apps.StatefulSet, host *api.ChiHost) {
+	if !host.GetCHI().Spec.Defaults.ShouldInjectSidecars() {
+		return
+	}
+
+	for i := range chop.Config().Template.Sidecars {
+		sidecar := &chop.Config().Template.Sidecars[i]
+		if _, ok := k8s.StatefulSetContainerGet(statefulSet, sidecar.Name, -1); ok {
+			continue
+		}
+		k8s.PodSpecAddContainer(
+			&statefulSet.Spec.Template.Spec,
+			*sidecar.DeepCopy(),
+		)
+	}
+}
+
 // setupEnvVars setup ENV vars for clickhouse container
 func setupEnvVars(statefulSet *apps.StatefulSet, host *api.ChiHost) {
 	container, ok := getMainContainer(statefulSet)
@@ -91,6 +131,18 @@ func setupEnvVars(statefulSet *apps.StatefulSet, host *api.ChiHost) {
 	}
 
 	container.Env = append(container.Env, host.GetCHI().EnsureRuntime().GetAttributes().AdditionalEnvVars...)
+
+	if host.GetInterserverHTTPHost() == api.InterserverHTTPHostPodIP {
+		// Pod IP is used as interserver host
+		container.Env = append(container.Env, core.EnvVar{
+			Name: model.PodEnvPodIP,
+			ValueFrom: &core.EnvVarSource{
+				FieldRef: &core.ObjectFieldSelector{
+					FieldPath: "status.podIP",
+				},
+			},
+		})
+	}
 }
 
 // ensureMainContainerSpecified is a unification wrapper
@@ -144,17 +196,25 @@ func ensureProbesSpecified(statefulSet *apps.StatefulSet, host *api.ChiHost) {
 	if container.ReadinessProbe == nil {
 		container.ReadinessProbe = newDefaultReadinessProbe(host)
 	}
+	if container.StartupProbe == nil {
+		container.StartupProbe = newDefaultStartupProbe(host)
+	}
 }
 
 // personalizeStatefulSetTemplate
 func (c *Creator) personalizeStatefulSetTemplate(statefulSet *apps.StatefulSet, host *api.ChiHost) {
 	// Ensure pod created by this StatefulSet has alias 127.0.0.1
+	hostnames := []string{
+		model.CreatePodHostname(host),
+	}
+	if hostname, ok := model.CreateHostDNSName(host); ok {
+		// Host recognizes itself in remote_servers by the name in custom DNS zone as well
+		hostnames = append(hostnames, hostname)
+	}
 	statefulSet.Spec.Template.Spec.HostAliases = []core.HostAlias{
 		{
-			IP: "127.0.0.1",
-			Hostnames: []string{
-				model.CreatePodHostname(host),
-			},
+			IP:        "127.0.0.1",
+			Hostnames: hostnames,
 		},
 	}
 
@@ -229,6 +289,7 @@ func (c *Creator) getPodTemplate(host *api.ChiHost) *api.PodTemplate {
 	// Now we can customize this Pod Template for particular host
 
 	model.PrepareAffinity(podTemplate, host)
+	model.ApplyDistribution(podTemplate, host)
 
 	return podTemplate
 }
@@ -237,6 +298,7 @@ func (c *Creator) getPodTemplate(host *api.ChiHost) *api.PodTemplate {
 func (c *Creator) statefulSetSetupVolumes(statefulSet *apps.StatefulSet, host *api.ChiHost) {
 	c.statefulSetSetupVolumesForConfigMaps(statefulSet, host)
 	c.statefulSetSetupVolumesForSecrets(statefulSet, host)
+	c.statefulSetSetupVolumesForTLS(statefulSet, host)
 }
 
 // statefulSetSetupVolumesForConfigMaps adds to each container in the Pod VolumeMount objects
@@ -246,11 +308,21 @@ func (c *Creator) statefulSetSetupVolumesForConfigMaps(statefulSet *apps.Statefu
 	configMapCommonUsersName := model.CreateConfigMapCommonUsersName(c.chi)
 
 	// Add all ConfigMap objects as Volume objects of type ConfigMap
+	newVolume := newVolumeForConfigMap
+	if c.chi.Spec.Defaults.IsConfigStoredInSecret() {
+		// Config files are delivered in Secrets having the same names as ConfigMaps would have
+		newVolume = newVolumeForSecret
+	}
+	newUsersVolume := newVolume
+	if c.chi.IsUsersStoredInSecret() {
+		// Users config files may be delivered in Secret on their own
+		newUsersVolume = newVolumeForSecret
+	}
 	k8s.StatefulSetAppendVolumes(
 		statefulSet,
-		newVolumeForConfigMap(configMapCommonName),
-		newVolumeForConfigMap(configMapCommonUsersName),
-		newVolumeForConfigMap(configMapHostName),
+		newVolume(configMapCommonName),
+		newUsersVolume(configMapCommonUsersName),
+		newVolume(configMapHostName),
 		//newVolumeForConfigMap(configMapHostMigrationName),
 	)
 
@@ -342,7 +414,7 @@ func (c *Creator) statefulSetApplyPodTemplate(
 				template.ObjectMeta.Labels,
 			)),
 			Annotations: model.Macro(host).Map(util.MergeStringMapsOverwrite(
-				c.annotations.GetHostScope(host),
+				c.annotations.GetPodTemplate(host),
 				template.ObjectMeta.Annotations,
 			)),
 		},
@@ -476,15 +548,21 @@ func newDefaultClickHouseContainer(host *api.ChiHost) core.Container {
 	container := core.Container{
 		Name:           model.ClickHouseContainerName,
 		Image:          model.DefaultClickHouseDockerImage,
-		LivenessProbe:  newDefaultClickHouseLivenessProbe(host),
-		ReadinessProbe: newDefaultClickHouseReadinessProbe(host),
+		LivenessProbe:  newDefaultLivenessProbe(host),
+		ReadinessProbe: newDefaultReadinessProbe(host),
+		StartupProbe:   newDefaultStartupProbe(host),
 	}
 	appendContainerPorts(&container, host)
 	return container
 }
 
-// newDefaultLogContainer returns default ClickHouse Log Container
+// newDefaultLogContainer returns default ClickHouse Log Container.
+// Container idles unless operator config opts-in to tail ClickHouse logs, since changed container restarts hosts
 func newDefaultLogContainer() core.Container {
+	script := "while true; do sleep 30; done;"
+	if chop.Config().Template.LogContainer.TailLogs.IsTrue() {
+		script = newLogTailerScript()
+	}
 	return core.Container{
 		Name:  model.ClickHouseLogContainerName,
 		Image: model.DefaultUbiDockerImage,
@@ -492,7 +570,25 @@ func newDefaultLogContainer() core.Container {
 			"/bin/sh", "-c", "--",
 		},
 		Args: []string{
-			"while true; do sleep 30; done;",
+			script,
 		},
 	}
 }
+
+// newLogTailerScript returns shell script which follows ClickHouse log files and multiplexes them to stdout.
+// Each line is prefixed with the name of the log it came from, so err and server logs can be told apart.
+// Files are followed by name, thus log rotation and files not created yet are handled by tail itself.
+func newLogTailerScript() string {
+	tail := func(prefix, file string) string {
+		return fmt.Sprintf(
+			`tail -n 0 -F %s 2>/dev/null | while IFS= read -r line; do echo "[%s] $line"; done &`,
+			path.Join(model.DirPathClickHouseLog, file),
+			prefix,
+		)
+	}
+	return strings.Join([]string{
+		tail("server", model.FileNameClickHouseServerLog),
+		tail("err", model.FileNameClickHouseServerErrLog),
+		"wait",
+	}, " ")
+}
@@ -0,0 +1,379 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/k8s"
+)
+
+// Validity of TLS certificates generated for clusters having 'secure: auto'
+const (
+	tlsCAValidity   = 10 * 365 * 24 * time.Hour
+	tlsHostValidity = 2 * 365 * 24 * time.Hour
+	// tlsRenewBefore specifies how long before expiration a certificate is re-issued
+	tlsRenewBefore = 30 * 24 * time.Hour
+	// tlsCAOverlap specifies how long the next CA is trusted by hosts before it starts to issue host certificates
+	tlsCAOverlap = 24 * time.Hour
+)
+
+// tlsCA is a certificate authority issuing certificates of the cluster hosts
+type tlsCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// CreateClusterTLSSecret creates Secret with TLS certificates of all hosts of the cluster.
+// CA and host certificates found in the current Secret are kept as long as they are valid,
+// so certificates are re-issued only for new hosts, renamed hosts and certificates about to expire.
+// CA about to expire is rotated in two steps, so hosts never get certificates issued by a CA not trusted by their peers:
+//  1. the next CA is created and is trusted along with the current one, host certificates are kept
+//  2. after tlsCAOverlap the next CA becomes the current one and re-issues host certificates,
+//     while the previous CA is still trusted till it expires
+func (c *Creator) CreateClusterTLSSecret(cluster *api.Cluster, cur *core.Secret) (*core.Secret, error) {
+	secret := &core.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: c.chi.Namespace,
+			Name:      model.CreateClusterTLSSecretName(cluster),
+		},
+		Data: make(map[string][]byte),
+		Type: core.SecretTypeOpaque,
+	}
+
+	var curData map[string][]byte
+	if cur != nil {
+		curData = cur.Data
+	}
+
+	bundle := parseTLSCertificates(curData[model.TLSSecretKeyCACertificate])
+	ca, err := parseTLSCA(firstTLSCertificate(curData[model.TLSSecretKeyCACertificate]), curData[model.TLSSecretKeyCAPrivateKey])
+	next, _ := parseTLSCA(curData[model.TLSSecretKeyNextCACertificate], curData[model.TLSSecretKeyNextCAPrivateKey])
+	switch {
+	case err != nil:
+		// There is no usable CA, start from scratch.
+		// Certificates issued by the previous CA are not trusted anymore
+		if ca, err = newTLSCA(c.chi.Name + "/" + cluster.Name); err != nil {
+			return nil, err
+		}
+		bundle = nil
+		next = nil
+		curData = nil
+	case (next != nil) && (time.Since(next.cert.NotBefore) > tlsCAOverlap):
+		// Next CA is trusted by hosts long enough to start issuing host certificates
+		ca, next = next, nil
+	case (next == nil) && isTLSCertificateExpiring(ca.cert):
+		// Hosts have to trust the next CA before it issues any host certificate
+		if next, err = newTLSCA(c.chi.Name + "/" + cluster.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	// CA issuing host certificates goes first, followed by the next CA and by previous CAs, which are not expired yet
+	trusted := []*x509.Certificate{ca.cert}
+	if next != nil {
+		trusted = append(trusted, next.cert)
+		secret.Data[model.TLSSecretKeyNextCACertificate] = next.certPEM
+		secret.Data[model.TLSSecretKeyNextCAPrivateKey] = next.keyPEM
+	}
+	for _, cert := range bundle {
+		if isTLSCertificateTrusted(trusted, cert) || time.Now().After(cert.NotAfter) {
+			continue
+		}
+		trusted = append(trusted, cert)
+	}
+	secret.Data[model.TLSSecretKeyCACertificate] = encodeTLSCertificates(trusted)
+	secret.Data[model.TLSSecretKeyCAPrivateKey] = ca.keyPEM
+
+	cluster.WalkHosts(func(host *api.ChiHost) error {
+		if err != nil {
+			return nil
+		}
+		certKey := model.CreateHostTLSSecretKeyCertificate(host)
+		keyKey := model.CreateHostTLSSecretKeyPrivateKey(host)
+		hostnames := model.CreateHostTLSHostnames(host)
+
+		certPEM, keyPEM := curData[certKey], curData[keyKey]
+		if !ca.verify(certPEM, keyPEM, hostnames) {
+			certPEM, keyPEM, err = ca.issue(hostnames)
+		}
+		secret.Data[certKey] = certPEM
+		secret.Data[keyKey] = keyPEM
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// statefulSetSetupVolumesForTLS mounts CA certificate and own TLS certificate of the host,
+// in case the cluster relies on auto-generated certificates
+func (c *Creator) statefulSetSetupVolumesForTLS(statefulSet *apps.StatefulSet, host *api.ChiHost) {
+	if !host.GetCluster().IsSecureAuto() {
+		return
+	}
+
+	name := model.CreateClusterTLSSecretName(host.GetCluster())
+	volume := newVolumeForSecret(name)
+	volume.Secret.Items = []core.KeyToPath{
+		{
+			Key:  model.TLSSecretKeyCACertificate,
+			Path: model.TLSFileCACertificate,
+		},
+		{
+			Key:  model.CreateHostTLSSecretKeyCertificate(host),
+			Path: model.TLSFileCertificate,
+		},
+		{
+			Key:  model.CreateHostTLSSecretKeyPrivateKey(host),
+			Path: model.TLSFilePrivateKey,
+		},
+	}
+
+	k8s.StatefulSetAppendVolumes(statefulSet, volume)
+	k8s.StatefulSetAppendVolumeMounts(statefulSet, newVolumeMount(name, model.DirPathTLS))
+}
+
+// newTLSCA creates new self-signed CA
+func newTLSCA(name string) (*tlsCA, error) {
+	now := time.Now()
+	return createTLSCA(name, now.Add(-time.Hour), now.Add(tlsCAValidity))
+}
+
+// createTLSCA creates self-signed CA valid within specified period
+func createTLSCA(name string, notBefore, notAfter time.Time) (*tlsCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate CA key: %v", err)
+	}
+	serial, err := newTLSSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"clickhouse-operator"},
+			CommonName:   name,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse CA certificate: %v", err)
+	}
+	keyPEM, err := encodeTLSPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsCA{
+		cert:    cert,
+		key:     key,
+		certPEM: encodeTLSCertificate(der),
+		keyPEM:  keyPEM,
+	}, nil
+}
+
+// parseTLSCA parses CA out of PEM-encoded certificate and private key.
+// Expired CA is rejected, CA about to expire is accepted in order to be rotated
+func parseTLSCA(certPEM, keyPEM []byte) (*tlsCA, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CA key type")
+	}
+	if !cert.IsCA || time.Now().After(cert.NotAfter) {
+		return nil, fmt.Errorf("CA certificate is not usable")
+	}
+	return &tlsCA{
+		cert:    cert,
+		key:     key,
+		certPEM: certPEM,
+		keyPEM:  keyPEM,
+	}, nil
+}
+
+// issue issues new certificate for specified hostnames, usable by both server and client sides of the connection
+func (ca *tlsCA) issue(hostnames []string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate key: %v", err)
+	}
+	serial, err := newTLSSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"clickhouse-operator"},
+			CommonName:   hostnames[0],
+		},
+		DNSNames:    hostnames,
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(tlsHostValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if template.NotAfter.After(ca.cert.NotAfter) {
+		template.NotAfter = ca.cert.NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create certificate: %v", err)
+	}
+	keyPEM, err = encodeTLSPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeTLSCertificate(der), keyPEM, nil
+}
+
+// verify checks whether certificate is issued by the CA for all specified hostnames, matches the private key
+// and is not about to expire
+func (ca *tlsCA) verify(certPEM, keyPEM []byte, hostnames []string) bool {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if cert.CheckSignatureFrom(ca.cert) != nil {
+		return false
+	}
+	if isTLSCertificateExpiring(cert) {
+		return false
+	}
+	for _, hostname := range hostnames {
+		if cert.VerifyHostname(hostname) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isTLSCertificateExpiring checks whether certificate is expired or is about to expire
+func isTLSCertificateExpiring(cert *x509.Certificate) bool {
+	return time.Now().Add(tlsRenewBefore).After(cert.NotAfter)
+}
+
+// newTLSSerialNumber creates random serial number of a certificate
+func newTLSSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate serial number: %v", err)
+	}
+	return serial, nil
+}
+
+// encodeTLSCertificate encodes DER certificate as PEM
+func encodeTLSCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// parseTLSCertificates parses all certificates of the PEM bundle, blocks failed to be parsed are skipped
+func parseTLSCertificates(bundlePEM []byte) (certs []*x509.Certificate) {
+	for {
+		var block *pem.Block
+		block, bundlePEM = pem.Decode(bundlePEM)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// firstTLSCertificate gets the first certificate of the PEM bundle, PEM-encoded
+func firstTLSCertificate(bundlePEM []byte) []byte {
+	certs := parseTLSCertificates(bundlePEM)
+	if len(certs) == 0 {
+		return nil
+	}
+	return encodeTLSCertificate(certs[0].Raw)
+}
+
+// isTLSCertificateTrusted checks whether certificate is among the trusted ones
+func isTLSCertificateTrusted(trusted []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, t := range trusted {
+		if t.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeTLSCertificates encodes certificates as PEM bundle
+func encodeTLSCertificates(certs []*x509.Certificate) []byte {
+	var bundle []byte
+	for _, cert := range certs {
+		bundle = append(bundle, encodeTLSCertificate(cert.Raw)...)
+	}
+	return bundle
+}
+
+// encodeTLSPrivateKey encodes private key as PKCS #8 PEM
+func encodeTLSPrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creator
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+)
+
+func newTLSTestCreator() (*Creator, *api.Cluster) {
+	chi := &api.ClickHouseInstallation{
+		ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "chi"},
+	}
+	cluster := &api.Cluster{
+		Name:   "cluster",
+		Layout: api.NewChiClusterLayout(),
+		Runtime: api.ClusterRuntime{
+			CHI: chi,
+		},
+	}
+	return &Creator{chi: chi}, cluster
+}
+
+func newTLSTestCA(t *testing.T, notBefore, notAfter time.Time) *tlsCA {
+	ca, err := createTLSCA("test", notBefore, notAfter)
+	require.NoError(t, err)
+	return ca
+}
+
+func requireTrustedCAs(t *testing.T, secret *core.Secret, expected ...*tlsCA) {
+	trusted := parseTLSCertificates(secret.Data[model.TLSSecretKeyCACertificate])
+	require.Len(t, trusted, len(expected))
+	for i := range expected {
+		require.True(t, trusted[i].Equal(expected[i].cert), "CA %d", i)
+	}
+}
+
+func Test_CreateClusterTLSSecret_RotateCA(t *testing.T) {
+	c, cluster := newTLSTestCreator()
+	now := time.Now()
+
+	// CA about to expire gets the next CA trusted along with it, while it keeps issuing certificates
+	expiring := newTLSTestCA(t, now.Add(-tlsCAValidity), now.Add(tlsRenewBefore/2))
+	secret, err := c.CreateClusterTLSSecret(cluster, &core.Secret{
+		Data: map[string][]byte{
+			model.TLSSecretKeyCACertificate: expiring.certPEM,
+			model.TLSSecretKeyCAPrivateKey:  expiring.keyPEM,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, expiring.keyPEM, secret.Data[model.TLSSecretKeyCAPrivateKey])
+	next, err := parseTLSCA(secret.Data[model.TLSSecretKeyNextCACertificate], secret.Data[model.TLSSecretKeyNextCAPrivateKey])
+	require.NoError(t, err)
+	requireTrustedCAs(t, secret, expiring, next)
+
+	// Next CA is not promoted till it is trusted long enough
+	again, err := c.CreateClusterTLSSecret(cluster, secret)
+	require.NoError(t, err)
+	require.Equal(t, secret.Data, again.Data)
+
+	// Next CA trusted long enough becomes the current one, while the previous CA is still trusted
+	next = newTLSTestCA(t, now.Add(-2*tlsCAOverlap), now.Add(tlsCAValidity))
+	secret.Data[model.TLSSecretKeyCACertificate] = encodeTLSCertificates([]*x509.Certificate{expiring.cert, next.cert})
+	secret.Data[model.TLSSecretKeyNextCACertificate] = next.certPEM
+	secret.Data[model.TLSSecretKeyNextCAPrivateKey] = next.keyPEM
+	promoted, err := c.CreateClusterTLSSecret(cluster, secret)
+	require.NoError(t, err)
+	require.Equal(t, next.keyPEM, promoted.Data[model.TLSSecretKeyCAPrivateKey])
+	require.NotContains(t, promoted.Data, model.TLSSecretKeyNextCACertificate)
+	requireTrustedCAs(t, promoted, next, expiring)
+}
+
+func Test_CreateClusterTLSSecret_DropExpiredCA(t *testing.T) {
+	c, cluster := newTLSTestCreator()
+	now := time.Now()
+
+	ca := newTLSTestCA(t, now.Add(-time.Hour), now.Add(tlsCAValidity))
+	expired := newTLSTestCA(t, now.Add(-tlsCAValidity), now.Add(-time.Hour))
+	secret, err := c.CreateClusterTLSSecret(cluster, &core.Secret{
+		Data: map[string][]byte{
+			model.TLSSecretKeyCACertificate: encodeTLSCertificates([]*x509.Certificate{ca.cert, expired.cert}),
+			model.TLSSecretKeyCAPrivateKey:  ca.keyPEM,
+		},
+	})
+	require.NoError(t, err)
+	requireTrustedCAs(t, secret, ca)
+}
@@ -49,6 +49,20 @@ func newVolumeForConfigMap(name string) core.Volume {
 	}
 }
 
+// newVolumeForSecret returns core.Volume object with defined name, which is backed by the Secret of the same name
+func newVolumeForSecret(name string) core.Volume {
+	var defaultMode int32 = 0644
+	return core.Volume{
+		Name: name,
+		VolumeSource: core.VolumeSource{
+			Secret: &core.SecretVolumeSource{
+				SecretName:  name,
+				DefaultMode: &defaultMode,
+			},
+		},
+	}
+}
+
 // newVolumeMount returns core.VolumeMount object with name and mount path
 func newVolumeMount(name, mountPath string) core.VolumeMount {
 	return core.VolumeMount{
@@ -0,0 +1,128 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"fmt"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// GetPodRequests gets resources requested by the pod as the scheduler counts them:
+// the sum of requests of containers, or the max of requests of each init container, whichever is bigger
+func GetPodRequests(spec *core.PodSpec) core.ResourceList {
+	requests := make(core.ResourceList)
+	for i := range spec.Containers {
+		for name, quantity := range spec.Containers[i].Resources.Requests {
+			sum := requests[name]
+			sum.Add(quantity)
+			requests[name] = sum
+		}
+	}
+	for i := range spec.InitContainers {
+		for name, quantity := range spec.InitContainers[i].Resources.Requests {
+			if current, ok := requests[name]; !ok || (quantity.Cmp(current) > 0) {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	for name, quantity := range spec.Overhead {
+		sum := requests[name]
+		sum.Add(quantity)
+		requests[name] = sum
+	}
+	return requests
+}
+
+// footprint accumulates resources requested by a set of hosts
+type footprint struct {
+	cpu     resource.Quantity
+	memory  resource.Quantity
+	storage resource.Quantity
+}
+
+// add adds resources requested by the host.
+// CPU and memory are requested by running hosts only, while storage is kept by stopped hosts as well
+func (f *footprint) add(host *api.ChiHost) {
+	if template, ok := host.GetPodTemplate(); ok && !host.IsStopped() {
+		requests := GetPodRequests(&template.Spec)
+		f.cpu.Add(requests[core.ResourceCPU])
+		f.memory.Add(requests[core.ResourceMemory])
+	}
+	for _, template := range getHostVolumeClaimTemplates(host) {
+		f.storage.Add(template.Spec.Resources.Requests[core.ResourceStorage])
+	}
+}
+
+// getHostVolumeClaimTemplates gets VolumeClaimTemplates used by the host, each template is listed once
+func getHostVolumeClaimTemplates(host *api.ChiHost) []*api.VolumeClaimTemplate {
+	var names []string
+	names = append(names, host.Templates.GetDataVolumeClaimTemplate(), host.Templates.GetLogVolumeClaimTemplate())
+	if template, ok := host.GetPodTemplate(); ok {
+		for i := range template.Spec.Containers {
+			for j := range template.Spec.Containers[i].VolumeMounts {
+				names = append(names, template.Spec.Containers[i].VolumeMounts[j].Name)
+			}
+		}
+	}
+
+	var templates []*api.VolumeClaimTemplate
+	used := make(map[string]bool)
+	for _, name := range names {
+		if (name == "") || used[name] {
+			continue
+		}
+		used[name] = true
+		// Volume mount may refer to a volume which is not created from VolumeClaimTemplate, such as a ConfigMap
+		if template, ok := host.GetCHI().GetVolumeClaimTemplate(name); ok {
+			templates = append(templates, template)
+		}
+	}
+	return templates
+}
+
+// CreateFootprint creates summary of resources requested by pods and volumes of the CHI, total and per cluster
+func CreateFootprint(chi *api.ClickHouseInstallation) *api.ChiFootprint {
+	var total footprint
+	var clusters []api.ChiClusterFootprint
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		var f footprint
+		cluster.WalkHosts(func(host *api.ChiHost) error {
+			f.add(host)
+			return nil
+		})
+		total.cpu.Add(f.cpu)
+		total.memory.Add(f.memory)
+		total.storage.Add(f.storage)
+		clusters = append(clusters, api.ChiClusterFootprint{
+			Name:    cluster.Name,
+			CPU:     f.cpu.String(),
+			Memory:  f.memory.String(),
+			Storage: f.storage.String(),
+		})
+		return nil
+	})
+
+	return &api.ChiFootprint{
+		Summary:  fmt.Sprintf("cpu=%s memory=%s storage=%s", total.cpu.String(), total.memory.String(), total.storage.String()),
+		CPU:      total.cpu.String(),
+		Memory:   total.memory.String(),
+		Storage:  total.storage.String(),
+		Clusters: clusters,
+	}
+}
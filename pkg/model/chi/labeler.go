@@ -53,7 +53,14 @@ const (
 	labelServiceValueCluster          = "cluster"
 	labelServiceValueShard            = "shard"
 	labelServiceValueHost             = "host"
+	labelServiceValueCHIReadWrite     = "chi-rw"
+	labelServiceValueCHIReadOnly      = "chi-ro"
 	LabelPVCReclaimPolicyName         = clickhouse_altinity_com.APIGroupName + "/" + "reclaimPolicy"
+	LabelReplicaRole                  = clickhouse_altinity_com.APIGroupName + "/" + "replica-role"
+	LabelReplicaRoleValueReadWrite    = "rw"
+	LabelReplicaRoleValueReadOnly     = "ro"
+	LabelBackupCandidate              = clickhouse_altinity_com.APIGroupName + "/" + "backup-candidate"
+	LabelBackupCandidateValue         = "true"
 
 	// Supplementary service labels - used to cooperate with k8s
 
@@ -123,6 +130,19 @@ func (l *Labeler) GetServiceCHI(chi *api.ClickHouseInstallation) map[string]stri
 		})
 }
 
+// GetServiceCHIReplicaRole
+func (l *Labeler) GetServiceCHIReplicaRole(chi *api.ClickHouseInstallation, role string) map[string]string {
+	value := labelServiceValueCHIReadOnly
+	if role == LabelReplicaRoleValueReadWrite {
+		value = labelServiceValueCHIReadWrite
+	}
+	return util.MergeStringMapsOverwrite(
+		l.getCHIScope(),
+		map[string]string{
+			LabelService: value,
+		})
+}
+
 // GetServiceCluster
 func (l *Labeler) GetServiceCluster(cluster *api.Cluster) map[string]string {
 	return util.MergeStringMapsOverwrite(
@@ -135,7 +155,7 @@ func (l *Labeler) GetServiceCluster(cluster *api.Cluster) map[string]string {
 // GetServiceShard
 func (l *Labeler) GetServiceShard(shard *api.ChiShard) map[string]string {
 	return util.MergeStringMapsOverwrite(
-		l.getShardScope(shard),
+		l.GetShardScope(shard),
 		map[string]string{
 			LabelService: labelServiceValueShard,
 		})
@@ -173,6 +193,15 @@ func (l *Labeler) GetSelectorCHIScopeReady() map[string]string {
 	return appendKeyReady(l.GetSelectorCHIScope())
 }
 
+// GetSelectorCHIReplicaRoleReady gets labels to select ready-labelled CHI hosts having specified replica role
+func (l *Labeler) GetSelectorCHIReplicaRoleReady(role string) map[string]string {
+	return util.MergeStringMapsOverwrite(
+		l.GetSelectorCHIScopeReady(),
+		map[string]string{
+			LabelReplicaRole: role,
+		})
+}
+
 // GetClusterScope gets labels for Cluster-scoped object
 func (l *Labeler) GetClusterScope(cluster *api.Cluster) map[string]string {
 	// Combine generated labels and CHI-provided labels
@@ -195,14 +224,14 @@ func GetSelectorClusterScopeReady(cluster *api.Cluster) map[string]string {
 	return appendKeyReady(GetSelectorClusterScope(cluster))
 }
 
-// getShardScope gets labels for Shard-scoped object
-func (l *Labeler) getShardScope(shard *api.ChiShard) map[string]string {
+// GetShardScope gets labels for Shard-scoped object
+func (l *Labeler) GetShardScope(shard *api.ChiShard) map[string]string {
 	// Combine generated labels and CHI-provided labels
-	return l.filterOutPredefined(l.appendCHIProvidedTo(getSelectorShardScope(shard)))
+	return l.filterOutPredefined(l.appendCHIProvidedTo(GetSelectorShardScope(shard)))
 }
 
-// getSelectorShardScope gets labels to select a Shard-scoped object
-func getSelectorShardScope(shard *api.ChiShard) map[string]string {
+// GetSelectorShardScope gets labels to select a Shard-scoped object
+func GetSelectorShardScope(shard *api.ChiShard) map[string]string {
 	// Do not include CHI-provided labels
 	return map[string]string{
 		LabelNamespace:   labelsNamer.getNamePartNamespace(shard),
@@ -215,7 +244,7 @@ func getSelectorShardScope(shard *api.ChiShard) map[string]string {
 
 // GetSelectorShardScopeReady gets labels to select a ready-labelled Shard-scoped object
 func GetSelectorShardScopeReady(shard *api.ChiShard) map[string]string {
-	return appendKeyReady(getSelectorShardScope(shard))
+	return appendKeyReady(GetSelectorShardScope(shard))
 }
 
 // GetHostScope gets labels for Host-scoped object
@@ -244,6 +273,14 @@ func (l *Labeler) GetHostScope(host *api.ChiHost, applySupplementaryServiceLabel
 	return l.filterOutPredefined(l.appendCHIProvidedTo(labels))
 }
 
+// GetReplicaRole gets replica role of the host - read-write for the first replica of a shard, read-only otherwise
+func GetReplicaRole(host *api.ChiHost) string {
+	if host.Runtime.Address.ReplicaIndex == 0 {
+		return LabelReplicaRoleValueReadWrite
+	}
+	return LabelReplicaRoleValueReadOnly
+}
+
 func appendConfigLabels(host *api.ChiHost, labels map[string]string) map[string]string {
 	if host.HasCurStatefulSet() {
 		if val, exists := host.Runtime.CurStatefulSet.Labels[LabelZookeeperConfigVersion]; exists {
@@ -324,10 +361,18 @@ func (l *Labeler) filterOutPredefined(m map[string]string) map[string]string {
 
 // appendCHIProvidedTo appends CHI-provided labels to labels set
 func (l *Labeler) appendCHIProvidedTo(dst map[string]string) map[string]string {
-	sourceLabels := util.CopyMapFilter(l.chi.Labels, chop.Config().Label.Include, chop.Config().Label.Exclude)
+	sourceLabels := GetCHIProvidedLabels(l.chi)
 	return util.MergeStringMapsOverwrite(dst, sourceLabels)
 }
 
+// GetCHIProvidedLabels gets labels of the CHI, which are propagated to child objects of the CHI
+func GetCHIProvidedLabels(chi *api.ClickHouseInstallation) map[string]string {
+	if chi == nil {
+		return nil
+	}
+	return util.CopyMapFilter(chi.Labels, chop.Config().Label.Include, chop.Config().Label.Exclude)
+}
+
 // makeSetFromObjectMeta makes k8sLabels.Set from ObjectMeta
 func makeSetFromObjectMeta(objMeta *meta.ObjectMeta) (k8sLabels.Set, error) {
 	// Check mandatory labels are in place
@@ -490,6 +535,25 @@ func AppendLabelReady(meta *meta.ObjectMeta) bool {
 	return true
 }
 
+// AppendLabelReplicaRole labels pod of the host with replica role of the host, in case read-write and read-only
+// Services are enabled. Label is put on pods directly, same as "Ready" label, and is not a part of pod template,
+// so enabling the Services does not roll pods.
+// Returns true in case label was added or changed.
+func AppendLabelReplicaRole(meta *meta.ObjectMeta, host *api.ChiHost) bool {
+	if (meta == nil) || !chop.Config().IsFeatureEnabled(api.FeatureGateReadWriteServices) {
+		return false
+	}
+	// First replica of each shard serves writes, the rest of replicas serve reads
+	role := GetReplicaRole(host)
+	if meta.Labels[LabelReplicaRole] == role {
+		return false
+	}
+	meta.Labels = util.MergeStringMapsOverwrite(meta.Labels, map[string]string{
+		LabelReplicaRole: role,
+	})
+	return true
+}
+
 // DeleteLabelReady deletes "Ready" label from ObjectMeta.Labels
 // Returns true in case label was in place and was deleted.
 func DeleteLabelReady(meta *meta.ObjectMeta) bool {
@@ -537,3 +601,53 @@ func DeleteAnnotationReady(meta *meta.ObjectMeta) bool {
 	// Not available, not deleted
 	return false
 }
+
+// HasLabelReady checks whether ObjectMeta.Labels has "Ready" label in Ready state
+func HasLabelReady(meta *meta.ObjectMeta) bool {
+	if meta == nil {
+		return false
+	}
+	return hasKeyReady(meta.Labels)
+}
+
+// HasLabelBackupCandidate checks whether ObjectMeta.Labels has "BackupCandidate" label
+func HasLabelBackupCandidate(meta *meta.ObjectMeta) bool {
+	if meta == nil {
+		return false
+	}
+	return meta.Labels[LabelBackupCandidate] == LabelBackupCandidateValue
+}
+
+// AppendLabelBackupCandidate appends "BackupCandidate" label to ObjectMeta.Labels.
+// Returns true in case label was not in place and was added.
+func AppendLabelBackupCandidate(meta *meta.ObjectMeta) bool {
+	if meta == nil {
+		// Nowhere to add to, not added
+		return false
+	}
+	if HasLabelBackupCandidate(meta) {
+		// Already in place, not added
+		return false
+	}
+	// Need to add
+	meta.Labels = util.MergeStringMapsOverwrite(meta.Labels, map[string]string{
+		LabelBackupCandidate: LabelBackupCandidateValue,
+	})
+	return true
+}
+
+// DeleteLabelBackupCandidate deletes "BackupCandidate" label from ObjectMeta.Labels
+// Returns true in case label was in place and was deleted.
+func DeleteLabelBackupCandidate(meta *meta.ObjectMeta) bool {
+	if meta == nil {
+		// Nowhere to delete from, not deleted
+		return false
+	}
+	if _, ok := meta.Labels[LabelBackupCandidate]; ok {
+		// In place, need to delete
+		delete(meta.Labels, LabelBackupCandidate)
+		return true
+	}
+	// Not available, not deleted
+	return false
+}
@@ -16,6 +16,7 @@ package chi
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -40,6 +41,9 @@ const (
 	namePartReplicaMaxLenLabelsCtx = 63
 )
 
+// OperatorServiceMonitorName specifies name of the Prometheus Operator ServiceMonitor of the operator's metrics Service
+const OperatorServiceMonitorName = "clickhouse-operator-metrics"
+
 const (
 	// chiServiceNamePattern is a template of CHI Service name. "clickhouse-{chi}"
 	chiServiceNamePattern = "clickhouse-" + macrosChiName
@@ -65,6 +69,9 @@ const (
 	// configMapCommonUsersNamePattern is a template of common users settings for the CHI ConfigMap. "chi-{chi}-common-usersd"
 	configMapCommonUsersNamePattern = "chi-" + macrosChiName + "-common-usersd"
 
+	// configMapImportSchemaNamePattern is a template of ConfigMap with schema of the imported CHI. "chi-{chi}-import-schema"
+	configMapImportSchemaNamePattern = "chi-" + macrosChiName + "-import-schema"
+
 	// configMapHostNamePattern is a template of macros ConfigMap. "chi-{chi}-deploy-confd-{cluster}-{shard}-{host}"
 	configMapHostNamePattern = "chi-" + macrosChiName + "-deploy-confd-" + macrosClusterName + "-" + macrosHostName
 
@@ -349,6 +356,11 @@ func CreateConfigMapCommonName(chi *api.ClickHouseInstallation) string {
 	return Macro(chi).Line(configMapCommonNamePattern)
 }
 
+// CreateConfigMapImportSchemaName returns a name for a ConfigMap with schema of the imported CHI
+func CreateConfigMapImportSchemaName(chi *api.ClickHouseInstallation) string {
+	return Macro(chi).Line(configMapImportSchemaNamePattern)
+}
+
 // CreateConfigMapCommonUsersName returns a name for a ConfigMap for replica's common users config
 func CreateConfigMapCommonUsersName(chi *api.ClickHouseInstallation) string {
 	return Macro(chi).Line(configMapCommonUsersNamePattern)
@@ -375,6 +387,23 @@ func CreateCHIServiceName(chi *api.ClickHouseInstallation) string {
 	return Macro(chi).Line(pattern)
 }
 
+// CreateCHIServiceReplicaRoleName creates a name of a ClickHouseInstallation Service resource
+// which selects hosts having specified replica role
+func CreateCHIServiceReplicaRoleName(chi *api.ClickHouseInstallation, role string) string {
+	return CreateCHIServiceName(chi) + "-" + role
+}
+
+// CreateCHIServiceGatewayName creates a name of a ClickHouseInstallation Service resource
+// which is used as a backend of Gateway API routes
+func CreateCHIServiceGatewayName(chi *api.ClickHouseInstallation) string {
+	return CreateCHIServiceName(chi) + "-native"
+}
+
+// CreateCHIGatewayRouteName creates a name of a Gateway API route of specified kind
+func CreateCHIGatewayRouteName(chi *api.ClickHouseInstallation, kind string) string {
+	return CreateCHIServiceGatewayName(chi) + "-" + strings.ToLower(kind)
+}
+
 // CreateCHIServiceFQDN creates a FQD name of a root ClickHouseInstallation Service resource
 func CreateCHIServiceFQDN(chi *api.ClickHouseInstallation) string {
 	// FQDN can be generated either from default pattern,
@@ -417,6 +446,17 @@ func CreateClusterServiceName(cluster *api.Cluster) string {
 	return Macro(cluster).Line(pattern)
 }
 
+// CreateClusterExtraServiceName returns a name of an extra Service of a cluster, created out of specified ServiceTemplate
+func CreateClusterExtraServiceName(cluster *api.Cluster, template *api.ServiceTemplate) string {
+	// ServiceTemplate may have personal name pattern specified,
+	// otherwise default name pattern is suffixed with name of the template
+	pattern := clusterServiceNamePattern + "-" + template.Name
+	if template.GenerateName != "" {
+		pattern = template.GenerateName
+	}
+	return Macro(cluster).Line(pattern)
+}
+
 // CreateShardServiceName returns a name of a shard's Service
 func CreateShardServiceName(shard *api.ChiShard) string {
 	// Name can be generated either from default name pattern,
@@ -471,13 +511,17 @@ func CreateHostTemplateName(host *api.ChiHost) string {
 	return "HostTemplate" + host.Name
 }
 
-// CreateInstanceHostname returns hostname (pod-hostname + service or FQDN) which can be used as a replica name
-// in all places where ClickHouse requires replica name. These are such places as:
-// 1. "remote_servers.xml" config file
-// 2. statements like SYSTEM DROP REPLICA <replica_name>
-// any other places
-// Function operations are based on .Spec.Defaults.ReplicasUseFQDN
+// CreateInstanceHostname returns hostname (pod-hostname + service or FQDN) which can be used to address the host
+// in all places where ClickHouse requires host address, such as "remote_servers.xml" config file.
+// Replica name registered in keeper is the value of {replica} macro, which is CreatePodHostname,
+// so statements like SYSTEM DROP REPLICA <replica_name> use CreatePodHostname instead.
+// Function operations are based on custom DNS zone of the cluster and .Spec.Defaults.ReplicasUseFQDN
 func CreateInstanceHostname(host *api.ChiHost) string {
+	if hostname, ok := CreateHostDNSName(host); ok {
+		// Custom DNS zone of the cluster takes precedence, hosts are addressed by names in the zone
+		return hostname
+	}
+
 	if host.GetCHI().Spec.Defaults.ReplicasUseFQDN.IsTrue() {
 		// In case .Spec.Defaults.ReplicasUseFQDN is set replicas would use FQDN pod hostname,
 		// otherwise hostname+service name (unique within namespace) would be used
@@ -488,6 +532,31 @@ func CreateInstanceHostname(host *api.ChiHost) string {
 	return CreatePodHostname(host)
 }
 
+// CreateInterserverHTTPHost returns host name or IP address the host is addressed by other replicas fetching parts from it.
+// IPv6 address is provided w/o square brackets, ClickHouse brackets it on its own when building URL.
+// Not applicable to PodIP, which is known within the pod only
+func CreateInterserverHTTPHost(host *api.ChiHost) string {
+	switch value := host.GetInterserverHTTPHost(); value {
+	case api.InterserverHTTPHostHostname:
+		return CreateInstanceHostname(host)
+	case api.InterserverHTTPHostFQDN:
+		return createPodFQDN(host)
+	default:
+		return normalizeHostAddress(value)
+	}
+}
+
+// normalizeHostAddress unwraps IPv6 address out of square brackets and brings IP address into canonical form.
+// Host name is returned as is
+func normalizeHostAddress(address string) string {
+	address = strings.TrimSpace(address)
+	unwrapped := strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+	if ip := net.ParseIP(unwrapped); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
 // IsAutoGeneratedHostName checks whether name is auto-generated
 func IsAutoGeneratedHostName(
 	name string,
@@ -557,6 +626,17 @@ func CreateStatefulSetServiceName(host *api.ChiHost) string {
 	return Macro(host).Line(pattern)
 }
 
+// CreateStatefulSetExtraServiceName returns a name of an extra Service of a host, created out of specified ServiceTemplate
+func CreateStatefulSetExtraServiceName(host *api.ChiHost, template *api.ServiceTemplate) string {
+	// ServiceTemplate may have personal name pattern specified,
+	// otherwise default name pattern is suffixed with name of the template
+	pattern := statefulSetServiceNamePattern + "-" + template.Name
+	if template.GenerateName != "" {
+		pattern = template.GenerateName
+	}
+	return Macro(host).Line(pattern)
+}
+
 // CreatePodHostname returns a hostname of a Pod of a ClickHouse instance.
 // Is supposed to be used where network connection to a Pod is required.
 // NB: right now Pod's hostname points to a Service, through which Pod can be accessed.
@@ -587,6 +667,22 @@ func createPodFQDN(host *api.ChiHost) string {
 	)
 }
 
+// CreateHostDNSName creates name of the host in custom DNS zone of the cluster, if specified
+// chi-1eb454-2-0.replicas.example.com
+func CreateHostDNSName(host *api.ChiHost) (string, bool) {
+	dns := host.GetCluster().GetDNS()
+	if !dns.HasZone() {
+		return "", false
+	}
+	return CreatePodHostname(host) + "." + dns.GetZone(), true
+}
+
+// CreatePodOwnFQDN creates a fully qualified domain name of a pod itself, resolved by pod's StatefulSet headless service
+// chi-1eb454-2-0-0.chi-1eb454-2-0.my-dev-domain.svc.cluster.local
+func CreatePodOwnFQDN(host *api.ChiHost) string {
+	return CreatePodName(host) + "." + createPodFQDN(host)
+}
+
 // createPodFQDNsOfCluster creates fully qualified domain names of all pods in a cluster
 func createPodFQDNsOfCluster(cluster *api.Cluster) (fqdns []string) {
 	cluster.WalkHosts(func(host *api.ChiHost) error {
@@ -753,3 +849,35 @@ func CreateClusterAutoSecretName(cluster *api.Cluster) string {
 		cluster.Name,
 	)
 }
+
+// CreateClusterSecretSlotsName creates Secret name where slots of the cluster secret passed to pods are kept
+func CreateClusterSecretSlotsName(cluster *api.Cluster) string {
+	if cluster.Name == "" {
+		return fmt.Sprintf(
+			"%s-secret-slots",
+			cluster.Runtime.CHI.Name,
+		)
+	}
+
+	return fmt.Sprintf(
+		"%s-%s-secret-slots",
+		cluster.Runtime.CHI.Name,
+		cluster.Name,
+	)
+}
+
+// CreateClusterTLSSecretName creates Secret name where auto-generated TLS certificates of the cluster are kept
+func CreateClusterTLSSecretName(cluster *api.Cluster) string {
+	if cluster.Name == "" {
+		return fmt.Sprintf(
+			"%s-tls",
+			cluster.Runtime.CHI.Name,
+		)
+	}
+
+	return fmt.Sprintf(
+		"%s-%s-tls",
+		cluster.Runtime.CHI.Name,
+		cluster.Name,
+	)
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entities
+
+import (
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// profilePreset specifies set of settings tuned for hosts of a particular size
+type profilePreset struct {
+	// settings specifies server settings, goes into .spec.configuration.settings
+	settings map[string]string
+	// profiles specifies settings of the profiles, goes into .spec.configuration.profiles
+	profiles map[string]string
+}
+
+// profilePresets specifies operator-maintained settings presets
+var profilePresets = map[string]profilePreset{
+	api.ProfilePresetSmall: {
+		settings: map[string]string{
+			"mark_cache_size":                           "268435456",
+			"uncompressed_cache_size":                   "134217728",
+			"max_concurrent_queries":                    "50",
+			"background_pool_size":                      "4",
+			"background_schedule_pool_size":             "16",
+			"background_fetches_pool_size":              "4",
+			"background_common_pool_size":               "4",
+			"background_move_pool_size":                 "2",
+			"background_distributed_schedule_pool_size": "4",
+		},
+		profiles: map[string]string{
+			"default/max_threads":      "2",
+			"default/max_memory_usage": "2147483648",
+		},
+	},
+	api.ProfilePresetMedium: {
+		settings: map[string]string{
+			"mark_cache_size":                           "2147483648",
+			"uncompressed_cache_size":                   "1073741824",
+			"max_concurrent_queries":                    "100",
+			"background_pool_size":                      "8",
+			"background_schedule_pool_size":             "64",
+			"background_fetches_pool_size":              "8",
+			"background_common_pool_size":               "8",
+			"background_move_pool_size":                 "4",
+			"background_distributed_schedule_pool_size": "8",
+		},
+		profiles: map[string]string{
+			"default/max_threads":      "8",
+			"default/max_memory_usage": "10737418240",
+		},
+	},
+	api.ProfilePresetLarge: {
+		settings: map[string]string{
+			"mark_cache_size":                           "5368709120",
+			"uncompressed_cache_size":                   "4294967296",
+			"max_concurrent_queries":                    "200",
+			"background_pool_size":                      "16",
+			"background_schedule_pool_size":             "128",
+			"background_fetches_pool_size":              "16",
+			"background_common_pool_size":               "16",
+			"background_move_pool_size":                 "8",
+			"background_distributed_schedule_pool_size": "16",
+		},
+		profiles: map[string]string{
+			"default/max_threads":      "16",
+			"default/max_memory_usage": "32212254720",
+		},
+	},
+}
+
+// IsValidProfilePreset checks whether settings preset is known
+func IsValidProfilePreset(preset string) bool {
+	_, ok := profilePresets[preset]
+	return ok
+}
+
+// ApplyProfilePreset fills configuration with settings of the preset.
+// Settings explicitly specified in the configuration are not overwritten.
+func ApplyProfilePreset(preset string, conf *api.Configuration) {
+	p, ok := profilePresets[preset]
+	if !ok || (conf == nil) {
+		return
+	}
+
+	if conf.Settings == nil {
+		conf.Settings = api.NewSettings()
+	}
+	for name, value := range p.settings {
+		conf.Settings.SetIfNotExists(name, api.NewSettingScalar(value))
+	}
+
+	if conf.Profiles == nil {
+		conf.Profiles = api.NewSettings()
+	}
+	for name, value := range p.profiles {
+		conf.Profiles.SetIfNotExists(name, api.NewSettingScalar(value))
+	}
+}
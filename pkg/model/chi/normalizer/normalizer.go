@@ -19,12 +19,14 @@ import (
 	"encoding/hex"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 
 	"github.com/google/uuid"
 
 	core "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
@@ -120,11 +122,123 @@ func (n *Normalizer) normalize() (*api.ClickHouseInstallation, error) {
 	// UseTemplates already done
 
 	n.finalizeCHI()
+	secretsErr := n.fillHostsSecretsChecksums()
 	n.fillStatus()
 
+	if secretsErr != nil {
+		return n.ctx.GetTarget(), secretsErr
+	}
+	if err := n.checkScaleInProtection(); err != nil {
+		return n.ctx.GetTarget(), err
+	}
+	if err := n.validatePodTemplates(); err != nil {
+		return n.ctx.GetTarget(), err
+	}
+	if err := n.validateHostPorts(); err != nil {
+		return n.ctx.GetTarget(), err
+	}
+	if err := n.validateInstancesPerPod(); err != nil {
+		return n.ctx.GetTarget(), err
+	}
+
 	return n.ctx.GetTarget(), nil
 }
 
+// ErrInvalidPodTemplate specifies error returned in case podTemplate would make pods fail
+var ErrInvalidPodTemplate = fmt.Errorf("invalid podTemplate")
+
+// validatePodTemplates checks podTemplates for known mistakes.
+// Warnings are logged, errors are reported as ErrInvalidPodTemplate
+func (n *Normalizer) validatePodTemplates() error {
+	var volumeClaimTemplates []string
+	for _, template := range n.ctx.GetTarget().Spec.Templates.GetVolumeClaimTemplates() {
+		volumeClaimTemplates = append(volumeClaimTemplates, template.Name)
+	}
+
+	var problems []string
+	podTemplates := n.ctx.GetTarget().Spec.Templates.GetPodTemplates()
+	for i := range podTemplates {
+		template := &podTemplates[i]
+		warnings, errs := templatesNormalizer.ValidatePodTemplate(template, volumeClaimTemplates)
+		for _, warning := range warnings {
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("%s", warning)
+		}
+		for _, err := range errs {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidPodTemplate, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// ErrInvalidLayout specifies error returned in case cluster layout can not be run by the operator
+var ErrInvalidLayout = fmt.Errorf("invalid layout")
+
+// validateInstancesPerPod checks clusters running multiple instances per pod are not replicated.
+// Operator runs its own SQL, such as schema migration, replica drop and version checks, against instance 0 only,
+// so replicas served by other instances would be left w/o schema and stale replicas would stay in keeper
+func (n *Normalizer) validateInstancesPerPod() error {
+	var problems []string
+	n.ctx.GetTarget().WalkClusters(func(cluster *api.Cluster) error {
+		if !cluster.GetLayout().HasMultipleInstancesPerPod() {
+			return nil
+		}
+		cluster.WalkShards(func(index int, shard *api.ChiShard) error {
+			if len(shard.Hosts) > 1 {
+				problems = append(problems, fmt.Sprintf("cluster %s runs %d instances per pod and has replicated shard %s",
+					cluster.Name, cluster.GetLayout().GetInstancesPerPod(), shard.Name))
+			}
+			return nil
+		})
+		return nil
+	})
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidLayout, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ErrPortConflict specifies error returned in case ports of hosts overlap, so pods would fail to listen
+var ErrPortConflict = fmt.Errorf("port conflict")
+
+// validateHostPorts checks ports of hosts for conflicts, which are reported as ErrPortConflict
+func (n *Normalizer) validateHostPorts() error {
+	if conflicts := creator.ValidateHostPorts(n.ctx.GetTarget()); len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrPortConflict, strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// ErrScaleInProtection specifies error returned in case layout deletes scale-in protected hosts
+var ErrScaleInProtection = fmt.Errorf("scale-in protected hosts would be deleted")
+
+// checkScaleInProtection ensures normalized CHI does not delete any of scale-in protected hosts
+func (n *Normalizer) checkScaleInProtection() error {
+	if len(n.ctx.Options().ScaleInProtectedHosts) == 0 {
+		return nil
+	}
+
+	podNames := model.CreatePodNames(n.ctx.GetTarget())
+	var deleted []string
+	for _, protected := range n.ctx.Options().ScaleInProtectedHosts {
+		if !util.InArray(protected, podNames) {
+			deleted = append(deleted, protected)
+		}
+	}
+
+	if len(deleted) > 0 {
+		sort.Strings(deleted)
+		return fmt.Errorf("%w: %s. Remove '%s' annotation from the pods in order to delete them",
+			ErrScaleInProtection, strings.Join(deleted, ", "), model.AnnotationScaleInProtection)
+	}
+
+	return nil
+}
+
 // finalizeCHI performs some finalization tasks, which should be done after CHI is normalized
 func (n *Normalizer) finalizeCHI() {
 	n.ctx.GetTarget().FillSelfCalculatedAddressInfo()
@@ -132,6 +246,7 @@ func (n *Normalizer) finalizeCHI() {
 	n.ctx.GetTarget().WalkHosts(func(host *api.ChiHost) error {
 		hostTemplate := n.getHostTemplate(host)
 		hostApplyHostTemplate(host, hostTemplate)
+		host.Settings = n.applyHostInterserverCredentials(host)
 		return nil
 	})
 	n.fillCHIAddressInfo()
@@ -185,6 +300,12 @@ func hostApplyHostTemplate(host *api.ChiHost, template *api.HostTemplate) {
 
 	host.Insecure = host.Insecure.MergeFrom(template.Spec.Insecure)
 	host.Secure = host.Secure.MergeFrom(template.Spec.Secure)
+	if host.InterserverHTTPHost == "" {
+		host.InterserverHTTPHost = template.Spec.InterserverHTTPHost
+	}
+	if host.InterserverCredentials == nil {
+		host.InterserverCredentials = template.Spec.InterserverCredentials.DeepCopy()
+	}
 
 	for _, portDistribution := range template.PortDistribution {
 		switch portDistribution.Type {
@@ -304,6 +425,7 @@ func (n *Normalizer) fillStatus() {
 	})
 	ip, _ := chop.Get().ConfigManager.GetRuntimeParam(deployment.OPERATOR_POD_IP)
 	n.ctx.GetTarget().FillStatus(endpoint, pods, fqdns, ip)
+	n.ctx.GetTarget().EnsureStatus().SetFootprint(model.CreateFootprint(n.ctx.GetTarget()))
 }
 
 // normalizeTaskID normalizes .spec.taskID
@@ -389,16 +511,64 @@ func (n *Normalizer) normalizeDefaults(defaults *api.ChiDefaults) *api.ChiDefaul
 		//defaults.Templates = api.NewChiTemplateNames()
 	}
 	defaults.Templates.HandleDeprecatedFields()
+	if (defaults.ProfilePreset != "") && !entitiesNormalizer.IsValidProfilePreset(defaults.ProfilePreset) {
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("unknown profilePreset: %s, skip it", defaults.ProfilePreset)
+		defaults.ProfilePreset = ""
+	}
+	defaults.ConfigStorage = n.normalizeConfigStorage(defaults.ConfigStorage)
+	defaults.Distribution = n.normalizeDistribution(defaults.Distribution)
 	return defaults
 }
 
+// normalizeDistribution normalizes .spec.defaults.distribution
+func (n *Normalizer) normalizeDistribution(distribution string) string {
+	switch strings.ToLower(distribution) {
+	case "":
+		return ""
+	case strings.ToLower(api.DistributionOnePerNode):
+		// Known value, overwrite it to ensure case-ness
+		return api.DistributionOnePerNode
+	case strings.ToLower(api.DistributionOnePerZone):
+		// Known value, overwrite it to ensure case-ness
+		return api.DistributionOnePerZone
+	case strings.ToLower(api.DistributionOnePerShardPerNode):
+		// Known value, overwrite it to ensure case-ness
+		return api.DistributionOnePerShardPerNode
+	}
+
+	log.V(1).M(n.ctx.GetTarget()).F().Warning("unknown distribution: %s, skip it", distribution)
+	return ""
+}
+
+// normalizeConfigStorage normalizes .spec.defaults.configStorage
+func (n *Normalizer) normalizeConfigStorage(storage string) string {
+	switch strings.ToLower(storage) {
+	case strings.ToLower(api.ConfigStorageSecret):
+		// Known value, overwrite it to ensure case-ness
+		return api.ConfigStorageSecret
+	}
+
+	// In case it is unknown value - just use default
+	return api.ConfigStorageConfigMap
+}
+
 // normalizeConfiguration normalizes .spec.configuration
 func (n *Normalizer) normalizeConfiguration(conf *api.Configuration) *api.Configuration {
 	if conf == nil {
 		conf = api.NewConfiguration()
 	}
 	conf.Zookeeper = n.normalizeConfigurationZookeeper(conf.Zookeeper)
+	// Streaming is rendered into settings ahead of settings normalization
+	conf.Streaming = n.normalizeConfigurationStreaming(conf.Streaming)
+	conf.Settings = n.applyConfigurationStreaming(conf.Settings, conf.Streaming, envVarNamePrefixConfigurationStreaming)
 	n.normalizeConfigurationAllSettingsBasedSections(conf)
+	entitiesNormalizer.ApplyProfilePreset(n.ctx.GetTarget().Spec.Defaults.GetProfilePreset(), conf)
+	conf.Logger = n.normalizeConfigurationLogger(conf.Logger)
+	n.applyConfigurationLogger(conf)
+	conf.LogTables = n.normalizeConfigurationLogTables(conf.LogTables)
+	n.applyConfigurationLogTables(conf)
+	conf.Security = n.normalizeConfigurationSecurity(conf.Security)
+	conf.Functions = n.normalizeConfigurationFunctions(conf.Functions)
 	conf.Clusters = n.normalizeClusters(conf.Clusters)
 	return conf
 }
@@ -408,10 +578,316 @@ func (n *Normalizer) normalizeConfigurationAllSettingsBasedSections(conf *api.Co
 	conf.Users = n.normalizeConfigurationUsers(conf.Users)
 	conf.Profiles = n.normalizeConfigurationProfiles(conf.Profiles)
 	conf.Quotas = n.normalizeConfigurationQuotas(conf.Quotas)
+	// Fragments are normalized ahead of settings, which may reference them
+	conf.Fragments = n.normalizeConfigurationFragments(conf.Fragments)
 	conf.Settings = n.normalizeConfigurationSettings(conf.Settings)
 	conf.Files = n.normalizeConfigurationFiles(conf.Files)
 }
 
+// normalizeConfigurationSecurity normalizes .spec.configuration.security
+func (n *Normalizer) normalizeConfigurationSecurity(security *api.ChiSecurity) *api.ChiSecurity {
+	if security == nil {
+		return nil
+	}
+
+	security.RemoteURLAllowHosts = n.normalizeConfigurationSecurityRemoteURLAllowHosts(security.RemoteURLAllowHosts)
+
+	if security.ListenBacklog < 0 {
+		// Negative backlog makes no sense, fallback to ClickHouse default
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid listenBacklog: %d", security.ListenBacklog)
+		security.ListenBacklog = 0
+	}
+
+	return security
+}
+
+// normalizeConfigurationLogger normalizes .spec.configuration.logger
+func (n *Normalizer) normalizeConfigurationLogger(logger *api.ChiLogger) *api.ChiLogger {
+	if logger == nil {
+		return nil
+	}
+
+	// Invalid values would break server config, skip them
+	if (logger.Level != "") && !api.IsValidLoggerLevel(logger.Level) {
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid logger level: %s", logger.Level)
+		logger.Level = ""
+	}
+	if (logger.Format != "") && !api.IsValidLoggerFormat(logger.Format) {
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid logger format: %s", logger.Format)
+		logger.Format = ""
+	}
+	if logger.Count < 0 {
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid logger count: %d", logger.Count)
+		logger.Count = 0
+	}
+	if logger.Console.HasValue() {
+		logger.Console = logger.Console.Normalize(false)
+	}
+
+	return logger
+}
+
+// applyConfigurationLogger renders .spec.configuration.logger into server settings.
+// Having logger rendered as regular settings makes configuration restart policy rules to be applied to it,
+// so logger changes are rolled out with config reload only.
+// Typed fields take precedence over raw logger settings.
+func (n *Normalizer) applyConfigurationLogger(conf *api.Configuration) {
+	settings := conf.Logger.AsSettings()
+	if len(settings) == 0 {
+		return
+	}
+
+	if conf.Settings == nil {
+		conf.Settings = api.NewSettings()
+	}
+	for name, value := range settings {
+		conf.Settings.Set(name, api.NewSettingScalar(value))
+	}
+}
+
+// normalizeConfigurationLogTables normalizes .spec.configuration.logTables
+func (n *Normalizer) normalizeConfigurationLogTables(tables api.ChiLogTables) api.ChiLogTables {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	// Invalid values would break server config, skip them
+	for _, name := range tables.Names() {
+		table := tables[name]
+		if !api.IsValidLogTable(name) {
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip unknown log table: %s", name)
+			delete(tables, name)
+			continue
+		}
+		if table == nil {
+			delete(tables, name)
+			continue
+		}
+		if table.Enabled.HasValue() {
+			table.Enabled = table.Enabled.Normalize(true)
+		}
+		if (table.Engine != "") && table.HasEngineParts() {
+			// ClickHouse refuses engine along with partitioning key, sorting key or TTL
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip partitionBy, orderBy and ttl of log table %s having engine specified", name)
+			table.PartitionBy = ""
+			table.OrderBy = ""
+			table.TTL = ""
+		}
+		if table.FlushIntervalMilliseconds < 0 {
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid flushIntervalMilliseconds of log table %s: %d", name, table.FlushIntervalMilliseconds)
+			table.FlushIntervalMilliseconds = 0
+		}
+	}
+
+	return tables
+}
+
+// applyConfigurationLogTables renders .spec.configuration.logTables into server settings.
+// Typed fields take precedence over raw log table settings.
+func (n *Normalizer) applyConfigurationLogTables(conf *api.Configuration) {
+	settings := conf.LogTables.AsSettings()
+	if len(settings) == 0 {
+		return
+	}
+
+	if conf.Settings == nil {
+		conf.Settings = api.NewSettings()
+	}
+	for name, value := range settings {
+		conf.Settings.Set(name, value)
+	}
+}
+
+// normalizeConfigurationStreaming normalizes .spec.configuration.streaming and .spec.configuration.clusters[n].streaming
+func (n *Normalizer) normalizeConfigurationStreaming(streaming *api.ChiStreaming) *api.ChiStreaming {
+	if streaming == nil {
+		return nil
+	}
+
+	// Invalid values would break server config, skip them
+	if kafka := streaming.GetKafka(); kafka != nil {
+		kafka.Brokers = util.NonEmpty(kafka.Brokers)
+		if (kafka.SecurityProtocol != "") && !api.IsValidKafkaSecurityProtocol(kafka.SecurityProtocol) {
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid kafka securityProtocol: %s", kafka.SecurityProtocol)
+			kafka.SecurityProtocol = ""
+		}
+		if (kafka.SASL != nil) && (kafka.SASL.Mechanism != "") && !api.IsValidKafkaSASLMechanism(kafka.SASL.Mechanism) {
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid kafka sasl mechanism: %s", kafka.SASL.Mechanism)
+			kafka.SASL.Mechanism = ""
+		}
+	}
+	if rabbitmq := streaming.GetRabbitMQ(); rabbitmq != nil {
+		if rabbitmq.Secure.HasValue() {
+			rabbitmq.Secure = rabbitmq.Secure.Normalize(false)
+		}
+	}
+
+	return streaming
+}
+
+// applyConfigurationStreaming renders streaming section into settings.
+// Typed fields take precedence over raw kafka/* and rabbitmq/* settings.
+// Secrets are passed via ENV vars, which are named after the prefix, so cluster-level secrets do not clash with CHI-level ones
+func (n *Normalizer) applyConfigurationStreaming(settings *api.Settings, streaming *api.ChiStreaming, envVarNamePrefix string) *api.Settings {
+	values := streaming.AsSettings()
+	secrets := streaming.AsSecretSettings()
+	if (len(values) == 0) && (len(secrets) == 0) {
+		return settings
+	}
+
+	if settings == nil {
+		settings = api.NewSettings()
+	}
+	for name, value := range values {
+		settings.Set(name, api.NewSettingScalar(value))
+	}
+	for name, ref := range secrets {
+		// In case not OK env var name will be empty and config will be incorrect. CH may not start
+		envVarName, _ := util.BuildShellEnvVarName(envVarNamePrefix + "_" + name)
+		n.appendAdditionalEnvVar(
+			core.EnvVar{
+				Name: envVarName,
+				ValueFrom: &core.EnvVarSource{
+					SecretKeyRef: ref,
+				},
+			},
+		)
+		settings.Set(name, api.NewSettingScalar("").SetAttribute("from_env", envVarName))
+	}
+	return settings
+}
+
+// applyHostInterserverCredentials renders interserver credentials of the host into host's settings.
+// Typed fields take precedence over raw interserver_http_credentials/* settings.
+// Password is passed via ENV var, which is named after the cluster and the host, so hosts migrated to new credentials do not clash with the rest
+func (n *Normalizer) applyHostInterserverCredentials(host *api.ChiHost) *api.Settings {
+	credentials := host.GetInterserverCredentials()
+	if (credentials != nil) && credentials.AllowEmpty.HasValue() {
+		credentials.AllowEmpty = credentials.AllowEmpty.Normalize(false)
+	}
+	values := credentials.AsSettings()
+	secrets := credentials.AsSecretSettings()
+	if (len(values) == 0) && (len(secrets) == 0) {
+		return host.Settings
+	}
+
+	settings := host.Settings
+	if settings == nil {
+		settings = api.NewSettings()
+	}
+	for name, value := range values {
+		settings.Set(name, api.NewSettingScalar(value))
+	}
+	for name, ref := range secrets {
+		// In case not OK env var name will be empty and config will be incorrect. CH may not start
+		envVarName, _ := util.BuildShellEnvVarName(envVarNamePrefixInterserverCredentials + "_" + host.Runtime.Address.ClusterName + "_" + host.GetName() + "_" + name)
+		n.appendAdditionalEnvVar(
+			core.EnvVar{
+				Name: envVarName,
+				ValueFrom: &core.EnvVarSource{
+					SecretKeyRef: ref,
+				},
+			},
+		)
+		settings.Set(name, api.NewSettingScalar("").SetAttribute("from_env", envVarName))
+	}
+	return settings
+}
+
+// normalizeConfigurationSecurityRemoteURLAllowHosts normalizes .spec.configuration.security.remoteURLAllowHosts
+func (n *Normalizer) normalizeConfigurationSecurityRemoteURLAllowHosts(hosts *api.RemoteURLAllowHosts) *api.RemoteURLAllowHosts {
+	if hosts == nil {
+		return nil
+	}
+
+	hosts.Hosts = util.NonEmpty(util.Unique(hosts.Hosts))
+	sort.Strings(hosts.Hosts)
+
+	// Host regexps are passed to ClickHouse as-is, thus invalid ones would break server config.
+	// Go's regexp package implements RE2 syntax, the same ClickHouse uses.
+	var regexps []string
+	for _, hostRegexp := range util.NonEmpty(util.Unique(hosts.HostRegexps)) {
+		if _, err := regexp.Compile(hostRegexp); err != nil {
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid host regexp: %s err: %v", hostRegexp, err)
+			continue
+		}
+		regexps = append(regexps, hostRegexp)
+	}
+	sort.Strings(regexps)
+	hosts.HostRegexps = regexps
+
+	return hosts
+}
+
+// functionSettingNameRegexp matches names of function settings, which are valid XML tag names
+var functionSettingNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// normalizeConfigurationFunctions normalizes .spec.configuration.functions
+func (n *Normalizer) normalizeConfigurationFunctions(functions *api.ChiFunctions) *api.ChiFunctions {
+	if functions == nil {
+		return nil
+	}
+
+	var normalized []*api.ChiFunction
+	for _, function := range functions.Functions {
+		if function == nil {
+			continue
+		}
+		if (function.Name == "") || (function.Command == "") || (function.ReturnType == "") {
+			// Function can not be described without name, command and return type
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip incomplete function: %s", function.Name)
+			continue
+		}
+		if strings.HasPrefix(function.Name, api.FunctionNameReservedPrefix) {
+			// Names with reserved prefix belong to functions maintained by the operator
+			log.V(1).M(n.ctx.GetTarget()).F().Warning("skip function with reserved name: %s", function.Name)
+			continue
+		}
+		switch strings.ToLower(function.Type) {
+		case api.FunctionTypeExecutablePool:
+			function.Type = api.FunctionTypeExecutablePool
+		default:
+			function.Type = api.FunctionTypeExecutable
+		}
+		if function.Format == "" {
+			function.Format = "TabSeparated"
+		}
+		for name := range function.Settings {
+			if !functionSettingNameRegexp.MatchString(name) {
+				// Setting name becomes XML tag name, which can not be escaped
+				log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid setting %s of function: %s", name, function.Name)
+				delete(function.Settings, name)
+			}
+		}
+		normalized = append(normalized, function)
+	}
+	functions.Functions = normalized
+
+	if functions.HasScriptsConfigMap() {
+		// Mount scripts as executable files
+		var defaultMode int32 = 0755
+		volumeName := "user-scripts"
+		n.appendAdditionalVolume(core.Volume{
+			Name: volumeName,
+			VolumeSource: core.VolumeSource{
+				ConfigMap: &core.ConfigMapVolumeSource{
+					LocalObjectReference: core.LocalObjectReference{
+						Name: functions.GetScriptsConfigMap(),
+					},
+					DefaultMode: &defaultMode,
+				},
+			},
+		})
+		n.appendAdditionalVolumeMount(core.VolumeMount{
+			Name:      volumeName,
+			ReadOnly:  true,
+			MountPath: model.DirPathUserScripts,
+		})
+	}
+
+	return functions
+}
+
 // normalizeTemplates normalizes .spec.templates
 func (n *Normalizer) normalizeTemplates(templates *api.Templates) *api.Templates {
 	if templates == nil {
@@ -423,6 +899,7 @@ func (n *Normalizer) normalizeTemplates(templates *api.Templates) *api.Templates
 	n.normalizePodTemplates(templates)
 	n.normalizeVolumeClaimTemplates(templates)
 	n.normalizeServiceTemplates(templates)
+	n.normalizePodDisruptionBudgetTemplates(templates)
 	return templates
 }
 
@@ -462,9 +939,76 @@ func (n *Normalizer) normalizeReconciling(reconciling *api.ChiReconciling) *api.
 		reconciling.SetPolicy(api.ReconcilingPolicyUnspecified)
 	}
 	reconciling.Cleanup = n.normalizeReconcilingCleanup(reconciling.Cleanup)
+	reconciling.Service = n.normalizeReconcilingService(reconciling.Service)
+	reconciling.Access = n.normalizeReconcilingAccess(reconciling.Access)
+	reconciling.Secrets = n.normalizeReconcilingSecrets(reconciling.Secrets)
 	return reconciling
 }
 
+func (n *Normalizer) normalizeReconcilingSecrets(secrets *api.ChiReconcilingSecrets) *api.ChiReconcilingSecrets {
+	if secrets == nil {
+		return nil
+	}
+
+	secrets.OnChange = n.normalizeSecretOnChange(secrets.OnChange, api.SecretOnChangeRestart)
+	var items []api.ChiReconcilingSecret
+	for _, item := range secrets.Items {
+		if item.Name == "" {
+			// Nothing to refer to
+			continue
+		}
+		item.OnChange = n.normalizeSecretOnChange(item.OnChange, secrets.OnChange)
+		items = append(items, item)
+	}
+	secrets.Items = items
+	return secrets
+}
+
+func (n *Normalizer) normalizeSecretOnChange(onChange string, value string) string {
+	switch strings.ToLower(onChange) {
+	case strings.ToLower(api.SecretOnChangeRestart):
+		// Known value, overwrite it to ensure case-ness
+		return api.SecretOnChangeRestart
+	case strings.ToLower(api.SecretOnChangeReload):
+		// Known value, overwrite it to ensure case-ness
+		return api.SecretOnChangeReload
+	case strings.ToLower(api.SecretOnChangeNone):
+		// Known value, overwrite it to ensure case-ness
+		return api.SecretOnChangeNone
+	default:
+		// Unknown value, fallback to default
+		return value
+	}
+}
+
+func (n *Normalizer) normalizeReconcilingAccess(access *api.ChiAccessReconciling) *api.ChiAccessReconciling {
+	if access == nil {
+		return nil
+	}
+	// Unknown endpoints are skipped, empty list falls back to operator's settings
+	access.Endpoints = api.NormalizeChEndpoints(access.Endpoints)
+	return access
+}
+
+func (n *Normalizer) normalizeReconcilingService(service *api.ChiServiceReconciling) *api.ChiServiceReconciling {
+	if service == nil {
+		service = api.NewChiServiceReconciling()
+	}
+
+	switch strings.ToLower(service.FieldsPolicy) {
+	case strings.ToLower(api.ServiceFieldsPolicyPreserve):
+		// Known value, overwrite it to ensure case-ness
+		service.FieldsPolicy = api.ServiceFieldsPolicyPreserve
+	case strings.ToLower(api.ServiceFieldsPolicyReplace):
+		// Known value, overwrite it to ensure case-ness
+		service.FieldsPolicy = api.ServiceFieldsPolicyReplace
+	default:
+		// Unknown value, fallback to default
+		service.FieldsPolicy = api.ServiceFieldsPolicyPreserve
+	}
+	return service
+}
+
 func (n *Normalizer) normalizeReconcilingCleanup(cleanup *api.ChiCleanup) *api.ChiCleanup {
 	if cleanup == nil {
 		cleanup = api.NewChiCleanup()
@@ -529,6 +1073,12 @@ func (n *Normalizer) normalizeServiceTemplates(templates *api.Templates) {
 	}
 }
 
+func (n *Normalizer) normalizePodDisruptionBudgetTemplates(templates *api.Templates) {
+	for i := range templates.PodDisruptionBudgetTemplates {
+		n.normalizePodDisruptionBudgetTemplate(&templates.PodDisruptionBudgetTemplates[i])
+	}
+}
+
 // normalizeHostTemplate normalizes .spec.templates.hostTemplates
 func (n *Normalizer) normalizeHostTemplate(template *api.HostTemplate) {
 	templatesNormalizer.NormalizeHostTemplate(template)
@@ -562,6 +1112,13 @@ func (n *Normalizer) normalizeServiceTemplate(template *api.ServiceTemplate) {
 	n.ctx.GetTarget().Spec.Templates.EnsureServiceTemplatesIndex().Set(template.Name, template)
 }
 
+// normalizePodDisruptionBudgetTemplate normalizes .spec.templates.podDisruptionBudgetTemplates
+func (n *Normalizer) normalizePodDisruptionBudgetTemplate(template *api.PodDisruptionBudgetTemplate) {
+	templatesNormalizer.NormalizePodDisruptionBudgetTemplate(template)
+	// Introduce PodDisruptionBudgetTemplate into Index
+	n.ctx.GetTarget().Spec.Templates.EnsurePodDisruptionBudgetTemplatesIndex().Set(template.Name, template)
+}
+
 // normalizeUseTemplates is a wrapper to hold the name of normalized section
 func (n *Normalizer) normalizeUseTemplates(templates []*api.TemplateRef) []*api.TemplateRef {
 	return templatesNormalizer.NormalizeTemplatesList(templates)
@@ -764,36 +1321,253 @@ func (n *Normalizer) substSettingsFieldWithMountedFile(settings *api.Settings, s
 		})
 }
 
+// substSettingsFieldWithMountedConfigMapFile substitutes files field referencing k8s config map with the mounted file.
+// Config map is mounted as is, its content is not copied into generated config files
+func (n *Normalizer) substSettingsFieldWithMountedConfigMapFile(settings *api.Settings, srcConfigMapRefField string) bool {
+	ref := settings.Get(srcConfigMapRefField).GetConfigMapKeyRef()
+	if ref == nil {
+		// No substitution done
+		return false
+	}
+
+	var defaultMode int32 = 0644
+	volumeName, ok1 := util.BuildRFC1035Label(srcConfigMapRefField)
+	volumeMountName, ok2 := util.BuildRFC1035Label(srcConfigMapRefField)
+	if !ok1 || !ok2 || (ref.Name == "") || (ref.Key == "") {
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip file: %s unable to mount config map: %s key: %s", srcConfigMapRefField, ref.Name, ref.Key)
+		settings.Delete(srcConfigMapRefField)
+		return false
+	}
+
+	n.appendAdditionalVolume(core.Volume{
+		Name: volumeName,
+		VolumeSource: core.VolumeSource{
+			ConfigMap: &core.ConfigMapVolumeSource{
+				LocalObjectReference: core.LocalObjectReference{
+					Name: ref.Name,
+				},
+				Items: []core.KeyToPath{
+					{
+						Key:  ref.Key,
+						Path: ref.Key,
+					},
+				},
+				DefaultMode: &defaultMode,
+				Optional:    ref.Optional,
+			},
+		},
+	})
+
+	// Mount as folder, the same way as secrets are
+	n.appendAdditionalVolumeMount(core.VolumeMount{
+		Name:      volumeMountName,
+		ReadOnly:  true,
+		MountPath: filepath.Join(model.DirPathConfigMapFilesConfig, srcConfigMapRefField, ref.Name),
+	})
+
+	// Referenced file is mounted, not rendered
+	settings.Delete(srcConfigMapRefField)
+	return true
+}
+
 func (n *Normalizer) appendClusterSecretEnvVar(cluster *api.Cluster) {
 	switch cluster.Secret.Source() {
 	case api.ClusterSecretSourcePlaintext:
 		// Secret has explicit value, it is not passed via ENV vars
 		// Do nothing here
-	case api.ClusterSecretSourceSecretRef:
-		// Secret has explicit SecretKeyRef
-		// Set the password for internode communication using an ENV VAR
-		n.appendAdditionalEnvVar(
-			core.EnvVar{
-				Name: model.InternodeClusterSecretEnvName,
-				ValueFrom: &core.EnvVarSource{
-					SecretKeyRef: cluster.Secret.GetSecretKeyRef(),
-				},
-			},
-		)
-	case api.ClusterSecretSourceAuto:
-		// Secret is auto-generated
-		// Set the password for internode communication using an ENV VAR
-		n.appendAdditionalEnvVar(
-			core.EnvVar{
-				Name: model.InternodeClusterSecretEnvName,
-				ValueFrom: &core.EnvVarSource{
-					SecretKeyRef: cluster.Secret.GetAutoSecretKeyRef(model.CreateClusterAutoSecretName(cluster)),
+	case api.ClusterSecretSourceSecretRef, api.ClusterSecretSourceAuto:
+		// Secret is either referenced explicitly or is auto-generated.
+		// Set the password for internode communication using ENV VARs, one per slot of the cluster secret,
+		// which are copied out of the referenced Secret by the operator
+		for _, slot := range []string{model.ClusterSecretSlotA, model.ClusterSecretSlotB} {
+			n.appendAdditionalEnvVar(
+				core.EnvVar{
+					Name: model.CreateClusterSecretSlotEnvName(slot),
+					ValueFrom: &core.EnvVarSource{
+						SecretKeyRef: &core.SecretKeySelector{
+							LocalObjectReference: core.LocalObjectReference{
+								Name: model.CreateClusterSecretSlotsName(cluster),
+							},
+							Key: slot,
+						},
+					},
 				},
-			},
-		)
+			)
+		}
 	}
 }
 
+// fillClusterSecretSlots fills version and active slot of the cluster secret, which is passed to pods via ENV vars.
+// Plaintext secret is not passed via ENV var and has no version.
+func (n *Normalizer) fillClusterSecretSlots(cluster *api.Cluster) {
+	switch cluster.Secret.Source() {
+	case api.ClusterSecretSourceSecretRef, api.ClusterSecretSourceAuto:
+	default:
+		return
+	}
+
+	secret, err := n.getSecret(n.ctx.GetTarget().Namespace, model.CreateClusterSecretSlotsName(cluster))
+	if err != nil {
+		// Secret may be not created yet
+		return
+	}
+
+	cluster.Runtime.SecretVersion = model.GetClusterSecretVersion(secret)
+	cluster.Runtime.SecretSlot = model.GetClusterSecretActiveSlot(secret)
+}
+
+// getClusterTLSCAChecksum gets checksum of CA certificates trusted by hosts of the cluster having 'secure: auto'
+func (n *Normalizer) getClusterTLSCAChecksum(cluster *api.Cluster) string {
+	if !cluster.IsSecureAuto() {
+		return ""
+	}
+
+	secret, err := n.getSecret(n.ctx.GetTarget().Namespace, model.CreateClusterTLSSecretName(cluster))
+	if err != nil {
+		// Secret may be not created yet
+		return ""
+	}
+
+	return model.GetTLSCAChecksum(secret)
+}
+
+// fillHostsSecretsChecksums fills checksums of Secrets referenced by pods of each host,
+// so hosts are able to pick up rotated Secrets either by restart or by config reload.
+// Secret which does not exist is left out of the checksum, any other error fails normalization,
+// since Secret left out due to a transient error would change the checksum and thus restart hosts
+func (n *Normalizer) fillHostsSecretsChecksums() error {
+	namespace := n.ctx.GetTarget().Namespace
+
+	// Secrets managed by the operator and the cluster secret are versioned on their own
+	skip := make(map[string]bool)
+	n.ctx.GetTarget().WalkClusters(func(cluster *api.Cluster) error {
+		skip[model.CreateClusterAutoSecretName(cluster)] = true
+		skip[model.CreateClusterSecretSlotsName(cluster)] = true
+		skip[model.CreateClusterTLSSecretName(cluster)] = true
+		if ref := cluster.Secret.GetSecretKeyRef(); ref != nil {
+			skip[ref.Name] = true
+		}
+		return nil
+	})
+
+	// Secrets are shared by hosts, so each Secret is fetched once
+	checksums := make(map[string]string)
+	getChecksum := func(name string) (string, error) {
+		if checksum, ok := checksums[name]; ok {
+			return checksum, nil
+		}
+		checksum := ""
+		secret, err := n.getSecret(namespace, name)
+		switch {
+		case err == nil:
+			checksum = model.GetSecretChecksum(secret)
+		case apiErrors.IsNotFound(err):
+			// Secret may be not created yet, it is picked up by pods as soon as created
+		default:
+			return "", fmt.Errorf("unable to get Secret %s/%s: %w", namespace, name, err)
+		}
+		checksums[name] = checksum
+		return checksum, nil
+	}
+
+	secrets := n.ctx.GetTarget().GetReconciling().GetSecrets()
+	var err error
+	n.ctx.GetTarget().WalkHosts(func(host *api.ChiHost) error {
+		if err != nil {
+			return nil
+		}
+		var restart, reload []string
+		for _, name := range n.getHostSecretNames(host) {
+			if skip[name] {
+				continue
+			}
+			onChange := secrets.GetOnChange(name)
+			if onChange == api.SecretOnChangeNone {
+				// Secret is not fetched, still CHI is reconciled on its change
+				n.ctx.GetTarget().EnsureRuntime().AddReferencedSecret(namespace, name)
+				continue
+			}
+			var checksum string
+			if checksum, err = getChecksum(name); err != nil {
+				return nil
+			}
+			if checksum == "" {
+				continue
+			}
+			switch onChange {
+			case api.SecretOnChangeRestart:
+				restart = append(restart, name+"="+checksum)
+			case api.SecretOnChangeReload:
+				reload = append(reload, name+"="+checksum)
+			}
+		}
+		host.Runtime.SecretsChecksum = hashSecretsChecksums(restart)
+		host.Runtime.SecretsReloadChecksum = hashSecretsChecksums(reload)
+		return nil
+	})
+	return err
+}
+
+// hashSecretsChecksums hashes checksums of Secrets. No Secrets make no checksum, so pods are not annotated with it
+func hashSecretsChecksums(checksums []string) string {
+	if len(checksums) == 0 {
+		return ""
+	}
+	return util.HashIntoString([]byte(strings.Join(checksums, ";")))
+}
+
+// getHostSecretNames gets sorted names of Secrets referenced by pods of the host,
+// either by pod template or by settings passed via ENV vars and mounted files
+func (n *Normalizer) getHostSecretNames(host *api.ChiHost) []string {
+	var volumes []core.Volume
+	var containers []core.Container
+	if podTemplate, ok := host.GetPodTemplate(); ok {
+		volumes = append(volumes, podTemplate.Spec.Volumes...)
+		containers = append(containers, podTemplate.Spec.InitContainers...)
+		containers = append(containers, podTemplate.Spec.Containers...)
+	}
+	volumes = append(volumes, n.ctx.GetTarget().EnsureRuntime().GetAttributes().AdditionalVolumes...)
+	containers = append(containers, core.Container{
+		Env: n.ctx.GetTarget().EnsureRuntime().GetAttributes().AdditionalEnvVars,
+	})
+
+	names := make(map[string]bool)
+	for i := range volumes {
+		volume := &volumes[i]
+		if volume.Secret != nil {
+			names[volume.Secret.SecretName] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					names[source.Secret.Name] = true
+				}
+			}
+		}
+	}
+	for i := range containers {
+		container := &containers[i]
+		for _, env := range container.Env {
+			if (env.ValueFrom != nil) && (env.ValueFrom.SecretKeyRef != nil) {
+				names[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				names[envFrom.SecretRef.Name] = true
+			}
+		}
+	}
+	delete(names, "")
+
+	var result []string
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
 func (n *Normalizer) appendAdditionalEnvVar(envVar core.EnvVar) {
 	// Sanity check
 	if envVar.Name == "" {
@@ -842,6 +1616,13 @@ func (n *Normalizer) appendAdditionalVolumeMount(volumeMount core.VolumeMount) {
 	n.ctx.GetTarget().EnsureRuntime().GetAttributes().AdditionalVolumeMounts = append(n.ctx.GetTarget().EnsureRuntime().GetAttributes().AdditionalVolumeMounts, volumeMount)
 }
 
+// getSecret fetches the Secret and registers it as referenced by the CHI, even in case it does not exist (yet),
+// so the CHI is reconciled on change of the Secret
+func (n *Normalizer) getSecret(namespace, name string) (*core.Secret, error) {
+	n.ctx.GetTarget().EnsureRuntime().AddReferencedSecret(namespace, name)
+	return n.secretGet(namespace, name)
+}
+
 var ErrSecretValueNotFound = fmt.Errorf("secret value not found")
 
 // fetchSecretFieldValue fetches the value of the specified field in the specified secret
@@ -849,7 +1630,7 @@ var ErrSecretValueNotFound = fmt.Errorf("secret value not found")
 func (n *Normalizer) fetchSecretFieldValue(secretAddress api.ObjectAddress) (string, error) {
 
 	// Fetch the secret
-	secret, err := n.secretGet(secretAddress.Namespace, secretAddress.Name)
+	secret, err := n.getSecret(secretAddress.Namespace, secretAddress.Name)
 	if err != nil {
 		log.V(1).M(secretAddress.Namespace, secretAddress.Name).F().Info("unable to read secret %s %v", secretAddress, err)
 		return "", ErrSecretValueNotFound
@@ -886,6 +1667,7 @@ const chopProfile = "clickhouse_operator"
 func (n *Normalizer) normalizeConfigurationUsers(users *api.Settings) *api.Settings {
 	// Ensure and normalize user settings
 	users = users.Ensure().Normalize()
+	n.normalizeUsersStoreInSecret(users)
 
 	// Add special "default" user to the list of users, which is used/required for:
 	// 1. ClickHouse hosts to communicate with each other
@@ -912,6 +1694,24 @@ func (n *Normalizer) normalizeConfigurationUsers(users *api.Settings) *api.Setti
 	return users
 }
 
+// usersStoreInSecret specifies pseudo-setting of .spec.configuration.users, which is not a user,
+// but requests users config files to be delivered in Secret instead of ConfigMap
+const usersStoreInSecret = "storeInSecret"
+
+// normalizeUsersStoreInSecret extracts storeInSecret out of users settings. Operator config provides the default
+func (n *Normalizer) normalizeUsersStoreInSecret(users *api.Settings) {
+	storeInSecret := chop.Config().ClickHouse.Config.User.StoreInSecret
+	if users.Has(usersStoreInSecret) {
+		if setting := users.Get(usersStoreInSecret); setting.IsScalar() {
+			value := api.StringBool(setting.ScalarString())
+			storeInSecret = &value
+		}
+		// It is not a user and should not make its way into users config files
+		users.Delete(usersStoreInSecret)
+	}
+	n.ctx.GetTarget().EnsureRuntime().GetAttributes().UsersStoredInSecret = storeInSecret.IsTrue()
+}
+
 func (n *Normalizer) removePlainPassword(user *api.SettingsUser) {
 	// If user has any of encrypted password(s) specified, we need to delete existing plaintext password.
 	// Set `remove` flag for user's plaintext `password`, which is specified as empty in stock ClickHouse users.xml,
@@ -922,8 +1722,10 @@ func (n *Normalizer) removePlainPassword(user *api.SettingsUser) {
 }
 
 const (
-	envVarNamePrefixConfigurationUsers    = "CONFIGURATION_USERS"
-	envVarNamePrefixConfigurationSettings = "CONFIGURATION_SETTINGS"
+	envVarNamePrefixConfigurationUsers     = "CONFIGURATION_USERS"
+	envVarNamePrefixConfigurationSettings  = "CONFIGURATION_SETTINGS"
+	envVarNamePrefixConfigurationStreaming = "CONFIGURATION_STREAMING"
+	envVarNamePrefixInterserverCredentials = "INTERSERVER_CREDENTIALS"
 )
 
 func (n *Normalizer) normalizeConfigurationUser(user *api.SettingsUser) {
@@ -972,6 +1774,8 @@ func (n *Normalizer) normalizeConfigurationUserEnsureMandatoryFields(user *api.S
 		quota = ""
 		ips = []string{ip}
 		hostRegexp = ""
+		// Operator manages users of ClickHouseUser resources with SQL, which requires access management
+		user.SetIfNotExists("access_management", api.NewSettingScalar("1"))
 	}
 
 	// Ensure required values are in place and apply non-empty values in case no own value(s) provided
@@ -1113,10 +1917,38 @@ func (n *Normalizer) normalizeConfigurationSettings(settings *api.Settings) *api
 
 	settings.WalkSafe(func(name string, setting *api.Setting) {
 		n.substSettingsFieldWithEnvRefToSecretField(settings, name, name, envVarNamePrefixConfigurationSettings, false)
+		n.substSettingsFieldWithFragmentRef(settings, name)
 	})
 	return settings
 }
 
+// normalizeConfigurationFragments normalizes .spec.configuration.fragments
+func (n *Normalizer) normalizeConfigurationFragments(fragments *api.Settings) *api.Settings {
+	if fragments == nil {
+		return nil
+	}
+	fragments.Normalize()
+	return fragments
+}
+
+// substSettingsFieldWithFragmentRef substitutes settings field referencing named config fragment,
+// such as '@fragment:kafka_common', with XML include of the fragment
+func (n *Normalizer) substSettingsFieldWithFragmentRef(settings *api.Settings, name string) {
+	fragment, ok := settings.Get(name).FragmentRef()
+	if !ok {
+		return
+	}
+
+	if !n.ctx.GetTarget().Spec.Configuration.HasFragment(fragment) {
+		// Include of unknown fragment would break server config, skip it
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip setting: %s referencing unknown fragment: %s", name, fragment)
+		settings.Delete(name)
+		return
+	}
+
+	settings.Set(name, api.NewSettingScalar("").SetAttribute("incl", fragment))
+}
+
 // normalizeConfigurationFiles normalizes .spec.configuration.files
 func (n *Normalizer) normalizeConfigurationFiles(files *api.Settings) *api.Settings {
 	if files == nil {
@@ -1127,6 +1959,7 @@ func (n *Normalizer) normalizeConfigurationFiles(files *api.Settings) *api.Setti
 
 	files.WalkSafe(func(key string, setting *api.Setting) {
 		n.substSettingsFieldWithMountedFile(files, key)
+		n.substSettingsFieldWithMountedConfigMapFile(files, key)
 	})
 
 	return files
@@ -1148,10 +1981,20 @@ func (n *Normalizer) normalizeCluster(cluster *api.Cluster) *api.Cluster {
 	cluster.InheritTemplatesFrom(n.ctx.GetTarget())
 
 	cluster.Zookeeper = n.normalizeConfigurationZookeeper(cluster.Zookeeper)
+	cluster.Streaming = n.normalizeConfigurationStreaming(cluster.Streaming)
+	cluster.Settings = n.applyConfigurationStreaming(cluster.Settings, cluster.Streaming, envVarNamePrefixConfigurationStreaming+"_"+cluster.Name)
 	cluster.Settings = n.normalizeConfigurationSettings(cluster.Settings)
 	cluster.Files = n.normalizeConfigurationFiles(cluster.Files)
 
 	cluster.SchemaPolicy = n.normalizeClusterSchemaPolicy(cluster.SchemaPolicy)
+	cluster.ClickHouseVersion = n.normalizeClusterClickHouseVersion(cluster.ClickHouseVersion)
+	if cluster.Stop.HasValue() {
+		cluster.Stop = cluster.Stop.Normalize(false)
+	}
+	if cluster.Cordon.HasValue() {
+		cluster.Cordon = cluster.Cordon.Normalize(false)
+	}
+	cluster.DNS = n.normalizeClusterDNS(cluster.DNS)
 
 	if cluster.Layout == nil {
 		cluster.Layout = api.NewChiClusterLayout()
@@ -1163,6 +2006,8 @@ func (n *Normalizer) normalizeCluster(cluster *api.Cluster) *api.Cluster {
 
 	n.createHostsField(cluster)
 	n.appendClusterSecretEnvVar(cluster)
+	n.fillClusterSecretSlots(cluster)
+	cluster.Runtime.TLSCAChecksum = n.getClusterTLSCAChecksum(cluster)
 
 	// Loop over all shards and replicas inside shards and fill structure
 	cluster.WalkShards(func(index int, shard *api.ChiShard) error {
@@ -1201,6 +2046,32 @@ func (n *Normalizer) createHostsField(cluster *api.Cluster) {
 	cluster.WalkHostsByReplicas(hostMergeFunc)
 }
 
+// clickHouseVersionRegexp matches ClickHouse versions clusters can be pinned to, such as 24.3 or 24.3.5.47
+var clickHouseVersionRegexp = regexp.MustCompile(`^[0-9]+\.[0-9]+(\.[0-9]+)*$`)
+
+// normalizeClusterClickHouseVersion normalizes ClickHouse version the cluster is pinned to
+func (n *Normalizer) normalizeClusterClickHouseVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if (version != "") && !clickHouseVersionRegexp.MatchString(version) {
+		// Unknown version format, would not be resolved into an image, podTemplate image is used instead
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid clickhouseVersion: %s", version)
+		return ""
+	}
+	return version
+}
+
+// normalizeClusterDNS normalizes custom DNS names specification of the cluster
+func (n *Normalizer) normalizeClusterDNS(dns *api.ChiClusterDNS) *api.ChiClusterDNS {
+	if dns == nil {
+		return nil
+	}
+	dns.Zone = strings.ToLower(dns.GetZone())
+	if dns.ExternalDNS.HasValue() {
+		dns.ExternalDNS = dns.ExternalDNS.Normalize(false)
+	}
+	return dns
+}
+
 // normalizeClusterLayoutShardsCountAndReplicasCount ensures at least 1 shard and 1 replica counters
 func (n *Normalizer) normalizeClusterSchemaPolicy(policy *api.SchemaPolicy) *api.SchemaPolicy {
 	if policy == nil {
@@ -1433,8 +2304,13 @@ func (n *Normalizer) normalizeReplicaName(replica *api.ChiReplica, index int) {
 	replica.Name = model.CreateReplicaName(replica, index)
 }
 
-// normalizeShardName normalizes shard weight
+// normalizeShardWeight normalizes shard weight
 func (n *Normalizer) normalizeShardWeight(shard *api.ChiShard) {
+	if (shard.Weight != nil) && (*shard.Weight < 0) {
+		// Negative weight makes no sense, fallback to ClickHouse default
+		log.V(1).M(n.ctx.GetTarget()).F().Warning("skip invalid weight: %d of shard: %s", *shard.Weight, shard.Name)
+		shard.Weight = nil
+	}
 }
 
 // normalizeShardHosts normalizes all replicas of specified shard
@@ -1475,6 +2351,8 @@ func (n *Normalizer) normalizeHost(
 
 	n.normalizeHostName(host, shard, shardIndex, replica, replicaIndex)
 	entitiesNormalizer.NormalizeHostPorts(host)
+	host.InheritPortsFrom(replica)
+	host.InheritStartupPriorityFrom(replica)
 	// Inherit from either Shard or Replica
 	var s *api.ChiShard
 	var r *api.ChiReplica
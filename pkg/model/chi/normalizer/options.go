@@ -21,6 +21,8 @@ type Options struct {
 	// DefaultUserAdditionalIPs specifies set of additional IPs applied to default user
 	DefaultUserAdditionalIPs   []string
 	DefaultUserInsertHostRegex bool
+	// ScaleInProtectedHosts specifies set of pod names which are protected from being removed by scale-in
+	ScaleInProtectedHosts []string
 }
 
 // NewOptions creates new Options
@@ -15,6 +15,8 @@
 package templates
 
 import (
+	"strings"
+
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
 	"github.com/altinity/clickhouse-operator/pkg/chop"
@@ -73,34 +75,66 @@ func ApplyCHITemplates(target, chi *api.ClickHouseInstallation) (appliedTemplate
 	templates := prepareListOfTemplates(chi)
 
 	// Apply templates from the list and count applied templates - just to make nice log entry
+	states := make(map[string]templateState)
 	for _, template := range templates {
-		if applyTemplate(target, template, chi) {
-			appliedTemplates = append(appliedTemplates, template)
-		}
+		appliedTemplates = append(appliedTemplates, applyTemplate(target, template, chi.Namespace, chi, states, nil)...)
 	}
 
 	log.V(1).M(chi).F().Info("Applied templates num: %d", len(appliedTemplates))
 	return appliedTemplates
 }
 
-// applyTemplate applies a template over target n.ctx.chi
+// templateState specifies state of a template within templates resolution
+type templateState int
+
+const (
+	// templateStateApplying specifies template which templates it extends are being applied
+	templateStateApplying templateState = iota + 1
+	// templateStateApplied specifies template which has been applied already
+	templateStateApplied
+)
+
+// applyTemplate applies a template over target n.ctx.chi.
+// Templates the template extends via its own `useTemplates` are applied first, recursively, so templates can be layered,
+// such as base -> environment -> team. Each template is applied once only, in order it is met first, cycles are skipped.
 // `chi *api.ClickHouseInstallation` is used to determine whether the template should be applied or not only
-func applyTemplate(target *api.ClickHouseInstallation, templateRef *api.TemplateRef, chi *api.ClickHouseInstallation) bool {
+func applyTemplate(
+	target *api.ClickHouseInstallation,
+	templateRef *api.TemplateRef,
+	defaultNamespace string,
+	chi *api.ClickHouseInstallation,
+	states map[string]templateState,
+	chain []string,
+) (appliedTemplates []*api.TemplateRef) {
 	if templateRef == nil {
 		log.Warning("unable to apply template - nil templateRef provided")
 		// Template is not applied
-		return false
+		return nil
 	}
 
 	// What template are we going to apply?
-	defaultNamespace := chi.Namespace
 	template := chop.Config().FindTemplate(templateRef, defaultNamespace)
 	if template == nil {
 		log.V(1).M(templateRef.Namespace, templateRef.Name).F().Warning(
 			"skip template - UNABLE to find by templateRef: %s/%s",
 			templateRef.Namespace, templateRef.Name)
 		// Template is not applied
-		return false
+		return nil
+	}
+
+	fullName := template.Namespace + "/" + template.Name
+	switch states[fullName] {
+	case templateStateApplying:
+		log.V(1).M(templateRef.Namespace, templateRef.Name).F().Warning(
+			"skip template: %s - cycle in templates chain: %s -> %s",
+			fullName, strings.Join(chain, " -> "), fullName)
+		// Template is not applied
+		return nil
+	case templateStateApplied:
+		log.V(2).M(templateRef.Namespace, templateRef.Name).F().Info(
+			"skip template: %s - applied already", fullName)
+		// Template is not applied once again
+		return nil
 	}
 
 	// What target(s) this template wants to be applied to?
@@ -113,8 +147,25 @@ func applyTemplate(target *api.ClickHouseInstallation, templateRef *api.Template
 			"Skip template: %s/%s. Selector: %v does not match labels: %v",
 			templateRef.Namespace, templateRef.Name, selector, chi.Labels)
 		// Template is not applied
-		return false
+		return nil
+	}
+
+	// Templates the template extends go first.
+	// Templates referenced w/o namespace are looked up in the namespace of the template
+	states[fullName] = templateStateApplying
+	extendedNamespace := template.Namespace
+	if extendedNamespace == "" {
+		extendedNamespace = defaultNamespace
+	}
+	for _, extendedRef := range template.Spec.UseTemplates {
+		if extendedRef == nil {
+			continue
+		}
+		// Template is shared, so its refs are not to be modified
+		ref := normalizeTemplateRef(extendedRef.DeepCopy())
+		appliedTemplates = append(appliedTemplates, applyTemplate(target, ref, extendedNamespace, chi, states, append(chain, fullName))...)
 	}
+	states[fullName] = templateStateApplied
 
 	//
 	// Template is found and wants to be applied on the target
@@ -128,7 +179,7 @@ func applyTemplate(target *api.ClickHouseInstallation, templateRef *api.Template
 	mergeFromTemplate(target, template)
 
 	// Template is applied
-	return true
+	return append(appliedTemplates, templateRef)
 }
 
 func mergeFromTemplate(target, template *api.ClickHouseInstallation) *api.ClickHouseInstallation {
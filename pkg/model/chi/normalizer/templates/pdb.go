@@ -0,0 +1,40 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templates
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// NormalizePodDisruptionBudgetTemplate normalizes .spec.templates.podDisruptionBudgetTemplates
+func NormalizePodDisruptionBudgetTemplate(template *api.PodDisruptionBudgetTemplate) {
+	// Check name
+	// Skip for now
+
+	// Check Spec
+	// Selector is managed by the operator
+	template.Spec.Selector = nil
+
+	// Kubernetes allows only one of minAvailable and maxUnavailable to be specified
+	if (template.Spec.MinAvailable != nil) && (template.Spec.MaxUnavailable != nil) {
+		template.Spec.MinAvailable = nil
+	}
+	if (template.Spec.MinAvailable == nil) && (template.Spec.MaxUnavailable == nil) {
+		maxUnavailable := intstr.FromInt(1)
+		template.Spec.MaxUnavailable = &maxUnavailable
+	}
+}
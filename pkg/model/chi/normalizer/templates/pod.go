@@ -15,11 +15,16 @@
 package templates
 
 import (
+	"fmt"
+	"path/filepath"
+
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
 	"github.com/altinity/clickhouse-operator/pkg/apis/deployment"
 	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
 // NormalizePodTemplate normalizes .spec.templates.podTemplates
@@ -157,3 +162,121 @@ func normalizePodDistribution(replicasCount int, podDistribution *api.PodDistrib
 	podDistribution.Type = deployment.PodDistributionUnspecified
 	return nil
 }
+
+// ValidatePodTemplate checks .spec.templates.podTemplates for known mistakes.
+// Warnings describe suspicious, but workable specs, errors describe specs which would make pods fail.
+func ValidatePodTemplate(template *api.PodTemplate, volumeClaimTemplates []string) (warnings []string, errs []error) {
+	// Duplicate volume names
+	volumes := make(map[string]*core.Volume)
+	for i := range template.Spec.Volumes {
+		volume := &template.Spec.Volumes[i]
+		if _, found := volumes[volume.Name]; found {
+			errs = append(errs, fmt.Errorf("podTemplate %s has duplicate volume: %s", template.Name, volume.Name))
+			continue
+		}
+		volumes[volume.Name] = volume
+	}
+
+	if len(template.Spec.Containers) == 0 {
+		// Default container would be created by the operator
+		return warnings, errs
+	}
+
+	// ClickHouse container
+	container := &template.Spec.Containers[0]
+	if c, found := getContainer(template, model.ClickHouseContainerName); found {
+		container = c
+	} else {
+		warnings = append(warnings, fmt.Sprintf(
+			"podTemplate %s has no container named '%s', container '%s' would be used as ClickHouse container",
+			template.Name, model.ClickHouseContainerName, container.Name))
+	}
+
+	// Probes
+	for _, probe := range []*core.Probe{container.LivenessProbe, container.ReadinessProbe, container.StartupProbe} {
+		if port, ok := getProbePort(probe); ok && !isContainerPort(container, port) {
+			warnings = append(warnings, fmt.Sprintf(
+				"podTemplate %s container %s has probe on unknown port: %s", template.Name, container.Name, port.String()))
+		}
+	}
+
+	// Data volume
+	for i := range template.Spec.Containers {
+		c := &template.Spec.Containers[i]
+		for _, mount := range c.VolumeMounts {
+			if filepath.Clean(mount.MountPath) != model.DirPathClickHouseData {
+				continue
+			}
+			if util.InArray(mount.Name, volumeClaimTemplates) {
+				// Mounted from volumeClaimTemplate
+				continue
+			}
+			volume, found := volumes[mount.Name]
+			switch {
+			case !found:
+				errs = append(errs, fmt.Errorf(
+					"podTemplate %s container %s mounts %s from unknown volume: %s",
+					template.Name, c.Name, mount.MountPath, mount.Name))
+			case volume.PersistentVolumeClaim == nil:
+				warnings = append(warnings, fmt.Sprintf(
+					"podTemplate %s container %s mounts %s from volume %s which is not a claim, data would not be persisted",
+					template.Name, c.Name, mount.MountPath, mount.Name))
+			}
+		}
+	}
+
+	return warnings, errs
+}
+
+func getContainer(template *api.PodTemplate, name string) (*core.Container, bool) {
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == name {
+			return &template.Spec.Containers[i], true
+		}
+	}
+	return nil, false
+}
+
+func getProbePort(probe *core.Probe) (intstr.IntOrString, bool) {
+	switch {
+	case probe == nil:
+		return intstr.IntOrString{}, false
+	case probe.HTTPGet != nil:
+		return probe.HTTPGet.Port, true
+	case probe.TCPSocket != nil:
+		return probe.TCPSocket.Port, true
+	}
+	return intstr.IntOrString{}, false
+}
+
+// isContainerPort checks whether port is either specified in the container or is one of the ClickHouse default ports
+func isContainerPort(container *core.Container, port intstr.IntOrString) bool {
+	names := []string{
+		model.ChDefaultTCPPortName,
+		model.ChDefaultTLSPortName,
+		model.ChDefaultHTTPPortName,
+		model.ChDefaultHTTPSPortName,
+		model.ChDefaultInterserverHTTPPortName,
+	}
+	numbers := []int32{
+		model.ChDefaultTCPPortNumber,
+		model.ChDefaultTLSPortNumber,
+		model.ChDefaultHTTPPortNumber,
+		model.ChDefaultHTTPSPortNumber,
+		model.ChDefaultInterserverHTTPPortNumber,
+	}
+	for _, p := range container.Ports {
+		names = append(names, p.Name)
+		numbers = append(numbers, p.ContainerPort)
+	}
+
+	if port.Type == intstr.String {
+		return util.InArray(port.StrVal, names)
+	}
+	for _, number := range numbers {
+		if number == port.IntVal {
+			return true
+		}
+	}
+	return false
+}
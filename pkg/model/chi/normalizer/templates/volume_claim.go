@@ -24,6 +24,12 @@ func NormalizeVolumeClaimTemplate(template *api.VolumeClaimTemplate) {
 	// StorageManagement
 	normalizeStorageManagement(&template.StorageManagement)
 
+	// Check PVCMigration
+	template.Migration = api.NewPVCMigrationFromString(template.Migration.String())
+	if !template.Migration.IsValid() {
+		template.Migration = api.PVCMigrationUnspecified
+	}
+
 	// Check Spec
 	// Skip for now
 }
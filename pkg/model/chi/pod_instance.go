@@ -0,0 +1,145 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"fmt"
+
+	core "k8s.io/api/core/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// In case cluster layout specifies multiple ClickHouse instances per pod, each instance runs in a container of its own.
+// Instances share the same config files, so all instance-specific values are provided via ENV vars.
+// Instance 0 runs in the main ClickHouse container and keeps ports and shard name of the host,
+// so a cluster can be switched to multiple instances per pod w/o changing the first instance.
+const (
+	PodInstanceEnvTCPPort             = "CLICKHOUSE_INSTANCE_TCP_PORT"
+	PodInstanceEnvTLSPort             = "CLICKHOUSE_INSTANCE_TCP_PORT_SECURE"
+	PodInstanceEnvHTTPPort            = "CLICKHOUSE_INSTANCE_HTTP_PORT"
+	PodInstanceEnvHTTPSPort           = "CLICKHOUSE_INSTANCE_HTTPS_PORT"
+	PodInstanceEnvInterserverHTTPPort = "CLICKHOUSE_INSTANCE_INTERSERVER_HTTP_PORT"
+	PodInstanceEnvShard               = "CLICKHOUSE_INSTANCE_SHARD"
+	PodInstanceEnvAllShardsShard      = "CLICKHOUSE_INSTANCE_ALL_SHARDS_SHARD"
+)
+
+// HostHasMultipleInstances checks whether pod of the host runs more than one ClickHouse instance
+func HostHasMultipleInstances(host *api.ChiHost) bool {
+	return host.GetInstancesPerPod() > 1
+}
+
+// HostWalkInstances walks over all ClickHouse instances run in the pod of the host
+func HostWalkInstances(host *api.ChiHost, f func(instance int)) {
+	for instance := 0; instance < host.GetInstancesPerPod(); instance++ {
+		f(instance)
+	}
+}
+
+// CreatePodInstanceContainerName creates name of the container running specified ClickHouse instance
+func CreatePodInstanceContainerName(instance int) string {
+	if instance == 0 {
+		return ClickHouseContainerName
+	}
+	return fmt.Sprintf("%s-%d", ClickHouseContainerName, instance)
+}
+
+// CreatePodInstancePortName creates name of the port of specified ClickHouse instance
+func CreatePodInstancePortName(name string, instance int) string {
+	if instance == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, instance)
+}
+
+// CreatePodInstanceDirName creates name of the sub-folder in data and log volumes used by specified ClickHouse instance
+func CreatePodInstanceDirName(instance int) string {
+	return fmt.Sprintf("instance-%d", instance)
+}
+
+// CreatePodInstanceShardName creates name of the shard served by specified ClickHouse instance
+func CreatePodInstanceShardName(host *api.ChiHost, instance int) string {
+	if instance == 0 {
+		return host.Runtime.Address.ShardName
+	}
+	return fmt.Sprintf("%s-%d", host.Runtime.Address.ShardName, instance)
+}
+
+// GetPodInstancePort gets port of specified ClickHouse instance
+func GetPodInstancePort(host *api.ChiHost, port int32, instance int) int32 {
+	if !api.IsPortAssigned(port) {
+		return port
+	}
+	return port + int32(instance)*host.GetCluster().GetLayout().GetInstancePortStep()
+}
+
+// GetPodInstanceAllShardsShardIndex gets index of the shard served by specified ClickHouse instance
+// within all-shards-one-replica cluster, which has a shard per each instance of each host
+func GetPodInstanceAllShardsShardIndex(host *api.ChiHost, instance int) int {
+	index := 0
+	host.GetCHI().WalkHosts(func(h *api.ChiHost) error {
+		if h.Runtime.Address.CHIScopeIndex < host.Runtime.Address.CHIScopeIndex {
+			index += h.GetInstancesPerPod()
+		}
+		return nil
+	})
+	return index + instance
+}
+
+// HostWalkInstanceAssignedPorts walks over all assigned ports of specified ClickHouse instance
+func HostWalkInstanceAssignedPorts(
+	host *api.ChiHost,
+	instance int,
+	f func(name string, port int32, protocol core.Protocol) bool,
+) {
+	HostWalkAssignedPorts(
+		host,
+		func(name string, port *int32, protocol core.Protocol) bool {
+			return f(CreatePodInstancePortName(name, instance), GetPodInstancePort(host, *port, instance), protocol)
+		},
+	)
+}
+
+// CreatePodInstanceEnvVars creates ENV vars providing instance-specific config values to specified ClickHouse instance
+func CreatePodInstanceEnvVars(host *api.ChiHost, instance int) []core.EnvVar {
+	env := []core.EnvVar{
+		{
+			Name:  PodInstanceEnvShard,
+			Value: CreatePodInstanceShardName(host, instance),
+		},
+		{
+			Name:  PodInstanceEnvAllShardsShard,
+			Value: fmt.Sprintf("%d", GetPodInstanceAllShardsShardIndex(host, instance)),
+		},
+	}
+	for _, p := range []struct {
+		name string
+		port int32
+	}{
+		{PodInstanceEnvTCPPort, host.TCPPort},
+		{PodInstanceEnvTLSPort, host.TLSPort},
+		{PodInstanceEnvHTTPPort, host.HTTPPort},
+		{PodInstanceEnvHTTPSPort, host.HTTPSPort},
+		{PodInstanceEnvInterserverHTTPPort, host.InterserverHTTPPort},
+	} {
+		if api.IsPortAssigned(p.port) {
+			env = append(env, core.EnvVar{
+				Name:  p.name,
+				Value: fmt.Sprintf("%d", GetPodInstancePort(host, p.port, instance)),
+			})
+		}
+	}
+	return env
+}
@@ -0,0 +1,285 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package render renders k8s objects out of ClickHouseInstallation exactly the way the operator does.
+// It is the stable entry point for tools outside of the operator, such as custom schedulers or policy engines,
+// which need to render or compare operator-equivalent manifests w/o reaching into operator's internals.
+// Operator config has to be set up with either chop.New or chop.NewFromConfig before rendering.
+package render
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	model "github.com/altinity/clickhouse-operator/pkg/model/chi"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/creator"
+	"github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer"
+)
+
+// SecretGetter fetches k8s Secret referenced by the CHI
+type SecretGetter func(namespace, name string) (*core.Secret, error)
+
+// Options specifies how objects are rendered
+type Options struct {
+	// PodIPs specifies IPs of pods of the CHI, which the default user is allowed to connect from.
+	// Reconcile fills them with IPs of running pods
+	PodIPs []string
+	// ScaleInProtectedHosts specifies names of pods, which are protected from being removed by scale-in
+	ScaleInProtectedHosts []string
+	// SecretGetter fetches Secrets referenced by the CHI. Referenced Secrets are reported as missing in case not specified
+	SecretGetter SecretGetter
+	// KeepSecretsData specifies whether rendered Secrets keep their data, otherwise keys only are kept
+	KeepSecretsData bool
+}
+
+// NewOptions creates new Options
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Objects specifies objects the operator would create for the CHI
+type Objects struct {
+	// CHI is the normalized CHI objects are rendered out of
+	CHI                  *api.ClickHouseInstallation   `json:"-"`
+	StatefulSets         []*apps.StatefulSet           `json:"statefulSets,omitempty"`
+	Services             []*core.Service               `json:"services,omitempty"`
+	ConfigMaps           []*core.ConfigMap             `json:"configMaps,omitempty"`
+	Secrets              []*core.Secret                `json:"secrets,omitempty"`
+	PodDisruptionBudgets []*policy.PodDisruptionBudget `json:"podDisruptionBudgets,omitempty"`
+	ServiceMonitors      []*unstructured.Unstructured  `json:"serviceMonitors,omitempty"`
+	GatewayRoutes        []*unstructured.Unstructured  `json:"gatewayRoutes,omitempty"`
+}
+
+// Renderer renders k8s objects out of CHI
+type Renderer interface {
+	// Normalize builds CHI the objects are rendered out of, with templates applied and defaults filled
+	Normalize(chi *api.ClickHouseInstallation) (*api.ClickHouseInstallation, error)
+	// Render renders all objects of the CHI
+	Render(chi *api.ClickHouseInstallation) (*Objects, error)
+}
+
+// renderer is the default Renderer
+type renderer struct {
+	options    *Options
+	normalizer *normalizer.Normalizer
+}
+
+// NewRenderer creates new Renderer
+func NewRenderer(options *Options) Renderer {
+	if options == nil {
+		options = NewOptions()
+	}
+	r := &renderer{
+		options: options,
+	}
+	r.normalizer = normalizer.NewNormalizer(r.getSecret)
+	return r
+}
+
+// getSecret fetches Secret with the SecretGetter of the options
+func (r *renderer) getSecret(namespace, name string) (*core.Secret, error) {
+	if r.options.SecretGetter == nil {
+		// Secrets are not available while rendering, they are treated as not created yet
+		return nil, apiErrors.NewNotFound(core.Resource("secrets"), name)
+	}
+	return r.options.SecretGetter(namespace, name)
+}
+
+// getSecretIfExists fetches Secret, which may be not created yet
+func (r *renderer) getSecretIfExists(namespace, name string) (*core.Secret, error) {
+	secret, err := r.getSecret(namespace, name)
+	if apiErrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// Normalize builds CHI the objects are rendered out of
+func (r *renderer) Normalize(chi *api.ClickHouseInstallation) (*api.ClickHouseInstallation, error) {
+	if chop.Config() == nil {
+		return nil, fmt.Errorf("operator config is not set up")
+	}
+	options := normalizer.NewOptions()
+	options.DefaultUserAdditionalIPs = r.options.PodIPs
+	options.ScaleInProtectedHosts = r.options.ScaleInProtectedHosts
+	return r.normalizer.CreateTemplatedCHI(chi, options)
+}
+
+// Render renders objects of the CHI the same way reconcile does.
+// Object lists are built by the creator, the same way they are built for the reconcile
+func (r *renderer) Render(chi *api.ClickHouseInstallation) (*Objects, error) {
+	normalized, err := r.Normalize(chi)
+	if err != nil {
+		return nil, err
+	}
+
+	c := creator.NewCreator(normalized)
+	objects := &Objects{
+		CHI: normalized,
+	}
+	addSecret := func(secret *core.Secret) {
+		if !r.options.KeepSecretsData {
+			secret = newSecretKeysOnly(secret)
+		}
+		objects.Secrets = append(objects.Secrets, secret)
+	}
+	addConfigMap := func(configMap *core.ConfigMap, inSecret bool) {
+		if inSecret {
+			addSecret(c.CreateConfigSecret(configMap))
+		} else {
+			objects.ConfigMaps = append(objects.ConfigMaps, configMap)
+		}
+	}
+
+	addConfigMap(c.CreateConfigMapCHICommon(nil), normalized.Spec.Defaults.IsConfigStoredInSecret())
+	addConfigMap(c.CreateConfigMapCHICommonUsers(), normalized.IsUsersStoredInSecret())
+
+	objects.Services = append(objects.Services, c.CreateServicesCHI()...)
+	if !normalized.IsStopped() && normalized.Spec.Monitoring.IsServiceMonitorEnabled() {
+		// ServiceMonitor is shared by all CHIs, it is rendered as it would be in case of the CHI alone
+		objects.ServiceMonitors = append(objects.ServiceMonitors, creator.CreateOperatorServiceMonitor(
+			creator.GetOperatorServiceMonitorNamespace(),
+			[]*api.ChiServiceMonitor{normalized.Spec.Monitoring.GetServiceMonitor()},
+		))
+	}
+	objects.GatewayRoutes = append(objects.GatewayRoutes, c.CreateGatewayRoutes()...)
+
+	for _, err := range normalized.WalkClusters(func(cluster *api.Cluster) error {
+		objects.Services = append(objects.Services, c.CreateServicesCluster(cluster)...)
+		secrets, err := r.renderClusterSecrets(c, cluster)
+		if err != nil {
+			return err
+		}
+		for _, secret := range secrets {
+			addSecret(secret)
+		}
+		objects.PodDisruptionBudgets = append(objects.PodDisruptionBudgets, c.CreatePodDisruptionBudgets(cluster)...)
+		return nil
+	}) {
+		if err != nil {
+			return nil, err
+		}
+	}
+	normalized.WalkShards(func(shard *api.ChiShard) error {
+		if service := c.CreateServiceShard(shard); service != nil {
+			objects.Services = append(objects.Services, service)
+		}
+		return nil
+	})
+	normalized.WalkHosts(func(host *api.ChiHost) error {
+		addConfigMap(c.CreateConfigMapHost(host), normalized.Spec.Defaults.IsConfigStoredInSecret())
+		objects.Services = append(objects.Services, c.CreateServicesHost(host)...)
+		objects.StatefulSets = append(objects.StatefulSets, c.CreateStatefulSet(host, false))
+		return nil
+	})
+
+	return objects, nil
+}
+
+// renderClusterSecrets renders Secrets of the cluster - auto-generated cluster secret, slots of the cluster secret
+// passed to pods and auto-generated TLS certificates. Secrets, which exist already, are kept the way reconcile keeps them
+func (r *renderer) renderClusterSecrets(c *creator.Creator, cluster *api.Cluster) ([]*core.Secret, error) {
+	var secrets []*core.Secret
+	namespace := cluster.Runtime.CHI.Namespace
+
+	// Value of the cluster secret the slots carry
+	var ref *core.SecretKeySelector
+	switch cluster.Secret.Source() {
+	case api.ClusterSecretSourceAuto:
+		name := model.CreateClusterAutoSecretName(cluster)
+		secret, err := r.getSecretIfExists(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			secret = c.CreateClusterSecret(name)
+		}
+		secrets = append(secrets, secret)
+		ref = cluster.Secret.GetAutoSecretKeyRef(name)
+	case api.ClusterSecretSourceSecretRef:
+		ref = cluster.Secret.GetSecretKeyRef()
+	}
+
+	if ref != nil {
+		slots, err := r.getSecretIfExists(namespace, model.CreateClusterSecretSlotsName(cluster))
+		if err != nil {
+			return nil, err
+		}
+		if slots == nil {
+			value, err := r.getClusterSecretValue(namespace, ref, secrets)
+			if err != nil {
+				return nil, err
+			}
+			slots = c.CreateClusterSecretSlots(cluster, value)
+		}
+		secrets = append(secrets, slots)
+	}
+
+	if cluster.IsSecureAuto() {
+		cur, err := r.getSecretIfExists(namespace, model.CreateClusterTLSSecretName(cluster))
+		if err != nil {
+			return nil, err
+		}
+		secret, err := c.CreateClusterTLSSecret(cluster, cur)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
+// getClusterSecretValue gets value of the cluster secret out of the Secret it is referenced by,
+// which is either one of the rendered Secrets, or is fetched. Value is empty in case the Secret is not created yet
+func (r *renderer) getClusterSecretValue(namespace string, ref *core.SecretKeySelector, rendered []*core.Secret) ([]byte, error) {
+	for _, secret := range rendered {
+		if secret.Name != ref.Name {
+			continue
+		}
+		if value, ok := secret.Data[ref.Key]; ok {
+			return value, nil
+		}
+		return []byte(secret.StringData[ref.Key]), nil
+	}
+	secret, err := r.getSecretIfExists(namespace, ref.Name)
+	if (err != nil) || (secret == nil) {
+		return nil, err
+	}
+	return secret.Data[ref.Key], nil
+}
+
+// newSecretKeysOnly makes a copy of the Secret w/o data, which is not to be published
+func newSecretKeysOnly(secret *core.Secret) *core.Secret {
+	keysOnly := &core.Secret{
+		TypeMeta:   secret.TypeMeta,
+		ObjectMeta: *secret.ObjectMeta.DeepCopy(),
+		Type:       secret.Type,
+		StringData: make(map[string]string),
+	}
+	for key := range secret.Data {
+		keysOnly.StringData[key] = ""
+	}
+	for key := range secret.StringData {
+		keysOnly.StringData[key] = ""
+	}
+	return keysOnly
+}
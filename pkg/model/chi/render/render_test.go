@@ -0,0 +1,85 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/yaml"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+)
+
+// update rewrites golden files with rendered objects: go test ./pkg/model/chi/render/ -update
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRenderGolden(t *testing.T) {
+	_ = chop.NewFromConfig(nil)
+
+	for _, name := range []string{
+		"simple",
+		"cluster-secret",
+		"stopped",
+	} {
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", name+".yaml"))
+			require.NoError(t, err)
+			chi := &api.ClickHouseInstallation{}
+			require.NoError(t, yaml.Unmarshal(input, chi))
+
+			objects, err := NewRenderer(NewOptions()).Render(chi)
+			require.NoError(t, err)
+			rendered, err := yaml.Marshal(objects)
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", name+".golden.yaml")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, rendered, 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), string(rendered))
+		})
+	}
+}
+
+func TestRenderIsRepeatable(t *testing.T) {
+	_ = chop.NewFromConfig(nil)
+
+	input, err := os.ReadFile(filepath.Join("testdata", "cluster-secret.yaml"))
+	require.NoError(t, err)
+	render := func() string {
+		chi := &api.ClickHouseInstallation{}
+		require.NoError(t, yaml.Unmarshal(input, chi))
+		objects, err := NewRenderer(NewOptions()).Render(chi)
+		require.NoError(t, err)
+		rendered, err := yaml.Marshal(objects)
+		require.NoError(t, err)
+		return string(rendered)
+	}
+	require.Equal(t, render(), render())
+}
+
+func TestNewFromConfigKeepsConfig(t *testing.T) {
+	_ = chop.NewFromConfig(nil)
+	config := chop.Config()
+	require.Error(t, chop.NewFromConfig(&api.OperatorConfig{}))
+	require.Same(t, config, chop.Config())
+}
@@ -17,6 +17,7 @@ package schemer
 import (
 	"context"
 	"strings"
+	"time"
 
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
@@ -25,15 +26,27 @@ import (
 	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
+// EndpointsGetter gets list of endpoints of the host, in order of preference
+type EndpointsGetter func(host *api.ChiHost) []string
+
+// QueryObserver is notified about each SQL call made to the host, such as to track latency of the calls
+type QueryObserver func(host *api.ChiHost, duration time.Duration, err error)
+
+// SlowQueryObserver is notified about each SQL statement made to the host running longer than slow query threshold
+type SlowQueryObserver func(host *api.ChiHost, sql string, duration time.Duration)
+
 // Cluster specifies ClickHouse cluster
 type Cluster struct {
 	*clickhouse.Cluster
+	endpoints    EndpointsGetter
+	observer     QueryObserver
+	slowObserver SlowQueryObserver
 }
 
 // NewCluster creates new cluster object
 func NewCluster() *Cluster {
 	return &Cluster{
-		clickhouse.NewCluster(),
+		Cluster: clickhouse.NewCluster(),
 	}
 }
 
@@ -46,8 +59,161 @@ func (c *Cluster) SetClusterConnectionParams(clusterConnectionParams *clickhouse
 	return c
 }
 
+// SetEndpointsGetter sets function to get host endpoints
+func (c *Cluster) SetEndpointsGetter(endpoints EndpointsGetter) *Cluster {
+	if c == nil {
+		return nil
+	}
+	c.endpoints = endpoints
+	return c
+}
+
+// SetQueryObserver sets function to be notified about SQL calls made to hosts
+func (c *Cluster) SetQueryObserver(observer QueryObserver) *Cluster {
+	if c == nil {
+		return nil
+	}
+	c.observer = observer
+	return c
+}
+
+// SetSlowQueryObserver sets function to be notified about slow SQL statements made to hosts
+func (c *Cluster) SetSlowQueryObserver(observer SlowQueryObserver) *Cluster {
+	if c == nil {
+		return nil
+	}
+	c.slowObserver = observer
+	return c
+}
+
+// observeSlowQueries makes slow SQL statements to be reported as made to the hosts the endpoints belong to
+func (c *Cluster) observeSlowQueries(endpoints *hostEndpoints) {
+	if c.slowObserver == nil {
+		c.Cluster.SetSlowQueryObserver(nil)
+		return
+	}
+	c.Cluster.SetSlowQueryObserver(func(endpoint, sql string, duration time.Duration) {
+		if host := endpoints.getHost(endpoint); host != nil {
+			c.slowObserver(host, sql, duration)
+		}
+	})
+}
+
+// observeQuery notifies observer, if any, about SQL call made to the host
+func (c *Cluster) observeQuery(host *api.ChiHost, start time.Time, err error) {
+	if c.observer != nil {
+		c.observer(host, time.Since(start), err)
+	}
+}
+
+// getHostEndpoints gets endpoints of the host, in order of preference
+func (c *Cluster) getHostEndpoints(host *api.ChiHost) []string {
+	if c.endpoints != nil {
+		if endpoints := c.endpoints(host); len(endpoints) > 0 {
+			return endpoints
+		}
+	}
+	return model.CreateFQDNs(host, api.ChiHost{}, false)
+}
+
+// getHostEndpoint gets the first reachable endpoint of the host.
+// In case no endpoint is reachable, the most preferable one is returned
+func (c *Cluster) getHostEndpoint(ctx context.Context, host *api.ChiHost) []string {
+	endpoints := c.getHostEndpoints(host)
+	if len(endpoints) < 2 {
+		return endpoints
+	}
+	for _, endpoint := range endpoints {
+		if c.IsHostReachable(ctx, endpoint) {
+			return []string{endpoint}
+		}
+		log.V(1).M(host).F().Warning("endpoint %s is not reachable, fallback to the next one", endpoint)
+	}
+	return endpoints[:1]
+}
+
+// getHosts gets hosts of the object within the scope, the same way model.CreateFQDNs does for FQDNs
+func getHosts(obj interface{}, scope interface{}) (hosts []*api.ChiHost) {
+	collect := func(host *api.ChiHost) error {
+		hosts = append(hosts, host)
+		return nil
+	}
+	switch typed := obj.(type) {
+	case *api.ClickHouseInstallation:
+		typed.WalkHosts(collect)
+	case *api.Cluster:
+		typed.WalkHosts(collect)
+	case *api.ChiShard:
+		typed.WalkHosts(collect)
+	case *api.ChiHost:
+		switch scope.(type) {
+		case api.ChiHost:
+			hosts = []*api.ChiHost{typed}
+		case api.ChiShard:
+			typed.GetShard().WalkHosts(collect)
+		case api.Cluster:
+			typed.GetCluster().WalkHosts(collect)
+		case api.ClickHouseInstallation:
+			typed.GetCHI().WalkHosts(collect)
+		}
+	}
+	return hosts
+}
+
+// hostEndpoints specifies endpoints of hosts, in order of preference, along with hosts they belong to
+type hostEndpoints struct {
+	endpoints []string
+	hosts     map[string]*api.ChiHost
+}
+
+// add adds endpoints of the host
+func (e *hostEndpoints) add(host *api.ChiHost, endpoints ...string) *hostEndpoints {
+	if e.hosts == nil {
+		e.hosts = make(map[string]*api.ChiHost)
+	}
+	for _, endpoint := range endpoints {
+		e.endpoints = append(e.endpoints, endpoint)
+		e.hosts[endpoint] = host
+	}
+	return e
+}
+
+// getEndpoints gets endpoints
+func (e *hostEndpoints) getEndpoints() []string {
+	if e == nil {
+		return nil
+	}
+	return e.endpoints
+}
+
+// getHost gets host the endpoint belongs to
+func (e *hostEndpoints) getHost(endpoint string) *api.ChiHost {
+	if e == nil {
+		return nil
+	}
+	return e.hosts[endpoint]
+}
+
+// getExecEndpoints gets endpoints to run SQL on each of the hosts, the first reachable endpoint of each host
+func (c *Cluster) getExecEndpoints(ctx context.Context, hosts []*api.ChiHost) *hostEndpoints {
+	endpoints := &hostEndpoints{}
+	for _, host := range hosts {
+		endpoints.add(host, c.getHostEndpoint(ctx, host)...)
+	}
+	return endpoints
+}
+
+// getQueryEndpoints gets endpoints to fetch data from any of the hosts, all endpoints of each host in order of preference
+func (c *Cluster) getQueryEndpoints(hosts []*api.ChiHost) *hostEndpoints {
+	endpoints := &hostEndpoints{}
+	for _, host := range hosts {
+		endpoints.add(host, c.getHostEndpoints(host)...)
+	}
+	return endpoints
+}
+
 // queryUnzipColumns
-func (c *Cluster) queryUnzipColumns(ctx context.Context, hosts []string, sql string, columns ...*[]string) error {
+func (c *Cluster) queryUnzipColumns(ctx context.Context, hosts []*api.ChiHost, sql string, columns ...*[]string) error {
 	if util.IsContextDone(ctx) {
 		log.V(2).Info("ctx is done")
 		return nil
@@ -59,7 +225,9 @@ func (c *Cluster) queryUnzipColumns(ctx context.Context, hosts []string, sql str
 	}
 
 	// Fetch data from any of specified hosts
-	query, err := c.SetHosts(hosts).QueryAny(ctx, sql)
+	endpoints := c.getQueryEndpoints(hosts)
+	c.observeSlowQueries(endpoints)
+	query, err := c.SetHosts(endpoints.getEndpoints()).QueryAny(ctx, sql)
 	if err != nil {
 		return nil
 	}
@@ -73,22 +241,22 @@ func (c *Cluster) queryUnzipColumns(ctx context.Context, hosts []string, sql str
 }
 
 // QueryUnzip2Columns unzips query result into two columns
-func (c *Cluster) QueryUnzip2Columns(ctx context.Context, endpoints []string, sql string) ([]string, []string, error) {
+func (c *Cluster) QueryUnzip2Columns(ctx context.Context, hosts []*api.ChiHost, sql string) ([]string, []string, error) {
 	var column1 []string
 	var column2 []string
-	if err := c.queryUnzipColumns(ctx, endpoints, sql, &column1, &column2); err != nil {
+	if err := c.queryUnzipColumns(ctx, hosts, sql, &column1, &column2); err != nil {
 		return nil, nil, err
 	}
 	return column1, column2, nil
 }
 
 // QueryUnzipAndApplyUUIDs unzips query result into two columns and applis UUID substituation if present
-func (c *Cluster) QueryUnzipAndApplyUUIDs(ctx context.Context, endpoints []string, sql string) ([]string, []string, error) {
+func (c *Cluster) QueryUnzipAndApplyUUIDs(ctx context.Context, hosts []*api.ChiHost, sql string) ([]string, []string, error) {
 	var column1 []string
 	var column2 []string
 	var column3 []string
 	var column4 []string
-	if err := c.queryUnzipColumns(ctx, endpoints, sql, &column1, &column2, &column3, &column4); err != nil {
+	if err := c.queryUnzipColumns(ctx, hosts, sql, &column1, &column2, &column3, &column4); err != nil {
 		return nil, nil, err
 	}
 	for i := 0; i < len(column1); i++ {
@@ -103,30 +271,39 @@ func (c *Cluster) QueryUnzipAndApplyUUIDs(ctx context.Context, endpoints []strin
 
 // ExecCHI runs set of SQL queries over the whole CHI
 func (c *Cluster) ExecCHI(ctx context.Context, chi *api.ClickHouseInstallation, SQLs []string, _opts ...*clickhouse.QueryOptions) error {
-	hosts := model.CreateFQDNs(chi, nil, false)
+	endpoints := c.getExecEndpoints(ctx, getHosts(chi, nil))
 	opts := clickhouse.QueryOptionsNormalize(_opts...)
-	return c.SetHosts(hosts).ExecAll(ctx, SQLs, opts)
+	c.observeSlowQueries(endpoints)
+	return c.SetHosts(endpoints.getEndpoints()).ExecAll(ctx, SQLs, opts)
 }
 
 // ExecCluster runs set of SQL queries over the cluster
 func (c *Cluster) ExecCluster(ctx context.Context, cluster *api.Cluster, SQLs []string, _opts ...*clickhouse.QueryOptions) error {
-	hosts := model.CreateFQDNs(cluster, nil, false)
+	endpoints := c.getExecEndpoints(ctx, getHosts(cluster, nil))
 	opts := clickhouse.QueryOptionsNormalize(_opts...)
-	return c.SetHosts(hosts).ExecAll(ctx, SQLs, opts)
+	c.observeSlowQueries(endpoints)
+	return c.SetHosts(endpoints.getEndpoints()).ExecAll(ctx, SQLs, opts)
 }
 
 // ExecShard runs set of SQL queries over the shard replicas
 func (c *Cluster) ExecShard(ctx context.Context, shard *api.ChiShard, SQLs []string, _opts ...*clickhouse.QueryOptions) error {
-	hosts := model.CreateFQDNs(shard, nil, false)
+	endpoints := c.getExecEndpoints(ctx, getHosts(shard, nil))
 	opts := clickhouse.QueryOptionsNormalize(_opts...)
-	return c.SetHosts(hosts).ExecAll(ctx, SQLs, opts)
+	c.observeSlowQueries(endpoints)
+	return c.SetHosts(endpoints.getEndpoints()).ExecAll(ctx, SQLs, opts)
 }
 
 // ExecHost runs set of SQL queries over the replica
-func (c *Cluster) ExecHost(ctx context.Context, host *api.ChiHost, SQLs []string, _opts ...*clickhouse.QueryOptions) error {
-	hosts := model.CreateFQDNs(host, api.ChiHost{}, false)
+func (c *Cluster) ExecHost(ctx context.Context, host *api.ChiHost, SQLs []string, _opts ...*clickhouse.QueryOptions) (err error) {
+	start := time.Now()
+	defer func() {
+		c.observeQuery(host, start, err)
+	}()
+
+	endpoints := c.getExecEndpoints(ctx, []*api.ChiHost{host})
 	opts := clickhouse.QueryOptionsNormalize(_opts...)
-	c.SetHosts(hosts)
+	c.SetHosts(endpoints.getEndpoints())
+	c.observeSlowQueries(endpoints)
 	if opts.GetSilent() {
 		c.SetLog(log.Silence())
 	} else {
@@ -136,17 +313,24 @@ func (c *Cluster) ExecHost(ctx context.Context, host *api.ChiHost, SQLs []string
 }
 
 // QueryHost runs specified query on specified host
-func (c *Cluster) QueryHost(ctx context.Context, host *api.ChiHost, sql string, _opts ...*clickhouse.QueryOptions) (*clickhouse.QueryResult, error) {
-	hosts := model.CreateFQDNs(host, api.ChiHost{}, false)
+func (c *Cluster) QueryHost(ctx context.Context, host *api.ChiHost, sql string, _opts ...*clickhouse.QueryOptions) (query *clickhouse.QueryResult, err error) {
+	start := time.Now()
+	defer func() {
+		c.observeQuery(host, start, err)
+	}()
+
+	// Endpoints are walked in order of preference
+	endpoints := c.getQueryEndpoints([]*api.ChiHost{host})
 	opts := clickhouse.QueryOptionsNormalize(_opts...)
-	c.SetHosts(hosts)
+	c.SetHosts(endpoints.getEndpoints())
+	c.observeSlowQueries(endpoints)
 	if opts.GetSilent() {
 		c.SetLog(log.Silence())
 	} else {
 		c.SetLog(log.New())
 	}
-	// Fetch data from any of specified hosts
-	return c.SetHosts(hosts).QueryAny(ctx, sql)
+	// Fetch data from any of specified endpoints
+	return c.QueryAny(ctx, sql)
 }
 
 // QueryHostInt runs specified query on specified host and returns one int as a result
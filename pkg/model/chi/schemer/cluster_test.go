@@ -0,0 +1,36 @@
+package schemer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+func TestClusterQueryEndpoints(t *testing.T) {
+	shard := &api.ChiShard{
+		Hosts: []*api.ChiHost{
+			{Name: "0-0"},
+			{Name: "0-1"},
+		},
+	}
+	require.Equal(t, shard.Hosts, getHosts(shard, nil))
+	require.Equal(t, shard.Hosts[1:], getHosts(shard.Hosts[1], api.ChiHost{}))
+
+	cluster := NewCluster().SetEndpointsGetter(func(host *api.ChiHost) []string {
+		return []string{"ip-" + host.Name, "pod-" + host.Name}
+	})
+	// Endpoint of a single host is not checked for reachability
+	require.Equal(t, []string{"pod-fqdn"}, NewCluster().SetEndpointsGetter(func(*api.ChiHost) []string {
+		return []string{"pod-fqdn"}
+	}).getExecEndpoints(context.Background(), shard.Hosts[:1]).getEndpoints())
+
+	// All endpoints of all hosts are queried in order of preference
+	endpoints := cluster.getQueryEndpoints(shard.Hosts)
+	require.Equal(t, []string{"ip-0-0", "pod-0-0", "ip-0-1", "pod-0-1"}, endpoints.getEndpoints())
+	// Slow statements are reported as made to the host the endpoint belongs to
+	require.Equal(t, shard.Hosts[1], endpoints.getHost("pod-0-1"))
+	require.Nil(t, endpoints.getHost("unknown"))
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemer
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+)
+
+// DDLQueueEntry describes entry of distributed DDL queue, as listed in system.distributed_ddl_queue
+type DDLQueueEntry struct {
+	// Entry specifies name of the entry, such as query-0000000042
+	Entry   string
+	Cluster string
+	Query   string
+	// Age specifies how long ago the entry was created
+	Age time.Duration
+	// Pending lists hosts which did not complete the entry yet
+	Pending []string
+	// Failed lists hosts which completed the entry with an exception
+	Failed []string
+}
+
+// IsCompleted checks whether the entry is completed by all hosts
+func (e *DDLQueueEntry) IsCompleted() bool {
+	return len(e.Pending) == 0
+}
+
+// sqlDDLQueue returns SQL to list entries of distributed DDL queue along with hosts the entries are not completed by
+func (s *ClusterSchemer) sqlDDLQueue() string {
+	return heredoc.Doc(`
+		SELECT
+			entry,
+			any(cluster) AS cluster,
+			any(query) AS query,
+			toString(dateDiff('second', min(query_create_time), now())) AS age,
+			arrayStringConcat(groupArrayIf(ifNull(host, ''), ifNull(toString(status), '') != 'Finished'), ',') AS pending,
+			arrayStringConcat(groupArrayIf(ifNull(host, ''), ifNull(exception_code, 0) != 0), ',') AS failed
+		FROM
+			system.distributed_ddl_queue
+		GROUP BY
+			entry
+		ORDER BY
+			entry
+		`,
+	)
+}
+
+// HostDDLQueue lists entries of distributed DDL queue as seen by the host
+func (s *ClusterSchemer) HostDDLQueue(ctx context.Context, host *api.ChiHost) ([]DDLQueueEntry, error) {
+	query, err := s.QueryHost(ctx, host, s.sqlDDLQueue(), clickhouse.NewQueryOptions().SetSilent(true))
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, nil
+	}
+	var entries, clusters, queries, ages, pending, failed []string
+	err = query.UnzipColumnsAsStrings(&entries, &clusters, &queries, &ages, &pending, &failed)
+	query.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []DDLQueueEntry
+	for i := range entries {
+		age, _ := strconv.Atoi(ages[i])
+		result = append(result, DDLQueueEntry{
+			Entry:   entries[i],
+			Cluster: clusters[i],
+			Query:   queries[i],
+			Age:     time.Duration(age) * time.Second,
+			Pending: splitHosts(pending[i]),
+			Failed:  splitHosts(failed[i]),
+		})
+	}
+	return result, nil
+}
+
+// splitHosts splits comma-separated list of hosts
+func splitHosts(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
@@ -0,0 +1,238 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemer
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// schemaObjectsParallelism specifies how many independent schema objects are created on a host concurrently
+const schemaObjectsParallelism = 4
+
+// Kinds of schema objects. Objects of a kind may depend on objects of preceding kinds only,
+// unless explicit reference in the CREATE statement says otherwise
+const (
+	schemaObjectKindDatabase = iota
+	schemaObjectKindFunction
+	schemaObjectKindTable
+	schemaObjectKindDictionary
+	schemaObjectKindView
+)
+
+// schemaObject describes schema object to be created along with objects it depends on
+type schemaObject struct {
+	name string
+	sql  string
+	kind int
+	ref  *regexp.Regexp
+	deps []*schemaObject
+
+	level    int
+	visiting bool
+	visited  bool
+}
+
+// newSchemaObject creates new schema object out of its name and CREATE statement
+func newSchemaObject(name, sql string) *schemaObject {
+	obj := &schemaObject{
+		name: name,
+		sql:  sql,
+		kind: getSchemaObjectKind(sql),
+	}
+	obj.ref = newSchemaObjectRefRegexp(obj)
+	return obj
+}
+
+// getSchemaObjectKind gets kind of the object created by the CREATE statement
+func getSchemaObjectKind(sql string) int {
+	statement := strings.ToUpper(strings.TrimSpace(sql))
+	switch {
+	case strings.HasPrefix(statement, "CREATE DATABASE"):
+		return schemaObjectKindDatabase
+	case strings.HasPrefix(statement, "CREATE FUNCTION"):
+		return schemaObjectKindFunction
+	case strings.HasPrefix(statement, "CREATE DICTIONARY"):
+		return schemaObjectKindDictionary
+	case strings.HasPrefix(statement, "CREATE TABLE"):
+		return schemaObjectKindTable
+	}
+	// VIEW, MATERIALIZED VIEW, LIVE VIEW, WINDOW VIEW
+	return schemaObjectKindView
+}
+
+// newSchemaObjectRefRegexp creates regexp matching references to the object within other CREATE statements.
+// Tables, dictionaries and views are referenced by qualified name, quoted or not, functions are referenced by call
+func newSchemaObjectRefRegexp(obj *schemaObject) *regexp.Regexp {
+	quoted := func(name string) string {
+		return "[`\"]?" + regexp.QuoteMeta(name) + "[`\"]?"
+	}
+	switch obj.kind {
+	case schemaObjectKindDatabase:
+		// Database is not referenced explicitly, objects depend on databases by kind
+		return nil
+	case schemaObjectKindFunction:
+		return regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(obj.name) + `\s*\(`)
+	}
+	parts := strings.SplitN(obj.name, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	return regexp.MustCompile(`(^|[^\w.])` + quoted(parts[0]) + `\.` + quoted(parts[1]) + `($|[^\w])`)
+}
+
+// isReferencedBy checks whether the object is referenced by CREATE statement of another object
+func (obj *schemaObject) isReferencedBy(another *schemaObject) bool {
+	if (obj == another) || (obj.ref == nil) {
+		return false
+	}
+	// Cheap check first, since regexp matching over all pairs of objects is expensive
+	name := obj.name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if !strings.Contains(another.sql, name) {
+		return false
+	}
+	return obj.ref.MatchString(another.sql)
+}
+
+// resolveLevel calculates level of the object, so the object is created after all objects of lower levels.
+// Level is not lower than kind of the object and is higher than levels of all objects the object depends on.
+// Circular dependencies are ignored, the object is retried in case creation fails
+func (obj *schemaObject) resolveLevel() int {
+	if obj.visited || obj.visiting {
+		return obj.level
+	}
+	obj.visiting = true
+	obj.level = obj.kind
+	for _, dep := range obj.deps {
+		if level := dep.resolveLevel() + 1; level > obj.level {
+			obj.level = level
+		}
+	}
+	obj.visiting = false
+	obj.visited = true
+	return obj.level
+}
+
+// orderSchemaObjects groups CREATE statements into levels, so statements of each level depend on
+// statements of preceding levels only and statements within a level may be executed concurrently.
+// Duplicate objects are created once
+func orderSchemaObjects(names, sqls []string) (levels [][]*schemaObject) {
+	var objects []*schemaObject
+	seen := make(map[string]bool)
+	for i := range names {
+		if (i >= len(sqls)) || seen[names[i]] {
+			continue
+		}
+		seen[names[i]] = true
+		objects = append(objects, newSchemaObject(names[i], sqls[i]))
+	}
+
+	for _, obj := range objects {
+		for _, dep := range objects {
+			if dep.isReferencedBy(obj) {
+				obj.deps = append(obj.deps, dep)
+			}
+		}
+	}
+
+	for _, obj := range objects {
+		level := obj.resolveLevel()
+		for len(levels) <= level {
+			levels = append(levels, nil)
+		}
+		levels[level] = append(levels[level], obj)
+	}
+
+	// Drop empty levels
+	var result [][]*schemaObject
+	for _, level := range levels {
+		if len(level) > 0 {
+			result = append(result, level)
+		}
+	}
+	return result
+}
+
+// execHostSchemaObjects creates schema objects on the host level by level, objects of a level are created concurrently.
+// Objects failed to be created are retried all together in the end, since dependency may be not recognized
+func (s *ClusterSchemer) execHostSchemaObjects(ctx context.Context, host *api.ChiHost, levels [][]*schemaObject) error {
+	var failed []string
+	for _, level := range levels {
+		if util.IsContextDone(ctx) {
+			log.V(2).Info("ctx is done")
+			return nil
+		}
+		failed = append(failed, s.execHostSchemaObjectsLevel(ctx, host, level)...)
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	log.V(1).M(host).F().Info("Retry schema objects failed to be created at %s: %d objects", host.Runtime.Address.HostName, len(failed))
+	return s.ExecHost(ctx, host, failed, s.newDDLQueryOptions().SetRetry(true))
+}
+
+// execHostSchemaObjectsLevel creates independent schema objects on the host concurrently.
+// Returns CREATE statements failed
+func (s *ClusterSchemer) execHostSchemaObjectsLevel(ctx context.Context, host *api.ChiHost, level []*schemaObject) (failed []string) {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	objects := make(chan *schemaObject)
+
+	workers := schemaObjectsParallelism
+	if len(level) < workers {
+		workers = len(level)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Cluster object is not safe for concurrent use, so each worker has its own one
+			cluster := NewCluster().
+				SetClusterConnectionParams(s.ClusterConnectionParams).
+				SetEndpointsGetter(s.endpoints).
+				SetQueryObserver(s.observer).
+				SetSlowQueryObserver(s.slowObserver)
+			for obj := range objects {
+				// Single attempt only - failed objects are retried after all levels are created
+				err := cluster.ExecHost(ctx, host, []string{obj.sql}, s.newDDLQueryOptions().SetRetry(false))
+				if err != nil {
+					log.V(1).M(host).F().Warning("Unable to create %s at %s, retry later. err: %v", obj.name, host.Runtime.Address.HostName, err)
+					lock.Lock()
+					failed = append(failed, obj.sql)
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, obj := range level {
+		objects <- obj
+	}
+	close(objects)
+	wg.Wait()
+
+	return failed
+}
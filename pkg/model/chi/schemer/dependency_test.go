@@ -0,0 +1,108 @@
+package schemer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// schemaObjectsLevels describes levels of the objects by names of the objects
+func schemaObjectsLevels(levels [][]*schemaObject) (names [][]string) {
+	for _, level := range levels {
+		var _names []string
+		for _, obj := range level {
+			_names = append(_names, obj.name)
+		}
+		names = append(names, _names)
+	}
+	return names
+}
+
+func TestOrderSchemaObjects(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		objects  [][2]string
+		expected [][]string
+	}{
+		{
+			name: "kinds",
+			objects: [][2]string{
+				{"db.v", "CREATE VIEW db.v AS SELECT 1"},
+				{"db.d", "CREATE DICTIONARY db.d (id UInt64) PRIMARY KEY id SOURCE(NULL()) LAYOUT(FLAT()) LIFETIME(0)"},
+				{"db.t", "CREATE TABLE db.t (a UInt64) ENGINE = MergeTree ORDER BY a"},
+				{"f", "CREATE FUNCTION f AS (x) -> x + 1"},
+				{"db", "CREATE DATABASE db"},
+			},
+			expected: [][]string{{"db"}, {"f"}, {"db.t"}, {"db.d"}, {"db.v"}},
+		},
+		{
+			name: "chain of views",
+			objects: [][2]string{
+				{"db.v2", "CREATE VIEW db.v2 AS SELECT * FROM db.v1"},
+				{"db.v1", "CREATE MATERIALIZED VIEW db.v1 TO db.t2 AS SELECT * FROM db.t1"},
+				{"db.t1", "CREATE TABLE db.t1 (a UInt64) ENGINE = MergeTree ORDER BY a"},
+				{"db.t2", "CREATE TABLE db.t2 AS db.t1"},
+			},
+			expected: [][]string{{"db.t1"}, {"db.t2"}, {"db.v1"}, {"db.v2"}},
+		},
+		{
+			name: "cross database",
+			objects: [][2]string{
+				{"db1", "CREATE DATABASE db1"},
+				{"db2", "CREATE DATABASE db2"},
+				{"db2.t", "CREATE TABLE db2.t AS db1.t ENGINE = MergeTree ORDER BY a"},
+				{"db1.t", "CREATE TABLE db1.t (a UInt64) ENGINE = MergeTree ORDER BY a"},
+				{"db1.v", "CREATE VIEW db1.v AS SELECT * FROM `db2`.`t`"},
+				{"db2.v", "CREATE VIEW db2.v AS SELECT * FROM \"db1\".\"v\""},
+			},
+			expected: [][]string{{"db1", "db2"}, {"db1.t"}, {"db2.t"}, {"db1.v"}, {"db2.v"}},
+		},
+		{
+			name: "names sharing prefix",
+			objects: [][2]string{
+				{"db.v1", "CREATE VIEW db.v1 AS SELECT 1"},
+				{"db.v10", "CREATE VIEW db.v10 AS SELECT 10"},
+				{"db.v", "CREATE VIEW db.v AS SELECT * FROM db.v10"},
+				{"other.v", "CREATE VIEW other.v AS SELECT * FROM otherdb.v1"},
+			},
+			expected: [][]string{{"db.v1", "db.v10", "other.v"}, {"db.v"}},
+		},
+		{
+			name: "functions",
+			objects: [][2]string{
+				{"db.v", "CREATE VIEW db.v AS SELECT f2(1)"},
+				{"f2", "CREATE FUNCTION f2 AS (x) -> f1(x) + 1"},
+				{"f1", "CREATE FUNCTION f1 AS (x) -> x + 1"},
+				{"myf1", "CREATE FUNCTION myf1 AS (x) -> x"},
+			},
+			expected: [][]string{{"f1", "myf1"}, {"f2"}, {"db.v"}},
+		},
+		{
+			name: "cycle",
+			objects: [][2]string{
+				{"db.a", "CREATE VIEW db.a AS SELECT * FROM db.b"},
+				{"db.b", "CREATE VIEW db.b AS SELECT * FROM db.a"},
+				{"db.c", "CREATE VIEW db.c AS SELECT * FROM db.a"},
+			},
+			expected: [][]string{{"db.b"}, {"db.a"}, {"db.c"}},
+		},
+		{
+			name: "duplicates",
+			objects: [][2]string{
+				{"db", "CREATE DATABASE db"},
+				{"db.t", "CREATE TABLE db.t (a UInt64) ENGINE = MergeTree ORDER BY a"},
+				{"db", "CREATE DATABASE db"},
+			},
+			expected: [][]string{{"db"}, {"db.t"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var names, sqls []string
+			for _, object := range test.objects {
+				names = append(names, object[0])
+				sqls = append(sqls, object[1])
+			}
+			require.Equal(t, test.expected, schemaObjectsLevels(orderSchemaObjects(names, sqls)))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/MakeNowJust/heredoc"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+)
+
+// DetachedPart describes part listed in system.detached_parts
+type DetachedPart struct {
+	Database string
+	Table    string
+	Name     string
+	// Reason is empty in case part is detached by user
+	Reason string
+	// Replicated specifies part belongs to Replicated* table, so data of the part can be fetched from other replicas
+	Replicated bool
+	// AgeDays specifies how many days ago the part was detached. Negative in case age is not known
+	AgeDays int
+}
+
+// sqlDetachedParts returns SQL to list detached parts of all tables
+func (s *ClusterSchemer) sqlDetachedParts() string {
+	// system.detached_parts has no modification_time column in older versions, age is unknown there
+	age := "'-1'"
+	if s.version.Matches(">= 23.4") {
+		age = "toString(dateDiff('day', modification_time, now()))"
+	}
+	return heredoc.Docf(`
+		SELECT
+			database,
+			table,
+			name,
+			ifNull(reason, '') AS reason,
+			toString((database, table) IN (
+				SELECT database, name FROM system.tables WHERE engine LIKE 'Replicated%%'
+			)) AS replicated,
+			%s AS age
+		FROM
+			system.detached_parts
+		WHERE
+			database != '' AND table != ''
+		ORDER BY
+			database, table, name
+		`,
+		age,
+	)
+}
+
+// sqlDropDetachedPart returns SQL to drop detached part
+func (s *ClusterSchemer) sqlDropDetachedPart(part DetachedPart) string {
+	return fmt.Sprintf(
+		"ALTER TABLE %s.%s DROP DETACHED PART %s SETTINGS allow_drop_detached = 1",
+		clickhouse.QuoteIdentifier(part.Database),
+		clickhouse.QuoteIdentifier(part.Table),
+		clickhouse.Quote(part.Name),
+	)
+}
+
+// HostDetachedParts lists detached parts of the host
+func (s *ClusterSchemer) HostDetachedParts(ctx context.Context, host *api.ChiHost) ([]DetachedPart, error) {
+	query, err := s.QueryHost(ctx, host, s.sqlDetachedParts())
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, nil
+	}
+	var databases, tables, names, reasons, replicated, ages []string
+	err = query.UnzipColumnsAsStrings(&databases, &tables, &names, &reasons, &replicated, &ages)
+	query.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]DetachedPart, len(names))
+	for i := range names {
+		age, err := strconv.Atoi(ages[i])
+		if err != nil {
+			age = -1
+		}
+		parts[i] = DetachedPart{
+			Database:   databases[i],
+			Table:      tables[i],
+			Name:       names[i],
+			Reason:     reasons[i],
+			Replicated: replicated[i] == "1",
+			AgeDays:    age,
+		}
+	}
+	return parts, nil
+}
+
+// HostDropDetachedParts drops specified detached parts of the host. Returns number of parts dropped
+func (s *ClusterSchemer) HostDropDetachedParts(ctx context.Context, host *api.ChiHost, parts []DetachedPart) (int, error) {
+	dropped := 0
+	opts := clickhouse.NewQueryOptions().SetRetry(false)
+	for _, part := range parts {
+		if err := s.ExecHost(ctx, host, []string{s.sqlDropDetachedPart(part)}, opts); err != nil {
+			return dropped, err
+		}
+		log.V(1).M(host).F().Info("Dropped detached part %s of %s.%s reason: %s", part.Name, part.Database, part.Table, part.Reason)
+		dropped++
+	}
+	return dropped, nil
+}
@@ -56,21 +56,21 @@ func (s *ClusterSchemer) getDistributedObjectsSQLs(ctx context.Context, host *ap
 	databaseNames, createDatabaseSQLs := debugCreateSQLs(
 		s.QueryUnzip2Columns(
 			ctx,
-			model.CreateFQDNs(host, api.ClickHouseInstallation{}, false),
+			getHosts(host, api.ClickHouseInstallation{}),
 			s.sqlCreateDatabaseDistributed(host.Runtime.Address.ClusterName),
 		),
 	)
 	tableNames, createTableSQLs := debugCreateSQLs(
 		s.QueryUnzipAndApplyUUIDs(
 			ctx,
-			model.CreateFQDNs(host, api.ClickHouseInstallation{}, false),
+			getHosts(host, api.ClickHouseInstallation{}),
 			s.sqlCreateTableDistributed(host.Runtime.Address.ClusterName),
 		),
 	)
 	functionNames, createFunctionSQLs := debugCreateSQLs(
 		s.QueryUnzip2Columns(
 			ctx,
-			model.CreateFQDNs(host, api.ClickHouseInstallation{}, false),
+			getHosts(host, api.ClickHouseInstallation{}),
 			s.sqlCreateFunction(host.Runtime.Address.ClusterName),
 		),
 	)
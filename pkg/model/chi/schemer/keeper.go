@@ -0,0 +1,103 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+)
+
+// restoreReplicaTimeout specifies timeout of restoring metadata of one replicated table,
+// which is proportional to the number of parts the table has
+const restoreReplicaTimeout = 10 * time.Minute
+
+// sqlKeeperAvailable returns SQL, which fails in case the host has no session with keeper
+func (s *ClusterSchemer) sqlKeeperAvailable() string {
+	return `SELECT count() FROM system.zookeeper WHERE path = '/'`
+}
+
+// sqlReadOnlyReplicas returns SQL to list replicated tables, which are read-only while having keeper session.
+// Such tables have no metadata in keeper, as it happens after the host is switched to the new keeper
+func (s *ClusterSchemer) sqlReadOnlyReplicas() string {
+	return heredoc.Doc(`
+		SELECT
+			concat(database, '.', table) AS name,
+			concat('"', database, '"."', table, '"') AS quoted
+		FROM
+			system.replicas
+		WHERE
+			is_readonly AND NOT is_session_expired
+		ORDER BY
+			name
+		`,
+	)
+}
+
+// sqlRestoreReplica returns set of SQLs re-creating keeper metadata of the replicated table out of the local data
+func (s *ClusterSchemer) sqlRestoreReplica(table string) []string {
+	return []string{
+		fmt.Sprintf("SYSTEM RESTART REPLICA %s", table),
+		fmt.Sprintf("SYSTEM RESTORE REPLICA %s", table),
+	}
+}
+
+// HostKeeperAvailable checks whether the host has session with keeper
+func (s *ClusterSchemer) HostKeeperAvailable(ctx context.Context, host *api.ChiHost) bool {
+	_, err := s.QueryHostInt(ctx, host, s.sqlKeeperAvailable(), clickhouse.NewQueryOptions().SetSilent(true))
+	return err == nil
+}
+
+// HostRestoreReplicas restores keeper metadata of read-only replicated tables of the host out of the local data.
+// Returns names of tables restored
+func (s *ClusterSchemer) HostRestoreReplicas(ctx context.Context, host *api.ChiHost) ([]string, error) {
+	query, err := s.QueryHost(ctx, host, s.sqlReadOnlyReplicas())
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, nil
+	}
+	var names, tables []string
+	err = query.UnzipColumnsAsStrings(&names, &tables)
+	query.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	log.V(1).M(host).F().Info("Restore replicas: %v", names)
+	opts := clickhouse.NewQueryOptions().SetRetry(false)
+	opts.SetQueryTimeout(restoreReplicaTimeout)
+	var restored, failed []string
+	for i := range tables {
+		if err := s.ExecHost(ctx, host, s.sqlRestoreReplica(tables[i]), opts); err != nil {
+			log.V(1).M(host).F().Warning("unable to restore replica %s err: %v", names[i], err)
+			failed = append(failed, names[i])
+			continue
+		}
+		restored = append(restored, names[i])
+	}
+	if len(failed) > 0 {
+		return restored, fmt.Errorf("unable to restore replicas: %s", strings.Join(failed, ", "))
+	}
+	return restored, nil
+}
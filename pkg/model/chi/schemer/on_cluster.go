@@ -0,0 +1,260 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/MakeNowJust/heredoc"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+const (
+	// onClusterDefaultTimeout specifies how long distributed DDL is waited for in case schemer has no DDL timeout specified
+	onClusterDefaultTimeout = 180 * time.Second
+	// onClusterPollInterval specifies how often system.distributed_ddl_queue is polled
+	onClusterPollInterval = 5 * time.Second
+)
+
+// Statuses of distributed DDL entry on a host, as listed in system.distributed_ddl_queue
+const (
+	DistributedDDLStatusInactive = "Inactive"
+	DistributedDDLStatusActive   = "Active"
+	DistributedDDLStatusFinished = "Finished"
+)
+
+// onClusterRegexp matches head of DDL query up to and including name of the object the query is about,
+// so 'ON CLUSTER' clause is to be inserted right after it
+var onClusterRegexp = regexp.MustCompile(
+	`(?is)^\s*(?:CREATE(?:\s+OR\s+REPLACE)?|ATTACH|DROP|DETACH|ALTER|TRUNCATE|OPTIMIZE)\s+` +
+		`(?:TEMPORARY\s+)?(?:TABLE|DATABASE|(?:MATERIALIZED\s+|LIVE\s+)?VIEW|DICTIONARY|FUNCTION)\s+` +
+		`(?:IF\s+(?:NOT\s+)?EXISTS\s+)?` +
+		"(?:`[^`]*`|\"[^\"]*\"|[^\\s(`\".]+)(?:\\.(?:`[^`]*`|\"[^\"]*\"|[^\\s(`\".]+))?",
+)
+
+// hasOnClusterRegexp matches DDL query having 'ON CLUSTER' clause already
+var hasOnClusterRegexp = regexp.MustCompile(`(?i)\sON\s+CLUSTER\s`)
+
+// DistributedDDLResult describes execution of distributed DDL query on hosts of the cluster
+type DistributedDDLResult struct {
+	// Entry specifies entry of the distributed DDL queue, such as query-0000000042
+	Entry string
+	// Finished lists hosts which completed the query successfully
+	Finished []string
+	// Failed lists hosts which completed the query with an exception, along with the exception
+	Failed map[string]string
+	// Stragglers lists hosts which did not complete the query in time
+	Stragglers []string
+}
+
+// IsCompleted checks whether the query is completed successfully by all hosts
+func (r *DistributedDDLResult) IsCompleted() bool {
+	if r == nil {
+		return false
+	}
+	return (len(r.Failed) == 0) && (len(r.Stragglers) == 0)
+}
+
+// String describes the result for logs and events
+func (r *DistributedDDLResult) String() string {
+	if r == nil {
+		return ""
+	}
+	var failed []string
+	for host, exception := range r.Failed {
+		failed = append(failed, host+": "+exception)
+	}
+	return fmt.Sprintf("entry: %s finished: %d failed: [%s] stragglers: [%s]",
+		r.Entry, len(r.Finished), strings.Join(failed, ", "), strings.Join(r.Stragglers, ", "))
+}
+
+// sqlOnCluster adds 'ON CLUSTER' clause to DDL query. Query having 'ON CLUSTER' clause already is kept as is
+func sqlOnCluster(sql, cluster string) (string, error) {
+	if hasOnClusterRegexp.MatchString(sql) {
+		return sql, nil
+	}
+	loc := onClusterRegexp.FindStringIndex(sql)
+	if loc == nil {
+		return "", fmt.Errorf("unable to add ON CLUSTER clause to: %s", sql)
+	}
+	return sql[:loc[1]] + " ON CLUSTER " + clickhouse.Quote(cluster) + sql[loc[1]:], nil
+}
+
+// sqlDistributedDDLQueue returns SQL to list statuses of distributed DDL entries of the cluster
+// created since the specified time and initiated by the host the SQL is run on
+func (s *ClusterSchemer) sqlDistributedDDLQueue(cluster string, since time.Time) string {
+	return heredoc.Docf(`
+		SELECT
+			entry,
+			query,
+			host,
+			toString(status) AS status,
+			toString(ifNull(exception_code, 0)) AS code,
+			ifNull(exception_text, '') AS exception
+		FROM
+			system.distributed_ddl_queue
+		WHERE
+			cluster = %s
+			AND query_create_time >= toDateTime(%d)
+			AND initiator_host IN (hostName(), FQDN())
+			AND initiator_port = tcpPort()
+		ORDER BY
+			entry, host
+		`,
+		clickhouse.Quote(cluster),
+		since.Unix(),
+	)
+}
+
+// normalizeDDL strips whitespace, quotes and case of the query, so the query as sent by the operator
+// is comparable to the query as formatted by ClickHouse in distributed DDL queue
+func normalizeDDL(sql string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', '`', '"', '\'':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, sql)
+}
+
+// selectDistributedDDLEntry selects entry of the query out of the entries initiated by the host.
+// Entry with the same query is selected. In case no entry has the same query, since ClickHouse rewrites
+// the query on formatting, the only entry is selected. Entries initiated by other clients concurrently
+// make the entry ambiguous, so no entry is selected then.
+func selectDistributedDDLEntry(sql string, entries, queries []string) (string, error) {
+	var candidates []string
+	for i := range entries {
+		if normalizeDDL(queries[i]) == normalizeDDL(sql) {
+			return entries[i], nil
+		}
+		if !util.InArray(entries[i], candidates) {
+			candidates = append(candidates, entries[i])
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", nil
+	case 1:
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("unable to tell distributed DDL entry of the query among concurrent entries: %s", strings.Join(candidates, ", "))
+}
+
+// hostDistributedDDLQueue fetches statuses of distributed DDL entry of the query as seen by the initiator host.
+// Nil result is returned in case no entry is found
+func (s *ClusterSchemer) hostDistributedDDLQueue(ctx context.Context, host *api.ChiHost, cluster, sql string, since time.Time) (*DistributedDDLResult, error) {
+	query, err := s.QueryHost(ctx, host, s.sqlDistributedDDLQueue(cluster, since), clickhouse.NewQueryOptions().SetSilent(true))
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, nil
+	}
+	var entries, queries, hosts, statuses, codes, exceptions []string
+	err = query.UnzipColumnsAsStrings(&entries, &queries, &hosts, &statuses, &codes, &exceptions)
+	query.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := selectDistributedDDLEntry(sql, entries, queries)
+	if (err != nil) || (entry == "") {
+		return nil, err
+	}
+
+	result := &DistributedDDLResult{
+		Entry:  entry,
+		Failed: make(map[string]string),
+	}
+	for i := range hosts {
+		switch {
+		case entries[i] != entry:
+			continue
+		case codes[i] != "0":
+			result.Failed[hosts[i]] = exceptions[i]
+		case statuses[i] == DistributedDDLStatusFinished:
+			result.Finished = append(result.Finished, hosts[i])
+		default:
+			result.Stragglers = append(result.Stragglers, hosts[i])
+		}
+	}
+	return result, nil
+}
+
+// ClusterExecOnCluster runs DDL query with 'ON CLUSTER' clause over the cluster, generated name of the cluster is used.
+// Query is initiated on the first host of the cluster, then system.distributed_ddl_queue is polled for completion
+// of the query on each host of the cluster. Hosts not completed the query in time are reported as stragglers
+func (s *ClusterSchemer) ClusterExecOnCluster(ctx context.Context, cluster *api.Cluster, sql string) (*DistributedDDLResult, error) {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("ctx is done")
+		return nil, nil
+	}
+
+	sql, err := sqlOnCluster(sql, cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	host := cluster.FirstHost()
+	if host == nil {
+		return nil, fmt.Errorf("cluster %s has no hosts", cluster.Name)
+	}
+
+	timeout := s.ddlTimeout
+	if timeout == 0 {
+		timeout = onClusterDefaultTimeout
+	}
+	// Entry creation time is tracked with seconds precision
+	since := time.Now().Truncate(time.Second)
+	deadline := time.Now().Add(timeout)
+
+	// Initiator waits for the query to complete on all hosts on its own and fails in case of stragglers,
+	// thus the error is not final - completion is checked via the queue anyway
+	opts := s.newDDLQueryOptions().SetRetry(false)
+	opts.SetQueryTimeout(timeout)
+	execErr := s.ExecHost(ctx, host, []string{sql}, opts)
+
+	for {
+		result, err := s.hostDistributedDDLQueue(ctx, host, cluster.Name, sql, since)
+		switch {
+		case err != nil:
+			return nil, err
+		case result == nil:
+			if execErr != nil {
+				return nil, execErr
+			}
+			return nil, fmt.Errorf("no distributed DDL entry found for cluster %s", cluster.Name)
+		case len(result.Stragglers) == 0:
+			log.V(1).M(host).F().Info("Distributed DDL completed on cluster %s: %s", cluster.Name, result)
+			return result, nil
+		case time.Now().After(deadline):
+			log.V(1).M(host).F().Warning("Distributed DDL is not completed in time on cluster %s: %s", cluster.Name, result)
+			return result, nil
+		}
+		if util.WaitContextDoneOrTimeout(ctx, onClusterPollInterval) {
+			log.V(2).Info("ctx is done")
+			return result, nil
+		}
+	}
+}
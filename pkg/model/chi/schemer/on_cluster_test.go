@@ -0,0 +1,137 @@
+package schemer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLOnCluster(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		sql      string
+		cluster  string
+		expected string
+	}{
+		{
+			name:     "create table",
+			sql:      "CREATE TABLE db.t (a UInt64) ENGINE = MergeTree ORDER BY a",
+			cluster:  "all",
+			expected: "CREATE TABLE db.t ON CLUSTER 'all' (a UInt64) ENGINE = MergeTree ORDER BY a",
+		},
+		{
+			name:     "create table if not exists w/o database",
+			sql:      "create table if not exists t (a UInt64) ENGINE = Memory",
+			cluster:  "all",
+			expected: "create table if not exists t ON CLUSTER 'all' (a UInt64) ENGINE = Memory",
+		},
+		{
+			name:     "backtick quoted names",
+			sql:      "ALTER TABLE `my db`.`my table` ADD COLUMN b String",
+			cluster:  "all",
+			expected: "ALTER TABLE `my db`.`my table` ON CLUSTER 'all' ADD COLUMN b String",
+		},
+		{
+			name:     "double quoted names",
+			sql:      `DROP TABLE IF EXISTS "db"."t.1"`,
+			cluster:  "all",
+			expected: `DROP TABLE IF EXISTS "db"."t.1" ON CLUSTER 'all'`,
+		},
+		{
+			name:     "materialized view",
+			sql:      "CREATE MATERIALIZED VIEW db.mv TO db.t AS SELECT 1",
+			cluster:  "all",
+			expected: "CREATE MATERIALIZED VIEW db.mv ON CLUSTER 'all' TO db.t AS SELECT 1",
+		},
+		{
+			name:     "database",
+			sql:      "CREATE DATABASE IF NOT EXISTS db",
+			cluster:  "all",
+			expected: "CREATE DATABASE IF NOT EXISTS db ON CLUSTER 'all'",
+		},
+		{
+			name:     "cluster name with quote is escaped",
+			sql:      "DROP TABLE db.t",
+			cluster:  `it's`,
+			expected: `DROP TABLE db.t ON CLUSTER 'it\'s'`,
+		},
+		{
+			name:     "cluster name with backslash is escaped",
+			sql:      "DROP TABLE db.t",
+			cluster:  `a\'b`,
+			expected: `DROP TABLE db.t ON CLUSTER 'a\\\'b'`,
+		},
+		{
+			name:     "on cluster is kept as is",
+			sql:      "CREATE TABLE db.t ON CLUSTER 'other' (a UInt64) ENGINE = Memory",
+			cluster:  "all",
+			expected: "CREATE TABLE db.t ON CLUSTER 'other' (a UInt64) ENGINE = Memory",
+		},
+		{
+			name:     "on cluster in lower case is kept as is",
+			sql:      "drop table db.t on cluster all",
+			cluster:  "all",
+			expected: "drop table db.t on cluster all",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := sqlOnCluster(test.sql, test.cluster)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestSQLOnClusterUnsupported(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT 1",
+		"INSERT INTO db.t VALUES (1)",
+		"",
+	} {
+		_, err := sqlOnCluster(sql, "all")
+		require.Error(t, err, sql)
+	}
+}
+
+func TestSelectDistributedDDLEntry(t *testing.T) {
+	sql := "CREATE TABLE db.t ON CLUSTER 'all' (a UInt64) ENGINE = Memory"
+
+	// Query formatted by ClickHouse matches the query sent
+	entry, err := selectDistributedDDLEntry(sql,
+		[]string{"query-0000000001", "query-0000000001", "query-0000000002", "query-0000000002"},
+		[]string{
+			"CREATE TABLE db.other ON CLUSTER all (`a` UInt64) ENGINE = Memory",
+			"CREATE TABLE db.other ON CLUSTER all (`a` UInt64) ENGINE = Memory",
+			"CREATE TABLE db.t ON CLUSTER all\n(\n    `a` UInt64\n)\nENGINE = Memory",
+			"CREATE TABLE db.t ON CLUSTER all\n(\n    `a` UInt64\n)\nENGINE = Memory",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "query-0000000002", entry)
+
+	// The only entry is selected even though query is rewritten by ClickHouse
+	entry, err = selectDistributedDDLEntry("CREATE TABLE t ON CLUSTER 'all' (a UInt64) ENGINE = Memory",
+		[]string{"query-0000000003", "query-0000000003"},
+		[]string{
+			"CREATE TABLE default.t ON CLUSTER all (`a` UInt64) ENGINE = Memory",
+			"CREATE TABLE default.t ON CLUSTER all (`a` UInt64) ENGINE = Memory",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "query-0000000003", entry)
+
+	// Concurrent entries w/o the same query are ambiguous
+	_, err = selectDistributedDDLEntry(sql,
+		[]string{"query-0000000004", "query-0000000005"},
+		[]string{
+			"DROP TABLE db.x ON CLUSTER all",
+			"DROP TABLE db.y ON CLUSTER all",
+		},
+	)
+	require.Error(t, err)
+
+	// No entries
+	entry, err = selectDistributedDDLEntry(sql, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "", entry)
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/MakeNowJust/heredoc"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+)
+
+// PartsBacklogPartition describes partition having number of active parts over the threshold
+type PartsBacklogPartition struct {
+	Database string
+	Table    string
+	// PartitionID specifies partition as listed in system.parts
+	PartitionID string
+	Parts       int
+	// Merges specifies number of merges running on the partition
+	Merges int
+}
+
+// sqlPartsBacklog returns SQL to list partitions having number of active parts over the threshold.
+// Partitions without merges have no row in system.merges, which is NULL in case join_use_nulls is set.
+func (s *ClusterSchemer) sqlPartsBacklog(threshold int) string {
+	return heredoc.Docf(`
+		SELECT
+			p.database,
+			p.table,
+			p.partition_id,
+			toString(p.parts) AS parts,
+			toString(ifNull(m.merges, 0)) AS merges
+		FROM
+		(
+			SELECT database, table, partition_id, count() AS parts
+			FROM system.parts
+			WHERE active AND database NOT IN ('system', 'information_schema', 'INFORMATION_SCHEMA')
+			GROUP BY database, table, partition_id
+			HAVING parts > %d
+		) AS p
+		LEFT JOIN
+		(
+			SELECT database, table, partition_id, count() AS merges
+			FROM system.merges
+			GROUP BY database, table, partition_id
+		) AS m
+		USING (database, table, partition_id)
+		ORDER BY
+			p.parts DESC
+		`,
+		threshold,
+	)
+}
+
+// sqlOptimizePartition returns SQL to merge parts of the partition
+func (s *ClusterSchemer) sqlOptimizePartition(partition PartsBacklogPartition) string {
+	return fmt.Sprintf(
+		"OPTIMIZE TABLE %s.%s PARTITION ID %s FINAL",
+		clickhouse.QuoteIdentifier(partition.Database),
+		clickhouse.QuoteIdentifier(partition.Table),
+		clickhouse.Quote(partition.PartitionID),
+	)
+}
+
+// HostPartsBacklog lists partitions of the host having number of active parts over the threshold
+func (s *ClusterSchemer) HostPartsBacklog(ctx context.Context, host *api.ChiHost, threshold int) ([]PartsBacklogPartition, error) {
+	query, err := s.QueryHost(ctx, host, s.sqlPartsBacklog(threshold))
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, nil
+	}
+	var databases, tables, partitionIDs, parts, merges []string
+	err = query.UnzipColumnsAsStrings(&databases, &tables, &partitionIDs, &parts, &merges)
+	query.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]PartsBacklogPartition, len(partitionIDs))
+	for i := range partitionIDs {
+		p, _ := strconv.Atoi(parts[i])
+		m, _ := strconv.Atoi(merges[i])
+		partitions[i] = PartsBacklogPartition{
+			Database:    databases[i],
+			Table:       tables[i],
+			PartitionID: partitionIDs[i],
+			Parts:       p,
+			Merges:      m,
+		}
+	}
+	return partitions, nil
+}
+
+// HostOptimizePartitions merges parts of specified partitions of the host. Returns number of partitions optimized
+func (s *ClusterSchemer) HostOptimizePartitions(ctx context.Context, host *api.ChiHost, partitions []PartsBacklogPartition) (int, error) {
+	optimized := 0
+	opts := clickhouse.NewQueryOptions().SetRetry(false)
+	for _, partition := range partitions {
+		if err := s.ExecHost(ctx, host, []string{s.sqlOptimizePartition(partition)}, opts); err != nil {
+			return optimized, err
+		}
+		log.V(1).M(host).F().Info("Optimized partition %s of %s.%s parts: %d", partition.PartitionID, partition.Database, partition.Table, partition.Parts)
+		optimized++
+	}
+	return optimized, nil
+}
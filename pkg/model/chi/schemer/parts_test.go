@@ -0,0 +1,31 @@
+package schemer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLDropDetachedPart(t *testing.T) {
+	s := &ClusterSchemer{}
+	require.Equal(t,
+		"ALTER TABLE `db`.`t\\`x` DROP DETACHED PART 'all_1_1_0\\'' SETTINGS allow_drop_detached = 1",
+		s.sqlDropDetachedPart(DetachedPart{
+			Database: "db",
+			Table:    "t`x",
+			Name:     "all_1_1_0'",
+		}),
+	)
+}
+
+func TestSQLOptimizePartition(t *testing.T) {
+	s := &ClusterSchemer{}
+	require.Equal(t,
+		"OPTIMIZE TABLE `db\\`x`.`t\"1` PARTITION ID '2024\\'01' FINAL",
+		s.sqlOptimizePartition(PartsBacklogPartition{
+			Database:    "db`x",
+			Table:       `t"1`,
+			PartitionID: "2024'01",
+		}),
+	)
+}
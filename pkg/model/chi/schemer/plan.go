@@ -0,0 +1,208 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemer
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// SchemaPlan describes schema objects to be created on a host, so the host gets the same schema as other hosts have.
+// Plan is a diff between objects found on other hosts and objects already existing on the host.
+type SchemaPlan struct {
+	// levels of objects to be created, objects of a level depend on objects of preceding levels only
+	levels [][]*schemaObject
+	// Existing lists objects, which already exist on the host
+	Existing []string
+	// Differs lists objects, which exist on the host with definition different from the one on other hosts
+	Differs []string
+}
+
+// GetNames gets names of the objects to be created in the order of creation
+func (p *SchemaPlan) GetNames() (names []string) {
+	if p == nil {
+		return nil
+	}
+	for _, level := range p.levels {
+		for _, obj := range level {
+			names = append(names, obj.name)
+		}
+	}
+	return names
+}
+
+// GetSQLs gets statements to be executed in the order of execution
+func (p *SchemaPlan) GetSQLs() (sqls []string) {
+	if p == nil {
+		return nil
+	}
+	for _, level := range p.levels {
+		for _, obj := range level {
+			sqls = append(sqls, obj.sql)
+		}
+	}
+	return sqls
+}
+
+// IsEmpty checks whether there is nothing to be created
+func (p *SchemaPlan) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	return len(p.levels) == 0
+}
+
+// schemaObjectDefinitionNoise matches parts of CREATE statement, which do not affect definition of the object
+var schemaObjectDefinitionNoise = regexp.MustCompile(`(?i)\s+IF\s+NOT\s+EXISTS|\s+(INNER\s+)?UUID\s+'[^']*'`)
+
+// normalizeSchemaObjectDefinition normalizes CREATE statement, so statements of the same object made on different hosts are equal
+func normalizeSchemaObjectDefinition(sql string) string {
+	return strings.Join(strings.Fields(schemaObjectDefinitionNoise.ReplaceAllString(sql, "")), " ")
+}
+
+// schemaObjectKey makes key of the object, unique among objects of all kinds.
+// Tables, dictionaries and views share the same namespace
+func schemaObjectKey(name, sql string) string {
+	switch getSchemaObjectKind(sql) {
+	case schemaObjectKindDatabase:
+		return "database:" + name
+	case schemaObjectKindFunction:
+		return "function:" + name
+	}
+	return "table:" + name
+}
+
+// newSchemaPlan makes plan out of levels of objects to be created skipping objects existing on the host.
+// existing maps key of the object existing on the host to its CREATE statement
+func newSchemaPlan(levels [][]*schemaObject, existing map[string]string) *SchemaPlan {
+	plan := &SchemaPlan{}
+	for _, level := range levels {
+		var missing []*schemaObject
+		for _, obj := range level {
+			sql, found := existing[schemaObjectKey(obj.name, obj.sql)]
+			switch {
+			case !found:
+				missing = append(missing, obj)
+			case obj.kind == schemaObjectKindDatabase:
+				// Database definition depends on ClickHouse version, so databases are compared by name only
+				plan.Existing = append(plan.Existing, obj.name)
+			case normalizeSchemaObjectDefinition(sql) == normalizeSchemaObjectDefinition(obj.sql):
+				plan.Existing = append(plan.Existing, obj.name)
+			default:
+				plan.Differs = append(plan.Differs, obj.name)
+			}
+		}
+		if len(missing) > 0 {
+			plan.levels = append(plan.levels, missing)
+		}
+	}
+	return plan
+}
+
+// sqlHostSchemaObjects returns SQL to list schema objects existing on the host along with their CREATE statements
+func (s *ClusterSchemer) sqlHostSchemaObjects() string {
+	return heredoc.Docf(`
+		SELECT
+			name,
+			concat('CREATE DATABASE "', name, '" Engine = ', engine)
+		FROM
+			system.databases
+		WHERE
+			name NOT IN (%s)
+		UNION ALL
+		SELECT
+			concat(database, '.', name),
+			create_table_query
+		FROM
+			system.tables
+		WHERE
+			database NOT IN (%s) AND
+			create_table_query != ''
+		UNION ALL
+		SELECT
+			name,
+			create_query
+		FROM
+			system.functions
+		WHERE
+			create_query != ''
+		SETTINGS show_table_uuid_in_table_create_query_if_not_nil=0
+		`,
+		ignoredDBs,
+		ignoredDBs,
+	)
+}
+
+// getHostSchemaObjects gets schema objects existing on the host.
+// Returns map of key of the object to its CREATE statement
+func (s *ClusterSchemer) getHostSchemaObjects(ctx context.Context, host *api.ChiHost) map[string]string {
+	existing := make(map[string]string)
+	names, sqls, err := s.QueryUnzip2Columns(ctx, getHosts(host, api.ChiHost{}), s.sqlHostSchemaObjects())
+	if err != nil {
+		// All objects are considered to be missing, they are created with IF NOT EXISTS anyway
+		log.V(1).M(host).F().Warning("Unable to list schema objects on host %s err: %v", host.Runtime.Address.HostName, err)
+		return existing
+	}
+	for i := range names {
+		if i < len(sqls) {
+			existing[schemaObjectKey(names[i], sqls[i])] = sqls[i]
+		}
+	}
+	return existing
+}
+
+// HostSchemaPlan plans schema objects to be created on the host. Objects include databases, tables, dictionaries,
+// functions, materialized and plain views of non-Replicated databases and distributed tables along with their local tables.
+// Objects are ordered topologically, so each object is created after all objects it depends on.
+func (s *ClusterSchemer) HostSchemaPlan(ctx context.Context, host *api.ChiHost) *SchemaPlan {
+	if util.IsContextDone(ctx) {
+		log.V(2).Info("ctx is done")
+		return nil
+	}
+
+	replicatedObjectNames,
+		replicatedCreateSQLs,
+		distributedObjectNames,
+		distributedCreateSQLs := s.createTablesSQLs(ctx, host)
+
+	// Replicated and distributed objects may depend on each other, so they are created in the same dependency order
+	names := util.ConcatSlices([][]string{replicatedObjectNames, distributedObjectNames})
+	sqls := util.ConcatSlices([][]string{replicatedCreateSQLs, distributedCreateSQLs})
+	if len(sqls) == 0 {
+		return &SchemaPlan{}
+	}
+
+	plan := newSchemaPlan(orderSchemaObjects(names, sqls), s.getHostSchemaObjects(ctx, host))
+	log.V(1).M(host).F().Info(
+		"Schema plan for host %s: create: %v existing: %v differs: %v",
+		host.Runtime.Address.HostName, plan.GetNames(), plan.Existing, plan.Differs)
+	return plan
+}
+
+// HostApplySchemaPlan creates schema objects planned for the host
+func (s *ClusterSchemer) HostApplySchemaPlan(ctx context.Context, host *api.ChiHost, plan *SchemaPlan) error {
+	if plan.IsEmpty() {
+		return nil
+	}
+	log.V(2).M(host).F().Info("\n%v", plan.GetSQLs())
+	return s.execHostSchemaObjects(ctx, host, plan.levels)
+}
@@ -30,6 +30,8 @@ import (
 type ClusterSchemer struct {
 	*Cluster
 	version *swversion.SoftWareVersion
+	// ddlTimeout specifies timeout of schema DDL queries, default one is used in case not specified
+	ddlTimeout time.Duration
 }
 
 // NewClusterSchemer creates new Schemer object
@@ -40,23 +42,74 @@ func NewClusterSchemer(clusterConnectionParams *clickhouse.ClusterConnectionPara
 	}
 }
 
+// SetDDLTimeout sets timeout of schema DDL queries
+func (s *ClusterSchemer) SetDDLTimeout(timeout time.Duration) *ClusterSchemer {
+	if s == nil {
+		return nil
+	}
+	s.ddlTimeout = timeout
+	return s
+}
+
+// newDDLQueryOptions creates query options of schema DDL queries
+func (s *ClusterSchemer) newDDLQueryOptions() *clickhouse.QueryOptions {
+	opts := clickhouse.NewQueryOptions()
+	if s.ddlTimeout > 0 {
+		opts.SetQueryTimeout(s.ddlTimeout)
+	}
+	return opts
+}
+
 // HostSyncTables calls SYSTEM SYNC REPLICA for replicated tables
 func (s *ClusterSchemer) HostSyncTables(ctx context.Context, host *api.ChiHost) error {
 	tableNames, syncTableSQLs, _ := s.sqlSyncTable(ctx, host)
 	log.V(1).M(host).F().Info("Sync tables: %v as %v", tableNames, syncTableSQLs)
-	opts := clickhouse.NewQueryOptions()
-	opts.SetQueryTimeout(120 * time.Second)
+	opts := s.newDDLQueryOptions()
+	if s.ddlTimeout == 0 {
+		opts.SetQueryTimeout(120 * time.Second)
+	}
 	return s.ExecHost(ctx, host, syncTableSQLs, opts)
 }
 
-// HostDropReplica calls SYSTEM DROP REPLICA
+// HostDropReplica calls SYSTEM DROP REPLICA.
+// Replica is named by the value of {replica} macro the dropped host has registered itself with in keeper
 func (s *ClusterSchemer) HostDropReplica(ctx context.Context, hostToRunOn, hostToDrop *api.ChiHost) error {
-	replica := model.CreateInstanceHostname(hostToDrop)
+	replica := model.CreatePodHostname(hostToDrop)
 	shard := hostToRunOn.Runtime.Address.ShardIndex
 	log.V(1).M(hostToRunOn).F().Info("Drop replica: %v at %v", replica, hostToRunOn.Runtime.Address.HostName)
 	return s.ExecHost(ctx, hostToRunOn, s.sqlDropReplica(shard, replica), clickhouse.NewQueryOptions().SetRetry(false))
 }
 
+// HostStopMerges calls SYSTEM STOP MERGES on the host
+func (s *ClusterSchemer) HostStopMerges(ctx context.Context, host *api.ChiHost) error {
+	log.V(1).M(host).F().Info("Stop merges at %v", host.Runtime.Address.HostName)
+	return s.ExecHost(ctx, host, []string{s.sqlStopMerges()}, clickhouse.NewQueryOptions().SetRetry(false))
+}
+
+// HostStartMerges calls SYSTEM START MERGES on the host
+func (s *ClusterSchemer) HostStartMerges(ctx context.Context, host *api.ChiHost) error {
+	log.V(1).M(host).F().Info("Start merges at %v", host.Runtime.Address.HostName)
+	return s.ExecHost(ctx, host, []string{s.sqlStartMerges()})
+}
+
+// HostStopFetches calls SYSTEM STOP FETCHES on the host
+func (s *ClusterSchemer) HostStopFetches(ctx context.Context, host *api.ChiHost) error {
+	log.V(1).M(host).F().Info("Stop fetches at %v", host.Runtime.Address.HostName)
+	return s.ExecHost(ctx, host, []string{s.sqlStopFetches()}, clickhouse.NewQueryOptions().SetRetry(false))
+}
+
+// HostStartFetches calls SYSTEM START FETCHES on the host
+func (s *ClusterSchemer) HostStartFetches(ctx context.Context, host *api.ChiHost) error {
+	log.V(1).M(host).F().Info("Start fetches at %v", host.Runtime.Address.HostName)
+	return s.ExecHost(ctx, host, []string{s.sqlStartFetches()})
+}
+
+// HostReloadConfig calls SYSTEM RELOAD CONFIG on the host
+func (s *ClusterSchemer) HostReloadConfig(ctx context.Context, host *api.ChiHost) error {
+	log.V(1).M(host).F().Info("Reload config at %v", host.Runtime.Address.HostName)
+	return s.ExecHost(ctx, host, []string{s.sqlReloadConfig()})
+}
+
 // createTablesSQLs makes all SQL for migrating tables
 func (s *ClusterSchemer) createTablesSQLs(
 	ctx context.Context,
@@ -88,40 +141,14 @@ func (s *ClusterSchemer) HostCreateTables(ctx context.Context, host *api.ChiHost
 	log.V(1).M(host).F().S().Info("Migrating schema objects to host %s", host.Runtime.Address.HostName)
 	defer log.V(1).M(host).F().E().Info("Migrating schema objects to host %s", host.Runtime.Address.HostName)
 
-	replicatedObjectNames,
-		replicatedCreateSQLs,
-		distributedObjectNames,
-		distributedCreateSQLs := s.createTablesSQLs(ctx, host)
-
-	var err1 error
-	if len(replicatedCreateSQLs) > 0 {
-		log.V(1).M(host).F().Info("Creating replicated objects at %s: %v", host.Runtime.Address.HostName, replicatedObjectNames)
-		log.V(2).M(host).F().Info("\n%v", replicatedCreateSQLs)
-		err1 = s.ExecHost(ctx, host, replicatedCreateSQLs, clickhouse.NewQueryOptions().SetRetry(true))
-	}
-
-	var err2 error
-	if len(distributedCreateSQLs) > 0 {
-		log.V(1).M(host).F().Info("Creating distributed objects at %s: %v", host.Runtime.Address.HostName, distributedObjectNames)
-		log.V(2).M(host).F().Info("\n%v", distributedCreateSQLs)
-		err2 = s.ExecHost(ctx, host, distributedCreateSQLs, clickhouse.NewQueryOptions().SetRetry(true))
-	}
-
-	if err2 != nil {
-		return err2
-	}
-	if err1 != nil {
-		return err1
-	}
-
-	return nil
+	return s.HostApplySchemaPlan(ctx, host, s.HostSchemaPlan(ctx, host))
 }
 
 // HostDropTables drops tables on a host
 func (s *ClusterSchemer) HostDropTables(ctx context.Context, host *api.ChiHost) error {
 	tableNames, dropTableSQLs, _ := s.sqlDropTable(ctx, host)
 	log.V(1).M(host).F().Info("Drop tables: %v as %v", tableNames, dropTableSQLs)
-	return s.ExecHost(ctx, host, dropTableSQLs, clickhouse.NewQueryOptions().SetRetry(false))
+	return s.ExecHost(ctx, host, dropTableSQLs, s.newDDLQueryOptions().SetRetry(false))
 }
 
 // IsHostInCluster checks whether host is a member of at least one ClickHouse cluster
@@ -148,11 +175,149 @@ func (s *ClusterSchemer) CHIDropDnsCache(ctx context.Context, chi *api.ClickHous
 	return nil
 }
 
+// HostReloadFunctions runs 'RELOAD FUNCTIONS' on the host and checks whether all specified functions are loaded.
+// Functions missing after reload means config with functions has not reached the host yet.
+func (s *ClusterSchemer) HostReloadFunctions(ctx context.Context, host *api.ChiHost, names []string) (bool, error) {
+	if err := s.ExecHost(ctx, host, []string{s.sqlReloadFunctions()}); err != nil {
+		return false, err
+	}
+	query, err := s.QueryHost(ctx, host, s.sqlExecutableFunctions())
+	if err != nil {
+		return false, err
+	}
+	if query == nil {
+		return false, nil
+	}
+	var loaded []string
+	err = query.UnzipColumnsAsStrings(&loaded)
+	query.Close()
+	if err != nil {
+		return false, err
+	}
+	return len(util.SubtractStringArrays(names, loaded)) == 0, nil
+}
+
+// HostReloadUsersConfig runs 'RELOAD CONFIG' on the host and checks whether default user is allowed to connect from the specified IP.
+// IP not allowed after reload means users config with the IP has not reached the host yet.
+func (s *ClusterSchemer) HostReloadUsersConfig(ctx context.Context, host *api.ChiHost, ip string) (bool, error) {
+	if err := s.ExecHost(ctx, host, []string{s.sqlReloadConfig()}); err != nil {
+		return false, err
+	}
+	count, err := s.QueryHostInt(ctx, host, s.sqlDefaultUserAllowedFromIP(ip))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CHISchemaSQLs collects SQL statements creating databases, tables and functions of each cluster of the CHI.
+// Statements are ordered so that databases are created before tables and local tables before distributed ones
+func (s *ClusterSchemer) CHISchemaSQLs(ctx context.Context, chi *api.ClickHouseInstallation) (names []string, sqls []string, err error) {
+	hosts := getHosts(chi, nil)
+	chi.WalkClusters(func(cluster *api.Cluster) error {
+		if err != nil {
+			return nil
+		}
+		for _, sql := range []string{
+			s.sqlCreateDatabaseReplicated(cluster.Name),
+			s.sqlCreateDatabaseDistributed(cluster.Name),
+			s.sqlCreateFunction(cluster.Name),
+		} {
+			_names, _sqls, _err := s.QueryUnzip2Columns(ctx, hosts, sql)
+			if _err != nil {
+				err = _err
+				return nil
+			}
+			names = append(names, _names...)
+			sqls = append(sqls, _sqls...)
+		}
+		for _, sql := range []string{
+			s.sqlCreateTableDistributed(cluster.Name),
+			s.sqlCreateTableReplicated(cluster.Name),
+		} {
+			_names, _sqls, _err := s.QueryUnzipAndApplyUUIDs(ctx, hosts, sql)
+			if _err != nil {
+				err = _err
+				return nil
+			}
+			names = append(names, _names...)
+			sqls = append(sqls, _sqls...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Same objects are reported by several clusters and queries, keep the first occurrence only
+	var uniqueNames, uniqueSQLs []string
+	for i := range sqls {
+		if util.InArray(sqls[i], uniqueSQLs) {
+			continue
+		}
+		uniqueNames = append(uniqueNames, names[i])
+		uniqueSQLs = append(uniqueSQLs, sqls[i])
+	}
+	return uniqueNames, uniqueSQLs, nil
+}
+
+// HostDataTables returns tables holding data on the host, which is not replicated outside of host's shard
+func (s *ClusterSchemer) HostDataTables(ctx context.Context, host *api.ChiHost) ([]string, error) {
+	query, err := s.QueryHost(ctx, host, s.sqlDataTables(host.GetShard().HostsCount()))
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, nil
+	}
+	defer query.Close()
+
+	var tables []string
+	err = query.UnzipColumnsAsStrings(&tables)
+	return tables, err
+}
+
+// HostUnreplicatedDataTables returns tables holding data on the host, which is not replicated to any other host
+func (s *ClusterSchemer) HostUnreplicatedDataTables(ctx context.Context, host *api.ChiHost) ([]string, error) {
+	query, err := s.QueryHost(ctx, host, s.sqlDataTables(1))
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, nil
+	}
+	defer query.Close()
+
+	var tables []string
+	err = query.UnzipColumnsAsStrings(&tables)
+	return tables, err
+}
+
 // HostActiveQueriesNum returns how many active queries are on the host
 func (s *ClusterSchemer) HostActiveQueriesNum(ctx context.Context, host *api.ChiHost) (int, error) {
 	return s.QueryHostInt(ctx, host, s.sqlActiveQueriesNum())
 }
 
+// HostUserQueriesNum returns how many queries, except for queries issued by the operator itself, are running on the host
+func (s *ClusterSchemer) HostUserQueriesNum(ctx context.Context, host *api.ChiHost) (int, error) {
+	return s.QueryHostInt(ctx, host, s.sqlUserQueriesNum())
+}
+
+// HostLaggingReplicasNum returns how many replicated tables on the host lag behind other replicas
+func (s *ClusterSchemer) HostLaggingReplicasNum(ctx context.Context, host *api.ChiHost) (int, error) {
+	return s.QueryHostInt(ctx, host, s.sqlLaggingReplicasNum())
+}
+
+// HostMaxReplicationDelay returns max replication delay in seconds of replicated tables on the host
+func (s *ClusterSchemer) HostMaxReplicationDelay(ctx context.Context, host *api.ChiHost) (int, error) {
+	return s.QueryHostInt(ctx, host, s.sqlMaxReplicationDelay())
+}
+
+// HostTablesNum returns how many tables of user databases the host has
+func (s *ClusterSchemer) HostTablesNum(ctx context.Context, host *api.ChiHost) (int, error) {
+	return s.QueryHostInt(ctx, host, s.sqlTablesNum())
+}
+
 // HostClickHouseVersion returns ClickHouse version on the host
 func (s *ClusterSchemer) HostClickHouseVersion(ctx context.Context, host *api.ChiHost) (string, error) {
 	return s.QueryHostString(ctx, host, s.sqlVersion())
@@ -59,7 +59,7 @@ func (s *ClusterSchemer) sqlDropTable(ctx context.Context, host *api.ChiHost) ([
 		ignoredDBs,
 	)
 
-	names, sqlStatements, _ := s.QueryUnzip2Columns(ctx, chi.CreateFQDNs(host, api.ChiHost{}, false), sql)
+	names, sqlStatements, _ := s.QueryUnzip2Columns(ctx, getHosts(host, api.ChiHost{}), sql)
 	return names, sqlStatements, nil
 }
 
@@ -76,7 +76,7 @@ func (s *ClusterSchemer) sqlSyncTable(ctx context.Context, host *api.ChiHost) ([
 		`,
 	)
 
-	names, sqlStatements, _ := s.QueryUnzip2Columns(ctx, chi.CreateFQDNs(host, api.ChiHost{}, false), sql)
+	names, sqlStatements, _ := s.QueryUnzip2Columns(ctx, getHosts(host, api.ChiHost{}), sql)
 	return names, sqlStatements, nil
 }
 
@@ -194,7 +194,7 @@ func (s *ClusterSchemer) sqlCreateDatabaseReplicated(cluster string) string {
 func (s *ClusterSchemer) sqlCreateTableReplicated(cluster string) string {
 	return heredoc.Docf(`
 		SELECT
-			DISTINCT tables.name,
+			DISTINCT concat(tables.database, '.', tables.name) AS qualified_name,
 			replaceRegexpOne(create_table_query, 'CREATE (TABLE|VIEW|MATERIALIZED VIEW|DICTIONARY|LIVE VIEW|WINDOW VIEW)', 'CREATE \\1 IF NOT EXISTS'),
 			extract(create_table_query, 'UUID \'([^\(\']*)') AS uuid,
 			extract(create_table_query, 'INNER UUID \'([^\(\']*)') AS inner_uuid
@@ -237,14 +237,104 @@ func (s *ClusterSchemer) sqlDropReplica(shard int, replica string) []string {
 	}
 }
 
+func (s *ClusterSchemer) sqlStopMerges() string {
+	return `SYSTEM STOP MERGES`
+}
+
+func (s *ClusterSchemer) sqlStartMerges() string {
+	return `SYSTEM START MERGES`
+}
+
+func (s *ClusterSchemer) sqlStopFetches() string {
+	return `SYSTEM STOP FETCHES`
+}
+
+func (s *ClusterSchemer) sqlStartFetches() string {
+	return `SYSTEM START FETCHES`
+}
+
 func (s *ClusterSchemer) sqlDropDNSCache() string {
 	return `SYSTEM DROP DNS CACHE`
 }
 
+// sqlDataTables returns SQL to list tables holding data, which is not replicated outside of the shard.
+// Replicated tables having more replicas than the shard has are considered to be replicated elsewhere.
+// Non-replicated tables have no row in system.replicas, which is NULL in case join_use_nulls is set.
+func (s *ClusterSchemer) sqlDataTables(shardReplicasNum int) string {
+	return heredoc.Docf(`
+		SELECT
+			concat(p.database, '.', p.table) AS name
+		FROM
+		(
+			SELECT
+				database,
+				table,
+				sum(rows) AS rows
+			FROM
+				system.parts
+			WHERE
+				active AND database NOT IN (%s)
+			GROUP BY
+				database, table
+		) AS p
+		LEFT JOIN system.replicas AS r ON (p.database = r.database) AND (p.table = r.table)
+		WHERE
+			(p.rows > 0) AND (ifNull(r.total_replicas, 0) <= %d)
+		ORDER BY
+			name
+		`,
+		ignoredDBs,
+		shardReplicasNum,
+	)
+}
+
+func (s *ClusterSchemer) sqlReloadFunctions() string {
+	return `SYSTEM RELOAD FUNCTIONS`
+}
+
+func (s *ClusterSchemer) sqlExecutableFunctions() string {
+	return `SELECT name FROM system.functions WHERE origin = 'ExecutableUserDefined'`
+}
+
+func (s *ClusterSchemer) sqlReloadConfig() string {
+	return `SYSTEM RELOAD CONFIG`
+}
+
+func (s *ClusterSchemer) sqlDefaultUserAllowedFromIP(ip string) string {
+	// IPv4 addresses may be reported as IPv4-mapped IPv6 ones
+	return fmt.Sprintf(
+		`SELECT count() FROM system.users WHERE name = 'default' AND (has(host_ip, '%s') OR has(host_ip, '::ffff:%s'))`,
+		ip, ip,
+	)
+}
+
 func (s *ClusterSchemer) sqlActiveQueriesNum() string {
 	return `SELECT count() FROM system.processes`
 }
 
+// sqlUserQueriesNum returns SQL to count running queries, except for queries issued by the operator itself
+func (s *ClusterSchemer) sqlUserQueriesNum() string {
+	return `SELECT count() FROM system.processes WHERE user != currentUser()`
+}
+
+// sqlLaggingReplicasNum returns SQL to count replicated tables, which have not fetched all parts from other replicas yet
+func (s *ClusterSchemer) sqlLaggingReplicasNum() string {
+	return `SELECT count() FROM system.replicas WHERE (absolute_delay > 0) OR (queue_size > 0)`
+}
+
+// sqlMaxReplicationDelay returns SQL to get max replication delay of replicated tables, 0 in case there are no replicated tables
+func (s *ClusterSchemer) sqlMaxReplicationDelay() string {
+	return `SELECT max(absolute_delay) FROM system.replicas`
+}
+
+// sqlTablesNum returns SQL to count tables of all user databases
+func (s *ClusterSchemer) sqlTablesNum() string {
+	return fmt.Sprintf(
+		`SELECT count() FROM system.tables WHERE database NOT IN (%s) AND NOT is_temporary`,
+		ignoredDBs,
+	)
+}
+
 func (s *ClusterSchemer) sqlVersion() string {
 	return `SELECT version()`
 }
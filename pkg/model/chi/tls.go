@@ -0,0 +1,81 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chi
+
+import (
+	"sort"
+
+	core "k8s.io/api/core/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// Clusters having 'secure: auto' rely on TLS certificates generated by the operator.
+// All certificates of the cluster are kept in one Secret, which carries the CA and a certificate per each host.
+// Each host mounts the CA certificates and own certificate only, CA private keys are never mounted.
+//
+// TLSSecretKeyCACertificate keeps bundle of CA certificates trusted by hosts. The first one of them is the CA
+// issuing host certificates, which private key is kept under TLSSecretKeyCAPrivateKey.
+// While the CA is rotated, the next CA is kept under TLSSecretKeyNextCACertificate and TLSSecretKeyNextCAPrivateKey
+// and is trusted along with the current one, and the previous CA is trusted till it expires.
+const (
+	TLSSecretKeyCACertificate     = "ca.crt"
+	TLSSecretKeyCAPrivateKey      = "ca.key"
+	TLSSecretKeyNextCACertificate = "ca-next.crt"
+	TLSSecretKeyNextCAPrivateKey  = "ca-next.key"
+)
+
+// Files of TLS certificates as mounted into DirPathTLS of the pod
+const (
+	TLSFileCACertificate = "ca.crt"
+	TLSFileCertificate   = "tls.crt"
+	TLSFilePrivateKey    = "tls.key"
+)
+
+// GetTLSCAChecksum gets checksum of the bundle of CA certificates trusted by hosts
+func GetTLSCAChecksum(secret *core.Secret) string {
+	if secret == nil {
+		return ""
+	}
+	bundle, ok := secret.Data[TLSSecretKeyCACertificate]
+	if !ok {
+		return ""
+	}
+	return util.HashIntoString(bundle)
+}
+
+// CreateHostTLSSecretKeyCertificate creates key of the Secret where certificate of the host is kept
+func CreateHostTLSSecretKeyCertificate(host *api.ChiHost) string {
+	return CreateStatefulSetName(host) + ".crt"
+}
+
+// CreateHostTLSSecretKeyPrivateKey creates key of the Secret where private key of the host certificate is kept
+func CreateHostTLSSecretKeyPrivateKey(host *api.ChiHost) string {
+	return CreateStatefulSetName(host) + ".key"
+}
+
+// CreateHostTLSHostnames creates hostnames the certificate of the host is issued for.
+// These are all the names the host may be addressed by from other hosts, whether ReplicasUseFQDN is set or not
+func CreateHostTLSHostnames(host *api.ChiHost) []string {
+	hostnames := util.Unique([]string{
+		CreatePodHostname(host),
+		CreateFQDN(host),
+		CreateInstanceHostname(host),
+		"localhost",
+	})
+	sort.Strings(hostnames)
+	return hostnames
+}
@@ -21,6 +21,7 @@ import (
 
 	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
 	apiChi "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/chk/keeper"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 	"github.com/altinity/clickhouse-operator/pkg/xml"
 )
@@ -44,6 +45,7 @@ func defaultKeeperSettings(path string) *apiChi.Settings {
 			"keeper_server/coordination_settings/session_timeout_ms":     "100000",
 			"keeper_server/coordination_settings/raft_logs_level":        "information",
 			"keeper_server/hostname_checks_enabled":                      "true",
+			"keeper_server/enable_reconfiguration":                       "true",
 
 			"openSSL/server/certificateFile":     "/etc/clickhouse-keeper/server.crt",
 			"openSSL/server/privateKeyFile":      "/etc/clickhouse-keeper/server.key",
@@ -84,8 +86,11 @@ func generateXMLConfig(settings *apiChi.Settings, chk *apiChk.ClickHouseKeeperIn
 	for i := 0; i < getCluster(chk).GetLayout().GetReplicasCount(); i++ {
 		util.Iline(raft, 12, "<server>")
 		util.Iline(raft, 12, "    <id>%d</id>", i)
-		util.Iline(raft, 12, "    <hostname>%s-%d.%s-headless.%s.svc.cluster.local</hostname>", chk.Name, i, chk.Name, chk.Namespace)
+		util.Iline(raft, 12, "    <hostname>%s</hostname>", GetMemberHostname(chk, i))
 		util.Iline(raft, 12, "    <port>%s</port>", fmt.Sprintf("%d", raftPort))
+		if priority, ok := getMemberPriority(chk, i); ok {
+			util.Iline(raft, 12, "    <priority>%d</priority>", priority)
+		}
 		util.Iline(raft, 12, "</server>")
 	}
 
@@ -99,3 +104,36 @@ func generateXMLConfig(settings *apiChi.Settings, chk *apiChk.ClickHouseKeeperIn
 	// </raft_configuration>
 	return strings.Replace(tmp, "            <server></server>\n", raft.String(), 1)
 }
+
+// CreateRaftMember creates description of the member of the quorum, as it is specified to 'reconfig' command
+func CreateRaftMember(chk *apiChk.ClickHouseKeeperInstallation, id int) keeper.RaftMember {
+	member := keeper.RaftMember{
+		ID:       id,
+		Address:  fmt.Sprintf("%s:%d", GetMemberHostname(chk, id), chk.Spec.GetRaftPort()),
+		Role:     keeper.RaftRoleParticipant,
+		Priority: keeper.DefaultRaftPriority,
+	}
+	if priority, ok := getMemberPriority(chk, id); ok {
+		member.Priority = priority
+	}
+	return member
+}
+
+// CreateRaftConfig creates the quorum configuration, members are indexed by id
+func CreateRaftConfig(chk *apiChk.ClickHouseKeeperInstallation) map[int]keeper.RaftMember {
+	members := make(map[int]keeper.RaftMember)
+	for id := 0; id < GetReplicasCount(chk); id++ {
+		members[id] = CreateRaftMember(chk, id)
+	}
+	return members
+}
+
+// getMemberPriority gets raft priority of the member based on the zone the member is located in
+func getMemberPriority(chk *apiChk.ClickHouseKeeperInstallation, id int) (int, bool) {
+	zone, ok := chk.Runtime.MemberZones[id]
+	if !ok {
+		// Zone of the member is not known yet
+		return 0, false
+	}
+	return getCluster(chk).GetZoneAwareness().GetZonePriority(zone)
+}
@@ -29,3 +29,7 @@ func GetReplicasCount(chk *api.ClickHouseKeeperInstallation) int {
 	}
 	return cluster.GetLayout().GetReplicasCount()
 }
+
+func GetZoneAwareness(chk *api.ClickHouseKeeperInstallation) *api.ChkZoneAwareness {
+	return getCluster(chk).GetZoneAwareness()
+}
@@ -92,12 +92,35 @@ func createPodTemplateSpec(chk *api.ClickHouseKeeperInstallation) core.PodSpec {
 	if len(podSpec.Volumes) == 0 {
 		podSpec.Volumes = createVolumes(chk)
 	}
+	if len(podSpec.TopologySpreadConstraints) == 0 {
+		podSpec.TopologySpreadConstraints = createTopologySpreadConstraints(chk)
+	}
 	podSpec.InitContainers = createInitContainers(chk)
 	podSpec.Containers = createContainers(chk)
 
 	return podSpec
 }
 
+// createTopologySpreadConstraints creates constraints which spread keeper members across zones,
+// so quorum survives loss of a zone
+func createTopologySpreadConstraints(chk *api.ClickHouseKeeperInstallation) []core.TopologySpreadConstraint {
+	zoneAwareness := getCluster(chk).GetZoneAwareness()
+	if !zoneAwareness.IsEnabled() {
+		return nil
+	}
+
+	return []core.TopologySpreadConstraint{
+		{
+			MaxSkew:           zoneAwareness.GetMaxSkew(),
+			TopologyKey:       zoneAwareness.GetTopologyKey(),
+			WhenUnsatisfiable: core.DoNotSchedule,
+			LabelSelector: &meta.LabelSelector{
+				MatchLabels: GetPodLabels(chk),
+			},
+		},
+	}
+}
+
 func createVolumes(chk *api.ClickHouseKeeperInstallation) []core.Volume {
 	var volumes []core.Volume
 
@@ -0,0 +1,309 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keeper server states as reported by 'mntr' command
+const (
+	ServerStateLeader     = "leader"
+	ServerStateFollower   = "follower"
+	ServerStateObserver   = "observer"
+	ServerStateStandalone = "standalone"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// raftConfigPath specifies node, which keeps the quorum configuration
+const raftConfigPath = "/keeper/config"
+
+// Client talks to a ClickHouse Keeper member via client port.
+// Supports 4-letter-word commands and admin requests of ZooKeeper protocol, such as 'reconfig'
+type Client struct {
+	address string
+	timeout time.Duration
+	// scheme and auth specify credentials of the session, if any
+	scheme string
+	auth   string
+}
+
+// NewClient creates new Client to the Keeper member specified by host and client port
+func NewClient(host string, port int) *Client {
+	return &Client{
+		address: net.JoinHostPort(host, strconv.Itoa(port)),
+		timeout: defaultTimeout,
+	}
+}
+
+// SetTimeout sets timeout of each request
+func (c *Client) SetTimeout(timeout time.Duration) *Client {
+	if c == nil {
+		return nil
+	}
+	c.timeout = timeout
+	return c
+}
+
+// SetAuth sets credentials sessions are authenticated with, such as 'digest' scheme with 'user:password' auth.
+// Nodes protected by ACL are accessible by the sessions having the same credentials as the ones nodes were created with
+func (c *Client) SetAuth(scheme, auth string) *Client {
+	if c == nil {
+		return nil
+	}
+	c.scheme = scheme
+	c.auth = auth
+	return c
+}
+
+// String returns address of the Keeper member
+func (c *Client) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.address
+}
+
+// dial opens connection to the Keeper member with the timeout applied
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(c.timeout))
+	return conn, nil
+}
+
+// openSession opens connection to the Keeper member and establishes authenticated session over it
+func (c *Client) openSession(ctx context.Context) (net.Conn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := connect(conn, c.timeout); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if c.auth != "" {
+		if err := addAuth(conn, c.scheme, c.auth); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// FourLetterWord runs 4-letter-word command and returns the response.
+// Command has to be listed in 'keeper_server/four_letter_word_white_list'
+func (c *Client) FourLetterWord(ctx context.Context, cmd string) (string, error) {
+	if len(cmd) != 4 {
+		return "", fmt.Errorf("4lw command expected, got: %s", cmd)
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(cmd)); err != nil {
+		return "", err
+	}
+	// Keeper closes connection as soon as response is written
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
+
+// IsOK checks whether the Keeper member is running and serving requests
+func (c *Client) IsOK(ctx context.Context) bool {
+	response, err := c.FourLetterWord(ctx, "ruok")
+	return (err == nil) && (strings.TrimSpace(response) == "imok")
+}
+
+// Monitor fetches 'mntr' stats of the Keeper member
+func (c *Client) Monitor(ctx context.Context) (map[string]string, error) {
+	return c.fetchStats(ctx, "mntr")
+}
+
+// LogInfo fetches 'lgif' raft log info of the Keeper member, such as last_committed_log_idx
+func (c *Client) LogInfo(ctx context.Context) (map[string]string, error) {
+	return c.fetchStats(ctx, "lgif")
+}
+
+// CommitLag gets how many raft log entries committed by the leader are not committed by the Keeper member yet
+func (c *Client) CommitLag(ctx context.Context) (int64, error) {
+	info, err := c.LogInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	committed, err := strconv.ParseInt(info["last_committed_log_idx"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("no committed log index reported by %s", c.address)
+	}
+	leaderCommitted, err := strconv.ParseInt(info["leader_committed_log_idx"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("no leader committed log index reported by %s", c.address)
+	}
+	if leaderCommitted < committed {
+		return 0, nil
+	}
+	return leaderCommitted - committed, nil
+}
+
+// fetchStats runs 4-letter-word command, which replies with 'key<TAB>value' lines, and parses the reply
+func (c *Client) fetchStats(ctx context.Context, cmd string) (map[string]string, error) {
+	response, err := c.FourLetterWord(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]string)
+	for _, line := range strings.Split(response, "\n") {
+		if fields := strings.SplitN(strings.TrimSpace(line), "\t", 2); len(fields) == 2 {
+			stats[fields[0]] = strings.TrimSpace(fields[1])
+		}
+	}
+	return stats, nil
+}
+
+// ServerState gets raft state of the Keeper member, such as leader or follower
+func (c *Client) ServerState(ctx context.Context) (string, error) {
+	stats, err := c.Monitor(ctx)
+	if err != nil {
+		return "", err
+	}
+	state, ok := stats["zk_server_state"]
+	if !ok {
+		return "", fmt.Errorf("no server state reported by %s", c.address)
+	}
+	return state, nil
+}
+
+// IsQuorumMember checks whether the Keeper member participates in the quorum
+func (c *Client) IsQuorumMember(ctx context.Context) bool {
+	state, err := c.ServerState(ctx)
+	if err != nil {
+		return false
+	}
+	switch state {
+	case ServerStateLeader, ServerStateFollower, ServerStateStandalone:
+		return true
+	}
+	return false
+}
+
+// IsInSync checks whether the Keeper member participates in the quorum and has committed all the raft log
+// committed by the leader. Member, which does not report raft log info, is considered to be in sync as soon as joined
+func (c *Client) IsInSync(ctx context.Context) bool {
+	if !c.IsQuorumMember(ctx) {
+		return false
+	}
+	lag, err := c.CommitLag(ctx)
+	return (err != nil) || (lag == 0)
+}
+
+// Reconfig changes quorum membership incrementally.
+// Joining members are specified as 'server.ID=HOST:PORT[;ROLE[;PRIORITY]]', leaving members are specified as IDs.
+// Members are changed by the quorum leader, request is forwarded to it by any member.
+func (c *Client) Reconfig(ctx context.Context, joining []string, leaving []int) error {
+	if (len(joining) == 0) && (len(leaving) == 0) {
+		return nil
+	}
+
+	conn, err := c.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var leavingIDs []string
+	for _, id := range leaving {
+		leavingIDs = append(leavingIDs, strconv.Itoa(id))
+	}
+	if err := reconfig(conn, strings.Join(joining, ","), strings.Join(leavingIDs, ",")); err != nil {
+		return err
+	}
+
+	_ = disconnect(conn)
+	return nil
+}
+
+// GetRaftConfig gets the quorum configuration as seen by the Keeper member, members are indexed by id
+func (c *Client) GetRaftConfig(ctx context.Context) (map[int]RaftMember, error) {
+	conn, err := c.openSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	data, err := getData(conn, 1, raftConfigPath)
+	if err != nil {
+		if errors.Is(err, ErrNoNode) {
+			// Quorum configuration is not published by Keeper versions, which do not support reconfiguration
+			return nil, fmt.Errorf("%w: %v", ErrReconfigNotSupported, err)
+		}
+		return nil, err
+	}
+	_ = disconnect(conn)
+
+	return ParseRaftConfig(string(data))
+}
+
+// DeleteRecursive deletes the node along with all its descendants. Node, which does not exist, is not an error
+func (c *Client) DeleteRecursive(ctx context.Context, path string) error {
+	conn, err := c.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Each request is numbered, so replies are matched to requests
+	xid := int32(1)
+	var walk func(path string) error
+	walk = func(path string) error {
+		_ = conn.SetDeadline(time.Now().Add(c.timeout))
+		xid++
+		children, err := getChildren(conn, xid, path)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := walk(path + "/" + child); err != nil && !errors.Is(err, ErrNoNode) {
+				return err
+			}
+		}
+		_ = conn.SetDeadline(time.Now().Add(c.timeout))
+		xid++
+		return deleteNode(conn, xid, path)
+	}
+	if err := walk(path); err != nil && !errors.Is(err, ErrNoNode) {
+		return err
+	}
+
+	xid++
+	_, _ = request(conn, xid, opClose, nil)
+	return nil
+}
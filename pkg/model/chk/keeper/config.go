@@ -0,0 +1,115 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keeper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Roles of the quorum members
+const (
+	RaftRoleParticipant = "participant"
+	RaftRoleLearner     = "learner"
+)
+
+// DefaultRaftPriority specifies priority of the member, which has no priority specified
+const DefaultRaftPriority = 1
+
+// RaftMember describes member of the quorum as listed in the quorum configuration
+type RaftMember struct {
+	ID       int
+	Address  string
+	Role     string
+	Priority int
+}
+
+// String formats the member as 'server.ID=HOST:PORT;ROLE;PRIORITY', which is accepted by 'reconfig' command
+func (m RaftMember) String() string {
+	return fmt.Sprintf("server.%d=%s;%s;%d", m.ID, m.Address, m.Role, m.Priority)
+}
+
+// ParseRaftMember parses member specified as 'server.ID=HOST:PORT[;ROLE[;PRIORITY]]'
+func ParseRaftMember(s string) (RaftMember, error) {
+	key, value, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok || !strings.HasPrefix(key, "server.") {
+		return RaftMember{}, fmt.Errorf("unexpected raft member: %s", s)
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(key, "server."))
+	if err != nil {
+		return RaftMember{}, fmt.Errorf("unexpected raft member id: %s", s)
+	}
+
+	member := RaftMember{
+		ID:       id,
+		Role:     RaftRoleParticipant,
+		Priority: DefaultRaftPriority,
+	}
+	fields := strings.Split(value, ";")
+	member.Address = fields[0]
+	if member.Address == "" {
+		return RaftMember{}, fmt.Errorf("no raft member address: %s", s)
+	}
+	if (len(fields) > 1) && (fields[1] != "") {
+		member.Role = fields[1]
+	}
+	if (len(fields) > 2) && (fields[2] != "") {
+		if member.Priority, err = strconv.Atoi(fields[2]); err != nil {
+			return RaftMember{}, fmt.Errorf("unexpected raft member priority: %s", s)
+		}
+	}
+	return member, nil
+}
+
+// ParseRaftConfig parses quorum configuration, which lists one member per line, into members indexed by id
+func ParseRaftConfig(config string) (map[int]RaftMember, error) {
+	members := make(map[int]RaftMember)
+	for _, line := range strings.Split(config, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		member, err := ParseRaftMember(line)
+		if err != nil {
+			return nil, err
+		}
+		members[member.ID] = member
+	}
+	return members, nil
+}
+
+// DiffRaftConfig gets members to be joined and members to leave, so the actual quorum configuration becomes the desired one.
+// Members, which are listed in both configurations with different address, role or priority, are joined anew, which updates them
+func DiffRaftConfig(actual, desired map[int]RaftMember) (joining []string, leaving []int) {
+	var ids []int
+	for id := range desired {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if member, ok := actual[id]; !ok || (member != desired[id]) {
+			joining = append(joining, desired[id].String())
+		}
+	}
+
+	for id := range actual {
+		if _, ok := desired[id]; !ok {
+			leaving = append(leaving, id)
+		}
+	}
+	sort.Ints(leaving)
+	return joining, leaving
+}
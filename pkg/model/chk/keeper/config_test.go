@@ -0,0 +1,54 @@
+package keeper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRaftMember(t *testing.T) {
+	member, err := ParseRaftMember("server.1=keeper-1.keeper-headless:9234;participant;3")
+	require.NoError(t, err)
+	require.Equal(t, RaftMember{ID: 1, Address: "keeper-1.keeper-headless:9234", Role: RaftRoleParticipant, Priority: 3}, member)
+
+	member, err = ParseRaftMember("server.2=keeper-2:9234")
+	require.NoError(t, err)
+	require.Equal(t, RaftMember{ID: 2, Address: "keeper-2:9234", Role: RaftRoleParticipant, Priority: DefaultRaftPriority}, member)
+	require.Equal(t, "server.2=keeper-2:9234;participant;1", member.String())
+
+	for _, s := range []string{"", "server.x=keeper:9234", "keeper:9234", "server.1=", "server.1=keeper:9234;participant;high"} {
+		_, err := ParseRaftMember(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestParseRaftConfig(t *testing.T) {
+	members, err := ParseRaftConfig("server.0=keeper-0:9234;participant;1\nserver.1=keeper-1:9234;learner;0\n")
+	require.NoError(t, err)
+	require.Equal(t, map[int]RaftMember{
+		0: {ID: 0, Address: "keeper-0:9234", Role: RaftRoleParticipant, Priority: 1},
+		1: {ID: 1, Address: "keeper-1:9234", Role: RaftRoleLearner, Priority: 0},
+	}, members)
+}
+
+func TestDiffRaftConfig(t *testing.T) {
+	member := func(id, priority int) RaftMember {
+		return RaftMember{ID: id, Address: "keeper:9234", Role: RaftRoleParticipant, Priority: priority}
+	}
+
+	// Same configuration
+	joining, leaving := DiffRaftConfig(
+		map[int]RaftMember{0: member(0, 1), 1: member(1, 1)},
+		map[int]RaftMember{0: member(0, 1), 1: member(1, 1)},
+	)
+	require.Empty(t, joining)
+	require.Empty(t, leaving)
+
+	// Member added, member removed and priority of a member changed
+	joining, leaving = DiffRaftConfig(
+		map[int]RaftMember{0: member(0, 1), 1: member(1, 1), 2: member(2, 1)},
+		map[int]RaftMember{0: member(0, 1), 1: member(1, 5), 3: member(3, 1)},
+	)
+	require.Equal(t, []string{"server.1=keeper:9234;participant;5", "server.3=keeper:9234;participant;1"}, joining)
+	require.Equal(t, []int{2}, leaving)
+}
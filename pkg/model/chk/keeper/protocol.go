@@ -0,0 +1,345 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keeper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Minimal subset of ZooKeeper protocol, which is required to run admin requests against ClickHouse Keeper
+// and to clean up nodes.
+// Refers to
+// https://github.com/apache/zookeeper/blob/master/zookeeper-jute/src/main/resources/zookeeper.jute
+
+// Request types
+const (
+	opDelete      int32 = 2
+	opGetData     int32 = 4
+	opGetChildren int32 = 8
+	opReconfig    int32 = 16
+	opClose       int32 = -11
+	opAuth        int32 = 100
+)
+
+// Reserved xids of the server-originated messages
+const (
+	xidWatcherEvent int32 = -1
+	xidPing         int32 = -2
+	xidAuth         int32 = -4
+)
+
+// Error codes of the replies
+const (
+	errCodeOK                 int32 = 0
+	errCodeUnimplemented      int32 = -6
+	errCodeNewConfigNoQuorum  int32 = -13
+	errCodeReconfigInProgress int32 = -14
+	errCodeReconfigDisabled   int32 = -123
+	errCodeNoNode             int32 = -101
+	errCodeAuthFailed         int32 = -115
+)
+
+// AuthSchemeDigest specifies authentication scheme of 'user:password' credentials, such as ClickHouse identity
+const AuthSchemeDigest = "digest"
+
+// maxPacketSize limits size of a reply to be read
+const maxPacketSize = 16 * 1024 * 1024
+
+var (
+	// ErrReconfigNotSupported specifies Keeper does not support dynamic reconfiguration or has it disabled
+	ErrReconfigNotSupported = errors.New("keeper reconfiguration is not supported")
+	// ErrReconfigRejected specifies Keeper is not able to apply reconfiguration right now
+	ErrReconfigRejected = errors.New("keeper reconfiguration is rejected")
+	// ErrNoNode specifies node does not exist
+	ErrNoNode = errors.New("keeper node does not exist")
+	// ErrAuthFailed specifies Keeper has rejected credentials of the session
+	ErrAuthFailed = errors.New("keeper authentication failed")
+)
+
+// buffer accumulates request body encoded by jute rules
+type buffer struct {
+	bytes.Buffer
+}
+
+func (b *buffer) writeInt(v int32) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *buffer) writeLong(v int64) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *buffer) writeBool(v bool) {
+	if v {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+}
+
+// writeString writes string, empty string is written as null
+func (b *buffer) writeString(s string) {
+	if s == "" {
+		b.writeInt(-1)
+		return
+	}
+	b.writeInt(int32(len(s)))
+	b.WriteString(s)
+}
+
+func (b *buffer) writeBuffer(data []byte) {
+	b.writeInt(int32(len(data)))
+	b.Write(data)
+}
+
+// readString reads string, null string is read as empty one
+func readString(r *bytes.Reader) (string, error) {
+	var size int32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return "", err
+	}
+	if size <= 0 {
+		return "", nil
+	}
+	if int(size) > r.Len() {
+		return "", fmt.Errorf("unexpected string size: %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// writePacket writes length-prefixed packet
+func writePacket(conn net.Conn, body *buffer) error {
+	packet := &buffer{}
+	packet.writeInt(int32(body.Len()))
+	packet.Write(body.Bytes())
+	_, err := conn.Write(packet.Bytes())
+	return err
+}
+
+// readPacket reads length-prefixed packet
+func readPacket(conn net.Conn) (*bytes.Reader, error) {
+	var size int32
+	if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if (size < 0) || (size > maxPacketSize) {
+		return nil, fmt.Errorf("unexpected packet size: %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// connect establishes new session
+func connect(conn net.Conn, timeout time.Duration) error {
+	req := &buffer{}
+	// protocolVersion
+	req.writeInt(0)
+	// lastZxidSeen
+	req.writeLong(0)
+	// timeOut
+	req.writeInt(int32(timeout.Milliseconds()))
+	// sessionId
+	req.writeLong(0)
+	// passwd
+	req.writeBuffer(make([]byte, 16))
+	// readOnly
+	req.writeBool(false)
+	if err := writePacket(conn, req); err != nil {
+		return err
+	}
+
+	reply, err := readPacket(conn)
+	if err != nil {
+		return err
+	}
+	var protocolVersion, sessionTimeout int32
+	var sessionID int64
+	_ = binary.Read(reply, binary.BigEndian, &protocolVersion)
+	_ = binary.Read(reply, binary.BigEndian, &sessionTimeout)
+	if err := binary.Read(reply, binary.BigEndian, &sessionID); err != nil {
+		return err
+	}
+	if sessionID == 0 {
+		return fmt.Errorf("session is not established")
+	}
+	return nil
+}
+
+// request sends request of specified type and waits for the reply to it. Body of the reply is returned
+func request(conn net.Conn, xid, op int32, body *buffer) (*bytes.Reader, error) {
+	req := &buffer{}
+	req.writeInt(xid)
+	req.writeInt(op)
+	if body != nil {
+		req.Write(body.Bytes())
+	}
+	if err := writePacket(conn, req); err != nil {
+		return nil, err
+	}
+
+	for {
+		reply, err := readPacket(conn)
+		if err != nil {
+			return nil, err
+		}
+		var replyXid, errCode int32
+		var zxid int64
+		_ = binary.Read(reply, binary.BigEndian, &replyXid)
+		_ = binary.Read(reply, binary.BigEndian, &zxid)
+		if err := binary.Read(reply, binary.BigEndian, &errCode); err != nil {
+			return nil, err
+		}
+
+		switch replyXid {
+		case xidWatcherEvent, xidPing:
+			// Not a reply to the request
+			continue
+		case xid:
+			return reply, replyError(errCode)
+		default:
+			return nil, fmt.Errorf("unexpected reply xid: %d, expected: %d", replyXid, xid)
+		}
+	}
+}
+
+// replyError converts error code of the reply into error
+func replyError(code int32) error {
+	switch code {
+	case errCodeOK:
+		return nil
+	case errCodeUnimplemented, errCodeReconfigDisabled:
+		return fmt.Errorf("%w: code %d", ErrReconfigNotSupported, code)
+	case errCodeNewConfigNoQuorum, errCodeReconfigInProgress:
+		return fmt.Errorf("%w: code %d", ErrReconfigRejected, code)
+	case errCodeNoNode:
+		return fmt.Errorf("%w: code %d", ErrNoNode, code)
+	case errCodeAuthFailed:
+		return fmt.Errorf("%w: code %d", ErrAuthFailed, code)
+	default:
+		return fmt.Errorf("keeper error code: %d", code)
+	}
+}
+
+// reconfig runs incremental reconfiguration without config version check
+func reconfig(conn net.Conn, joining, leaving string) error {
+	body := &buffer{}
+	// joiningServers
+	body.writeString(joining)
+	// leavingServers
+	body.writeString(leaving)
+	// newMembers, used by non-incremental reconfiguration only
+	body.writeString("")
+	// curConfigId, -1 means no version check
+	body.writeLong(-1)
+	_, err := request(conn, 1, opReconfig, body)
+	return err
+}
+
+// readBuffer reads length-prefixed data, null data is read as empty one
+func readBuffer(r *bytes.Reader) ([]byte, error) {
+	var size int32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, nil
+	}
+	if int(size) > r.Len() {
+		return nil, fmt.Errorf("unexpected data size: %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// getData gets data of the node
+func getData(conn net.Conn, xid int32, path string) ([]byte, error) {
+	body := &buffer{}
+	body.writeString(path)
+	// watch
+	body.writeBool(false)
+	reply, err := request(conn, xid, opGetData, body)
+	if err != nil {
+		return nil, err
+	}
+	return readBuffer(reply)
+}
+
+// getChildren lists names of children of the node
+func getChildren(conn net.Conn, xid int32, path string) ([]string, error) {
+	body := &buffer{}
+	body.writeString(path)
+	// watch
+	body.writeBool(false)
+	reply, err := request(conn, xid, opGetChildren, body)
+	if err != nil {
+		return nil, err
+	}
+	var count int32
+	if err := binary.Read(reply, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	var children []string
+	for i := int32(0); i < count; i++ {
+		child, err := readString(reply)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// deleteNode deletes the node having no children, of any version
+func deleteNode(conn net.Conn, xid int32, path string) error {
+	body := &buffer{}
+	body.writeString(path)
+	// version, -1 means no version check
+	body.writeInt(-1)
+	_, err := request(conn, xid, opDelete, body)
+	return err
+}
+
+// addAuth adds credentials of the specified scheme, such as 'digest' one having 'user:password' format, to the session
+func addAuth(conn net.Conn, scheme, auth string) error {
+	body := &buffer{}
+	// type, unused
+	body.writeInt(0)
+	body.writeString(scheme)
+	body.writeBuffer([]byte(auth))
+	_, err := request(conn, xidAuth, opAuth, body)
+	return err
+}
+
+// disconnect closes the session
+func disconnect(conn net.Conn) error {
+	_, err := request(conn, 2, opClose, nil)
+	return err
+}
@@ -0,0 +1,276 @@
+package keeper
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeeperRequest describes request received by the fake Keeper
+type fakeKeeperRequest struct {
+	op   int32
+	body *bytes.Reader
+}
+
+// fakeKeeperReply describes reply the fake Keeper sends to a request
+type fakeKeeperReply struct {
+	errCode int32
+	body    []byte
+}
+
+// newFakeKeeper starts Keeper, which serves a single session and replies to requests via handle.
+// Returns client to the fake Keeper and channel closed once the session is over
+func newFakeKeeper(t *testing.T, handle func(req fakeKeeperRequest) fakeKeeperReply) (*Client, chan struct{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Connect request is replied with established session
+		if _, err := readPacket(conn); err != nil {
+			return
+		}
+		session := &buffer{}
+		session.writeInt(0)
+		session.writeInt(10000)
+		session.writeLong(1)
+		session.writeBuffer(make([]byte, 16))
+		_ = writePacket(conn, session)
+
+		for {
+			packet, err := readPacket(conn)
+			if err != nil {
+				return
+			}
+			var xid, op int32
+			_ = binary.Read(packet, binary.BigEndian, &xid)
+			_ = binary.Read(packet, binary.BigEndian, &op)
+			reply := handle(fakeKeeperRequest{op: op, body: packet})
+
+			header := &buffer{}
+			header.writeInt(xid)
+			header.writeLong(1)
+			header.writeInt(reply.errCode)
+			header.Write(reply.body)
+			_ = writePacket(conn, header)
+			if op == opClose {
+				return
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return NewClient(host, p).SetTimeout(5 * time.Second), done
+}
+
+func TestClientReconfig(t *testing.T) {
+	var joining, leaving, members string
+	var configID int64
+	client, done := newFakeKeeper(t, func(req fakeKeeperRequest) fakeKeeperReply {
+		if req.op == opReconfig {
+			joining, _ = readString(req.body)
+			leaving, _ = readString(req.body)
+			members, _ = readString(req.body)
+			_ = binary.Read(req.body, binary.BigEndian, &configID)
+		}
+		return fakeKeeperReply{}
+	})
+
+	err := client.Reconfig(context.Background(), []string{"server.3=keeper-3:9234;participant;1", "server.4=keeper-4:9234;participant;1"}, []int{1, 2})
+	require.NoError(t, err)
+	<-done
+	require.Equal(t, "server.3=keeper-3:9234;participant;1,server.4=keeper-4:9234;participant;1", joining)
+	require.Equal(t, "1,2", leaving)
+	require.Equal(t, "", members)
+	require.Equal(t, int64(-1), configID)
+}
+
+func TestClientReconfigErrors(t *testing.T) {
+	for code, expected := range map[int32]error{
+		errCodeReconfigDisabled:   ErrReconfigNotSupported,
+		errCodeUnimplemented:      ErrReconfigNotSupported,
+		errCodeReconfigInProgress: ErrReconfigRejected,
+		errCodeNewConfigNoQuorum:  ErrReconfigRejected,
+	} {
+		client, _ := newFakeKeeper(t, func(req fakeKeeperRequest) fakeKeeperReply {
+			return fakeKeeperReply{errCode: code}
+		})
+		err := client.Reconfig(context.Background(), nil, []int{1})
+		require.True(t, errors.Is(err, expected), "code: %d err: %v", code, err)
+	}
+}
+
+func TestClientGetRaftConfig(t *testing.T) {
+	var path string
+	client, done := newFakeKeeper(t, func(req fakeKeeperRequest) fakeKeeperReply {
+		if req.op != opGetData {
+			return fakeKeeperReply{}
+		}
+		path, _ = readString(req.body)
+		reply := &buffer{}
+		reply.writeBuffer([]byte("server.0=keeper-0:9234;participant;1\nserver.1=keeper-1:9234;participant;2"))
+		return fakeKeeperReply{body: reply.Bytes()}
+	})
+
+	members, err := client.GetRaftConfig(context.Background())
+	require.NoError(t, err)
+	<-done
+	require.Equal(t, raftConfigPath, path)
+	require.Equal(t, map[int]RaftMember{
+		0: {ID: 0, Address: "keeper-0:9234", Role: RaftRoleParticipant, Priority: 1},
+		1: {ID: 1, Address: "keeper-1:9234", Role: RaftRoleParticipant, Priority: 2},
+	}, members)
+}
+
+func TestClientGetRaftConfigNotSupported(t *testing.T) {
+	client, _ := newFakeKeeper(t, func(req fakeKeeperRequest) fakeKeeperReply {
+		return fakeKeeperReply{errCode: errCodeNoNode}
+	})
+
+	_, err := client.GetRaftConfig(context.Background())
+	require.True(t, errors.Is(err, ErrReconfigNotSupported), "err: %v", err)
+}
+
+func TestReadString(t *testing.T) {
+	for _, s := range []string{"query-0000000001", "a"} {
+		b := &buffer{}
+		b.writeString(s)
+		read, err := readString(bytes.NewReader(b.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, s, read)
+	}
+
+	// Null string is read as empty one
+	b := &buffer{}
+	b.writeInt(-1)
+	read, err := readString(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, "", read)
+
+	// String longer than the data left is refused
+	b = &buffer{}
+	b.writeInt(10)
+	b.WriteString("short")
+	_, err = readString(bytes.NewReader(b.Bytes()))
+	require.Error(t, err)
+}
+
+// newFakeKeeperTree starts fake Keeper serving children listing and deletion of nodes of the tree.
+// Tree lists children of each node, nodes are deleted from the tree as they are deleted in Keeper
+func newFakeKeeperTree(t *testing.T, tree map[string][]string, auth *string) (*Client, chan struct{}) {
+	return newFakeKeeper(t, func(req fakeKeeperRequest) fakeKeeperReply {
+		switch req.op {
+		case opAuth:
+			var _type int32
+			_ = binary.Read(req.body, binary.BigEndian, &_type)
+			scheme, _ := readString(req.body)
+			data, _ := readBuffer(req.body)
+			*auth = scheme + ":" + string(data)
+		case opGetChildren:
+			path, _ := readString(req.body)
+			children, ok := tree[path]
+			if !ok {
+				return fakeKeeperReply{errCode: errCodeNoNode}
+			}
+			reply := &buffer{}
+			reply.writeInt(int32(len(children)))
+			for _, child := range children {
+				reply.writeString(child)
+			}
+			return fakeKeeperReply{body: reply.Bytes()}
+		case opDelete:
+			path, _ := readString(req.body)
+			var version int32
+			_ = binary.Read(req.body, binary.BigEndian, &version)
+			if version != -1 {
+				// Node of any version is expected to be deleted
+				return fakeKeeperReply{errCode: -103}
+			}
+			if _, ok := tree[path]; !ok {
+				return fakeKeeperReply{errCode: errCodeNoNode}
+			}
+			if len(tree[path]) > 0 {
+				// Node having children can not be deleted
+				return fakeKeeperReply{errCode: -111}
+			}
+			delete(tree, path)
+			parent := path[:strings.LastIndex(path, "/")]
+			name := path[strings.LastIndex(path, "/")+1:]
+			var children []string
+			for _, child := range tree[parent] {
+				if child != name {
+					children = append(children, child)
+				}
+			}
+			tree[parent] = children
+		}
+		return fakeKeeperReply{}
+	})
+}
+
+func TestClientDeleteRecursive(t *testing.T) {
+	tree := map[string][]string{
+		"/ddl":                                  {"query-0000000001", "query-0000000002"},
+		"/ddl/query-0000000001":                 {"finished", "active"},
+		"/ddl/query-0000000001/finished":        {"host-0"},
+		"/ddl/query-0000000001/finished/host-0": {},
+		"/ddl/query-0000000001/active":          {},
+		"/ddl/query-0000000002":                 {},
+	}
+	var auth string
+	client, done := newFakeKeeperTree(t, tree, &auth)
+	client.SetAuth(AuthSchemeDigest, "user:password")
+
+	err := client.DeleteRecursive(context.Background(), "/ddl/query-0000000001")
+	require.NoError(t, err)
+	<-done
+	require.Equal(t, "digest:user:password", auth)
+	require.Equal(t, map[string][]string{
+		"/ddl":                  {"query-0000000002"},
+		"/ddl/query-0000000002": {},
+	}, tree)
+}
+
+func TestClientDeleteRecursiveNoNode(t *testing.T) {
+	tree := map[string][]string{
+		"/ddl": {},
+	}
+	var auth string
+	client, done := newFakeKeeperTree(t, tree, &auth)
+
+	err := client.DeleteRecursive(context.Background(), "/ddl/query-0000000001")
+	require.NoError(t, err)
+	<-done
+	require.Equal(t, "", auth)
+	require.Equal(t, map[string][]string{"/ddl": {}}, tree)
+}
+
+func TestClientAuthFailed(t *testing.T) {
+	client, _ := newFakeKeeper(t, func(req fakeKeeperRequest) fakeKeeperReply {
+		if req.op == opAuth {
+			return fakeKeeperReply{errCode: errCodeAuthFailed}
+		}
+		return fakeKeeperReply{}
+	})
+	client.SetAuth(AuthSchemeDigest, "user:wrong")
+
+	err := client.DeleteRecursive(context.Background(), "/ddl/query-0000000001")
+	require.True(t, errors.Is(err, ErrAuthFailed), "err: %v", err)
+}
@@ -23,3 +23,22 @@ import (
 func getHeadlessServiceName(chk *api.ClickHouseKeeperInstallation) string {
 	return fmt.Sprintf("%s-headless", chk.GetName())
 }
+
+func getStatefulSetName(chk *api.ClickHouseKeeperInstallation) string {
+	return chk.GetName()
+}
+
+// GetMemberPodName gets name of the pod of the keeper member
+func GetMemberPodName(chk *api.ClickHouseKeeperInstallation, id int) string {
+	return fmt.Sprintf("%s-%d", getStatefulSetName(chk), id)
+}
+
+// GetMemberPVCName gets name of the PVC of the keeper member made of the specified volume claim template
+func GetMemberPVCName(chk *api.ClickHouseKeeperInstallation, claim string, id int) string {
+	return fmt.Sprintf("%s-%s", claim, GetMemberPodName(chk, id))
+}
+
+// GetMemberHostname gets FQDN of the keeper member
+func GetMemberHostname(chk *api.ClickHouseKeeperInstallation, id int) string {
+	return fmt.Sprintf("%s.%s.%s.svc.cluster.local", GetMemberPodName(chk, id), getHeadlessServiceName(chk), chk.Namespace)
+}
@@ -17,6 +17,7 @@ package chk
 import (
 	"strings"
 
+	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
@@ -42,12 +43,15 @@ func NewNormalizerContext(options *normalizer.Options) *NormalizerContext {
 
 // Normalizer specifies structures normalizer
 type Normalizer struct {
-	ctx *NormalizerContext
+	ctx       *NormalizerContext
+	templates TemplatesGetter
 }
 
 // NewNormalizer creates new normalizer
-func NewNormalizer() *Normalizer {
-	return &Normalizer{}
+func NewNormalizer(templates TemplatesGetter) *Normalizer {
+	return &Normalizer{
+		templates: templates,
+	}
 }
 
 func newCHK() *apiChk.ClickHouseKeeperInstallation {
@@ -78,6 +82,14 @@ func (n *Normalizer) CreateTemplatedCHK(
 
 	n.ctx.chk = newCHK()
 
+	// Apply templates - both auto and explicitly requested - on top of context target
+	usedTemplates := n.applyCHKTemplates(n.ctx.chk, chk)
+	for _, template := range usedTemplates {
+		n.ctx.chk.EnsureStatus().PushUsedTemplate(template)
+	}
+	n.ctx.chk.EnsureStatus().TemplatesChecksum = n.getTemplatesChecksum(usedTemplates, chk)
+
+	// After all templates applied, place provided CHK on top of the whole stack
 	n.ctx.chk.MergeFrom(chk, apiChi.MergeTypeOverrideByNonEmptyValues)
 
 	return n.normalize()
@@ -235,10 +247,42 @@ func (n *Normalizer) normalizeCluster(cluster *apiChk.ChkCluster) *apiChk.ChkClu
 		cluster.Layout = apiChk.NewChkClusterLayout()
 	}
 	cluster.Layout = n.normalizeClusterLayoutShardsCountAndReplicasCount(cluster.Layout)
+	cluster.ZoneAwareness = n.normalizeClusterZoneAwareness(cluster.ZoneAwareness)
 
 	return cluster
 }
 
+// normalizeClusterZoneAwareness normalizes zone awareness of a cluster
+func (n *Normalizer) normalizeClusterZoneAwareness(zoneAwareness *apiChk.ChkZoneAwareness) *apiChk.ChkZoneAwareness {
+	if zoneAwareness == nil {
+		// No zone awareness requested
+		return nil
+	}
+
+	if zoneAwareness.TopologyKey == "" {
+		zoneAwareness.TopologyKey = core.LabelTopologyZone
+	}
+
+	if zoneAwareness.MaxSkew < 1 {
+		zoneAwareness.MaxSkew = 1
+	}
+
+	// Zones must be named and priority can not be negative
+	var zones []apiChk.ChkZone
+	for _, zone := range zoneAwareness.Zones {
+		if zone.Name == "" {
+			continue
+		}
+		if zone.Priority < 0 {
+			zone.Priority = 0
+		}
+		zones = append(zones, zone)
+	}
+	zoneAwareness.Zones = zones
+
+	return zoneAwareness
+}
+
 // normalizeClusterLayoutShardsCountAndReplicasCount ensures at least 1 shard and 1 replica counters
 func (n *Normalizer) normalizeClusterLayoutShardsCountAndReplicasCount(layout *apiChk.ChkClusterLayout) *apiChk.ChkClusterLayout {
 	// Ensure layout
@@ -0,0 +1,196 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chk
+
+import (
+	"encoding/json"
+	"sort"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	apiChk "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	apiChi "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/chop"
+	templatesNormalizer "github.com/altinity/clickhouse-operator/pkg/model/chi/normalizer/templates"
+	"github.com/altinity/clickhouse-operator/pkg/util"
+)
+
+// TemplatesGetter gets all CHK templates available to the normalizer
+type TemplatesGetter func() []*apiChk.ClickHouseKeeperInstallation
+
+// getTemplates gets all CHK templates available
+func (n *Normalizer) getTemplates() []*apiChk.ClickHouseKeeperInstallation {
+	if n.templates == nil {
+		return nil
+	}
+	return n.templates()
+}
+
+// findTemplate finds specified template within possibly specified namespace
+func (n *Normalizer) findTemplate(templateRef *apiChi.TemplateRef, fallbackNamespace string) *apiChk.ClickHouseKeeperInstallation {
+	templates := n.getTemplates()
+
+	// Try to find direct match
+	for _, template := range templates {
+		if template.MatchFullName(templateRef.Namespace, templateRef.Name) {
+			return template
+		}
+	}
+
+	if templateRef.Namespace != "" {
+		// With fully-specified template namespace+name pair exact match is applicable only
+		return nil
+	}
+
+	// Look for templates with specified name in "predefined" namespace
+	for _, template := range templates {
+		if template.MatchFullName(fallbackNamespace, templateRef.Name) {
+			return template
+		}
+	}
+
+	return nil
+}
+
+// getAutoTemplates gets all auto templates.
+// Auto templates are sorted alphabetically by tuple: namespace, name
+func (n *Normalizer) getAutoTemplates() (autoTemplates []*apiChk.ClickHouseKeeperInstallation) {
+	for _, template := range n.getTemplates() {
+		if template.IsAuto() {
+			autoTemplates = append(autoTemplates, template)
+		}
+	}
+	sort.SliceStable(autoTemplates, func(i, j int) bool {
+		if autoTemplates[i].Namespace != autoTemplates[j].Namespace {
+			return autoTemplates[i].Namespace < autoTemplates[j].Namespace
+		}
+		return autoTemplates[i].Name < autoTemplates[j].Name
+	})
+	return autoTemplates
+}
+
+// prepareListOfTemplates prepares list of CHK templates to be used by the CHK
+func (n *Normalizer) prepareListOfTemplates(chk *apiChk.ClickHouseKeeperInstallation) (templates []*apiChi.TemplateRef) {
+	// 1. Get list of auto templates available
+	for _, template := range n.getAutoTemplates() {
+		log.V(1).M(chk).F().Info(
+			"Adding auto-template to the list of applicable templates: %s/%s ",
+			template.Namespace, template.Name)
+		templates = append(templates, &apiChi.TemplateRef{
+			Name:      template.Name,
+			Namespace: template.Namespace,
+			UseType:   templatesNormalizer.UseTypeMerge,
+		})
+	}
+	// 2. Append templates which are explicitly requested by the CHK
+	templates = append(templates, chk.Spec.UseTemplates...)
+	// 3 Normalize list of templates
+	templates = templatesNormalizer.NormalizeTemplatesList(templates)
+
+	log.V(1).M(chk).F().Info("Found applicable templates num: %d", len(templates))
+	return templates
+}
+
+// applyCHKTemplates applies templates over target
+func (n *Normalizer) applyCHKTemplates(target, chk *apiChk.ClickHouseKeeperInstallation) (appliedTemplates []*apiChi.TemplateRef) {
+	for _, templateRef := range n.prepareListOfTemplates(chk) {
+		if n.applyTemplate(target, templateRef, chk) {
+			appliedTemplates = append(appliedTemplates, templateRef)
+		}
+	}
+
+	log.V(1).M(chk).F().Info("Applied templates num: %d", len(appliedTemplates))
+	return appliedTemplates
+}
+
+// getTemplatesChecksum gets checksum of templates applied to the CHK,
+// so edit of any of them is told from a resync, as templates edits do not bump generation of the CHK.
+// Checksum is empty in case no templates are applied
+func (n *Normalizer) getTemplatesChecksum(templateRefs []*apiChi.TemplateRef, chk *apiChk.ClickHouseKeeperInstallation) string {
+	var parts []interface{}
+	for _, templateRef := range templateRefs {
+		if template := n.findTemplate(templateRef, chk.Namespace); template != nil {
+			parts = append(parts, []interface{}{template.Labels, template.Annotations, template.Spec})
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(parts)
+	return util.HashIntoString(b)
+}
+
+// applyTemplate applies a template over target
+// `chk` is used to determine whether the template should be applied or not only
+func (n *Normalizer) applyTemplate(
+	target *apiChk.ClickHouseKeeperInstallation,
+	templateRef *apiChi.TemplateRef,
+	chk *apiChk.ClickHouseKeeperInstallation,
+) bool {
+	if templateRef == nil {
+		log.Warning("unable to apply template - nil templateRef provided")
+		return false
+	}
+
+	template := n.findTemplate(templateRef, chk.Namespace)
+	if template == nil {
+		log.V(1).M(templateRef.Namespace, templateRef.Name).F().Warning(
+			"skip template - UNABLE to find by templateRef: %s/%s",
+			templateRef.Namespace, templateRef.Name)
+		return false
+	}
+
+	selector := template.Spec.Templating.GetSelector()
+	if !selector.Matches(chk.Labels) {
+		// This template does not want to be applied to this CHK
+		log.V(1).M(templateRef.Namespace, templateRef.Name).F().Info(
+			"Skip template: %s/%s. Selector: %v does not match labels: %v",
+			templateRef.Namespace, templateRef.Name, selector, chk.Labels)
+		return false
+	}
+
+	log.V(1).M(templateRef.Namespace, templateRef.Name).F().Info(
+		"Apply template: %s/%s. Selector: %v matches labels: %v",
+		templateRef.Namespace, templateRef.Name, selector, chk.Labels)
+
+	mergeFromTemplate(target, template)
+
+	return true
+}
+
+func mergeFromTemplate(target, template *apiChk.ClickHouseKeeperInstallation) *apiChk.ClickHouseKeeperInstallation {
+	// Merge template's Labels over target's Labels
+	target.Labels = util.MergeStringMapsOverwrite(
+		target.Labels,
+		util.CopyMapFilter(
+			template.Labels,
+			chop.Config().Label.Include,
+			chop.Config().Label.Exclude,
+		),
+	)
+
+	// Merge template's Annotations over target's Annotations
+	target.Annotations = util.MergeStringMapsOverwrite(
+		target.Annotations, util.CopyMapFilter(
+			template.Annotations,
+			chop.Config().Annotation.Include,
+			append(chop.Config().Annotation.Exclude, util.ListSkippedAnnotations()...),
+		),
+	)
+
+	// Merge template's Spec over target's Spec
+	(&target.Spec).MergeFrom(&template.Spec, apiChi.MergeTypeOverrideByNonEmptyValues)
+
+	return target
+}
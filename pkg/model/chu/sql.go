@@ -0,0 +1,156 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/model/clickhouse"
+)
+
+var (
+	// privilegeRegexp matches privilege, optionally narrowed down to columns, such as SELECT or SELECT(a, b)
+	privilegeRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*(\([A-Za-z0-9_, ]+\))?$`)
+	// grantTargetRegexp matches database and table privileges are granted on, such as '*.*', 'db.*' or 'db.table'.
+	// Quoted names must not contain backslashes, since a backslash escapes the closing backtick
+	grantTargetRegexp = regexp.MustCompile("^(\\*|[A-Za-z0-9_]+|`[^`\\\\]+`)(\\.(\\*|[A-Za-z0-9_]+|`[^`\\\\]+`))?$")
+)
+
+// Validate checks whether user is specified completely and can be safely expressed in SQL
+func Validate(chu *api.ClickHouseUser) error {
+	switch {
+	case chu.Spec.CHI == "":
+		return fmt.Errorf("no CHI specified")
+	case !chu.Spec.Password.HasValue():
+		return fmt.Errorf("no password secret specified")
+	}
+	for _, grant := range chu.Spec.Grants {
+		if !grantTargetRegexp.MatchString(grant.On) {
+			return fmt.Errorf("invalid grant target: %s", grant.On)
+		}
+		if len(grant.Privileges) == 0 {
+			return fmt.Errorf("no privileges specified for grant on %s", grant.On)
+		}
+		for _, privilege := range grant.Privileges {
+			if !privilegeRegexp.MatchString(privilege) {
+				return fmt.Errorf("invalid privilege: %s", privilege)
+			}
+		}
+	}
+	return nil
+}
+
+// CreatePasswordHash creates SHA256 hash of the password, so plain password is sent to ClickHouse neither
+func CreatePasswordHash(password string) string {
+	hash := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateUserSQLs creates SQLs which bring the user on a host to the state specified by ClickHouseUser.
+// Roles and grants which were applied earlier and are not specified anymore are revoked.
+// All statements are idempotent, so they are safe to be run repeatedly
+func CreateUserSQLs(chu *api.ClickHouseUser, passwordHash string) (sqls []string) {
+	name := clickhouse.QuoteIdentifier(chu.GetUserName())
+	status := chu.GetStatus()
+
+	if (status != nil) && (status.UserName != "") && (status.UserName != chu.GetUserName()) {
+		// User is renamed
+		sqls = append(sqls, CreateDropUserSQL(status.UserName))
+	}
+
+	identified := fmt.Sprintf("IDENTIFIED WITH sha256_hash BY %s", clickhouse.Quote(passwordHash))
+	sqls = append(sqls, fmt.Sprintf("CREATE USER IF NOT EXISTS %s %s", name, identified))
+
+	alter := fmt.Sprintf("ALTER USER %s %s %s", name, identified, createHosts(chu.Spec.Networks))
+	if chu.Spec.Profile != "" {
+		alter += fmt.Sprintf(" SETTINGS PROFILE %s", clickhouse.Quote(chu.Spec.Profile))
+	}
+	sqls = append(sqls, alter)
+
+	if status != nil {
+		for _, role := range status.Roles {
+			if !contains(chu.Spec.Roles, role) {
+				sqls = append(sqls, fmt.Sprintf("REVOKE %s FROM %s", clickhouse.QuoteIdentifier(role), name))
+			}
+		}
+		for _, grant := range status.Grants {
+			if !containsGrant(chu.Spec.Grants, grant) {
+				sqls = append(sqls, fmt.Sprintf("REVOKE %s ON %s FROM %s", strings.Join(grant.Privileges, ", "), grant.On, name))
+			}
+		}
+	}
+
+	if len(chu.Spec.Roles) > 0 {
+		var roles []string
+		for _, role := range chu.Spec.Roles {
+			roles = append(roles, clickhouse.QuoteIdentifier(role))
+		}
+		sqls = append(sqls,
+			fmt.Sprintf("GRANT %s TO %s", strings.Join(roles, ", "), name),
+			fmt.Sprintf("ALTER USER %s DEFAULT ROLE ALL", name),
+		)
+	}
+	for _, grant := range chu.Spec.Grants {
+		sql := fmt.Sprintf("GRANT %s ON %s TO %s", strings.Join(grant.Privileges, ", "), grant.On, name)
+		if grant.WithGrantOption {
+			sql += " WITH GRANT OPTION"
+		}
+		sqls = append(sqls, sql)
+	}
+
+	return sqls
+}
+
+// CreateDropUserSQL creates SQL which drops the user
+func CreateDropUserSQL(name string) string {
+	return fmt.Sprintf("DROP USER IF EXISTS %s", clickhouse.QuoteIdentifier(name))
+}
+
+// createHosts creates HOST clause, which lists networks the user is allowed to connect from
+func createHosts(networks []string) string {
+	if len(networks) == 0 {
+		return "HOST ANY"
+	}
+	var hosts []string
+	for _, network := range networks {
+		hosts = append(hosts, "IP "+clickhouse.Quote(network))
+	}
+	return "HOST " + strings.Join(hosts, ", ")
+}
+
+// contains checks whether list contains the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsGrant checks whether list contains the grant
+func containsGrant(list []api.ChuGrant, grant api.ChuGrant) bool {
+	for _, item := range list {
+		if item.Equals(grant) {
+			return true
+		}
+	}
+	return false
+}
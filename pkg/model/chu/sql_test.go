@@ -0,0 +1,64 @@
+package chu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+func newTestCHU(grants ...api.ChuGrant) *api.ClickHouseUser {
+	chu := &api.ClickHouseUser{}
+	chu.Name = "analyst"
+	chu.Spec.CHI = "demo"
+	chu.Spec.Password = &api.SettingSource{
+		ValueFrom: &api.DataSource{
+			SecretKeyRef: &core.SecretKeySelector{
+				LocalObjectReference: core.LocalObjectReference{Name: "credentials"},
+				Key:                  "password",
+			},
+		},
+	}
+	chu.Spec.Grants = grants
+	return chu
+}
+
+func TestValidateGrantTarget(t *testing.T) {
+	for _, on := range []string{"*.*", "db.*", "db.table", "`my db`.`my table`", "db"} {
+		require.NoError(t, Validate(newTestCHU(api.ChuGrant{On: on, Privileges: []string{"SELECT"}})), on)
+	}
+	for _, on := range []string{"", "db.table; DROP", "`db\\`.x", "`db\\`", "db.`t\\`", "`db`.`t` TO x"} {
+		require.Error(t, Validate(newTestCHU(api.ChuGrant{On: on, Privileges: []string{"SELECT"}})), on)
+	}
+}
+
+func TestValidatePrivilege(t *testing.T) {
+	require.NoError(t, Validate(newTestCHU(api.ChuGrant{On: "db.*", Privileges: []string{"SELECT(a, b)", "ALTER UPDATE"}})))
+	require.Error(t, Validate(newTestCHU(api.ChuGrant{On: "db.*", Privileges: []string{"SELECT; DROP"}})))
+	require.Error(t, Validate(newTestCHU(api.ChuGrant{On: "db.*"})))
+}
+
+func TestCreateUserSQLs(t *testing.T) {
+	chu := newTestCHU(api.ChuGrant{On: "db.*", Privileges: []string{"SELECT"}, WithGrantOption: true})
+	chu.Spec.Roles = []string{"reporting"}
+	chu.Status = &api.ChuStatus{
+		UserName: "analyst",
+		Roles:    []string{"old"},
+		Grants:   []api.ChuGrant{{On: "old.*", Privileges: []string{"INSERT"}}},
+	}
+	require.Equal(t, []string{
+		"CREATE USER IF NOT EXISTS `analyst` IDENTIFIED WITH sha256_hash BY 'hash'",
+		"ALTER USER `analyst` IDENTIFIED WITH sha256_hash BY 'hash' HOST ANY",
+		"REVOKE `old` FROM `analyst`",
+		"REVOKE INSERT ON old.* FROM `analyst`",
+		"GRANT `reporting` TO `analyst`",
+		"ALTER USER `analyst` DEFAULT ROLE ALL",
+		"GRANT SELECT ON db.* TO `analyst` WITH GRANT OPTION",
+	}, CreateUserSQLs(chu, "hash"))
+}
+
+func TestCreateDropUserSQL(t *testing.T) {
+	require.Equal(t, "DROP USER IF EXISTS `a\\`b`", CreateDropUserSQL("a`b"))
+}
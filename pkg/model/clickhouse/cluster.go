@@ -16,19 +16,27 @@ package clickhouse
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
+	"time"
 
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 	r "github.com/altinity/clickhouse-operator/pkg/util/retry"
 )
 
+// SlowQueryObserver is notified about each SQL statement running longer than slow query threshold on the host
+type SlowQueryObserver func(host, sql string, duration time.Duration)
+
 // Cluster specifies clickhouse cluster object
 type Cluster struct {
 	*ClusterConnectionParams
 	Hosts []string
 	l     log.Announcer
+	// slowQueryObserver is notified about slow queries, if any
+	slowQueryObserver SlowQueryObserver
 }
 
 // NewCluster creates new clickhouse cluster object
@@ -47,6 +55,36 @@ func (c *Cluster) SetLog(a log.Announcer) *Cluster {
 	return c
 }
 
+// SetSlowQueryObserver sets function to be notified about slow queries
+func (c *Cluster) SetSlowQueryObserver(observer SlowQueryObserver) *Cluster {
+	if c == nil {
+		return nil
+	}
+	c.slowQueryObserver = observer
+	return c
+}
+
+// observeSlowQuery reports SQL statement run on the host via the connection in case it ran longer than slow query threshold.
+// Statement is logged along with DSN, which has credentials hidden, and with fingerprint the statement is reported by
+func (c *Cluster) observeSlowQuery(host string, conn *Connection, sql string, start time.Time) {
+	duration := time.Since(start)
+	threshold := c.GetSlowQueryThreshold()
+	if (threshold <= 0) || (duration < threshold) {
+		return
+	}
+	c.l.V(1).F().Warning("Slow query (%s) took %s fingerprint %s for SQL: %s",
+		conn.Params().GetDSNWithHiddenCredentials(), duration, SQLFingerprint(sql), sql)
+	if c.slowQueryObserver != nil {
+		c.slowQueryObserver(host, sql, duration)
+	}
+}
+
+// SQLFingerprint gets short fingerprint of SQL statement, which is the same regardless of whitespaces in the statement
+func SQLFingerprint(sql string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(sql), " ")))
+	return hex.EncodeToString(sum[:8])
+}
+
 // SetCredentials sets endpoint credentials
 //func (c *Cluster) SetCredentials(clusterCredentials *ClusterCredentials) *Cluster {
 //	if c == nil {
@@ -70,6 +108,11 @@ func (c *Cluster) getHostConnection(host string) *Connection {
 	return GetPooledDBConnection(c.NewEndpointConnectionParams(host)).SetLog(c.l)
 }
 
+// IsHostReachable checks whether connection to the host can be established
+func (c *Cluster) IsHostReachable(ctx context.Context, host string) bool {
+	return c.getHostConnection(host).ensureConnected(ctx)
+}
+
 // QueryAny walks over all endpoints and runs query sequentially on each of them.
 // In case endpoint returned result, walk is completed and result is returned.
 // In case endpoint failed, continue with the next endpoint.
@@ -82,7 +125,10 @@ func (c *Cluster) QueryAny(ctx context.Context, sql string) (*QueryResult, error
 		}
 
 		c.l.V(1).Info("Run query on: %s of %v", host, c.Hosts)
-		query, err := c.getHostConnection(host).QueryContext(ctx, sql)
+		conn := c.getHostConnection(host)
+		start := time.Now()
+		query, err := conn.QueryContext(ctx, sql)
+		c.observeSlowQuery(host, conn, sql, start)
 		if err == nil {
 			// Endpoint returned result, no need to iterate more
 			return query, nil
@@ -154,12 +200,16 @@ func (c *Cluster) exec(ctx context.Context, host string, queries []string, _opts
 					// Skip malformed or already executed SQL query, move to the next one
 					continue
 				}
+				start := time.Now()
 				err := conn.Exec(ctx, sql, opts)
+				c.observeSlowQuery(host, conn, sql, start)
 				if err != nil && strings.Contains(err.Error(), "Code: 253") && strings.Contains(sql, "CREATE TABLE") {
 					// WARNING: error message or code may change in newer ClickHouse versions
 					c.l.V(1).M(host).F().Info("Replica is already in ZooKeeper. Trying ATTACH TABLE instead")
 					sqlAttach := strings.ReplaceAll(sql, "CREATE TABLE", "ATTACH TABLE")
+					start = time.Now()
 					err = conn.Exec(ctx, sqlAttach, opts)
+					c.observeSlowQuery(host, conn, sqlAttach, start)
 				}
 				if err == nil || strings.Contains(err.Error(), "ALREADY_EXISTS") {
 					queries[i] = "" // Query is executed or object already exists, removing from the list
@@ -16,31 +16,31 @@ package clickhouse
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"database/sql"
 	"fmt"
-
-	// go-clickhouse is explicitly required in order to setup connection to clickhouse db
-	//goch "github.com/mailru/go-clickhouse"
-	goch "github.com/mailru/go-clickhouse/v2"
+	"sync"
+	"time"
 
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 	"github.com/altinity/clickhouse-operator/pkg/util"
 )
 
-// const clickHouseDriverName = "clickhouse"
-const clickHouseDriverName = "chhttp"
-
-func init() {
-	goch.RegisterTLSConfig(tlsSettings, &tls.Config{InsecureSkipVerify: true})
-}
+// connectionMaxIdleConns specifies how many idle connections to the endpoint are kept open for reuse
+const connectionMaxIdleConns = 4
 
 // Connection specifies clickhouse database connection object
 type Connection struct {
 	params *EndpointConnectionParams
 	db     *sql.DB
-	l      log.Announcer
+	// l is guarded by lMu, since users of the pool may set log announcer of the shared connection any time
+	l   log.Announcer
+	lMu sync.RWMutex
+	// mu guards db, closed and used, since connection is shared by all users of the pool
+	mu sync.Mutex
+	// closed specifies connection is evicted from the pool and must not be reopened
+	closed bool
+	// used specifies when connection was used last time
+	used time.Time
 }
 
 // NewConnection creates new clickhouse connection
@@ -49,6 +49,7 @@ func NewConnection(params *EndpointConnectionParams) *Connection {
 	return &Connection{
 		params: params,
 		l:      log.New(),
+		used:   time.Now(),
 	}
 
 }
@@ -66,31 +67,25 @@ func (c *Connection) SetLog(l log.Announcer) *Connection {
 	if c == nil {
 		return nil
 	}
+	c.lMu.Lock()
+	defer c.lMu.Unlock()
 	c.l = l
 	return c
 }
 
+// log gets log announcer
+func (c *Connection) log() log.Announcer {
+	c.lMu.RLock()
+	defer c.lMu.RUnlock()
+	return c.l
+}
+
 // connect performs connect
 func (c *Connection) connect(ctx context.Context) {
-	// Add root CA
-	if c.params.rootCA != "" {
-		rootCAs := x509.NewCertPool()
-		if cert, err := x509.ParseCertificate([]byte(c.params.rootCA)); err != nil {
-			c.l.V(1).F().Error("unable to parse CERT specified in rootCA: %v", err)
-		} else {
-			rootCAs.AddCert(cert)
-			if err := goch.RegisterTLSConfig(tlsSettings, &tls.Config{
-				RootCAs: rootCAs,
-			}); err != nil {
-				c.l.V(1).F().Error("unable to register TLS config %v", err)
-			}
-		}
-	}
-
-	c.l.V(2).Info("Establishing connection: %s", c.params.GetDSNWithHiddenCredentials())
-	dbConnection, err := sql.Open(clickHouseDriverName, c.params.GetDSN())
+	c.log().V(2).Info("Establishing connection: %s", c.params.GetDSNWithHiddenCredentials())
+	dbConnection, err := getDriver(c.params.driver).open(c.params, c.log())
 	if err != nil {
-		c.l.V(1).F().Error("FAILED Open(%s). Err: %v", c.params.GetDSNWithHiddenCredentials(), err)
+		c.log().V(1).F().Error("FAILED Open(%s). Err: %v", c.params.GetDSNWithHiddenCredentials(), err)
 		return
 	}
 
@@ -99,24 +94,92 @@ func (c *Connection) connect(ctx context.Context) {
 	defer cancel()
 
 	if err := dbConnection.PingContext(pingCtx); err != nil {
-		c.l.V(1).F().Error("FAILED Ping(%s). Err: %v", c.params.GetDSNWithHiddenCredentials(), err)
+		c.log().V(1).F().Error("FAILED Ping(%s). Err: %v", c.params.GetDSNWithHiddenCredentials(), err)
 		_ = dbConnection.Close()
 		return
 	}
 
+	// Keep underlying connections open for reuse, instead of establishing new connection per query
+	dbConnection.SetMaxIdleConns(connectionMaxIdleConns)
+	dbConnection.SetConnMaxIdleTime(poolIdleTimeout)
+	dbConnection.SetConnMaxLifetime(poolMaxLifetime)
+
 	c.db = dbConnection
 }
 
 // ensureConnected ensures connection is set
 func (c *Connection) ensureConnected(ctx context.Context) bool {
+	return c.ensureDB(ctx) != nil
+}
+
+// ensureDB ensures connection is set and returns database handle, nil in case connection can not be established.
+// Handle is returned, rather than accessed via the connection, since shared connection may be closed meanwhile.
+// Closed connection is not reopened, since it is not tracked by the pool anymore and would never be closed again,
+// handle of the connection, which replaces it in the pool, is returned instead
+func (c *Connection) ensureDB(ctx context.Context) *sql.DB {
+	if db, evicted := c.ensureOwnDB(ctx); !evicted {
+		return db
+	}
+
+	c.log().V(1).F().Info("Connection is evicted from the pool, use the pooled one: %s", c.params.GetDSNWithHiddenCredentials())
+	db, _ := GetPooledDBConnection(c.params).ensureOwnDB(ctx)
+	return db
+}
+
+// ensureOwnDB ensures connection is set and returns its own database handle, unless the connection is evicted from the pool
+func (c *Connection) ensureOwnDB(ctx context.Context) (db *sql.DB, evicted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, true
+	}
+
+	c.used = time.Now()
+
 	if c.db != nil {
-		c.l.V(2).F().Info("Already connected: %s", c.params.GetDSNWithHiddenCredentials())
-		return true
+		c.log().V(2).F().Info("Already connected: %s", c.params.GetDSNWithHiddenCredentials())
+		return c.db, false
 	}
 
 	c.connect(ctx)
 
-	return c.db != nil
+	return c.db, false
+}
+
+// isEvicted checks whether connection is evicted from the pool
+func (c *Connection) isEvicted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+// Close closes connection. Queries already started are completed
+func (c *Connection) Close() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.db == nil {
+		return
+	}
+	if err := c.db.Close(); err != nil {
+		c.log().V(1).F().Warning("FAILED Close(%s). Err: %v", c.params.GetDSNWithHiddenCredentials(), err)
+	}
+	c.db = nil
+}
+
+// lastUsed gets time connection was used last time
+func (c *Connection) lastUsed() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.used
 }
 
 // QueryContext runs given sql query on behalf of specified context
@@ -125,9 +188,10 @@ func (c *Connection) QueryContext(ctx context.Context, sql string) (*QueryResult
 		return nil, nil
 	}
 
-	if !c.ensureConnected(ctx) {
+	db := c.ensureDB(ctx)
+	if db == nil {
 		s := fmt.Sprintf("FAILED connect(%s) for SQL: %s", c.params.GetDSNWithHiddenCredentials(), sql)
-		c.l.V(1).F().Error(s)
+		c.log().V(1).F().Error(s)
 		return nil, fmt.Errorf(s)
 	}
 
@@ -138,15 +202,21 @@ func (c *Connection) QueryContext(ctx context.Context, sql string) (*QueryResult
 	// Query should have timeout
 	queryCtx, cancel := context.WithTimeout(c.ensureCtx(ctx), c.params.GetQueryTimeout())
 
-	rows, err := c.db.QueryContext(queryCtx, sql)
+	rows, err := db.QueryContext(queryCtx, sql)
+	if (err != nil) && c.isEvicted() {
+		// Connection is evicted from the pool while the query was being started, retry with the pooled one
+		if db = c.ensureDB(ctx); db != nil {
+			rows, err = db.QueryContext(queryCtx, sql)
+		}
+	}
 	if err != nil {
 		cancel()
 		s := fmt.Sprintf("FAILED Query(%s) %v for SQL: %s", c.params.GetDSNWithHiddenCredentials(), err, sql)
-		c.l.V(1).F().Error(s)
+		c.log().V(1).F().Error(s)
 		return nil, err
 	}
 
-	c.l.V(2).Info("clickhouse.QueryContext():'%s'", sql)
+	c.log().V(2).Info("clickhouse.QueryContext():'%s'", sql)
 
 	return NewQueryResult(queryCtx, cancel, rows), nil
 }
@@ -180,22 +250,29 @@ func (c *Connection) Exec(_ctx context.Context, sql string, opts *QueryOptions)
 	ctx, cancel := c.ctx(_ctx, opts)
 	defer cancel()
 
-	if !c.ensureConnected(ctx) {
+	db := c.ensureDB(ctx)
+	if db == nil {
 		cancel()
 		s := fmt.Sprintf("FAILED connect(%s) for SQL: %s", c.params.GetDSNWithHiddenCredentials(), sql)
-		c.l.V(1).F().Error(s)
+		c.log().V(1).F().Error(s)
 		return fmt.Errorf(s)
 	}
 
-	_, err := c.db.ExecContext(ctx, sql)
+	_, err := db.ExecContext(ctx, sql)
+	if (err != nil) && c.isEvicted() {
+		// Connection is evicted from the pool while the query was being started, retry with the pooled one
+		if db = c.ensureDB(ctx); db != nil {
+			_, err = db.ExecContext(ctx, sql)
+		}
+	}
 
 	if err != nil {
 		cancel()
-		c.l.V(1).F().Error("FAILED Exec(%s) %v for SQL: %s", c.params.GetDSNWithHiddenCredentials(), err, sql)
+		c.log().V(1).F().Error("FAILED Exec(%s) %v for SQL: %s", c.params.GetDSNWithHiddenCredentials(), err, sql)
 		return err
 	}
 
-	c.l.V(2).F().Info("\n%s", sql)
+	c.log().V(2).F().Info("\n%s", sql)
 
 	return nil
 }
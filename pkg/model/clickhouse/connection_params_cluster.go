@@ -25,9 +25,9 @@ type ClusterConnectionParams struct {
 }
 
 // NewClusterConnectionParams creates new ClusterConnectionParams
-func NewClusterConnectionParams(scheme, username, password, rootCA string, port int) *ClusterConnectionParams {
+func NewClusterConnectionParams(driver, compression, scheme, username, password, rootCA string, port int) *ClusterConnectionParams {
 	return &ClusterConnectionParams{
-		NewClusterCredentials(scheme, username, password, rootCA, port),
+		NewClusterCredentials(driver, compression, scheme, username, password, rootCA, port),
 		NewTimeouts(),
 	}
 }
@@ -36,6 +36,8 @@ func NewClusterConnectionParams(scheme, username, password, rootCA string, port
 // CHOp config to get parameters from
 func NewClusterConnectionParamsFromCHOpConfig(config *api.OperatorConfig) *ClusterConnectionParams {
 	params := NewClusterConnectionParams(
+		config.ClickHouse.Access.Driver,
+		config.ClickHouse.Access.Compression,
 		config.ClickHouse.Access.Scheme,
 		config.ClickHouse.Access.Username,
 		config.ClickHouse.Access.Password,
@@ -44,6 +46,9 @@ func NewClusterConnectionParamsFromCHOpConfig(config *api.OperatorConfig) *Clust
 	)
 	params.SetConnectTimeout(config.ClickHouse.Access.Timeouts.Connect)
 	params.SetQueryTimeout(config.ClickHouse.Access.Timeouts.Query)
+	if threshold := config.ClickHouse.Access.SlowQueries.Threshold; threshold != nil {
+		params.SetSlowQueryThreshold(*threshold)
+	}
 
 	return params
 }
@@ -57,12 +62,43 @@ func (p *ClusterConnectionParams) SetTimeouts(timeouts *Timeouts) *ClusterConnec
 	return p
 }
 
+// SetHostPorts adjusts scheme and port with per-host ports, according to the driver.
+// HTTP driver uses HTTP and HTTPS ports, native driver uses TCP and TLS ports.
+// In case of 'auto' scheme plain port is preferred, secure one is used in case plain port is not assigned
+func (p *ClusterConnectionParams) SetHostPorts(tcpPort, tlsPort, httpPort, httpsPort int32) *ClusterConnectionParams {
+	if p == nil {
+		return nil
+	}
+	plainPort, securePort := httpPort, httpsPort
+	if p.Driver == api.ChDriverNative {
+		plainPort, securePort = tcpPort, tlsPort
+	}
+	switch p.Scheme {
+	case api.ChSchemeAuto:
+		switch {
+		case api.IsPortAssigned(plainPort):
+			p.Scheme = api.ChSchemeHTTP
+			p.Port = int(plainPort)
+		case api.IsPortAssigned(securePort):
+			p.Scheme = api.ChSchemeHTTPS
+			p.Port = int(securePort)
+		}
+	case api.ChSchemeHTTP:
+		p.Port = int(plainPort)
+	case api.ChSchemeHTTPS:
+		p.Port = int(securePort)
+	}
+	return p
+}
+
 // NewEndpointConnectionParams creates endpoint connection params for a specified host in the cluster
 func (p *ClusterConnectionParams) NewEndpointConnectionParams(host string) *EndpointConnectionParams {
 	if p == nil {
 		return nil
 	}
 	return NewEndpointConnectionParams(
+		p.Driver,
+		p.Compression,
 		p.Scheme,
 		host,
 		p.Username,
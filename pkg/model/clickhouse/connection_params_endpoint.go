@@ -21,9 +21,9 @@ type EndpointConnectionParams struct {
 }
 
 // NewEndpointConnectionParams creates new EndpointConnectionParams
-func NewEndpointConnectionParams(scheme, hostname, username, password, rootCA string, port int) *EndpointConnectionParams {
+func NewEndpointConnectionParams(driver, compression, scheme, hostname, username, password, rootCA string, port int) *EndpointConnectionParams {
 	return &EndpointConnectionParams{
-		NewEndpointCredentials(scheme, hostname, username, password, rootCA, port),
+		NewEndpointCredentials(driver, compression, scheme, hostname, username, password, rootCA, port),
 		NewTimeouts(),
 	}
 }
@@ -16,20 +16,24 @@ package clickhouse
 
 // ClusterCredentials specifies cluster endpoint credentials
 type ClusterCredentials struct {
-	Scheme   string
-	Username string
-	Password string
-	RootCA   string
-	Port     int
+	Driver      string
+	Compression string
+	Scheme      string
+	Username    string
+	Password    string
+	RootCA      string
+	Port        int
 }
 
 // NewClusterCredentials creates new ClusterCredentials
-func NewClusterCredentials(scheme, username, password, rootCA string, port int) *ClusterCredentials {
+func NewClusterCredentials(driver, compression, scheme, username, password, rootCA string, port int) *ClusterCredentials {
 	return &ClusterCredentials{
-		Scheme:   scheme,
-		Username: username,
-		Password: password,
-		RootCA:   rootCA,
-		Port:     port,
+		Driver:      driver,
+		Compression: compression,
+		Scheme:      scheme,
+		Username:    username,
+		Password:    password,
+		RootCA:      rootCA,
+		Port:        port,
 	}
 }
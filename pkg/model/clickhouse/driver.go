@@ -0,0 +1,56 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"crypto/x509"
+	"database/sql"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// driver specifies how connection to ClickHouse is established over particular protocol
+type driver interface {
+	// makeDSN makes DSN of the endpoint, which identifies connection in logs and in the pool
+	makeDSN(c *EndpointCredentials, hideCredentials bool) string
+	// open opens database handle to the endpoint. Connection itself is not established
+	open(params *EndpointConnectionParams, l log.Announcer) (*sql.DB, error)
+}
+
+// drivers specifies available drivers by name
+var drivers = map[string]driver{
+	api.ChDriverHTTP:   &httpDriver{},
+	api.ChDriverNative: &nativeDriver{},
+}
+
+// getDriver gets driver by name, HTTP driver is used by default
+func getDriver(name string) driver {
+	if d, ok := drivers[name]; ok {
+		return d
+	}
+	return drivers[api.ChDriverHTTP]
+}
+
+// makeRootCAs makes cert pool with root CA to verify ClickHouse server certificate with
+func makeRootCAs(rootCA string) (*x509.CertPool, error) {
+	cert, err := x509.ParseCertificate([]byte(rootCA))
+	if err != nil {
+		return nil, err
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(cert)
+	return rootCAs, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+
+	// go-clickhouse is explicitly required in order to setup connection to clickhouse db
+	//goch "github.com/mailru/go-clickhouse"
+	goch "github.com/mailru/go-clickhouse/v2"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+)
+
+// const clickHouseDriverName = "clickhouse"
+const clickHouseDriverName = "chhttp"
+
+func init() {
+	goch.RegisterTLSConfig(tlsSettings, &tls.Config{InsecureSkipVerify: true})
+}
+
+// httpDriver connects to ClickHouse via HTTP(S) interface
+type httpDriver struct{}
+
+// makeDSN makes DSN of the endpoint
+func (d *httpDriver) makeDSN(c *EndpointCredentials, hideCredentials bool) string {
+	baseUrl := fmt.Sprintf(
+		chDsnUrlPattern,
+		c.scheme,
+		c.makeUsernamePassword(hideCredentials),
+		c.makeHostPort(),
+	)
+	if c.isSecure() {
+		baseUrl += "?tls_config=" + tlsSettings
+	}
+	return baseUrl
+}
+
+// open opens database handle to the endpoint
+func (d *httpDriver) open(params *EndpointConnectionParams, l log.Announcer) (*sql.DB, error) {
+	// Add root CA
+	if params.rootCA != "" {
+		if rootCAs, err := makeRootCAs(params.rootCA); err != nil {
+			l.V(1).F().Error("unable to parse CERT specified in rootCA: %v", err)
+		} else {
+			if err := goch.RegisterTLSConfig(tlsSettings, &tls.Config{
+				RootCAs: rootCAs,
+			}); err != nil {
+				l.V(1).F().Error("unable to register TLS config %v", err)
+			}
+		}
+	}
+
+	return sql.Open(clickHouseDriverName, params.GetDSN())
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+
+	goch "github.com/ClickHouse/clickhouse-go/v2"
+
+	log "github.com/altinity/clickhouse-operator/pkg/announcer"
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+)
+
+// nativeCompressionMethods specifies compression methods of native protocol by name
+var nativeCompressionMethods = map[string]goch.CompressionMethod{
+	api.ChCompressionNone: goch.CompressionNone,
+	api.ChCompressionLZ4:  goch.CompressionLZ4,
+	api.ChCompressionZSTD: goch.CompressionZSTD,
+}
+
+// nativeDriver connects to ClickHouse via native TCP protocol, so it works with clusters which have HTTP disabled
+type nativeDriver struct{}
+
+// makeDSN makes DSN of the endpoint
+func (d *nativeDriver) makeDSN(c *EndpointCredentials, hideCredentials bool) string {
+	baseUrl := fmt.Sprintf(
+		chDsnUrlPattern,
+		nativeScheme,
+		c.makeUsernamePassword(hideCredentials),
+		c.makeHostPort(),
+	)
+	baseUrl += "?compress=" + d.getCompressionMethod(c).String()
+	if c.isSecure() {
+		baseUrl += "&secure=true"
+	}
+	return baseUrl
+}
+
+// open opens database handle to the endpoint
+func (d *nativeDriver) open(params *EndpointConnectionParams, l log.Announcer) (*sql.DB, error) {
+	options := &goch.Options{
+		Protocol: goch.Native,
+		Addr:     []string{params.makeHostPort()},
+		Auth: goch.Auth{
+			Username: params.username,
+			Password: params.password,
+		},
+		Compression: &goch.Compression{
+			Method: d.getCompressionMethod(params.EndpointCredentials),
+		},
+		DialTimeout: params.GetConnectTimeout(),
+	}
+
+	if params.isSecure() {
+		// Same as HTTP driver, server certificate is verified only in case root CA is specified
+		options.TLS = &tls.Config{InsecureSkipVerify: true}
+		if params.rootCA != "" {
+			if rootCAs, err := makeRootCAs(params.rootCA); err != nil {
+				l.V(1).F().Error("unable to parse CERT specified in rootCA: %v", err)
+			} else {
+				options.TLS = &tls.Config{RootCAs: rootCAs}
+			}
+		}
+	}
+
+	return goch.OpenDB(options), nil
+}
+
+// getCompressionMethod gets compression method of the endpoint, LZ4 by default
+func (d *nativeDriver) getCompressionMethod(c *EndpointCredentials) goch.CompressionMethod {
+	if method, ok := nativeCompressionMethods[c.compression]; ok {
+		return method
+	}
+	return goch.CompressionLZ4
+}
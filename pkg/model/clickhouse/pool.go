@@ -16,52 +16,96 @@ package clickhouse
 
 import (
 	"sync"
+	"time"
 
 	log "github.com/altinity/clickhouse-operator/pkg/announcer"
 )
 
+const (
+	// poolIdleTimeout specifies how long pooled connection may stay unused before it is evicted from the pool
+	poolIdleTimeout = 10 * time.Minute
+	// poolMaxLifetime specifies how long connection may stay in the pool,
+	// so connections to pods, which are recreated with new IP addresses, are not kept forever
+	poolMaxLifetime = 1 * time.Hour
+	// poolEvictionPeriod specifies how often the pool is checked for connections to be evicted
+	poolEvictionPeriod = 1 * time.Minute
+)
+
+// poolEntry specifies pooled connection
+type poolEntry struct {
+	connection *Connection
+	created    time.Time
+}
+
+// isExpired checks whether pooled connection is to be evicted.
+// Idle time is counted from the last query, since callers may hold connection long after it is looked up
+func (e *poolEntry) isExpired(now time.Time) bool {
+	return (now.Sub(e.connection.lastUsed()) > poolIdleTimeout) || (now.Sub(e.created) > poolMaxLifetime)
+}
+
 var (
-	dbConnectionPool               = sync.Map{}
-	dbConnectionPoolEntryInitMutex = sync.Mutex{}
+	dbConnectionPool        = make(map[string]*poolEntry)
+	dbConnectionPoolMutex   = sync.Mutex{}
+	dbConnectionPoolEvicted = time.Now()
 )
 
 // GetPooledDBConnection gets connection out of the pool.
+// Connections are pooled by endpoint and credentials and shared by all users of the pool.
 // In case no connection available new connection is created and returned.
 func GetPooledDBConnection(params *EndpointConnectionParams) *Connection {
 	key := makePoolKey(params)
+	now := time.Now()
 
-	if connection, existed := dbConnectionPool.Load(key); existed {
-		log.V(2).F().Info("Found pooled connection: %s", params.GetDSNWithHiddenCredentials())
-		return connection.(*Connection)
-	}
+	dbConnectionPoolMutex.Lock()
+	defer dbConnectionPoolMutex.Unlock()
 
-	// Pooled connection not found, need to add it to the pool
+	evictExpiredDBConnections(now)
 
-	dbConnectionPoolEntryInitMutex.Lock()
-	defer dbConnectionPoolEntryInitMutex.Unlock()
-
-	// Double check for race condition
-	if connection, existed := dbConnectionPool.Load(key); existed {
+	if entry, existed := dbConnectionPool[key]; existed {
 		log.V(2).F().Info("Found pooled connection: %s", params.GetDSNWithHiddenCredentials())
-		return connection.(*Connection)
+		return entry.connection
 	}
 
+	// Pooled connection not found, need to add it to the pool
 	log.V(2).F().Info("Add connection to the pool: %s", params.GetDSNWithHiddenCredentials())
-	dbConnectionPool.Store(key, NewConnection(params))
+	entry := &poolEntry{
+		connection: NewConnection(params),
+		created:    now,
+	}
+	dbConnectionPool[key] = entry
 
-	// Fetch from the pool
-	if connection, existed := dbConnectionPool.Load(key); existed {
-		log.V(2).F().Info("Found pooled connection: %s", params.GetDSNWithHiddenCredentials())
-		return connection.(*Connection)
+	return entry.connection
+}
+
+// evictExpiredDBConnections closes and evicts connections, which are either idle or too old.
+// Expected to be called with the pool locked
+func evictExpiredDBConnections(now time.Time) {
+	if now.Sub(dbConnectionPoolEvicted) < poolEvictionPeriod {
+		return
 	}
+	dbConnectionPoolEvicted = now
 
-	return nil
+	for key, entry := range dbConnectionPool {
+		if entry.isExpired(now) {
+			log.V(2).F().Info("Evict connection from the pool: %s", entry.connection.Params().GetDSNWithHiddenCredentials())
+			entry.connection.Close()
+			delete(dbConnectionPool, key)
+		}
+	}
 }
 
-// DropHost deletes host from the pool
-// TODO we need to be able to remove entries from the pool
+// DropHost closes and deletes connections to the host from the pool
 func DropHost(host string) {
+	dbConnectionPoolMutex.Lock()
+	defer dbConnectionPoolMutex.Unlock()
 
+	for key, entry := range dbConnectionPool {
+		if entry.connection.Params().GetHostname() == host {
+			log.V(2).F().Info("Drop connection from the pool: %s", entry.connection.Params().GetDSNWithHiddenCredentials())
+			entry.connection.Close()
+			delete(dbConnectionPool, key)
+		}
+	}
 }
 
 // makePoolKey makes key out of connection params to be used by the pool
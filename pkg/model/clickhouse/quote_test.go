@@ -0,0 +1,20 @@
+package clickhouse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	require.Equal(t, `'abc'`, Quote("abc"))
+	require.Equal(t, `'it\'s'`, Quote("it's"))
+	require.Equal(t, `'a\\\'b'`, Quote(`a\'b`))
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	require.Equal(t, "`db`", QuoteIdentifier("db"))
+	require.Equal(t, "`my\\`table`", QuoteIdentifier("my`table"))
+	require.Equal(t, "`a\\\\\\`b`", QuoteIdentifier("a\\`b"))
+	require.Equal(t, "`t\"1`", QuoteIdentifier(`t"1`))
+}
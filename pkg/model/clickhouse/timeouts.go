@@ -29,6 +29,8 @@ type Timeouts struct {
 	connect time.Duration
 	// query specifies timeout used when running query
 	query time.Duration
+	// slowQuery specifies duration query is reported as slow after. Zero disables slow queries reporting
+	slowQuery time.Duration
 }
 
 // NewTimeouts creates new set of timeouts
@@ -80,3 +82,20 @@ func (t *Timeouts) SetQueryTimeout(timeout time.Duration) *Timeouts {
 	t.query = timeout
 	return t
 }
+
+// GetSlowQueryThreshold gets slow query threshold
+func (t *Timeouts) GetSlowQueryThreshold() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.slowQuery
+}
+
+// SetSlowQueryThreshold sets slow query threshold
+func (t *Timeouts) SetSlowQueryThreshold(threshold time.Duration) *Timeouts {
+	if t == nil {
+		return nil
+	}
+	t.slowQuery = threshold
+	return t
+}
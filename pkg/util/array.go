@@ -62,6 +62,16 @@ func IntersectStringArrays(a []string, b []string) (res []string) {
 	return res
 }
 
+// SubtractStringArrays returns items of `a` which are not present in `b`
+func SubtractStringArrays(a []string, b []string) (res []string) {
+	for _, item := range a {
+		if !InArray(item, b) {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
 // RemoveFromArray removes the needle from the haystack
 func RemoveFromArray(needle string, haystack []string) []string {
 	result := []string{}
@@ -35,6 +35,20 @@ const (
 	noEol = ""
 )
 
+// textEscaper escapes characters not allowed in XML text
+var textEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// EscapeText escapes text to be used as XML tag value
+func EscapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
 // GenerateFromSettings creates XML representation from the provided settings
 func GenerateFromSettings(w io.Writer, settings *api.Settings, prefix string) {
 	if settings.Len() == 0 {